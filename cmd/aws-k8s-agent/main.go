@@ -74,9 +74,24 @@ func _main() int {
 		return 1
 	}
 
+	// Settings reloaded from a ConfigMap, applied by the pool manager
+	ipamContext.StartConfigReloader()
+
 	// Pool manager
 	go ipamContext.StartNodeIPPoolManager()
 
+	// ENIConfig status
+	go ipamContext.StartENIConfigStatusController()
+
+	// SNAT policies
+	go ipamContext.StartSNATPolicyController()
+
+	// Egress Elastic IPs
+	go ipamContext.StartEgressEIPController()
+
+	// Host network drift repair
+	go ipamContext.StartHostNetworkReconciler()
+
 	if !utils.GetBoolAsStringEnvVar(envDisableMetrics, false) {
 		// Prometheus metrics
 		go metrics.ServeMetrics(metricsPort)
@@ -73,6 +73,7 @@ const (
 	defaultEgressV4PluginLogFile = "/var/log/nholuongut-routed-eni/egress-v4-plugin.log"
 	defaultEgressV6PluginLogFile = "/var/log/nholuongut-routed-eni/egress-v6-plugin.log"
 	defaultPluginLogLevel        = "Debug"
+	defaultEnableIPv4            = false
 	defaultEnableIPv6            = false
 	defaultEnableIPv6Egress      = false
 	defaultEnableIPv4Egress      = true
@@ -82,7 +83,10 @@ const (
 	defaultEnBandwidthPlugin     = false
 	defaultEnPrefixDelegation    = false
 	defaultIPCooldownPeriod      = 30
+	defaultStickyIPTTL           = 300
 	defaultDisablePodV6          = false
+	defaultPodDatapath           = "iprule"
+	defaultNetfilterBackend      = "iptables"
 
 	envHostCniBinPath        = "HOST_CNI_BIN_PATH"
 	envHostCniConfDirPath    = "HOST_CNI_CONFDIR_PATH"
@@ -100,12 +104,17 @@ const (
 	envMinIPTarget           = "MINIMUM_IP_TARGET"
 	envWarmPrefixTarget      = "WARM_PREFIX_TARGET"
 	envEnBandwidthPlugin     = "ENABLE_BANDWIDTH_PLUGIN"
+	envEnIPv4                = "ENABLE_IPv4"
 	envEnIPv6                = "ENABLE_IPv6"
 	envEnIPv6Egress          = "ENABLE_V6_EGRESS"
 	envEnIPv4Egress          = "ENABLE_V4_EGRESS"
 	envRandomizeSNAT         = "nholuongut_VPC_K8S_CNI_RANDOMIZESNAT"
 	envIPCooldownPeriod      = "IP_COOLDOWN_PERIOD"
+	envIPAllocationStrategy  = "IP_ALLOCATION_STRATEGY"
+	envStickyIPTTL           = "STICKY_IP_TTL"
 	envDisablePodV6          = "DISABLE_POD_V6"
+	envPodDatapath           = "POD_DATAPATH"
+	envNetfilterBackend      = "NETFILTER_BACKEND"
 )
 
 // NetConfList describes an ordered list of networks.
@@ -143,8 +152,12 @@ type NetConf struct {
 
 	PodSGEnforcingMode string `json:"podSGEnforcingMode,omitempty"`
 
+	PodDatapath string `json:"podDatapath,omitempty"`
+
 	RandomizeSNAT string `json:"randomizeSNAT,omitempty"`
 
+	NetfilterBackend string `json:"netfilterBackend,omitempty"`
+
 	// MTU for eth0
 	MTU string `json:"mtu,omitempty"`
 
@@ -219,14 +232,22 @@ func generateJSON(jsonFile string, outFile string, getPrimaryIP func(ipv4 bool)
 	// enabledIPv6 is to determine if EKS cluster is IPv4 or IPv6 cluster
 	// if this EKS cluster is IPv6 cluster, egress-cni-plugin will enable IPv4 egress by default
 	// if this EKS cluster is IPv4 cluster, egress-cni-plugin will only enable IPv6 egress if env var "ENABLE_V6_EGRESS" is "true"
+	// if both IPv4 and IPv6 are enabled, pods get addresses of both families from ipamd and egress-cni-plugin is disabled
 	enabledIPv6 := utils.GetBoolAsStringEnvVar(envEnIPv6, defaultEnableIPv6)
+	enabledDualStack := enabledIPv6 && utils.GetBoolAsStringEnvVar(envEnIPv4, defaultEnableIPv4)
 	var egressIPAMSubnet string
 	var egressIPAMDst string
 	var egressIPAMDataDir string
 	var egressEnabled bool
 	var egressPluginLogFile string
 	var nodeIP = ""
-	if enabledIPv6 {
+	if enabledDualStack {
+		egressIPAMSubnet = egressPluginIpamSubnetV6
+		egressIPAMDst = egressPluginIpamDstV6
+		egressIPAMDataDir = egressPluginIpamDataDirV6
+		egressPluginLogFile = utils.GetEnv(envEgressV6PluginLogFile, defaultEgressV6PluginLogFile)
+		egressEnabled = false
+	} else if enabledIPv6 {
 		// EKS IPv6 cluster
 		egressIPAMSubnet = egressPluginIpamSubnetV4
 		egressIPAMDst = egressPluginIpamDstV4
@@ -263,14 +284,17 @@ func generateJSON(jsonFile string, outFile string, getPrimaryIP func(ipv4 bool)
 	// If pod MTU environment variable is set, overwrite ENI MTU.
 	podMTU := utils.GetEnv(envPodMTU, eniMTU)
 	podSGEnforcingMode := utils.GetEnv(envPodSGEnforcingMode, defaultPodSGEnforcingMode)
+	podDatapath := utils.GetEnv(envPodDatapath, defaultPodDatapath)
 	pluginLogFile := utils.GetEnv(envPluginLogFile, defaultPluginLogFile)
 	pluginLogLevel := utils.GetEnv(envPluginLogLevel, defaultPluginLogLevel)
 	randomizeSNAT := utils.GetEnv(envRandomizeSNAT, defaultRandomizeSNAT)
+	netfilterBackend := utils.GetEnv(envNetfilterBackend, defaultNetfilterBackend)
 
 	netconf := string(byteValue)
 	netconf = strings.Replace(netconf, "__VETHPREFIX__", vethPrefix, -1)
 	netconf = strings.Replace(netconf, "__MTU__", podMTU, -1)
 	netconf = strings.Replace(netconf, "__PODSGENFORCINGMODE__", podSGEnforcingMode, -1)
+	netconf = strings.Replace(netconf, "__PODDATAPATH__", podDatapath, -1)
 	netconf = strings.Replace(netconf, "__PLUGINLOGFILE__", pluginLogFile, -1)
 	netconf = strings.Replace(netconf, "__PLUGINLOGLEVEL__", pluginLogLevel, -1)
 	netconf = strings.Replace(netconf, "__EGRESSPLUGINLOGFILE__", egressPluginLogFile, -1)
@@ -279,6 +303,7 @@ func generateJSON(jsonFile string, outFile string, getPrimaryIP func(ipv4 bool)
 	netconf = strings.Replace(netconf, "__EGRESSPLUGINIPAMDST__", egressIPAMDst, -1)
 	netconf = strings.Replace(netconf, "__EGRESSPLUGINIPAMDATADIR__", egressIPAMDataDir, -1)
 	netconf = strings.Replace(netconf, "__RANDOMIZESNAT__", randomizeSNAT, -1)
+	netconf = strings.Replace(netconf, "__NETFILTERBACKEND__", netfilterBackend, -1)
 	netconf = strings.Replace(netconf, "__NODEIP__", nodeIP, -1)
 
 	byteValue = []byte(netconf)
@@ -361,6 +386,26 @@ func validateEnvVars() bool {
 		}
 	}
 
+	// Validate the pod datapath. The bandwidth plugin installs its own ingress qdisc on the host veth,
+	// which cannot coexist with the clsact qdisc used by the eBPF datapath.
+	podDatapath := utils.GetEnv(envPodDatapath, defaultPodDatapath)
+	if podDatapath != "iprule" && podDatapath != "ebpf" {
+		log.Errorf("%s must be set to either 'iprule' or 'ebpf'", envPodDatapath)
+		return false
+	}
+	if podDatapath == "ebpf" && utils.GetBoolAsStringEnvVar(envEnBandwidthPlugin, defaultEnBandwidthPlugin) {
+		log.Errorf("%s cannot be set to 'ebpf' when %s is enabled", envPodDatapath, envEnBandwidthPlugin)
+		return false
+	}
+
+	// Validate that NETFILTER_BACKEND is a known backend
+	switch netfilterBackend := utils.GetEnv(envNetfilterBackend, defaultNetfilterBackend); netfilterBackend {
+	case "iptables", "nftables", "auto":
+	default:
+		log.Errorf("%s must be set to one of 'iptables', 'nftables' or 'auto'. %s is invalid", envNetfilterBackend, netfilterBackend)
+		return false
+	}
+
 	// Validate that IP_COOLDOWN_PERIOD is a valid integer
 	ipCooldownPeriod, err, input := utils.GetIntFromStringEnvVar(envIPCooldownPeriod, defaultIPCooldownPeriod)
 	if err != nil || ipCooldownPeriod < 0 {
@@ -368,6 +413,21 @@ func validateEnvVars() bool {
 		return false
 	}
 
+	// Validate that STICKY_IP_TTL is a valid integer
+	stickyIPTTL, err, input := utils.GetIntFromStringEnvVar(envStickyIPTTL, defaultStickyIPTTL)
+	if err != nil || stickyIPTTL < 0 {
+		log.Errorf("STICKY_IP_TTL MUST be a valid positive integer. %s is invalid", input)
+		return false
+	}
+
+	// Validate that IP_ALLOCATION_STRATEGY is a known strategy, if set
+	switch ipAllocationStrategy := utils.GetEnv(envIPAllocationStrategy, ""); ipAllocationStrategy {
+	case "", "random", "pack", "spread", "primary-eni":
+	default:
+		log.Errorf("%s must be set to one of 'random', 'pack', 'spread' or 'primary-eni'. %s is invalid", envIPAllocationStrategy, ipAllocationStrategy)
+		return false
+	}
+
 	// Validate MTU value for ENIs and pods
 	if !validateMTU(envEniMTU) || !validateMTU(envPodMTU) {
 		return false
@@ -49,6 +49,34 @@ func TestGenerateJSONPlusBandwidthAndTuning(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+// Validate that egress-cni plugin is disabled in dual stack mode, even if v4 egress is requested
+func TestEgressCNIPluginDisabledInDualStack(t *testing.T) {
+	_ = os.Setenv(envEnIPv4, "true")
+	_ = os.Setenv(envEnIPv6, "true")
+	_ = os.Setenv(envEnIPv4Egress, "true")
+	defer os.Unsetenv(envEnIPv4)
+	defer os.Unsetenv(envEnIPv6)
+
+	// Use a temporary file for the parsed output.
+	tmpfile, err := os.CreateTemp("", "temp-nholuongut-vpc-cni.conflist")
+	assert.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	err = generateJSON(nholuongutConflist, tmpfile.Name(), getPrimaryIPMock)
+	assert.NoError(t, err)
+
+	var jsonData map[string]interface{}
+	jsonFile, err := os.ReadFile(tmpfile.Name())
+	assert.NoError(t, err)
+
+	err = json.Unmarshal(jsonFile, &jsonData)
+	assert.NoError(t, err)
+
+	plugins, _ := jsonData["plugins"].([]interface{})
+	assert.Equal(t, "egress-cni", plugins[1].(map[string]interface{})["type"])
+	assert.Equal(t, "false", plugins[1].(map[string]interface{})["enabled"])
+}
+
 // Validate setting environment POD_MTU/nholuongut_VPC_ENI_MTU, takes effect for egress-cni plugin
 func TestEgressCNIPluginIPv4EgressTakesMTUEnvVar(t *testing.T) {
 	_ = os.Setenv(envEnIPv4Egress, "true")
@@ -136,3 +164,27 @@ func TestMTUValidation(t *testing.T) {
 	assert.False(t, validateMTU(envEniMTU))
 	assert.False(t, validateMTU(envPodMTU))
 }
+
+func TestPodDatapathValidation(t *testing.T) {
+	defer os.Unsetenv(envPodDatapath)
+	defer os.Unsetenv(envEnBandwidthPlugin)
+	for _, env := range []string{envEnBandwidthPlugin, envEniMTU, envPodMTU, envEnIPv6} {
+		_ = os.Unsetenv(env)
+	}
+
+	// By default, the pod datapath should be valid
+	assert.True(t, validateEnvVars())
+
+	// Known datapaths should succeed
+	_ = os.Setenv(envPodDatapath, "ebpf")
+	assert.True(t, validateEnvVars())
+
+	// Unknown datapaths should fail
+	_ = os.Setenv(envPodDatapath, "xdp")
+	assert.False(t, validateEnvVars())
+
+	// The eBPF datapath cannot be combined with the bandwidth plugin
+	_ = os.Setenv(envPodDatapath, "ebpf")
+	_ = os.Setenv(envEnBandwidthPlugin, "true")
+	assert.False(t, validateEnvVars())
+}
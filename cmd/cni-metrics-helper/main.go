@@ -0,0 +1,166 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// The CNI metrics helper binary, which aggregates the metrics of all nholuongut-node pods in the cluster
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/cmd/cni-metrics-helper/metrics"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/k8sapi"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/publisher"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/logger"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/utils/prometheusmetrics"
+)
+
+const (
+	appName = "cni-metrics-helper"
+	// metricsPort is the port where the aggregated prometheus metrics are re-exposed
+	metricsPort = 61681
+
+	// Environment variables to select where the metrics are sent
+	envUseCloudWatch = "USE_CLOUDWATCH"
+	envUsePrometheus = "USE_PROMETHEUS"
+
+	// Environment variable to set the interval, in seconds, at which the metrics are collected
+	envMetricUpdateInterval     = "METRIC_UPDATE_INTERVAL"
+	defaultMetricUpdateInterval = 30
+
+	// Environment variables to override the region and the CLUSTER_ID CloudWatch dimension
+	envRegion    = "nholuongut_REGION"
+	envClusterID = "nholuongut_CLUSTER_ID"
+)
+
+type options struct {
+	submitCW   bool
+	submitProm bool
+	help       bool
+}
+
+func main() {
+	os.Exit(_main())
+}
+
+func _main() int {
+	// The metrics helper runs as a deployment, so log to stdout rather than to a file on the host
+	logConfig := logger.Configuration{
+		LogLevel:    logger.GetLogLevel(),
+		LogLocation: "stdout",
+	}
+	log := logger.New(&logConfig)
+
+	options := &options{}
+	flags := pflag.NewFlagSet("", pflag.ExitOnError)
+	flags.BoolVar(&options.submitCW, "cloudwatch", true, "Publish the aggregated metrics to CloudWatch")
+	flags.BoolVar(&options.submitProm, "prometheus", false, "Re-expose the aggregated metrics to Prometheus")
+	flags.BoolVar(&options.help, "help", false, "Display this help message")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(os.Args); err != nil {
+		log.Errorf("Error on parsing parameters: %s", err)
+		return 1
+	}
+	if options.help {
+		flags.Usage()
+		return 1
+	}
+
+	// Environment variables take precedence over the flags
+	options.submitCW = getBoolEnvVar(envUseCloudWatch, options.submitCW)
+	options.submitProm = getBoolEnvVar(envUsePrometheus, options.submitProm)
+
+	metricUpdateInterval := defaultMetricUpdateInterval
+	if val, ok := os.LookupEnv(envMetricUpdateInterval); ok {
+		interval, err := strconv.Atoi(val)
+		if err != nil || interval <= 0 {
+			log.Errorf("Invalid %s %q, using the default of %d seconds", envMetricUpdateInterval, val, defaultMetricUpdateInterval)
+		} else {
+			metricUpdateInterval = interval
+		}
+	}
+	// Publish to CloudWatch at twice the collection interval so that every push carries data
+	publishInterval := metricUpdateInterval * 2
+
+	// The region is injected when using IRSA; otherwise the publisher falls back to IMDS
+	region := os.Getenv(envRegion)
+	clusterID := os.Getenv(envClusterID)
+
+	log.Infof("Starting CNIMetricsHelper. Sending metrics to CloudWatch: %v, Prometheus: %v, LogLevel %s, metricUpdateInterval %d",
+		options.submitCW, options.submitProm, logConfig.LogLevel, metricUpdateInterval)
+
+	clientSet, err := k8sapi.GetKubeClientSet()
+	if err != nil {
+		log.Errorf("Error creating clientset: %v", err)
+		return 1
+	}
+	k8sClient, err := k8sapi.CreateKubeClient(appName)
+	if err != nil {
+		log.Errorf("Error creating Kubernetes Client: %v", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var cw publisher.Publisher
+	if options.submitCW {
+		cw, err = publisher.New(ctx, region, clusterID, log)
+		if err != nil {
+			log.Errorf("Failed to create publisher: %v", err)
+			return 1
+		}
+		go cw.Start(publishInterval)
+		defer cw.Stop()
+	}
+
+	if options.submitProm {
+		metrics.PrometheusRegister()
+		go prometheusmetrics.ServeMetrics(metricsPort)
+	}
+
+	podWatcher := metrics.NewDefaultPodWatcher(k8sClient, log)
+	cniMetric := metrics.CNIMetricsNew(clientSet, cw, options.submitCW, options.submitProm, log, podWatcher)
+
+	ticker := time.NewTicker(time.Duration(metricUpdateInterval) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		log.Info("Collecting metrics ...")
+		metrics.Handler(ctx, cniMetric)
+	}
+	return 0
+}
+
+// getBoolEnvVar parses a boolean environment variable, also accepting "yes" and "no"
+func getBoolEnvVar(env string, defaultVal bool) bool {
+	val, ok := os.LookupEnv(env)
+	if !ok {
+		return defaultVal
+	}
+	switch strings.ToLower(val) {
+	case "yes", "true":
+		return true
+	case "no", "false":
+		return false
+	}
+	return defaultVal
+}
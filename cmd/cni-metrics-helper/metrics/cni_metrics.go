@@ -0,0 +1,332 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metrics
+
+import (
+	"context"
+
+	"github.com/nholuongut/nholuongut-sdk-go/nholuongut"
+	"github.com/nholuongut/nholuongut-sdk-go/service/cloudwatch"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/publisher"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/logger"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/utils/prometheusmetrics"
+)
+
+// Port where ipamd publishes its prometheus metrics
+const metricsPort = 61678
+
+const (
+	assignedIPAddressesMetric = "nholuongutcni_assigned_ip_addresses"
+	maxIPAddressesMetric      = "nholuongutcni_ip_max"
+)
+
+var (
+	nodesIPExhausted = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "nholuongutcni_nodes_ip_exhausted",
+			Help: "The number of nodes which have assigned all the IP addresses they can allocate",
+		},
+	)
+	maxNodeIPUtilization = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "nholuongutcni_max_node_ip_utilization_ratio",
+			Help: "The highest ratio of assigned to allocatable IP addresses among all nodes",
+		},
+	)
+)
+
+// InterestingCNIMetrics defines metrics parsing definition for the nholuongut-node metrics
+var InterestingCNIMetrics = map[string]metricsConvert{
+	assignedIPAddressesMetric: {
+		actions: []metricsAction{
+			{cwMetricName: "assignIPAddresses",
+				matchFunc:  matchAny,
+				actionFunc: metricsAdd,
+				data:       &dataPoints{},
+			},
+			{cwMetricName: "maxAssignIPAddressesPerNode",
+				matchFunc:  matchAny,
+				actionFunc: metricsMax,
+				data:       &dataPoints{},
+			},
+		},
+	},
+	"nholuongutcni_total_ip_addresses": {
+		actions: []metricsAction{
+			{cwMetricName: "totalIPAddresses",
+				matchFunc:  matchAny,
+				actionFunc: metricsAdd,
+				data:       &dataPoints{},
+			},
+		},
+	},
+	"nholuongutcni_total_ipv4_prefixes": {
+		actions: []metricsAction{
+			{cwMetricName: "totalIPv4Prefixes",
+				matchFunc:  matchAny,
+				actionFunc: metricsAdd,
+				data:       &dataPoints{},
+			},
+		},
+	},
+	"nholuongutcni_eni_allocated": {
+		actions: []metricsAction{
+			{cwMetricName: "eniAllocated",
+				matchFunc:  matchAny,
+				actionFunc: metricsAdd,
+				data:       &dataPoints{},
+			},
+		},
+	},
+	"nholuongutcni_eni_max": {
+		actions: []metricsAction{
+			{cwMetricName: "eniMaxAvailable",
+				matchFunc:  matchAny,
+				actionFunc: metricsAdd,
+				data:       &dataPoints{},
+			},
+		},
+	},
+	maxIPAddressesMetric: {
+		actions: []metricsAction{
+			{cwMetricName: "maxIPAddresses",
+				matchFunc:  matchAny,
+				actionFunc: metricsAdd,
+				data:       &dataPoints{},
+			},
+		},
+	},
+	"nholuongutcni_ipamd_action_inprogress": {
+		actions: []metricsAction{
+			{cwMetricName: "ipamdActionInProgress",
+				matchFunc:  matchAny,
+				actionFunc: metricsAdd,
+				data:       &dataPoints{},
+			},
+		},
+	},
+	"nholuongutcni_add_ip_req_count": {
+		actions: []metricsAction{
+			{cwMetricName: "addReqCount",
+				matchFunc:  matchAny,
+				actionFunc: metricsAdd,
+				data:       &dataPoints{},
+			},
+		},
+	},
+	"nholuongutcni_del_ip_req_count": {
+		actions: []metricsAction{
+			{cwMetricName: "delReqCount",
+				matchFunc:  matchAny,
+				actionFunc: metricsAdd,
+				data:       &dataPoints{},
+			},
+		},
+	},
+	"nholuongutcni_ipamd_error_count": {
+		actions: []metricsAction{
+			{cwMetricName: "ipamdErr",
+				matchFunc:  matchAny,
+				actionFunc: metricsAdd,
+				data:       &dataPoints{},
+			},
+		},
+	},
+	"nholuongutcni_nholuongut_api_error_count": {
+		actions: []metricsAction{
+			{cwMetricName: "nholuongutAPIErr",
+				matchFunc:  matchAny,
+				actionFunc: metricsAdd,
+				data:       &dataPoints{},
+			},
+		},
+	},
+	"nholuongutcni_nholuongut_utils_error_count": {
+		actions: []metricsAction{
+			{cwMetricName: "nholuongutUtilErr",
+				matchFunc:  matchAny,
+				actionFunc: metricsAdd,
+				data:       &dataPoints{},
+			},
+		},
+	},
+	"nholuongutcni_reconcile_count": {
+		actions: []metricsAction{
+			{cwMetricName: "reconcileCount",
+				matchFunc:  matchAny,
+				actionFunc: metricsAdd,
+				data:       &dataPoints{},
+			},
+		},
+	},
+	"nholuongutcni_force_removed_enis": {
+		actions: []metricsAction{
+			{cwMetricName: "forceRemoveENI",
+				matchFunc:  matchAny,
+				actionFunc: metricsAdd,
+				data:       &dataPoints{},
+			},
+		},
+	},
+	"nholuongutcni_force_removed_ips": {
+		actions: []metricsAction{
+			{cwMetricName: "forceRemoveIPs",
+				matchFunc:  matchAny,
+				actionFunc: metricsAdd,
+				data:       &dataPoints{},
+			},
+		},
+	},
+	"nholuongutcni_no_available_ip_addresses": {
+		actions: []metricsAction{
+			{cwMetricName: "noAvailableIPAddrs",
+				matchFunc:  matchAny,
+				actionFunc: metricsAdd,
+				data:       &dataPoints{},
+			},
+		},
+	},
+}
+
+// nodeIPSummary holds the values derived from every single nholuongut-node pod
+type nodeIPSummary struct {
+	exhaustedNodes       float64
+	maxIPUtilizationRate float64
+}
+
+// CNIMetricsTarget defines data structure for the nholuongut-node metrics target
+type CNIMetricsTarget struct {
+	interestingMetrics map[string]metricsConvert
+	cwMetricsPublisher publisher.Publisher
+	clientSet          kubernetes.Interface
+	podWatcher         PodWatcher
+	submitCW           bool
+	submitProm         bool
+	log                logger.Logger
+	summary            nodeIPSummary
+}
+
+// CNIMetricsNew creates a new metricsTarget
+func CNIMetricsNew(clientSet kubernetes.Interface, cw publisher.Publisher, submitCW bool, submitProm bool,
+	log logger.Logger, podWatcher PodWatcher) *CNIMetricsTarget {
+	return &CNIMetricsTarget{
+		interestingMetrics: newMetricsConvertState(InterestingCNIMetrics),
+		cwMetricsPublisher: cw,
+		clientSet:          clientSet,
+		podWatcher:         podWatcher,
+		submitCW:           submitCW,
+		submitProm:         submitProm,
+		log:                log,
+	}
+}
+
+// PrometheusRegister registers the gauges re-exposed by the CNI metrics helper
+func PrometheusRegister() {
+	for _, collector := range prometheusmetrics.GetSupportedPrometheusCNIMetricsMapping() {
+		prometheus.MustRegister(collector)
+	}
+	prometheus.MustRegister(nodesIPExhausted)
+	prometheus.MustRegister(maxNodeIPUtilization)
+}
+
+func (t *CNIMetricsTarget) grabMetricsFromTarget(ctx context.Context, cniPod string) ([]byte, error) {
+	t.log.Debugf("Grabbing metrics from CNI: %s", cniPod)
+	output, err := getMetricsFromPod(ctx, t.clientSet, cniPod, metav1.NamespaceSystem, metricsPort)
+	if err != nil {
+		t.log.Errorf("grabMetricsFromTarget: Failed to grab CNI endpoint: %v", err)
+		return nil, err
+	}
+	return output, nil
+}
+
+func (t *CNIMetricsTarget) getInterestingMetrics() map[string]metricsConvert {
+	return t.interestingMetrics
+}
+
+func (t *CNIMetricsTarget) getCWMetricsPublisher() publisher.Publisher {
+	return t.cwMetricsPublisher
+}
+
+func (t *CNIMetricsTarget) getTargetList(ctx context.Context) ([]string, error) {
+	return t.podWatcher.GetCNIPods(ctx)
+}
+
+func (t *CNIMetricsTarget) submitCloudWatch() bool {
+	return t.submitCW
+}
+
+func (t *CNIMetricsTarget) submitPrometheus() bool {
+	return t.submitProm
+}
+
+func (t *CNIMetricsTarget) getLogger() logger.Logger {
+	return t.log
+}
+
+func (t *CNIMetricsTarget) resetSummary() {
+	t.summary = nodeIPSummary{}
+}
+
+// summarizeTarget records whether the node behind a single nholuongut-node pod ran out of IP addresses
+func (t *CNIMetricsTarget) summarizeTarget(families map[string]*dto.MetricFamily) {
+	assigned, foundAssigned := gaugeValue(families[assignedIPAddressesMetric])
+	maxIPs, foundMax := gaugeValue(families[maxIPAddressesMetric])
+	if !foundAssigned || !foundMax || maxIPs <= 0 {
+		return
+	}
+	if assigned >= maxIPs {
+		t.summary.exhaustedNodes++
+	}
+	if utilization := assigned / maxIPs; utilization > t.summary.maxIPUtilizationRate {
+		t.summary.maxIPUtilizationRate = utilization
+	}
+}
+
+func (t *CNIMetricsTarget) produceSummary() {
+	if t.submitCW {
+		t.cwMetricsPublisher.Publish(
+			&cloudwatch.MetricDatum{
+				MetricName: nholuongut.String("nodesIPExhausted"),
+				Unit:       nholuongut.String(cloudwatch.StandardUnitCount),
+				Value:      nholuongut.Float64(t.summary.exhaustedNodes),
+			},
+			&cloudwatch.MetricDatum{
+				MetricName: nholuongut.String("maxNodeIPUtilization"),
+				Unit:       nholuongut.String(cloudwatch.StandardUnitPercent),
+				Value:      nholuongut.Float64(t.summary.maxIPUtilizationRate * 100),
+			},
+		)
+	}
+	if t.submitProm {
+		nodesIPExhausted.Set(t.summary.exhaustedNodes)
+		maxNodeIPUtilization.Set(t.summary.maxIPUtilizationRate)
+	}
+}
+
+// gaugeValue returns the sum of all samples of a gauge family
+func gaugeValue(family *dto.MetricFamily) (float64, bool) {
+	if family == nil || family.GetType() != dto.MetricType_GAUGE || len(family.GetMetric()) == 0 {
+		return 0, false
+	}
+	var value float64
+	for _, metric := range family.GetMetric() {
+		value += metric.GetGauge().GetValue()
+	}
+	return value, true
+}
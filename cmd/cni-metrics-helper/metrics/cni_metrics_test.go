@@ -0,0 +1,214 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/nholuongut/nholuongut-sdk-go/service/cloudwatch"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/publisher/mock_publisher"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/logger"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/utils/prometheusmetrics"
+)
+
+const nodeMetricsFormat = `# HELP nholuongutcni_assigned_ip_addresses The number of IP addresses assigned to pods
+# TYPE nholuongutcni_assigned_ip_addresses gauge
+nholuongutcni_assigned_ip_addresses %d
+# HELP nholuongutcni_total_ip_addresses The total number of IP addresses
+# TYPE nholuongutcni_total_ip_addresses gauge
+nholuongutcni_total_ip_addresses %d
+# HELP nholuongutcni_ip_max The maximum number of IP addresses that can be allocated to the instance
+# TYPE nholuongutcni_ip_max gauge
+nholuongutcni_ip_max %d
+# HELP nholuongutcni_add_ip_req_count The number of add IP address requests
+# TYPE nholuongutcni_add_ip_req_count counter
+nholuongutcni_add_ip_req_count %d
+# HELP nholuongutcni_unrelated_metric Not scraped by the metrics helper
+# TYPE nholuongutcni_unrelated_metric gauge
+nholuongutcni_unrelated_metric 42
+`
+
+type testNodeMetrics struct {
+	assigned, total, max, addReq int
+}
+
+// testCNIEndpoints stubs the API server pod proxy of the nholuongut-node pods
+type testCNIEndpoints struct {
+	lock  sync.Mutex
+	nodes map[string]testNodeMetrics
+}
+
+func (e *testCNIEndpoints) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// /api/v1/namespaces/kube-system/pods/<pod>:61678/proxy/metrics
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) != 9 || parts[4] != metav1.NamespaceSystem || parts[8] != "metrics" {
+		http.NotFound(w, r)
+		return
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	node, ok := e.nodes[strings.TrimSuffix(parts[6], fmt.Sprintf(":%d", metricsPort))]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	fmt.Fprintf(w, nodeMetricsFormat, node.assigned, node.total, node.max, node.addReq)
+}
+
+func (e *testCNIEndpoints) set(pod string, node testNodeMetrics) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.nodes[pod] = node
+}
+
+type testPublisher struct {
+	*mock_publisher.MockPublisher
+	data map[string]float64
+}
+
+func newTestPublisher(ctrl *gomock.Controller) *testPublisher {
+	p := &testPublisher{MockPublisher: mock_publisher.NewMockPublisher(ctrl), data: map[string]float64{}}
+	p.EXPECT().Publish(gomock.Any()).Do(func(datums ...*cloudwatch.MetricDatum) {
+		for _, datum := range datums {
+			p.data[*datum.MetricName] = *datum.Value
+		}
+	}).AnyTimes()
+	return p
+}
+
+func cniPod(name string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: metav1.NamespaceSystem,
+			Labels:    map[string]string{"k8s-app": cniPodLabelValue},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func setupCNIMetricsTarget(t *testing.T, cw *testPublisher, endpoints *testCNIEndpoints) *CNIMetricsTarget {
+	server := httptest.NewServer(endpoints)
+	t.Cleanup(server.Close)
+	clientSet, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	assert.NoError(t, err)
+
+	k8sSchema := runtime.NewScheme()
+	corev1.AddToScheme(k8sSchema)
+	otherPod := cniPod("coredns", corev1.PodRunning)
+	otherPod.Labels["k8s-app"] = "kube-dns"
+	k8sClient := fake.NewClientBuilder().WithScheme(k8sSchema).WithObjects(
+		cniPod("nholuongut-node-a", corev1.PodRunning),
+		cniPod("nholuongut-node-b", corev1.PodRunning),
+		cniPod("nholuongut-node-c", corev1.PodPending),
+		otherPod,
+	).Build()
+
+	log := getMetricsLog()
+	return CNIMetricsNew(clientSet, cw, true, true, log, NewDefaultPodWatcher(k8sClient, log))
+}
+
+func TestGetCNIPods(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	target := setupCNIMetricsTarget(t, newTestPublisher(ctrl), &testCNIEndpoints{})
+	pods, err := target.getTargetList(context.Background())
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"nholuongut-node-a", "nholuongut-node-b"}, pods)
+}
+
+func TestCNIMetricsHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cw := newTestPublisher(ctrl)
+	endpoints := &testCNIEndpoints{nodes: map[string]testNodeMetrics{
+		"nholuongut-node-a": {assigned: 10, total: 14, max: 29, addReq: 5},
+		"nholuongut-node-b": {assigned: 29, total: 29, max: 29, addReq: 7},
+	}}
+	target := setupCNIMetricsTarget(t, cw, endpoints)
+	ctx := context.Background()
+
+	// First poll: gauges are aggregated, counters have no previous data point yet
+	Handler(ctx, target)
+	assert.Equal(t, float64(39), cw.data["assignIPAddresses"])
+	assert.Equal(t, float64(29), cw.data["maxAssignIPAddressesPerNode"])
+	assert.Equal(t, float64(43), cw.data["totalIPAddresses"])
+	assert.Equal(t, float64(58), cw.data["maxIPAddresses"])
+	assert.Equal(t, float64(1), cw.data["nodesIPExhausted"])
+	assert.Equal(t, float64(100), cw.data["maxNodeIPUtilization"])
+	assert.NotContains(t, cw.data, "addReqCount")
+	assert.Equal(t, float64(39), testutil.ToFloat64(prometheusmetrics.AssignedIPs))
+	assert.Equal(t, float64(58), testutil.ToFloat64(prometheusmetrics.IpMax))
+	assert.Equal(t, float64(1), testutil.ToFloat64(nodesIPExhausted))
+
+	// Second poll: counters are published as the delta since the first poll
+	endpoints.set("nholuongut-node-a", testNodeMetrics{assigned: 12, total: 14, max: 29, addReq: 8})
+	endpoints.set("nholuongut-node-b", testNodeMetrics{assigned: 20, total: 29, max: 29, addReq: 9})
+	Handler(ctx, target)
+	assert.Equal(t, float64(32), cw.data["assignIPAddresses"])
+	assert.Equal(t, float64(20), cw.data["maxAssignIPAddressesPerNode"])
+	assert.Equal(t, float64(5), cw.data["addReqCount"])
+	assert.Equal(t, float64(0), cw.data["nodesIPExhausted"])
+	assert.InDelta(t, float64(20)/29*100, cw.data["maxNodeIPUtilization"], 0.001)
+	assert.Equal(t, float64(0), testutil.ToFloat64(nodesIPExhausted))
+
+	// Third poll: a restarted nholuongut-node pod resets its counter, so no delta is published
+	delete(cw.data, "addReqCount")
+	endpoints.set("nholuongut-node-b", testNodeMetrics{assigned: 0, total: 0, max: 29, addReq: 1})
+	Handler(ctx, target)
+	assert.Equal(t, float64(12), cw.data["assignIPAddresses"])
+	assert.NotContains(t, cw.data, "addReqCount")
+}
+
+func TestCNIMetricsHandlerUnreachablePod(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cw := newTestPublisher(ctrl)
+	endpoints := &testCNIEndpoints{nodes: map[string]testNodeMetrics{
+		"nholuongut-node-a": {assigned: 3, total: 14, max: 29, addReq: 3},
+	}}
+	target := setupCNIMetricsTarget(t, cw, endpoints)
+
+	Handler(context.Background(), target)
+	assert.Equal(t, float64(3), cw.data["assignIPAddresses"])
+	assert.Equal(t, float64(29), cw.data["maxIPAddresses"])
+	assert.Equal(t, float64(0), cw.data["nodesIPExhausted"])
+}
+
+func getMetricsLog() logger.Logger {
+	logConfig := logger.Configuration{
+		LogLevel:    "Debug",
+		LogLocation: "stdout",
+	}
+	return logger.New(&logConfig)
+}
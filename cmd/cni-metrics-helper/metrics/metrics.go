@@ -0,0 +1,319 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package metrics handles the scraping, aggregation and publishing of the metrics exposed by ipamd
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/nholuongut/nholuongut-sdk-go/nholuongut"
+	"github.com/nholuongut/nholuongut-sdk-go/service/cloudwatch"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/publisher"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/logger"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/utils/prometheusmetrics"
+)
+
+type metricMatcher func(metric *dto.Metric) bool
+type actionFuncType func(aggregatedValue *float64, sampleValue float64)
+
+// metricsTarget is a set of endpoints whose metrics are grabbed and aggregated together
+type metricsTarget interface {
+	grabMetricsFromTarget(ctx context.Context, target string) ([]byte, error)
+	getInterestingMetrics() map[string]metricsConvert
+	getCWMetricsPublisher() publisher.Publisher
+	getTargetList(ctx context.Context) ([]string, error)
+	submitCloudWatch() bool
+	submitPrometheus() bool
+	getLogger() logger.Logger
+}
+
+// targetSummarizer is implemented by metrics targets which also derive values from every single
+// endpoint, such as the number of nodes which ran out of IP addresses
+type targetSummarizer interface {
+	resetSummary()
+	summarizeTarget(families map[string]*dto.MetricFamily)
+	produceSummary()
+}
+
+type metricsConvert struct {
+	actions []metricsAction
+}
+
+type metricsAction struct {
+	cwMetricName string
+	matchFunc    metricMatcher
+	actionFunc   actionFuncType
+	data         *dataPoints
+}
+
+type dataPoints struct {
+	lastSingleDataPoint float64
+	curSingleDataPoint  float64
+}
+
+// newMetricsConvertState copies the parsing definitions with zeroed data points, so that every target
+// keeps its own state between polls
+func newMetricsConvertState(definitions map[string]metricsConvert) map[string]metricsConvert {
+	result := make(map[string]metricsConvert, len(definitions))
+	for name, convert := range definitions {
+		actions := make([]metricsAction, 0, len(convert.actions))
+		for _, act := range convert.actions {
+			act.data = &dataPoints{}
+			actions = append(actions, act)
+		}
+		result[name] = metricsConvert{actions: actions}
+	}
+	return result
+}
+
+func matchAny(metric *dto.Metric) bool {
+	return true
+}
+
+func metricsAdd(aggregatedValue *float64, sampleValue float64) {
+	*aggregatedValue += sampleValue
+}
+
+func metricsMax(aggregatedValue *float64, sampleValue float64) {
+	if *aggregatedValue < sampleValue {
+		*aggregatedValue = sampleValue
+	}
+}
+
+// getMetricsFromPod reads the metrics endpoint of a pod through the API server proxy
+func getMetricsFromPod(ctx context.Context, clientSet kubernetes.Interface, podName string, namespace string, port int) ([]byte, error) {
+	rawOutput, err := clientSet.CoreV1().RESTClient().Get().
+		Namespace(namespace).
+		Resource("pods").
+		SubResource("proxy").
+		Name(fmt.Sprintf("%v:%v", podName, port)).
+		Suffix("metrics").
+		Do(ctx).Raw()
+	if err != nil {
+		return nil, err
+	}
+	return rawOutput, nil
+}
+
+func processGauge(metric *dto.Metric, act *metricsAction) {
+	act.actionFunc(&act.data.curSingleDataPoint, metric.GetGauge().GetValue())
+}
+
+func processCounter(metric *dto.Metric, act *metricsAction) {
+	act.actionFunc(&act.data.curSingleDataPoint, metric.GetCounter().GetValue())
+}
+
+// postProcessingCounter turns the aggregated counter totals into the delta since the previous poll.
+// It returns true when the delta cannot be trusted, either because there is no previous poll or
+// because a counter went backwards after a target restarted or went away.
+func postProcessingCounter(convert metricsConvert, log logger.Logger) bool {
+	resetDetected := false
+	noPreviousDataPoint := true
+	noCurrentDataPoint := true
+	for _, action := range convert.actions {
+		currentTotal := action.data.curSingleDataPoint
+		// Only do delta if metric target did NOT restart
+		if action.data.curSingleDataPoint < action.data.lastSingleDataPoint {
+			resetDetected = true
+		} else {
+			action.data.curSingleDataPoint -= action.data.lastSingleDataPoint
+		}
+
+		if action.data.lastSingleDataPoint != 0 {
+			noPreviousDataPoint = false
+		}
+		if action.data.curSingleDataPoint != 0 {
+			noCurrentDataPoint = false
+		}
+		action.data.lastSingleDataPoint = currentTotal
+	}
+
+	if resetDetected || (noPreviousDataPoint && !noCurrentDataPoint) {
+		log.Debugf("Reset detected resetDetected: %v, noPreviousDataPoint: %v, noCurrentDataPoint: %v",
+			resetDetected, noPreviousDataPoint, noCurrentDataPoint)
+		return true
+	}
+	return false
+}
+
+func processMetric(family *dto.MetricFamily, convert metricsConvert) {
+	mType := family.GetType()
+	for _, metric := range family.GetMetric() {
+		for i := range convert.actions {
+			act := &convert.actions[i]
+			if !act.matchFunc(metric) {
+				continue
+			}
+			switch mType {
+			case dto.MetricType_GAUGE:
+				processGauge(metric, act)
+			case dto.MetricType_COUNTER:
+				processCounter(metric, act)
+			}
+		}
+	}
+}
+
+func filterMetrics(originalMetrics map[string]*dto.MetricFamily,
+	interestingMetrics map[string]metricsConvert) map[string]*dto.MetricFamily {
+	result := map[string]*dto.MetricFamily{}
+	for metric := range interestingMetrics {
+		if family, found := originalMetrics[metric]; found {
+			result[metric] = family
+		}
+	}
+	return result
+}
+
+func produceCloudWatchMetrics(families map[string]*dto.MetricFamily, convertDef map[string]metricsConvert,
+	resetDetected bool, cw publisher.Publisher) {
+	for key, family := range families {
+		convertMetrics := convertDef[key]
+		for _, action := range convertMetrics.actions {
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				// Counters are published as the delta since the last poll, which is meaningless after a reset
+				if resetDetected {
+					continue
+				}
+				cw.Publish(&cloudwatch.MetricDatum{
+					MetricName: nholuongut.String(action.cwMetricName),
+					Unit:       nholuongut.String(cloudwatch.StandardUnitCount),
+					Value:      nholuongut.Float64(action.data.curSingleDataPoint),
+				})
+			case dto.MetricType_GAUGE:
+				cw.Publish(&cloudwatch.MetricDatum{
+					MetricName: nholuongut.String(action.cwMetricName),
+					Unit:       nholuongut.String(cloudwatch.StandardUnitCount),
+					Value:      nholuongut.Float64(action.data.curSingleDataPoint),
+				})
+			}
+		}
+	}
+}
+
+// producePrometheusMetrics re-exposes the aggregated values. Only gauges are supported for now.
+func producePrometheusMetrics(families map[string]*dto.MetricFamily, convertDef map[string]metricsConvert, log logger.Logger) {
+	prometheusCNIMetrics := prometheusmetrics.GetSupportedPrometheusCNIMetricsMapping()
+	if len(prometheusCNIMetrics) == 0 {
+		log.Infof("Skipping since prometheus mapping is missing")
+		return
+	}
+	for key, family := range families {
+		if family.GetType() != dto.MetricType_GAUGE {
+			continue
+		}
+		collector, ok := prometheusCNIMetrics[family.GetName()]
+		if !ok {
+			continue
+		}
+		gauge, ok := collector.(prometheus.Gauge)
+		if !ok {
+			continue
+		}
+		// The first action holds the cluster-wide value, the others are derived views of it
+		if actions := convertDef[key].actions; len(actions) > 0 {
+			gauge.Set(actions[0].data.curSingleDataPoint)
+		}
+	}
+}
+
+func resetMetrics(interestingMetrics map[string]metricsConvert) {
+	for _, convert := range interestingMetrics {
+		for _, act := range convert.actions {
+			if act.data != nil {
+				act.data.curSingleDataPoint = 0
+			}
+		}
+	}
+}
+
+func metricsListGrabAggregateConvert(ctx context.Context, t metricsTarget) (map[string]*dto.MetricFamily, map[string]metricsConvert, bool, error) {
+	log := t.getLogger()
+	interestingMetrics := t.getInterestingMetrics()
+	resetMetrics(interestingMetrics)
+	summarizer, summarize := t.(targetSummarizer)
+	if summarize {
+		summarizer.resetSummary()
+	}
+
+	targetList, err := t.getTargetList(ctx)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	log.Debugf("Total TargetList pod count: %v", len(targetList))
+
+	families := map[string]*dto.MetricFamily{}
+	for _, target := range targetList {
+		log.Debugf("Grab/Aggregate metrics from %v", target)
+		rawOutput, err := t.grabMetricsFromTarget(ctx, target)
+		if err != nil {
+			// it may take some time to remove terminated metric targets
+			continue
+		}
+
+		parser := &expfmt.TextParser{}
+		origFamilies, err := parser.TextToMetricFamilies(bytes.NewReader(rawOutput))
+		if err != nil {
+			log.Warnf("Failed to parse metrics from %s: %v", target, err)
+			continue
+		}
+
+		targetFamilies := filterMetrics(origFamilies, interestingMetrics)
+		for name, family := range targetFamilies {
+			processMetric(family, interestingMetrics[name])
+			families[name] = family
+		}
+		if summarize {
+			summarizer.summarizeTarget(targetFamilies)
+		}
+	}
+
+	resetDetected := false
+	for name, family := range families {
+		if family.GetType() == dto.MetricType_COUNTER && postProcessingCounter(interestingMetrics[name], log) {
+			resetDetected = true
+		}
+	}
+	return families, interestingMetrics, resetDetected, nil
+}
+
+// Handler grabs metrics from the target, aggregates them and publishes them to CloudWatch and/or Prometheus
+func Handler(ctx context.Context, t metricsTarget) {
+	families, interestingMetrics, resetDetected, err := metricsListGrabAggregateConvert(ctx, t)
+	if err != nil {
+		t.getLogger().Errorf("Failed to grab metrics: %v", err)
+		return
+	}
+	if resetDetected {
+		t.getLogger().Infof("Skipping counters in the 1st poll after reset")
+	}
+
+	if t.submitCloudWatch() {
+		produceCloudWatchMetrics(families, interestingMetrics, resetDetected, t.getCWMetricsPublisher())
+	}
+	if t.submitPrometheus() {
+		producePrometheusMetrics(families, interestingMetrics, t.getLogger())
+	}
+	if summarizer, ok := t.(targetSummarizer); ok {
+		summarizer.produceSummary()
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metrics
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/logger"
+)
+
+const cniPodLabelValue = "nholuongut-node"
+
+// PodWatcher lists the nholuongut-node pods to scrape
+type PodWatcher interface {
+	GetCNIPods(ctx context.Context) ([]string, error)
+}
+
+type defaultPodWatcher struct {
+	k8sClient client.Client
+	log       logger.Logger
+}
+
+// NewDefaultPodWatcher creates a new PodWatcher
+func NewDefaultPodWatcher(k8sClient client.Client, log logger.Logger) PodWatcher {
+	return &defaultPodWatcher{
+		k8sClient: k8sClient,
+		log:       log,
+	}
+}
+
+// GetCNIPods returns the names of the running nholuongut-node pods
+func (d *defaultPodWatcher) GetCNIPods(ctx context.Context) ([]string, error) {
+	var podList corev1.PodList
+	err := d.k8sClient.List(ctx, &podList,
+		client.InNamespace(metav1.NamespaceSystem),
+		client.MatchingLabels{"k8s-app": cniPodLabelValue})
+	if err != nil {
+		d.log.Errorf("Failed to list CNI pods: %v", err)
+		return nil, err
+	}
+
+	var cniPods []string
+	for _, pod := range podList.Items {
+		// Pods which are not running yet or anymore have no metrics endpoint to scrape
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		cniPods = append(cniPods, pod.Name)
+	}
+	d.log.Debugf("Found %d running CNI pods out of %d", len(cniPods), len(podList.Items))
+	return cniPods, nil
+}
@@ -0,0 +1,346 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diagnostics
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/networkutils"
+)
+
+// Severity tells how likely a finding is to break pod networking
+type Severity string
+
+const (
+	// SeverityError is a finding which breaks the traffic of pods
+	SeverityError Severity = "error"
+	// SeverityWarning is an inconsistency which may be transient, such as a pod being set up
+	SeverityWarning Severity = "warning"
+)
+
+// Names of the checks
+const (
+	CheckPodWithoutRules      = "pod-without-rules"
+	CheckRuleWithoutPod       = "rule-without-pod"
+	CheckENIWithoutRouteTable = "eni-without-route-table"
+	CheckENIWithoutLink       = "eni-without-link"
+	CheckIPNotOnENI           = "ip-not-on-eni"
+	CheckCheckpointOnly       = "allocation-not-in-datastore"
+	CheckDataStoreOnly        = "allocation-not-in-checkpoint"
+	CheckENINotAttached       = "eni-not-attached"
+)
+
+// Finding is an inconsistency between the sources of the node state
+type Finding struct {
+	Check    string   `json:"check"`
+	Severity Severity `json:"severity"`
+	// Subject is the pod, ENI, address or rule the finding is about
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+}
+
+// podAddress is an address of a pod, as recorded in the checkpoint
+type podAddress struct {
+	pod string
+	ip  net.IP
+	// routeTable is the table of the fromContainer rule, the main table when the pod has none
+	routeTable int
+}
+
+// Check cross-checks the sources of the node state, and returns the findings sorted by check and subject
+func Check(state *NodeState) []Finding {
+	var findings []Finding
+	pods := podAddresses(state.Checkpoint)
+	if state.Checkpoint != nil && state.Rules != nil {
+		findings = append(findings, checkPodRules(state.Rules, pods)...)
+	}
+	if state.DataStore != nil && state.Routes != nil {
+		findings = append(findings, checkENIRouteTables(state.DataStore, state.Routes)...)
+	}
+	if state.AttachedENIs != nil && state.Links != nil {
+		findings = append(findings, checkENILinks(state)...)
+	}
+	if state.DataStore != nil && state.AttachedENIs != nil {
+		findings = append(findings, checkENIAddresses(state.DataStore, state.AttachedENIs)...)
+	}
+	if state.Checkpoint != nil && state.DataStore != nil {
+		findings = append(findings, checkAllocations(state.Checkpoint, state.DataStore)...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Check != findings[j].Check {
+			return findings[i].Check < findings[j].Check
+		}
+		return findings[i].Subject < findings[j].Subject
+	})
+	return findings
+}
+
+// podAddresses returns the addresses of the pods of the checkpoint which are routed by ip rules. Pods using a branch
+// ENI are routed by their VLAN, and the addresses held for deleted sticky IP pods are not in use.
+func podAddresses(checkpoint *datastore.CheckpointData) []podAddress {
+	if checkpoint == nil {
+		return nil
+	}
+	var pods []podAddress
+	for _, entry := range checkpoint.Allocations {
+		if entry.VlanID != 0 || entry.ReleaseTimestamp != 0 {
+			continue
+		}
+		pod := fmt.Sprintf("%s/%s (%s)", entry.Metadata.K8SPodNamespace, entry.Metadata.K8SPodName, entry.IPAMKey)
+		if ip := net.ParseIP(entry.IPv4); ip != nil {
+			routeTable := unix.RT_TABLE_MAIN
+			if entry.DeviceNumber > 0 {
+				routeTable = entry.DeviceNumber + 1
+			}
+			pods = append(pods, podAddress{pod: pod, ip: ip, routeTable: routeTable})
+		}
+		// IPv6 addresses are always allocated from the prefix of the primary ENI
+		if ip := net.ParseIP(entry.IPv6); ip != nil {
+			pods = append(pods, podAddress{pod: pod, ip: ip, routeTable: unix.RT_TABLE_MAIN})
+		}
+	}
+	return pods
+}
+
+// checkPodRules finds the pods without their toContainer and fromContainer rules, and the rules of addresses
+// without a pod. Pods of the eBPF datapath are routed without rules per pod, so only stale rules are reported then.
+func checkPodRules(rules []netlink.Rule, pods []podAddress) []Finding {
+	ebpfDatapath := false
+	toContainer := make(map[string]netlink.Rule)
+	fromContainer := make(map[string]netlink.Rule)
+	for _, rule := range rules {
+		switch {
+		case rule.Priority == networkutils.ToContainerRulePriority && rule.Table == networkutils.PodRouteTable:
+			ebpfDatapath = true
+		case rule.Priority == networkutils.ToContainerRulePriority && rule.Dst != nil:
+			toContainer[rule.Dst.IP.String()] = rule
+		case rule.Priority == networkutils.FromPodRulePriority && rule.Src != nil:
+			fromContainer[rule.Src.IP.String()] = rule
+		}
+	}
+
+	var findings []Finding
+	podIPs := make(map[string]bool)
+	for _, pod := range pods {
+		ip := pod.ip.String()
+		podIPs[ip] = true
+		if ebpfDatapath {
+			continue
+		}
+		if _, ok := toContainer[ip]; !ok {
+			findings = append(findings, Finding{
+				Check:    CheckPodWithoutRules,
+				Severity: SeverityError,
+				Subject:  ip,
+				Message: fmt.Sprintf("pod %s has no rule %d: to %s lookup main", pod.pod,
+					networkutils.ToContainerRulePriority, hostCIDR(pod.ip)),
+			})
+		}
+		if pod.routeTable == unix.RT_TABLE_MAIN {
+			continue
+		}
+		if rule, ok := fromContainer[ip]; !ok {
+			findings = append(findings, Finding{
+				Check:    CheckPodWithoutRules,
+				Severity: SeverityError,
+				Subject:  ip,
+				Message: fmt.Sprintf("pod %s has no rule %d: from %s lookup %d", pod.pod,
+					networkutils.FromPodRulePriority, hostCIDR(pod.ip), pod.routeTable),
+			})
+		} else if rule.Table != pod.routeTable {
+			findings = append(findings, Finding{
+				Check:    CheckPodWithoutRules,
+				Severity: SeverityError,
+				Subject:  ip,
+				Message: fmt.Sprintf("pod %s has rule %s instead of lookup %d", pod.pod, describeRule(rule),
+					pod.routeTable),
+			})
+		}
+	}
+
+	for _, byIP := range []map[string]netlink.Rule{toContainer, fromContainer} {
+		for ip, rule := range byIP {
+			if podIPs[ip] {
+				continue
+			}
+			findings = append(findings, Finding{
+				Check:    CheckRuleWithoutPod,
+				Severity: SeverityWarning,
+				Subject:  ip,
+				Message:  fmt.Sprintf("rule %s belongs to no pod of the checkpoint", describeRule(rule)),
+			})
+		}
+	}
+	return findings
+}
+
+// checkENIRouteTables finds the secondary ENIs of the datastore whose route table has no default route
+func checkENIRouteTables(eniInfos *datastore.ENIInfos, routes map[int][]netlink.Route) []Finding {
+	var findings []Finding
+	for _, eni := range eniInfos.ENIs {
+		if eni.IsPrimary || eni.DeviceNumber == 0 {
+			continue
+		}
+		table := eni.DeviceNumber + 1
+		hasDefaultRoute := false
+		for _, route := range routes[table] {
+			if isDefaultRoute(route) {
+				hasDefaultRoute = true
+				break
+			}
+		}
+		if hasDefaultRoute {
+			continue
+		}
+		message := fmt.Sprintf("route table %d of device number %d has no default route", table, eni.DeviceNumber)
+		if len(routes[table]) == 0 {
+			message = fmt.Sprintf("route table %d of device number %d is missing", table, eni.DeviceNumber)
+		}
+		findings = append(findings, Finding{
+			Check:    CheckENIWithoutRouteTable,
+			Severity: SeverityError,
+			Subject:  eni.ID,
+			Message:  message,
+		})
+	}
+	return findings
+}
+
+// checkENILinks finds the ENIs attached to the instance without a network interface on the host
+func checkENILinks(state *NodeState) []Finding {
+	var findings []Finding
+	for _, eni := range state.AttachedENIs {
+		if state.linkByMAC(eni.MAC) != nil {
+			continue
+		}
+		findings = append(findings, Finding{
+			Check:    CheckENIWithoutLink,
+			Severity: SeverityError,
+			Subject:  eni.ENIID,
+			Message:  fmt.Sprintf("no network interface has MAC address %s of device number %d", eni.MAC, eni.DeviceNumber),
+		})
+	}
+	return findings
+}
+
+// checkENIAddresses finds the addresses and prefixes of the datastore which are not assigned to their ENI, and the
+// ENIs of the datastore which are not attached
+func checkENIAddresses(eniInfos *datastore.ENIInfos, attachedENIs []AttachedENI) []Finding {
+	attached := make(map[string]map[string]bool)
+	for _, eni := range attachedENIs {
+		attached[eni.ENIID] = make(map[string]bool)
+		for _, cidr := range eni.CIDRs {
+			attached[eni.ENIID][cidr] = true
+		}
+	}
+
+	var findings []Finding
+	for _, eni := range eniInfos.ENIs {
+		cidrs, ok := attached[eni.ID]
+		if !ok {
+			findings = append(findings, Finding{
+				Check:    CheckENINotAttached,
+				Severity: SeverityError,
+				Subject:  eni.ID,
+				Message:  "ENI of the datastore is not attached to the instance",
+			})
+			continue
+		}
+		for _, cidrInfos := range []map[string]*datastore.CidrInfo{eni.AvailableIPv4Cidrs, eni.IPv6Cidrs} {
+			for _, cidrInfo := range cidrInfos {
+				cidr := cidrInfo.Cidr.String()
+				if cidrs[cidr] {
+					continue
+				}
+				findings = append(findings, Finding{
+					Check:    CheckIPNotOnENI,
+					Severity: SeverityError,
+					Subject:  cidr,
+					Message:  fmt.Sprintf("%s is in the datastore but not assigned to ENI %s", cidr, eni.ID),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// checkAllocations finds the pod addresses which are in the checkpoint but not assigned in the datastore, and the
+// other way around. Both are written by ipamd, so they only differ while a pod is added or deleted.
+func checkAllocations(checkpoint *datastore.CheckpointData, eniInfos *datastore.ENIInfos) []Finding {
+	inCheckpoint := make(map[string]datastore.CheckpointEntry)
+	for _, entry := range checkpoint.Allocations {
+		if entry.VlanID != 0 || entry.ReleaseTimestamp != 0 {
+			continue
+		}
+		for _, ip := range []string{entry.IPv4, entry.IPv6} {
+			if ip != "" {
+				inCheckpoint[ip] = entry
+			}
+		}
+	}
+	inDataStore := make(map[string]*datastore.AddressInfo)
+	for _, eni := range eniInfos.ENIs {
+		for _, cidrInfos := range []map[string]*datastore.CidrInfo{eni.AvailableIPv4Cidrs, eni.IPv6Cidrs} {
+			for _, cidrInfo := range cidrInfos {
+				for _, addr := range cidrInfo.IPAddresses {
+					if addr.Assigned() {
+						inDataStore[addr.Address] = addr
+					}
+				}
+			}
+		}
+	}
+
+	var findings []Finding
+	for ip, entry := range inCheckpoint {
+		if _, ok := inDataStore[ip]; ok {
+			continue
+		}
+		findings = append(findings, Finding{
+			Check:    CheckCheckpointOnly,
+			Severity: SeverityWarning,
+			Subject:  ip,
+			Message: fmt.Sprintf("address of pod %s/%s is in the checkpoint but not assigned in the datastore",
+				entry.Metadata.K8SPodNamespace, entry.Metadata.K8SPodName),
+		})
+	}
+	for ip, addr := range inDataStore {
+		if _, ok := inCheckpoint[ip]; ok {
+			continue
+		}
+		findings = append(findings, Finding{
+			Check:    CheckDataStoreOnly,
+			Severity: SeverityWarning,
+			Subject:  ip,
+			Message: fmt.Sprintf("address of pod %s/%s is assigned in the datastore but not in the checkpoint",
+				addr.IPAMMetadata.K8SPodNamespace, addr.IPAMMetadata.K8SPodName),
+		})
+	}
+	return findings
+}
+
+// isDefaultRoute returns true if the route matches all addresses, default routes are listed without a destination
+func isDefaultRoute(route netlink.Route) bool {
+	if route.Dst == nil {
+		return true
+	}
+	ones, _ := route.Dst.Mask.Size()
+	return ones == 0
+}
@@ -0,0 +1,216 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diagnostics
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/networkutils"
+)
+
+func cidr(s string) *net.IPNet {
+	ip, ipNet, _ := net.ParseCIDR(s)
+	ipNet.IP = ip
+	return ipNet
+}
+
+func checkpointEntry(containerID, ipv4 string, deviceNumber int) datastore.CheckpointEntry {
+	return datastore.CheckpointEntry{
+		IPAMKey:      datastore.IPAMKey{NetworkName: "nholuongut-cni", ContainerID: containerID, IfName: "eth0"},
+		IPv4:         ipv4,
+		DeviceNumber: deviceNumber,
+		Metadata:     datastore.IPAMMetadata{K8SPodNamespace: "default", K8SPodName: containerID},
+	}
+}
+
+func dataStoreENI(id string, deviceNumber int, addresses map[string]string) datastore.ENI {
+	eni := datastore.ENI{
+		ID:                 id,
+		IsPrimary:          deviceNumber == 0,
+		DeviceNumber:       deviceNumber,
+		AvailableIPv4Cidrs: make(map[string]*datastore.CidrInfo),
+	}
+	for address, containerID := range addresses {
+		addr := &datastore.AddressInfo{Address: address}
+		if containerID != "" {
+			addr.IPAMKey = datastore.IPAMKey{NetworkName: "nholuongut-cni", ContainerID: containerID, IfName: "eth0"}
+			addr.IPAMMetadata = datastore.IPAMMetadata{K8SPodNamespace: "default", K8SPodName: containerID}
+		}
+		eni.AvailableIPv4Cidrs[address+"/32"] = &datastore.CidrInfo{
+			Cidr:        *cidr(address + "/32"),
+			IPAddresses: map[string]*datastore.AddressInfo{address: addr},
+		}
+	}
+	return eni
+}
+
+// healthyState is a node with a pod on the primary ENI and a pod on the secondary ENI
+func healthyState() *NodeState {
+	return &NodeState{
+		Checkpoint: &datastore.CheckpointData{
+			Version: datastore.CheckpointFormatVersion,
+			Allocations: []datastore.CheckpointEntry{
+				checkpointEntry("pod-a", "10.0.0.10", 0),
+				checkpointEntry("pod-b", "10.0.1.10", 1),
+			},
+		},
+		DataStore: &datastore.ENIInfos{ENIs: map[string]datastore.ENI{
+			"eni-0": dataStoreENI("eni-0", 0, map[string]string{"10.0.0.10": "pod-a"}),
+			"eni-1": dataStoreENI("eni-1", 1, map[string]string{"10.0.1.10": "pod-b", "10.0.1.11": ""}),
+		}},
+		AttachedENIs: []AttachedENI{
+			{ENIID: "eni-0", MAC: "02:00:00:00:00:00", CIDRs: []string{"10.0.0.5/32", "10.0.0.10/32"}},
+			{ENIID: "eni-1", MAC: "02:00:00:00:00:01", DeviceNumber: 1, CIDRs: []string{"10.0.1.5/32", "10.0.1.10/32", "10.0.1.11/32"}},
+		},
+		Links: []Link{
+			{Name: "eth0", Index: 2, MAC: "02:00:00:00:00:00"},
+			{Name: "eth1", Index: 3, MAC: "02:00:00:00:00:01"},
+		},
+		Rules: []netlink.Rule{
+			{Priority: networkutils.ToContainerRulePriority, Dst: cidr("10.0.0.10/32"), Table: unix.RT_TABLE_MAIN},
+			{Priority: networkutils.ToContainerRulePriority, Dst: cidr("10.0.1.10/32"), Table: unix.RT_TABLE_MAIN},
+			{Priority: networkutils.FromPodRulePriority, Src: cidr("10.0.1.10/32"), Table: 2},
+		},
+		Routes: map[int][]netlink.Route{
+			2: {
+				{LinkIndex: 3, Dst: cidr("10.0.1.1/32"), Table: 2},
+				{LinkIndex: 3, Gw: net.ParseIP("10.0.1.1"), Table: 2},
+			},
+		},
+		Errors: map[string]string{},
+	}
+}
+
+func TestCheckHealthyNode(t *testing.T) {
+	assert.Empty(t, Check(healthyState()))
+}
+
+func TestCheckPodRules(t *testing.T) {
+	state := healthyState()
+	// The fromContainer rule of pod-b is gone, and a pod left a rule behind
+	state.Rules = []netlink.Rule{
+		state.Rules[0],
+		state.Rules[1],
+		{Priority: networkutils.ToContainerRulePriority, Dst: cidr("10.0.1.99/32"), Table: unix.RT_TABLE_MAIN},
+	}
+	assert.Equal(t, []Finding{
+		{
+			Check:    CheckPodWithoutRules,
+			Severity: SeverityError,
+			Subject:  "10.0.1.10",
+			Message:  "pod default/pod-b (nholuongut-cni/pod-b/eth0) has no rule 1536: from 10.0.1.10/32 lookup 2",
+		},
+		{
+			Check:    CheckRuleWithoutPod,
+			Severity: SeverityWarning,
+			Subject:  "10.0.1.99",
+			Message:  "rule 512: to 10.0.1.99/32 lookup 254 belongs to no pod of the checkpoint",
+		},
+	}, Check(state))
+
+	// Pods of the eBPF datapath have no rules
+	state.Rules = []netlink.Rule{{Priority: networkutils.ToContainerRulePriority, Table: networkutils.PodRouteTable}}
+	assert.Empty(t, Check(state))
+}
+
+func TestCheckENIs(t *testing.T) {
+	state := healthyState()
+	// The route table of eni-1 was flushed, and the unassigned 10.0.1.11 is no longer on the ENI
+	state.Routes = map[int][]netlink.Route{}
+	state.AttachedENIs[1].CIDRs = []string{"10.0.1.5/32", "10.0.1.10/32"}
+	state.Links = state.Links[:1]
+	assert.Equal(t, []Finding{
+		{
+			Check:    CheckENIWithoutLink,
+			Severity: SeverityError,
+			Subject:  "eni-1",
+			Message:  "no network interface has MAC address 02:00:00:00:00:01 of device number 1",
+		},
+		{
+			Check:    CheckENIWithoutRouteTable,
+			Severity: SeverityError,
+			Subject:  "eni-1",
+			Message:  "route table 2 of device number 1 is missing",
+		},
+		{
+			Check:    CheckIPNotOnENI,
+			Severity: SeverityError,
+			Subject:  "10.0.1.11/32",
+			Message:  "10.0.1.11/32 is in the datastore but not assigned to ENI eni-1",
+		},
+	}, Check(state))
+
+	state = healthyState()
+	state.AttachedENIs = state.AttachedENIs[:1]
+	findings := Check(state)
+	assert.Len(t, findings, 1)
+	assert.Equal(t, CheckENINotAttached, findings[0].Check)
+}
+
+func TestCheckAllocations(t *testing.T) {
+	state := healthyState()
+	state.Checkpoint.Allocations = state.Checkpoint.Allocations[:1]
+	state.Rules = state.Rules[:1]
+	assert.Equal(t, []Finding{{
+		Check:    CheckDataStoreOnly,
+		Severity: SeverityWarning,
+		Subject:  "10.0.1.10",
+		Message:  "address of pod default/pod-b is assigned in the datastore but not in the checkpoint",
+	}}, Check(state))
+}
+
+func TestCheckSkipsSourcesWhichCouldNotBeRead(t *testing.T) {
+	state := healthyState()
+	state.DataStore = nil
+	state.Rules, state.Routes, state.Links = nil, nil, nil
+	state.Errors[SourceIntrospection] = "connection refused"
+	state.Errors[SourceNetlink] = "operation not permitted"
+	assert.Empty(t, Check(state))
+}
+
+func TestReport(t *testing.T) {
+	state := healthyState()
+	state.Rules = state.Rules[:2]
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	report := NewReport(state, now, false)
+	assert.Equal(t, Summary{
+		CheckpointAllocations: 2,
+		DataStoreENIs:         2,
+		AttachedENIs:          2,
+		Rules:                 2,
+		RouteTables:           1,
+		Errors:                1,
+	}, report.Summary)
+
+	var buf bytes.Buffer
+	assert.NoError(t, report.WriteJSON(&buf))
+	var decoded Report
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, report.Findings, decoded.Findings)
+	assert.Nil(t, decoded.State)
+
+	buf.Reset()
+	assert.NoError(t, report.WriteText(&buf))
+	assert.Contains(t, buf.String(), "1 errors, 0 warnings")
+	assert.Contains(t, buf.String(), "has no rule 1536: from 10.0.1.10/32 lookup 2")
+}
@@ -0,0 +1,119 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Report is the outcome of the checks, printed as text or JSON
+type Report struct {
+	Time time.Time `json:"time"`
+	// Summary counts the objects of each source
+	Summary Summary `json:"summary"`
+	// Errors maps the sources which could not be read to the error, their checks are skipped
+	Errors   map[string]string `json:"errors,omitempty"`
+	Findings []Finding         `json:"findings"`
+	// State is the node state the checks ran against, only included on request
+	State *NodeState `json:"state,omitempty"`
+}
+
+// Summary counts the objects of each source of the node state
+type Summary struct {
+	CheckpointAllocations int `json:"checkpointAllocations"`
+	DataStoreENIs         int `json:"dataStoreENIs"`
+	AttachedENIs          int `json:"attachedENIs"`
+	Rules                 int `json:"rules"`
+	RouteTables           int `json:"routeTables"`
+	Errors                int `json:"errors"`
+	Warnings              int `json:"warnings"`
+}
+
+// NewReport runs the checks against the node state. The state is only included in the report if includeState is set.
+func NewReport(state *NodeState, now time.Time, includeState bool) *Report {
+	report := &Report{
+		Time:     now,
+		Errors:   state.Errors,
+		Findings: Check(state),
+	}
+	if report.Findings == nil {
+		report.Findings = []Finding{}
+	}
+	if state.Checkpoint != nil {
+		report.Summary.CheckpointAllocations = len(state.Checkpoint.Allocations)
+	}
+	if state.DataStore != nil {
+		report.Summary.DataStoreENIs = len(state.DataStore.ENIs)
+	}
+	report.Summary.AttachedENIs = len(state.AttachedENIs)
+	report.Summary.Rules = len(state.Rules)
+	report.Summary.RouteTables = len(state.Routes)
+	for _, finding := range report.Findings {
+		if finding.Severity == SeverityError {
+			report.Summary.Errors++
+		} else {
+			report.Summary.Warnings++
+		}
+	}
+	if includeState {
+		report.State = state
+	}
+	return report
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteText writes the report for humans
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Node network diagnostics at %s\n\n", r.Time.Format(time.RFC3339))
+	fmt.Fprintf(tw, "Checkpoint allocations:\t%d\n", r.Summary.CheckpointAllocations)
+	fmt.Fprintf(tw, "Datastore ENIs:\t%d\n", r.Summary.DataStoreENIs)
+	fmt.Fprintf(tw, "Attached ENIs:\t%d\n", r.Summary.AttachedENIs)
+	fmt.Fprintf(tw, "IP rules:\t%d\n", r.Summary.Rules)
+	fmt.Fprintf(tw, "Route tables:\t%d\n", r.Summary.RouteTables)
+
+	if len(r.Errors) > 0 {
+		sources := make([]string, 0, len(r.Errors))
+		for source := range r.Errors {
+			sources = append(sources, source)
+		}
+		sort.Strings(sources)
+		fmt.Fprintf(tw, "\nSources which could not be read, their checks were skipped:\n")
+		for _, source := range sources {
+			fmt.Fprintf(tw, "  %s:\t%s\n", source, r.Errors[source])
+		}
+	}
+
+	if len(r.Findings) == 0 {
+		fmt.Fprintf(tw, "\nNo findings\n")
+		return tw.Flush()
+	}
+	fmt.Fprintf(tw, "\n%d errors, %d warnings\n\n", r.Summary.Errors, r.Summary.Warnings)
+	fmt.Fprintf(tw, "SEVERITY\tCHECK\tSUBJECT\tMESSAGE\n")
+	for _, finding := range r.Findings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", finding.Severity, finding.Check, finding.Subject, finding.Message)
+	}
+	return tw.Flush()
+}
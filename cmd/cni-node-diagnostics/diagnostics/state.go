@@ -0,0 +1,247 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package diagnostics collects the network state of a node from the IPAM checkpoint, the ipamd introspection
+// endpoints, the instance metadata and netlink, and cross-checks them
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/netlinkwrapper"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/nholuongututils"
+)
+
+// introspectionTimeout bounds each request to the introspection endpoints
+const introspectionTimeout = 5 * time.Second
+
+// NodeState is the network state of the node, as seen from each source. A source which could not be read is left
+// empty and its error is recorded in Errors, the checks needing it are then skipped.
+type NodeState struct {
+	// Checkpoint is the IPAM checkpoint of ipamd
+	Checkpoint *datastore.CheckpointData `json:"checkpoint,omitempty"`
+	// DataStore is the state of the ipamd datastore, served by the /v1/enis introspection endpoint
+	DataStore *datastore.ENIInfos `json:"dataStore,omitempty"`
+	// AttachedENIs are the ENIs of the instance, from the instance metadata
+	AttachedENIs []AttachedENI `json:"attachedENIs,omitempty"`
+	// Links are the network interfaces of the host
+	Links []Link `json:"links,omitempty"`
+	// Rules are the IPv4 and IPv6 ip rules of the host
+	Rules []netlink.Rule `json:"rules,omitempty"`
+	// Routes are the IPv4 and IPv6 routes of the host, by route table
+	Routes map[int][]netlink.Route `json:"routes,omitempty"`
+	// Errors maps the sources which could not be read to the error
+	Errors map[string]string `json:"-"`
+}
+
+// Sources of the node state
+const (
+	SourceCheckpoint    = "checkpoint"
+	SourceIntrospection = "introspection"
+	SourceMetadata      = "instance-metadata"
+	SourceNetlink       = "netlink"
+)
+
+// AttachedENI is an ENI attached to the instance
+type AttachedENI struct {
+	ENIID        string `json:"eniID"`
+	MAC          string `json:"mac"`
+	DeviceNumber int    `json:"deviceNumber"`
+	// CIDRs are the secondary IPv4 addresses (/32), and the IPv4 and IPv6 prefixes of the ENI
+	CIDRs []string `json:"cidrs"`
+}
+
+// Link is a network interface of the host
+type Link struct {
+	Name  string `json:"name"`
+	Index int    `json:"index"`
+	MAC   string `json:"mac"`
+}
+
+// Collector reads the node state
+type Collector struct {
+	// CheckpointPath is the path of the IPAM checkpoint
+	CheckpointPath string
+	// IntrospectionURL is the base URL of the ipamd introspection endpoints, they are not queried if it is empty
+	IntrospectionURL string
+	// IMDS reads the instance metadata, it is not queried if nil
+	IMDS *nholuongututils.TypedIMDS
+	// NetLink reads the links, rules and routes of the host
+	NetLink netlinkwrapper.NetLink
+}
+
+// Collect reads the node state from every source
+func (c *Collector) Collect(ctx context.Context) *NodeState {
+	state := &NodeState{Errors: make(map[string]string)}
+
+	checkpoint, err := datastore.ReadCheckpoint(c.CheckpointPath)
+	if err != nil {
+		state.Errors[SourceCheckpoint] = errors.Wrapf(err, "failed to read %s", c.CheckpointPath).Error()
+	} else {
+		state.Checkpoint = checkpoint
+	}
+
+	if c.IntrospectionURL != "" {
+		if state.DataStore, err = c.getENIInfos(ctx); err != nil {
+			state.Errors[SourceIntrospection] = err.Error()
+		}
+	}
+
+	if c.IMDS != nil {
+		if state.AttachedENIs, err = c.getAttachedENIs(ctx); err != nil {
+			state.Errors[SourceMetadata] = err.Error()
+		}
+	}
+
+	if err := c.readNetlink(state); err != nil {
+		state.Links, state.Rules, state.Routes = nil, nil, nil
+		state.Errors[SourceNetlink] = err.Error()
+	}
+	return state
+}
+
+// getENIInfos queries the datastore from the introspection endpoint
+func (c *Collector) getENIInfos(ctx context.Context) (*datastore.ENIInfos, error) {
+	ctx, cancel := context.WithTimeout(ctx, introspectionTimeout)
+	defer cancel()
+	url := strings.TrimSuffix(c.IntrospectionURL, "/") + "/v1/enis"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to query %s", url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to query %s: %s", url, resp.Status)
+	}
+	var eniInfos datastore.ENIInfos
+	if err := json.NewDecoder(resp.Body).Decode(&eniInfos); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode the response of %s", url)
+	}
+	return &eniInfos, nil
+}
+
+// getAttachedENIs reads the ENIs of the instance and their addresses from the instance metadata
+func (c *Collector) getAttachedENIs(ctx context.Context) ([]AttachedENI, error) {
+	macs, err := c.IMDS.GetMACs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	enis := make([]AttachedENI, 0, len(macs))
+	for _, mac := range macs {
+		eni := AttachedENI{MAC: mac}
+		if eni.ENIID, err = c.IMDS.GetInterfaceID(ctx, mac); err != nil {
+			return nil, err
+		}
+		if eni.DeviceNumber, err = c.IMDS.GetDeviceNumber(ctx, mac); err != nil {
+			return nil, err
+		}
+		ips, err := c.IMDS.GetLocalIPv4s(ctx, mac)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			eni.CIDRs = append(eni.CIDRs, ip.String()+"/32")
+		}
+		// Prefixes are optional, so a missing key is not an error
+		v4Prefixes, err := c.IMDS.GetIPv4Prefixes(ctx, mac)
+		if err != nil {
+			return nil, err
+		}
+		v6Prefixes, err := c.IMDS.GetIPv6Prefixes(ctx, mac)
+		if err != nil {
+			return nil, err
+		}
+		for _, prefix := range append(v4Prefixes, v6Prefixes...) {
+			eni.CIDRs = append(eni.CIDRs, prefix.String())
+		}
+		enis = append(enis, eni)
+	}
+	return enis, nil
+}
+
+// readNetlink reads the links, rules and routes of the host
+func (c *Collector) readNetlink(state *NodeState) error {
+	links, err := c.NetLink.LinkList()
+	if err != nil {
+		return errors.Wrap(err, "failed to list links")
+	}
+	for _, link := range links {
+		attrs := link.Attrs()
+		state.Links = append(state.Links, Link{Name: attrs.Name, Index: attrs.Index, MAC: attrs.HardwareAddr.String()})
+	}
+
+	state.Routes = make(map[int][]netlink.Route)
+	for _, family := range []int{unix.AF_INET, unix.AF_INET6} {
+		rules, err := c.NetLink.RuleList(family)
+		if err != nil {
+			return errors.Wrap(err, "failed to list ip rules")
+		}
+		state.Rules = append(state.Rules, rules...)
+
+		// Table 0 with RT_FILTER_TABLE lists the routes of every table
+		routes, err := c.NetLink.RouteListFiltered(family, &netlink.Route{Table: unix.RT_TABLE_UNSPEC}, netlink.RT_FILTER_TABLE)
+		if err != nil {
+			return errors.Wrap(err, "failed to list routes")
+		}
+		for _, route := range routes {
+			state.Routes[route.Table] = append(state.Routes[route.Table], route)
+		}
+	}
+	return nil
+}
+
+// linkByMAC returns the link with the MAC address, nil if there is none
+func (state *NodeState) linkByMAC(mac string) *Link {
+	for i := range state.Links {
+		if strings.EqualFold(state.Links[i].MAC, mac) {
+			return &state.Links[i]
+		}
+	}
+	return nil
+}
+
+// hostCIDR returns the /32 or /128 network of the address
+func hostCIDR(ip net.IP) *net.IPNet {
+	bits := 128
+	if ip.To4() != nil {
+		bits = 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+}
+
+// describeRule formats the rule as ip rule show does
+func describeRule(rule netlink.Rule) string {
+	description := fmt.Sprintf("%d:", rule.Priority)
+	if rule.Src != nil {
+		description += " from " + rule.Src.String()
+	}
+	if rule.Dst != nil {
+		description += " to " + rule.Dst.String()
+	}
+	return description + fmt.Sprintf(" lookup %d", rule.Table)
+}
@@ -0,0 +1,125 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// The node network diagnostics binary, which cross-checks the IPAM checkpoint, the ipamd datastore, the instance
+// metadata and the ip rules and routes of the node it runs on
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nholuongut/nholuongut-sdk-go/nholuongut/ec2metadata"
+	"github.com/spf13/pflag"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/cmd/cni-node-diagnostics/diagnostics"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/netlinkwrapper"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/nholuongututils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/nholuongututils/nholuongutsession"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/logger"
+)
+
+const (
+	// Environment variable and default of the path of the IPAM checkpoint, the same as ipamd
+	envBackingStorePath     = "nholuongut_VPC_K8S_CNI_BACKING_STORE"
+	defaultBackingStorePath = "/var/run/nholuongut-node/ipam.json"
+
+	defaultIntrospectionURL = "http://127.0.0.1:61679"
+
+	outputText = "text"
+	outputJSON = "json"
+
+	// exitFindings is the exit code when a finding of error severity is reported
+	exitFindings = 2
+)
+
+type options struct {
+	checkpointPath   string
+	introspectionURL string
+	skipMetadata     bool
+	output           string
+	includeState     bool
+	help             bool
+}
+
+func main() {
+	os.Exit(_main())
+}
+
+func _main() int {
+	// The report goes to stdout, so log to stderr
+	logConfig := logger.Configuration{
+		LogLevel:    logger.GetLogLevel(),
+		LogLocation: "stderr",
+	}
+	log := logger.New(&logConfig)
+
+	options := &options{}
+	checkpointPath := defaultBackingStorePath
+	if value := os.Getenv(envBackingStorePath); value != "" {
+		checkpointPath = value
+	}
+	flags := pflag.NewFlagSet("", pflag.ExitOnError)
+	flags.StringVar(&options.checkpointPath, "checkpoint", checkpointPath, "Path of the IPAM checkpoint")
+	flags.StringVar(&options.introspectionURL, "introspection-url", defaultIntrospectionURL,
+		"Base URL of the ipamd introspection endpoints, empty to skip them")
+	flags.BoolVar(&options.skipMetadata, "skip-metadata", false, "Do not read the ENIs from the instance metadata")
+	flags.StringVarP(&options.output, "output", "o", outputText, "Format of the report, text or json")
+	flags.BoolVar(&options.includeState, "include-state", false, "Include the collected node state in the json report")
+	flags.BoolVar(&options.help, "help", false, "Display this help message")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(os.Args); err != nil {
+		log.Errorf("Error on parsing parameters: %s", err)
+		return 1
+	}
+	if options.help {
+		flags.Usage()
+		return 1
+	}
+	if options.output != outputText && options.output != outputJSON {
+		log.Errorf("Invalid output %q, must be %s or %s", options.output, outputText, outputJSON)
+		return 1
+	}
+
+	collector := &diagnostics.Collector{
+		CheckpointPath:   options.checkpointPath,
+		IntrospectionURL: options.introspectionURL,
+		NetLink:          netlinkwrapper.NewNetLink(),
+	}
+	if !options.skipMetadata {
+		imds := nholuongututils.NewTypedIMDS(ec2metadata.New(nholuongutsession.New()))
+		collector.IMDS = &imds
+	}
+
+	state := collector.Collect(context.Background())
+	report := diagnostics.NewReport(state, time.Now(), options.includeState)
+	var err error
+	if options.output == outputJSON {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		log.Errorf("Failed to write the report: %v", err)
+		return 1
+	}
+	if report.Summary.Errors > 0 {
+		return exitFindings
+	}
+	return 0
+}
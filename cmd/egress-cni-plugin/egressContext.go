@@ -57,7 +57,7 @@ type egressContext struct {
 	ArgsIfName    string
 	Veth          vethwrapper.Veth
 	IPTablesIface iptableswrapper.IPTablesIface
-	IptCreator    func(iptables.Protocol) (iptableswrapper.IPTablesIface, error)
+	IptCreator    func(iptables.Protocol, iptableswrapper.Backend) (iptableswrapper.IPTablesIface, error)
 
 	NetConf   *NetConf
 	Result    *current.Result
@@ -81,8 +81,8 @@ func NewEgressAddContext(nsPath, ifName string) egressContext {
 		NsPath:     nsPath,
 		ArgsIfName: ifName,
 		Veth:       vethwrapper.NewSetupVeth(),
-		IptCreator: func(protocol iptables.Protocol) (iptableswrapper.IPTablesIface, error) {
-			return iptableswrapper.NewIPTables(protocol)
+		IptCreator: func(protocol iptables.Protocol, backend iptableswrapper.Backend) (iptableswrapper.IPTablesIface, error) {
+			return iptableswrapper.New(protocol, backend)
 		},
 	}
 }
@@ -94,8 +94,8 @@ func NewEgressDelContext(nsPath string) egressContext {
 		Link:   netlinkwrapper.NewNetLink(),
 		Ns:     nswrapper.NewNS(),
 		NsPath: nsPath,
-		IptCreator: func(protocol iptables.Protocol) (iptableswrapper.IPTablesIface, error) {
-			return iptableswrapper.NewIPTables(protocol)
+		IptCreator: func(protocol iptables.Protocol, backend iptableswrapper.Backend) (iptableswrapper.IPTablesIface, error) {
+			return iptableswrapper.New(protocol, backend)
 		},
 	}
 }
@@ -242,7 +242,7 @@ func (ec *egressContext) setupHostVethV4(vethName string) error {
 // cmdAddEgressV4 exec necessary settings to support IPv4 egress traffic in EKS IPv6 cluster
 func (ec *egressContext) cmdAddEgressV4() (err error) {
 	if ec.IPTablesIface == nil {
-		if ec.IPTablesIface, err = ec.IptCreator(iptables.ProtocolIPv4); err != nil {
+		if ec.IPTablesIface, err = ec.IptCreator(iptables.ProtocolIPv4, ec.NetConf.netfilterBackend()); err != nil {
 			ec.Log.Error("command iptables not found")
 			return err
 		}
@@ -293,7 +293,7 @@ func (ec *egressContext) cmdDelEgress(ipv4 bool) (err error) {
 	}
 
 	if ec.IPTablesIface == nil {
-		if ec.IPTablesIface, err = ec.IptCreator(protocol); err != nil {
+		if ec.IPTablesIface, err = ec.IptCreator(protocol, ec.NetConf.netfilterBackend()); err != nil {
 			ec.Log.Error("command iptables not found")
 			// without iptables ir ip6tables, chain/rules could not be removed
 			return err
@@ -353,7 +353,7 @@ func (ec *egressContext) cmdAddEgressV6() (err error) {
 	// 5. IPv6 egress traffic of all containers in a node shares node primary interface (eth0) through SNAT
 
 	if ec.IPTablesIface == nil {
-		if ec.IPTablesIface, err = ec.IptCreator(iptables.ProtocolIPv6); err != nil {
+		if ec.IPTablesIface, err = ec.IptCreator(iptables.ProtocolIPv6, ec.NetConf.netfilterBackend()); err != nil {
 			ec.Log.Error("command ip6tables not found")
 			return err
 		}
@@ -21,6 +21,7 @@ import (
 	"github.com/containernetworking/cni/pkg/types"
 	cniversion "github.com/containernetworking/cni/pkg/version"
 
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/iptableswrapper"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/logger"
 )
 
@@ -49,10 +50,21 @@ type NetConf struct {
 	// IP to use as SNAT target
 	NodeIP net.IP `json:"nodeIP"`
 
+	// Netfilter backend used to program SNAT rules, iptables or nftables
+	NetfilterBackend string `json:"netfilterBackend"`
+
 	PluginLogFile  string `json:"pluginLogFile"`
 	PluginLogLevel string `json:"pluginLogLevel"`
 }
 
+// netfilterBackend returns the configured netfilter backend, defaulting to iptables
+func (conf *NetConf) netfilterBackend() iptableswrapper.Backend {
+	if conf.NetfilterBackend == "" {
+		return iptableswrapper.DefaultBackend
+	}
+	return iptableswrapper.Backend(conf.NetfilterBackend)
+}
+
 // LoadConf load stdin and parse to NetConf type, a new log instance is created based on conf settings
 func LoadConf(bytes []byte) (*NetConf, logger.Logger, error) {
 	conf := &NetConf{}
@@ -33,6 +33,7 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/nholuongut/amazon-vpc-cni-k8s/cmd/routed-eni-cni-plugin/driver"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/datapath"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/grpcwrapper"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/networkutils"
@@ -51,6 +52,9 @@ const npAgentAddress = "127.0.0.1:50052"
 
 const dummyInterfacePrefix = "dummy"
 
+// errPluginNotAvailable is the well known error code of the STATUS command when the plugin cannot service ADD requests
+const errPluginNotAvailable uint = 50
+
 var version string
 
 // NetConf stores the common network config for the CNI plugin
@@ -68,6 +72,9 @@ type NetConf struct {
 	// PodSGEnforcingMode is the enforcing mode for Security groups for pods feature
 	PodSGEnforcingMode sgpp.EnforcingMode `json:"podSGEnforcingMode"`
 
+	// PodDatapath routes the traffic of the pods behind secondary ENIs with ip rules or eBPF programs
+	PodDatapath datapath.Mode `json:"podDatapath"`
+
 	PluginLogFile string `json:"pluginLogFile"`
 
 	PluginLogLevel string `json:"pluginLogLevel"`
@@ -100,6 +107,7 @@ func LoadNetConf(bytes []byte) (*NetConf, logger.Logger, error) {
 		MTU:                "9001",
 		VethPrefix:         "eni",
 		PodSGEnforcingMode: sgpp.DefaultEnforcingMode,
+		PodDatapath:        datapath.DefaultMode,
 	}
 
 	if err := json.Unmarshal(bytes, &conf); err != nil {
@@ -189,27 +197,26 @@ func add(args *skel.CmdArgs, cniTypes typeswrapper.CNITYPES, grpcClient grpcwrap
 		args.ContainerID, args.IfName, r)
 
 	// We will let the values in result struct guide us in terms of IP Address Family configured.
-	var v4Addr, v6Addr, addr *net.IPNet
-
-	// We don't support dual stack mode currently so it has to be either v4 or v6 mode.
+	// In dual stack mode, both v4 and v6 addresses are set.
+	var v4Addr, v6Addr *net.IPNet
 	if r.IPv4Addr != "" {
 		v4Addr = &net.IPNet{
 			IP:   net.ParseIP(r.IPv4Addr),
 			Mask: net.CIDRMask(32, 32),
 		}
-		addr = v4Addr
-	} else if r.IPv6Addr != "" {
+	}
+	if r.IPv6Addr != "" {
 		v6Addr = &net.IPNet{
 			IP:   net.ParseIP(r.IPv6Addr),
 			Mask: net.CIDRMask(128, 128),
 		}
-		addr = v6Addr
 	}
 	// AddNetwork guarantees that Gateway string is a valid IPNet
 	gw := net.ParseIP(r.PodENISubnetGW)
 
 	var hostVethName string
 	var dummyInterface *current.Interface
+	bandwidth := driver.Bandwidth{Ingress: r.IngressBandwidth, Egress: r.EgressBandwidth}
 
 	// The dummy interface is purely virtual and is stored in the prevResult struct to assist in cleanup during the DEL command.
 	dummyInterfaceName := networkutils.GeneratePodHostVethName(dummyInterfacePrefix, string(k8sArgs.K8S_POD_NAMESPACE), string(k8sArgs.K8S_POD_NAME))
@@ -219,14 +226,17 @@ func add(args *skel.CmdArgs, cniTypes typeswrapper.CNITYPES, grpcClient grpcwrap
 		hostVethNamePrefix := sgpp.BuildHostVethNamePrefix(conf.VethPrefix, conf.PodSGEnforcingMode)
 		hostVethName = networkutils.GeneratePodHostVethName(hostVethNamePrefix, string(k8sArgs.K8S_POD_NAMESPACE), string(k8sArgs.K8S_POD_NAME))
 		err = driverClient.SetupBranchENIPodNetwork(hostVethName, args.IfName, args.Netns, v4Addr, v6Addr, int(r.PodVlanId), r.PodENIMAC,
-			r.PodENISubnetGW, int(r.ParentIfIndex), mtu, conf.PodSGEnforcingMode, log)
+			r.PodENISubnetGW, int(r.ParentIfIndex), mtu, bandwidth, conf.PodSGEnforcingMode, log)
 		// For branch ENI mode, the pod VLAN ID is packed in Interface.Mac
 		dummyInterface = &current.Interface{Name: dummyInterfaceName, Mac: fmt.Sprint(r.PodVlanId)}
 	} else {
 		// build hostVethName
 		// Note: the maximum length for linux interface name is 15
 		hostVethName = networkutils.GeneratePodHostVethName(conf.VethPrefix, string(k8sArgs.K8S_POD_NAMESPACE), string(k8sArgs.K8S_POD_NAME))
-		err = driverClient.SetupPodNetwork(hostVethName, args.IfName, args.Netns, v4Addr, v6Addr, int(r.DeviceNumber), mtu, log)
+		err = driverClient.SetupPodNetwork(hostVethName, args.IfName, args.Netns, v4Addr, v6Addr, int(r.DeviceNumber), mtu, bandwidth, conf.PodDatapath, log)
+		if err == nil && len(r.ExtraInterfaces) > 0 {
+			err = setupExtraInterfaces(args, k8sArgs, conf, r, v4Addr, mtu, driverClient, log)
+		}
 		// For non-branch ENI, the pod VLAN ID value of 0 is packed in Interface.Mac, while the interface device number is packed in Interface.Sandbox
 		dummyInterface = &current.Interface{Name: dummyInterfaceName, Mac: fmt.Sprint(0), Sandbox: fmt.Sprint(r.DeviceNumber)}
 	}
@@ -257,12 +267,16 @@ func add(args *skel.CmdArgs, cniTypes typeswrapper.CNITYPES, grpcClient grpcwrap
 	}
 
 	containerInterfaceIndex := 1
-	ips := []*current.IPConfig{
-		{
+	var ips []*current.IPConfig
+	for _, addr := range []*net.IPNet{v4Addr, v6Addr} {
+		if addr == nil {
+			continue
+		}
+		ips = append(ips, &current.IPConfig{
 			Interface: &containerInterfaceIndex,
 			Address:   *addr,
 			Gateway:   gw,
-		},
+		})
 	}
 
 	hostInterface := &current.Interface{Name: hostVethName}
@@ -279,6 +293,18 @@ func add(args *skel.CmdArgs, cniTypes typeswrapper.CNITYPES, grpcClient grpcwrap
 	// dummy interface is appended to PrevResult for use during cleanup
 	result.Interfaces = append(result.Interfaces, dummyInterface)
 
+	// extra interfaces come after the dummy interface, so that the indexes above stay the same
+	for _, extraInterface := range r.ExtraInterfaces {
+		result.Interfaces = append(result.Interfaces,
+			&current.Interface{Name: networkutils.GeneratePodExtraHostVethName(conf.VethPrefix, string(k8sArgs.K8S_POD_NAMESPACE), string(k8sArgs.K8S_POD_NAME), extraInterface.IfName)},
+			&current.Interface{Name: extraInterface.IfName, Sandbox: args.Netns})
+		extraInterfaceIndex := len(result.Interfaces) - 1
+		result.IPs = append(result.IPs, &current.IPConfig{
+			Interface: &extraInterfaceIndex,
+			Address:   net.IPNet{IP: net.ParseIP(extraInterface.IPv4Addr), Mask: net.CIDRMask(32, 32)},
+		})
+	}
+
 	if utils.IsStrictMode(r.NetworkPolicyMode) {
 		// Set up a connection to the network policy agent
 		npConn, err := grpcClient.Dial(npAgentAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
@@ -409,31 +435,37 @@ func del(args *skel.CmdArgs, cniTypes typeswrapper.CNITYPES, grpcClient grpcwrap
 	log.Infof("Received del network response from ipamd for pod %s namespace %s sandbox %s: %+v", string(k8sArgs.K8S_POD_NAME),
 		string(k8sArgs.K8S_POD_NAMESPACE), string(k8sArgs.K8S_POD_INFRA_CONTAINER_ID), r)
 
-	var deletedPodIP net.IP
-	var maskLen int
+	var v4Addr, v6Addr *net.IPNet
 	if r.IPv4Addr != "" {
-		deletedPodIP = net.ParseIP(r.IPv4Addr)
-		maskLen = 32
-	} else if r.IPv6Addr != "" {
-		deletedPodIP = net.ParseIP(r.IPv6Addr)
-		maskLen = 128
+		v4Addr = &net.IPNet{
+			IP:   net.ParseIP(r.IPv4Addr),
+			Mask: net.CIDRMask(32, 32),
+		}
 	}
-
-	if deletedPodIP != nil {
-		addr := &net.IPNet{
-			IP:   deletedPodIP,
-			Mask: net.CIDRMask(maskLen, maskLen),
+	if r.IPv6Addr != "" {
+		v6Addr = &net.IPNet{
+			IP:   net.ParseIP(r.IPv6Addr),
+			Mask: net.CIDRMask(128, 128),
 		}
+	}
 
+	if v4Addr != nil || v6Addr != nil {
 		// vlanID != 0 means pod using security group
 		if r.PodVlanId != 0 {
 			if isNetnsEmpty(args.Netns) {
 				log.Infof("Ignoring TeardownPodENI as Netns is empty for SG pod:%s namespace: %s containerID:%s", k8sArgs.K8S_POD_NAME, k8sArgs.K8S_POD_NAMESPACE, k8sArgs.K8S_POD_INFRA_CONTAINER_ID)
 				return nil
 			}
+			addr := v4Addr
+			if addr == nil {
+				addr = v6Addr
+			}
 			err = driverClient.TeardownBranchENIPodNetwork(addr, int(r.PodVlanId), conf.PodSGEnforcingMode, log)
 		} else {
-			err = driverClient.TeardownPodNetwork(addr, int(r.DeviceNumber), log)
+			err = teardownPodAddrs(driverClient, v4Addr, v6Addr, int(r.DeviceNumber), log)
+			if err == nil {
+				err = teardownExtraInterfaces(driverClient, r.ExtraInterfaces, log)
+			}
 		}
 
 		if err != nil {
@@ -447,16 +479,278 @@ func del(args *skel.CmdArgs, cniTypes typeswrapper.CNITYPES, grpcClient grpcwrap
 	return nil
 }
 
-func getContainerIP(prevResult *current.Result, contVethName string) (net.IPNet, error) {
+func cmdCheck(args *skel.CmdArgs) error {
+	return check(args, typeswrapper.New(), grpcwrapper.New(), rpcwrapper.New(), driver.New())
+}
+
+// check verifies that ipamd still holds the addresses of the pod, and that its network matches prevResult. Any drift
+// is returned as an error, so that the container runtime can act on it.
+func check(args *skel.CmdArgs, cniTypes typeswrapper.CNITYPES, grpcClient grpcwrapper.GRPC, rpcClient rpcwrapper.RPC,
+	driverClient driver.NetworkAPIs) error {
+
+	conf, log, err := LoadNetConf(args.StdinData)
+	if err != nil {
+		return errors.Wrap(err, "check cmd: error loading config from args")
+	}
+
+	log.Infof("Received CNI check request: ContainerID(%s) Netns(%s) IfName(%s) Args(%s) Path(%s) argsStdinData(%s)",
+		args.ContainerID, args.Netns, args.IfName, args.Args, args.Path, args.StdinData)
+
+	var k8sArgs K8sArgs
+	if err := cniTypes.LoadArgs(args.Args, &k8sArgs); err != nil {
+		log.Errorf("Failed to load k8s config from args: %v", err)
+		return errors.Wrap(err, "check cmd: failed to load k8s config from args")
+	}
+
+	prevResult, ok := conf.PrevResult.(*current.Result)
+	if !ok {
+		return errors.New("check cmd: prevResult is required")
+	}
+	v4Addr, v6Addr, err := getContainerIPs(prevResult, args.IfName)
+	if err != nil {
+		return errors.Wrap(err, "check cmd: invalid prevResult")
+	}
+
+	conn, err := grpcClient.Dial(ipamdAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Errorf("Failed to connect to backend server for container %s: %v", args.ContainerID, err)
+		return errors.Wrap(err, "check cmd: failed to connect to backend server")
+	}
+	defer conn.Close()
+
+	c := rpcClient.NewCNIBackendClient(conn)
+	r, err := c.CheckNetwork(context.Background(), &pb.CheckNetworkRequest{
+		ClientVersion:     version,
+		K8S_POD_NAME:      string(k8sArgs.K8S_POD_NAME),
+		K8S_POD_NAMESPACE: string(k8sArgs.K8S_POD_NAMESPACE),
+		ContainerID:       args.ContainerID,
+		IfName:            args.IfName,
+		NetworkName:       conf.Name,
+		IPv4Addr:          ipString(v4Addr),
+		IPv6Addr:          ipString(v6Addr),
+	})
+	if err != nil {
+		log.Errorf("Error received from CheckNetwork gRPC call for container %s: %v", args.ContainerID, err)
+		return errors.Wrap(err, "check cmd: Error received from CheckNetwork gRPC call")
+	}
+	if !r.Success {
+		return errors.New("check cmd: ipamd does not hold the addresses of the container")
+	}
+
+	if r.PodVlanId != 0 {
+		hostVethNamePrefix := sgpp.BuildHostVethNamePrefix(conf.VethPrefix, conf.PodSGEnforcingMode)
+		hostVethName := networkutils.GeneratePodHostVethName(hostVethNamePrefix, string(k8sArgs.K8S_POD_NAMESPACE), string(k8sArgs.K8S_POD_NAME))
+		err = driverClient.CheckBranchENIPodNetwork(hostVethName, args.IfName, args.Netns, v4Addr, v6Addr, int(r.PodVlanId),
+			conf.PodSGEnforcingMode, log)
+	} else {
+		hostVethName := networkutils.GeneratePodHostVethName(conf.VethPrefix, string(k8sArgs.K8S_POD_NAMESPACE), string(k8sArgs.K8S_POD_NAME))
+		err = driverClient.CheckPodNetwork(hostVethName, args.IfName, args.Netns, v4Addr, v6Addr, int(r.DeviceNumber), conf.PodDatapath, log)
+	}
+	if err != nil {
+		log.Errorf("Failed CheckPodNetwork for container %s: %v", args.ContainerID, err)
+		return errors.Wrap(err, "check cmd: pod network does not match prevResult")
+	}
+	log.Infof("Pod network of container %s matches prevResult", args.ContainerID)
+	return nil
+}
+
+func cmdGC(args *skel.CmdArgs) error {
+	return gc(args, grpcwrapper.New(), rpcwrapper.New(), driver.New())
+}
+
+// gc asks ipamd to release the allocations of the network whose attachment is not in the valid attachments sent by the
+// container runtime, then tears down their routes and rules on the host. Their network namespace is gone already.
+func gc(args *skel.CmdArgs, grpcClient grpcwrapper.GRPC, rpcClient rpcwrapper.RPC, driverClient driver.NetworkAPIs) error {
+	conf, log, err := LoadNetConf(args.StdinData)
+	if err != nil {
+		return errors.Wrap(err, "gc cmd: error loading config from args")
+	}
+
+	log.Infof("Received CNI gc request: network %s with %d valid attachments", conf.Name, len(conf.ValidAttachments))
+
+	conn, err := grpcClient.Dial(ipamdAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Errorf("Failed to connect to backend server for network %s: %v", conf.Name, err)
+		return errors.Wrap(err, "gc cmd: failed to connect to backend server")
+	}
+	defer conn.Close()
+
+	validAttachments := make([]*pb.Attachment, 0, len(conf.ValidAttachments))
+	for _, attachment := range conf.ValidAttachments {
+		validAttachments = append(validAttachments, &pb.Attachment{ContainerID: attachment.ContainerID, IfName: attachment.IfName})
+	}
+	c := rpcClient.NewCNIBackendClient(conn)
+	r, err := c.GarbageCollect(context.Background(), &pb.GarbageCollectRequest{
+		ClientVersion:    version,
+		NetworkName:      conf.Name,
+		ValidAttachments: validAttachments,
+	})
+	if err != nil {
+		log.Errorf("Error received from GarbageCollect gRPC call for network %s: %v", conf.Name, err)
+		return errors.Wrap(err, "gc cmd: Error received from GarbageCollect gRPC call")
+	}
+
+	var teardownErr error
+	for _, released := range r.Released {
+		log.Infof("Tearing down the network of stale container %s: IPv4Addr: %s, IPv6Addr: %s",
+			released.ContainerID, released.IPv4Addr, released.IPv6Addr)
+		var v4Addr, v6Addr *net.IPNet
+		if released.IPv4Addr != "" {
+			v4Addr = &net.IPNet{IP: net.ParseIP(released.IPv4Addr), Mask: net.CIDRMask(32, 32)}
+		}
+		if released.IPv6Addr != "" {
+			v6Addr = &net.IPNet{IP: net.ParseIP(released.IPv6Addr), Mask: net.CIDRMask(128, 128)}
+		}
+		if v4Addr == nil && v6Addr == nil {
+			continue
+		}
+		if released.PodVlanId != 0 {
+			addr := v4Addr
+			if addr == nil {
+				addr = v6Addr
+			}
+			err = driverClient.TeardownBranchENIPodNetwork(addr, int(released.PodVlanId), conf.PodSGEnforcingMode, log)
+		} else {
+			err = teardownPodAddrs(driverClient, v4Addr, v6Addr, int(released.DeviceNumber), log)
+		}
+		if err != nil {
+			// Keep going, the addresses are released already
+			log.Errorf("Failed on TeardownPodNetwork for stale container %s: %v", released.ContainerID, err)
+			teardownErr = errors.Wrapf(err, "gc cmd: failed on tear down network of container %s", released.ContainerID)
+		}
+	}
+	if teardownErr != nil {
+		return teardownErr
+	}
+	if !r.Success {
+		return errors.New("gc cmd: ipamd failed to release some stale allocations")
+	}
+	log.Infof("Released %d stale allocations of network %s", len(r.Released), conf.Name)
+	return nil
+}
+
+func cmdStatus(args *skel.CmdArgs) error {
+	return status(args, grpcwrapper.New(), rpcwrapper.New())
+}
+
+// status reports the plugin as not available when ipamd cannot be reached. ipamd only serves requests once it is
+// initialized. An IP pool without free address is only logged, as it is replenished by ipamd and the node must stay
+// ready for the pods with an address.
+func status(args *skel.CmdArgs, grpcClient grpcwrapper.GRPC, rpcClient rpcwrapper.RPC) error {
+	conf, log, err := LoadNetConf(args.StdinData)
+	if err != nil {
+		return errors.Wrap(err, "status cmd: error loading config from args")
+	}
+
+	conn, err := grpcClient.Dial(ipamdAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Errorf("Failed to connect to backend server: %v", err)
+		return types.NewError(errPluginNotAvailable, "failed to connect to ipamd", err.Error())
+	}
+	defer conn.Close()
+
+	c := rpcClient.NewCNIBackendClient(conn)
+	r, err := c.Status(context.Background(), &pb.StatusRequest{ClientVersion: version})
+	if err != nil {
+		log.Errorf("Error received from Status gRPC call: %v", err)
+		return types.NewError(errPluginNotAvailable, "ipamd is not reachable", err.Error())
+	}
+	log.Debugf("Received status response from ipamd for network %s: %+v", conf.Name, r)
+	if r.FreeIPv4Addresses <= 0 && r.FreeIPv6Addresses <= 0 {
+		log.Warnf("The IP pool of ipamd has no free address for network %s", conf.Name)
+	}
+	return nil
+}
+
+// ipString returns the address of addr, or "" if addr is nil
+func ipString(addr *net.IPNet) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.IP.String()
+}
+
+// teardownPodAddrs cleans up the routes and rules of each pod address. In dual stack mode, deviceNumber is the one of
+// the ENI backing the IPv4 address while IPv6 addresses always belong to the primary ENI.
+func teardownPodAddrs(driverClient driver.NetworkAPIs, v4Addr *net.IPNet, v6Addr *net.IPNet, deviceNumber int, log logger.Logger) error {
+	if v4Addr != nil {
+		if err := driverClient.TeardownPodNetwork(v4Addr, deviceNumber, log); err != nil {
+			return err
+		}
+	}
+	if v6Addr != nil {
+		v6DeviceNumber := deviceNumber
+		if v4Addr != nil {
+			v6DeviceNumber = 0
+		}
+		if err := driverClient.TeardownPodNetwork(v6Addr, v6DeviceNumber, log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setupExtraInterfaces sets up the extra interfaces of a pod once its interface is set up. On failure, the routes and
+// rules set up for the pod are cleaned up, so that only the addresses remain to be released.
+func setupExtraInterfaces(args *skel.CmdArgs, k8sArgs K8sArgs, conf *NetConf, r *pb.AddNetworkReply, v4Addr *net.IPNet, mtu int,
+	driverClient driver.NetworkAPIs, log logger.Logger) error {
+	var setUp []*pb.PodInterface
+	for _, extraInterface := range r.ExtraInterfaces {
+		hostVethName := networkutils.GeneratePodExtraHostVethName(conf.VethPrefix, string(k8sArgs.K8S_POD_NAMESPACE), string(k8sArgs.K8S_POD_NAME), extraInterface.IfName)
+		addr := &net.IPNet{IP: net.ParseIP(extraInterface.IPv4Addr), Mask: net.CIDRMask(32, 32)}
+		if err := driverClient.SetupPodExtraInterface(hostVethName, extraInterface.IfName, args.Netns, addr, int(extraInterface.DeviceNumber), mtu,
+			conf.PodDatapath, log); err != nil {
+			if teardownErr := teardownPodAddrs(driverClient, v4Addr, nil, int(r.DeviceNumber), log); teardownErr != nil {
+				log.Errorf("Failed to clean up the network of container %s: %v", args.ContainerID, teardownErr)
+			}
+			if teardownErr := teardownExtraInterfaces(driverClient, setUp, log); teardownErr != nil {
+				log.Errorf("Failed to clean up the extra interfaces of container %s: %v", args.ContainerID, teardownErr)
+			}
+			return errors.Wrapf(err, "failed to setup extra interface %s", extraInterface.IfName)
+		}
+		setUp = append(setUp, extraInterface)
+	}
+	return nil
+}
+
+// teardownExtraInterfaces cleans up the routes and rules of the extra interfaces of a pod. The veth pairs go away with
+// the network namespace.
+func teardownExtraInterfaces(driverClient driver.NetworkAPIs, extraInterfaces []*pb.PodInterface, log logger.Logger) error {
+	for _, extraInterface := range extraInterfaces {
+		addr := &net.IPNet{IP: net.ParseIP(extraInterface.IPv4Addr), Mask: net.CIDRMask(32, 32)}
+		if err := driverClient.TeardownPodNetwork(addr, int(extraInterface.DeviceNumber), log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getContainerIPs returns the IPv4 and IPv6 addresses of the container interface found in prevResult.
+// Dual stack pods have one address of each family.
+func getContainerIPs(prevResult *current.Result, contVethName string) (v4Addr *net.IPNet, v6Addr *net.IPNet, err error) {
 	containerIfaceIndex, _, found := cniutils.FindInterfaceByName(prevResult.Interfaces, contVethName)
 	if !found {
-		return net.IPNet{}, errors.Errorf("cannot find contVethName %s in prevResult", contVethName)
+		return nil, nil, errors.Errorf("cannot find contVethName %s in prevResult", contVethName)
 	}
 	containerIPs := cniutils.FindIPConfigsByIfaceIndex(prevResult.IPs, containerIfaceIndex)
-	if len(containerIPs) != 1 {
-		return net.IPNet{}, errors.Errorf("found %d containerIPs for %v in prevResult", len(containerIPs), contVethName)
+	if len(containerIPs) < 1 || len(containerIPs) > 2 {
+		return nil, nil, errors.Errorf("found %d containerIPs for %v in prevResult", len(containerIPs), contVethName)
+	}
+	for _, containerIP := range containerIPs {
+		addr := containerIP.Address
+		if addr.IP.To4() != nil {
+			if v4Addr != nil {
+				return nil, nil, errors.Errorf("found multiple IPv4 containerIPs for %v in prevResult", contVethName)
+			}
+			v4Addr = &addr
+		} else {
+			if v6Addr != nil {
+				return nil, nil, errors.Errorf("found multiple IPv6 containerIPs for %v in prevResult", contVethName)
+			}
+			v6Addr = &addr
+		}
 	}
-	return containerIPs[0].Address, nil
+	return v4Addr, v6Addr, nil
 }
 
 // tryDelWithPrevResult will try to process CNI delete request without IPAMD.
@@ -486,12 +780,17 @@ func tryDelWithPrevResult(driverClient driver.NetworkAPIs, conf *NetConf, k8sArg
 		return true, nil
 	}
 
-	containerIP, err := getContainerIP(prevResult, contVethName)
+	v4Addr, v6Addr, err := getContainerIPs(prevResult, contVethName)
 	if err != nil {
 		return false, err
 	}
+	// Branch ENI pods only have a single address family
+	containerIP := v4Addr
+	if containerIP == nil {
+		containerIP = v6Addr
+	}
 
-	if err := driverClient.TeardownBranchENIPodNetwork(&containerIP, podVlanID, conf.PodSGEnforcingMode, log); err != nil {
+	if err := driverClient.TeardownBranchENIPodNetwork(containerIP, podVlanID, conf.PodSGEnforcingMode, log); err != nil {
 		return true, err
 	}
 	return true, nil
@@ -522,13 +821,13 @@ func teardownPodNetworkWithPrevResult(driverClient driver.NetworkAPIs, conf *Net
 		log.Errorf("Invalid device number for pod: %s", dummyIface.Sandbox)
 		return false
 	}
-	containerIP, err := getContainerIP(prevResult, contVethName)
+	v4Addr, v6Addr, err := getContainerIPs(prevResult, contVethName)
 	if err != nil {
 		log.Errorf("Failed to get container IP: %v", err)
 		return false
 	}
 
-	if err := driverClient.TeardownPodNetwork(&containerIP, deviceNumber, log); err != nil {
+	if err := teardownPodAddrs(driverClient, v4Addr, v6Addr, deviceNumber, log); err != nil {
 		log.Errorf("Failed to teardown pod network: %v", err)
 		return false
 	}
@@ -547,7 +846,14 @@ func main() {
 	log := logger.DefaultLogger()
 	about := fmt.Sprintf("nholuongut CNI %s", version)
 	exitCode := 0
-	if e := skel.PluginMainWithError(cmdAdd, nil, cmdDel, cniSpecVersion.All, about); e != nil {
+	funcs := skel.CNIFuncs{
+		Add:    cmdAdd,
+		Check:  cmdCheck,
+		Del:    cmdDel,
+		GC:     cmdGC,
+		Status: cmdStatus,
+	}
+	if e := skel.PluginMainFuncsWithError(funcs, cniSpecVersion.All, about); e != nil {
 		if err := e.Print(); err != nil {
 			log.Errorf("Failed to write error to stdout: %v", err)
 		}
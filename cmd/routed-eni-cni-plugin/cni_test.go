@@ -19,6 +19,7 @@ import (
 	"net"
 	"testing"
 
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/datapath"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/sgpp"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/logger"
 	"github.com/nholuongut/nholuongut-sdk-go/nholuongut"
@@ -30,6 +31,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
 
+	"github.com/nholuongut/amazon-vpc-cni-k8s/cmd/routed-eni-cni-plugin/driver"
 	mock_driver "github.com/nholuongut/amazon-vpc-cni-k8s/cmd/routed-eni-cni-plugin/driver/mocks"
 	mock_grpcwrapper "github.com/nholuongut/amazon-vpc-cni-k8s/pkg/grpcwrapper/mocks"
 	mock_rpcwrapper "github.com/nholuongut/amazon-vpc-cni-k8s/pkg/rpcwrapper/mocks"
@@ -58,6 +60,7 @@ var netConf = &NetConf{
 		Type:       cniType,
 	},
 	PodSGEnforcingMode: sgpp.DefaultEnforcingMode,
+	PodDatapath:        datapath.DefaultMode,
 	PluginLogLevel:     pluginLogLevel,
 	PluginLogFile:      pluginLogFile,
 }
@@ -102,7 +105,7 @@ func TestCmdAdd(t *testing.T) {
 		Mask: net.IPv4Mask(255, 255, 255, 255),
 	}
 	mocksNetwork.EXPECT().SetupPodNetwork(gomock.Any(), cmdArgs.IfName, cmdArgs.Netns,
-		v4Addr, nil, int(addNetworkReply.DeviceNumber), gomock.Any(), gomock.Any()).Return(nil)
+		v4Addr, nil, int(addNetworkReply.DeviceNumber), gomock.Any(), driver.Bandwidth{}, datapath.ModeIPRule, gomock.Any()).Return(nil)
 
 	mocksTypes.EXPECT().PrintResult(gomock.Any(), gomock.Any()).Return(nil)
 
@@ -110,6 +113,88 @@ func TestCmdAdd(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestCmdAddEBPFDatapath(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	ebpfNetConf := *netConf
+	ebpfNetConf.PodDatapath = datapath.ModeEBPF
+	stdinData, _ := json.Marshal(ebpfNetConf)
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	addNetworkReply := &rpc.AddNetworkReply{Success: true, IPv4Addr: ipAddr, DeviceNumber: devNum, NetworkPolicyMode: "none"}
+	mockC.EXPECT().AddNetwork(gomock.Any(), gomock.Any()).Return(addNetworkReply, nil)
+
+	v4Addr := &net.IPNet{
+		IP:   net.ParseIP(addNetworkReply.IPv4Addr),
+		Mask: net.IPv4Mask(255, 255, 255, 255),
+	}
+	mocksNetwork.EXPECT().SetupPodNetwork(gomock.Any(), cmdArgs.IfName, cmdArgs.Netns,
+		v4Addr, nil, int(addNetworkReply.DeviceNumber), gomock.Any(), driver.Bandwidth{}, datapath.ModeEBPF, gomock.Any()).Return(nil)
+
+	mocksTypes.EXPECT().PrintResult(gomock.Any(), gomock.Any()).Return(nil)
+
+	err := add(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+}
+
+func TestCmdAddDualStack(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	stdinData, _ := json.Marshal(netConf)
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	addNetworkReply := &rpc.AddNetworkReply{Success: true, IPv4Addr: ipAddr, IPv6Addr: "2001:db8::15", DeviceNumber: devNum, NetworkPolicyMode: "none"}
+	mockC.EXPECT().AddNetwork(gomock.Any(), gomock.Any()).Return(addNetworkReply, nil)
+
+	v4Addr := &net.IPNet{
+		IP:   net.ParseIP(addNetworkReply.IPv4Addr),
+		Mask: net.IPv4Mask(255, 255, 255, 255),
+	}
+	v6Addr := &net.IPNet{
+		IP:   net.ParseIP(addNetworkReply.IPv6Addr),
+		Mask: net.CIDRMask(128, 128),
+	}
+	mocksNetwork.EXPECT().SetupPodNetwork(gomock.Any(), cmdArgs.IfName, cmdArgs.Netns,
+		v4Addr, v6Addr, int(addNetworkReply.DeviceNumber), gomock.Any(), driver.Bandwidth{}, datapath.ModeIPRule, gomock.Any()).Return(nil)
+
+	mocksTypes.EXPECT().PrintResult(gomock.Any(), gomock.Any()).DoAndReturn(func(result types.Result, _ string) error {
+		ips := result.(*current.Result).IPs
+		assert.Len(t, ips, 2)
+		assert.Equal(t, *v4Addr, ips[0].Address)
+		assert.Equal(t, *v6Addr, ips[1].Address)
+		return nil
+	})
+
+	err := add(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+}
+
 func TestCmdAddWithNPenabled(t *testing.T) {
 	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
 	defer ctrl.Finish()
@@ -146,7 +231,7 @@ func TestCmdAddWithNPenabled(t *testing.T) {
 		Mask: net.IPv4Mask(255, 255, 255, 255),
 	}
 	mocksNetwork.EXPECT().SetupPodNetwork(gomock.Any(), cmdArgs.IfName, cmdArgs.Netns,
-		v4Addr, nil, int(addNetworkReply.DeviceNumber), gomock.Any(), gomock.Any()).Return(nil)
+		v4Addr, nil, int(addNetworkReply.DeviceNumber), gomock.Any(), driver.Bandwidth{}, datapath.ModeIPRule, gomock.Any()).Return(nil)
 
 	mocksTypes.EXPECT().PrintResult(gomock.Any(), gomock.Any()).Return(nil)
 
@@ -190,7 +275,7 @@ func TestCmdAddWithNPenabledWithErr(t *testing.T) {
 		Mask: net.IPv4Mask(255, 255, 255, 255),
 	}
 	mocksNetwork.EXPECT().SetupPodNetwork(gomock.Any(), cmdArgs.IfName, cmdArgs.Netns,
-		v4Addr, nil, int(addNetworkReply.DeviceNumber), gomock.Any(), gomock.Any()).Return(nil)
+		v4Addr, nil, int(addNetworkReply.DeviceNumber), gomock.Any(), driver.Bandwidth{}, datapath.ModeIPRule, gomock.Any()).Return(nil)
 
 	err := add(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
 	assert.Error(t, err)
@@ -251,7 +336,7 @@ func TestCmdAddErrSetupPodNetwork(t *testing.T) {
 	}
 
 	mocksNetwork.EXPECT().SetupPodNetwork(gomock.Any(), cmdArgs.IfName, cmdArgs.Netns,
-		addr, nil, int(addNetworkReply.DeviceNumber), gomock.Any(), gomock.Any()).Return(errors.New("error on SetupPodNetwork"))
+		addr, nil, int(addNetworkReply.DeviceNumber), gomock.Any(), driver.Bandwidth{}, datapath.ModeIPRule, gomock.Any()).Return(errors.New("error on SetupPodNetwork"))
 
 	// when SetupPodNetwork fails, expect to return IP back to datastore
 	delNetworkReply := &rpc.DelNetworkReply{Success: true, IPv4Addr: ipAddr, DeviceNumber: devNum}
@@ -262,6 +347,99 @@ func TestCmdAddErrSetupPodNetwork(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestCmdAddExtraInterfaces(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	stdinData, _ := json.Marshal(netConf)
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	addNetworkReply := &rpc.AddNetworkReply{Success: true, IPv4Addr: ipAddr, DeviceNumber: devNum, NetworkPolicyMode: "none",
+		ExtraInterfaces: []*rpc.PodInterface{{IfName: "net1", IPv4Addr: "10.1.0.7", DeviceNumber: 2}}}
+	mockC.EXPECT().AddNetwork(gomock.Any(), gomock.Any()).Return(addNetworkReply, nil)
+
+	v4Addr := &net.IPNet{
+		IP:   net.ParseIP(addNetworkReply.IPv4Addr),
+		Mask: net.IPv4Mask(255, 255, 255, 255),
+	}
+	extraAddr := &net.IPNet{
+		IP:   net.ParseIP("10.1.0.7"),
+		Mask: net.IPv4Mask(255, 255, 255, 255),
+	}
+	mocksNetwork.EXPECT().SetupPodNetwork(gomock.Any(), cmdArgs.IfName, cmdArgs.Netns,
+		v4Addr, nil, int(addNetworkReply.DeviceNumber), gomock.Any(), driver.Bandwidth{}, datapath.ModeIPRule, gomock.Any()).Return(nil)
+	mocksNetwork.EXPECT().SetupPodExtraInterface(gomock.Any(), "net1", cmdArgs.Netns, extraAddr, 2, gomock.Any(), datapath.ModeIPRule, gomock.Any()).Return(nil)
+
+	mocksTypes.EXPECT().PrintResult(gomock.Any(), gomock.Any()).DoAndReturn(func(result types.Result, _ string) error {
+		r := result.(*current.Result)
+		// host veth, container veth and dummy interface of the pod, then host veth and container veth of net1
+		assert.Len(t, r.Interfaces, 5)
+		assert.Equal(t, "net1", r.Interfaces[4].Name)
+		assert.Len(t, r.IPs, 2)
+		assert.Equal(t, 1, *r.IPs[0].Interface)
+		assert.Equal(t, 4, *r.IPs[1].Interface)
+		assert.Equal(t, *extraAddr, r.IPs[1].Address)
+		return nil
+	})
+
+	err := add(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+}
+
+func TestCmdAddErrSetupExtraInterface(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	stdinData, _ := json.Marshal(netConf)
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	addNetworkReply := &rpc.AddNetworkReply{Success: true, IPv4Addr: ipAddr, DeviceNumber: devNum, NetworkPolicyMode: "none",
+		ExtraInterfaces: []*rpc.PodInterface{{IfName: "net1", IPv4Addr: "10.1.0.7", DeviceNumber: 2}}}
+	mockC.EXPECT().AddNetwork(gomock.Any(), gomock.Any()).Return(addNetworkReply, nil)
+
+	addr := &net.IPNet{
+		IP:   net.ParseIP(addNetworkReply.IPv4Addr),
+		Mask: net.IPv4Mask(255, 255, 255, 255),
+	}
+	mocksNetwork.EXPECT().SetupPodNetwork(gomock.Any(), cmdArgs.IfName, cmdArgs.Netns,
+		addr, nil, int(addNetworkReply.DeviceNumber), gomock.Any(), driver.Bandwidth{}, datapath.ModeIPRule, gomock.Any()).Return(nil)
+	mocksNetwork.EXPECT().SetupPodExtraInterface(gomock.Any(), "net1", cmdArgs.Netns, gomock.Any(), 2, gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(errors.New("error on SetupPodExtraInterface"))
+
+	// when an extra interface fails, expect the pod routes to be cleaned up and the IPs returned back to datastore
+	mocksNetwork.EXPECT().TeardownPodNetwork(addr, int(addNetworkReply.DeviceNumber), gomock.Any()).Return(nil)
+	delNetworkReply := &rpc.DelNetworkReply{Success: true, IPv4Addr: ipAddr, DeviceNumber: devNum, ExtraInterfaces: addNetworkReply.ExtraInterfaces}
+	mockC.EXPECT().DelNetwork(gomock.Any(), gomock.Any()).Return(delNetworkReply, nil)
+
+	err := add(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+
+	assert.Error(t, err)
+}
+
 func TestCmdDel(t *testing.T) {
 	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
 	defer ctrl.Finish()
@@ -296,6 +474,86 @@ func TestCmdDel(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestCmdDelDualStack(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	stdinData, _ := json.Marshal(netConf)
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	delNetworkReply := &rpc.DelNetworkReply{Success: true, IPv4Addr: ipAddr, IPv6Addr: "2001:db8::15", DeviceNumber: devNum}
+
+	mockC.EXPECT().DelNetwork(gomock.Any(), gomock.Any()).Return(delNetworkReply, nil)
+
+	v4Addr := &net.IPNet{
+		IP:   net.ParseIP(delNetworkReply.IPv4Addr),
+		Mask: net.IPv4Mask(255, 255, 255, 255),
+	}
+	v6Addr := &net.IPNet{
+		IP:   net.ParseIP(delNetworkReply.IPv6Addr),
+		Mask: net.CIDRMask(128, 128),
+	}
+
+	// IPv6 addresses always belong to the primary ENI
+	mocksNetwork.EXPECT().TeardownPodNetwork(v4Addr, int(delNetworkReply.DeviceNumber), gomock.Any()).Return(nil)
+	mocksNetwork.EXPECT().TeardownPodNetwork(v6Addr, 0, gomock.Any()).Return(nil)
+
+	err := del(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+}
+
+func TestCmdDelExtraInterfaces(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	stdinData, _ := json.Marshal(netConf)
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	delNetworkReply := &rpc.DelNetworkReply{Success: true, IPv4Addr: ipAddr, DeviceNumber: devNum,
+		ExtraInterfaces: []*rpc.PodInterface{{IfName: "net1", IPv4Addr: "10.1.0.7", DeviceNumber: 2}}}
+
+	mockC.EXPECT().DelNetwork(gomock.Any(), gomock.Any()).Return(delNetworkReply, nil)
+
+	addr := &net.IPNet{
+		IP:   net.ParseIP(delNetworkReply.IPv4Addr),
+		Mask: net.IPv4Mask(255, 255, 255, 255),
+	}
+	extraAddr := &net.IPNet{
+		IP:   net.ParseIP("10.1.0.7"),
+		Mask: net.IPv4Mask(255, 255, 255, 255),
+	}
+
+	mocksNetwork.EXPECT().TeardownPodNetwork(addr, int(delNetworkReply.DeviceNumber), gomock.Any()).Return(nil)
+	mocksNetwork.EXPECT().TeardownPodNetwork(extraAddr, 2, gomock.Any()).Return(nil)
+
+	err := del(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+}
+
 func TestCmdDelErrDelNetwork(t *testing.T) {
 	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
 	defer ctrl.Finish()
@@ -386,7 +644,7 @@ func TestCmdAddForPodENINetwork(t *testing.T) {
 		Mask: net.IPv4Mask(255, 255, 255, 255),
 	}
 	mocksNetwork.EXPECT().SetupBranchENIPodNetwork(gomock.Any(), cmdArgs.IfName, cmdArgs.Netns, addr, nil, 1, "eniHardwareAddr",
-		"10.0.0.1", 2, gomock.Any(), sgpp.EnforcingModeStrict, gomock.Any()).Return(nil)
+		"10.0.0.1", 2, gomock.Any(), driver.Bandwidth{}, sgpp.EnforcingModeStrict, gomock.Any()).Return(nil)
 
 	mocksTypes.EXPECT().PrintResult(gomock.Any(), gomock.Any()).Return(nil)
 
@@ -428,6 +686,248 @@ func TestCmdDelForPodENINetwork(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+// checkStdinData returns the config of a check request whose prevResult gives addr to the container interface
+func checkStdinData(t *testing.T, addr string) []byte {
+	conf := *netConf
+	conf.CNIVersion = "1.0.0"
+	conf.RawPrevResult = map[string]interface{}{
+		"cniVersion": "1.0.0",
+		"interfaces": []map[string]interface{}{
+			{"name": "eni8ea2c11fe35"},
+			{"name": ifName, "sandbox": netNS},
+		},
+		"ips": []map[string]interface{}{
+			{"address": addr, "interface": 1},
+		},
+	}
+	stdinData, err := json.Marshal(conf)
+	assert.NoError(t, err)
+	return stdinData
+}
+
+func TestCmdCheck(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: checkStdinData(t, ipAddr+"/32")}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	checkNetworkReply := &rpc.CheckNetworkReply{Success: true, DeviceNumber: devNum}
+	mockC.EXPECT().CheckNetwork(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, in *rpc.CheckNetworkRequest, _ ...interface{}) (*rpc.CheckNetworkReply, error) {
+			assert.Equal(t, ipAddr, in.IPv4Addr)
+			assert.Equal(t, "", in.IPv6Addr)
+			return checkNetworkReply, nil
+		})
+
+	v4Addr := &net.IPNet{
+		IP:   net.ParseIP(ipAddr),
+		Mask: net.IPv4Mask(255, 255, 255, 255),
+	}
+	mocksNetwork.EXPECT().CheckPodNetwork(gomock.Any(), cmdArgs.IfName, cmdArgs.Netns,
+		gomock.Any(), nil, devNum, datapath.ModeIPRule, gomock.Any()).DoAndReturn(
+		func(_, _, _ string, addr, _ *net.IPNet, _ int, _ datapath.Mode, _ logger.Logger) error {
+			assert.Equal(t, v4Addr.String(), addr.String())
+			return nil
+		})
+
+	err := check(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+}
+
+func TestCmdCheckWithoutPrevResult(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	stdinData, _ := json.Marshal(netConf)
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: stdinData}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	err := check(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.EqualError(t, err, "check cmd: prevResult is required")
+}
+
+func TestCmdCheckErrCheckNetwork(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: checkStdinData(t, ipAddr+"/32")}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	checkNetworkReply := &rpc.CheckNetworkReply{Success: false}
+	mockC.EXPECT().CheckNetwork(gomock.Any(), gomock.Any()).Return(checkNetworkReply, errors.New("error on CheckNetwork"))
+
+	err := check(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Error(t, err)
+}
+
+func TestCmdCheckForPodENINetwork(t *testing.T) {
+	ctrl, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	cmdArgs := &skel.CmdArgs{ContainerID: containerID,
+		Netns:     netNS,
+		IfName:    ifName,
+		StdinData: checkStdinData(t, ipAddr+"/32")}
+
+	mocksTypes.EXPECT().LoadArgs(gomock.Any(), gomock.Any()).Return(nil)
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	checkNetworkReply := &rpc.CheckNetworkReply{Success: true, DeviceNumber: -1, PodVlanId: 1}
+	mockC.EXPECT().CheckNetwork(gomock.Any(), gomock.Any()).Return(checkNetworkReply, nil)
+
+	mocksNetwork.EXPECT().CheckBranchENIPodNetwork(gomock.Any(), cmdArgs.IfName, cmdArgs.Netns, gomock.Any(), nil, 1,
+		sgpp.EnforcingModeStrict, gomock.Any()).Return(errors.New("route is missing"))
+
+	err := check(cmdArgs, mocksTypes, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.EqualError(t, err, "check cmd: pod network does not match prevResult: route is missing")
+}
+
+func TestCmdGC(t *testing.T) {
+	ctrl, _, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	conf := *netConf
+	conf.ValidAttachments = []types.GCAttachment{{ContainerID: containerID, IfName: ifName}}
+	stdinData, _ := json.Marshal(conf)
+
+	cmdArgs := &skel.CmdArgs{StdinData: stdinData}
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	gcReq := &rpc.GarbageCollectRequest{
+		NetworkName:      cniName,
+		ValidAttachments: []*rpc.Attachment{{ContainerID: containerID, IfName: ifName}},
+	}
+	gcReply := &rpc.GarbageCollectReply{
+		Success: true,
+		Released: []*rpc.ReleasedAllocation{
+			{ContainerID: "stale-container", IfName: ifName, IPv4Addr: ipAddr, DeviceNumber: devNum},
+			{ContainerID: "stale-sgpp-container", IfName: ifName, IPv4Addr: "10.0.1.16", PodVlanId: 1},
+		},
+	}
+	mockC.EXPECT().GarbageCollect(gomock.Any(), gcReq).Return(gcReply, nil)
+
+	mocksNetwork.EXPECT().TeardownPodNetwork(&net.IPNet{IP: net.ParseIP(ipAddr), Mask: net.CIDRMask(32, 32)},
+		devNum, gomock.Any()).Return(nil)
+	mocksNetwork.EXPECT().TeardownBranchENIPodNetwork(&net.IPNet{IP: net.ParseIP("10.0.1.16"), Mask: net.CIDRMask(32, 32)},
+		1, sgpp.EnforcingModeStrict, gomock.Any()).Return(nil)
+
+	err := gc(cmdArgs, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.Nil(t, err)
+}
+
+func TestCmdGCErrTeardown(t *testing.T) {
+	ctrl, _, mocksGRPC, mocksRPC, mocksNetwork := setup(t)
+	defer ctrl.Finish()
+
+	stdinData, _ := json.Marshal(netConf)
+
+	cmdArgs := &skel.CmdArgs{StdinData: stdinData}
+
+	conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+	mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+	mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+	mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+
+	gcReply := &rpc.GarbageCollectReply{
+		Success: true,
+		Released: []*rpc.ReleasedAllocation{
+			{ContainerID: "stale-container-1", IfName: ifName, IPv4Addr: "10.0.1.16", DeviceNumber: devNum},
+			{ContainerID: "stale-container-2", IfName: ifName, IPv4Addr: "10.0.1.17", DeviceNumber: devNum},
+		},
+	}
+	mockC.EXPECT().GarbageCollect(gomock.Any(), gomock.Any()).Return(gcReply, nil)
+
+	// The failure to tear down one container does not stop the others
+	mocksNetwork.EXPECT().TeardownPodNetwork(gomock.Any(), devNum, gomock.Any()).Return(errors.New("error on teardown"))
+	mocksNetwork.EXPECT().TeardownPodNetwork(gomock.Any(), devNum, gomock.Any()).Return(nil)
+
+	err := gc(cmdArgs, mocksGRPC, mocksRPC, mocksNetwork)
+	assert.EqualError(t, err, "gc cmd: failed on tear down network of container stale-container-1: error on teardown")
+}
+
+func TestCmdStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		statusReply *rpc.StatusReply
+		statusErr   error
+		wantErr     error
+	}{
+		{
+			name:        "pool has free addresses",
+			statusReply: &rpc.StatusReply{Success: true, FreeIPv4Addresses: 3},
+		},
+		{
+			// The pool is replenished by ipamd, which must not make the node not ready
+			name:        "pool is exhausted",
+			statusReply: &rpc.StatusReply{Success: true},
+		},
+		{
+			name:      "ipamd is not reachable",
+			statusErr: errors.New("connection refused"),
+			wantErr:   types.NewError(errPluginNotAvailable, "ipamd is not reachable", "connection refused"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl, _, mocksGRPC, mocksRPC, _ := setup(t)
+			defer ctrl.Finish()
+
+			stdinData, _ := json.Marshal(netConf)
+
+			conn, _ := grpc.Dial(ipamdAddress, grpc.WithInsecure())
+
+			mocksGRPC.EXPECT().Dial(gomock.Any(), gomock.Any()).Return(conn, nil)
+			mockC := mock_rpc.NewMockCNIBackendClient(ctrl)
+			mocksRPC.EXPECT().NewCNIBackendClient(conn).Return(mockC)
+			mockC.EXPECT().Status(gomock.Any(), gomock.Any()).Return(tt.statusReply, tt.statusErr)
+
+			err := status(&skel.CmdArgs{StdinData: stdinData}, mocksGRPC, mocksRPC)
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func Test_tryDelWithPrevResult(t *testing.T) {
 	type teardownBranchENIPodNetworkCall struct {
 		containerAddr      *net.IPNet
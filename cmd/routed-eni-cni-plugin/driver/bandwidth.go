@@ -0,0 +1,187 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package driver
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/logger"
+)
+
+const (
+	// ifbLinkPrefix is the prefix of the ifb devices limiting the traffic from the pods
+	ifbLinkPrefix = "ifb"
+	// ifbTxQueueLen is the transmit queue length of the ifb devices, the default of ethernet devices
+	ifbTxQueueLen = 1000
+	// bandwidthLatency is the time the traffic of a pod may wait in the token bucket filter before it is dropped
+	bandwidthLatency = 25 * time.Millisecond
+	// minBandwidthBurst is the minimum size in bytes of the token bucket, so that jumbo frames still go through
+	minBandwidthBurst = 64 * 1024
+	// ifbRedirectFilterPriority leaves priority 1 to the filters which must see the traffic of the pod before it is
+	// redirected to its ifb
+	ifbRedirectFilterPriority = 2
+)
+
+// Bandwidth holds the rate limits of a pod in bits per second, 0 if unlimited
+type Bandwidth struct {
+	// Ingress limits the traffic to the pod
+	Ingress uint64
+	// Egress limits the traffic from the pod
+	Egress uint64
+}
+
+// setupTBF limits the traffic sent on link, e.g. the traffic to the pod on its host veth
+func (n *linuxNetwork) setupTBF(link netlink.Link, rate uint64, log logger.Logger) error {
+	if err := n.netLink.QdiscAdd(newTBF(link.Attrs().Index, rate)); err != nil {
+		return errors.Wrapf(err, "failed to add tbf qdisc to %s", link.Attrs().Name)
+	}
+	log.Debugf("Successfully limited the traffic sent on %s to %d bit/s", link.Attrs().Name, rate)
+	return nil
+}
+
+// setupEgressBandwidth limits the traffic from the pod. The traffic the host receives on hostVeth is redirected to an
+// ifb device, which shapes it on egress.
+//
+// The filter is attached to a clsact qdisc rather than to an ingress one, so that it coexists with the tc programs the
+// network policy agent attaches to the host veth.
+//
+// # tc qdisc add dev $hostVeth clsact
+// # tc filter add dev $hostVeth ingress prio 2 protocol all u32 match u32 0 0 action mirred egress redirect dev $ifb
+func (n *linuxNetwork) setupEgressBandwidth(hostVeth netlink.Link, containerAddr *net.IPNet, rate uint64, mtu int, log logger.Logger) error {
+	ifbName := buildIfbLinkName(containerAddr)
+	// Clean up the ifb device of a previous pod with the same address, in case it was not torn down
+	if err := n.teardownEgressBandwidth(containerAddr, log); err != nil {
+		return err
+	}
+	if err := n.netLink.LinkAdd(&netlink.Ifb{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:   ifbName,
+			Flags:  net.FlagUp,
+			MTU:    mtu,
+			TxQLen: ifbTxQueueLen,
+		},
+	}); err != nil {
+		return errors.Wrapf(err, "failed to add ifb link %s", ifbName)
+	}
+	ifb, err := n.netLink.LinkByName(ifbName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find ifb link %s", ifbName)
+	}
+	// Explicitly set the link to UP state, because netlink doesn't always do that on all the platforms with net.FlagUp.
+	if err := n.netLink.LinkSetUp(ifb); err != nil {
+		return errors.Wrapf(err, "failed to set ifb link %s up", ifbName)
+	}
+	if err := n.setupTBF(ifb, rate, log); err != nil {
+		return err
+	}
+
+	if err := n.addClsactQdisc(hostVeth); err != nil {
+		return err
+	}
+	if err := n.netLink.FilterAdd(buildIfbRedirectFilter(hostVeth.Attrs().Index, ifb.Attrs().Index)); err != nil {
+		return errors.Wrapf(err, "failed to redirect the traffic of %s to %s", hostVeth.Attrs().Name, ifbName)
+	}
+	log.Debugf("Successfully redirected the traffic received on %s to %s", hostVeth.Attrs().Name, ifbName)
+	return nil
+}
+
+// addClsactQdisc adds the clsact qdisc holding the tc filters on the traffic received on link, unless it exists
+//
+// # tc qdisc add dev $link clsact
+func (n *linuxNetwork) addClsactQdisc(link netlink.Link) error {
+	clsact := &netlink.GenericQdisc{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: link.Attrs().Index,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_CLSACT,
+		},
+		QdiscType: "clsact",
+	}
+	if err := n.netLink.QdiscAdd(clsact); err != nil && !os.IsExist(err) {
+		return errors.Wrapf(err, "failed to add clsact qdisc to %s", link.Attrs().Name)
+	}
+	return nil
+}
+
+// teardownEgressBandwidth deletes the ifb device limiting the traffic from containerAddr, if any
+func (n *linuxNetwork) teardownEgressBandwidth(containerAddr *net.IPNet, log logger.Logger) error {
+	ifbName := buildIfbLinkName(containerAddr)
+	if ifb, err := n.netLink.LinkByName(ifbName); err == nil {
+		if err := n.netLink.LinkDel(ifb); err != nil {
+			return errors.Wrapf(err, "failed to delete ifb link %s", ifbName)
+		}
+		log.Debugf("Successfully deleted ifb link %s", ifbName)
+	}
+	return nil
+}
+
+// buildIfbLinkName returns the name of the ifb device limiting the traffic from containerAddr. Its length is
+// 14 characters, within the limit of 15 of linux interface names.
+func buildIfbLinkName(containerAddr *net.IPNet) string {
+	h := sha1.New()
+	h.Write([]byte(containerAddr.IP.String()))
+	return ifbLinkPrefix + hex.EncodeToString(h.Sum(nil))[:11]
+}
+
+// buildIfbRedirectFilter returns the filter redirecting all the traffic received by the link of linkIndex to the ifb
+// device of ifbIndex
+func buildIfbRedirectFilter(linkIndex int, ifbIndex int) *netlink.U32 {
+	return &netlink.U32{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: linkIndex,
+			Parent:    netlink.HANDLE_MIN_INGRESS,
+			Priority:  ifbRedirectFilterPriority,
+			Protocol:  unix.ETH_P_ALL,
+		},
+		ClassId:    netlink.MakeHandle(1, 1),
+		RedirIndex: ifbIndex,
+		Actions: []netlink.Action{
+			&netlink.MirredAction{
+				MirredAction: netlink.TCA_EGRESS_REDIR,
+				Ifindex:      ifbIndex,
+			},
+		},
+	}
+}
+
+// newTBF returns the token bucket filter limiting the traffic sent on the link of linkIndex to rate bits per second
+func newTBF(linkIndex int, rate uint64) *netlink.Tbf {
+	rateInBytes := rate / 8
+	// Bytes sent at rate during bandwidthLatency
+	latencyInBytes := rateInBytes * uint64(bandwidthLatency) / uint64(time.Second)
+	burstInBytes := latencyInBytes
+	if burstInBytes < minBandwidthBurst {
+		burstInBytes = minBandwidthBurst
+	}
+	return &netlink.Tbf{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: linkIndex,
+			Handle:    netlink.MakeHandle(1, 0),
+			Parent:    netlink.HANDLE_ROOT,
+		},
+		Rate: rateInBytes,
+		// Size of the queue of packets waiting for tokens
+		Limit: uint32(latencyInBytes + burstInBytes),
+		// Size of the bucket, in ticks needed to send burstInBytes at rateInBytes
+		Buffer: uint32(float64(burstInBytes) * float64(netlink.TIME_UNITS_PER_SEC) / float64(rateInBytes) * netlink.TickInUsec()),
+	}
+}
@@ -23,11 +23,13 @@ import (
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/sgpp"
 
 	"github.com/pkg/errors"
+	"github.com/samber/lo"
 	"golang.org/x/sys/unix"
 
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/vishvananda/netlink"
 
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/datapath"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipwrapper"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/netlinkwrapper"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/networkutils"
@@ -43,20 +45,36 @@ const (
 	//Time duration CNI waits for an IPv6 address assigned to an interface
 	//to move to stable state before error'ing out.
 	v6DADTimeout = 10 * time.Second
+
+	// extraInterfaceRouteTableBase is added to the link index of an extra container interface to get the route table
+	// of its routes within the container namespace
+	extraInterfaceRouteTableBase = 1000
 )
 
 // NetworkAPIs defines network API calls
 type NetworkAPIs interface {
 	// SetupPodNetwork sets up pod network for normal ENI based pods
-	SetupPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet, deviceNumber int, mtu int, log logger.Logger) error
+	SetupPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet, deviceNumber int, mtu int,
+		bandwidth Bandwidth, podDatapath datapath.Mode, log logger.Logger) error
 	// TeardownPodNetwork clean up pod network for normal ENI based pods
 	TeardownPodNetwork(containerAddr *net.IPNet, deviceNumber int, log logger.Logger) error
 
 	// SetupBranchENIPodNetwork sets up pod network for branch ENI based pods
 	SetupBranchENIPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet, vlanID int, eniMAC string,
-		subnetGW string, parentIfIndex int, mtu int, podSGEnforcingMode sgpp.EnforcingMode, log logger.Logger) error
+		subnetGW string, parentIfIndex int, mtu int, bandwidth Bandwidth, podSGEnforcingMode sgpp.EnforcingMode, log logger.Logger) error
 	// TeardownBranchENIPodNetwork cleans up pod network for branch ENI based pods
 	TeardownBranchENIPodNetwork(containerAddr *net.IPNet, vlanID int, podSGEnforcingMode sgpp.EnforcingMode, log logger.Logger) error
+
+	// SetupPodExtraInterface sets up an extra pod interface, whose traffic is routed by source address within the pod
+	SetupPodExtraInterface(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, deviceNumber int, mtu int,
+		podDatapath datapath.Mode, log logger.Logger) error
+
+	// CheckPodNetwork verifies that the pod network set up by SetupPodNetwork is still in place
+	CheckPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet, deviceNumber int,
+		podDatapath datapath.Mode, log logger.Logger) error
+	// CheckBranchENIPodNetwork verifies that the pod network set up by SetupBranchENIPodNetwork is still in place
+	CheckBranchENIPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet, vlanID int,
+		podSGEnforcingMode sgpp.EnforcingMode, log logger.Logger) error
 }
 
 type linuxNetwork struct {
@@ -85,6 +103,9 @@ type createVethPairContext struct {
 	ip           ipwrapper.IP
 	mtu          int
 	procSys      procsyswrapper.ProcSys
+	// extraInterface routes the traffic from the container addresses via a route table of the container veth,
+	// rather than the main route table, so that it does not replace the default route of the pod
+	extraInterface bool
 }
 
 func newCreateVethPairContext(contVethName string, hostVethName string, v4Addr *net.IPNet, v6Addr *net.IPNet, mtu int) *createVethPairContext {
@@ -153,6 +174,33 @@ func (createVethContext *createVethPairContext) run(hostNS ns.NetNS) error {
 		}
 	}
 
+	// In dual stack mode, the container gets both an IPv4 and an IPv6 address, each with its own default route.
+	for _, containerAddr := range []*net.IPNet{createVethContext.v4Addr, createVethContext.v6Addr} {
+		if containerAddr == nil {
+			continue
+		}
+		if err = createVethContext.setupContainerAddr(hostVeth, contVeth, containerAddr); err != nil {
+			return err
+		}
+	}
+
+	if createVethContext.v6Addr != nil && createVethContext.v6Addr.IP.To16() != nil {
+		if err := cniutils.WaitForAddressesToBeStable(createVethContext.netLink, createVethContext.contVethName, v6DADTimeout, WAIT_INTERVAL); err != nil {
+			return errors.Wrap(err, "setup NS network: failed while waiting for v6 addresses to be stable")
+		}
+	}
+
+	// Now that the everything has been successfully set up in the container, move the "host" end of the
+	// veth into the host namespace.
+	if err = createVethContext.netLink.LinkSetNsFd(hostVeth, int(hostNS.Fd())); err != nil {
+		return errors.Wrap(err, "setup NS network: failed to move veth to host netns")
+	}
+	return nil
+}
+
+// setupContainerAddr adds containerAddr to the container veth and routes all traffic of its address family
+// via a dummy next hop
+func (createVethContext *createVethPairContext) setupContainerAddr(hostVeth netlink.Link, contVeth netlink.Link, containerAddr *net.IPNet) error {
 	// Add a connected route to a dummy next hop (169.254.1.1 or fe80::1)
 	// # ip route show
 	// default via 169.254.1.1 dev eth0
@@ -160,42 +208,60 @@ func (createVethContext *createVethPairContext) run(hostNS ns.NetNS) error {
 
 	var gw net.IP
 	var maskLen int
-	var addr *netlink.Addr
 	var defNet *net.IPNet
 
-	if createVethContext.v4Addr != nil {
+	if containerAddr.IP.To4() != nil {
 		gw = net.IPv4(169, 254, 1, 1)
 		maskLen = 32
-		addr = &netlink.Addr{IPNet: createVethContext.v4Addr}
 		defNet = &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, maskLen)}
-	} else if createVethContext.v6Addr != nil {
+	} else {
 		gw = net.IP{0xfe, 0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
 		maskLen = 128
-		addr = &netlink.Addr{IPNet: createVethContext.v6Addr}
 		defNet = &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, maskLen)}
 	}
+	addr := &netlink.Addr{IPNet: containerAddr}
 
 	gwNet := &net.IPNet{IP: gw, Mask: net.CIDRMask(maskLen, maskLen)}
 
-	if err = createVethContext.netLink.RouteReplace(&netlink.Route{
+	// The routes of the pod interface go to the main route table
+	var rtTable int
+	if createVethContext.extraInterface {
+		rtTable = extraInterfaceRouteTableBase + contVeth.Attrs().Index
+	}
+
+	if err := createVethContext.netLink.RouteReplace(&netlink.Route{
 		LinkIndex: contVeth.Attrs().Index,
 		Scope:     netlink.SCOPE_LINK,
-		Dst:       gwNet}); err != nil {
+		Dst:       gwNet,
+		Table:     rtTable}); err != nil {
 		return errors.Wrap(err, "setup NS network: failed to add default gateway")
 	}
 
 	// Add a default route via dummy next hop(169.254.1.1 or fe80::1). Then all outgoing traffic will be routed by this
 	// default route via dummy next hop (169.254.1.1 or fe80::1)
-	if err = createVethContext.netLink.RouteAdd(&netlink.Route{
+	if err := createVethContext.netLink.RouteAdd(&netlink.Route{
 		LinkIndex: contVeth.Attrs().Index,
 		Scope:     netlink.SCOPE_UNIVERSE,
 		Dst:       defNet,
 		Gw:        gw,
+		Table:     rtTable,
 	}); err != nil {
 		return errors.Wrap(err, "setup NS network: failed to add default route")
 	}
 
-	if err = createVethContext.netLink.AddrAdd(contVeth, addr); err != nil {
+	if createVethContext.extraInterface {
+		// # ip rule show
+		// 1536:	from 10.0.0.20 lookup 1003
+		fromContainerRule := createVethContext.netLink.NewRule()
+		fromContainerRule.Src = containerAddr
+		fromContainerRule.Priority = networkutils.FromPodRulePriority
+		fromContainerRule.Table = rtTable
+		if err := createVethContext.netLink.RuleAdd(fromContainerRule); err != nil && !networkutils.IsRuleExistsError(err) {
+			return errors.Wrapf(err, "setup NS network: failed to add rule for %s", containerAddr.String())
+		}
+	}
+
+	if err := createVethContext.netLink.AddrAdd(contVeth, addr); err != nil {
 		return errors.Wrapf(err, "setup NS network: failed to add IP addr to %q", createVethContext.contVethName)
 	}
 
@@ -209,49 +275,84 @@ func (createVethContext *createVethPairContext) run(hostNS ns.NetNS) error {
 		HardwareAddr: hostVeth.Attrs().HardwareAddr,
 	}
 
-	if err = createVethContext.netLink.NeighAdd(neigh); err != nil {
+	if err := createVethContext.netLink.NeighAdd(neigh); err != nil {
 		return errors.Wrap(err, "setup NS network: failed to add static ARP")
 	}
-
-	if createVethContext.v6Addr != nil && createVethContext.v6Addr.IP.To16() != nil {
-		if err := cniutils.WaitForAddressesToBeStable(createVethContext.netLink, createVethContext.contVethName, v6DADTimeout, WAIT_INTERVAL); err != nil {
-			return errors.Wrap(err, "setup NS network: failed while waiting for v6 addresses to be stable")
-		}
-	}
-
-	// Now that the everything has been successfully set up in the container, move the "host" end of the
-	// veth into the host namespace.
-	if err = createVethContext.netLink.LinkSetNsFd(hostVeth, int(hostNS.Fd())); err != nil {
-		return errors.Wrap(err, "setup NS network: failed to move veth to host netns")
-	}
 	return nil
 }
 
 // SetupPodNetwork wires up linux networking for a pod's network
 // we expect v4Addr and v6Addr to have correct IPAddress Family.
+// The traffic of the pod is shaped on the host veth, and on an ifb device for the traffic from the pod.
+// The traffic from the pod is routed via the route table of its ENI by an ip rule, or by a tc/eBPF program in the eBPF datapath.
 func (n *linuxNetwork) SetupPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet,
-	deviceNumber int, mtu int, log logger.Logger) error {
-	log.Debugf("SetupPodNetwork: hostVethName=%s, contVethName=%s, netnsPath=%s, v4Addr=%v, v6Addr=%v, deviceNumber=%d, mtu=%d",
-		hostVethName, contVethName, netnsPath, v4Addr, v6Addr, deviceNumber, mtu)
+	deviceNumber int, mtu int, bandwidth Bandwidth, podDatapath datapath.Mode, log logger.Logger) error {
+	log.Debugf("SetupPodNetwork: hostVethName=%s, contVethName=%s, netnsPath=%s, v4Addr=%v, v6Addr=%v, deviceNumber=%d, mtu=%d, bandwidth=%+v, podDatapath=%s",
+		hostVethName, contVethName, netnsPath, v4Addr, v6Addr, deviceNumber, mtu, bandwidth, podDatapath)
 
 	hostVeth, err := n.setupVeth(hostVethName, contVethName, netnsPath, v4Addr, v6Addr, mtu, log)
 	if err != nil {
 		return errors.Wrapf(err, "SetupPodNetwork: failed to setup veth pair")
 	}
 
-	var containerAddr *net.IPNet
+	rtTable := unix.RT_TABLE_MAIN
+	if deviceNumber > 0 {
+		rtTable = deviceNumber + 1
+	}
 	if v4Addr != nil {
-		containerAddr = v4Addr
-	} else if v6Addr != nil {
-		containerAddr = v6Addr
+		if err := n.setupContainerRoutes(hostVeth, v4Addr, rtTable, podDatapath, log); err != nil {
+			return errors.Wrap(err, "SetupPodNetwork")
+		}
+	}
+	if v6Addr != nil {
+		v6RtTable := rtTable
+		if v4Addr != nil {
+			// In dual stack mode, deviceNumber is the one of the ENI backing the IPv4 address. IPv6 addresses are
+			// always allocated from the primary ENI prefix, so IPv6 traffic uses the main routing table.
+			v6RtTable = unix.RT_TABLE_MAIN
+		}
+		if err := n.setupContainerRoutes(hostVeth, v6Addr, v6RtTable, podDatapath, log); err != nil {
+			return errors.Wrap(err, "SetupPodNetwork")
+		}
+	}
+	if bandwidth.Ingress > 0 {
+		if err := n.setupTBF(hostVeth, bandwidth.Ingress, log); err != nil {
+			return errors.Wrapf(err, "SetupPodNetwork: unable to limit ingress bandwidth")
+		}
+	}
+	if bandwidth.Egress > 0 {
+		containerAddr := v4Addr
+		if containerAddr == nil {
+			containerAddr = v6Addr
+		}
+		if err := n.setupEgressBandwidth(hostVeth, containerAddr, bandwidth.Egress, mtu, log); err != nil {
+			return errors.Wrapf(err, "SetupPodNetwork: unable to limit egress bandwidth")
+		}
+	}
+	return nil
+}
+
+// SetupPodExtraInterface wires up linux networking for an extra interface of a pod. The routes of the interface are
+// kept in a route table of their own within the pod, selected by the source address, so that the default route of
+// the pod is left alone. The interface is torn down by TeardownPodNetwork.
+func (n *linuxNetwork) SetupPodExtraInterface(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet,
+	deviceNumber int, mtu int, podDatapath datapath.Mode, log logger.Logger) error {
+	log.Debugf("SetupPodExtraInterface: hostVethName=%s, contVethName=%s, netnsPath=%s, v4Addr=%v, deviceNumber=%d, mtu=%d, podDatapath=%s",
+		hostVethName, contVethName, netnsPath, v4Addr, deviceNumber, mtu, podDatapath)
+
+	createVethContext := newCreateVethPairContext(contVethName, hostVethName, v4Addr, nil, mtu)
+	createVethContext.extraInterface = true
+	hostVeth, err := n.setupVethPair(createVethContext, netnsPath, log)
+	if err != nil {
+		return errors.Wrapf(err, "SetupPodExtraInterface: failed to setup veth pair")
 	}
 
 	rtTable := unix.RT_TABLE_MAIN
 	if deviceNumber > 0 {
 		rtTable = deviceNumber + 1
 	}
-	if err := n.setupIPBasedContainerRouteRules(hostVeth, containerAddr, rtTable, log); err != nil {
-		return errors.Wrapf(err, "SetupPodNetwork: unable to setup IP based container routes and rules")
+	if err := n.setupContainerRoutes(hostVeth, v4Addr, rtTable, podDatapath, log); err != nil {
+		return errors.Wrap(err, "SetupPodExtraInterface")
 	}
 	return nil
 }
@@ -264,18 +365,25 @@ func (n *linuxNetwork) TeardownPodNetwork(containerAddr *net.IPNet, deviceNumber
 	if deviceNumber > 0 {
 		rtTable = deviceNumber + 1
 	}
+	// The pod may have been set up with either datapath, so both are torn down
 	if err := n.teardownIPBasedContainerRouteRules(containerAddr, rtTable, log); err != nil {
 		return errors.Wrapf(err, "TeardownPodNetwork: unable to teardown IP based container routes and rules")
 	}
+	n.teardownEBPFContainerRoute(containerAddr, log)
+	// The qdiscs of the host veth go away with it, but the ifb device has to be deleted
+	if err := n.teardownEgressBandwidth(containerAddr, log); err != nil {
+		return errors.Wrapf(err, "TeardownPodNetwork: unable to teardown egress bandwidth limit")
+	}
 	return nil
 }
 
 // SetupBranchENIPodNetwork sets up the network ns for pods requesting its own security group
 // we expect v4Addr and v6Addr to have correct IPAddress Family.
+// The traffic to the pod is shaped on the host veth, and the traffic from the pod on the vlan link of its branch ENI.
 func (n *linuxNetwork) SetupBranchENIPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet,
-	vlanID int, eniMAC string, subnetGW string, parentIfIndex int, mtu int, podSGEnforcingMode sgpp.EnforcingMode, log logger.Logger) error {
-	log.Debugf("SetupBranchENIPodNetwork: hostVethName=%s, contVethName=%s, netnsPath=%s, v4Addr=%v, v6Addr=%v, vlanID=%d, eniMAC=%s, subnetGW=%s, parentIfIndex=%d, mtu=%d, podSGEnforcingMode=%v",
-		hostVethName, contVethName, netnsPath, v4Addr, v6Addr, vlanID, eniMAC, subnetGW, parentIfIndex, mtu, podSGEnforcingMode)
+	vlanID int, eniMAC string, subnetGW string, parentIfIndex int, mtu int, bandwidth Bandwidth, podSGEnforcingMode sgpp.EnforcingMode, log logger.Logger) error {
+	log.Debugf("SetupBranchENIPodNetwork: hostVethName=%s, contVethName=%s, netnsPath=%s, v4Addr=%v, v6Addr=%v, vlanID=%d, eniMAC=%s, subnetGW=%s, parentIfIndex=%d, mtu=%d, bandwidth=%+v, podSGEnforcingMode=%v",
+		hostVethName, contVethName, netnsPath, v4Addr, v6Addr, vlanID, eniMAC, subnetGW, parentIfIndex, mtu, bandwidth, podSGEnforcingMode)
 
 	hostVeth, err := n.setupVeth(hostVethName, contVethName, netnsPath, v4Addr, v6Addr, mtu, log)
 	if err != nil {
@@ -320,6 +428,17 @@ func (n *linuxNetwork) SetupBranchENIPodNetwork(hostVethName string, contVethNam
 			return errors.Wrapf(err, "SetupBranchENIPodNetwork: unable to setup IP based container routes and rules")
 		}
 	}
+	if bandwidth.Ingress > 0 {
+		if err := n.setupTBF(hostVeth, bandwidth.Ingress, log); err != nil {
+			return errors.Wrapf(err, "SetupBranchENIPodNetwork: unable to limit ingress bandwidth")
+		}
+	}
+	if bandwidth.Egress > 0 {
+		// The vlan link only carries the traffic of the pod, so it is shaped like the host veth
+		if err := n.setupTBF(vlanLink, bandwidth.Egress, log); err != nil {
+			return errors.Wrapf(err, "SetupBranchENIPodNetwork: unable to limit egress bandwidth")
+		}
+	}
 	return nil
 }
 
@@ -347,8 +466,222 @@ func (n *linuxNetwork) TeardownBranchENIPodNetwork(containerAddr *net.IPNet, vla
 	return nil
 }
 
+// CheckPodNetwork verifies the veth pair, addresses and routes of the pod, and its route and rules on the host
+func (n *linuxNetwork) CheckPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet,
+	deviceNumber int, podDatapath datapath.Mode, log logger.Logger) error {
+	log.Debugf("CheckPodNetwork: hostVethName=%s, contVethName=%s, netnsPath=%s, v4Addr=%v, v6Addr=%v, deviceNumber=%d, podDatapath=%s",
+		hostVethName, contVethName, netnsPath, v4Addr, v6Addr, deviceNumber, podDatapath)
+
+	hostVeth, err := n.checkVeth(hostVethName, contVethName, netnsPath, v4Addr, v6Addr)
+	if err != nil {
+		return errors.Wrap(err, "CheckPodNetwork")
+	}
+
+	rtTable := unix.RT_TABLE_MAIN
+	if deviceNumber > 0 {
+		rtTable = deviceNumber + 1
+	}
+	if v4Addr != nil {
+		if err := n.checkContainerRoutes(hostVeth, v4Addr, rtTable, podDatapath); err != nil {
+			return errors.Wrap(err, "CheckPodNetwork")
+		}
+	}
+	if v6Addr != nil {
+		v6RtTable := rtTable
+		if v4Addr != nil {
+			// IPv6 traffic uses the main routing table in dual stack mode, see SetupPodNetwork
+			v6RtTable = unix.RT_TABLE_MAIN
+		}
+		if err := n.checkContainerRoutes(hostVeth, v6Addr, v6RtTable, podDatapath); err != nil {
+			return errors.Wrap(err, "CheckPodNetwork")
+		}
+	}
+	return nil
+}
+
+// CheckBranchENIPodNetwork verifies the veth pair, addresses and routes of the pod, its vlan and its rules on the host
+func (n *linuxNetwork) CheckBranchENIPodNetwork(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet,
+	vlanID int, podSGEnforcingMode sgpp.EnforcingMode, log logger.Logger) error {
+	log.Debugf("CheckBranchENIPodNetwork: hostVethName=%s, contVethName=%s, netnsPath=%s, v4Addr=%v, v6Addr=%v, vlanID=%d, podSGEnforcingMode=%v",
+		hostVethName, contVethName, netnsPath, v4Addr, v6Addr, vlanID, podSGEnforcingMode)
+
+	hostVeth, err := n.checkVeth(hostVethName, contVethName, netnsPath, v4Addr, v6Addr)
+	if err != nil {
+		return errors.Wrap(err, "CheckBranchENIPodNetwork")
+	}
+	vlanLinkName := buildVlanLinkName(vlanID)
+	if _, err := n.netLink.LinkByName(vlanLinkName); err != nil {
+		return errors.Wrapf(err, "CheckBranchENIPodNetwork: failed to find vlan %s", vlanLinkName)
+	}
+
+	containerAddr := v4Addr
+	if containerAddr == nil {
+		containerAddr = v6Addr
+	}
+	rtTable := vlanID + 100
+	switch podSGEnforcingMode {
+	case sgpp.EnforcingModeStrict:
+		err = n.checkIIFBasedContainerRules(hostVeth, containerAddr, vlanLinkName, rtTable)
+	case sgpp.EnforcingModeStandard:
+		err = n.checkIPBasedContainerRouteRules(hostVeth, containerAddr, rtTable)
+	}
+	if err != nil {
+		return errors.Wrap(err, "CheckBranchENIPodNetwork")
+	}
+	return nil
+}
+
+// checkVeth verifies that both ends of the veth pair are up, and that the container one has the addresses and routes
+// set up by createVethPairContext. It returns the host end.
+func (n *linuxNetwork) checkVeth(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet) (netlink.Link, error) {
+	hostVeth, err := n.netLink.LinkByName(hostVethName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find hostVeth %s", hostVethName)
+	}
+	if hostVeth.Attrs().Flags&net.FlagUp == 0 {
+		return nil, errors.Errorf("hostVeth %s is down", hostVethName)
+	}
+
+	checkContainerContext := newCheckContainerContext(contVethName, v4Addr, v6Addr)
+	if err := n.ns.WithNetNSPath(netnsPath, checkContainerContext.run); err != nil {
+		return nil, errors.Wrap(err, "failed to check container network")
+	}
+	return hostVeth, nil
+}
+
+// checkContainerContext wraps the parameters and the method to verify the network of the container namespace
+type checkContainerContext struct {
+	contVethName string
+	v4Addr       *net.IPNet
+	v6Addr       *net.IPNet
+	netLink      netlinkwrapper.NetLink
+}
+
+func newCheckContainerContext(contVethName string, v4Addr *net.IPNet, v6Addr *net.IPNet) *checkContainerContext {
+	return &checkContainerContext{
+		contVethName: contVethName,
+		v4Addr:       v4Addr,
+		v6Addr:       v6Addr,
+		netLink:      netlinkwrapper.NewNetLink(),
+	}
+}
+
+// run defines the closure to execute within the container's namespace to verify its veth
+func (checkContext *checkContainerContext) run(_ ns.NetNS) error {
+	contVeth, err := checkContext.netLink.LinkByName(checkContext.contVethName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find link %q", checkContext.contVethName)
+	}
+	if contVeth.Attrs().Flags&net.FlagUp == 0 {
+		return errors.Errorf("link %q is down", checkContext.contVethName)
+	}
+
+	for _, containerAddr := range []*net.IPNet{checkContext.v4Addr, checkContext.v6Addr} {
+		if containerAddr == nil {
+			continue
+		}
+		family, gw := unix.AF_INET, net.IPv4(169, 254, 1, 1)
+		if containerAddr.IP.To4() == nil {
+			family, gw = unix.AF_INET6, net.IP{0xfe, 0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+		}
+		addrs, err := checkContext.netLink.AddrList(contVeth, family)
+		if err != nil {
+			return errors.Wrapf(err, "failed to list the addresses of %q", checkContext.contVethName)
+		}
+		if !lo.ContainsBy(addrs, func(addr netlink.Addr) bool { return addr.IPNet.String() == containerAddr.String() }) {
+			return errors.Errorf("address %s is missing on %q", containerAddr, checkContext.contVethName)
+		}
+		routes, err := checkContext.netLink.RouteList(contVeth, family)
+		if err != nil {
+			return errors.Wrapf(err, "failed to list the routes of %q", checkContext.contVethName)
+		}
+		if !lo.ContainsBy(routes, func(route netlink.Route) bool { return isDefaultRoute(route) && route.Gw.Equal(gw) }) {
+			return errors.Errorf("default route via %s is missing on %q", gw, checkContext.contVethName)
+		}
+	}
+	return nil
+}
+
+func isDefaultRoute(route netlink.Route) bool {
+	if route.Dst == nil {
+		return true
+	}
+	ones, _ := route.Dst.Mask.Size()
+	return ones == 0
+}
+
+// checkContainerRoutes verifies the routes and rules set up by setupContainerRoutes
+func (n *linuxNetwork) checkContainerRoutes(hostVeth netlink.Link, containerAddr *net.IPNet, rtTable int, podDatapath datapath.Mode) error {
+	if podDatapath == datapath.ModeEBPF {
+		return n.checkEBPFContainerRoutes(hostVeth, containerAddr, rtTable)
+	}
+	return n.checkIPBasedContainerRouteRules(hostVeth, containerAddr, rtTable)
+}
+
+// checkIPBasedContainerRouteRules verifies the route and rules set up by setupIPBasedContainerRouteRules
+func (n *linuxNetwork) checkIPBasedContainerRouteRules(hostVeth netlink.Link, containerAddr *net.IPNet, rtTable int) error {
+	family := unix.AF_INET
+	if containerAddr.IP.To4() == nil {
+		family = unix.AF_INET6
+	}
+	routes, err := n.netLink.RouteList(hostVeth, family)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list the routes of hostVeth %s", hostVeth.Attrs().Name)
+	}
+	if !lo.ContainsBy(routes, func(route netlink.Route) bool { return ipNetEqual(route.Dst, containerAddr) }) {
+		return errors.Errorf("route to %s via hostVeth %s is missing", containerAddr, hostVeth.Attrs().Name)
+	}
+
+	rules, err := n.netLink.RuleList(family)
+	if err != nil {
+		return errors.Wrap(err, "failed to list rules")
+	}
+	if !lo.ContainsBy(rules, func(rule netlink.Rule) bool {
+		return ipNetEqual(rule.Dst, containerAddr) && rule.Priority == networkutils.ToContainerRulePriority &&
+			rule.Table == unix.RT_TABLE_MAIN
+	}) {
+		return errors.Errorf("toContainer rule for %s is missing", containerAddr)
+	}
+	if rtTable != unix.RT_TABLE_MAIN && !lo.ContainsBy(rules, func(rule netlink.Rule) bool {
+		return ipNetEqual(rule.Src, containerAddr) && rule.Priority == networkutils.FromPodRulePriority && rule.Table == rtTable
+	}) {
+		return errors.Errorf("fromContainer rule for %s to table %d is missing", containerAddr, rtTable)
+	}
+	return nil
+}
+
+// checkIIFBasedContainerRules verifies the rules set up by setupIIFBasedContainerRouteRules
+func (n *linuxNetwork) checkIIFBasedContainerRules(hostVeth netlink.Link, containerAddr *net.IPNet, vlanLinkName string, rtTable int) error {
+	family := unix.AF_INET
+	if containerAddr.IP.To4() == nil {
+		family = unix.AF_INET6
+	}
+	rules, err := n.netLink.RuleList(family)
+	if err != nil {
+		return errors.Wrap(err, "failed to list rules")
+	}
+	for _, iifName := range []string{vlanLinkName, hostVeth.Attrs().Name} {
+		if !lo.ContainsBy(rules, func(rule netlink.Rule) bool {
+			return rule.IifName == iifName && rule.Priority == networkutils.VlanRulePriority && rule.Table == rtTable
+		}) {
+			return errors.Errorf("rule from %s to table %d is missing", iifName, rtTable)
+		}
+	}
+	return nil
+}
+
+func ipNetEqual(a *net.IPNet, b *net.IPNet) bool {
+	return a != nil && b != nil && a.String() == b.String()
+}
+
 // setupVeth sets up veth for the pod.
 func (n *linuxNetwork) setupVeth(hostVethName string, contVethName string, netnsPath string, v4Addr *net.IPNet, v6Addr *net.IPNet, mtu int, log logger.Logger) (netlink.Link, error) {
+	return n.setupVethPair(newCreateVethPairContext(contVethName, hostVethName, v4Addr, v6Addr, mtu), netnsPath, log)
+}
+
+// setupVethPair creates the veth pair of createVethContext and sets up its host end.
+func (n *linuxNetwork) setupVethPair(createVethContext *createVethPairContext, netnsPath string, log logger.Logger) (netlink.Link, error) {
+	hostVethName := createVethContext.hostVethName
 	// Clean up if hostVeth exists.
 	if oldHostVeth, err := n.netLink.LinkByName(hostVethName); err == nil {
 		if err = n.netLink.LinkDel(oldHostVeth); err != nil {
@@ -357,7 +690,6 @@ func (n *linuxNetwork) setupVeth(hostVethName string, contVethName string, netns
 		log.Debugf("Successfully deleted old hostVeth %s", hostVethName)
 	}
 
-	createVethContext := newCreateVethPairContext(contVethName, hostVethName, v4Addr, v6Addr, mtu)
 	if err := n.ns.WithNetNSPath(netnsPath, createVethContext.run); err != nil {
 		return nil, errors.Wrap(err, "failed to setup veth network")
 	}
@@ -467,6 +799,14 @@ func (n *linuxNetwork) teardownVlan(vlanID int, log logger.Logger) error {
 	return nil
 }
 
+// setupContainerRoutes setups the routes and route rules for containers with podDatapath
+func (n *linuxNetwork) setupContainerRoutes(hostVeth netlink.Link, containerAddr *net.IPNet, rtTable int, podDatapath datapath.Mode, log logger.Logger) error {
+	if podDatapath == datapath.ModeEBPF {
+		return errors.Wrap(n.setupEBPFContainerRoutes(hostVeth, containerAddr, rtTable, log), "unable to setup eBPF based container routes")
+	}
+	return errors.Wrap(n.setupIPBasedContainerRouteRules(hostVeth, containerAddr, rtTable, log), "unable to setup IP based container routes and rules")
+}
+
 // setupIPBasedContainerRouteRules setups the routes and route rules for containers based on IP.
 // traffic to container(to containerAddr) will be routed via the `main` route table.
 // traffic from container(from containerAddr) will be routed via the specified rtTable.
@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/cninswrapper/mock_ns"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/datapath"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/netlinkwrapper/mock_netlink"
 	mock_netlinkwrapper "github.com/nholuongut/amazon-vpc-cni-k8s/pkg/netlinkwrapper/mocks"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/networkutils"
@@ -62,6 +63,33 @@ func Test_linuxNetwork_SetupPodNetwork(t *testing.T) {
 	fromContainerRuleForRTTable4.Priority = networkutils.FromPodRulePriority
 	fromContainerRuleForRTTable4.Table = 4
 
+	containerV6Addr := &net.IPNet{
+		IP:   net.ParseIP("2001:db8::42"),
+		Mask: net.CIDRMask(128, 128),
+	}
+
+	ifbName := buildIfbLinkName(containerAddr)
+	ifbWithIndex10 := &netlink.Ifb{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:  ifbName,
+			Index: 10,
+		},
+	}
+
+	toContainerV6Rule := netlink.NewRule()
+	toContainerV6Rule.Dst = containerV6Addr
+	toContainerV6Rule.Priority = networkutils.ToContainerRulePriority
+	toContainerV6Rule.Table = unix.RT_TABLE_MAIN
+
+	clsactWithIndex9 := &netlink.GenericQdisc{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: hostVethWithIndex9.Index,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_CLSACT,
+		},
+		QdiscType: "clsact",
+	}
+
 	type linkByNameCall struct {
 		linkName string
 		link     netlink.Link
@@ -92,6 +120,23 @@ func Test_linuxNetwork_SetupPodNetwork(t *testing.T) {
 		value string
 		err   error
 	}
+	type linkAddCall struct {
+		link netlink.Link
+		err  error
+	}
+	type qdiscAddCall struct {
+		qdisc netlink.Qdisc
+		err   error
+	}
+	type filterAddCall struct {
+		filter netlink.Filter
+		err    error
+	}
+	type bpfProgLoadCall struct {
+		insns []uint64
+		fd    int
+		err   error
+	}
 
 	type fields struct {
 		linkByNameCalls    []linkByNameCall
@@ -101,6 +146,10 @@ func Test_linuxNetwork_SetupPodNetwork(t *testing.T) {
 		procSysSetCalls    []procSysSetCall
 		routeReplaceCalls  []routeReplaceCall
 		ruleAddCalls       []ruleAddCall
+		linkAddCalls       []linkAddCall
+		qdiscAddCalls      []qdiscAddCall
+		filterAddCalls     []filterAddCall
+		bpfProgLoadCalls   []bpfProgLoadCall
 	}
 	type args struct {
 		hostVethName string
@@ -110,6 +159,8 @@ func Test_linuxNetwork_SetupPodNetwork(t *testing.T) {
 		v6Addr       *net.IPNet
 		deviceNumber int
 		mtu          int
+		bandwidth    Bandwidth
+		podDatapath  datapath.Mode
 	}
 	tests := []struct {
 		name    string
@@ -117,6 +168,311 @@ func Test_linuxNetwork_SetupPodNetwork(t *testing.T) {
 		args    args
 		wantErr error
 	}{
+		{
+			name: "successfully setup pod network with eBPF datapath - pod sponsored by eth0",
+			fields: fields{
+				linkByNameCalls: []linkByNameCall{
+					{
+						linkName: "eni8ea2c11fe35",
+						err:      errors.New("not exists"),
+					},
+					{
+						linkName: "eni8ea2c11fe35",
+						link:     hostVethWithIndex9,
+					},
+				},
+				linkSetupCalls: []linkSetupCall{
+					{
+						link: hostVethWithIndex9,
+					},
+				},
+				routeReplaceCalls: []routeReplaceCall{
+					{
+						route: &netlink.Route{
+							LinkIndex: hostVethWithIndex9.Index,
+							Scope:     netlink.SCOPE_LINK,
+							Dst:       containerAddr,
+							Table:     networkutils.PodRouteTable,
+						},
+					},
+				},
+				withNetNSPathCalls: []withNetNSPathCall{
+					{
+						netNSPath: "/proc/42/ns/net",
+					},
+				},
+				procSysSetCalls: []procSysSetCall{
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/accept_ra",
+						value: "0",
+					},
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/accept_redirects",
+						value: "1",
+					},
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/forwarding",
+						value: "0",
+					},
+				},
+			},
+			args: args{
+				hostVethName: "eni8ea2c11fe35",
+				contVethName: "eth0",
+				netnsPath:    "/proc/42/ns/net",
+				v4Addr:       containerAddr,
+				deviceNumber: 0,
+				mtu:          9001,
+				podDatapath:  datapath.ModeEBPF,
+			},
+		},
+		{
+			name: "successfully setup pod network with eBPF datapath - pod sponsored by eth3",
+			fields: fields{
+				linkByNameCalls: []linkByNameCall{
+					{
+						linkName: "eni8ea2c11fe35",
+						err:      errors.New("not exists"),
+					},
+					{
+						linkName: "eni8ea2c11fe35",
+						link:     hostVethWithIndex9,
+					},
+				},
+				linkSetupCalls: []linkSetupCall{
+					{
+						link: hostVethWithIndex9,
+					},
+				},
+				routeReplaceCalls: []routeReplaceCall{
+					{
+						route: &netlink.Route{
+							LinkIndex: hostVethWithIndex9.Index,
+							Scope:     netlink.SCOPE_LINK,
+							Dst:       containerAddr,
+							Table:     networkutils.PodRouteTable,
+						},
+					},
+				},
+				withNetNSPathCalls: []withNetNSPathCall{
+					{
+						netNSPath: "/proc/42/ns/net",
+					},
+				},
+				procSysSetCalls: []procSysSetCall{
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/accept_ra",
+						value: "0",
+					},
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/accept_redirects",
+						value: "1",
+					},
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/forwarding",
+						value: "0",
+					},
+				},
+				qdiscAddCalls: []qdiscAddCall{
+					{
+						qdisc: clsactWithIndex9,
+						err:   syscall.EEXIST,
+					},
+				},
+				bpfProgLoadCalls: []bpfProgLoadCall{
+					{
+						insns: buildPodRouteMarkProg(0x40000),
+						fd:    42,
+					},
+				},
+				filterAddCalls: []filterAddCall{
+					{
+						filter: buildPodRouteMarkFilter(hostVethWithIndex9.Index, 42),
+					},
+				},
+			},
+			args: args{
+				hostVethName: "eni8ea2c11fe35",
+				contVethName: "eth0",
+				netnsPath:    "/proc/42/ns/net",
+				v4Addr:       containerAddr,
+				deviceNumber: 3,
+				mtu:          9001,
+				podDatapath:  datapath.ModeEBPF,
+			},
+		},
+		{
+			name: "failed to load program of eBPF datapath",
+			fields: fields{
+				linkByNameCalls: []linkByNameCall{
+					{
+						linkName: "eni8ea2c11fe35",
+						err:      errors.New("not exists"),
+					},
+					{
+						linkName: "eni8ea2c11fe35",
+						link:     hostVethWithIndex9,
+					},
+				},
+				linkSetupCalls: []linkSetupCall{
+					{
+						link: hostVethWithIndex9,
+					},
+				},
+				routeReplaceCalls: []routeReplaceCall{
+					{
+						route: &netlink.Route{
+							LinkIndex: hostVethWithIndex9.Index,
+							Scope:     netlink.SCOPE_LINK,
+							Dst:       containerAddr,
+							Table:     networkutils.PodRouteTable,
+						},
+					},
+				},
+				withNetNSPathCalls: []withNetNSPathCall{
+					{
+						netNSPath: "/proc/42/ns/net",
+					},
+				},
+				procSysSetCalls: []procSysSetCall{
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/accept_ra",
+						value: "0",
+					},
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/accept_redirects",
+						value: "1",
+					},
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/forwarding",
+						value: "0",
+					},
+				},
+				qdiscAddCalls: []qdiscAddCall{
+					{
+						qdisc: clsactWithIndex9,
+					},
+				},
+				bpfProgLoadCalls: []bpfProgLoadCall{
+					{
+						insns: buildPodRouteMarkProg(0x40000),
+						err:   syscall.EPERM,
+					},
+				},
+			},
+			args: args{
+				hostVethName: "eni8ea2c11fe35",
+				contVethName: "eth0",
+				netnsPath:    "/proc/42/ns/net",
+				v4Addr:       containerAddr,
+				deviceNumber: 3,
+				mtu:          9001,
+				podDatapath:  datapath.ModeEBPF,
+			},
+			wantErr: errors.New("SetupPodNetwork: unable to setup eBPF based container routes: failed to load pod_route_mark program: operation not permitted"),
+		},
+		{
+			name: "successfully setup pod network with bandwidth limits",
+			fields: fields{
+				linkByNameCalls: []linkByNameCall{
+					{
+						linkName: "eni8ea2c11fe35",
+						err:      errors.New("not exists"),
+					},
+					{
+						linkName: "eni8ea2c11fe35",
+						link:     hostVethWithIndex9,
+					},
+					{
+						linkName: ifbName,
+						err:      errors.New("not exists"),
+					},
+					{
+						linkName: ifbName,
+						link:     ifbWithIndex10,
+					},
+				},
+				linkSetupCalls: []linkSetupCall{
+					{
+						link: hostVethWithIndex9,
+					},
+					{
+						link: ifbWithIndex10,
+					},
+				},
+				routeReplaceCalls: []routeReplaceCall{
+					{
+						route: &netlink.Route{
+							LinkIndex: hostVethWithIndex9.Index,
+							Scope:     netlink.SCOPE_LINK,
+							Dst:       containerAddr,
+							Table:     unix.RT_TABLE_MAIN,
+						},
+					},
+				},
+				ruleAddCalls: []ruleAddCall{
+					{
+						rule: toContainerRule,
+					},
+				},
+				withNetNSPathCalls: []withNetNSPathCall{
+					{
+						netNSPath: "/proc/42/ns/net",
+					},
+				},
+				procSysSetCalls: []procSysSetCall{
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/accept_ra",
+						value: "0",
+					},
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/accept_redirects",
+						value: "1",
+					},
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/forwarding",
+						value: "0",
+					},
+				},
+				linkAddCalls: []linkAddCall{
+					{
+						link: &netlink.Ifb{
+							LinkAttrs: netlink.LinkAttrs{
+								Name:   ifbName,
+								Flags:  net.FlagUp,
+								MTU:    9001,
+								TxQLen: 1000,
+							},
+						},
+					},
+				},
+				qdiscAddCalls: []qdiscAddCall{
+					{
+						qdisc: newTBF(hostVethWithIndex9.Index, 10_000_000),
+					},
+					{
+						qdisc: newTBF(ifbWithIndex10.Index, 20_000_000),
+					},
+					{
+						qdisc: clsactWithIndex9,
+					},
+				},
+				filterAddCalls: []filterAddCall{
+					{
+						filter: buildIfbRedirectFilter(hostVethWithIndex9.Index, ifbWithIndex10.Index),
+					},
+				},
+			},
+			args: args{
+				hostVethName: "eni8ea2c11fe35",
+				contVethName: "eth0",
+				netnsPath:    "/proc/42/ns/net",
+				v4Addr:       containerAddr,
+				deviceNumber: 0,
+				mtu:          9001,
+				bandwidth:    Bandwidth{Ingress: 10_000_000, Egress: 20_000_000},
+			},
+		},
 		{
 			name: "successfully setup pod network - pod sponsored by eth0",
 			fields: fields{
@@ -180,6 +536,83 @@ func Test_linuxNetwork_SetupPodNetwork(t *testing.T) {
 				mtu:          9001,
 			},
 		},
+		{
+			name: "successfully setup dual stack pod network - IPv4 sponsored by eth3",
+			fields: fields{
+				linkByNameCalls: []linkByNameCall{
+					{
+						linkName: "eni8ea2c11fe35",
+						err:      errors.New("not exists"),
+					},
+					{
+						linkName: "eni8ea2c11fe35",
+						link:     hostVethWithIndex9,
+					},
+				},
+				linkSetupCalls: []linkSetupCall{
+					{
+						link: hostVethWithIndex9,
+					},
+				},
+				routeReplaceCalls: []routeReplaceCall{
+					{
+						route: &netlink.Route{
+							LinkIndex: hostVethWithIndex9.Index,
+							Scope:     netlink.SCOPE_LINK,
+							Dst:       containerAddr,
+							Table:     unix.RT_TABLE_MAIN,
+						},
+					},
+					{
+						route: &netlink.Route{
+							LinkIndex: hostVethWithIndex9.Index,
+							Scope:     netlink.SCOPE_LINK,
+							Dst:       containerV6Addr,
+							Table:     unix.RT_TABLE_MAIN,
+						},
+					},
+				},
+				ruleAddCalls: []ruleAddCall{
+					{
+						rule: toContainerRule,
+					},
+					{
+						rule: fromContainerRuleForRTTable4,
+					},
+					{
+						rule: toContainerV6Rule,
+					},
+				},
+				withNetNSPathCalls: []withNetNSPathCall{
+					{
+						netNSPath: "/proc/42/ns/net",
+					},
+				},
+				procSysSetCalls: []procSysSetCall{
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/accept_ra",
+						value: "0",
+					},
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/accept_redirects",
+						value: "1",
+					},
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/forwarding",
+						value: "0",
+					},
+				},
+			},
+			args: args{
+				hostVethName: "eni8ea2c11fe35",
+				contVethName: "eth0",
+				netnsPath:    "/proc/42/ns/net",
+				v4Addr:       containerAddr,
+				v6Addr:       containerV6Addr,
+				deviceNumber: 3,
+				mtu:          9001,
+			},
+		},
 		{
 			name: "successfully setup pod network - pod sponsored by eth3",
 			fields: fields{
@@ -357,6 +790,18 @@ func Test_linuxNetwork_SetupPodNetwork(t *testing.T) {
 			for _, call := range tt.fields.ruleAddCalls {
 				netLink.EXPECT().RuleAdd(call.rule).Return(call.err)
 			}
+			for _, call := range tt.fields.linkAddCalls {
+				netLink.EXPECT().LinkAdd(call.link).Return(call.err)
+			}
+			for _, call := range tt.fields.qdiscAddCalls {
+				netLink.EXPECT().QdiscAdd(call.qdisc).Return(call.err)
+			}
+			for _, call := range tt.fields.filterAddCalls {
+				netLink.EXPECT().FilterAdd(call.filter).Return(call.err)
+			}
+			for _, call := range tt.fields.bpfProgLoadCalls {
+				netLink.EXPECT().BpfProgLoad(netlink.BPF_PROG_TYPE_SCHED_CLS, call.insns, podRouteMarkProgLicense).Return(call.fd, call.err)
+			}
 
 			ns := mock_nswrapper.NewMockNS(ctrl)
 			for _, call := range tt.fields.withNetNSPathCalls {
@@ -374,7 +819,8 @@ func Test_linuxNetwork_SetupPodNetwork(t *testing.T) {
 				ns:      ns,
 				procSys: procSys,
 			}
-			err := n.SetupPodNetwork(tt.args.hostVethName, tt.args.contVethName, tt.args.netnsPath, tt.args.v4Addr, tt.args.v6Addr, tt.args.deviceNumber, tt.args.mtu, testLogger)
+			err := n.SetupPodNetwork(tt.args.hostVethName, tt.args.contVethName, tt.args.netnsPath, tt.args.v4Addr, tt.args.v6Addr, tt.args.deviceNumber, tt.args.mtu, tt.args.bandwidth,
+				tt.args.podDatapath, testLogger)
 			if tt.wantErr != nil {
 				assert.EqualError(t, err, tt.wantErr.Error())
 			} else {
@@ -395,6 +841,11 @@ func Test_linuxNetwork_TeardownPodNetwork(t *testing.T) {
 		Dst:   containerAddr,
 		Table: unix.RT_TABLE_MAIN,
 	}
+	toContainerPodRouteTableRoute := &netlink.Route{
+		Scope: netlink.SCOPE_LINK,
+		Dst:   containerAddr,
+		Table: networkutils.PodRouteTable,
+	}
 	toContainerRule := netlink.NewRule()
 	toContainerRule.Dst = containerAddr
 	toContainerRule.Priority = networkutils.ToContainerRulePriority
@@ -404,6 +855,13 @@ func Test_linuxNetwork_TeardownPodNetwork(t *testing.T) {
 	fromContainerRuleForRTTable4.Src = containerAddr
 	fromContainerRuleForRTTable4.Priority = networkutils.FromPodRulePriority
 	fromContainerRuleForRTTable4.Table = 4
+	ifbName := buildIfbLinkName(containerAddr)
+	ifbWithIndex10 := &netlink.Ifb{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:  ifbName,
+			Index: 10,
+		},
+	}
 	type routeDelCall struct {
 		route *netlink.Route
 		err   error
@@ -412,9 +870,20 @@ func Test_linuxNetwork_TeardownPodNetwork(t *testing.T) {
 		rule *netlink.Rule
 		err  error
 	}
+	type linkByNameCall struct {
+		linkName string
+		link     netlink.Link
+		err      error
+	}
+	type linkDelCall struct {
+		link netlink.Link
+		err  error
+	}
 	type fields struct {
-		routeDelCalls []routeDelCall
-		ruleDelCalls  []ruleDelCall
+		routeDelCalls   []routeDelCall
+		ruleDelCalls    []ruleDelCall
+		linkByNameCalls []linkByNameCall
+		linkDelCalls    []linkDelCall
 	}
 
 	type args struct {
@@ -434,12 +903,56 @@ func Test_linuxNetwork_TeardownPodNetwork(t *testing.T) {
 					{
 						route: toContainerRoute,
 					},
+					{
+						route: toContainerPodRouteTableRoute,
+						err:   syscall.ESRCH,
+					},
 				},
 				ruleDelCalls: []ruleDelCall{
 					{
 						rule: toContainerRule,
 					},
 				},
+				linkByNameCalls: []linkByNameCall{
+					{
+						linkName: ifbName,
+						err:      errors.New("not exists"),
+					},
+				},
+			},
+			args: args{
+				containerAddr: containerAddr,
+				deviceNumber:  0,
+			},
+		},
+		{
+			name: "successfully teardown pod network with bandwidth limits",
+			fields: fields{
+				routeDelCalls: []routeDelCall{
+					{
+						route: toContainerRoute,
+					},
+					{
+						route: toContainerPodRouteTableRoute,
+						err:   syscall.ESRCH,
+					},
+				},
+				ruleDelCalls: []ruleDelCall{
+					{
+						rule: toContainerRule,
+					},
+				},
+				linkByNameCalls: []linkByNameCall{
+					{
+						linkName: ifbName,
+						link:     ifbWithIndex10,
+					},
+				},
+				linkDelCalls: []linkDelCall{
+					{
+						link: ifbWithIndex10,
+					},
+				},
 			},
 			args: args{
 				containerAddr: containerAddr,
@@ -453,6 +966,10 @@ func Test_linuxNetwork_TeardownPodNetwork(t *testing.T) {
 					{
 						route: toContainerRoute,
 					},
+					{
+						route: toContainerPodRouteTableRoute,
+						err:   syscall.ESRCH,
+					},
 				},
 				ruleDelCalls: []ruleDelCall{
 					{
@@ -466,6 +983,12 @@ func Test_linuxNetwork_TeardownPodNetwork(t *testing.T) {
 						err:  syscall.ENOENT,
 					},
 				},
+				linkByNameCalls: []linkByNameCall{
+					{
+						linkName: ifbName,
+						err:      errors.New("not exists"),
+					},
+				},
 			},
 			args: args{
 				containerAddr: containerAddr,
@@ -502,6 +1025,12 @@ func Test_linuxNetwork_TeardownPodNetwork(t *testing.T) {
 			for _, call := range tt.fields.ruleDelCalls {
 				netLink.EXPECT().RuleDel(call.rule).Return(call.err)
 			}
+			for _, call := range tt.fields.linkByNameCalls {
+				netLink.EXPECT().LinkByName(call.linkName).Return(call.link, call.err)
+			}
+			for _, call := range tt.fields.linkDelCalls {
+				netLink.EXPECT().LinkDel(call.link).Return(call.err)
+			}
 
 			n := &linuxNetwork{
 				netLink: netLink,
@@ -631,6 +1160,10 @@ func Test_linuxNetwork_SetupBranchENIPodNetwork(t *testing.T) {
 		value string
 		err   error
 	}
+	type qdiscAddCall struct {
+		qdisc netlink.Qdisc
+		err   error
+	}
 
 	type fields struct {
 		linkByNameCalls    []linkByNameCall
@@ -642,6 +1175,7 @@ func Test_linuxNetwork_SetupBranchENIPodNetwork(t *testing.T) {
 		ruleDelCalls       []ruleDelCall
 		withNetNSPathCalls []withNetNSPathCall
 		procSysSetCalls    []procSysSetCall
+		qdiscAddCalls      []qdiscAddCall
 	}
 	type args struct {
 		hostVethName       string
@@ -654,6 +1188,7 @@ func Test_linuxNetwork_SetupBranchENIPodNetwork(t *testing.T) {
 		subnetGW           string
 		parentIfIndex      int
 		mtu                int
+		bandwidth          Bandwidth
 		podSGEnforcingMode sgpp.EnforcingMode
 	}
 	tests := []struct {
@@ -663,7 +1198,125 @@ func Test_linuxNetwork_SetupBranchENIPodNetwork(t *testing.T) {
 		wantErr error
 	}{
 		{
-			name: "successfully setup pod network - traffic enforced with strict mode",
+			name: "successfully setup pod network - traffic enforced with strict mode",
+			fields: fields{
+				linkByNameCalls: []linkByNameCall{
+					{
+						linkName: "eni8ea2c11fe35",
+						err:      errors.New("not exists"),
+					},
+					{
+						linkName: "eni8ea2c11fe35",
+						link:     hostVethWithIndex9,
+					},
+					{
+						linkName: "vlan.eth.7",
+						err:      errors.New("not exists"),
+					},
+				},
+				linkAddCalls: []linkAddCall{
+					{
+						link:      buildVlanLink("vlan.eth.7", vlanID, parentIfIndex, eniMac),
+						linkIndex: 11,
+					},
+				},
+				linkSetupCalls: []linkSetupCall{
+					{
+						link: hostVethWithIndex9,
+					},
+					{
+						link: vlanLinkPostAddWithIndex11,
+					},
+				},
+				routeReplaceCalls: []routeReplaceCall{
+					{
+						route: &netlink.Route{
+							LinkIndex: vlanLinkPostAddWithIndex11.Index,
+							Dst:       &net.IPNet{IP: net.ParseIP(subnetGW), Mask: net.CIDRMask(32, 32)},
+							Scope:     netlink.SCOPE_LINK,
+							Table:     107,
+						},
+					},
+					{
+						route: &netlink.Route{
+							LinkIndex: vlanLinkPostAddWithIndex11.Index,
+							Dst:       &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)},
+							Scope:     netlink.SCOPE_UNIVERSE,
+							Gw:        net.ParseIP(subnetGW),
+							Table:     107,
+						},
+					},
+					{
+						route: &netlink.Route{
+							LinkIndex: hostVethWithIndex9.Index,
+							Scope:     netlink.SCOPE_LINK,
+							Dst:       containerAddr,
+							Table:     107,
+						},
+					},
+				},
+				ruleAddCalls: []ruleAddCall{
+					{
+						rule: fromHostVlanRule,
+					},
+					{
+						rule: fromHostVethRule,
+					},
+				},
+				ruleDelCalls: []ruleDelCall{
+					{
+						rule: oldFromHostVethRule,
+						err:  syscall.ENOENT,
+					},
+				},
+				withNetNSPathCalls: []withNetNSPathCall{
+					{
+						netNSPath: "/proc/42/ns/net",
+					},
+				},
+				procSysSetCalls: []procSysSetCall{
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/accept_ra",
+						value: "0",
+					},
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/accept_redirects",
+						value: "1",
+					},
+					{
+						key:   "net/ipv6/conf/eni8ea2c11fe35/forwarding",
+						value: "0",
+					},
+					{
+						key:   "net/ipv6/conf/vlan.eth.7/accept_ra",
+						value: "0",
+					},
+					{
+						key:   "net/ipv6/conf/vlan.eth.7/accept_redirects",
+						value: "1",
+					},
+					{
+						key:   "net/ipv6/conf/vlan.eth.7/forwarding",
+						value: "0",
+					},
+				},
+			},
+			args: args{
+				hostVethName:       "eni8ea2c11fe35",
+				contVethName:       "eth0",
+				netnsPath:          "/proc/42/ns/net",
+				v4Addr:             containerAddr,
+				v6Addr:             nil,
+				vlanID:             vlanID,
+				eniMAC:             eniMac,
+				subnetGW:           subnetGW,
+				parentIfIndex:      parentIfIndex,
+				mtu:                9001,
+				podSGEnforcingMode: sgpp.EnforcingModeStrict,
+			},
+		},
+		{
+			name: "successfully setup pod network with bandwidth limits - traffic enforced with strict mode",
 			fields: fields{
 				linkByNameCalls: []linkByNameCall{
 					{
@@ -734,6 +1387,14 @@ func Test_linuxNetwork_SetupBranchENIPodNetwork(t *testing.T) {
 						err:  syscall.ENOENT,
 					},
 				},
+				qdiscAddCalls: []qdiscAddCall{
+					{
+						qdisc: newTBF(hostVethWithIndex9.Index, 10_000_000),
+					},
+					{
+						qdisc: newTBF(vlanLinkPostAddWithIndex11.Index, 20_000_000),
+					},
+				},
 				withNetNSPathCalls: []withNetNSPathCall{
 					{
 						netNSPath: "/proc/42/ns/net",
@@ -777,6 +1438,7 @@ func Test_linuxNetwork_SetupBranchENIPodNetwork(t *testing.T) {
 				subnetGW:           subnetGW,
 				parentIfIndex:      parentIfIndex,
 				mtu:                9001,
+				bandwidth:          Bandwidth{Ingress: 10_000_000, Egress: 20_000_000},
 				podSGEnforcingMode: sgpp.EnforcingModeStrict,
 			},
 		},
@@ -1554,6 +2216,9 @@ func Test_linuxNetwork_SetupBranchENIPodNetwork(t *testing.T) {
 			for _, call := range tt.fields.ruleDelCalls {
 				netLink.EXPECT().RuleDel(call.rule).Return(call.err)
 			}
+			for _, call := range tt.fields.qdiscAddCalls {
+				netLink.EXPECT().QdiscAdd(call.qdisc).Return(call.err)
+			}
 
 			ns := mock_nswrapper.NewMockNS(ctrl)
 			for _, call := range tt.fields.withNetNSPathCalls {
@@ -1571,7 +2236,7 @@ func Test_linuxNetwork_SetupBranchENIPodNetwork(t *testing.T) {
 				ns:      ns,
 				procSys: procSys,
 			}
-			err := n.SetupBranchENIPodNetwork(tt.args.hostVethName, tt.args.contVethName, tt.args.netnsPath, tt.args.v4Addr, tt.args.v6Addr, tt.args.vlanID, tt.args.eniMAC, tt.args.subnetGW, tt.args.parentIfIndex, tt.args.mtu, tt.args.podSGEnforcingMode, testLogger)
+			err := n.SetupBranchENIPodNetwork(tt.args.hostVethName, tt.args.contVethName, tt.args.netnsPath, tt.args.v4Addr, tt.args.v6Addr, tt.args.vlanID, tt.args.eniMAC, tt.args.subnetGW, tt.args.parentIfIndex, tt.args.mtu, tt.args.bandwidth, tt.args.podSGEnforcingMode, testLogger)
 			if tt.wantErr != nil {
 				assert.EqualError(t, err, tt.wantErr.Error())
 			} else {
@@ -1975,84 +2640,202 @@ func Test_createVethPairContext_run(t *testing.T) {
 			Index:        9,
 			HardwareAddr: net.HardwareAddr("00:00:5e:00:53:af"),
 		},
-	}
-
-	type linkByNameCall struct {
-		linkName string
-		link     netlink.Link
-		err      error
-	}
-	type linkAddCall struct {
-		link netlink.Link
-		err  error
-	}
-	type linkSetupCall struct {
-		link netlink.Link
-		err  error
-	}
-	type routeReplaceCall struct {
-		route *netlink.Route
-		err   error
-	}
-	type routeAddCall struct {
-		route *netlink.Route
-		err   error
-	}
-	type addrAddCall struct {
-		link netlink.Link
-		addr *netlink.Addr
-		err  error
-	}
-	type addrListCall struct {
-		link   netlink.Link
-		family int
-		addrs  []netlink.Addr
-		err    error
-	}
-	type neighAddCall struct {
-		neigh *netlink.Neigh
-		err   error
-	}
-	type linkSetNsFdCall struct {
-		link netlink.Link
-		fd   int
-		err  error
-	}
-	type procSysSetCall struct {
-		key   string
-		value string
-		err   error
-	}
-	type nsFDCall struct {
-		fd uintptr
-	}
-
-	type fields struct {
-		linkByNameCalls   []linkByNameCall
-		linkAddCalls      []linkAddCall
-		linkSetupCalls    []linkSetupCall
-		routeReplaceCalls []routeReplaceCall
-		routeAddCalls     []routeAddCall
-		addrAddCalls      []addrAddCall
-		addrListCalls     []addrListCall
-		neighAddCalls     []neighAddCall
-		linkSetNsFdCalls  []linkSetNsFdCall
-		procSysSetCalls   []procSysSetCall
-		nsFDCalls         []nsFDCall
-	}
-	type args struct {
-		contVethName string
-		hostVethName string
-		v4Addr       *net.IPNet
-		v6Addr       *net.IPNet
-		mtu          int
-	}
-	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		wantErr error
-	}{
+	}
+
+	type linkByNameCall struct {
+		linkName string
+		link     netlink.Link
+		err      error
+	}
+	type linkAddCall struct {
+		link netlink.Link
+		err  error
+	}
+	type linkSetupCall struct {
+		link netlink.Link
+		err  error
+	}
+	type routeReplaceCall struct {
+		route *netlink.Route
+		err   error
+	}
+	type routeAddCall struct {
+		route *netlink.Route
+		err   error
+	}
+	type addrAddCall struct {
+		link netlink.Link
+		addr *netlink.Addr
+		err  error
+	}
+	type addrListCall struct {
+		link   netlink.Link
+		family int
+		addrs  []netlink.Addr
+		err    error
+	}
+	type neighAddCall struct {
+		neigh *netlink.Neigh
+		err   error
+	}
+	type ruleAddCall struct {
+		rule *netlink.Rule
+		err  error
+	}
+	type linkSetNsFdCall struct {
+		link netlink.Link
+		fd   int
+		err  error
+	}
+	type procSysSetCall struct {
+		key   string
+		value string
+		err   error
+	}
+	type nsFDCall struct {
+		fd uintptr
+	}
+
+	type fields struct {
+		linkByNameCalls   []linkByNameCall
+		linkAddCalls      []linkAddCall
+		linkSetupCalls    []linkSetupCall
+		routeReplaceCalls []routeReplaceCall
+		routeAddCalls     []routeAddCall
+		addrAddCalls      []addrAddCall
+		addrListCalls     []addrListCall
+		neighAddCalls     []neighAddCall
+		ruleAddCalls      []ruleAddCall
+		linkSetNsFdCalls  []linkSetNsFdCall
+		procSysSetCalls   []procSysSetCall
+		nsFDCalls         []nsFDCall
+	}
+	type args struct {
+		contVethName   string
+		hostVethName   string
+		v4Addr         *net.IPNet
+		v6Addr         *net.IPNet
+		mtu            int
+		extraInterface bool
+	}
+	extraInterfaceAddr := &net.IPNet{
+		IP:   net.ParseIP("192.168.130.7"),
+		Mask: net.CIDRMask(32, 32),
+	}
+	fromExtraInterfaceRule := netlink.NewRule()
+	fromExtraInterfaceRule.Src = extraInterfaceAddr
+	fromExtraInterfaceRule.Priority = networkutils.FromPodRulePriority
+	fromExtraInterfaceRule.Table = 1001
+
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantErr error
+	}{
+		{
+			name: "successfully created vethPair for an extra interface",
+			fields: fields{
+				linkByNameCalls: []linkByNameCall{
+					{
+						linkName: "eni8ea2c11fe35",
+						link:     hostVethWithIndex9,
+					},
+					{
+						linkName: "eth0",
+						link:     contVethWithIndex1,
+					},
+				},
+				linkAddCalls: []linkAddCall{
+					{
+						link: &netlink.Veth{
+							LinkAttrs: netlink.LinkAttrs{
+								Name:  "eth0",
+								Flags: net.FlagUp,
+								MTU:   9001,
+							},
+							PeerName: "eni8ea2c11fe35",
+						},
+					},
+				},
+				linkSetupCalls: []linkSetupCall{
+					{
+						link: hostVethWithIndex9,
+					},
+					{
+						link: contVethWithIndex1,
+					},
+				},
+				routeReplaceCalls: []routeReplaceCall{
+					{
+						route: &netlink.Route{
+							LinkIndex: contVethWithIndex1.Attrs().Index,
+							Scope:     netlink.SCOPE_LINK,
+							Dst: &net.IPNet{
+								IP:   net.IPv4(169, 254, 1, 1),
+								Mask: net.CIDRMask(32, 32),
+							},
+							Table: 1001,
+						},
+					},
+				},
+				routeAddCalls: []routeAddCall{
+					{
+						route: &netlink.Route{
+							LinkIndex: contVethWithIndex1.Attrs().Index,
+							Scope:     netlink.SCOPE_UNIVERSE,
+							Dst: &net.IPNet{
+								IP:   net.IPv4zero,
+								Mask: net.CIDRMask(0, 32),
+							},
+							Gw:    net.IPv4(169, 254, 1, 1),
+							Table: 1001,
+						},
+					},
+				},
+				ruleAddCalls: []ruleAddCall{
+					{
+						rule: fromExtraInterfaceRule,
+					},
+				},
+				addrAddCalls: []addrAddCall{
+					{
+						link: contVethWithIndex1,
+						addr: &netlink.Addr{
+							IPNet: extraInterfaceAddr,
+						},
+					},
+				},
+				neighAddCalls: []neighAddCall{
+					{
+						neigh: &netlink.Neigh{
+							LinkIndex:    contVethWithIndex1.Attrs().Index,
+							State:        netlink.NUD_PERMANENT,
+							IP:           net.IPv4(169, 254, 1, 1),
+							HardwareAddr: hostVethWithIndex9.Attrs().HardwareAddr,
+						},
+					},
+				},
+				linkSetNsFdCalls: []linkSetNsFdCall{
+					{
+						link: hostVethWithIndex9,
+						fd:   3,
+					},
+				},
+				nsFDCalls: []nsFDCall{
+					{
+						fd: uintptr(3),
+					},
+				},
+			},
+			args: args{
+				contVethName:   "eth0",
+				hostVethName:   "eni8ea2c11fe35",
+				v4Addr:         extraInterfaceAddr,
+				mtu:            9001,
+				extraInterface: true,
+			},
+		},
 		{
 			name: "successfully created vethPair for ipv4 pods",
 			fields: fields{
@@ -3120,6 +3903,7 @@ func Test_createVethPairContext_run(t *testing.T) {
 			defer ctrl.Finish()
 
 			netLink := mock_netlinkwrapper.NewMockNetLink(ctrl)
+			netLink.EXPECT().NewRule().DoAndReturn(func() *netlink.Rule { return netlink.NewRule() }).AnyTimes()
 			for _, call := range tt.fields.linkByNameCalls {
 				netLink.EXPECT().LinkByName(call.linkName).Return(call.link, call.err)
 			}
@@ -3144,6 +3928,9 @@ func Test_createVethPairContext_run(t *testing.T) {
 			for _, call := range tt.fields.neighAddCalls {
 				netLink.EXPECT().NeighAdd(call.neigh).Return(call.err)
 			}
+			for _, call := range tt.fields.ruleAddCalls {
+				netLink.EXPECT().RuleAdd(call.rule).Return(call.err)
+			}
 			for _, call := range tt.fields.linkSetNsFdCalls {
 				netLink.EXPECT().LinkSetNsFd(call.link, call.fd).Return(call.err)
 			}
@@ -3159,13 +3946,14 @@ func Test_createVethPairContext_run(t *testing.T) {
 			}
 
 			createVethContext := &createVethPairContext{
-				contVethName: tt.args.contVethName,
-				hostVethName: tt.args.hostVethName,
-				v4Addr:       tt.args.v4Addr,
-				v6Addr:       tt.args.v6Addr,
-				mtu:          tt.args.mtu,
-				netLink:      netLink,
-				procSys:      procSys,
+				contVethName:   tt.args.contVethName,
+				hostVethName:   tt.args.hostVethName,
+				v4Addr:         tt.args.v4Addr,
+				v6Addr:         tt.args.v6Addr,
+				mtu:            tt.args.mtu,
+				netLink:        netLink,
+				procSys:        procSys,
+				extraInterface: tt.args.extraInterface,
 			}
 			err := createVethContext.run(hostNS)
 			if tt.wantErr != nil {
@@ -3177,6 +3965,250 @@ func Test_createVethPairContext_run(t *testing.T) {
 	}
 }
 
+func Test_linuxNetwork_CheckPodNetwork(t *testing.T) {
+	hostVeth := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:  "eni8ea2c11fe35",
+			Index: 9,
+			Flags: net.FlagUp,
+		},
+	}
+	downHostVeth := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:  "eni8ea2c11fe35",
+			Index: 9,
+		},
+	}
+	containerAddr := &net.IPNet{
+		IP:   net.ParseIP("192.168.100.42"),
+		Mask: net.CIDRMask(32, 32),
+	}
+
+	type linkByNameCall struct {
+		linkName string
+		link     netlink.Link
+		err      error
+	}
+	type withNetNSPathCall struct {
+		netNSPath string
+		err       error
+	}
+	type fields struct {
+		linkByNameCalls    []linkByNameCall
+		withNetNSPathCalls []withNetNSPathCall
+		routes             []netlink.Route
+		rules              []netlink.Rule
+		filters            []netlink.Filter
+	}
+	type args struct {
+		deviceNumber int
+		podDatapath  datapath.Mode
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantErr error
+	}{
+		{
+			name: "pod network matches - without dedicated route table",
+			fields: fields{
+				linkByNameCalls:    []linkByNameCall{{linkName: "eni8ea2c11fe35", link: hostVeth}},
+				withNetNSPathCalls: []withNetNSPathCall{{netNSPath: "/proc/42/ns/net"}},
+				routes:             []netlink.Route{{Dst: containerAddr, Scope: netlink.SCOPE_LINK}},
+				rules:              []netlink.Rule{{Dst: containerAddr, Priority: networkutils.ToContainerRulePriority, Table: unix.RT_TABLE_MAIN}},
+			},
+			args: args{
+				deviceNumber: 0,
+			},
+		},
+		{
+			name: "pod network matches - with dedicated route table",
+			fields: fields{
+				linkByNameCalls:    []linkByNameCall{{linkName: "eni8ea2c11fe35", link: hostVeth}},
+				withNetNSPathCalls: []withNetNSPathCall{{netNSPath: "/proc/42/ns/net"}},
+				routes:             []netlink.Route{{Dst: containerAddr, Scope: netlink.SCOPE_LINK}},
+				rules: []netlink.Rule{
+					{Dst: containerAddr, Priority: networkutils.ToContainerRulePriority, Table: unix.RT_TABLE_MAIN},
+					{Src: containerAddr, Priority: networkutils.FromPodRulePriority, Table: 4},
+				},
+			},
+			args: args{
+				deviceNumber: 3,
+			},
+		},
+		{
+			name: "fromContainer rule is missing",
+			fields: fields{
+				linkByNameCalls:    []linkByNameCall{{linkName: "eni8ea2c11fe35", link: hostVeth}},
+				withNetNSPathCalls: []withNetNSPathCall{{netNSPath: "/proc/42/ns/net"}},
+				routes:             []netlink.Route{{Dst: containerAddr, Scope: netlink.SCOPE_LINK}},
+				rules:              []netlink.Rule{{Dst: containerAddr, Priority: networkutils.ToContainerRulePriority, Table: unix.RT_TABLE_MAIN}},
+			},
+			args: args{
+				deviceNumber: 3,
+			},
+			wantErr: errors.New("CheckPodNetwork: fromContainer rule for 192.168.100.42/32 to table 4 is missing"),
+		},
+		{
+			name: "route to container is missing",
+			fields: fields{
+				linkByNameCalls:    []linkByNameCall{{linkName: "eni8ea2c11fe35", link: hostVeth}},
+				withNetNSPathCalls: []withNetNSPathCall{{netNSPath: "/proc/42/ns/net"}},
+			},
+			wantErr: errors.New("CheckPodNetwork: route to 192.168.100.42/32 via hostVeth eni8ea2c11fe35 is missing"),
+		},
+		{
+			name: "pod network matches - eBPF datapath",
+			fields: fields{
+				linkByNameCalls:    []linkByNameCall{{linkName: "eni8ea2c11fe35", link: hostVeth}},
+				withNetNSPathCalls: []withNetNSPathCall{{netNSPath: "/proc/42/ns/net"}},
+				routes:             []netlink.Route{{Dst: containerAddr, Scope: netlink.SCOPE_LINK, Table: networkutils.PodRouteTable}},
+				filters:            []netlink.Filter{buildPodRouteMarkFilter(hostVeth.Index, 0)},
+			},
+			args: args{
+				deviceNumber: 3,
+				podDatapath:  datapath.ModeEBPF,
+			},
+		},
+		{
+			name: "route to container is missing - eBPF datapath",
+			fields: fields{
+				linkByNameCalls:    []linkByNameCall{{linkName: "eni8ea2c11fe35", link: hostVeth}},
+				withNetNSPathCalls: []withNetNSPathCall{{netNSPath: "/proc/42/ns/net"}},
+			},
+			args: args{
+				deviceNumber: 3,
+				podDatapath:  datapath.ModeEBPF,
+			},
+			wantErr: errors.New("CheckPodNetwork: route to 192.168.100.42/32 via hostVeth eni8ea2c11fe35 in table 5000 is missing"),
+		},
+		{
+			name: "program is missing - eBPF datapath",
+			fields: fields{
+				linkByNameCalls:    []linkByNameCall{{linkName: "eni8ea2c11fe35", link: hostVeth}},
+				withNetNSPathCalls: []withNetNSPathCall{{netNSPath: "/proc/42/ns/net"}},
+				routes:             []netlink.Route{{Dst: containerAddr, Scope: netlink.SCOPE_LINK, Table: networkutils.PodRouteTable}},
+				filters:            []netlink.Filter{buildIfbRedirectFilter(hostVeth.Index, 10)},
+			},
+			args: args{
+				deviceNumber: 3,
+				podDatapath:  datapath.ModeEBPF,
+			},
+			wantErr: errors.New("CheckPodNetwork: pod_route_mark program on hostVeth eni8ea2c11fe35 is missing"),
+		},
+		{
+			name: "container network does not match",
+			fields: fields{
+				linkByNameCalls:    []linkByNameCall{{linkName: "eni8ea2c11fe35", link: hostVeth}},
+				withNetNSPathCalls: []withNetNSPathCall{{netNSPath: "/proc/42/ns/net", err: errors.New(`link "eth0" is down`)}},
+			},
+			wantErr: errors.New(`CheckPodNetwork: failed to check container network: link "eth0" is down`),
+		},
+		{
+			name: "hostVeth is down",
+			fields: fields{
+				linkByNameCalls: []linkByNameCall{{linkName: "eni8ea2c11fe35", link: downHostVeth}},
+			},
+			wantErr: errors.New("CheckPodNetwork: hostVeth eni8ea2c11fe35 is down"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			netLink := mock_netlinkwrapper.NewMockNetLink(ctrl)
+			for _, call := range tt.fields.linkByNameCalls {
+				netLink.EXPECT().LinkByName(call.linkName).Return(call.link, call.err)
+			}
+			netLink.EXPECT().RouteList(gomock.Any(), unix.AF_INET).Return(tt.fields.routes, nil).MaxTimes(1)
+			netLink.EXPECT().RuleList(unix.AF_INET).Return(tt.fields.rules, nil).MaxTimes(1)
+			netLink.EXPECT().RouteListFiltered(unix.AF_INET, &netlink.Route{LinkIndex: hostVeth.Index, Table: networkutils.PodRouteTable},
+				netlink.RT_FILTER_OIF|netlink.RT_FILTER_TABLE).Return(tt.fields.routes, nil).MaxTimes(1)
+			netLink.EXPECT().FilterList(hostVeth, uint32(netlink.HANDLE_MIN_INGRESS)).Return(tt.fields.filters, nil).MaxTimes(1)
+			ns := mock_nswrapper.NewMockNS(ctrl)
+			for _, call := range tt.fields.withNetNSPathCalls {
+				ns.EXPECT().WithNetNSPath(call.netNSPath, gomock.Any()).Return(call.err)
+			}
+
+			n := &linuxNetwork{
+				netLink: netLink,
+				ns:      ns,
+			}
+			err := n.CheckPodNetwork("eni8ea2c11fe35", "eth0", "/proc/42/ns/net", containerAddr, nil, tt.args.deviceNumber, tt.args.podDatapath, testLogger)
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_checkContainerContext_run(t *testing.T) {
+	contVeth := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:  "eth0",
+			Index: 1,
+			Flags: net.FlagUp,
+		},
+	}
+	containerAddr := &net.IPNet{
+		IP:   net.ParseIP("192.168.100.42"),
+		Mask: net.CIDRMask(32, 32),
+	}
+	defaultRoute := netlink.Route{
+		Dst: &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)},
+		Gw:  net.IPv4(169, 254, 1, 1),
+	}
+
+	tests := []struct {
+		name    string
+		addrs   []netlink.Addr
+		routes  []netlink.Route
+		wantErr error
+	}{
+		{
+			name:   "container network matches",
+			addrs:  []netlink.Addr{{IPNet: containerAddr}},
+			routes: []netlink.Route{defaultRoute},
+		},
+		{
+			name:    "address is missing",
+			wantErr: errors.New(`address 192.168.100.42/32 is missing on "eth0"`),
+		},
+		{
+			name:    "default route is missing",
+			addrs:   []netlink.Addr{{IPNet: containerAddr}},
+			wantErr: errors.New(`default route via 169.254.1.1 is missing on "eth0"`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			netLink := mock_netlinkwrapper.NewMockNetLink(ctrl)
+			netLink.EXPECT().LinkByName("eth0").Return(contVeth, nil)
+			netLink.EXPECT().AddrList(contVeth, unix.AF_INET).Return(tt.addrs, nil)
+			netLink.EXPECT().RouteList(contVeth, unix.AF_INET).Return(tt.routes, nil).MaxTimes(1)
+
+			checkContext := &checkContainerContext{
+				contVethName: "eth0",
+				v4Addr:       containerAddr,
+				netLink:      netLink,
+			}
+			err := checkContext.run(nil)
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func Test_linuxNetwork_setupVeth(t *testing.T) {
 	hostVethWithIndex9 := &netlink.Veth{
 		LinkAttrs: netlink.LinkAttrs{
@@ -5229,3 +6261,20 @@ func Test_buildVlanLink(t *testing.T) {
 		})
 	}
 }
+
+func Test_buildPodRouteMarkProg(t *testing.T) {
+	assert.Equal(t, []uint64{
+		// r2 = *(u32 *)(r1 + 8)
+		0x0000000000081261,
+		// w2 &= 0xff00ffff
+		0xff00ffff00000254,
+		// w2 |= 0x40000
+		0x0004000000000244,
+		// *(u32 *)(r1 + 8) = r2
+		0x0000000000082163,
+		// r0 = -1
+		0xffffffff000000b7,
+		// exit
+		0x0000000000000095,
+	}, buildPodRouteMarkProg(networkutils.PodRouteMark(4)))
+}
@@ -0,0 +1,165 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package driver
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/netlinkwrapper"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/networkutils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/logger"
+)
+
+const (
+	// podRouteMarkProgName is the name of the tc filter marking the traffic from the pods in the eBPF datapath
+	podRouteMarkProgName = "pod_route_mark"
+	// podRouteMarkProgLicense is the license of the program, which calls no GPL-only helper
+	podRouteMarkProgLicense = "Apache-2.0"
+	// podRouteMarkFilterPriority runs the program before the filter redirecting the traffic from the pod to its ifb
+	// device, as the traffic coming back from the ifb device skips the tc filters of the host veth
+	podRouteMarkFilterPriority = 1
+	// skbMarkOffset is the offset of the mark in struct __sk_buff
+	skbMarkOffset = 8
+)
+
+// setupEBPFContainerRoutes sets up the route to containerAddr in the pod route table, looked up before the route tables
+// of the ENIs. Unless rtTable is the main route table, a tc/eBPF program on hostVeth marks the traffic from the pod with
+// rtTable, which the rule of its ENI set up by ipamd routes via rtTable.
+func (n *linuxNetwork) setupEBPFContainerRoutes(hostVeth netlink.Link, containerAddr *net.IPNet, rtTable int, log logger.Logger) error {
+	route := netlink.Route{
+		LinkIndex: hostVeth.Attrs().Index,
+		Scope:     netlink.SCOPE_LINK,
+		Dst:       containerAddr,
+		Table:     networkutils.PodRouteTable,
+	}
+	if err := n.netLink.RouteReplace(&route); err != nil {
+		return errors.Wrapf(err, "failed to setup container route, containerAddr=%s, hostVeth=%s, rtTable=%v",
+			containerAddr.String(), hostVeth.Attrs().Name, networkutils.PodRouteTable)
+	}
+	log.Debugf("Successfully setup container route, containerAddr=%s, hostVeth=%s, rtTable=%v",
+		containerAddr.String(), hostVeth.Attrs().Name, networkutils.PodRouteTable)
+
+	if rtTable == unix.RT_TABLE_MAIN {
+		return nil
+	}
+	if err := n.addClsactQdisc(hostVeth); err != nil {
+		return err
+	}
+	mark := networkutils.PodRouteMark(rtTable)
+	// The program stays loaded as long as the filter references it, its file descriptor is released when the plugin exits
+	fd, err := n.netLink.BpfProgLoad(netlink.BPF_PROG_TYPE_SCHED_CLS, buildPodRouteMarkProg(mark), podRouteMarkProgLicense)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load %s program", podRouteMarkProgName)
+	}
+	if err := n.netLink.FilterAdd(buildPodRouteMarkFilter(hostVeth.Attrs().Index, fd)); err != nil {
+		return errors.Wrapf(err, "failed to attach %s program to hostVeth %s", podRouteMarkProgName, hostVeth.Attrs().Name)
+	}
+	log.Debugf("Successfully setup %s program, containerAddr=%s, hostVeth=%s, mark=%#x",
+		podRouteMarkProgName, containerAddr.String(), hostVeth.Attrs().Name, mark)
+	return nil
+}
+
+// teardownEBPFContainerRoute deletes the route to containerAddr from the pod route table. The program goes away with
+// the host veth.
+func (n *linuxNetwork) teardownEBPFContainerRoute(containerAddr *net.IPNet, log logger.Logger) {
+	route := netlink.Route{
+		Scope: netlink.SCOPE_LINK,
+		Dst:   containerAddr,
+		Table: networkutils.PodRouteTable,
+	}
+	// like the route in the main route table, it is automatically deleted by kernel when the hostVeth is deleted.
+	if err := n.netLink.RouteDel(&route); err != nil && !netlinkwrapper.IsNotExistsError(err) {
+		log.Warnf("failed to delete container route, containerAddr=%s, rtTable=%v: %v", containerAddr.String(), networkutils.PodRouteTable, err)
+	}
+}
+
+// checkEBPFContainerRoutes verifies the route and program set up by setupEBPFContainerRoutes
+func (n *linuxNetwork) checkEBPFContainerRoutes(hostVeth netlink.Link, containerAddr *net.IPNet, rtTable int) error {
+	family := unix.AF_INET
+	if containerAddr.IP.To4() == nil {
+		family = unix.AF_INET6
+	}
+	routes, err := n.netLink.RouteListFiltered(family, &netlink.Route{
+		LinkIndex: hostVeth.Attrs().Index,
+		Table:     networkutils.PodRouteTable,
+	}, netlink.RT_FILTER_OIF|netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list the routes of hostVeth %s", hostVeth.Attrs().Name)
+	}
+	if !lo.ContainsBy(routes, func(route netlink.Route) bool { return ipNetEqual(route.Dst, containerAddr) }) {
+		return errors.Errorf("route to %s via hostVeth %s in table %d is missing", containerAddr, hostVeth.Attrs().Name, networkutils.PodRouteTable)
+	}
+
+	if rtTable == unix.RT_TABLE_MAIN {
+		return nil
+	}
+	filters, err := n.netLink.FilterList(hostVeth, netlink.HANDLE_MIN_INGRESS)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list the filters of hostVeth %s", hostVeth.Attrs().Name)
+	}
+	if !lo.ContainsBy(filters, func(filter netlink.Filter) bool {
+		bpfFilter, ok := filter.(*netlink.BpfFilter)
+		return ok && bpfFilter.Name == podRouteMarkProgName
+	}) {
+		return errors.Errorf("%s program on hostVeth %s is missing", podRouteMarkProgName, hostVeth.Attrs().Name)
+	}
+	return nil
+}
+
+// buildPodRouteMarkProg returns the tc program setting mark on the traffic from a pod, leaving the bits of the mark
+// outside of networkutils.PodRouteMarkMask alone, e.g. the connmark restored by iptables later on:
+//
+//	r2 = skb->mark
+//	r2 &= ~PodRouteMarkMask
+//	r2 |= mark
+//	skb->mark = r2
+//	return TC_ACT_UNSPEC
+//
+// TC_ACT_UNSPEC hands the traffic over to the next filter, if any.
+func buildPodRouteMarkProg(mark uint32) []uint64 {
+	keptBits := ^uint32(networkutils.PodRouteMarkMask)
+	return []uint64{
+		bpfInsn(unix.BPF_LDX|unix.BPF_MEM|unix.BPF_W, 2, 1, skbMarkOffset, 0),
+		bpfInsn(unix.BPF_ALU|unix.BPF_AND|unix.BPF_K, 2, 0, 0, int32(keptBits)),
+		bpfInsn(unix.BPF_ALU|unix.BPF_OR|unix.BPF_K, 2, 0, 0, int32(mark)),
+		bpfInsn(unix.BPF_STX|unix.BPF_MEM|unix.BPF_W, 1, 2, skbMarkOffset, 0),
+		bpfInsn(unix.BPF_ALU64|unix.BPF_MOV|unix.BPF_K, 0, 0, 0, -1),
+		bpfInsn(unix.BPF_JMP|unix.BPF_EXIT, 0, 0, 0, 0),
+	}
+}
+
+// bpfInsn encodes an eBPF instruction, struct bpf_insn, in the byte order of the host
+func bpfInsn(opcode uint8, dstReg uint8, srcReg uint8, off int16, imm int32) uint64 {
+	return uint64(opcode) | uint64(srcReg<<4|dstReg&0xf)<<8 | uint64(uint16(off))<<16 | uint64(uint32(imm))<<32
+}
+
+// buildPodRouteMarkFilter returns the filter running the program of fd on the traffic received by the link of linkIndex
+func buildPodRouteMarkFilter(linkIndex int, fd int) *netlink.BpfFilter {
+	return &netlink.BpfFilter{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: linkIndex,
+			Parent:    netlink.HANDLE_MIN_INGRESS,
+			Priority:  podRouteMarkFilterPriority,
+			Protocol:  unix.ETH_P_ALL,
+		},
+		Fd:           fd,
+		Name:         podRouteMarkProgName,
+		DirectAction: true,
+	}
+}
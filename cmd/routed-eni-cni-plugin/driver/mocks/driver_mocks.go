@@ -22,6 +22,8 @@ import (
 	net "net"
 	reflect "reflect"
 
+	driver "github.com/nholuongut/amazon-vpc-cni-k8s/cmd/routed-eni-cni-plugin/driver"
+	datapath "github.com/nholuongut/amazon-vpc-cni-k8s/pkg/datapath"
 	sgpp "github.com/nholuongut/amazon-vpc-cni-k8s/pkg/sgpp"
 	logger "github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/logger"
 	gomock "github.com/golang/mock/gomock"
@@ -50,32 +52,74 @@ func (m *MockNetworkAPIs) EXPECT() *MockNetworkAPIsMockRecorder {
 	return m.recorder
 }
 
+// CheckBranchENIPodNetwork mocks base method.
+func (m *MockNetworkAPIs) CheckBranchENIPodNetwork(arg0, arg1, arg2 string, arg3, arg4 *net.IPNet, arg5 int, arg6 sgpp.EnforcingMode, arg7 logger.Logger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckBranchENIPodNetwork", arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckBranchENIPodNetwork indicates an expected call of CheckBranchENIPodNetwork.
+func (mr *MockNetworkAPIsMockRecorder) CheckBranchENIPodNetwork(arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckBranchENIPodNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).CheckBranchENIPodNetwork), arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+}
+
+// CheckPodNetwork mocks base method.
+func (m *MockNetworkAPIs) CheckPodNetwork(arg0, arg1, arg2 string, arg3, arg4 *net.IPNet, arg5 int, arg6 datapath.Mode, arg7 logger.Logger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckPodNetwork", arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckPodNetwork indicates an expected call of CheckPodNetwork.
+func (mr *MockNetworkAPIsMockRecorder) CheckPodNetwork(arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckPodNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).CheckPodNetwork), arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+}
+
 // SetupBranchENIPodNetwork mocks base method.
-func (m *MockNetworkAPIs) SetupBranchENIPodNetwork(arg0, arg1, arg2 string, arg3, arg4 *net.IPNet, arg5 int, arg6, arg7 string, arg8, arg9 int, arg10 sgpp.EnforcingMode, arg11 logger.Logger) error {
+func (m *MockNetworkAPIs) SetupBranchENIPodNetwork(arg0, arg1, arg2 string, arg3, arg4 *net.IPNet, arg5 int, arg6, arg7 string, arg8, arg9 int, arg10 driver.Bandwidth, arg11 sgpp.EnforcingMode, arg12 logger.Logger) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetupBranchENIPodNetwork", arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11)
+	ret := m.ctrl.Call(m, "SetupBranchENIPodNetwork", arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11, arg12)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SetupBranchENIPodNetwork indicates an expected call of SetupBranchENIPodNetwork.
-func (mr *MockNetworkAPIsMockRecorder) SetupBranchENIPodNetwork(arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11 interface{}) *gomock.Call {
+func (mr *MockNetworkAPIsMockRecorder) SetupBranchENIPodNetwork(arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11, arg12 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetupBranchENIPodNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).SetupBranchENIPodNetwork), arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11, arg12)
+}
+
+// SetupPodExtraInterface mocks base method.
+func (m *MockNetworkAPIs) SetupPodExtraInterface(arg0, arg1, arg2 string, arg3 *net.IPNet, arg4, arg5 int, arg6 datapath.Mode, arg7 logger.Logger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetupPodExtraInterface", arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetupPodExtraInterface indicates an expected call of SetupPodExtraInterface.
+func (mr *MockNetworkAPIsMockRecorder) SetupPodExtraInterface(arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetupBranchENIPodNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).SetupBranchENIPodNetwork), arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetupPodExtraInterface", reflect.TypeOf((*MockNetworkAPIs)(nil).SetupPodExtraInterface), arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
 }
 
 // SetupPodNetwork mocks base method.
-func (m *MockNetworkAPIs) SetupPodNetwork(arg0, arg1, arg2 string, arg3, arg4 *net.IPNet, arg5, arg6 int, arg7 logger.Logger) error {
+func (m *MockNetworkAPIs) SetupPodNetwork(arg0, arg1, arg2 string, arg3, arg4 *net.IPNet, arg5, arg6 int, arg7 driver.Bandwidth, arg8 datapath.Mode, arg9 logger.Logger) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetupPodNetwork", arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+	ret := m.ctrl.Call(m, "SetupPodNetwork", arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SetupPodNetwork indicates an expected call of SetupPodNetwork.
-func (mr *MockNetworkAPIsMockRecorder) SetupPodNetwork(arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7 interface{}) *gomock.Call {
+func (mr *MockNetworkAPIsMockRecorder) SetupPodNetwork(arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetupPodNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).SetupPodNetwork), arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetupPodNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).SetupPodNetwork), arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9)
 }
 
 // TeardownBranchENIPodNetwork mocks base method.
@@ -27,15 +27,59 @@ import (
 type ENIConfigSpec struct {
 	SecurityGroups []string `json:"securityGroups"`
 	Subnet         string   `json:"subnet"`
+	// Tags are added to the ENIs created with the ENIConfig, on top of ADDITIONAL_ENI_TAGS
+	Tags map[string]string `json:"tags,omitempty"`
+	// Description is appended to the description of the ENIs created with the ENIConfig
+	Description string `json:"description,omitempty"`
+	// EnablePrefixDelegation overrides ENABLE_PREFIX_DELEGATION on the nodes using the ENIConfig
+	EnablePrefixDelegation *bool `json:"enablePrefixDelegation,omitempty"`
+	// WarmIPTarget overrides WARM_IP_TARGET on the nodes using the ENIConfig
+	//+kubebuilder:validation:Minimum=0
+	WarmIPTarget *int `json:"warmIPTarget,omitempty"`
+	// MinimumIPTarget overrides MINIMUM_IP_TARGET on the nodes using the ENIConfig
+	//+kubebuilder:validation:Minimum=0
+	MinimumIPTarget *int `json:"minimumIPTarget,omitempty"`
+	// WarmPrefixTarget overrides WARM_PREFIX_TARGET on the nodes using the ENIConfig
+	//+kubebuilder:validation:Minimum=0
+	WarmPrefixTarget *int `json:"warmPrefixTarget,omitempty"`
 }
 
+// Condition types of ENIConfigStatus
+const (
+	// ENIConfigSubnetValid is True when the subnet exists in the VPC and in the availability zone of the nodes
+	// using the ENIConfig
+	ENIConfigSubnetValid = "SubnetValid"
+	// ENIConfigSecurityGroupsValid is True when the security groups exist in the VPC of the subnet
+	ENIConfigSecurityGroupsValid = "SecurityGroupsValid"
+	// ENIConfigReady is True when ENIs can be created with the ENIConfig
+	ENIConfigReady = "Ready"
+)
+
 // ENIConfigStatus defines the observed state of ENIConfig
 type ENIConfigStatus struct {
-	// Fill me
+	// Conditions are the results of the validation of the subnet and security groups against EC2
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// AvailabilityZone is the availability zone of the subnet
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+	// VPCID is the VPC of the subnet
+	VPCID string `json:"vpcID,omitempty"`
+	// AvailableIPAddressCount is the number of free IPv4 addresses of the subnet
+	AvailableIPAddressCount *int64 `json:"availableIPAddressCount,omitempty"`
+	// NodeCount is the number of nodes using the ENIConfig
+	NodeCount int `json:"nodeCount"`
+	// ObservedGeneration is the generation of the spec which was validated
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastValidatedTime is the time of the last validation
+	LastValidatedTime *metav1.Time `json:"lastValidatedTime,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Subnet",type=string,JSONPath=`.spec.subnet`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Nodes",type=integer,JSONPath=`.status.nodeCount`
+//+kubebuilder:printcolumn:name="Free IPs",type=integer,JSONPath=`.status.availableIPAddressCount`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // ENIConfig is the Schema for the eniconfigs API
 type ENIConfig struct {
@@ -0,0 +1,80 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SNATAction is what is done to the source address of the traffic matched by a SNATPolicy
+// +kubebuilder:validation:Enum=NoSNAT;SNAT;SNATRandomFully
+type SNATAction string
+
+const (
+	// SNATActionNoSNAT keeps the pod IP as the source address
+	SNATActionNoSNAT SNATAction = "NoSNAT"
+	// SNATActionSNAT translates the source address to the primary IP of the node, with the port randomization of
+	// nholuongut_VPC_K8S_CNI_RANDOMIZESNAT
+	SNATActionSNAT SNATAction = "SNAT"
+	// SNATActionSNATRandomFully translates the source address to the primary IP of the node, with fully randomized
+	// source ports
+	SNATActionSNATRandomFully SNATAction = "SNATRandomFully"
+)
+
+// SNATPolicySpec defines the desired state of SNATPolicy
+type SNATPolicySpec struct {
+	// NodeSelector selects the nodes the policy applies to, all nodes when it is not set
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+	// PodSelector selects the pods the policy applies to, all pods when it is not set
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+	// Destinations are the IPv4 CIDRs the traffic of the pods is matched against
+	//+kubebuilder:validation:MinItems=1
+	Destinations []string `json:"destinations"`
+	// Action is what is done to the source address of the matched traffic
+	Action SNATAction `json:"action"`
+	// Priority orders the policies, the lowest first. The first policy matching the traffic applies, ties are broken
+	// by name.
+	Priority int32 `json:"priority,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Action",type=string,JSONPath=`.spec.action`
+//+kubebuilder:printcolumn:name="Priority",type=integer,JSONPath=`.spec.priority`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SNATPolicy is the Schema for the snatpolicies API. The nodes it selects report the generation they applied, or
+// failed to apply, with events on it rather than in a status, which would grow with the number of nodes.
+type SNATPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SNATPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SNATPolicyList contains a list of SNATPolicy
+type SNATPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SNATPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SNATPolicy{}, &SNATPolicyList{})
+}
@@ -22,6 +22,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -30,8 +31,8 @@ func (in *ENIConfig) DeepCopyInto(out *ENIConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ENIConfig.
@@ -87,6 +88,38 @@ func (in *ENIConfigList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ENIConfigSpec) DeepCopyInto(out *ENIConfigSpec) {
 	*out = *in
+	if in.SecurityGroups != nil {
+		in, out := &in.SecurityGroups, &out.SecurityGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.EnablePrefixDelegation != nil {
+		in, out := &in.EnablePrefixDelegation, &out.EnablePrefixDelegation
+		*out = new(bool)
+		**out = **in
+	}
+	if in.WarmIPTarget != nil {
+		in, out := &in.WarmIPTarget, &out.WarmIPTarget
+		*out = new(int)
+		**out = **in
+	}
+	if in.MinimumIPTarget != nil {
+		in, out := &in.MinimumIPTarget, &out.MinimumIPTarget
+		*out = new(int)
+		**out = **in
+	}
+	if in.WarmPrefixTarget != nil {
+		in, out := &in.WarmPrefixTarget, &out.WarmPrefixTarget
+		*out = new(int)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ENIConfigSpec.
@@ -102,6 +135,22 @@ func (in *ENIConfigSpec) DeepCopy() *ENIConfigSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ENIConfigStatus) DeepCopyInto(out *ENIConfigStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AvailableIPAddressCount != nil {
+		in, out := &in.AvailableIPAddressCount, &out.AvailableIPAddressCount
+		*out = new(int64)
+		**out = **in
+	}
+	if in.LastValidatedTime != nil {
+		in, out := &in.LastValidatedTime, &out.LastValidatedTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ENIConfigStatus.
@@ -113,3 +162,91 @@ func (in *ENIConfigStatus) DeepCopy() *ENIConfigStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SNATPolicy) DeepCopyInto(out *SNATPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SNATPolicy.
+func (in *SNATPolicy) DeepCopy() *SNATPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(SNATPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SNATPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SNATPolicyList) DeepCopyInto(out *SNATPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SNATPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SNATPolicyList.
+func (in *SNATPolicyList) DeepCopy() *SNATPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(SNATPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SNATPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SNATPolicySpec) DeepCopyInto(out *SNATPolicySpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Destinations != nil {
+		in, out := &in.Destinations, &out.Destinations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SNATPolicySpec.
+func (in *SNATPolicySpec) DeepCopy() *SNATPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SNATPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
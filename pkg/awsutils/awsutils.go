@@ -59,6 +59,9 @@ const (
 	eniNodeTagKey           = "node.k8s.amazonnholuongut.com/instance_id"
 	eniCreatedAtTagKey      = "node.k8s.amazonnholuongut.com/createdAt"
 	eniClusterTagKey        = "cluster.k8s.amazonnholuongut.com/name"
+	eniIPPoolTagKey         = "node.k8s.amazonnholuongut.com/ip-pool"
+	eniNoManageTagKey       = "node.k8s.amazonnholuongut.com/no_manage"
+	eniEgressEIPTagKey      = "node.k8s.amazonnholuongut.com/egress-eip"
 	additionalEniTagsEnvVar = "ADDITIONAL_ENI_TAGS"
 	reservedTagKeyPrefix    = "k8s.amazonnholuongut.com"
 	subnetDiscoveryTagKey   = "kubernetes.io/role/cni"
@@ -89,7 +92,13 @@ var log = logger.Get()
 // APIs defines interfaces calls for adding/getting/deleting ENIs/secondary IPs. The APIs are not thread-safe.
 type APIs interface {
 	// AllocENI creates an ENI and attaches it to the instance
-	AllocENI(useCustomCfg bool, sg []*string, eniCfgSubnet string, numIPs int) (eni string, err error)
+	AllocENI(useCustomCfg bool, sg []*string, eniCfgSubnet string, numIPs int, opts ENIOptions) (eni string, err error)
+
+	// AllocIPPoolENI creates an ENI of the IP pool in the given subnet and attaches it to the instance
+	AllocIPPoolENI(ipPool string, sg []*string, subnet string, numIPs int, opts ENIOptions) (eni string, err error)
+
+	// AllocEgressENI creates an unmanaged ENI for egress Elastic IPs and attaches it to the instance
+	AllocEgressENI(sg []*string, subnet string) (eni string, err error)
 
 	// FreeENI detaches ENI interface and deletes it
 	FreeENI(eniName string) error
@@ -103,6 +112,12 @@ type APIs interface {
 	// GetIPv4sFromEC2 returns the IPv4 addresses for a given ENI
 	GetIPv4sFromEC2(eniID string) (addrList []*ec2.NetworkInterfacePrivateIpAddress, err error)
 
+	// GetSubnetsFromEC2 returns the subnets among subnetIDs which exist
+	GetSubnetsFromEC2(subnetIDs []string) ([]*ec2.Subnet, error)
+
+	// GetSecurityGroupsFromEC2 returns the security groups among groupIDs which exist
+	GetSecurityGroupsFromEC2(groupIDs []string) ([]*ec2.SecurityGroup, error)
+
 	// GetIPv4PrefixesFromEC2 returns the IPv4 prefixes for a given ENI
 	GetIPv4PrefixesFromEC2(eniID string) (addrList []*ec2.Ipv4PrefixSpecification, err error)
 
@@ -118,9 +133,24 @@ type APIs interface {
 	// AllocIPAddresses allocates numIPs IP addresses on a ENI
 	AllocIPAddresses(eniID string, numIPs int) (*ec2.AssignPrivateIpAddressesOutput, error)
 
+	// AllocIPv4Cidr allocates the given secondary IP (/32) or prefix (/28) on a ENI
+	AllocIPv4Cidr(eniID string, cidr net.IPNet) error
+
+	// AllocSecondaryIP allocates a single secondary IP address on a ENI, also when prefix delegation is enabled
+	AllocSecondaryIP(eniID string) (string, error)
+
 	// DeallocIPAddresses deallocates the list of IP addresses from a ENI
 	DeallocIPAddresses(eniID string, ips []string) error
 
+	// DescribeElasticIPs returns the Elastic IPs among allocationIDs, and the ones associated with eniID
+	DescribeElasticIPs(allocationIDs []string, eniID string) ([]ElasticIP, error)
+
+	// AssociateElasticIP associates an Elastic IP with a private IP of a ENI and returns the association ID
+	AssociateElasticIP(allocationID, eniID, privateIP string) (string, error)
+
+	// DisassociateElasticIP removes the association of an Elastic IP
+	DisassociateElasticIP(associationID string) error
+
 	// DeallocPrefixAddresses deallocates the list of IP addresses from a ENI
 	DeallocPrefixAddresses(eniID string, ips []string) error
 
@@ -139,6 +169,9 @@ type APIs interface {
 	// GetPrimaryENI returns the primary ENI
 	GetPrimaryENI() string
 
+	// GetVPCID returns the VPC of the instance
+	GetVPCID() string
+
 	// GetENIIPv4Limit return IP address limit per ENI based on EC2 instance type
 	GetENIIPv4Limit() int
 
@@ -190,6 +223,23 @@ type APIs interface {
 	IsPrefixDelegationSupported() bool
 }
 
+// ENIOptions are the settings of the ENIConfig applied to new ENIs, besides the subnet and security groups
+type ENIOptions struct {
+	// Tags are added to the tags of the ENI. Reserved tag keys are ignored.
+	Tags map[string]string
+	// Description is appended to the description of the ENI
+	Description string
+}
+
+// ElasticIP is an Elastic IP and its association, if any
+type ElasticIP struct {
+	AllocationID       string
+	PublicIP           string
+	AssociationID      string
+	NetworkInterfaceID string
+	PrivateIP          string
+}
+
 // EC2InstanceMetadataCache caches instance metadata
 type EC2InstanceMetadataCache struct {
 	// metadata info
@@ -352,6 +402,11 @@ func (i instrumentedIMDS) GetMetadataWithContext(ctx context.Context, p string)
 	return result, nil
 }
 
+// NewTypedIMDS returns the typed instance metadata, with its requests instrumented
+func NewTypedIMDS(ec2Metadata EC2MetadataIface) TypedIMDS {
+	return TypedIMDS{instrumentedIMDS{ec2Metadata}}
+}
+
 // New creates an EC2InstanceMetadataCache
 func New(useSubnetDiscovery, useCustomNetworking, disableLeakedENICleanup, v4Enabled, v6Enabled bool) (*EC2InstanceMetadataCache, error) {
 	// ctx is passed to initWithEC2Metadata func to cancel spawned go-routines when tests are run
@@ -360,7 +415,7 @@ func New(useSubnetDiscovery, useCustomNetworking, disableLeakedENICleanup, v4Ena
 	sess := nholuongutsession.New()
 	ec2Metadata := ec2metadata.New(sess)
 	cache := &EC2InstanceMetadataCache{}
-	cache.imds = TypedIMDS{instrumentedIMDS{ec2Metadata}}
+	cache.imds = NewTypedIMDS(ec2Metadata)
 	cache.clusterName = os.Getenv(clusterNameEnvVar)
 	cache.additionalENITags = loadAdditionalENITags()
 
@@ -699,7 +754,7 @@ func (cache *EC2InstanceMetadataCache) getENIMetadata(eniMAC string) (ENIMetadat
 	var ec2ipv4Prefixes []*ec2.Ipv4PrefixSpecification
 	var ec2ipv6Prefixes []*ec2.Ipv6PrefixSpecification
 
-	// If IPv6 is enabled, get attached v6 prefixes.
+	// If IPv6 is enabled, get attached v6 prefixes. In dual stack mode, both v4 and v6 prefixes are fetched.
 	if cache.v6Enabled {
 		imdsIPv6Prefixes, err := cache.imds.GetIPv6Prefixes(ctx, eniMAC)
 		if err != nil {
@@ -711,7 +766,8 @@ func (cache *EC2InstanceMetadataCache) getENIMetadata(eniMAC string) (ENIMetadat
 				Ipv6Prefix: nholuongut.String(ipv6prefix.String()),
 			})
 		}
-	} else if cache.v4Enabled && ((eniMAC == primaryMAC && !cache.useCustomNetworking) || (eniMAC != primaryMAC)) {
+	}
+	if cache.v4Enabled && ((eniMAC == primaryMAC && !cache.useCustomNetworking) || (eniMAC != primaryMAC)) {
 		// Get prefix on primary ENI when custom networking is enabled is not needed.
 		// If primary ENI has prefixes attached and then we move to custom networking, we don't need to fetch
 		// the prefix since recommendation is to terminate the nodes and that would have deleted the prefix on the
@@ -791,8 +847,31 @@ func (cache *EC2InstanceMetadataCache) nholuongutGetFreeDeviceNumber() (int, err
 
 // AllocENI creates an ENI and attaches it to the instance
 // returns: newly created ENI ID
-func (cache *EC2InstanceMetadataCache) AllocENI(useCustomCfg bool, sg []*string, eniCfgSubnet string, numIPs int) (string, error) {
-	eniID, err := cache.createENI(useCustomCfg, sg, eniCfgSubnet, numIPs)
+func (cache *EC2InstanceMetadataCache) AllocENI(useCustomCfg bool, sg []*string, eniCfgSubnet string, numIPs int, opts ENIOptions) (string, error) {
+	return cache.allocENI(cache.useCustomNetworking, sg, eniCfgSubnet, numIPs, nil, opts)
+}
+
+// AllocIPPoolENI creates an ENI of the IP pool and attaches it to the instance. The ENI is tagged with the pool so that
+// ipamd recovers it on restart.
+// returns: newly created ENI ID
+func (cache *EC2InstanceMetadataCache) AllocIPPoolENI(ipPool string, sg []*string, subnet string, numIPs int, opts ENIOptions) (string, error) {
+	return cache.allocENI(true, sg, subnet, numIPs, map[string]string{eniIPPoolTagKey: ipPool}, opts)
+}
+
+// AllocEgressENI creates an ENI without secondary IPs for egress Elastic IPs and attaches it to the instance. The ENI is
+// tagged as unmanaged, so that ipamd never hands out its addresses to pods. It is created in the given subnet and with
+// the given security groups, or like the primary ENI when subnet is empty.
+// returns: newly created ENI ID
+func (cache *EC2InstanceMetadataCache) AllocEgressENI(sg []*string, subnet string) (string, error) {
+	reservedTags := map[string]string{
+		eniNoManageTagKey:  "true",
+		eniEgressEIPTagKey: "true",
+	}
+	return cache.allocENI(subnet != "", sg, subnet, 0, reservedTags, ENIOptions{})
+}
+
+func (cache *EC2InstanceMetadataCache) allocENI(useCustomCfg bool, sg []*string, eniCfgSubnet string, numIPs int, reservedTags map[string]string, opts ENIOptions) (string, error) {
+	eniID, err := cache.createENI(useCustomCfg, sg, eniCfgSubnet, numIPs, reservedTags, opts)
 	if err != nil {
 		return "", errors.Wrap(err, "AllocENI: failed to create ENI")
 	}
@@ -863,15 +942,31 @@ func (cache *EC2InstanceMetadataCache) attachENI(eniID string) (string, error) {
 	return nholuongut.StringValue(attachOutput.AttachmentId), err
 }
 
+// createENI creates an ENI with numIPs secondary IPs or prefixes. reservedTags are set on the ENI on top of the tags of
+// every ENI, the subnet and security groups are only used when useCustomCfg is set.
 // return ENI id, error
-func (cache *EC2InstanceMetadataCache) createENI(useCustomCfg bool, sg []*string, eniCfgSubnet string, numIPs int) (string, error) {
+func (cache *EC2InstanceMetadataCache) createENI(useCustomCfg bool, sg []*string, eniCfgSubnet string, numIPs int, reservedTags map[string]string, opts ENIOptions) (string, error) {
+	// Leaked ENIs are found by the prefix of their description, so the description of the ENIConfig comes last
 	eniDescription := eniDescriptionPrefix + cache.instanceID
+	if opts.Description != "" {
+		eniDescription += " " + opts.Description
+	}
 	tags := map[string]string{
 		eniCreatedAtTagKey: time.Now().Format(time.RFC3339),
 	}
 	for key, value := range cache.buildENITags() {
 		tags[key] = value
 	}
+	for key, value := range opts.Tags {
+		if strings.Contains(key, reservedTagKeyPrefix) {
+			log.Warnf("ignoring tagKey %v from ENIConfig tags as it contains reserved prefix %v", key, reservedTagKeyPrefix)
+			continue
+		}
+		tags[key] = value
+	}
+	for key, value := range reservedTags {
+		tags[key] = value
+	}
 	tagSpec := []*ec2.TagSpecification{
 		{
 			ResourceType: nholuongut.String(ec2.ResourceTypeNetworkInterface),
@@ -888,29 +983,24 @@ func (cache *EC2InstanceMetadataCache) createENI(useCustomCfg bool, sg []*string
 	log.Infof("Trying to allocate %d IP addresses on new ENI", needIPs)
 	log.Debugf("PD enabled - %t", cache.enablePrefixDelegation)
 
-	input := &ec2.CreateNetworkInterfaceInput{}
-
-	if cache.enablePrefixDelegation {
-		input = &ec2.CreateNetworkInterfaceInput{
-			Description:       nholuongut.String(eniDescription),
-			Groups:            nholuongut.StringSlice(cache.securityGroups.SortedList()),
-			SubnetId:          nholuongut.String(cache.subnetID),
-			TagSpecifications: tagSpec,
-			Ipv4PrefixCount:   nholuongut.Int64(int64(needIPs)),
-		}
-	} else {
-		input = &ec2.CreateNetworkInterfaceInput{
-			Description:                    nholuongut.String(eniDescription),
-			Groups:                         nholuongut.StringSlice(cache.securityGroups.SortedList()),
-			SubnetId:                       nholuongut.String(cache.subnetID),
-			TagSpecifications:              tagSpec,
-			SecondaryPrivateIpAddressCount: nholuongut.Int64(int64(needIPs)),
+	input := &ec2.CreateNetworkInterfaceInput{
+		Description:       nholuongut.String(eniDescription),
+		Groups:            nholuongut.StringSlice(cache.securityGroups.SortedList()),
+		SubnetId:          nholuongut.String(cache.subnetID),
+		TagSpecifications: tagSpec,
+	}
+	// ENIs for egress Elastic IPs are created without secondary IPs, they are added one at a time
+	if needIPs > 0 {
+		if cache.enablePrefixDelegation {
+			input.Ipv4PrefixCount = nholuongut.Int64(int64(needIPs))
+		} else {
+			input.SecondaryPrivateIpAddressCount = nholuongut.Int64(int64(needIPs))
 		}
 	}
 
 	var err error
 	var networkInterfaceID string
-	if cache.useCustomNetworking {
+	if useCustomCfg {
 		input = createENIUsingCustomCfg(sg, eniCfgSubnet, input)
 		log.Infof("Creating ENI with security groups: %v in subnet: %s", nholuongut.StringValueSlice(input.Groups), nholuongut.StringValue(input.SubnetId))
 
@@ -1248,6 +1338,62 @@ func (cache *EC2InstanceMetadataCache) GetIPv4sFromEC2(eniID string) (addrList [
 	return firstNI.PrivateIpAddresses, nil
 }
 
+// GetSubnetsFromEC2 calls EC2 and returns the subnets among subnetIDs which exist. The subnets are looked up with a
+// filter, so that unknown IDs are left out of the result rather than failing the call.
+func (cache *EC2InstanceMetadataCache) GetSubnetsFromEC2(subnetIDs []string) ([]*ec2.Subnet, error) {
+	if len(subnetIDs) == 0 {
+		return nil, nil
+	}
+	input := &ec2.DescribeSubnetsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   nholuongut.String("subnet-id"),
+				Values: nholuongut.StringSlice(subnetIDs),
+			},
+		},
+	}
+
+	start := time.Now()
+	result, err := cache.ec2SVC.DescribeSubnetsWithContext(context.Background(), input)
+	prometheusmetrics.Ec2ApiReq.WithLabelValues("DescribeSubnets").Inc()
+	prometheusmetrics.nholuongutAPILatency.WithLabelValues("DescribeSubnets", fmt.Sprint(err != nil), nholuongutReqStatus(err)).Observe(msSince(start))
+	if err != nil {
+		checkAPIErrorAndBroadcastEvent(err, "ec2:DescribeSubnets")
+		nholuongutAPIErrInc("DescribeSubnets", err)
+		prometheusmetrics.Ec2ApiErr.WithLabelValues("DescribeSubnets").Inc()
+		return nil, errors.Wrap(err, "failed to describe subnets")
+	}
+	return result.Subnets, nil
+}
+
+// GetSecurityGroupsFromEC2 calls EC2 and returns the security groups among groupIDs which exist. Like subnets, the
+// security groups are looked up with a filter.
+func (cache *EC2InstanceMetadataCache) GetSecurityGroupsFromEC2(groupIDs []string) ([]*ec2.SecurityGroup, error) {
+	if len(groupIDs) == 0 {
+		return nil, nil
+	}
+	input := &ec2.DescribeSecurityGroupsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   nholuongut.String("group-id"),
+				Values: nholuongut.StringSlice(groupIDs),
+			},
+		},
+	}
+
+	start := time.Now()
+	result, err := cache.ec2SVC.DescribeSecurityGroupsWithContext(context.Background(), input)
+	prometheusmetrics.Ec2ApiReq.WithLabelValues("DescribeSecurityGroups").Inc()
+	prometheusmetrics.nholuongutAPILatency.WithLabelValues("DescribeSecurityGroups", fmt.Sprint(err != nil), nholuongutReqStatus(err)).Observe(msSince(start))
+	if err != nil {
+		checkAPIErrorAndBroadcastEvent(err, "ec2:DescribeSecurityGroups")
+		nholuongutAPIErrInc("DescribeSecurityGroups", err)
+		prometheusmetrics.Ec2ApiErr.WithLabelValues("DescribeSecurityGroups").Inc()
+		return nil, errors.Wrap(err, "failed to describe security groups")
+	}
+	return result.SecurityGroups, nil
+}
+
 // GetIPv4PrefixesFromEC2 calls EC2 and returns a list of all addresses on the ENI
 func (cache *EC2InstanceMetadataCache) GetIPv4PrefixesFromEC2(eniID string) (addrList []*ec2.Ipv4PrefixSpecification, err error) {
 	eniIds := []*string{nholuongut.String(eniID)}
@@ -1711,6 +1857,33 @@ func (cache *EC2InstanceMetadataCache) AllocIPAddresses(eniID string, numIPs int
 	return output, nil
 }
 
+// AllocIPv4Cidr allocates the given secondary IP (/32) or prefix (/28) on the ENI, rather than letting EC2 pick them
+func (cache *EC2InstanceMetadataCache) AllocIPv4Cidr(eniID string, cidr net.IPNet) error {
+	log.Infof("Trying to allocate %s on ENI %s", cidr.String(), eniID)
+	input := &ec2.AssignPrivateIpAddressesInput{
+		NetworkInterfaceId: nholuongut.String(eniID),
+	}
+	if ones, bits := cidr.Mask.Size(); ones == bits {
+		input.PrivateIpAddresses = nholuongut.StringSlice([]string{cidr.IP.String()})
+	} else {
+		input.Ipv4Prefixes = nholuongut.StringSlice([]string{cidr.String()})
+	}
+
+	start := time.Now()
+	_, err := cache.ec2SVC.AssignPrivateIpAddressesWithContext(context.Background(), input)
+	prometheusmetrics.Ec2ApiReq.WithLabelValues("AssignPrivateIpAddresses").Inc()
+	prometheusmetrics.nholuongutAPILatency.WithLabelValues("AssignPrivateIpAddresses", fmt.Sprint(err != nil), nholuongutReqStatus(err)).Observe(msSince(start))
+	if err != nil {
+		checkAPIErrorAndBroadcastEvent(err, "ec2:AssignPrivateIpAddresses")
+		log.Errorf("Failed to allocate %s on ENI %v: %v", cidr.String(), eniID, err)
+		nholuongutAPIErrInc("AssignPrivateIpAddresses", err)
+		prometheusmetrics.Ec2ApiErr.WithLabelValues("AssignPrivateIpAddresses").Inc()
+		return errors.Wrap(err, fmt.Sprintf("allocate IP address: failed to allocate %s on ENI %s", cidr.String(), eniID))
+	}
+	log.Infof("Allocated %s on ENI %s", cidr.String(), eniID)
+	return nil
+}
+
 func (cache *EC2InstanceMetadataCache) AllocIPv6Prefixes(eniID string) ([]*string, error) {
 	//We only need to allocate one IPv6 prefix per ENI.
 	input := &ec2.AssignIpv6AddressesInput{
@@ -1759,7 +1932,7 @@ func (cache *EC2InstanceMetadataCache) waitForENIAndIPsAttached(eni string, want
 					returnedENI.IPv4Addresses, returnedENI.IPv4Prefixes, returnedENI.IPv6Prefixes)
 				if cache.enablePrefixDelegation {
 					eniIPCount = len(returnedENI.IPv4Prefixes)
-					if cache.v6Enabled {
+					if cache.v6Enabled && !cache.v4Enabled {
 						eniIPCount = len(returnedENI.IPv6Prefixes)
 					}
 				} else {
@@ -1805,6 +1978,130 @@ func (cache *EC2InstanceMetadataCache) waitForENIAndIPsAttached(eni string, want
 	return eniMetadata, nil
 }
 
+// AllocSecondaryIP allocates a single secondary IP address on an ENI and returns it. Unlike AllocIPAddresses, it never
+// allocates a prefix.
+func (cache *EC2InstanceMetadataCache) AllocSecondaryIP(eniID string) (string, error) {
+	log.Infof("Trying to allocate a secondary IP address on ENI %s", eniID)
+	input := &ec2.AssignPrivateIpAddressesInput{
+		NetworkInterfaceId:             nholuongut.String(eniID),
+		SecondaryPrivateIpAddressCount: nholuongut.Int64(1),
+	}
+
+	start := time.Now()
+	output, err := cache.ec2SVC.AssignPrivateIpAddressesWithContext(context.Background(), input)
+	prometheusmetrics.Ec2ApiReq.WithLabelValues("AssignPrivateIpAddresses").Inc()
+	prometheusmetrics.nholuongutAPILatency.WithLabelValues("AssignPrivateIpAddresses", fmt.Sprint(err != nil), nholuongutReqStatus(err)).Observe(msSince(start))
+	if err != nil {
+		checkAPIErrorAndBroadcastEvent(err, "ec2:AssignPrivateIpAddresses")
+		nholuongutAPIErrInc("AssignPrivateIpAddresses", err)
+		prometheusmetrics.Ec2ApiErr.WithLabelValues("AssignPrivateIpAddresses").Inc()
+		return "", errors.Wrapf(err, "failed to allocate a secondary IP address on ENI %s", eniID)
+	}
+	if len(output.AssignedPrivateIpAddresses) != 1 {
+		return "", errors.Errorf("allocated %d secondary IP addresses on ENI %s instead of 1", len(output.AssignedPrivateIpAddresses), eniID)
+	}
+	ip := nholuongut.StringValue(output.AssignedPrivateIpAddresses[0].PrivateIpAddress)
+	log.Infof("Allocated secondary IP address %s on ENI %s", ip, eniID)
+	return ip, nil
+}
+
+// DescribeElasticIPs calls EC2 and returns the Elastic IPs among allocationIDs which exist, as well as the ones which
+// are associated with eniID when it is not empty
+func (cache *EC2InstanceMetadataCache) DescribeElasticIPs(allocationIDs []string, eniID string) ([]ElasticIP, error) {
+	var filters []*ec2.Filter
+	if len(allocationIDs) > 0 {
+		filters = append(filters, &ec2.Filter{
+			Name:   nholuongut.String("allocation-id"),
+			Values: nholuongut.StringSlice(allocationIDs),
+		})
+	}
+	if eniID != "" {
+		filters = append(filters, &ec2.Filter{
+			Name:   nholuongut.String("network-interface-id"),
+			Values: nholuongut.StringSlice([]string{eniID}),
+		})
+	}
+	if len(filters) == 0 {
+		return nil, nil
+	}
+
+	// EC2 ANDs the filters of a call, so each one is looked up on its own
+	seen := make(map[string]bool)
+	var eips []ElasticIP
+	for _, filter := range filters {
+		input := &ec2.DescribeAddressesInput{Filters: []*ec2.Filter{filter}}
+		start := time.Now()
+		result, err := cache.ec2SVC.DescribeAddressesWithContext(context.Background(), input)
+		prometheusmetrics.Ec2ApiReq.WithLabelValues("DescribeAddresses").Inc()
+		prometheusmetrics.nholuongutAPILatency.WithLabelValues("DescribeAddresses", fmt.Sprint(err != nil), nholuongutReqStatus(err)).Observe(msSince(start))
+		if err != nil {
+			checkAPIErrorAndBroadcastEvent(err, "ec2:DescribeAddresses")
+			nholuongutAPIErrInc("DescribeAddresses", err)
+			prometheusmetrics.Ec2ApiErr.WithLabelValues("DescribeAddresses").Inc()
+			return nil, errors.Wrap(err, "failed to describe Elastic IPs")
+		}
+		for _, address := range result.Addresses {
+			allocationID := nholuongut.StringValue(address.AllocationId)
+			if seen[allocationID] {
+				continue
+			}
+			seen[allocationID] = true
+			eips = append(eips, ElasticIP{
+				AllocationID:       allocationID,
+				PublicIP:           nholuongut.StringValue(address.PublicIp),
+				AssociationID:      nholuongut.StringValue(address.AssociationId),
+				NetworkInterfaceID: nholuongut.StringValue(address.NetworkInterfaceId),
+				PrivateIP:          nholuongut.StringValue(address.PrivateIpAddress),
+			})
+		}
+	}
+	return eips, nil
+}
+
+// AssociateElasticIP associates the Elastic IP with the private IP of the ENI and returns the association ID. It fails
+// if the Elastic IP is already associated, so that an address used elsewhere is never taken over.
+func (cache *EC2InstanceMetadataCache) AssociateElasticIP(allocationID, eniID, privateIP string) (string, error) {
+	log.Infof("Trying to associate Elastic IP %s with %s on ENI %s", allocationID, privateIP, eniID)
+	input := &ec2.AssociateAddressInput{
+		AllocationId:       nholuongut.String(allocationID),
+		NetworkInterfaceId: nholuongut.String(eniID),
+		PrivateIpAddress:   nholuongut.String(privateIP),
+		AllowReassociation: nholuongut.Bool(false),
+	}
+
+	start := time.Now()
+	output, err := cache.ec2SVC.AssociateAddressWithContext(context.Background(), input)
+	prometheusmetrics.Ec2ApiReq.WithLabelValues("AssociateAddress").Inc()
+	prometheusmetrics.nholuongutAPILatency.WithLabelValues("AssociateAddress", fmt.Sprint(err != nil), nholuongutReqStatus(err)).Observe(msSince(start))
+	if err != nil {
+		checkAPIErrorAndBroadcastEvent(err, "ec2:AssociateAddress")
+		nholuongutAPIErrInc("AssociateAddress", err)
+		prometheusmetrics.Ec2ApiErr.WithLabelValues("AssociateAddress").Inc()
+		return "", errors.Wrapf(err, "failed to associate Elastic IP %s with %s", allocationID, privateIP)
+	}
+	return nholuongut.StringValue(output.AssociationId), nil
+}
+
+// DisassociateElasticIP removes the association of an Elastic IP
+func (cache *EC2InstanceMetadataCache) DisassociateElasticIP(associationID string) error {
+	log.Infof("Trying to disassociate Elastic IP association %s", associationID)
+	input := &ec2.DisassociateAddressInput{
+		AssociationId: nholuongut.String(associationID),
+	}
+
+	start := time.Now()
+	_, err := cache.ec2SVC.DisassociateAddressWithContext(context.Background(), input)
+	prometheusmetrics.Ec2ApiReq.WithLabelValues("DisassociateAddress").Inc()
+	prometheusmetrics.nholuongutAPILatency.WithLabelValues("DisassociateAddress", fmt.Sprint(err != nil), nholuongutReqStatus(err)).Observe(msSince(start))
+	if err != nil {
+		checkAPIErrorAndBroadcastEvent(err, "ec2:DisassociateAddress")
+		nholuongutAPIErrInc("DisassociateAddress", err)
+		prometheusmetrics.Ec2ApiErr.WithLabelValues("DisassociateAddress").Inc()
+		return errors.Wrapf(err, "failed to disassociate Elastic IP association %s", associationID)
+	}
+	return nil
+}
+
 // DeallocIPAddresses frees IP address on an ENI
 func (cache *EC2InstanceMetadataCache) DeallocIPAddresses(eniID string, ips []string) error {
 	if len(ips) == 0 {
@@ -2053,6 +2350,11 @@ func (cache *EC2InstanceMetadataCache) GetPrimaryENI() string {
 	return cache.primaryENI
 }
 
+// GetVPCID returns the VPC of the instance
+func (cache *EC2InstanceMetadataCache) GetVPCID() string {
+	return cache.vpcID
+}
+
 // GetPrimaryENImac returns the mac address of primary eni
 func (cache *EC2InstanceMetadataCache) GetPrimaryENImac() string {
 	return cache.primaryENImac
@@ -17,6 +17,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"reflect"
 	"strconv"
@@ -452,10 +453,104 @@ func TestAllocENI(t *testing.T) {
 		useSubnetDiscovery: true,
 	}
 
-	_, err := cache.AllocENI(false, nil, "", 5)
+	_, err := cache.AllocENI(false, nil, "", 5, ENIOptions{})
 	assert.NoError(t, err)
 }
 
+func TestAllocIPPoolENI(t *testing.T) {
+	ctrl, mockEC2 := setup(t)
+	defer ctrl.Finish()
+
+	mockMetadata := testMetadata(nil)
+
+	// The ENI is created in the subnet and security groups of the pool, and tagged with the pool and the tags of its
+	// ENIConfig
+	cureniID := eniID
+	eni := ec2.CreateNetworkInterfaceOutput{NetworkInterface: &ec2.NetworkInterface{NetworkInterfaceId: &cureniID}}
+	mockEC2.EXPECT().CreateNetworkInterfaceWithContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, input *ec2.CreateNetworkInterfaceInput, _ ...interface{}) (*ec2.CreateNetworkInterfaceOutput, error) {
+			assert.Equal(t, "subnet-pci", nholuongut.StringValue(input.SubnetId))
+			assert.Equal(t, []string{"sg-pci"}, nholuongut.StringValueSlice(input.Groups))
+			tags := convertSDKTagsToTags(input.TagSpecifications[0].Tags)
+			assert.Equal(t, "pci", tags[eniIPPoolTagKey])
+			assert.Equal(t, "payments", tags["team"])
+			assert.NotContains(t, tags, "k8s.amazonnholuongut.com/team")
+			assert.Equal(t, eniDescriptionPrefix+"i-0123 PCI workloads", nholuongut.StringValue(input.Description))
+			return &eni, nil
+		})
+
+	deviceNum := int64(0)
+	result := &ec2.DescribeInstancesOutput{
+		Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{{NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+			{Attachment: &ec2.InstanceNetworkInterfaceAttachment{DeviceIndex: &deviceNum}},
+		}}}}}}
+	mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(result, nil)
+	attachmentID := "eni-attach-58ddda9d"
+	attachResult := &ec2.AttachNetworkInterfaceOutput{
+		AttachmentId: &attachmentID}
+	mockEC2.EXPECT().AttachNetworkInterfaceWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(attachResult, nil)
+	mockEC2.EXPECT().ModifyNetworkInterfaceAttributeWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+
+	cache := &EC2InstanceMetadataCache{
+		ec2SVC:       mockEC2,
+		imds:         TypedIMDS{mockMetadata},
+		instanceType: "c5n.18xlarge",
+		instanceID:   "i-0123",
+	}
+
+	opts := ENIOptions{
+		Tags:        map[string]string{"team": "payments", "k8s.amazonnholuongut.com/team": "payments"},
+		Description: "PCI workloads",
+	}
+	_, err := cache.AllocIPPoolENI("pci", nholuongut.StringSlice([]string{"sg-pci"}), "subnet-pci", 5, opts)
+	assert.NoError(t, err)
+}
+
+func TestAllocEgressENI(t *testing.T) {
+	ctrl, mockEC2 := setup(t)
+	defer ctrl.Finish()
+
+	mockMetadata := testMetadata(nil)
+
+	// The egress ENI is created without secondary IPs, and tagged so that ipamd neither manages nor leaks it
+	cureniID := eniID
+	eni := ec2.CreateNetworkInterfaceOutput{NetworkInterface: &ec2.NetworkInterface{NetworkInterfaceId: &cureniID}}
+	mockEC2.EXPECT().CreateNetworkInterfaceWithContext(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, input *ec2.CreateNetworkInterfaceInput, _ ...interface{}) (*ec2.CreateNetworkInterfaceOutput, error) {
+			assert.Equal(t, "subnet-egress", nholuongut.StringValue(input.SubnetId))
+			assert.Equal(t, []string{"sg-egress"}, nholuongut.StringValueSlice(input.Groups))
+			assert.Nil(t, input.SecondaryPrivateIpAddressCount)
+			assert.Nil(t, input.Ipv4PrefixCount)
+			tags := convertSDKTagsToTags(input.TagSpecifications[0].Tags)
+			assert.Equal(t, "true", tags[eniNoManageTagKey])
+			assert.Equal(t, "true", tags[eniEgressEIPTagKey])
+			return &eni, nil
+		})
+
+	deviceNum := int64(0)
+	result := &ec2.DescribeInstancesOutput{
+		Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{{NetworkInterfaces: []*ec2.InstanceNetworkInterface{
+			{Attachment: &ec2.InstanceNetworkInterfaceAttachment{DeviceIndex: &deviceNum}},
+		}}}}}}
+	mockEC2.EXPECT().DescribeInstancesWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(result, nil)
+	attachmentID := "eni-attach-58ddda9d"
+	attachResult := &ec2.AttachNetworkInterfaceOutput{
+		AttachmentId: &attachmentID}
+	mockEC2.EXPECT().AttachNetworkInterfaceWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(attachResult, nil)
+	mockEC2.EXPECT().ModifyNetworkInterfaceAttributeWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+
+	cache := &EC2InstanceMetadataCache{
+		ec2SVC:       mockEC2,
+		imds:         TypedIMDS{mockMetadata},
+		instanceType: "c5n.18xlarge",
+		instanceID:   "i-0123",
+	}
+
+	id, err := cache.AllocEgressENI(nholuongut.StringSlice([]string{"sg-egress"}), "subnet-egress")
+	assert.NoError(t, err)
+	assert.Equal(t, eniID, id)
+}
+
 func TestAllocENINoFreeDevice(t *testing.T) {
 	ctrl, mockEC2 := setup(t)
 	defer ctrl.Finish()
@@ -503,7 +598,7 @@ func TestAllocENINoFreeDevice(t *testing.T) {
 		useSubnetDiscovery: true,
 	}
 
-	_, err := cache.AllocENI(false, nil, "", 5)
+	_, err := cache.AllocENI(false, nil, "", 5, ENIOptions{})
 	assert.Error(t, err)
 }
 
@@ -556,7 +651,7 @@ func TestAllocENIMaxReached(t *testing.T) {
 		useSubnetDiscovery: true,
 	}
 
-	_, err := cache.AllocENI(false, nil, "", 5)
+	_, err := cache.AllocENI(false, nil, "", 5, ENIOptions{})
 	assert.Error(t, err)
 }
 
@@ -603,7 +698,7 @@ func TestAllocENIWithIPAddresses(t *testing.T) {
 	mockEC2.EXPECT().ModifyNetworkInterfaceAttributeWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
 
 	cache := &EC2InstanceMetadataCache{ec2SVC: mockEC2, instanceType: "c5n.18xlarge", useSubnetDiscovery: true}
-	_, err := cache.AllocENI(false, nil, subnetID, 5)
+	_, err := cache.AllocENI(false, nil, subnetID, 5, ENIOptions{})
 	assert.NoError(t, err)
 
 	// when required IP numbers(50) is higher than ENI's limit(49)
@@ -613,7 +708,7 @@ func TestAllocENIWithIPAddresses(t *testing.T) {
 	mockEC2.EXPECT().AttachNetworkInterfaceWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(attachResult, nil)
 	mockEC2.EXPECT().ModifyNetworkInterfaceAttributeWithContext(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
 	cache = &EC2InstanceMetadataCache{ec2SVC: mockEC2, instanceType: "c5n.18xlarge", useSubnetDiscovery: true}
-	_, err = cache.AllocENI(false, nil, subnetID, 49)
+	_, err = cache.AllocENI(false, nil, subnetID, 49, ENIOptions{})
 	assert.NoError(t, err)
 }
 
@@ -647,7 +742,7 @@ func TestAllocENIWithIPAddressesAlreadyFull(t *testing.T) {
 		instanceType:       "t3.xlarge",
 		useSubnetDiscovery: true,
 	}
-	_, err := cache.AllocENI(true, nil, "", 14)
+	_, err := cache.AllocENI(true, nil, "", 14, ENIOptions{})
 	assert.Error(t, err)
 }
 
@@ -701,7 +796,7 @@ func TestAllocENIWithPrefixAddresses(t *testing.T) {
 		enablePrefixDelegation: true,
 		useSubnetDiscovery:     true,
 	}
-	_, err := cache.AllocENI(false, nil, subnetID, 1)
+	_, err := cache.AllocENI(false, nil, subnetID, 1, ENIOptions{})
 	assert.NoError(t, err)
 }
 
@@ -736,7 +831,7 @@ func TestAllocENIWithPrefixesAlreadyFull(t *testing.T) {
 		enablePrefixDelegation: true,
 		useSubnetDiscovery:     true,
 	}
-	_, err := cache.AllocENI(true, nil, "", 1)
+	_, err := cache.AllocENI(true, nil, "", 1, ENIOptions{})
 	assert.Error(t, err)
 }
 
@@ -892,6 +987,61 @@ func TestAllocIPAddressOnErr(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestAllocSecondaryIP(t *testing.T) {
+	ctrl, mockEC2 := setup(t)
+	defer ctrl.Finish()
+
+	input := &ec2.AssignPrivateIpAddressesInput{
+		NetworkInterfaceId:             nholuongut.String(eniID),
+		SecondaryPrivateIpAddressCount: nholuongut.Int64(1),
+	}
+	output := &ec2.AssignPrivateIpAddressesOutput{
+		AssignedPrivateIpAddresses: []*ec2.AssignedPrivateIpAddress{{PrivateIpAddress: nholuongut.String("10.0.0.20")}},
+	}
+	mockEC2.EXPECT().AssignPrivateIpAddressesWithContext(gomock.Any(), input, gomock.Any()).Return(output, nil)
+
+	cache := &EC2InstanceMetadataCache{ec2SVC: mockEC2, enablePrefixDelegation: true}
+	ip, err := cache.AllocSecondaryIP(eniID)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.20", ip)
+}
+
+func TestDescribeElasticIPs(t *testing.T) {
+	ctrl, mockEC2 := setup(t)
+	defer ctrl.Finish()
+
+	free := &ec2.Address{AllocationId: nholuongut.String("eipalloc-1"), PublicIp: nholuongut.String("3.0.0.1")}
+	associated := &ec2.Address{
+		AllocationId:       nholuongut.String("eipalloc-2"),
+		PublicIp:           nholuongut.String("3.0.0.2"),
+		AssociationId:      nholuongut.String("eipassoc-2"),
+		NetworkInterfaceId: nholuongut.String(eniID),
+		PrivateIpAddress:   nholuongut.String("10.0.0.20"),
+	}
+	// Each filter is looked up on its own, and the Elastic IPs found by both are only returned once
+	mockEC2.EXPECT().DescribeAddressesWithContext(gomock.Any(), &ec2.DescribeAddressesInput{Filters: []*ec2.Filter{{
+		Name:   nholuongut.String("allocation-id"),
+		Values: nholuongut.StringSlice([]string{"eipalloc-1", "eipalloc-2"}),
+	}}}, gomock.Any()).Return(&ec2.DescribeAddressesOutput{Addresses: []*ec2.Address{free, associated}}, nil)
+	mockEC2.EXPECT().DescribeAddressesWithContext(gomock.Any(), &ec2.DescribeAddressesInput{Filters: []*ec2.Filter{{
+		Name:   nholuongut.String("network-interface-id"),
+		Values: nholuongut.StringSlice([]string{eniID}),
+	}}}, gomock.Any()).Return(&ec2.DescribeAddressesOutput{Addresses: []*ec2.Address{associated}}, nil)
+
+	cache := &EC2InstanceMetadataCache{ec2SVC: mockEC2}
+	eips, err := cache.DescribeElasticIPs([]string{"eipalloc-1", "eipalloc-2"}, eniID)
+	assert.NoError(t, err)
+	assert.Equal(t, []ElasticIP{
+		{AllocationID: "eipalloc-1", PublicIP: "3.0.0.1"},
+		{AllocationID: "eipalloc-2", PublicIP: "3.0.0.2", AssociationID: "eipassoc-2", NetworkInterfaceID: eniID, PrivateIP: "10.0.0.20"},
+	}, eips)
+
+	// Nothing to look up
+	eips, err = cache.DescribeElasticIPs(nil, "")
+	assert.NoError(t, err)
+	assert.Empty(t, eips)
+}
+
 func TestAllocIPAddresses(t *testing.T) {
 	ctrl, mockEC2 := setup(t)
 	defer ctrl.Finish()
@@ -945,6 +1095,31 @@ func TestAllocIPAddressesAlreadyFull(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestAllocIPv4Cidr(t *testing.T) {
+	ctrl, mockEC2 := setup(t)
+	defer ctrl.Finish()
+	cache := &EC2InstanceMetadataCache{ec2SVC: mockEC2, instanceType: "c5n.18xlarge"}
+
+	// A /32 is allocated as a secondary IP
+	input := &ec2.AssignPrivateIpAddressesInput{
+		NetworkInterfaceId: nholuongut.String(eniID),
+		PrivateIpAddresses: nholuongut.StringSlice([]string{"10.0.0.5"}),
+	}
+	mockEC2.EXPECT().AssignPrivateIpAddressesWithContext(gomock.Any(), input, gomock.Any()).Return(nil, nil)
+	assert.NoError(t, cache.AllocIPv4Cidr(eniID, net.IPNet{IP: net.ParseIP("10.0.0.5"), Mask: net.CIDRMask(32, 32)}))
+
+	// Anything else is allocated as a prefix
+	input = &ec2.AssignPrivateIpAddressesInput{
+		NetworkInterfaceId: nholuongut.String(eniID),
+		Ipv4Prefixes:       nholuongut.StringSlice([]string{"10.0.0.16/28"}),
+	}
+	retErr := nholuonguterr.New("InvalidParameterValue", "Address is in use", nil)
+	mockEC2.EXPECT().AssignPrivateIpAddressesWithContext(gomock.Any(), input, gomock.Any()).Return(nil, retErr)
+	err := cache.AllocIPv4Cidr(eniID, net.IPNet{IP: net.ParseIP("10.0.0.16").To4(), Mask: net.CIDRMask(28, 32)})
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, retErr))
+}
+
 func TestAllocPrefixAddresses(t *testing.T) {
 	ctrl, mockEC2 := setup(t)
 	defer ctrl.Finish()
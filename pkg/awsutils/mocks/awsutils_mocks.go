@@ -22,9 +22,8 @@ import (
 	net "net"
 	reflect "reflect"
 
-	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
-
 	nholuongututils "github.com/nholuongut/amazon-vpc-cni-k8s/pkg/nholuongututils"
+	datastore "github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
 	vpc "github.com/nholuongut/amazon-vpc-cni-k8s/pkg/vpc"
 	ec2 "github.com/nholuongut/nholuongut-sdk-go/service/ec2"
 	gomock "github.com/golang/mock/gomock"
@@ -54,18 +53,33 @@ func (m *MockAPIs) EXPECT() *MockAPIsMockRecorder {
 }
 
 // AllocENI mocks base method.
-func (m *MockAPIs) AllocENI(arg0 bool, arg1 []*string, arg2 string, arg3 int) (string, error) {
+func (m *MockAPIs) AllocENI(arg0 bool, arg1 []*string, arg2 string, arg3 int, arg4 nholuongututils.ENIOptions) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AllocENI", arg0, arg1, arg2, arg3)
+	ret := m.ctrl.Call(m, "AllocENI", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // AllocENI indicates an expected call of AllocENI.
-func (mr *MockAPIsMockRecorder) AllocENI(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockAPIsMockRecorder) AllocENI(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllocENI", reflect.TypeOf((*MockAPIs)(nil).AllocENI), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllocENI", reflect.TypeOf((*MockAPIs)(nil).AllocENI), arg0, arg1, arg2, arg3, arg4)
+}
+
+// AllocEgressENI mocks base method.
+func (m *MockAPIs) AllocEgressENI(arg0 []*string, arg1 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AllocEgressENI", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AllocEgressENI indicates an expected call of AllocEgressENI.
+func (mr *MockAPIsMockRecorder) AllocEgressENI(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllocEgressENI", reflect.TypeOf((*MockAPIs)(nil).AllocEgressENI), arg0, arg1)
 }
 
 // AllocIPAddress mocks base method.
@@ -97,6 +111,35 @@ func (mr *MockAPIsMockRecorder) AllocIPAddresses(arg0, arg1 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllocIPAddresses", reflect.TypeOf((*MockAPIs)(nil).AllocIPAddresses), arg0, arg1)
 }
 
+// AllocIPPoolENI mocks base method.
+func (m *MockAPIs) AllocIPPoolENI(arg0 string, arg1 []*string, arg2 string, arg3 int, arg4 nholuongututils.ENIOptions) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AllocIPPoolENI", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AllocIPPoolENI indicates an expected call of AllocIPPoolENI.
+func (mr *MockAPIsMockRecorder) AllocIPPoolENI(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllocIPPoolENI", reflect.TypeOf((*MockAPIs)(nil).AllocIPPoolENI), arg0, arg1, arg2, arg3, arg4)
+}
+
+// AllocIPv4Cidr mocks base method.
+func (m *MockAPIs) AllocIPv4Cidr(arg0 string, arg1 net.IPNet) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AllocIPv4Cidr", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AllocIPv4Cidr indicates an expected call of AllocIPv4Cidr.
+func (mr *MockAPIsMockRecorder) AllocIPv4Cidr(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllocIPv4Cidr", reflect.TypeOf((*MockAPIs)(nil).AllocIPv4Cidr), arg0, arg1)
+}
+
 // AllocIPv6Prefixes mocks base method.
 func (m *MockAPIs) AllocIPv6Prefixes(arg0 string) ([]*string, error) {
 	m.ctrl.T.Helper()
@@ -112,6 +155,36 @@ func (mr *MockAPIsMockRecorder) AllocIPv6Prefixes(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllocIPv6Prefixes", reflect.TypeOf((*MockAPIs)(nil).AllocIPv6Prefixes), arg0)
 }
 
+// AllocSecondaryIP mocks base method.
+func (m *MockAPIs) AllocSecondaryIP(arg0 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AllocSecondaryIP", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AllocSecondaryIP indicates an expected call of AllocSecondaryIP.
+func (mr *MockAPIsMockRecorder) AllocSecondaryIP(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllocSecondaryIP", reflect.TypeOf((*MockAPIs)(nil).AllocSecondaryIP), arg0)
+}
+
+// AssociateElasticIP mocks base method.
+func (m *MockAPIs) AssociateElasticIP(arg0, arg1, arg2 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssociateElasticIP", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AssociateElasticIP indicates an expected call of AssociateElasticIP.
+func (mr *MockAPIsMockRecorder) AssociateElasticIP(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssociateElasticIP", reflect.TypeOf((*MockAPIs)(nil).AssociateElasticIP), arg0, arg1, arg2)
+}
+
 // DeallocIPAddresses mocks base method.
 func (m *MockAPIs) DeallocIPAddresses(arg0 string, arg1 []string) error {
 	m.ctrl.T.Helper()
@@ -155,6 +228,35 @@ func (mr *MockAPIsMockRecorder) DescribeAllENIs() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeAllENIs", reflect.TypeOf((*MockAPIs)(nil).DescribeAllENIs))
 }
 
+// DescribeElasticIPs mocks base method.
+func (m *MockAPIs) DescribeElasticIPs(arg0 []string, arg1 string) ([]nholuongututils.ElasticIP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DescribeElasticIPs", arg0, arg1)
+	ret0, _ := ret[0].([]nholuongututils.ElasticIP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeElasticIPs indicates an expected call of DescribeElasticIPs.
+func (mr *MockAPIsMockRecorder) DescribeElasticIPs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeElasticIPs", reflect.TypeOf((*MockAPIs)(nil).DescribeElasticIPs), arg0, arg1)
+}
+
+// DisassociateElasticIP mocks base method.
+func (m *MockAPIs) DisassociateElasticIP(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisassociateElasticIP", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisassociateElasticIP indicates an expected call of DisassociateElasticIP.
+func (mr *MockAPIsMockRecorder) DisassociateElasticIP(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisassociateElasticIP", reflect.TypeOf((*MockAPIs)(nil).DisassociateElasticIP), arg0)
+}
+
 // FetchInstanceTypeLimits mocks base method.
 func (m *MockAPIs) FetchInstanceTypeLimits() error {
 	m.ctrl.T.Helper()
@@ -369,6 +471,50 @@ func (mr *MockAPIsMockRecorder) GetPrimaryENImac() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrimaryENImac", reflect.TypeOf((*MockAPIs)(nil).GetPrimaryENImac))
 }
 
+// GetSecurityGroupsFromEC2 mocks base method.
+func (m *MockAPIs) GetSecurityGroupsFromEC2(arg0 []string) ([]*ec2.SecurityGroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSecurityGroupsFromEC2", arg0)
+	ret0, _ := ret[0].([]*ec2.SecurityGroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSecurityGroupsFromEC2 indicates an expected call of GetSecurityGroupsFromEC2.
+func (mr *MockAPIsMockRecorder) GetSecurityGroupsFromEC2(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSecurityGroupsFromEC2", reflect.TypeOf((*MockAPIs)(nil).GetSecurityGroupsFromEC2), arg0)
+}
+
+// GetSubnetsFromEC2 mocks base method.
+func (m *MockAPIs) GetSubnetsFromEC2(arg0 []string) ([]*ec2.Subnet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubnetsFromEC2", arg0)
+	ret0, _ := ret[0].([]*ec2.Subnet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubnetsFromEC2 indicates an expected call of GetSubnetsFromEC2.
+func (mr *MockAPIsMockRecorder) GetSubnetsFromEC2(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubnetsFromEC2", reflect.TypeOf((*MockAPIs)(nil).GetSubnetsFromEC2), arg0)
+}
+
+// GetVPCID mocks base method.
+func (m *MockAPIs) GetVPCID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVPCID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetVPCID indicates an expected call of GetVPCID.
+func (mr *MockAPIsMockRecorder) GetVPCID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVPCID", reflect.TypeOf((*MockAPIs)(nil).GetVPCID))
+}
+
 // GetVPCIPv4CIDRs mocks base method.
 func (m *MockAPIs) GetVPCIPv4CIDRs() ([]string, error) {
 	m.ctrl.T.Helper()
@@ -468,17 +614,17 @@ func (mr *MockAPIsMockRecorder) IsUnmanagedENI(arg0 interface{}) *gomock.Call {
 }
 
 // RefreshSGIDs mocks base method.
-func (m *MockAPIs) RefreshSGIDs(mac string, store *datastore.DataStore) error {
+func (m *MockAPIs) RefreshSGIDs(arg0 string, arg1 *datastore.DataStore) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RefreshSGIDs", mac, store)
+	ret := m.ctrl.Call(m, "RefreshSGIDs", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // RefreshSGIDs indicates an expected call of RefreshSGIDs.
-func (mr *MockAPIsMockRecorder) RefreshSGIDs(mac, store interface{}) *gomock.Call {
+func (mr *MockAPIsMockRecorder) RefreshSGIDs(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshSGIDs", reflect.TypeOf((*MockAPIs)(nil).RefreshSGIDs), mac, store)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshSGIDs", reflect.TypeOf((*MockAPIs)(nil).RefreshSGIDs), arg0, arg1)
 }
 
 // SetMultiCardENIs mocks base method.
@@ -0,0 +1,18 @@
+package datapath
+
+type Mode string
+
+const (
+	// ModeIPRule routes the traffic of the pods with a to-pod and a from-pod ip rule per pod
+	ModeIPRule Mode = "iprule"
+	// ModeEBPF routes the traffic of the pods with a tc/eBPF program on their host veth, which marks the traffic from
+	// the pod with its route table, and with ip rules per ENI rather than per pod
+	ModeEBPF Mode = "ebpf"
+)
+
+const (
+	// DefaultMode is the default datapath if not specified explicitly.
+	DefaultMode Mode = ModeIPRule
+	// environment variable knob to decide the datapath of the pods.
+	envMode = "POD_DATAPATH"
+)
@@ -0,0 +1,18 @@
+package datapath
+
+import (
+	"os"
+)
+
+// LoadModeFromEnv tries to load the datapath from environment variable and fall-back to DefaultMode.
+func LoadModeFromEnv() Mode {
+	envVal, _ := os.LookupEnv(envMode)
+	switch envVal {
+	case string(ModeIPRule):
+		return ModeIPRule
+	case string(ModeEBPF):
+		return ModeEBPF
+	default:
+		return DefaultMode
+	}
+}
@@ -0,0 +1,45 @@
+package datapath
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadModeFromEnv(t *testing.T) {
+	tests := []struct {
+		name   string
+		envVal string
+		want   Mode
+	}{
+		{
+			name:   "use iprule datapath when POD_DATAPATH set to iprule",
+			envVal: "iprule",
+			want:   ModeIPRule,
+		},
+		{
+			name:   "use ebpf datapath when POD_DATAPATH set to ebpf",
+			envVal: "ebpf",
+			want:   ModeEBPF,
+		},
+		{
+			name: "default to iprule datapath when POD_DATAPATH not set",
+			want: ModeIPRule,
+		},
+		{
+			name:   "default to iprule datapath when POD_DATAPATH incorrectly configured",
+			envVal: "xdp",
+			want:   ModeIPRule,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.envVal != "" {
+				os.Setenv(envMode, tt.envVal)
+				defer os.Unsetenv(envMode)
+			}
+			assert.Equal(t, tt.want, LoadModeFromEnv())
+		})
+	}
+}
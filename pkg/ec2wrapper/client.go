@@ -37,6 +37,10 @@ type EC2 interface {
 	CreateTagsWithContext(ctx nholuongut.Context, input *ec2svc.CreateTagsInput, opts ...request.Option) (*ec2svc.CreateTagsOutput, error)
 	DescribeNetworkInterfacesPagesWithContext(ctx nholuongut.Context, input *ec2svc.DescribeNetworkInterfacesInput, fn func(*ec2svc.DescribeNetworkInterfacesOutput, bool) bool, opts ...request.Option) error
 	DescribeSubnetsWithContext(ctx nholuongut.Context, input *ec2svc.DescribeSubnetsInput, opts ...request.Option) (*ec2svc.DescribeSubnetsOutput, error)
+	DescribeSecurityGroupsWithContext(ctx nholuongut.Context, input *ec2svc.DescribeSecurityGroupsInput, opts ...request.Option) (*ec2svc.DescribeSecurityGroupsOutput, error)
+	DescribeAddressesWithContext(ctx nholuongut.Context, input *ec2svc.DescribeAddressesInput, opts ...request.Option) (*ec2svc.DescribeAddressesOutput, error)
+	AssociateAddressWithContext(ctx nholuongut.Context, input *ec2svc.AssociateAddressInput, opts ...request.Option) (*ec2svc.AssociateAddressOutput, error)
+	DisassociateAddressWithContext(ctx nholuongut.Context, input *ec2svc.DisassociateAddressInput, opts ...request.Option) (*ec2svc.DisassociateAddressOutput, error)
 }
 
 // New creates a new EC2 wrapper
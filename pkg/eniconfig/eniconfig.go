@@ -81,17 +81,19 @@ func MyENIConfig(ctx context.Context, k8sClient client.Client) (*v1alpha1.ENICon
 	}
 
 	log.Infof("Found ENI Config Name: %s", eniConfigName)
+	return GetENIConfig(ctx, k8sClient, eniConfigName)
+}
+
+// GetENIConfig returns the ENIConfig with the given name
+func GetENIConfig(ctx context.Context, k8sClient client.Client, eniConfigName string) (*v1alpha1.ENIConfigSpec, error) {
 	var eniConfig v1alpha1.ENIConfig
-	err = k8sClient.Get(ctx, types.NamespacedName{Name: eniConfigName}, &eniConfig)
+	err := k8sClient.Get(ctx, types.NamespacedName{Name: eniConfigName}, &eniConfig)
 	if err != nil {
 		log.Errorf("error while retrieving eniconfig: %s", err)
 		return nil, ErrNoENIConfig
 	}
 
-	return &v1alpha1.ENIConfigSpec{
-		SecurityGroups: eniConfig.Spec.SecurityGroups,
-		Subnet:         eniConfig.Spec.Subnet,
-	}, nil
+	return eniConfig.Spec.DeepCopy(), nil
 }
 
 // getEniConfigAnnotationDef returns eniConfigAnnotation
@@ -0,0 +1,164 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package eniconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nholuongut/nholuongut-sdk-go/nholuongut"
+	"github.com/nholuongut/nholuongut-sdk-go/service/ec2"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/apis/crd/v1alpha1"
+)
+
+// Reasons of the ENIConfigStatus conditions
+const (
+	reasonSubnetAvailable          = "SubnetAvailable"
+	reasonSubnetNotFound           = "SubnetNotFound"
+	reasonSubnetFull               = "SubnetFull"
+	reasonAvailabilityZoneMismatch = "AvailabilityZoneMismatch"
+	reasonVPCMismatch              = "VPCMismatch"
+	reasonSecurityGroupsAvailable  = "SecurityGroupsAvailable"
+	reasonPrimaryENISecurityGroups = "PrimaryENISecurityGroups"
+	reasonSecurityGroupNotFound    = "SecurityGroupNotFound"
+	reasonValid                    = "Valid"
+)
+
+// ValidateENIConfig returns the status of eniConfig, given the EC2 view of its subnet and security groups and the
+// availability zones of the nodes using it. subnet is nil if it does not exist, and securityGroups holds the existing
+// groups by ID. Conditions keep their transition time as long as their status does not change.
+func ValidateENIConfig(eniConfig *v1alpha1.ENIConfig, vpcID string, subnet *ec2.Subnet,
+	securityGroups map[string]*ec2.SecurityGroup, nodeZones []string, now metav1.Time) v1alpha1.ENIConfigStatus {
+	status := v1alpha1.ENIConfigStatus{
+		Conditions:         append([]metav1.Condition(nil), eniConfig.Status.Conditions...),
+		NodeCount:          len(nodeZones),
+		ObservedGeneration: eniConfig.Generation,
+		LastValidatedTime:  &now,
+	}
+	// The security groups must be in the VPC of the ENI
+	eniVPCID := vpcID
+	if subnet != nil {
+		status.AvailabilityZone = nholuongut.StringValue(subnet.AvailabilityZone)
+		status.VPCID = nholuongut.StringValue(subnet.VpcId)
+		status.AvailableIPAddressCount = subnet.AvailableIpAddressCount
+		eniVPCID = status.VPCID
+	}
+
+	subnetValid := validateSubnet(eniConfig.Spec.Subnet, vpcID, subnet, nodeZones)
+	securityGroupsValid := validateSecurityGroups(eniConfig.Spec.SecurityGroups, eniVPCID, securityGroups)
+	ready := metav1.Condition{
+		Type:    v1alpha1.ENIConfigReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  reasonValid,
+		Message: "ENIs can be created with the ENIConfig",
+	}
+	for _, condition := range []metav1.Condition{subnetValid, securityGroupsValid} {
+		if condition.Status != metav1.ConditionTrue && ready.Status == metav1.ConditionTrue {
+			ready.Status = metav1.ConditionFalse
+			ready.Reason = condition.Reason
+			ready.Message = condition.Message
+		}
+	}
+
+	for _, condition := range []metav1.Condition{subnetValid, securityGroupsValid, ready} {
+		condition.ObservedGeneration = eniConfig.Generation
+		condition.LastTransitionTime = now
+		meta.SetStatusCondition(&status.Conditions, condition)
+	}
+	return status
+}
+
+// validateSubnet returns the SubnetValid condition
+func validateSubnet(subnetID, vpcID string, subnet *ec2.Subnet, nodeZones []string) metav1.Condition {
+	condition := metav1.Condition{Type: v1alpha1.ENIConfigSubnetValid, Status: metav1.ConditionFalse}
+	if subnet == nil {
+		condition.Reason = reasonSubnetNotFound
+		condition.Message = fmt.Sprintf("subnet %q does not exist", subnetID)
+		return condition
+	}
+
+	zone := nholuongut.StringValue(subnet.AvailabilityZone)
+	var otherZones []string
+	otherZoneNodes := 0
+	for _, nodeZone := range nodeZones {
+		if nodeZone == "" || nodeZone == zone {
+			continue
+		}
+		otherZoneNodes++
+		if !lo.Contains(otherZones, nodeZone) {
+			otherZones = append(otherZones, nodeZone)
+		}
+	}
+	sort.Strings(otherZones)
+
+	switch {
+	case vpcID != "" && nholuongut.StringValue(subnet.VpcId) != vpcID:
+		condition.Reason = reasonVPCMismatch
+		condition.Message = fmt.Sprintf("subnet %s is in VPC %s, the nodes are in VPC %s", subnetID,
+			nholuongut.StringValue(subnet.VpcId), vpcID)
+	case otherZoneNodes > 0:
+		condition.Reason = reasonAvailabilityZoneMismatch
+		condition.Message = fmt.Sprintf("subnet %s is in %s, %d node(s) using the ENIConfig are in %s", subnetID, zone,
+			otherZoneNodes, strings.Join(otherZones, ", "))
+	case nholuongut.Int64Value(subnet.AvailableIpAddressCount) == 0:
+		condition.Reason = reasonSubnetFull
+		condition.Message = fmt.Sprintf("subnet %s has no free IP addresses", subnetID)
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonSubnetAvailable
+		condition.Message = fmt.Sprintf("subnet %s in %s has %d free IP addresses", subnetID, zone,
+			nholuongut.Int64Value(subnet.AvailableIpAddressCount))
+	}
+	return condition
+}
+
+// validateSecurityGroups returns the SecurityGroupsValid condition
+func validateSecurityGroups(groupIDs []string, vpcID string, securityGroups map[string]*ec2.SecurityGroup) metav1.Condition {
+	condition := metav1.Condition{Type: v1alpha1.ENIConfigSecurityGroupsValid, Status: metav1.ConditionFalse}
+	if len(groupIDs) == 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonPrimaryENISecurityGroups
+		condition.Message = "ENIs get the security groups of the primary ENI"
+		return condition
+	}
+
+	var missing, otherVPC []string
+	for _, groupID := range groupIDs {
+		securityGroup, ok := securityGroups[groupID]
+		if !ok {
+			missing = append(missing, groupID)
+		} else if vpcID != "" && nholuongut.StringValue(securityGroup.VpcId) != vpcID {
+			otherVPC = append(otherVPC, groupID)
+		}
+	}
+
+	switch {
+	case len(missing) > 0:
+		condition.Reason = reasonSecurityGroupNotFound
+		condition.Message = fmt.Sprintf("security groups %s do not exist", strings.Join(missing, ", "))
+	case len(otherVPC) > 0:
+		condition.Reason = reasonVPCMismatch
+		condition.Message = fmt.Sprintf("security groups %s are not in VPC %s", strings.Join(otherVPC, ", "), vpcID)
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonSecurityGroupsAvailable
+		condition.Message = fmt.Sprintf("security groups %s exist in VPC %s", strings.Join(groupIDs, ", "), vpcID)
+	}
+	return condition
+}
@@ -0,0 +1,107 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package eniconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nholuongut/nholuongut-sdk-go/nholuongut"
+	"github.com/nholuongut/nholuongut-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/apis/crd/v1alpha1"
+)
+
+func TestValidateENIConfig(t *testing.T) {
+	eniConfig := &v1alpha1.ENIConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "us-west-2a", Generation: 2},
+		Spec:       v1alpha1.ENIConfigSpec{Subnet: "subnet-1", SecurityGroups: []string{"sg-1", "sg-2"}},
+	}
+	subnet := &ec2.Subnet{
+		SubnetId:                nholuongut.String("subnet-1"),
+		VpcId:                   nholuongut.String("vpc-1"),
+		AvailabilityZone:        nholuongut.String("us-west-2a"),
+		AvailableIpAddressCount: nholuongut.Int64(250),
+	}
+	securityGroups := map[string]*ec2.SecurityGroup{
+		"sg-1": {GroupId: nholuongut.String("sg-1"), VpcId: nholuongut.String("vpc-1")},
+		"sg-2": {GroupId: nholuongut.String("sg-2"), VpcId: nholuongut.String("vpc-1")},
+	}
+
+	tests := []struct {
+		name              string
+		subnet            *ec2.Subnet
+		securityGroups    map[string]*ec2.SecurityGroup
+		nodeZones         []string
+		wantReady         metav1.ConditionStatus
+		wantSubnet        string
+		wantSecurityGroup string
+	}{
+		{"valid", subnet, securityGroups, []string{"us-west-2a", "us-west-2a", ""}, metav1.ConditionTrue,
+			reasonSubnetAvailable, reasonSecurityGroupsAvailable},
+		{"subnet not found", nil, securityGroups, nil, metav1.ConditionFalse,
+			reasonSubnetNotFound, reasonSecurityGroupsAvailable},
+		{"other zone", subnet, securityGroups, []string{"us-west-2a", "us-west-2b"}, metav1.ConditionFalse,
+			reasonAvailabilityZoneMismatch, reasonSecurityGroupsAvailable},
+		{"subnet full", &ec2.Subnet{VpcId: nholuongut.String("vpc-1"), AvailabilityZone: nholuongut.String("us-west-2a"),
+			AvailableIpAddressCount: nholuongut.Int64(0)}, securityGroups, nil, metav1.ConditionFalse,
+			reasonSubnetFull, reasonSecurityGroupsAvailable},
+		{"subnet in other VPC", &ec2.Subnet{VpcId: nholuongut.String("vpc-2"), AvailabilityZone: nholuongut.String("us-west-2a"),
+			AvailableIpAddressCount: nholuongut.Int64(10)}, nil, nil, metav1.ConditionFalse,
+			reasonVPCMismatch, reasonSecurityGroupNotFound},
+		{"security group not found", subnet, map[string]*ec2.SecurityGroup{"sg-1": securityGroups["sg-1"]}, nil,
+			metav1.ConditionFalse, reasonSubnetAvailable, reasonSecurityGroupNotFound},
+		{"security group in other VPC", subnet, map[string]*ec2.SecurityGroup{"sg-1": securityGroups["sg-1"],
+			"sg-2": {VpcId: nholuongut.String("vpc-2")}}, nil, metav1.ConditionFalse, reasonSubnetAvailable, reasonVPCMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := metav1.NewTime(time.Now())
+			status := ValidateENIConfig(eniConfig, "vpc-1", tt.subnet, tt.securityGroups, tt.nodeZones, now)
+			assert.Equal(t, len(tt.nodeZones), status.NodeCount)
+			assert.Equal(t, int64(2), status.ObservedGeneration)
+			assert.Equal(t, &now, status.LastValidatedTime)
+			assert.Equal(t, tt.wantReady, meta.FindStatusCondition(status.Conditions, v1alpha1.ENIConfigReady).Status)
+			assert.Equal(t, tt.wantSubnet, meta.FindStatusCondition(status.Conditions, v1alpha1.ENIConfigSubnetValid).Reason)
+			assert.Equal(t, tt.wantSecurityGroup, meta.FindStatusCondition(status.Conditions, v1alpha1.ENIConfigSecurityGroupsValid).Reason)
+		})
+	}
+}
+
+func TestValidateENIConfigKeepsTransitionTime(t *testing.T) {
+	eniConfig := &v1alpha1.ENIConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec:       v1alpha1.ENIConfigSpec{Subnet: "subnet-1"},
+	}
+	subnet := &ec2.Subnet{
+		VpcId:                   nholuongut.String("vpc-1"),
+		AvailabilityZone:        nholuongut.String("us-west-2a"),
+		AvailableIpAddressCount: nholuongut.Int64(250),
+	}
+
+	first := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	eniConfig.Status = ValidateENIConfig(eniConfig, "vpc-1", subnet, nil, nil, first)
+	assert.Equal(t, reasonPrimaryENISecurityGroups,
+		meta.FindStatusCondition(eniConfig.Status.Conditions, v1alpha1.ENIConfigSecurityGroupsValid).Reason)
+
+	// Fewer free addresses don't change the state of the subnet
+	subnet.AvailableIpAddressCount = nholuongut.Int64(100)
+	status := ValidateENIConfig(eniConfig, "vpc-1", subnet, nil, nil, metav1.NewTime(time.Now()))
+	assert.Equal(t, int64(100), *status.AvailableIPAddressCount)
+	assert.Equal(t, first, meta.FindStatusCondition(status.Conditions, v1alpha1.ENIConfigSubnetValid).LastTransitionTime)
+	// The previous status is left alone
+	assert.Equal(t, int64(250), *eniConfig.Status.AvailableIPAddressCount)
+}
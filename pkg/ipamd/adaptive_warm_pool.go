@@ -0,0 +1,198 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/utils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/utils/prometheusmetrics"
+)
+
+const (
+	// envAdaptiveWarmPool (default false) raises or lowers the warm IP target by the pods waiting for an address on
+	// the node and by the AddNetwork and DelNetwork calls of the last minute. It only applies when WARM_IP_TARGET or
+	// MINIMUM_IP_TARGET is set.
+	envAdaptiveWarmPool = "ENABLE_ADAPTIVE_WARM_POOL"
+
+	// envAdaptiveWarmIPTargetMin (default 0) is the lowest warm IP target of the adaptive warm pool
+	envAdaptiveWarmIPTargetMin = "ADAPTIVE_WARM_IP_TARGET_MIN"
+
+	// envAdaptiveWarmIPTargetMax (default WARM_IP_TARGET plus the IPs of one ENI) is the highest warm IP target of the
+	// adaptive warm pool
+	envAdaptiveWarmIPTargetMax = "ADAPTIVE_WARM_IP_TARGET_MAX"
+
+	// adaptiveWarmPoolWindow is how long AddNetwork and DelNetwork calls are counted for
+	adaptiveWarmPoolWindow = time.Minute
+)
+
+// adaptiveWarmPool tracks the pod churn of the node, and holds the warm IP target derived from it
+type adaptiveWarmPool struct {
+	lock sync.Mutex
+	// adds and dels are the times of the AddNetwork and DelNetwork calls within the window, oldest first
+	adds []time.Time
+	dels []time.Time
+	// minTarget and maxTarget bound the warm IP target, maxTarget is unset when it follows WARM_IP_TARGET
+	minTarget int
+	maxTarget int
+	// warmIPTarget is the last computed target, valid once updated is set
+	warmIPTarget int
+	updated      bool
+}
+
+// newAdaptiveWarmPool returns the adaptive warm pool if it is enabled, nil otherwise
+func newAdaptiveWarmPool() *adaptiveWarmPool {
+	if !utils.GetBoolAsStringEnvVar(envAdaptiveWarmPool, false) {
+		return nil
+	}
+	minTarget, _, _ := utils.GetIntFromStringEnvVar(envAdaptiveWarmIPTargetMin, 0)
+	maxTarget, _, _ := utils.GetIntFromStringEnvVar(envAdaptiveWarmIPTargetMax, noWarmIPTarget)
+	if minTarget < 0 {
+		minTarget = 0
+	}
+	if maxTarget != noWarmIPTarget && maxTarget < minTarget {
+		log.Warnf("%s is lower than %s, using %d for both", envAdaptiveWarmIPTargetMax, envAdaptiveWarmIPTargetMin, minTarget)
+		maxTarget = minTarget
+	}
+	log.Infof("Adaptive warm pool - minimum warm IP target: %d, maximum warm IP target: %d", minTarget, maxTarget)
+	return &adaptiveWarmPool{minTarget: minTarget, maxTarget: maxTarget}
+}
+
+// recordAdd counts an AddNetwork call
+func (p *adaptiveWarmPool) recordAdd(now time.Time) {
+	if p == nil {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.adds = append(pruneChurn(p.adds, now), now)
+}
+
+// recordDel counts a DelNetwork call
+func (p *adaptiveWarmPool) recordDel(now time.Time) {
+	if p == nil {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.dels = append(pruneChurn(p.dels, now), now)
+}
+
+// churn returns the number of AddNetwork and DelNetwork calls within the window
+func (p *adaptiveWarmPool) churn(now time.Time) (adds, dels int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.adds = pruneChurn(p.adds, now)
+	p.dels = pruneChurn(p.dels, now)
+	return len(p.adds), len(p.dels)
+}
+
+// pruneChurn drops the calls which are out of the window
+func pruneChurn(calls []time.Time, now time.Time) []time.Time {
+	i := 0
+	for i < len(calls) && now.Sub(calls[i]) > adaptiveWarmPoolWindow {
+		i++
+	}
+	return calls[i:]
+}
+
+// target returns the warm IP target, once computed
+func (p *adaptiveWarmPool) target() (int, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.warmIPTarget, p.updated
+}
+
+// setTarget records the warm IP target, and returns the previous one
+func (p *adaptiveWarmPool) setTarget(warmIPTarget int) (int, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	previous, updated := p.warmIPTarget, p.updated
+	p.warmIPTarget, p.updated = warmIPTarget, true
+	return previous, updated
+}
+
+// effectiveWarmIPTarget returns the warm IP target the pools are sized by, which the adaptive warm pool adjusts when
+// enabled
+func (c *IPAMContext) effectiveWarmIPTarget() int {
+	if c.adaptiveWarmPool != nil {
+		if warmIPTarget, ok := c.adaptiveWarmPool.target(); ok {
+			return warmIPTarget
+		}
+	}
+	return c.warmIPTarget
+}
+
+// updateAdaptiveWarmPool computes the warm IP target from the configured one, the pods of the node waiting for an
+// address and the pod churn of the window, within the configured bounds
+func (c *IPAMContext) updateAdaptiveWarmPool(ctx context.Context, now time.Time) {
+	if c.adaptiveWarmPool == nil || !c.warmIPTargetsDefined() {
+		return
+	}
+	pendingPods, err := c.pendingPods(ctx)
+	if err != nil {
+		log.Debugf("Unable to list the pending pods of the node: %v", err)
+	}
+	adds, dels := c.adaptiveWarmPool.churn(now)
+
+	maxTarget := c.adaptiveWarmPool.maxTarget
+	if maxTarget == noWarmIPTarget {
+		maxTarget = c.warmIPTarget + c.maxIPsPerENI
+	}
+	warmIPTarget := min(max(c.warmIPTarget+pendingPods+adds-dels, c.adaptiveWarmPool.minTarget), maxTarget)
+
+	prometheusmetrics.AdaptivePendingPods.Set(float64(pendingPods))
+	prometheusmetrics.AdaptivePodChurn.With(prometheus.Labels{"call": "add"}).Set(float64(adds))
+	prometheusmetrics.AdaptivePodChurn.With(prometheus.Labels{"call": "del"}).Set(float64(dels))
+	prometheusmetrics.AdaptiveWarmIPTarget.Set(float64(warmIPTarget))
+
+	previous, updated := c.adaptiveWarmPool.setTarget(warmIPTarget)
+	if updated && previous == warmIPTarget {
+		return
+	}
+	// The first target is compared with the configured one, the next ones with the previous target
+	if !updated {
+		previous = c.warmIPTarget
+	}
+	direction := "raise"
+	if warmIPTarget == c.warmIPTarget {
+		direction = "reset"
+	} else if warmIPTarget < previous {
+		direction = "lower"
+	}
+	prometheusmetrics.AdaptiveWarmIPTargetChanges.With(prometheus.Labels{"direction": direction}).Inc()
+	log.Infof("Adaptive warm IP target is %d (configured: %d, pending pods: %d, adds: %d, dels: %d in the last %v)",
+		warmIPTarget, c.warmIPTarget, pendingPods, adds, dels, adaptiveWarmPoolWindow)
+}
+
+// pendingPods returns the number of pods scheduled to the node which are waiting for an address
+func (c *IPAMContext) pendingPods(ctx context.Context) (int, error) {
+	var pods corev1.PodList
+	if err := c.k8sClient.List(ctx, &pods); err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == c.myNodeName && !pod.Spec.HostNetwork && pod.Status.Phase == corev1.PodPending &&
+			pod.Status.PodIP == "" {
+			pending++
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,130 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/utils/prometheusmetrics"
+)
+
+func TestNewAdaptiveWarmPool(t *testing.T) {
+	assert.Nil(t, newAdaptiveWarmPool())
+
+	os.Setenv(envAdaptiveWarmPool, "true")
+	os.Setenv(envAdaptiveWarmIPTargetMin, "4")
+	os.Setenv(envAdaptiveWarmIPTargetMax, "2")
+	defer os.Unsetenv(envAdaptiveWarmPool)
+	defer os.Unsetenv(envAdaptiveWarmIPTargetMin)
+	defer os.Unsetenv(envAdaptiveWarmIPTargetMax)
+	pool := newAdaptiveWarmPool()
+	assert.Equal(t, 4, pool.minTarget)
+	assert.Equal(t, 4, pool.maxTarget)
+
+	os.Unsetenv(envAdaptiveWarmIPTargetMax)
+	pool = newAdaptiveWarmPool()
+	assert.Equal(t, noWarmIPTarget, pool.maxTarget)
+}
+
+func TestAdaptiveWarmPoolChurn(t *testing.T) {
+	pool := &adaptiveWarmPool{}
+	now := time.Now()
+	pool.recordAdd(now.Add(-2 * adaptiveWarmPoolWindow))
+	pool.recordAdd(now.Add(-time.Second))
+	pool.recordAdd(now)
+	pool.recordDel(now.Add(-2 * adaptiveWarmPoolWindow))
+	adds, dels := pool.churn(now)
+	assert.Equal(t, 2, adds)
+	assert.Equal(t, 0, dels)
+
+	// A disabled pool ignores the calls
+	var disabled *adaptiveWarmPool
+	disabled.recordAdd(now)
+	disabled.recordDel(now)
+}
+
+func TestUpdateAdaptiveWarmPool(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	for i, phase := range []corev1.PodPhase{corev1.PodPending, corev1.PodPending, corev1.PodRunning} {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-" + string(rune('a'+i)), Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: myNodeName},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+		assert.NoError(t, m.k8sClient.Create(ctx, pod))
+	}
+	hostNetworkPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "host", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: myNodeName, HostNetwork: true},
+		Status:     corev1.PodStatus{Phase: corev1.PodPending},
+	}
+	assert.NoError(t, m.k8sClient.Create(ctx, hostNetworkPod))
+
+	mockContext := &IPAMContext{
+		k8sClient:        m.k8sClient,
+		myNodeName:       myNodeName,
+		maxIPsPerENI:     14,
+		warmIPTarget:     2,
+		adaptiveWarmPool: &adaptiveWarmPool{minTarget: 1, maxTarget: noWarmIPTarget},
+	}
+	assert.Equal(t, 2, mockContext.effectiveWarmIPTarget())
+
+	// Two pending pods and a burst of three pods raise the target
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		mockContext.adaptiveWarmPool.recordAdd(now)
+	}
+	mockContext.updateAdaptiveWarmPool(ctx, now)
+	assert.Equal(t, 7, mockContext.effectiveWarmIPTarget())
+
+	// The target is capped at the IPs of one ENI over the configured one
+	for i := 0; i < 20; i++ {
+		mockContext.adaptiveWarmPool.recordAdd(now)
+	}
+	mockContext.updateAdaptiveWarmPool(ctx, now)
+	assert.Equal(t, 16, mockContext.effectiveWarmIPTarget())
+
+	// A lower target which is still above the configured one is counted as lowered
+	lowered := testutil.ToFloat64(prometheusmetrics.AdaptiveWarmIPTargetChanges.With(prometheus.Labels{"direction": "lower"}))
+	later := now.Add(2 * adaptiveWarmPoolWindow)
+	mockContext.adaptiveWarmPool.recordAdd(later)
+	mockContext.updateAdaptiveWarmPool(ctx, later)
+	assert.Equal(t, 5, mockContext.effectiveWarmIPTarget())
+	assert.Equal(t, lowered+1, testutil.ToFloat64(prometheusmetrics.AdaptiveWarmIPTargetChanges.With(prometheus.Labels{"direction": "lower"})))
+
+	// Once the burst is over and pods are deleted, the target is lowered down to the minimum
+	for i := 0; i < 6; i++ {
+		mockContext.adaptiveWarmPool.recordDel(later)
+	}
+	mockContext.updateAdaptiveWarmPool(ctx, later)
+	assert.Equal(t, 1, mockContext.effectiveWarmIPTarget())
+
+	// Without WARM_IP_TARGET or MINIMUM_IP_TARGET, the warm ENI target is used as is
+	mockContext.warmIPTarget = noWarmIPTarget
+	mockContext.adaptiveWarmPool = &adaptiveWarmPool{maxTarget: noWarmIPTarget}
+	mockContext.updateAdaptiveWarmPool(ctx, later)
+	assert.Equal(t, noWarmIPTarget, mockContext.effectiveWarmIPTarget())
+}
@@ -0,0 +1,103 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/rpc"
+)
+
+var allocationEventTypes = map[datastore.AllocationEventType]rpc.AllocationEvent_Type{
+	datastore.AllocationAssigned:   rpc.AllocationEvent_ASSIGN,
+	datastore.AllocationUnassigned: rpc.AllocationEvent_UNASSIGN,
+	datastore.ENIAttached:          rpc.AllocationEvent_ENI_ATTACH,
+	datastore.ENIDetached:          rpc.AllocationEvent_ENI_DETACH,
+	datastore.CidrAdded:            rpc.AllocationEvent_PREFIX_ADD,
+	datastore.CidrRemoved:          rpc.AllocationEvent_PREFIX_REMOVE,
+}
+
+// WatchAllocations sends the addresses assigned to pods, followed by a SNAPSHOT_END event and the changes of the
+// datastore until the client goes away. The stream is aborted if the client does not keep up with the changes.
+func (s *server) WatchAllocations(in *rpc.WatchAllocationsRequest, stream rpc.IPAMBackend_WatchAllocationsServer) error {
+	watcher := s.ipamContext.dataStore.WatchAllocations(datastore.DefaultWatchBufferSize)
+	defer watcher.Stop()
+	log.Infof("Allocation watch started with %d assigned addresses", len(watcher.Snapshot))
+
+	now := time.Now().UnixNano()
+	for _, info := range watcher.Snapshot {
+		if err := stream.Send(&rpc.AllocationEvent{
+			EventType: rpc.AllocationEvent_SNAPSHOT,
+			Key:       toRPCIPAMKey(info.IPAMKey),
+			Metadata:  toRPCIPAMMetadata(info.IPAMMetadata),
+			IPAddress: info.IP,
+			ENI:       info.ENIID,
+			Timestamp: now,
+		}); err != nil {
+			return err
+		}
+	}
+	if err := stream.Send(&rpc.AllocationEvent{EventType: rpc.AllocationEvent_SNAPSHOT_END, Timestamp: now}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			log.Infof("Allocation watch ended: %v", stream.Context().Err())
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return status.Error(codes.Aborted, "the watch fell behind the changes of the datastore, watch again")
+			}
+			if err := stream.Send(toRPCAllocationEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toRPCAllocationEvent(event datastore.AllocationEvent) *rpc.AllocationEvent {
+	rpcEvent := &rpc.AllocationEvent{
+		EventType: allocationEventTypes[event.Type],
+		IPAddress: event.IPAddress,
+		ENI:       event.ENI,
+		CIDR:      event.Cidr,
+		IsPrefix:  event.IsPrefix,
+		Timestamp: event.Timestamp.UnixNano(),
+	}
+	if !event.IPAMKey.IsZero() {
+		rpcEvent.Key = toRPCIPAMKey(event.IPAMKey)
+		rpcEvent.Metadata = toRPCIPAMMetadata(event.IPAMMetadata)
+	}
+	return rpcEvent
+}
+
+func toRPCIPAMKey(key datastore.IPAMKey) *rpc.IPAMKey {
+	return &rpc.IPAMKey{NetworkName: key.NetworkName, ContainerID: key.ContainerID, IfName: key.IfName}
+}
+
+func toRPCIPAMMetadata(metadata datastore.IPAMMetadata) *rpc.IPAMMetadata {
+	return &rpc.IPAMMetadata{
+		K8S_POD_NAMESPACE: metadata.K8SPodNamespace,
+		K8S_POD_NAME:      metadata.K8SPodName,
+		HostVethName:      metadata.HostVethName,
+		StickyIP:          metadata.StickyIP,
+		IPPool:            metadata.IPPool,
+	}
+}
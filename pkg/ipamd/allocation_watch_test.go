@@ -0,0 +1,89 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/rpc"
+)
+
+// fakeWatchAllocationsServer records the events sent on the stream
+type fakeWatchAllocationsServer struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events chan *rpc.AllocationEvent
+}
+
+func (f *fakeWatchAllocationsServer) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeWatchAllocationsServer) Send(event *rpc.AllocationEvent) error {
+	f.events <- event
+	return nil
+}
+
+func (f *fakeWatchAllocationsServer) next(t *testing.T) *rpc.AllocationEvent {
+	select {
+	case event := <-f.events:
+		assert.NotZero(t, event.Timestamp)
+		event.Timestamp = 0
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+		return nil
+	}
+}
+
+func TestServer_WatchAllocations(t *testing.T) {
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	assert.NoError(t, ds.AddENI("eni-1", 0, true, false, false))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP("10.0.0.1"), Mask: net.IPv4Mask(255, 255, 255, 255)}, false))
+	key := datastore.IPAMKey{NetworkName: "nholuongut-cni", ContainerID: "sandbox-1", IfName: "eth0"}
+	_, _, err := ds.AssignPodIPv4Address(key, datastore.IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "pod-1"})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &fakeWatchAllocationsServer{ctx: ctx, events: make(chan *rpc.AllocationEvent, 10)}
+	s := &server{ipamContext: &IPAMContext{dataStore: ds}}
+	done := make(chan error)
+	go func() {
+		done <- s.WatchAllocations(&rpc.WatchAllocationsRequest{}, stream)
+	}()
+
+	rpcKey := &rpc.IPAMKey{NetworkName: "nholuongut-cni", ContainerID: "sandbox-1", IfName: "eth0"}
+	rpcMetadata := &rpc.IPAMMetadata{K8S_POD_NAMESPACE: "default", K8S_POD_NAME: "pod-1"}
+	assert.Equal(t, &rpc.AllocationEvent{EventType: rpc.AllocationEvent_SNAPSHOT, Key: rpcKey, Metadata: rpcMetadata,
+		IPAddress: "10.0.0.1", ENI: "eni-1"}, stream.next(t))
+	assert.Equal(t, &rpc.AllocationEvent{EventType: rpc.AllocationEvent_SNAPSHOT_END}, stream.next(t))
+
+	assert.NoError(t, ds.AddENI("eni-2", 1, false, false, false))
+	assert.Equal(t, &rpc.AllocationEvent{EventType: rpc.AllocationEvent_ENI_ATTACH, ENI: "eni-2"}, stream.next(t))
+	_, _, _, _, err = ds.UnassignPodIPAddress(key)
+	assert.NoError(t, err)
+	assert.Equal(t, &rpc.AllocationEvent{EventType: rpc.AllocationEvent_UNASSIGN, Key: rpcKey, Metadata: rpcMetadata,
+		IPAddress: "10.0.0.1", ENI: "eni-1"}, stream.next(t))
+
+	// The watch ends with the stream
+	cancel()
+	assert.NoError(t, <-done)
+}
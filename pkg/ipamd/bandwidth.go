@@ -0,0 +1,58 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// ingressBandwidthKey is the standard annotation limiting the traffic to the pod, e.g. "10M" for 10 Mbit/s
+	ingressBandwidthKey = "kubernetes.io/ingress-bandwidth"
+	// egressBandwidthKey is the standard annotation limiting the traffic from the pod
+	egressBandwidthKey = "kubernetes.io/egress-bandwidth"
+)
+
+// Same bounds as the kubelet, in bits per second
+var (
+	minBandwidth = resource.MustParse("1k")
+	maxBandwidth = resource.MustParse("1P")
+)
+
+// podBandwidth returns the rate limits of the bandwidth annotations of a pod in bits per second, 0 if unlimited
+func podBandwidth(podAnnotations map[string]string) (ingress uint64, egress uint64, err error) {
+	if ingress, err = parseBandwidth(podAnnotations, ingressBandwidthKey); err != nil {
+		return 0, 0, err
+	}
+	if egress, err = parseBandwidth(podAnnotations, egressBandwidthKey); err != nil {
+		return 0, 0, err
+	}
+	return ingress, egress, nil
+}
+
+func parseBandwidth(podAnnotations map[string]string, key string) (uint64, error) {
+	val, ok := podAnnotations[key]
+	if !ok {
+		return 0, nil
+	}
+	quantity, err := resource.ParseQuantity(val)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid %s annotation %q", key, val)
+	}
+	if quantity.Cmp(minBandwidth) < 0 || quantity.Cmp(maxBandwidth) > 0 {
+		return 0, errors.Errorf("%s annotation %q must be between %s and %s", key, val, minBandwidth.String(), maxBandwidth.String())
+	}
+	return uint64(quantity.Value()), nil
+}
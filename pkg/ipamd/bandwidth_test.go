@@ -0,0 +1,125 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	pb "github.com/nholuongut/amazon-vpc-cni-k8s/rpc"
+)
+
+func TestPodBandwidth(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantIngress uint64
+		wantEgress  uint64
+		wantErr     string
+	}{
+		{name: "unlimited"},
+		{name: "ingress", annotations: map[string]string{ingressBandwidthKey: "10M"}, wantIngress: 10_000_000},
+		{name: "egress", annotations: map[string]string{egressBandwidthKey: "1Gi"}, wantEgress: 1 << 30},
+		{
+			name:        "both",
+			annotations: map[string]string{ingressBandwidthKey: "500k", egressBandwidthKey: "2G"},
+			wantIngress: 500_000,
+			wantEgress:  2_000_000_000,
+		},
+		{name: "not a quantity", annotations: map[string]string{ingressBandwidthKey: "fast"}, wantErr: `invalid kubernetes.io/ingress-bandwidth annotation "fast"`},
+		{name: "too low", annotations: map[string]string{egressBandwidthKey: "100"}, wantErr: "must be between 1k and 1P"},
+		{name: "too high", annotations: map[string]string{egressBandwidthKey: "2P"}, wantErr: "must be between 1k and 1P"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingress, egress, err := podBandwidth(tt.annotations)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantIngress, ingress)
+			assert.Equal(t, tt.wantEgress, egress)
+		})
+	}
+}
+
+func TestServer_BandwidthPod(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	assert.NoError(t, ds.AddENI("eni-1", 0, true, false, false))
+	for _, addr := range []string{"10.0.0.1", "10.0.0.2"} {
+		assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP(addr), Mask: net.CIDRMask(32, 32)}, false))
+	}
+	m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return([]string{"10.0.0.0/8"}, nil).Times(2)
+	m.network.EXPECT().UseExternalSNAT().Return(true).Times(2)
+
+	s := &server{
+		version: "1.2.3",
+		ipamContext: &IPAMContext{
+			nholuongutClient:     m.nholuongututils,
+			k8sClient:     m.k8sClient,
+			networkClient: m.network,
+			dataStore:     ds,
+			enableIPv4:    true,
+			maxIPsPerENI:  14,
+			vethPrefix:    "eni",
+		},
+	}
+
+	assert.NoError(t, m.k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}))
+	for i, tc := range []struct {
+		enableBandwidthPlugin bool
+		wantIngress           uint64
+		wantEgress            uint64
+	}{
+		{wantIngress: 10_000_000, wantEgress: 20_000_000},
+		// The chained bandwidth plugin shapes the traffic instead
+		{enableBandwidthPlugin: true},
+	} {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        fmt.Sprintf("shaped-%d", i),
+				Namespace:   "default",
+				Annotations: map[string]string{ingressBandwidthKey: "10M", egressBandwidthKey: "20M"},
+			},
+		}
+		assert.NoError(t, m.k8sClient.Create(ctx, pod))
+		s.ipamContext.enableBandwidthPlugin = tc.enableBandwidthPlugin
+
+		resp, err := s.AddNetwork(ctx, &pb.AddNetworkRequest{
+			ClientVersion:     "1.2.3",
+			K8S_POD_NAME:      pod.Name,
+			K8S_POD_NAMESPACE: pod.Namespace,
+			Netns:             "netns",
+			NetworkName:       "net0",
+			ContainerID:       fmt.Sprintf("cid-%d", i),
+			IfName:            "eth0",
+		})
+		assert.NoError(t, err)
+		assert.True(t, resp.Success)
+		assert.Equal(t, tc.wantIngress, resp.IngressBandwidth)
+		assert.Equal(t, tc.wantEgress, resp.EgressBandwidth)
+	}
+}
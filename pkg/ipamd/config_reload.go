@@ -0,0 +1,294 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/k8sapi"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/networkutils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/eventrecorder"
+)
+
+const (
+	// envIPAMDConfigMap is the ConfigMap, given as name or namespace/name, which holds ipamd settings applied
+	// without restarting nholuongut-node. Its keys are the names of the env variables they replace.
+	envIPAMDConfigMap = "IPAMD_CONFIG_MAP"
+
+	// defaultConfigMapNamespace is the namespace of the ConfigMap when IPAMD_CONFIG_MAP does not name one
+	defaultConfigMapNamespace = "kube-system"
+)
+
+// reloadableEnvs are the env variables which the ConfigMap can set
+var reloadableEnvs = append([]string{envWarmENITarget, envWarmIPTarget, envMinimumIPTarget, envWarmPrefixTarget, envMaxENI},
+	networkutils.ReloadableEnvs...)
+
+// configReloader holds the latest data of the ConfigMap until the IP pool manager applies it
+type configReloader struct {
+	lock sync.Mutex
+	// configMap is the namespace/name of the ConfigMap
+	configMap string
+	// baseEnv holds the reloadable env variables nholuongut-node was started with, they apply again once the
+	// ConfigMap no longer sets them
+	baseEnv map[string]string
+	// env holds the values of the reloadable env variables which are applied, it is only used by the pool manager
+	env     map[string]string
+	data    map[string]string
+	updated bool
+}
+
+func newConfigReloader(configMap string) *configReloader {
+	baseEnv := make(map[string]string)
+	for _, envVar := range reloadableEnvs {
+		if value, found := os.LookupEnv(envVar); found {
+			baseEnv[envVar] = value
+		}
+	}
+	return &configReloader{configMap: configMap, baseEnv: baseEnv, env: baseEnv}
+}
+
+// reloadableConfig holds the settings which the ConfigMap of IPAMD_CONFIG_MAP can change
+type reloadableConfig struct {
+	warmENITarget    int
+	warmIPTarget     int
+	minimumIPTarget  int
+	warmPrefixTarget int
+	// maxENI is the MAX_ENI setting, before the ENI limit of the instance applies
+	maxENI int
+	// snatEnv holds the values of the SNAT env variables which are set
+	snatEnv map[string]string
+}
+
+// loadReloadableConfig reads the reloadable settings, looking up their env variable with lookupEnv
+func loadReloadableConfig(lookupEnv func(string) (string, bool)) reloadableConfig {
+	snatEnv := make(map[string]string)
+	for _, envVar := range networkutils.ReloadableEnvs {
+		if value, found := lookupEnv(envVar); found {
+			snatEnv[envVar] = value
+		}
+	}
+	return reloadableConfig{
+		warmENITarget:    parseWarmENITarget(lookupEnv(envWarmENITarget)),
+		warmIPTarget:     parseWarmIPTarget(lookupEnv(envWarmIPTarget)),
+		minimumIPTarget:  parseMinimumIPTarget(lookupEnv(envMinimumIPTarget)),
+		warmPrefixTarget: parseWarmPrefixTarget(lookupEnv(envWarmPrefixTarget)),
+		maxENI:           parseMaxENI(lookupEnv(envMaxENI)),
+		snatEnv:          snatEnv,
+	}
+}
+
+// set records the data of the ConfigMap, nil if it was deleted
+func (r *configReloader) set(data map[string]string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.data = data
+	r.updated = true
+}
+
+// take returns the data of the ConfigMap if it changed since the last call
+func (r *configReloader) take() (map[string]string, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !r.updated {
+		return nil, false
+	}
+	r.updated = false
+	return r.data, true
+}
+
+// StartConfigReloader watches the ConfigMap named by IPAMD_CONFIG_MAP, if set. Its changes are applied by the IP pool
+// manager.
+func (c *IPAMContext) StartConfigReloader() {
+	configMap := os.Getenv(envIPAMDConfigMap)
+	if configMap == "" {
+		return
+	}
+	if !c.enableIPv4 {
+		log.Infof("%s is ignored, none of the reloadable settings apply in IPv6 mode", envIPAMDConfigMap)
+		return
+	}
+	namespace, name := defaultConfigMapNamespace, configMap
+	if i := strings.Index(configMap, "/"); i >= 0 {
+		namespace, name = configMap[:i], configMap[i+1:]
+	}
+	clientSet, err := k8sapi.GetKubeClientSet()
+	if err != nil {
+		log.Errorf("Failed to create clientset, ConfigMap %s/%s is not watched: %v", namespace, name, err)
+		return
+	}
+
+	c.configReloader = newConfigReloader(namespace + "/" + name)
+	onChange := func(obj interface{}) {
+		if configMap, ok := obj.(*corev1.ConfigMap); ok {
+			log.Infof("ConfigMap %s changed", c.configReloader.configMap)
+			c.configReloader.set(configMap.Data)
+		}
+	}
+	_, controller := cache.NewInformerWithOptions(cache.InformerOptions{
+		ListerWatcher: cache.NewListWatchFromClient(clientSet.CoreV1().RESTClient(), "configmaps", namespace,
+			fields.OneTermEqualSelector("metadata.name", name)),
+		ObjectType: &corev1.ConfigMap{},
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    onChange,
+			UpdateFunc: func(_, obj interface{}) { onChange(obj) },
+			DeleteFunc: func(interface{}) {
+				log.Infof("ConfigMap %s was deleted", c.configReloader.configMap)
+				c.configReloader.set(nil)
+			},
+		},
+	})
+	log.Infof("Watching ConfigMap %s for ipamd settings", c.configReloader.configMap)
+	go controller.Run(wait.NeverStop)
+}
+
+// applyReloadedConfig applies the latest data of the ConfigMap, and records the outcome as an event on the
+// nholuongut-node pod
+func (c *IPAMContext) applyReloadedConfig() {
+	if c.configReloader == nil {
+		return
+	}
+	data, ok := c.configReloader.take()
+	if !ok {
+		return
+	}
+	changed, err := c.reloadConfig(data)
+	if err != nil {
+		log.Errorf("Failed to apply ConfigMap %s: %v", c.configReloader.configMap, err)
+		sendConfigReloadEvent(corev1.EventTypeWarning, fmt.Sprintf("Failed to apply ConfigMap %s: %v",
+			c.configReloader.configMap, err))
+		return
+	}
+	if len(changed) == 0 {
+		return
+	}
+	var settings []string
+	for _, envVar := range changed {
+		if value, found := c.configReloader.env[envVar]; found {
+			settings = append(settings, envVar+"="+value)
+		} else {
+			settings = append(settings, envVar+" unset")
+		}
+	}
+	message := fmt.Sprintf("Applied %s from ConfigMap %s", strings.Join(settings, ", "), c.configReloader.configMap)
+	log.Info(message)
+	sendConfigReloadEvent(corev1.EventTypeNormal, message)
+}
+
+// reloadConfig validates data and applies the reloadable settings from it, the env variables it leaves unset get back
+// their initial value. The environment of the process is not changed. The settings depending on the changed env
+// variables are then applied. It returns the changed env variables.
+func (c *IPAMContext) reloadConfig(data map[string]string) ([]string, error) {
+	if err := validateReloadedConfig(data); err != nil {
+		return nil, err
+	}
+	env := make(map[string]string)
+	for _, envVar := range reloadableEnvs {
+		if value, set := data[envVar]; set {
+			env[envVar] = value
+		} else if value, set := c.configReloader.baseEnv[envVar]; set {
+			env[envVar] = value
+		}
+	}
+	var changed []string
+	for _, envVar := range reloadableEnvs {
+		value, set := env[envVar]
+		current, found := c.configReloader.env[envVar]
+		if set != found || value != current {
+			changed = append(changed, envVar)
+		}
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	config := loadReloadableConfig(func(envVar string) (string, bool) {
+		value, found := env[envVar]
+		return value, found
+	})
+
+	// The configuration is checked as on start before the settings are used
+	c.configLock.Lock()
+	previous := c.reloadedConfig
+	c.reloadedConfig = &config
+	if !c.isConfigValid() {
+		c.reloadedConfig = previous
+		c.configLock.Unlock()
+		return nil, errors.New("the configuration is not supported, see the ipamd logs")
+	}
+	c.maxENI = c.limitMaxENI(config.maxENI)
+	c.configLock.Unlock()
+	c.configReloader.env = env
+	// The ENIConfig of the node and the warm pool profiles keep precedence over the reloaded settings
+	c.applyENIConfigOverrides(c.eniConfigName, c.eniConfigSpec)
+	log.Infof("Reloaded settings - warm ENI target: %d, warm IP target: %d, minimum IP target: %d, warm prefix target: %d, max ENI: %d",
+		c.warmENITarget, c.warmIPTarget, c.minimumIPTarget, c.warmPrefixTarget, c.maxENI)
+
+	if lo.Some(changed, networkutils.ReloadableEnvs) {
+		c.networkClient.ReloadSNATConfig(config.snatEnv)
+		vpcCIDRs, err := c.nholuongutClient.GetVPCIPv4CIDRs()
+		if err != nil {
+			return changed, errors.Wrap(err, "failed to get VPC CIDRs to update the SNAT rules")
+		}
+		primaryIP := c.nholuongutClient.GetLocalIPv4()
+		if err := c.networkClient.UpdateHostIptablesRules(vpcCIDRs, c.nholuongutClient.GetPrimaryENImac(), &primaryIP,
+			c.enableIPv4, c.enableIPv6); err != nil {
+			return changed, errors.Wrap(err, "failed to update the SNAT rules")
+		}
+	}
+	return changed, nil
+}
+
+// validateReloadedConfig returns an error if data sets an env variable which is not reloadable, or to a value the
+// helpers reading it would replace with the default
+func validateReloadedConfig(data map[string]string) error {
+	keys := lo.Keys(data)
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := data[key]
+		switch {
+		case lo.Contains(networkutils.ReloadableEnvs, key):
+			if err := networkutils.ValidateReloadableEnv(key, value); err != nil {
+				return err
+			}
+		case key == envMaxENI:
+			if _, err := strconv.Atoi(value); err != nil {
+				return errors.Errorf("%s must be an integer, got %q", key, value)
+			}
+		case lo.Contains(reloadableEnvs, key):
+			if input, err := strconv.Atoi(value); err != nil || input < 0 {
+				return errors.Errorf("%s must be a non-negative integer, got %q", key, value)
+			}
+		default:
+			return errors.Errorf("%s can not be changed without restarting nholuongut-node", key)
+		}
+	}
+	return nil
+}
+
+func sendConfigReloadEvent(eventType, message string) {
+	if eventRecorder := eventrecorder.Get(); eventRecorder != nil {
+		eventRecorder.SendPodEvent(eventType, eventrecorder.EventReason, "ConfigReload", message)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/events"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/eventrecorder"
+)
+
+func TestValidateReloadedConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			data: map[string]string{
+				envWarmIPTarget:                      "5",
+				envMaxENI:                            "-1",
+				"nholuongut_VPC_K8S_CNI_EXTERNALSNAT":       "true",
+				"nholuongut_VPC_K8S_CNI_EXCLUDE_SNAT_CIDRS": "10.1.0.0/16, 10.2.0.0/16",
+				"nholuongut_VPC_K8S_CNI_RANDOMIZESNAT":      "hashrandom",
+			},
+		},
+		{
+			name:    "negative warm target",
+			data:    map[string]string{envWarmENITarget: "-1"},
+			wantErr: "WARM_ENI_TARGET must be a non-negative integer, got \"-1\"",
+		},
+		{
+			name:    "invalid CIDR",
+			data:    map[string]string{"nholuongut_VPC_K8S_CNI_EXCLUDE_SNAT_CIDRS": "10.1.0.0/16,fd00::/64"},
+			wantErr: "nholuongut_VPC_K8S_CNI_EXCLUDE_SNAT_CIDRS must be a list of IPv4 CIDRs, \"fd00::/64\" is not one",
+		},
+		{
+			name:    "not reloadable",
+			data:    map[string]string{envEnableIpv4PrefixDelegation: "true"},
+			wantErr: "ENABLE_PREFIX_DELEGATION can not be changed without restarting nholuongut-node",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateReloadedConfig(tt.data)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyReloadedConfig(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	fakeRecorder := eventrecorder.InitMockEventRecorder()
+	os.Setenv(envWarmIPTarget, "5")
+	defer os.Unsetenv(envWarmIPTarget)
+
+	mockContext := &IPAMContext{
+		nholuongutClient:     m.nholuongututils,
+		networkClient: m.network,
+		enableIPv4:    true,
+		warmENITarget: 1,
+		warmIPTarget:  5,
+	}
+	mockContext.configReloader = newConfigReloader("kube-system/ipamd")
+	m.nholuongututils.EXPECT().GetENILimit().Return(4).AnyTimes()
+
+	// Warm targets are applied without touching the SNAT rules
+	mockContext.configReloader.set(map[string]string{envWarmIPTarget: "2", envMinimumIPTarget: "10"})
+	mockContext.applyReloadedConfig()
+	assert.Equal(t, 2, mockContext.warmIPTarget)
+	assert.Equal(t, 10, mockContext.minimumIPTarget)
+	assert.Equal(t, 4, mockContext.maxENI)
+	assertEvent(t, fakeRecorder, "Normal", "Applied WARM_IP_TARGET=2, MINIMUM_IP_TARGET=10 from ConfigMap kube-system/ipamd")
+
+	// Nothing is applied again until the ConfigMap changes
+	mockContext.warmIPTarget = 3
+	mockContext.applyReloadedConfig()
+	assert.Equal(t, 3, mockContext.warmIPTarget)
+
+	// An invalid ConfigMap is rejected as a whole
+	mockContext.configReloader.set(map[string]string{envWarmIPTarget: "1", envMaxENI: "two"})
+	mockContext.applyReloadedConfig()
+	assert.Equal(t, 3, mockContext.warmIPTarget)
+	assertEvent(t, fakeRecorder, "Warning", "Failed to apply ConfigMap kube-system/ipamd: MAX_ENI must be an integer, got \"two\"")
+
+	// A ConfigMap is rejected if the configuration fails the checks run on start
+	mockContext.enableIPv4 = false
+	mockContext.configReloader.set(map[string]string{envWarmIPTarget: "1"})
+	mockContext.applyReloadedConfig()
+	assert.Equal(t, 3, mockContext.warmIPTarget)
+	assertEvent(t, fakeRecorder, "Warning", "Failed to apply ConfigMap kube-system/ipamd: the configuration is not supported, see the ipamd logs")
+	mockContext.enableIPv4 = true
+
+	// SNAT changes update the host iptables rules, the env variables are left as they were
+	primaryIP := net.ParseIP("10.0.0.10")
+	m.network.EXPECT().ReloadSNATConfig(map[string]string{"nholuongut_VPC_K8S_CNI_EXTERNALSNAT": "true"})
+	m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return([]string{"10.0.0.0/16"}, nil)
+	m.nholuongututils.EXPECT().GetLocalIPv4().Return(primaryIP)
+	m.nholuongututils.EXPECT().GetPrimaryENImac().Return("12:ef:2a:98:e5:5a")
+	m.network.EXPECT().UpdateHostIptablesRules([]string{"10.0.0.0/16"}, "12:ef:2a:98:e5:5a", gomock.Any(), true, false)
+	mockContext.configReloader.set(map[string]string{envWarmIPTarget: "2", envMinimumIPTarget: "10",
+		"nholuongut_VPC_K8S_CNI_EXTERNALSNAT": "true"})
+	mockContext.applyReloadedConfig()
+	assert.Equal(t, 2, mockContext.warmIPTarget)
+	assertEvent(t, fakeRecorder, "Normal", "Applied nholuongut_VPC_K8S_CNI_EXTERNALSNAT=true from ConfigMap kube-system/ipamd")
+	_, found := os.LookupEnv("nholuongut_VPC_K8S_CNI_EXTERNALSNAT")
+	assert.False(t, found)
+
+	// Deleting the ConfigMap brings back the env variables nholuongut-node was started with
+	m.network.EXPECT().ReloadSNATConfig(map[string]string{})
+	m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return([]string{"10.0.0.0/16"}, nil)
+	m.nholuongututils.EXPECT().GetLocalIPv4().Return(primaryIP)
+	m.nholuongututils.EXPECT().GetPrimaryENImac().Return("12:ef:2a:98:e5:5a")
+	m.network.EXPECT().UpdateHostIptablesRules(gomock.Any(), gomock.Any(), gomock.Any(), true, false)
+	mockContext.configReloader.set(nil)
+	mockContext.applyReloadedConfig()
+	assert.Equal(t, 5, mockContext.warmIPTarget)
+	assert.Equal(t, noMinimumIPTarget, mockContext.minimumIPTarget)
+}
+
+func assertEvent(t *testing.T, fakeRecorder *events.FakeRecorder, eventType, message string) {
+	select {
+	case event := <-fakeRecorder.Events:
+		assert.Equal(t, eventType+" "+eventrecorder.EventReason+" "+message, event)
+	default:
+		t.Errorf("no %s event recorded", eventType)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datastore
+
+import (
+	"bytes"
+	"os"
+	"sort"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/logger"
+)
+
+// envIPAllocationStrategy specifies the order in which ENIs and their CIDRs are used to assign pod IPs
+const envIPAllocationStrategy = "IP_ALLOCATION_STRATEGY"
+
+// AllocationStrategy decides which ENI and which secondary IP or prefix a new pod IP is taken from
+type AllocationStrategy string
+
+const (
+	// AllocationStrategyRandom takes the first free address found, in no particular order
+	AllocationStrategyRandom AllocationStrategy = "random"
+	// AllocationStrategyPack takes the address from the most utilized ENI, and from its most utilized CIDR, so that
+	// the least utilized ones are left empty and can be released
+	AllocationStrategyPack AllocationStrategy = "pack"
+	// AllocationStrategySpread takes the address from the least utilized ENI. Within an ENI, CIDRs are packed.
+	AllocationStrategySpread AllocationStrategy = "spread"
+	// AllocationStrategyPrimaryENI takes the address from the primary ENI as long as it has one. Other ENIs are packed.
+	AllocationStrategyPrimaryENI AllocationStrategy = "primary-eni"
+
+	// DefaultAllocationStrategy is the strategy used when none is configured
+	DefaultAllocationStrategy = AllocationStrategyRandom
+)
+
+// getAllocationStrategy returns the strategy configured by the IP_ALLOCATION_STRATEGY env variable
+func getAllocationStrategy(log logger.Logger) AllocationStrategy {
+	envVal, found := os.LookupEnv(envIPAllocationStrategy)
+	if !found || envVal == "" {
+		return DefaultAllocationStrategy
+	}
+	switch strategy := AllocationStrategy(envVal); strategy {
+	case AllocationStrategyRandom, AllocationStrategyPack, AllocationStrategySpread, AllocationStrategyPrimaryENI:
+		return strategy
+	}
+	log.Warnf("Invalid %s %q, using the default %q", envIPAllocationStrategy, envVal, DefaultAllocationStrategy)
+	return DefaultAllocationStrategy
+}
+
+// assignedAddresses is the number of addresses assigned in the IPv4 or IPv6 CIDRs of the ENI
+func (e *ENI) assignedAddresses(isIPv6 bool) int {
+	count := 0
+	for _, cidr := range e.cidrs(isIPv6) {
+		count += cidr.AssignedIPAddressesInCidr()
+	}
+	return count
+}
+
+// orderedENIs returns the ENIs of the pool in the order new IPv4 or IPv6 addresses should be taken from them.
+// Except for the random strategy, ties are broken by device number and ENI ID so that the order is stable.
+func (ds *DataStore) orderedENIs(isIPv6 bool) []*ENI {
+	enis := make([]*ENI, 0, len(ds.eniPool))
+	for _, eni := range ds.eniPool {
+		enis = append(enis, eni)
+	}
+	if ds.allocationStrategy == AllocationStrategyRandom {
+		return enis
+	}
+
+	assigned := make(map[*ENI]int, len(enis))
+	for _, eni := range enis {
+		assigned[eni] = eni.assignedAddresses(isIPv6)
+	}
+	sort.Slice(enis, func(i, j int) bool {
+		lhs, rhs := enis[i], enis[j]
+		if ds.allocationStrategy == AllocationStrategyPrimaryENI && lhs.IsPrimary != rhs.IsPrimary {
+			return lhs.IsPrimary
+		}
+		if assigned[lhs] != assigned[rhs] {
+			if ds.allocationStrategy == AllocationStrategySpread {
+				return assigned[lhs] < assigned[rhs]
+			}
+			return assigned[lhs] > assigned[rhs]
+		}
+		if lhs.DeviceNumber != rhs.DeviceNumber {
+			return lhs.DeviceNumber < rhs.DeviceNumber
+		}
+		return lhs.ID < rhs.ID
+	})
+	return enis
+}
+
+// orderedCidrs returns the IPv4 or IPv6 CIDRs of the ENI in the order new addresses should be taken from them.
+// Every strategy but random packs CIDRs, so that prefixes and secondary IPs can be freed.
+func (ds *DataStore) orderedCidrs(eni *ENI, isIPv6 bool) []*CidrInfo {
+	eniCidrs := eni.cidrs(isIPv6)
+	cidrs := make([]*CidrInfo, 0, len(eniCidrs))
+	for _, cidr := range eniCidrs {
+		cidrs = append(cidrs, cidr)
+	}
+	if ds.allocationStrategy == AllocationStrategyRandom {
+		return cidrs
+	}
+
+	assigned := make(map[*CidrInfo]int, len(cidrs))
+	for _, cidr := range cidrs {
+		assigned[cidr] = cidr.AssignedIPAddressesInCidr()
+	}
+	sort.Slice(cidrs, func(i, j int) bool {
+		lhs, rhs := cidrs[i], cidrs[j]
+		if assigned[lhs] != assigned[rhs] {
+			return assigned[lhs] > assigned[rhs]
+		}
+		return bytes.Compare(lhs.Cidr.IP.To16(), rhs.Cidr.IP.To16()) < 0
+	})
+	return cidrs
+}
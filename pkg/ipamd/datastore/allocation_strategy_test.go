@@ -0,0 +1,148 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datastore
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAllocationStrategy(t *testing.T) {
+	defer os.Unsetenv(envIPAllocationStrategy)
+
+	os.Unsetenv(envIPAllocationStrategy)
+	assert.Equal(t, DefaultAllocationStrategy, getAllocationStrategy(Testlog))
+
+	for _, strategy := range []AllocationStrategy{AllocationStrategyRandom, AllocationStrategyPack,
+		AllocationStrategySpread, AllocationStrategyPrimaryENI} {
+		os.Setenv(envIPAllocationStrategy, string(strategy))
+		assert.Equal(t, strategy, getAllocationStrategy(Testlog))
+	}
+
+	os.Setenv(envIPAllocationStrategy, "round-robin")
+	assert.Equal(t, DefaultAllocationStrategy, getAllocationStrategy(Testlog))
+}
+
+// allocationStrategyTestDataStore returns a data store in PD mode with one prefix on the primary ENI and two
+// prefixes on a secondary ENI
+func allocationStrategyTestDataStore(t *testing.T, strategy AllocationStrategy) *DataStore {
+	ds := NewDataStore(Testlog, NullCheckpoint{}, true)
+	ds.allocationStrategy = strategy
+	ds.ipCooldownPeriod = 0
+
+	assert.NoError(t, ds.AddENI("eni-1", 0, true, false, false))
+	assert.NoError(t, ds.AddENI("eni-2", 1, false, false, false))
+	for eniID, prefixes := range map[string][]string{
+		"eni-1": {"10.0.0.0/28"},
+		"eni-2": {"10.0.2.0/28", "10.0.1.0/28"},
+	} {
+		for _, prefix := range prefixes {
+			_, ipnet, _ := net.ParseCIDR(prefix)
+			assert.NoError(t, ds.AddIPv4CidrToStore(eniID, *ipnet, true))
+		}
+	}
+	return ds
+}
+
+func allocationStrategyTestKey(i int) IPAMKey {
+	return IPAMKey{NetworkName: "net0", ContainerID: fmt.Sprintf("sandbox-%d", i), IfName: "eth0"}
+}
+
+// assignPods assigns an address to the sandboxes numbered [from, to)
+func assignPods(t *testing.T, ds *DataStore, from, to int) {
+	for i := from; i < to; i++ {
+		_, _, err := ds.AssignPodIPv4Address(allocationStrategyTestKey(i), IPAMMetadata{})
+		assert.NoError(t, err)
+	}
+}
+
+// unassignPods releases the address of the sandboxes numbered [from, to)
+func unassignPods(t *testing.T, ds *DataStore, from, to int) {
+	for i := from; i < to; i++ {
+		_, _, _, _, err := ds.UnassignPodIPAddress(allocationStrategyTestKey(i))
+		assert.NoError(t, err)
+	}
+}
+
+// assignedPerCidr returns the number of assigned addresses of each prefix
+func assignedPerCidr(ds *DataStore) map[string]int {
+	assigned := make(map[string]int)
+	for _, eni := range ds.eniPool {
+		for _, cidr := range eni.AvailableIPv4Cidrs {
+			assigned[cidr.Cidr.String()] = cidr.AssignedIPAddressesInCidr()
+		}
+	}
+	return assigned
+}
+
+func TestAllocationStrategyPack(t *testing.T) {
+	ds := allocationStrategyTestDataStore(t, AllocationStrategyPack)
+
+	// The primary ENI wins the tie, then the lowest prefix of the next ENI is filled first
+	assignPods(t, ds, 0, 20)
+	assert.Equal(t, map[string]int{"10.0.0.0/28": 16, "10.0.1.0/28": 4, "10.0.2.0/28": 0}, assignedPerCidr(ds))
+
+	// Release most of the primary ENI, new pods go to the most utilized ENI
+	unassignPods(t, ds, 0, 14)
+	assignPods(t, ds, 20, 22)
+	assert.Equal(t, map[string]int{"10.0.0.0/28": 2, "10.0.1.0/28": 6, "10.0.2.0/28": 0}, assignedPerCidr(ds))
+	assert.Len(t, ds.FreeablePrefixes("eni-2"), 1)
+}
+
+func TestAllocationStrategySpread(t *testing.T) {
+	ds := allocationStrategyTestDataStore(t, AllocationStrategySpread)
+
+	// ENIs take turns, but prefixes of an ENI are still packed
+	assignPods(t, ds, 0, 6)
+	assert.Equal(t, map[string]int{"10.0.0.0/28": 3, "10.0.1.0/28": 3, "10.0.2.0/28": 0}, assignedPerCidr(ds))
+
+	// Release the pods of the primary ENI, new pods go to the least utilized ENI
+	for i := 0; i < 6; i += 2 {
+		unassignPods(t, ds, i, i+1)
+	}
+	assignPods(t, ds, 6, 8)
+	assert.Equal(t, map[string]int{"10.0.0.0/28": 2, "10.0.1.0/28": 3, "10.0.2.0/28": 0}, assignedPerCidr(ds))
+}
+
+func TestAllocationStrategyPrimaryENI(t *testing.T) {
+	ds := allocationStrategyTestDataStore(t, AllocationStrategyPrimaryENI)
+
+	assignPods(t, ds, 0, 24)
+	assert.Equal(t, map[string]int{"10.0.0.0/28": 16, "10.0.1.0/28": 8, "10.0.2.0/28": 0}, assignedPerCidr(ds))
+
+	// The primary ENI is preferred even if the secondary ENI is more utilized
+	unassignPods(t, ds, 0, 14)
+	assignPods(t, ds, 24, 26)
+	assert.Equal(t, map[string]int{"10.0.0.0/28": 4, "10.0.1.0/28": 8, "10.0.2.0/28": 0}, assignedPerCidr(ds))
+}
+
+func TestAllocationStrategyIPv6(t *testing.T) {
+	ds := NewDataStore(Testlog, NullCheckpoint{}, true)
+	ds.allocationStrategy = AllocationStrategyPack
+	assert.NoError(t, ds.AddENI("eni-1", 0, true, false, false))
+	for _, prefix := range []string{"2001:db8:0:2::/80", "2001:db8:0:1::/80"} {
+		_, ipnet, _ := net.ParseCIDR(prefix)
+		assert.NoError(t, ds.AddIPv6CidrToStore("eni-1", *ipnet, true))
+	}
+
+	for i := 0; i < 3; i++ {
+		ip, _, err := ds.AssignPodIPv6Address(allocationStrategyTestKey(i), IPAMMetadata{})
+		assert.NoError(t, err)
+		assert.True(t, ds.eniPool["eni-1"].IPv6Cidrs["2001:db8:0:1::/80"].Cidr.Contains(net.ParseIP(ip)))
+	}
+}
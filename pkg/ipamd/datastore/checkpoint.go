@@ -12,6 +12,14 @@ type Checkpointer interface {
 	Restore(into interface{}) error
 }
 
+// Journaler is implemented by the checkpointers which can persist the change made by a single mutation of the
+// datastore, rather than the whole state
+type Journaler interface {
+	// Journal persists the entries put and the keys deleted by a mutation. data returns the whole state, which
+	// is only built when the change cannot be persisted on its own.
+	Journal(put []CheckpointEntry, del []IPAMKey, data func() *CheckpointData) error
+}
+
 // NullCheckpoint discards data and always returns "not found". For testing only!
 type NullCheckpoint struct{}
 
@@ -79,6 +79,16 @@ const backfillNetworkIface = "unknown"
 // ErrUnknownPod is an error when there is no pod in data store matching pod name, namespace, sandbox id
 var ErrUnknownPod = errors.New("datastore: unknown pod")
 
+// AnyDeviceNumber lets the data store take a pod address from any ENI of its IP pool
+const AnyDeviceNumber = -1
+
+// DefaultIPPool is the pool of the ENIs allocated in the subnet of the primary ENI, or of the node's ENIConfig with
+// custom networking. Pods which don't ask for a specific IP pool get their address from it.
+const DefaultIPPool = ""
+
+// ErrUnknownStaticCidr is an error when the secondary IP or prefix requested for a pod is not on any ENI of the data store
+var ErrUnknownStaticCidr = errors.New("datastore: requested IP/Prefix is not allocated")
+
 // IPAMKey is the IPAM primary key.  Quoting CNI spec:
 //
 //	Plugins that store state should do so using a primary key of
@@ -103,6 +113,13 @@ func (k IPAMKey) String() string {
 type IPAMMetadata struct {
 	K8SPodNamespace string `json:"k8sPodNamespace,omitempty"`
 	K8SPodName      string `json:"k8sPodName,omitempty"`
+	// HostVethName is the name of the host side interface of the pod, the container side one is IPAMKey.IfName
+	HostVethName string `json:"hostVethName,omitempty"`
+	// StickyIP is set for pods whose address is held for their namespace/name when they are deleted, so that a
+	// pod recreated with the same identity (e.g. a StatefulSet pod) gets it back
+	StickyIP bool `json:"stickyIP,omitempty"`
+	// IPPool is the pool of ENIs the address is taken from
+	IPPool string `json:"ipPool,omitempty"`
 }
 
 // ENI represents a single ENI. Exported fields will be marshaled for introspection.
@@ -118,6 +135,8 @@ type ENI struct {
 	IsEFA bool
 	// DeviceNumber is the device number of ENI (0 means the primary ENI)
 	DeviceNumber int
+	// IPPool is the pool of the ENI, only pods of this pool get addresses from it
+	IPPool string
 	// IPv4Addresses shows whether each address is assigned, the key is IP address, which must
 	// be in dot-decimal notation with no leading zeros and no whitespace(eg: "10.1.0.253")
 	// Key is the IP address - PD: "IP/28" and SIP: "IP/32"
@@ -154,18 +173,17 @@ func (cidr *CidrInfo) Size() int {
 }
 
 func (e *ENI) findAddressForSandbox(ipamKey IPAMKey) (*CidrInfo, *AddressInfo) {
-	// Either v4 or v6 for now.
 	// Check in V4 prefixes
-	for _, availableCidr := range e.AvailableIPv4Cidrs {
-		for _, addr := range availableCidr.IPAddresses {
-			if addr.IPAMKey == ipamKey {
-				return availableCidr, addr
-			}
-		}
+	if availableCidr, addr := findAddressInCidrs(e.AvailableIPv4Cidrs, ipamKey); addr != nil {
+		return availableCidr, addr
 	}
-
 	// Check in V6 prefixes
-	for _, availableCidr := range e.IPv6Cidrs {
+	return findAddressInCidrs(e.IPv6Cidrs, ipamKey)
+}
+
+// findAddressInCidrs returns the CIDR and address assigned to ipamKey, or (nil, nil) if not found
+func findAddressInCidrs(cidrs map[string]*CidrInfo, ipamKey IPAMKey) (*CidrInfo, *AddressInfo) {
+	for _, availableCidr := range cidrs {
 		for _, addr := range availableCidr.IPAddresses {
 			if addr.IPAMKey == ipamKey {
 				return availableCidr, addr
@@ -200,14 +218,17 @@ func (cidr *CidrInfo) AssignedIPAddressesInCidr() int {
 type CidrStats struct {
 	AssignedIPs int
 	CooldownIPs int
+	ReservedIPs int
 }
 
-// Gets number of assigned IPs and the IPs in cooldown from a given CIDR
-func (cidr *CidrInfo) GetIPStatsFromCidr(ipCooldownPeriod time.Duration) CidrStats {
+// Gets number of assigned IPs, the IPs in cooldown and the IPs held for sticky IP pods from a given CIDR
+func (cidr *CidrInfo) GetIPStatsFromCidr(ipCooldownPeriod, stickyIPTTL time.Duration) CidrStats {
 	stats := CidrStats{}
 	for _, addr := range cidr.IPAddresses {
 		if addr.Assigned() {
 			stats.AssignedIPs++
+		} else if addr.isReserved(stickyIPTTL) {
+			stats.ReservedIPs++
 		} else if addr.inCoolingPeriod(ipCooldownPeriod) {
 			stats.CooldownIPs++
 		}
@@ -256,13 +277,36 @@ func (p *ENIPool) FindAddressForSandbox(ipamKey IPAMKey) (*ENI, *CidrInfo, *Addr
 	return nil, nil, nil
 }
 
+// FindIPv4AddressForSandbox returns the ENI, CIDR and AddressInfo of the sandbox's IPv4 address or (nil, nil, nil) if not found
+func (p *ENIPool) FindIPv4AddressForSandbox(ipamKey IPAMKey) (*ENI, *CidrInfo, *AddressInfo) {
+	for _, eni := range *p {
+		if availableCidr, addr := findAddressInCidrs(eni.AvailableIPv4Cidrs, ipamKey); addr != nil {
+			return eni, availableCidr, addr
+		}
+	}
+	return nil, nil, nil
+}
+
+// FindIPv6AddressForSandbox returns the ENI, CIDR and AddressInfo of the sandbox's IPv6 address or (nil, nil, nil) if not found
+func (p *ENIPool) FindIPv6AddressForSandbox(ipamKey IPAMKey) (*ENI, *CidrInfo, *AddressInfo) {
+	for _, eni := range *p {
+		if availableCidr, addr := findAddressInCidrs(eni.IPv6Cidrs, ipamKey); addr != nil {
+			return eni, availableCidr, addr
+		}
+	}
+	return nil, nil, nil
+}
+
 // PodIPInfo contains pod's IP and the device number of the ENI
 type PodIPInfo struct {
-	IPAMKey IPAMKey
+	IPAMKey      IPAMKey
+	IPAMMetadata IPAMMetadata
 	// IP is the IPv4 address of pod
 	IP string
 	// DeviceNumber is the device number of the ENI
 	DeviceNumber int
+	// ENIID is the ID of the ENI
+	ENIID string
 }
 
 // DataStore contains node level ENI/IP
@@ -277,6 +321,15 @@ type DataStore struct {
 	netLink          netlinkwrapper.NetLink
 	isPDEnabled      bool
 	ipCooldownPeriod time.Duration
+	// stickyIPTTL is how long the address of a deleted sticky IP pod is held for its namespace/name
+	stickyIPTTL time.Duration
+	// allocationStrategy decides which ENI and CIDR new pod IPs are taken from
+	allocationStrategy AllocationStrategy
+	// branchENIPods holds the pods using a branch ENI, keyed by sandbox. Their addresses belong to the branch ENI
+	// and are not part of the eniPool, but they are checkpointed with the other allocations.
+	branchENIPods map[IPAMKey]CheckpointEntry
+	// watchers receive the changes of the data store, see WatchAllocations
+	watchers map[*AllocationWatcher]bool
 }
 
 // ENIInfos contains ENI IP information
@@ -292,17 +345,24 @@ type ENIInfos struct {
 // NewDataStore returns DataStore structure
 func NewDataStore(log logger.Logger, backingStore Checkpointer, isPDEnabled bool) *DataStore {
 	return &DataStore{
-		eniPool:          make(ENIPool),
-		log:              log,
-		backingStore:     backingStore,
-		netLink:          netlinkwrapper.NewNetLink(),
-		isPDEnabled:      isPDEnabled,
-		ipCooldownPeriod: getCooldownPeriod(),
+		eniPool:            make(ENIPool),
+		branchENIPods:      make(map[IPAMKey]CheckpointEntry),
+		log:                log,
+		backingStore:       backingStore,
+		netLink:            netlinkwrapper.NewNetLink(),
+		isPDEnabled:        isPDEnabled,
+		ipCooldownPeriod:   getCooldownPeriod(),
+		stickyIPTTL:        getStickyIPTTL(),
+		allocationStrategy: getAllocationStrategy(log),
 	}
 }
 
 // CheckpointFormatVersion is the version stamp used on stored checkpoints.
-const CheckpointFormatVersion = "vpc-cni-ipam/1"
+const CheckpointFormatVersion = "vpc-cni-ipam/2"
+
+// checkpointFormatVersionV1 is the version stamp of checkpoints which only record the sandbox and its addresses.
+// They are migrated to CheckpointFormatVersion when read.
+const checkpointFormatVersionV1 = "vpc-cni-ipam/1"
 
 // CheckpointData is the format of stored checkpoints. Note this is
 // deliberately a "dumb" format since efficiency is less important
@@ -320,11 +380,39 @@ type CheckpointEntry struct {
 	IPv6                string       `json:"ipv6,omitempty"`
 	AllocationTimestamp int64        `json:"allocationTimestamp"`
 	Metadata            IPAMMetadata `json:"metadata"`
+
+	// The fields below were added in vpc-cni-ipam/2 and are empty in entries migrated from vpc-cni-ipam/1.
+
+	// IPv4ENI and IPv4Cidr are the ENI and the secondary IP (/32) or prefix (/28) the IPv4 address belongs to
+	IPv4ENI  string `json:"ipv4ENI,omitempty"`
+	IPv4Cidr string `json:"ipv4Cidr,omitempty"`
+	// IPv6ENI and IPv6Cidr are the ENI and the prefix the IPv6 address belongs to
+	IPv6ENI  string `json:"ipv6ENI,omitempty"`
+	IPv6Cidr string `json:"ipv6Cidr,omitempty"`
+	// DeviceNumber is the device number of the ENI of the IPv4 address, or of the IPv6 address if there is
+	// no IPv4 one. It is -1 for pods using a branch ENI.
+	DeviceNumber int `json:"deviceNumber,omitempty"`
+	// VlanID is the VLAN of the branch ENI of pods using security groups for pods, 0 otherwise
+	VlanID int `json:"vlanID,omitempty"`
+	// ReleaseTimestamp is set on the entries of addresses held for a deleted sticky IP pod. They are keyed by
+	// the pod namespace/name rather than by a sandbox.
+	ReleaseTimestamp int64 `json:"releaseTimestamp,omitempty"`
+}
+
+// isBranchENI returns true if the entry belongs to a pod using a branch ENI, whose addresses are not managed
+// by the ENI pool
+func (entry *CheckpointEntry) isBranchENI() bool {
+	return entry.VlanID != 0
+}
+
+// isReservation returns true if the entry holds the addresses of a deleted sticky IP pod
+func (entry *CheckpointEntry) isReservation() bool {
+	return entry.ReleaseTimestamp != 0
 }
 
 // ReadBackingStore initializes the IP allocation state from the
 // configured backing store. Should be called before using data store.
-func (ds *DataStore) ReadBackingStore(isv6Enabled bool) error {
+func (ds *DataStore) ReadBackingStore(isv4Enabled, isv6Enabled bool) error {
 	var data CheckpointData
 
 	// Read from checkpoint file
@@ -339,7 +427,14 @@ func (ds *DataStore) ReadBackingStore(isv6Enabled bool) error {
 		}
 		return errors.Wrap(err, "failed ipam state recovery from backing store")
 	}
-	if data.Version != CheckpointFormatVersion {
+	switch data.Version {
+	case CheckpointFormatVersion:
+	case checkpointFormatVersionV1:
+		// v1 entries lack the ENI details, which are looked up in the ENI pool below. The migrated state is
+		// written in the current format once the recovery is complete.
+		ds.log.Infof("Migrating ipam state from %s to %s", data.Version, CheckpointFormatVersion)
+	default:
+		// Never overwrite a checkpoint written by a newer version, so that it can still be read after a rollback
 		return errors.Errorf("failed ipam state recovery due to unexpected checkpointVersion: %v/%v", data.Version, CheckpointFormatVersion)
 	}
 	if normalizedData, err := ds.normalizeCheckpointDataByPodVethExistence(data); err != nil {
@@ -351,44 +446,38 @@ func (ds *DataStore) ReadBackingStore(isv6Enabled bool) error {
 	ds.lock.Lock()
 	defer ds.lock.Unlock()
 
+	var reservations []CheckpointEntry
 	for _, allocation := range data.Allocations {
-		ipv4Addr := net.ParseIP(allocation.IPv4)
-		ipv6Addr := net.ParseIP(allocation.IPv6)
-		var ipAddr net.IP
-		found := false
-	eniloop:
-		for _, eni := range ds.eniPool {
-			eniCidrs := eni.AvailableIPv4Cidrs
-			ipAddr = ipv4Addr
-			if isv6Enabled {
-				ds.log.Debugf("v6 is enabled")
-				eniCidrs = eni.IPv6Cidrs
-				ipAddr = ipv6Addr
+		if allocation.isReservation() {
+			// Restored once the assigned addresses are known
+			reservations = append(reservations, allocation)
+			continue
+		}
+		if allocation.isBranchENI() {
+			ds.branchENIPods[allocation.IPAMKey] = allocation
+			ds.log.Debugf("Recovered %s => branch ENI %s%s, vlan %d", allocation.IPAMKey,
+				allocation.IPv4ENI, allocation.IPv6ENI, allocation.VlanID)
+			continue
+		}
+		// In dual-stack mode a single entry carries both the IPv4 and the IPv6 address of the sandbox
+		if isv4Enabled && allocation.IPv4 != "" {
+			if err := ds.restoreAllocationUnsafe(allocation, net.ParseIP(allocation.IPv4), false); err != nil {
+				return err
 			}
-			for _, cidr := range eniCidrs {
-				ds.log.Debugf("Checking if IP: %v belongs to CIDR: %v", ipAddr, cidr.Cidr)
-				if cidr.Cidr.Contains(ipAddr) {
-					// Found!
-					found = true
-					if _, ok := cidr.IPAddresses[ipAddr.String()]; ok {
-						return errors.New(IPAlreadyInStoreError)
-					}
-					addr := &AddressInfo{Address: ipAddr.String()}
-					cidr.IPAddresses[ipAddr.String()] = addr
-					ds.assignPodIPAddressUnsafe(addr, allocation.IPAMKey, allocation.Metadata, time.Unix(0, allocation.AllocationTimestamp))
-					ds.log.Debugf("Recovered %s => %s/%s", allocation.IPAMKey, eni.ID, addr.Address)
-					// Increment ENI IP usage upon finding assigned ips
-					prometheusmetrics.EniIPsInUse.WithLabelValues(eni.ID).Inc()
-					// Update prometheus for ips per cidr
-					// Secondary IP mode will have /32:1 and Prefix mode will have /28:<number of /32s>
-					prometheusmetrics.IpsPerCidr.With(prometheus.Labels{"cidr": cidr.Cidr.String()}).Inc()
-					break eniloop
-				}
+		}
+		if isv6Enabled && allocation.IPv6 != "" {
+			ds.log.Debugf("v6 is enabled")
+			if err := ds.restoreAllocationUnsafe(allocation, net.ParseIP(allocation.IPv6), true); err != nil {
+				return err
 			}
 		}
-		if !found {
-			ds.log.Infof("datastore: Sandbox %s uses unknown IP Address %s - presuming stale/dead",
-				allocation.IPAMKey, ipAddr.String())
+	}
+	for _, reservation := range reservations {
+		if isv4Enabled && reservation.IPv4 != "" {
+			ds.restoreReservationUnsafe(reservation, net.ParseIP(reservation.IPv4), false)
+		}
+		if isv6Enabled && reservation.IPv6 != "" {
+			ds.restoreReservationUnsafe(reservation, net.ParseIP(reservation.IPv6), true)
 		}
 	}
 
@@ -401,54 +490,221 @@ func (ds *DataStore) ReadBackingStore(isv6Enabled bool) error {
 	return nil
 }
 
-func (ds *DataStore) writeBackingStoreUnsafe() error {
-	allocations := make([]CheckpointEntry, 0, ds.assigned)
+// restoreAllocationUnsafe marks ipAddr as assigned to the checkpointed sandbox if it belongs to one of the
+// ENI CIDRs of the given address family. The ENI and CIDR recorded in the entry are tried first, other ENIs
+// are searched when they are unknown (e.g. migrated entries) or no longer hold the address.
+func (ds *DataStore) restoreAllocationUnsafe(allocation CheckpointEntry, ipAddr net.IP, isIPv6 bool) error {
+	eniID, cidrStr := allocation.IPv4ENI, allocation.IPv4Cidr
+	if isIPv6 {
+		eniID, cidrStr = allocation.IPv6ENI, allocation.IPv6Cidr
+	}
+	if eni, ok := ds.eniPool[eniID]; ok {
+		if cidr, ok := eni.cidrs(isIPv6)[cidrStr]; ok && cidr.Cidr.Contains(ipAddr) {
+			return ds.restoreAddressUnsafe(allocation, eni, cidr, ipAddr)
+		}
+		ds.log.Debugf("Sandbox %s: %s is no longer on %s/%s", allocation.IPAMKey, ipAddr, eniID, cidrStr)
+	}
+
+	for _, eni := range ds.eniPool {
+		for _, cidr := range eni.cidrs(isIPv6) {
+			ds.log.Debugf("Checking if IP: %v belongs to CIDR: %v", ipAddr, cidr.Cidr)
+			if cidr.Cidr.Contains(ipAddr) {
+				// Found!
+				return ds.restoreAddressUnsafe(allocation, eni, cidr, ipAddr)
+			}
+		}
+	}
+	ds.log.Infof("datastore: Sandbox %s uses unknown IP Address %s - presuming stale/dead",
+		allocation.IPAMKey, ipAddr.String())
+	return nil
+}
+
+// restoreAddressUnsafe marks ipAddr of the given ENI CIDR as assigned to the checkpointed sandbox
+func (ds *DataStore) restoreAddressUnsafe(allocation CheckpointEntry, eni *ENI, cidr *CidrInfo, ipAddr net.IP) error {
+	if _, ok := cidr.IPAddresses[ipAddr.String()]; ok {
+		return errors.New(IPAlreadyInStoreError)
+	}
+	addr := &AddressInfo{Address: ipAddr.String()}
+	cidr.IPAddresses[ipAddr.String()] = addr
+	ds.assignPodIPAddressUnsafe(addr, allocation.IPAMKey, allocation.Metadata, time.Unix(0, allocation.AllocationTimestamp))
+	ds.log.Debugf("Recovered %s => %s/%s", allocation.IPAMKey, eni.ID, addr.Address)
+	// Increment ENI IP usage upon finding assigned ips
+	prometheusmetrics.EniIPsInUse.WithLabelValues(eni.ID).Inc()
+	// Update prometheus for ips per cidr
+	// Secondary IP mode will have /32:1 and Prefix mode will have /28:<number of /32s>
+	prometheusmetrics.IpsPerCidr.With(prometheus.Labels{"cidr": cidr.Cidr.String()}).Inc()
+	return nil
+}
+
+// writeBackingStoreUnsafe persists the entries of keys, changed by a mutation. A backing store which journals changes
+// only gets these entries, or their deletion when they are gone, the others get the whole state. The whole state is
+// persisted when keys are not given.
+func (ds *DataStore) writeBackingStoreUnsafe(keys ...IPAMKey) error {
+	data := func() *CheckpointData {
+		return &CheckpointData{
+			Version:     CheckpointFormatVersion,
+			Allocations: ds.allocationsUnsafe(),
+		}
+	}
+	journal, ok := ds.backingStore.(Journaler)
+	if !ok || len(keys) == 0 {
+		return ds.backingStore.Checkpoint(data())
+	}
+
+	var put []CheckpointEntry
+	var del []IPAMKey
+	for _, key := range keys {
+		if entry, found := ds.checkpointEntryUnsafe(key); found {
+			put = append(put, entry)
+		} else {
+			del = append(del, key)
+		}
+	}
+	return journal.Journal(put, del, data)
+}
+
+// sandboxCheckpointKeys returns the keys of the entries which change when the sandbox of ipamKey gets or releases
+// addresses: its own, and the one of the reservation of its pod if it has sticky IPs
+func sandboxCheckpointKeys(ipamKey IPAMKey, ipamMetadata IPAMMetadata) []IPAMKey {
+	if ipamMetadata.StickyIP {
+		return []IPAMKey{ipamKey, reservationKey(ipamMetadata)}
+	}
+	return []IPAMKey{ipamKey}
+}
+
+// checkpointEntryUnsafe returns the entry of key as allocationsUnsafe records it, false if there is none
+func (ds *DataStore) checkpointEntryUnsafe(key IPAMKey) (CheckpointEntry, bool) {
+	if entry, ok := ds.branchENIPods[key]; ok {
+		return entry, true
+	}
+	entries := newCheckpointEntries(1)
+	for _, eni := range ds.eniPool {
+		for _, isIPv6 := range []bool{false, true} {
+			for _, cidr := range eni.cidrs(isIPv6) {
+				for _, addr := range cidr.IPAddresses {
+					if (addr.Assigned() && addr.IPAMKey == key) ||
+						(addr.isReserved(ds.stickyIPTTL) && reservationKey(addr.IPAMMetadata) == key) {
+						entries.add(eni, cidr, addr, isIPv6)
+					}
+				}
+			}
+		}
+	}
+	if len(entries.entries) == 0 {
+		return CheckpointEntry{}, false
+	}
+	return entries.entries[0], true
+}
+
+// Allocations returns a snapshot of the allocations, as they are checkpointed.
+// Note result may already be stale by the time you look at it.
+func (ds *DataStore) Allocations() []CheckpointEntry {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+	return ds.allocationsUnsafe()
+}
 
+// allocationsUnsafe returns an entry per sandbox holding addresses or a branch ENI, and per deleted sticky IP pod
+// whose addresses are held
+func (ds *DataStore) allocationsUnsafe() []CheckpointEntry {
+	entries := newCheckpointEntries(ds.assigned + len(ds.branchENIPods))
 	for _, eni := range ds.eniPool {
 		// Loop through ENI's v4 prefixes
 		for _, assignedAddr := range eni.AvailableIPv4Cidrs {
 			for _, addr := range assignedAddr.IPAddresses {
-				if addr.Assigned() {
-					entry := CheckpointEntry{
-						IPAMKey:             addr.IPAMKey,
-						IPv4:                addr.Address,
-						AllocationTimestamp: addr.AssignedTime.UnixNano(),
-						Metadata:            addr.IPAMMetadata,
-					}
-					allocations = append(allocations, entry)
+				if addr.Assigned() || addr.isReserved(ds.stickyIPTTL) {
+					entries.add(eni, assignedAddr, addr, false)
 				}
 			}
 		}
 		// Loop through ENI's v6 prefixes
 		for _, assignedAddr := range eni.IPv6Cidrs {
 			for _, addr := range assignedAddr.IPAddresses {
-				if addr.Assigned() {
-					entry := CheckpointEntry{
-						IPAMKey:             addr.IPAMKey,
-						IPv6:                addr.Address,
-						AllocationTimestamp: addr.AssignedTime.UnixNano(),
-						Metadata:            addr.IPAMMetadata,
-					}
-					allocations = append(allocations, entry)
+				if addr.Assigned() || addr.isReserved(ds.stickyIPTTL) {
+					entries.add(eni, assignedAddr, addr, true)
 				}
 			}
 		}
 	}
+	allocations := entries.entries
+	for _, entry := range ds.branchENIPods {
+		allocations = append(allocations, entry)
+	}
+	return allocations
+}
 
-	data := CheckpointData{
-		Version:     CheckpointFormatVersion,
-		Allocations: allocations,
+// checkpointEntries builds the checkpoint entries of addresses. A dual-stack sandbox holds one address of each
+// family, possibly on different ENIs. Both are recorded in the same entry, so keep track of where each sandbox's
+// entry lives.
+type checkpointEntries struct {
+	entries []CheckpointEntry
+	index   map[IPAMKey]int
+}
+
+func newCheckpointEntries(size int) *checkpointEntries {
+	return &checkpointEntries{
+		entries: make([]CheckpointEntry, 0, size),
+		index:   make(map[IPAMKey]int, size),
 	}
+}
 
-	return ds.backingStore.Checkpoint(&data)
+// add records addr of the CIDR of eni in the entry of its sandbox. Addresses held for sticky IP pods are recorded
+// under the pod identity, see reservationKey.
+func (c *checkpointEntries) add(eni *ENI, cidr *CidrInfo, addr *AddressInfo, isIPv6 bool) {
+	key := addr.IPAMKey
+	if !addr.Assigned() {
+		key = reservationKey(addr.IPAMMetadata)
+	}
+	i, ok := c.index[key]
+	if !ok {
+		i = len(c.entries)
+		c.index[key] = i
+		entry := CheckpointEntry{
+			IPAMKey:             key,
+			AllocationTimestamp: addr.AssignedTime.UnixNano(),
+			Metadata:            addr.IPAMMetadata,
+		}
+		if !addr.Assigned() {
+			entry.ReleaseTimestamp = addr.UnassignedTime.UnixNano()
+		}
+		c.entries = append(c.entries, entry)
+	}
+	entry := &c.entries[i]
+	if isIPv6 {
+		entry.IPv6 = addr.Address
+		entry.IPv6ENI = eni.ID
+		entry.IPv6Cidr = cidr.Cidr.String()
+		// The device number of the IPv4 ENI wins in dual-stack mode
+		if entry.IPv4ENI == "" {
+			entry.DeviceNumber = eni.DeviceNumber
+		}
+	} else {
+		entry.IPv4 = addr.Address
+		entry.IPv4ENI = eni.ID
+		entry.IPv4Cidr = cidr.Cidr.String()
+		entry.DeviceNumber = eni.DeviceNumber
+	}
+}
+
+// cidrs returns the IPv4 or IPv6 CIDRs of the ENI
+func (e *ENI) cidrs(isIPv6 bool) map[string]*CidrInfo {
+	if isIPv6 {
+		return e.IPv6Cidrs
+	}
+	return e.AvailableIPv4Cidrs
 }
 
 // AddENI add ENI to data store
 func (ds *DataStore) AddENI(eniID string, deviceNumber int, isPrimary, isTrunk, isEFA bool) error {
+	return ds.AddENIToPool(eniID, deviceNumber, isPrimary, isTrunk, isEFA, DefaultIPPool)
+}
+
+// AddENIToPool adds an ENI of the IP pool to data store
+func (ds *DataStore) AddENIToPool(eniID string, deviceNumber int, isPrimary, isTrunk, isEFA bool, ipPool string) error {
 	ds.lock.Lock()
 	defer ds.lock.Unlock()
 
-	ds.log.Debugf("DataStore add an ENI %s", eniID)
+	ds.log.Debugf("DataStore add an ENI %s to IP pool %q", eniID, ipPool)
 
 	_, ok := ds.eniPool[eniID]
 	if ok {
@@ -461,7 +717,9 @@ func (ds *DataStore) AddENI(eniID string, deviceNumber int, isPrimary, isTrunk,
 		IsEFA:              isEFA,
 		ID:                 eniID,
 		DeviceNumber:       deviceNumber,
+		IPPool:             ipPool,
 		AvailableIPv4Cidrs: make(map[string]*CidrInfo)}
+	ds.publishUnsafe(AllocationEvent{Type: ENIAttached, ENI: eniID})
 
 	prometheusmetrics.Enis.Set(float64(len(ds.eniPool)))
 	// Initialize ENI IPs In Use to 0 when an ENI is created
@@ -496,6 +754,7 @@ func (ds *DataStore) AddIPv4CidrToStore(eniID string, ipv4Cidr net.IPNet, isPref
 	}
 
 	curENI.AvailableIPv4Cidrs[strIPv4Cidr] = newCidrInfo
+	ds.publishCidrUnsafe(CidrAdded, eniID, newCidrInfo)
 
 	ds.total += newCidrInfo.Size()
 	if isPrefix {
@@ -553,6 +812,7 @@ func (ds *DataStore) DelIPv4CidrFromStore(eniID string, cidr net.IPNet, force bo
 	}
 	prometheusmetrics.TotalIPs.Set(float64(ds.total))
 	delete(curENI.AvailableIPv4Cidrs, strIPv4Cidr)
+	ds.publishCidrUnsafe(CidrRemoved, eniID, deletableCidr)
 	ds.log.Infof("Deleted ENI(%s)'s IP/Prefix %s from datastore", eniID, strIPv4Cidr)
 
 	return nil
@@ -591,6 +851,7 @@ func (ds *DataStore) AddIPv6CidrToStore(eniID string, ipv6Cidr net.IPNet, isPref
 		AddressFamily: "6",
 	}
 	ds.total += curENI.IPv6Cidrs[strIPv6Cidr].Size()
+	ds.publishCidrUnsafe(CidrAdded, eniID, curENI.IPv6Cidrs[strIPv6Cidr])
 	if isPrefix {
 		ds.allocatedPrefix++
 	}
@@ -600,9 +861,14 @@ func (ds *DataStore) AddIPv6CidrToStore(eniID string, ipv6Cidr net.IPNet, isPref
 	return nil
 }
 
+// AssignPodIPAddress assigns an address of every enabled family to the pod. In dual-stack mode the IPv4 and IPv6
+// addresses are assigned under a single lock and checkpointed as one entry, so either both or neither are assigned.
+// The returned device number is the one of the ENI backing the IPv4 address, if any.
 func (ds *DataStore) AssignPodIPAddress(ipamKey IPAMKey, ipamMetadata IPAMMetadata, isIPv4Enabled bool, isIPv6Enabled bool) (ipv4Address string,
 	ipv6Address string, deviceNumber int, err error) {
-	//Currently it's either v4 or v6. Dual Stack mode isn't supported.
+	if isIPv4Enabled && isIPv6Enabled {
+		return ds.assignPodDualStackAddresses(ipamKey, ipamMetadata)
+	}
 	if isIPv4Enabled {
 		ipv4Address, deviceNumber, err = ds.AssignPodIPv4Address(ipamKey, ipamMetadata)
 	} else if isIPv6Enabled {
@@ -611,6 +877,67 @@ func (ds *DataStore) AssignPodIPAddress(ipamKey IPAMKey, ipamMetadata IPAMMetada
 	return ipv4Address, ipv6Address, deviceNumber, err
 }
 
+// assignPodDualStackAddresses assigns both an IPv4 and an IPv6 address to the pod and writes them to the backing
+// store together. Any family already assigned to the sandbox is reused.
+func (ds *DataStore) assignPodDualStackAddresses(ipamKey IPAMKey, ipamMetadata IPAMMetadata) (ipv4Address string,
+	ipv6Address string, deviceNumber int, err error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	if !ds.isPDEnabled {
+		return "", "", -1, fmt.Errorf("PD is not enabled. V6 is only supported in PD mode")
+	}
+	ds.log.Debugf("AssignPodIPAddress: IP address pool stats: total %d, assigned %d", ds.total, ds.assigned)
+
+	v4ENI, v4Cidr, v4Addr := ds.eniPool.FindIPv4AddressForSandbox(ipamKey)
+	v6ENI, v6Cidr, v6Addr := ds.eniPool.FindIPv6AddressForSandbox(ipamKey)
+	if v4Addr != nil && v6Addr != nil {
+		ds.log.Infof("AssignPodIPAddress: duplicate pod assign for sandbox %s", ipamKey)
+		return v4Addr.Address, v6Addr.Address, v4ENI.DeviceNumber, nil
+	}
+
+	var newV4, newV6 bool
+	var v4Reservation, v6Reservation *AddressInfo
+	if v4Addr == nil {
+		v4Reservation = ds.reservationUnsafe(ipamMetadata, false)
+		if v4ENI, v4Cidr, v4Addr, err = ds.assignPodIPv4AddressUnsafe(ipamKey, ipamMetadata, AnyDeviceNumber); err != nil {
+			return "", "", -1, err
+		}
+		newV4 = true
+	}
+	if v6Addr == nil {
+		v6Reservation = ds.reservationUnsafe(ipamMetadata, true)
+		if v6ENI, v6Cidr, v6Addr, err = ds.assignPodIPv6AddressUnsafe(ipamKey, ipamMetadata); err != nil {
+			// Unwind the IPv4 assignment so the sandbox is left without any address
+			if newV4 {
+				ds.unwindPodIPAddressUnsafe(v4Cidr, v4Addr, v4Reservation)
+			}
+			return "", "", -1, err
+		}
+		newV6 = true
+	}
+
+	if err := ds.writeBackingStoreUnsafe(sandboxCheckpointKeys(ipamKey, ipamMetadata)...); err != nil {
+		ds.log.Warnf("Failed to update backing store: %v", err)
+		// Important! Unwind both assignments
+		if newV4 {
+			ds.unwindPodIPAddressUnsafe(v4Cidr, v4Addr, v4Reservation)
+		}
+		if newV6 {
+			ds.unwindPodIPAddressUnsafe(v6Cidr, v6Addr, v6Reservation)
+		}
+		return "", "", -1, err
+	}
+	// Increment ENI IP usage on pod allocation
+	if newV4 {
+		prometheusmetrics.EniIPsInUse.WithLabelValues(v4ENI.ID).Inc()
+	}
+	if newV6 {
+		prometheusmetrics.EniIPsInUse.WithLabelValues(v6ENI.ID).Inc()
+	}
+	return v4Addr.Address, v6Addr.Address, v4ENI.DeviceNumber, nil
+}
+
 // AssignPodIPv6Address assigns an IPv6 address to pod. Returns the assigned IPv6 address along with device number
 func (ds *DataStore) AssignPodIPv6Address(ipamKey IPAMKey, ipamMetadata IPAMMetadata) (ipv6Address string, deviceNumber int, err error) {
 	ds.lock.Lock()
@@ -621,21 +948,43 @@ func (ds *DataStore) AssignPodIPv6Address(ipamKey IPAMKey, ipamMetadata IPAMMeta
 	}
 	ds.log.Debugf("AssignPodIPv6Address: IPv6 address pool stats: assigned %d", ds.assigned)
 
-	if eni, _, addr := ds.eniPool.FindAddressForSandbox(ipamKey); addr != nil {
+	if eni, _, addr := ds.eniPool.FindIPv6AddressForSandbox(ipamKey); addr != nil {
 		ds.log.Infof("AssignPodIPv6Address: duplicate pod assign for sandbox %s", ipamKey)
 		return addr.Address, eni.DeviceNumber, nil
 	}
 
-	// In IPv6 Prefix Delegation mode, eniPool will only have Primary ENI.
-	for _, eni := range ds.eniPool {
+	reservation := ds.reservationUnsafe(ipamMetadata, true)
+	eni, V6Cidr, addr, err := ds.assignPodIPv6AddressUnsafe(ipamKey, ipamMetadata)
+	if err != nil {
+		return "", -1, err
+	}
+	if err := ds.writeBackingStoreUnsafe(sandboxCheckpointKeys(ipamKey, ipamMetadata)...); err != nil {
+		ds.log.Warnf("Failed to update backing store: %v", err)
+		// Important! Unwind assignment
+		ds.unwindPodIPAddressUnsafe(V6Cidr, addr, reservation)
+		return "", -1, err
+	}
+	// Increment ENI IP usage on pod IPv6 allocation
+	prometheusmetrics.EniIPsInUse.WithLabelValues(eni.ID).Inc()
+	return addr.Address, eni.DeviceNumber, nil
+}
+
+// assignPodIPv6AddressUnsafe picks a free IPv6 address from the ENI prefixes and marks it as assigned to the sandbox.
+// The caller must hold the lock and persist the assignment.
+func (ds *DataStore) assignPodIPv6AddressUnsafe(ipamKey IPAMKey, ipamMetadata IPAMMetadata) (*ENI, *CidrInfo, *AddressInfo, error) {
+	if eni, V6Cidr, addr := ds.assignReservedIPAddressUnsafe(ipamKey, ipamMetadata, true); addr != nil {
+		return eni, V6Cidr, addr, nil
+	}
+	// IPv6 prefixes are only ever attached to the Primary ENI.
+	for _, eni := range ds.orderedENIs(true) {
 		if len(eni.IPv6Cidrs) == 0 {
 			continue
 		}
-		for _, V6Cidr := range eni.IPv6Cidrs {
+		for _, V6Cidr := range ds.orderedCidrs(eni, true) {
 			if !V6Cidr.IsPrefix {
 				continue
 			}
-			ipv6Address, err = ds.getFreeIPv6AddrFromCidr(V6Cidr)
+			ipv6Address, err := ds.getFreeIPv6AddrFromCidr(V6Cidr)
 			if err != nil {
 				ds.log.Debugf("Unable to get IP address from prefix: %v", err)
 				//In v6 mode, we (should) only have one CIDR/Prefix. So, we can bail out but we will let the loop
@@ -647,21 +996,11 @@ func (ds *DataStore) AssignPodIPv6Address(ipamKey IPAMKey, ipamMetadata IPAMMeta
 			V6Cidr.IPAddresses[ipv6Address] = addr
 
 			ds.assignPodIPAddressUnsafe(addr, ipamKey, ipamMetadata, time.Now())
-			if err := ds.writeBackingStoreUnsafe(); err != nil {
-				ds.log.Warnf("Failed to update backing store: %v", err)
-				// Important! Unwind assignment
-				ds.unassignPodIPAddressUnsafe(addr)
-				//Remove the IP from eni DB
-				delete(V6Cidr.IPAddresses, addr.Address)
-				return "", -1, err
-			}
-			// Increment ENI IP usage on pod IPv6 allocation
-			prometheusmetrics.EniIPsInUse.WithLabelValues(eni.ID).Inc()
-			return addr.Address, eni.DeviceNumber, nil
+			return eni, V6Cidr, addr, nil
 		}
 	}
 	prometheusmetrics.NoAvailableIPAddrs.Inc()
-	return "", -1, errors.New("AssignPodIPv6Address: no available IP addresses")
+	return nil, nil, nil, errors.New("AssignPodIPv6Address: no available IP addresses")
 }
 
 // AssignPodIPv4Address assigns an IPv4 address to pod
@@ -672,13 +1011,68 @@ func (ds *DataStore) AssignPodIPv4Address(ipamKey IPAMKey, ipamMetadata IPAMMeta
 
 	ds.log.Debugf("AssignPodIPv4Address: IP address pool stats: total %d, assigned %d", ds.total, ds.assigned)
 
-	if eni, _, addr := ds.eniPool.FindAddressForSandbox(ipamKey); addr != nil {
+	if eni, _, addr := ds.eniPool.FindIPv4AddressForSandbox(ipamKey); addr != nil {
 		ds.log.Infof("AssignPodIPv4Address: duplicate pod assign for sandbox %s", ipamKey)
 		return addr.Address, eni.DeviceNumber, nil
 	}
 
+	return ds.assignPodIPv4AddressAndPersistUnsafe(ipamKey, ipamMetadata, AnyDeviceNumber)
+}
+
+// AssignPodIPv4AddressOnDevice assigns an IPv4 address of the ENI with the given device number to the sandbox. The
+// ENI must belong to the IP pool of the pod. It is used for the extra interfaces of multi-homed pods.
+func (ds *DataStore) AssignPodIPv4AddressOnDevice(ipamKey IPAMKey, ipamMetadata IPAMMetadata, deviceNumber int) (ipv4address string, err error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	if eni, _, addr := ds.eniPool.FindIPv4AddressForSandbox(ipamKey); addr != nil {
+		if eni.DeviceNumber != deviceNumber {
+			return "", errors.Errorf("AssignPodIPv4AddressOnDevice: sandbox %s already holds %s on device %d", ipamKey, addr.Address, eni.DeviceNumber)
+		}
+		ds.log.Infof("AssignPodIPv4AddressOnDevice: duplicate pod assign for sandbox %s", ipamKey)
+		return addr.Address, nil
+	}
 	for _, eni := range ds.eniPool {
-		for _, availableCidr := range eni.AvailableIPv4Cidrs {
+		if eni.DeviceNumber == deviceNumber && eni.IPPool != ipamMetadata.IPPool {
+			return "", errors.Errorf("AssignPodIPv4AddressOnDevice: device %d belongs to IP pool %q, not %q", deviceNumber, eni.IPPool, ipamMetadata.IPPool)
+		}
+	}
+	ipv4address, _, err = ds.assignPodIPv4AddressAndPersistUnsafe(ipamKey, ipamMetadata, deviceNumber)
+	return ipv4address, err
+}
+
+func (ds *DataStore) assignPodIPv4AddressAndPersistUnsafe(ipamKey IPAMKey, ipamMetadata IPAMMetadata, deviceNumber int) (string, int, error) {
+	reservation := ds.reservationUnsafe(ipamMetadata, false)
+	eni, availableCidr, addr, err := ds.assignPodIPv4AddressUnsafe(ipamKey, ipamMetadata, deviceNumber)
+	if err != nil {
+		return "", -1, err
+	}
+	if err := ds.writeBackingStoreUnsafe(sandboxCheckpointKeys(ipamKey, ipamMetadata)...); err != nil {
+		ds.log.Warnf("Failed to update backing store: %v", err)
+		// Important! Unwind assignment
+		ds.unwindPodIPAddressUnsafe(availableCidr, addr, reservation)
+		return "", -1, err
+	}
+	// Increment ENI IP usage on pod IPv4 allocation
+	prometheusmetrics.EniIPsInUse.WithLabelValues(eni.ID).Inc()
+	return addr.Address, eni.DeviceNumber, nil
+}
+
+// assignPodIPv4AddressUnsafe picks a free IPv4 address from the ENI pool and marks it as assigned to the sandbox.
+// The address is taken from the ENIs of the IP pool of the pod, only from the ENI with deviceNumber unless it is
+// AnyDeviceNumber. The caller must hold the lock and persist the assignment.
+func (ds *DataStore) assignPodIPv4AddressUnsafe(ipamKey IPAMKey, ipamMetadata IPAMMetadata, deviceNumber int) (*ENI, *CidrInfo, *AddressInfo, error) {
+	if eni, availableCidr, addr := ds.assignReservedIPAddressUnsafe(ipamKey, ipamMetadata, false); addr != nil {
+		return eni, availableCidr, addr, nil
+	}
+	for _, eni := range ds.orderedENIs(false) {
+		if eni.IPPool != ipamMetadata.IPPool {
+			continue
+		}
+		if deviceNumber != AnyDeviceNumber && eni.DeviceNumber != deviceNumber {
+			continue
+		}
+		for _, availableCidr := range ds.orderedCidrs(eni, false) {
 			var addr *AddressInfo
 			var strPrivateIPv4 string
 			var err error
@@ -712,27 +1106,110 @@ func (ds *DataStore) AssignPodIPv4Address(ipamKey IPAMKey, ipamMetadata IPAMMeta
 
 			availableCidr.IPAddresses[strPrivateIPv4] = addr
 			ds.assignPodIPAddressUnsafe(addr, ipamKey, ipamMetadata, time.Now())
-
-			if err := ds.writeBackingStoreUnsafe(); err != nil {
-				ds.log.Warnf("Failed to update backing store: %v", err)
-				// Important! Unwind assignment
-				ds.unassignPodIPAddressUnsafe(addr)
-				// Remove the IP from eni DB
-				delete(availableCidr.IPAddresses, addr.Address)
-				// Update prometheus for ips per cidr
-				prometheusmetrics.IpsPerCidr.With(prometheus.Labels{"cidr": availableCidr.Cidr.String()}).Dec()
-				return "", -1, err
-			}
-			// Increment ENI IP usage on pod IPv4 allocation
-			prometheusmetrics.EniIPsInUse.WithLabelValues(eni.ID).Inc()
-			return addr.Address, eni.DeviceNumber, nil
+			return eni, availableCidr, addr, nil
 		}
 		ds.log.Debugf("AssignPodIPv4Address: ENI %s does not have available addresses", eni.ID)
 	}
 
 	prometheusmetrics.NoAvailableIPAddrs.Inc()
+	if deviceNumber != AnyDeviceNumber {
+		ds.log.Errorf("DataStore has no available IP/Prefix addresses on device %d", deviceNumber)
+		return nil, nil, nil, errors.Errorf("AssignPodIPv4Address: no available IP/Prefix addresses on device %d", deviceNumber)
+	}
+	if ipamMetadata.IPPool != DefaultIPPool {
+		ds.log.Errorf("DataStore has no available IP/Prefix addresses in IP pool %q", ipamMetadata.IPPool)
+		return nil, nil, nil, errors.Errorf("AssignPodIPv4Address: no available IP/Prefix addresses in IP pool %q", ipamMetadata.IPPool)
+	}
 	ds.log.Errorf("DataStore has no available IP/Prefix addresses")
-	return "", -1, errors.New("AssignPodIPv4Address: no available IP/Prefix addresses")
+	return nil, nil, nil, errors.New("AssignPodIPv4Address: no available IP/Prefix addresses")
+}
+
+// AssignPodStaticIPv4Address assigns the requested IPv4 address (/32), or a free address of the requested prefix (/28),
+// to the pod. The address may belong to a secondary IP or a prefix regardless of the PD mode, but must belong to an ENI
+// of the IP pool of the pod. ErrUnknownStaticCidr is returned if it doesn't belong to any ENI of the data store, so that
+// the caller can allocate it first.
+func (ds *DataStore) AssignPodStaticIPv4Address(ipamKey IPAMKey, ipamMetadata IPAMMetadata, cidr net.IPNet) (ipv4Address string, deviceNumber int, err error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	if eni, _, addr := ds.eniPool.FindIPv4AddressForSandbox(ipamKey); addr != nil {
+		ds.log.Infof("AssignPodStaticIPv4Address: duplicate pod assign for sandbox %s", ipamKey)
+		return addr.Address, eni.DeviceNumber, nil
+	}
+
+	ones, bits := cidr.Mask.Size()
+	var eni *ENI
+	var availableCidr *CidrInfo
+	for _, curENI := range ds.eniPool {
+		for _, curCidr := range curENI.AvailableIPv4Cidrs {
+			// A single address may be taken from a prefix, a prefix must match exactly
+			if (ones == bits && curCidr.Cidr.Contains(cidr.IP)) || curCidr.Cidr.String() == cidr.String() {
+				eni, availableCidr = curENI, curCidr
+			}
+		}
+	}
+	if availableCidr == nil {
+		return "", -1, ErrUnknownStaticCidr
+	}
+	if eni.IPPool != ipamMetadata.IPPool {
+		return "", -1, errors.Errorf("requested IP/Prefix %s belongs to IP pool %q, not %q", cidr.String(), eni.IPPool, ipamMetadata.IPPool)
+	}
+
+	var strPrivateIPv4 string
+	if ones == bits {
+		strPrivateIPv4 = cidr.IP.String()
+		if addr, ok := availableCidr.IPAddresses[strPrivateIPv4]; ok {
+			if addr.Assigned() {
+				return "", -1, errors.Errorf("requested IP %s is already in use by sandbox %s", strPrivateIPv4, addr.IPAMKey)
+			}
+			if addr.isReserved(ds.stickyIPTTL) && !addr.isReservedFor(ipamMetadata, ds.stickyIPTTL) {
+				return "", -1, errors.Errorf("requested IP %s is held for pod %s/%s", strPrivateIPv4,
+					addr.IPAMMetadata.K8SPodNamespace, addr.IPAMMetadata.K8SPodName)
+			}
+		}
+	} else {
+		strPrivateIPv4, err = ds.getFreeIPv4AddrfromCidr(availableCidr)
+		if err != nil {
+			return "", -1, errors.Wrapf(err, "no free IP in requested prefix %s", cidr.String())
+		}
+	}
+
+	var reservation *AddressInfo
+	addr := availableCidr.IPAddresses[strPrivateIPv4]
+	if addr == nil {
+		addr = &AddressInfo{Address: strPrivateIPv4}
+		availableCidr.IPAddresses[strPrivateIPv4] = addr
+	} else if addr.isReservedFor(ipamMetadata, ds.stickyIPTTL) {
+		held := *addr
+		reservation = &held
+	}
+	ds.assignPodIPAddressUnsafe(addr, ipamKey, ipamMetadata, time.Now())
+	// Update prometheus for ips per cidr
+	prometheusmetrics.IpsPerCidr.With(prometheus.Labels{"cidr": availableCidr.Cidr.String()}).Inc()
+	if err := ds.writeBackingStoreUnsafe(sandboxCheckpointKeys(ipamKey, ipamMetadata)...); err != nil {
+		ds.log.Warnf("Failed to update backing store: %v", err)
+		// Important! Unwind assignment
+		ds.unwindPodIPAddressUnsafe(availableCidr, addr, reservation)
+		return "", -1, err
+	}
+	// Increment ENI IP usage on pod IPv4 allocation
+	prometheusmetrics.EniIPsInUse.WithLabelValues(eni.ID).Inc()
+	return addr.Address, eni.DeviceNumber, nil
+}
+
+// unwindPodIPAddressUnsafe reverts an assignment that could not be persisted. The address is held for the pod again
+// if it was reserved for it, as recorded in reservation, otherwise it is removed from the ENI DB.
+func (ds *DataStore) unwindPodIPAddressUnsafe(availableCidr *CidrInfo, addr *AddressInfo, reservation *AddressInfo) {
+	ds.unassignPodIPAddressUnsafe(addr)
+	if reservation != nil && reservation.Address == addr.Address {
+		*addr = *reservation
+	} else {
+		delete(availableCidr.IPAddresses, addr.Address)
+	}
+	if availableCidr.AddressFamily == "4" {
+		// Update prometheus for ips per cidr
+		prometheusmetrics.IpsPerCidr.With(prometheus.Labels{"cidr": availableCidr.Cidr.String()}).Dec()
+	}
 }
 
 // assignPodIPAddressUnsafe mark Address as assigned.
@@ -746,6 +1223,7 @@ func (ds *DataStore) assignPodIPAddressUnsafe(addr *AddressInfo, ipamKey IPAMKey
 	addr.IPAMKey = ipamKey // This marks the addr as assigned
 	addr.IPAMMetadata = ipamMetadata
 	addr.AssignedTime = assignedTime
+	ds.publishAddressUnsafe(AllocationAssigned, addr, ipamKey, ipamMetadata)
 
 	ds.assigned++
 	// Prometheus gauge
@@ -760,8 +1238,14 @@ func (ds *DataStore) unassignPodIPAddressUnsafe(addr *AddressInfo) {
 	}
 	ds.log.Infof("unassignPodIPAddressUnsafe: Unassign IP %v from sandbox %s",
 		addr.Address, addr.IPAMKey)
+	ds.publishAddressUnsafe(AllocationUnassigned, addr, addr.IPAMKey, addr.IPAMMetadata)
 	addr.IPAMKey = IPAMKey{} // unassign the addr
-	addr.IPAMMetadata = IPAMMetadata{}
+	if addr.IPAMMetadata.StickyIP {
+		// Keep the pod identity, the address is held for it until stickyIPTTL expires
+		addr.UnassignedTime = time.Now()
+	} else {
+		addr.IPAMMetadata = IPAMMetadata{}
+	}
 	ds.assigned--
 	// Prometheus gauge
 	prometheusmetrics.AssignedIPs.Set(float64(ds.assigned))
@@ -777,40 +1261,72 @@ type DataStoreStats struct {
 	AssignedIPs int
 	// Number of addresses in cooldown
 	CooldownIPs int
+	// Number of addresses held for deleted sticky IP pods
+	ReservedIPs int
+
+	// IP pool of the stats, only set for the stats of a single pool
+	IPPool string
 }
 
 func (stats *DataStoreStats) String() string {
-	return fmt.Sprintf("Total IPs/Prefixes = %d/%d, AssignedIPs/CooldownIPs: %d/%d",
-		stats.TotalIPs, stats.TotalPrefixes, stats.AssignedIPs, stats.CooldownIPs)
+	return fmt.Sprintf("Total IPs/Prefixes = %d/%d, AssignedIPs/CooldownIPs/ReservedIPs: %d/%d/%d",
+		stats.TotalIPs, stats.TotalPrefixes, stats.AssignedIPs, stats.CooldownIPs, stats.ReservedIPs)
 }
 
 func (stats *DataStoreStats) AvailableAddresses() int {
 	return stats.TotalIPs - stats.AssignedIPs
 }
 
+// FreeAddresses returns the number of addresses which can be assigned right away, that is neither in cooldown nor
+// held for a sticky IP pod
+func (stats *DataStoreStats) FreeAddresses() int {
+	return stats.TotalIPs - stats.AssignedIPs - stats.CooldownIPs - stats.ReservedIPs
+}
+
 // GetIPStats returns DataStoreStats for addressFamily
 func (ds *DataStore) GetIPStats(addressFamily string) *DataStoreStats {
 	ds.lock.Lock()
 	defer ds.lock.Unlock()
 
-	stats := &DataStoreStats{
-		TotalPrefixes: ds.allocatedPrefix,
-	}
+	stats := ds.getIPStatsUnsafe(addressFamily, func(*ENI) bool { return true })
+	stats.TotalPrefixes = ds.allocatedPrefix
+	return stats
+}
+
+// GetPoolIPStats returns DataStoreStats for addressFamily, counting only the ENIs of the IP pool
+func (ds *DataStore) GetPoolIPStats(ipPool, addressFamily string) *DataStoreStats {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	stats := ds.getIPStatsUnsafe(addressFamily, func(eni *ENI) bool { return eni.IPPool == ipPool })
+	stats.IPPool = ipPool
+	return stats
+}
+
+func (ds *DataStore) getIPStatsUnsafe(addressFamily string, include func(*ENI) bool) *DataStoreStats {
+	stats := &DataStoreStats{}
 	for _, eni := range ds.eniPool {
+		if !include(eni) {
+			continue
+		}
 		AssignedCIDRs := eni.AvailableIPv4Cidrs
 		if addressFamily == "6" {
 			AssignedCIDRs = eni.IPv6Cidrs
 		}
 		for _, cidr := range AssignedCIDRs {
 			if addressFamily == "4" && ((ds.isPDEnabled && cidr.IsPrefix) || (!ds.isPDEnabled && !cidr.IsPrefix)) {
-				cidrStats := cidr.GetIPStatsFromCidr(ds.ipCooldownPeriod)
+				cidrStats := cidr.GetIPStatsFromCidr(ds.ipCooldownPeriod, ds.stickyIPTTL)
 				stats.AssignedIPs += cidrStats.AssignedIPs
 				stats.CooldownIPs += cidrStats.CooldownIPs
+				stats.ReservedIPs += cidrStats.ReservedIPs
 				stats.TotalIPs += cidr.Size()
 			} else if addressFamily == "6" {
 				stats.AssignedIPs += cidr.AssignedIPAddressesInCidr()
 				stats.TotalIPs += cidr.Size()
 			}
+			if cidr.IsPrefix {
+				stats.TotalPrefixes++
+			}
 		}
 	}
 	return stats
@@ -845,7 +1361,7 @@ func (ds *DataStore) GetEFAENIs() map[string]bool {
 func (ds *DataStore) isRequiredForWarmIPTarget(warmIPTarget int, eni *ENI) bool {
 	otherWarmIPs := 0
 	for _, other := range ds.eniPool {
-		if other.ID != eni.ID {
+		if other.ID != eni.ID && other.IPPool == eni.IPPool {
 			for _, otherPrefixes := range other.AvailableIPv4Cidrs {
 				if (ds.isPDEnabled && otherPrefixes.IsPrefix) || (!ds.isPDEnabled && !otherPrefixes.IsPrefix) {
 					otherWarmIPs += otherPrefixes.Size() - otherPrefixes.AssignedIPAddressesInCidr()
@@ -866,7 +1382,7 @@ func (ds *DataStore) isRequiredForWarmIPTarget(warmIPTarget int, eni *ENI) bool
 func (ds *DataStore) isRequiredForMinimumIPTarget(minimumIPTarget int, eni *ENI) bool {
 	otherIPs := 0
 	for _, other := range ds.eniPool {
-		if other.ID != eni.ID {
+		if other.ID != eni.ID && other.IPPool == eni.IPPool {
 			for _, otherPrefixes := range other.AvailableIPv4Cidrs {
 				if (ds.isPDEnabled && otherPrefixes.IsPrefix) || (!ds.isPDEnabled && !otherPrefixes.IsPrefix) {
 					otherIPs += otherPrefixes.Size()
@@ -887,7 +1403,7 @@ func (ds *DataStore) isRequiredForMinimumIPTarget(minimumIPTarget int, eni *ENI)
 func (ds *DataStore) isRequiredForWarmPrefixTarget(warmPrefixTarget int, eni *ENI) bool {
 	freePrefixes := 0
 	for _, other := range ds.eniPool {
-		if other.ID != eni.ID {
+		if other.ID != eni.ID && other.IPPool == eni.IPPool {
 			for _, otherPrefixes := range other.AvailableIPv4Cidrs {
 				if otherPrefixes.AssignedIPAddressesInCidr() == 0 {
 					freePrefixes++
@@ -898,8 +1414,12 @@ func (ds *DataStore) isRequiredForWarmPrefixTarget(warmPrefixTarget int, eni *EN
 	return freePrefixes < warmPrefixTarget
 }
 
-func (ds *DataStore) getDeletableENI(warmIPTarget, minimumIPTarget, warmPrefixTarget int) *ENI {
+func (ds *DataStore) getDeletableENI(ipPool string, warmIPTarget, minimumIPTarget, warmPrefixTarget int) *ENI {
 	for _, eni := range ds.eniPool {
+		if eni.IPPool != ipPool {
+			continue
+		}
+
 		if eni.IsPrimary {
 			ds.log.Debugf("ENI %s cannot be deleted because it is primary", eni.ID)
 			continue
@@ -910,7 +1430,7 @@ func (ds *DataStore) getDeletableENI(warmIPTarget, minimumIPTarget, warmPrefixTa
 			continue
 		}
 
-		if eni.hasIPInCooling(ds.ipCooldownPeriod) {
+		if eni.hasIPInCooling(ds.ipCooldownPeriod, ds.stickyIPTTL) {
 			ds.log.Debugf("ENI %s cannot be deleted because has IPs in cooling", eni.ID)
 			continue
 		}
@@ -956,11 +1476,11 @@ func (e *ENI) isTooYoung() bool {
 	return time.Since(e.createTime) < minENILifeTime
 }
 
-// HasIPInCooling returns true if an IP address was unassigned recently.
-func (e *ENI) hasIPInCooling(ipCooldownPeriod time.Duration) bool {
+// HasIPInCooling returns true if an IP address was unassigned recently, or is held for a sticky IP pod.
+func (e *ENI) hasIPInCooling(ipCooldownPeriod, stickyIPTTL time.Duration) bool {
 	for _, assignedaddr := range e.AvailableIPv4Cidrs {
 		for _, addr := range assignedaddr.IPAddresses {
-			if addr.inCoolingPeriod(ipCooldownPeriod) {
+			if addr.inCoolingPeriod(ipCooldownPeriod) || addr.isReserved(stickyIPTTL) {
 				return true
 			}
 		}
@@ -992,14 +1512,14 @@ func (ds *DataStore) GetAllocatableENIs(maxIPperENI int, skipPrimary bool) []*EN
 	return enis
 }
 
-// RemoveUnusedENIFromStore removes a deletable ENI from the data store.
+// RemoveUnusedENIFromStore removes a deletable ENI of the IP pool from the data store.
 // It returns the name of the ENI which has been removed from the data store and needs to be deleted,
 // or empty string if no ENI could be removed.
-func (ds *DataStore) RemoveUnusedENIFromStore(warmIPTarget, minimumIPTarget, warmPrefixTarget int) string {
+func (ds *DataStore) RemoveUnusedENIFromStore(ipPool string, warmIPTarget, minimumIPTarget, warmPrefixTarget int) string {
 	ds.lock.Lock()
 	defer ds.lock.Unlock()
 
-	deletableENI := ds.getDeletableENI(warmIPTarget, minimumIPTarget, warmPrefixTarget)
+	deletableENI := ds.getDeletableENI(ipPool, warmIPTarget, minimumIPTarget, warmPrefixTarget)
 	if deletableENI == nil {
 		return ""
 	}
@@ -1015,6 +1535,7 @@ func (ds *DataStore) RemoveUnusedENIFromStore(warmIPTarget, minimumIPTarget, war
 	ds.log.Infof("RemoveUnusedENIFromStore %s: IP/Prefix address pool stats: free %d addresses, total: %d, assigned: %d, total prefixes: %d",
 		removableENI, len(ds.eniPool[removableENI].AvailableIPv4Cidrs), ds.total, ds.assigned, ds.allocatedPrefix)
 
+	ds.publishUnsafe(AllocationEvent{Type: ENIDetached, ENI: removableENI})
 	delete(ds.eniPool, removableENI)
 
 	// Prometheus update
@@ -1071,6 +1592,7 @@ func (ds *DataStore) RemoveENIFromDataStore(eniID string, force bool) error {
 
 	ds.log.Infof("RemoveENIFromDataStore %s: IP/Prefix address pool stats: free %d addresses, total: %d, assigned: %d, total prefixes: %d",
 		eniID, len(eni.AvailableIPv4Cidrs), ds.total, ds.assigned, ds.allocatedPrefix)
+	ds.publishUnsafe(AllocationEvent{Type: ENIDetached, ENI: eniID})
 	delete(ds.eniPool, eniID)
 
 	// Prometheus gauge
@@ -1080,37 +1602,234 @@ func (ds *DataStore) RemoveENIFromDataStore(eniID string, force bool) error {
 	return nil
 }
 
-// UnassignPodIPAddress a) find out the IP address based on PodName and PodNameSpace
-// b)  mark IP address as unassigned c) returns IP address, ENI's device number, error
-func (ds *DataStore) UnassignPodIPAddress(ipamKey IPAMKey) (e *ENI, ip string, deviceNumber int, err error) {
+// UnassignPodIPAddress a) find out the IP addresses based on PodName and PodNameSpace
+// b)  mark IP addresses as unassigned c) returns the ENI of the IPv4 address (or the IPv6 one if the pod has no IPv4),
+// IPv4 and IPv6 addresses, ENI's device number, error. In dual-stack mode both addresses are released together.
+func (ds *DataStore) UnassignPodIPAddress(ipamKey IPAMKey) (e *ENI, ipv4 string, ipv6 string, deviceNumber int, err error) {
 	ds.lock.Lock()
 	defer ds.lock.Unlock()
 	ds.log.Debugf("UnassignPodIPAddress: IP address pool stats: total %d, assigned %d, sandbox %s", ds.total, ds.assigned, ipamKey)
 
-	eni, availableCidr, addr := ds.eniPool.FindAddressForSandbox(ipamKey)
-	if addr == nil {
+	v4ENI, v4Cidr, v4Addr := ds.eniPool.FindIPv4AddressForSandbox(ipamKey)
+	v6ENI, v6Cidr, v6Addr := ds.eniPool.FindIPv6AddressForSandbox(ipamKey)
+	if v4Addr == nil && v6Addr == nil {
 		// If the entry is not present in state file, check if it is present under placeholder value.
 		// This scenario could happen if the pod was created by an older CNI version back when CRI read was done.
 		ds.log.Debugf("UnassignPodIPAddress: Failed to find IPAM entry under full key, trying CRI-migrated version")
 		ipamKey.NetworkName = backfillNetworkName
 		ipamKey.IfName = backfillNetworkIface
-		eni, availableCidr, addr = ds.eniPool.FindAddressForSandbox(ipamKey)
+		v4ENI, v4Cidr, v4Addr = ds.eniPool.FindIPv4AddressForSandbox(ipamKey)
+		v6ENI, v6Cidr, v6Addr = ds.eniPool.FindIPv6AddressForSandbox(ipamKey)
 
 		// If entry is still not found, IPAMD has no knowledge of this pod, so there is nothing to do.
-		if addr == nil {
+		if v4Addr == nil && v6Addr == nil {
 			ds.log.Warnf("UnassignPodIPAddress: Failed to find sandbox %s", ipamKey)
-			return nil, "", 0, ErrUnknownPod
+			return nil, "", "", 0, ErrUnknownPod
 		}
 	}
 
-	originalIPAMMetadata := addr.IPAMMetadata
-	originalAssignedTime := addr.AssignedTime
-	ds.unassignPodIPAddressUnsafe(addr)
-	if err := ds.writeBackingStoreUnsafe(); err != nil {
+	var v4Metadata, v6Metadata IPAMMetadata
+	var v4AssignedTime, v6AssignedTime time.Time
+	if v4Addr != nil {
+		v4Metadata, v4AssignedTime = v4Addr.IPAMMetadata, v4Addr.AssignedTime
+		ds.unassignPodIPAddressUnsafe(v4Addr)
+	}
+	if v6Addr != nil {
+		v6Metadata, v6AssignedTime = v6Addr.IPAMMetadata, v6Addr.AssignedTime
+		ds.unassignPodIPAddressUnsafe(v6Addr)
+	}
+	// Both addresses of a dual-stack sandbox belong to the same pod
+	keys := sandboxCheckpointKeys(ipamKey, v6Metadata)
+	if v4Addr != nil {
+		keys = sandboxCheckpointKeys(ipamKey, v4Metadata)
+	}
+	if err := ds.writeBackingStoreUnsafe(keys...); err != nil {
 		// Unwind un-assignment
-		ds.assignPodIPAddressUnsafe(addr, ipamKey, originalIPAMMetadata, originalAssignedTime)
-		return nil, "", 0, err
+		if v4Addr != nil {
+			ds.assignPodIPAddressUnsafe(v4Addr, ipamKey, v4Metadata, v4AssignedTime)
+		}
+		if v6Addr != nil {
+			ds.assignPodIPAddressUnsafe(v6Addr, ipamKey, v6Metadata, v6AssignedTime)
+		}
+		return nil, "", "", 0, err
+	}
+
+	if v4Addr != nil {
+		ds.finishUnassignUnsafe(ipamKey, v4ENI, v4Cidr, v4Addr)
+	}
+	if v6Addr != nil {
+		ds.finishUnassignUnsafe(ipamKey, v6ENI, v6Cidr, v6Addr)
+	}
+
+	e = v4ENI
+	if v4Addr != nil {
+		ipv4 = v4Addr.Address
+	} else {
+		e = v6ENI
+	}
+	if v6Addr != nil {
+		ipv6 = v6Addr.Address
+	}
+	return e, ipv4, ipv6, e.DeviceNumber, nil
+}
+
+// AssignPodENIAddress records the addresses of a pod using the branch ENI eniID with the given VLAN. The addresses
+// are allocated by the VPC resource controller rather than taken from the ENI pool, they are only checkpointed
+// so that the pod can be torn down without looking up its annotation, even after a restart.
+func (ds *DataStore) AssignPodENIAddress(ipamKey IPAMKey, ipamMetadata IPAMMetadata, ipv4Address string, ipv6Address string,
+	eniID string, vlanID int) error {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	if vlanID == 0 {
+		return errors.Errorf("AssignPodENIAddress: missing VLAN ID of branch ENI %s for sandbox %s", eniID, ipamKey)
+	}
+	if _, ok := ds.branchENIPods[ipamKey]; ok {
+		ds.log.Infof("AssignPodENIAddress: duplicate pod assign for sandbox %s", ipamKey)
+		return nil
+	}
+
+	entry := CheckpointEntry{
+		IPAMKey:             ipamKey,
+		IPv4:                ipv4Address,
+		IPv6:                ipv6Address,
+		AllocationTimestamp: time.Now().UnixNano(),
+		Metadata:            ipamMetadata,
+		DeviceNumber:        -1,
+		VlanID:              vlanID,
+	}
+	if ipv4Address != "" {
+		entry.IPv4ENI = eniID
+	}
+	if ipv6Address != "" {
+		entry.IPv6ENI = eniID
+	}
+	ds.branchENIPods[ipamKey] = entry
+	if err := ds.writeBackingStoreUnsafe(ipamKey); err != nil {
+		ds.log.Warnf("Failed to update backing store: %v", err)
+		// Important! Unwind assignment
+		delete(ds.branchENIPods, ipamKey)
+		return err
+	}
+	ds.log.Infof("AssignPodENIAddress: sandbox %s uses branch ENI %s, vlan %d", ipamKey, eniID, vlanID)
+	return nil
+}
+
+// UnassignPodENIAddress forgets the pod using a branch ENI recorded by AssignPodENIAddress and returns its entry.
+// ErrUnknownPod is returned if the sandbox is not recorded.
+func (ds *DataStore) UnassignPodENIAddress(ipamKey IPAMKey) (CheckpointEntry, error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	entry, ok := ds.branchENIPods[ipamKey]
+	if !ok {
+		return CheckpointEntry{}, ErrUnknownPod
 	}
+	delete(ds.branchENIPods, ipamKey)
+	if err := ds.writeBackingStoreUnsafe(ipamKey); err != nil {
+		// Unwind un-assignment
+		ds.branchENIPods[ipamKey] = entry
+		return CheckpointEntry{}, err
+	}
+	ds.log.Infof("UnassignPodENIAddress: sandbox %s released branch ENI vlan %d", ipamKey, entry.VlanID)
+	return entry, nil
+}
+
+// GetPodAllocation returns the addresses held by the sandbox, as they are checkpointed. ErrUnknownPod is returned if
+// the sandbox holds none.
+func (ds *DataStore) GetPodAllocation(ipamKey IPAMKey) (CheckpointEntry, error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	if entry, ok := ds.branchENIPods[ipamKey]; ok {
+		return entry, nil
+	}
+	entry := CheckpointEntry{IPAMKey: ipamKey}
+	if eni, availableCidr, addr := ds.eniPool.FindIPv6AddressForSandbox(ipamKey); addr != nil {
+		entry.IPv6 = addr.Address
+		entry.IPv6ENI = eni.ID
+		entry.IPv6Cidr = availableCidr.Cidr.String()
+		entry.DeviceNumber = eni.DeviceNumber
+		entry.AllocationTimestamp = addr.AssignedTime.UnixNano()
+		entry.Metadata = addr.IPAMMetadata
+	}
+	// The device number of the IPv4 ENI wins in dual-stack mode
+	if eni, availableCidr, addr := ds.eniPool.FindIPv4AddressForSandbox(ipamKey); addr != nil {
+		entry.IPv4 = addr.Address
+		entry.IPv4ENI = eni.ID
+		entry.IPv4Cidr = availableCidr.Cidr.String()
+		entry.DeviceNumber = eni.DeviceNumber
+		entry.AllocationTimestamp = addr.AssignedTime.UnixNano()
+		entry.Metadata = addr.IPAMMetadata
+	}
+	if entry.IPv4 == "" && entry.IPv6 == "" {
+		return CheckpointEntry{}, ErrUnknownPod
+	}
+	return entry, nil
+}
+
+// SandboxInterfaces returns the keys of the interfaces of the sandbox holding addresses in networkName, that is the
+// one set up by the CNI ADD and the extra interfaces of multi-homed pods
+func (ds *DataStore) SandboxInterfaces(networkName string, containerID string) []IPAMKey {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	interfaces := make(map[IPAMKey]bool)
+	for _, eni := range ds.eniPool {
+		for _, isIPv6 := range []bool{false, true} {
+			for _, cidr := range eni.cidrs(isIPv6) {
+				for _, addr := range cidr.IPAddresses {
+					if addr.Assigned() && addr.IPAMKey.NetworkName == networkName && addr.IPAMKey.ContainerID == containerID {
+						interfaces[addr.IPAMKey] = true
+					}
+				}
+			}
+		}
+	}
+	ret := make([]IPAMKey, 0, len(interfaces))
+	for ipamKey := range interfaces {
+		ret = append(ret, ipamKey)
+	}
+	return ret
+}
+
+// StaleAllocations returns the sandboxes of networkName holding addresses or a branch ENI whose container ID is not in
+// validContainerIDs. All the interfaces of a valid container are left alone, including the extra interfaces the
+// container runtime does not know of. Allocations made after assignedBefore are left out, as their sandbox may still
+// be unknown to the caller.
+func (ds *DataStore) StaleAllocations(networkName string, validContainerIDs map[string]bool, assignedBefore time.Time) []IPAMKey {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	stale := make(map[IPAMKey]bool)
+	isStale := func(ipamKey IPAMKey, assignedTime time.Time) bool {
+		return ipamKey.NetworkName == networkName && !validContainerIDs[ipamKey.ContainerID] && assignedTime.Before(assignedBefore)
+	}
+	for _, eni := range ds.eniPool {
+		for _, isIPv6 := range []bool{false, true} {
+			for _, cidr := range eni.cidrs(isIPv6) {
+				for _, addr := range cidr.IPAddresses {
+					if addr.Assigned() && isStale(addr.IPAMKey, addr.AssignedTime) {
+						stale[addr.IPAMKey] = true
+					}
+				}
+			}
+		}
+	}
+	for ipamKey, entry := range ds.branchENIPods {
+		if isStale(ipamKey, time.Unix(0, entry.AllocationTimestamp)) {
+			stale[ipamKey] = true
+		}
+	}
+	ret := make([]IPAMKey, 0, len(stale))
+	for ipamKey := range stale {
+		ret = append(ret, ipamKey)
+	}
+	return ret
+}
+
+// finishUnassignUnsafe starts the cooldown of an address whose un-assignment has been persisted and updates metrics
+func (ds *DataStore) finishUnassignUnsafe(ipamKey IPAMKey, eni *ENI, availableCidr *CidrInfo, addr *AddressInfo) {
 	addr.UnassignedTime = time.Now()
 
 	//Update prometheus for ips per cidr
@@ -1119,7 +1838,6 @@ func (ds *DataStore) UnassignPodIPAddress(ipamKey IPAMKey) (e *ENI, ip string, d
 		ipamKey, addr.Address, eni.DeviceNumber)
 	// Decrement ENI IP usage when a pod is deallocated
 	prometheusmetrics.EniIPsInUse.WithLabelValues(eni.ID).Dec()
-	return eni, addr.Address, eni.DeviceNumber, nil
 }
 
 // AllocatedIPs returns a recent snapshot of allocated sandbox<->IPs.
@@ -1127,16 +1845,38 @@ func (ds *DataStore) UnassignPodIPAddress(ipamKey IPAMKey) (e *ENI, ip string, d
 func (ds *DataStore) AllocatedIPs() []PodIPInfo {
 	ds.lock.Lock()
 	defer ds.lock.Unlock()
+	return ds.allocatedIPsUnsafe(false)
+}
+
+// AllocatedIPv6s returns a recent snapshot of allocated sandbox<->IPv6 addresses.
+// Note result may already be stale by the time you look at it.
+func (ds *DataStore) AllocatedIPv6s() []PodIPInfo {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+	return ds.allocatedIPsUnsafe(true)
+}
+
+// WithAllocatedIPs calls f with the IPv4 and the IPv6 addresses assigned to pods, while holding the lock, so that none
+// of them is released before f returns. f must not call the data store.
+func (ds *DataStore) WithAllocatedIPs(f func(ipv4s []PodIPInfo, ipv6s []PodIPInfo)) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+	f(ds.allocatedIPsUnsafe(false), ds.allocatedIPsUnsafe(true))
+}
 
+// allocatedIPsUnsafe returns the assigned addresses of the address family
+func (ds *DataStore) allocatedIPsUnsafe(isIPv6 bool) []PodIPInfo {
 	ret := make([]PodIPInfo, 0, ds.eniPool.AssignedIPv4Addresses())
 	for _, eni := range ds.eniPool {
-		for _, assignedaddr := range eni.AvailableIPv4Cidrs {
+		for _, assignedaddr := range eni.cidrs(isIPv6) {
 			for _, addr := range assignedaddr.IPAddresses {
 				if addr.Assigned() {
 					info := PodIPInfo{
 						IPAMKey:      addr.IPAMKey,
+						IPAMMetadata: addr.IPAMMetadata,
 						IP:           addr.Address,
 						DeviceNumber: eni.DeviceNumber,
+						ENIID:        eni.ID,
 					}
 					ret = append(ret, info)
 				}
@@ -1160,7 +1900,7 @@ func (ds *DataStore) FreeableIPs(eniID string) []net.IPNet {
 
 	freeable := make([]net.IPNet, 0, len(eni.AvailableIPv4Cidrs))
 	for _, assignedaddr := range eni.AvailableIPv4Cidrs {
-		if !assignedaddr.IsPrefix && assignedaddr.AssignedIPAddressesInCidr() == 0 {
+		if !assignedaddr.IsPrefix && assignedaddr.AssignedIPAddressesInCidr() == 0 && !assignedaddr.hasReservedIP(ds.stickyIPTTL) {
 			freeable = append(freeable, assignedaddr.Cidr)
 		}
 	}
@@ -1182,7 +1922,7 @@ func (ds *DataStore) FreeablePrefixes(eniID string) []net.IPNet {
 
 	freeable := make([]net.IPNet, 0, len(eni.AvailableIPv4Cidrs))
 	for _, assignedaddr := range eni.AvailableIPv4Cidrs {
-		if assignedaddr.IsPrefix && assignedaddr.AssignedIPAddressesInCidr() == 0 {
+		if assignedaddr.IsPrefix && assignedaddr.AssignedIPAddressesInCidr() == 0 && !assignedaddr.hasReservedIP(ds.stickyIPTTL) {
 			freeable = append(freeable, assignedaddr.Cidr)
 		}
 	}
@@ -1262,13 +2002,16 @@ func (ds *DataStore) GetENICIDRs(eniID string) ([]string, []string, error) {
 	return ipPool, prefixPool, nil
 }
 
-// GetFreePrefixes return free prefixes
-func (ds *DataStore) GetFreePrefixes() int {
+// GetFreePrefixes return free prefixes of the IP pool
+func (ds *DataStore) GetFreePrefixes(ipPool string) int {
 	ds.lock.Lock()
 	defer ds.lock.Unlock()
 
 	freePrefixes := 0
 	for _, other := range ds.eniPool {
+		if other.IPPool != ipPool {
+			continue
+		}
 		for _, otherPrefixes := range other.AvailableIPv4Cidrs {
 			if otherPrefixes.IsPrefix && otherPrefixes.AssignedIPAddressesInCidr() == 0 {
 				freePrefixes++
@@ -1318,7 +2061,7 @@ func (ds *DataStore) getUnusedIP(availableCidr *CidrInfo) (string, error) {
 	//Check if there is any IP out of cooldown
 	var cachedIP string
 	for _, addr := range availableCidr.IPAddresses {
-		if !addr.Assigned() && !addr.inCoolingPeriod(ds.ipCooldownPeriod) {
+		if !addr.Assigned() && !addr.inCoolingPeriod(ds.ipCooldownPeriod) && !addr.isReserved(ds.stickyIPTTL) {
 			//if the IP is out of cooldown and not assigned then cache the first available IP
 			//continue cleaning up the DB, this is to avoid stale entries and a new thread :)
 			if cachedIP == "" {
@@ -1381,7 +2124,7 @@ func (ds *DataStore) FindFreeableCidrs(eniID string) []CidrInfo {
 
 	var freeable []CidrInfo
 	for _, assignedaddr := range eni.AvailableIPv4Cidrs {
-		if assignedaddr.AssignedIPAddressesInCidr() == 0 {
+		if assignedaddr.AssignedIPAddressesInCidr() == 0 && !assignedaddr.hasReservedIP(ds.stickyIPTTL) {
 			tempFreeable := CidrInfo{
 				Cidr:          assignedaddr.Cidr,
 				IPAddresses:   nil,
@@ -1399,13 +2142,17 @@ func DivCeil(x, y int) int {
 	return (x + y - 1) / y
 }
 
-// CheckFreeableENIexists will return true if there is an ENI which is unused.
+// CheckFreeableENIexists will return true if there is an ENI of the IP pool which is unused.
 // Could have just called getDeletaleENI, this is just to optimize a bit.
-func (ds *DataStore) CheckFreeableENIexists() bool {
+func (ds *DataStore) CheckFreeableENIexists(ipPool string) bool {
 	ds.lock.Lock()
 	defer ds.lock.Unlock()
 
 	for _, eni := range ds.eniPool {
+		if eni.IPPool != ipPool {
+			continue
+		}
+
 		if eni.IsPrimary {
 			ds.log.Debugf("ENI %s cannot be deleted because it is primary", eni.ID)
 			continue
@@ -1462,6 +2209,20 @@ func (ds *DataStore) validateAllocationByPodVethExistence(allocation CheckpointE
 	if allocation.Metadata.K8SPodNamespace == "" || allocation.Metadata.K8SPodName == "" {
 		return nil
 	}
+	// the pod of a reservation is gone, its veth as well
+	if allocation.isReservation() {
+		return nil
+	}
+
+	if hostVethName := allocation.Metadata.HostVethName; hostVethName != "" {
+		for _, link := range hostNSLinks {
+			if link.Attrs().Name == hostVethName {
+				return nil
+			}
+		}
+		return errors.Errorf("host-side veth %s not found for pod %v/%v", hostVethName,
+			allocation.Metadata.K8SPodNamespace, allocation.Metadata.K8SPodName)
+	}
 
 	linkNameSuffix := networkutils.GeneratePodHostVethNameSuffix(allocation.Metadata.K8SPodNamespace, allocation.Metadata.K8SPodName)
 	for _, link := range hostNSLinks {
@@ -1485,52 +2246,48 @@ func (ds *DataStore) PruneStaleAllocations(staleAllocations []CheckpointEntry) {
 func (ds *DataStore) DeleteToContainerRule(entry *CheckpointEntry) {
 	ds.log.Infof("Delete toContainer rule for v4: %s, v6: %s", entry.IPv4, entry.IPv6)
 	// Remove toContainer rule, if it exists. Note that toContainer rule will always be in main routing table.
-	toContainerRule := ds.netLink.NewRule()
-	toContainerRule.Priority = networkutils.ToContainerRulePriority
-	toContainerRule.Table = unix.RT_TABLE_MAIN
-	var addr *net.IPNet
-
-	if entry.IPv4 != "" {
-		addr = &net.IPNet{
-			IP:   net.ParseIP(entry.IPv4),
-			Mask: net.CIDRMask(32, 32),
-		}
-	} else {
-		addr = &net.IPNet{
-			IP:   net.ParseIP(entry.IPv6),
-			Mask: net.CIDRMask(128, 128),
+	for _, addr := range entry.podAddrs() {
+		toContainerRule := ds.netLink.NewRule()
+		toContainerRule.Priority = networkutils.ToContainerRulePriority
+		toContainerRule.Table = unix.RT_TABLE_MAIN
+		toContainerRule.Dst = addr
+		if err := ds.netLink.RuleDel(toContainerRule); err != nil && !networkutils.ContainsNoSuchRule(err) {
+			// Continue to prune, even on deletion error
+			ds.log.Errorf("failed to delete toContainer rule, addr=%s", addr.String())
 		}
 	}
-	toContainerRule.Dst = addr
-	if err := ds.netLink.RuleDel(toContainerRule); err != nil && !networkutils.ContainsNoSuchRule(err) {
-		// Continue to prune, even on deletion error
-		ds.log.Errorf("failed to delete toContainer rule, addr=%s", addr.String())
-	}
 }
 
 func (ds *DataStore) DeleteFromContainerRule(entry *CheckpointEntry) {
 	ds.log.Infof("Delete fromContainer rule for v4: %s, v6: %s", entry.IPv4, entry.IPv6)
 	// Remove fromContainer rule, if it exists. Note that fromContainer rule can be in any routing table,
 	// so no table is set.
-	fromContainerRule := ds.netLink.NewRule()
-	fromContainerRule.Priority = networkutils.FromPodRulePriority
-	fromContainerRule.Table = unix.RT_TABLE_UNSPEC
-	var addr *net.IPNet
+	for _, addr := range entry.podAddrs() {
+		fromContainerRule := ds.netLink.NewRule()
+		fromContainerRule.Priority = networkutils.FromPodRulePriority
+		fromContainerRule.Table = unix.RT_TABLE_UNSPEC
+		fromContainerRule.Src = addr
+		if err := ds.netLink.RuleDel(fromContainerRule); err != nil && !networkutils.ContainsNoSuchRule(err) {
+			// Continue to prune, even on deletion error
+			ds.log.Errorf("failed to delete fromPod rule, addr=%s", addr.String())
+		}
+	}
+}
 
+// podAddrs returns the host addresses recorded in the entry. A dual-stack entry has both an IPv4 and an IPv6 address.
+func (entry *CheckpointEntry) podAddrs() []*net.IPNet {
+	var addrs []*net.IPNet
 	if entry.IPv4 != "" {
-		addr = &net.IPNet{
+		addrs = append(addrs, &net.IPNet{
 			IP:   net.ParseIP(entry.IPv4),
 			Mask: net.CIDRMask(32, 32),
-		}
-	} else {
-		addr = &net.IPNet{
+		})
+	}
+	if entry.IPv6 != "" {
+		addrs = append(addrs, &net.IPNet{
 			IP:   net.ParseIP(entry.IPv6),
 			Mask: net.CIDRMask(128, 128),
-		}
-	}
-	fromContainerRule.Src = addr
-	if err := ds.netLink.RuleDel(fromContainerRule); err != nil && !networkutils.ContainsNoSuchRule(err) {
-		// Continue to prune, even on deletion error
-		ds.log.Errorf("failed to delete fromPod rule, addr=%s", addr.String())
+		})
 	}
+	return addrs
 }
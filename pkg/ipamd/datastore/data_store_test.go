@@ -17,6 +17,7 @@ import (
 	"errors"
 	"net"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -546,9 +547,12 @@ func TestPodIPv4Address(t *testing.T) {
 		Version: CheckpointFormatVersion,
 		Allocations: []CheckpointEntry{
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
-				IPv4:     "1.1.1.1",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
+				IPv4:         "1.1.1.1",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPv4ENI:      "eni-1",
+				IPv4Cidr:     "1.1.1.1/32",
+				DeviceNumber: 1,
 			},
 		},
 	}
@@ -585,9 +589,12 @@ func TestPodIPv4Address(t *testing.T) {
 		Version: CheckpointFormatVersion,
 		Allocations: []CheckpointEntry{
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
-				IPv4:     "1.1.1.1",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
+				IPv4:         "1.1.1.1",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPv4ENI:      "eni-1",
+				IPv4Cidr:     "1.1.1.1/32",
+				DeviceNumber: 1,
 			},
 		},
 	}
@@ -609,14 +616,20 @@ func TestPodIPv4Address(t *testing.T) {
 		Version: CheckpointFormatVersion,
 		Allocations: []CheckpointEntry{
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
-				IPv4:     "1.1.1.1",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
+				IPv4:         "1.1.1.1",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPv4ENI:      "eni-1",
+				IPv4Cidr:     "1.1.1.1/32",
+				DeviceNumber: 1,
 			},
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-2", IfName: "eth0"},
-				IPv4:     "1.1.2.2",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-2"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-2", IfName: "eth0"},
+				IPv4:         "1.1.2.2",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-2"},
+				IPv4ENI:      "eni-2",
+				IPv4Cidr:     "1.1.2.2/32",
+				DeviceNumber: 2,
 			},
 		},
 	}
@@ -644,19 +657,28 @@ func TestPodIPv4Address(t *testing.T) {
 		Version: CheckpointFormatVersion,
 		Allocations: []CheckpointEntry{
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
-				IPv4:     "1.1.1.1",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
+				IPv4:         "1.1.1.1",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPv4ENI:      "eni-1",
+				IPv4Cidr:     "1.1.1.1/32",
+				DeviceNumber: 1,
 			},
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-2", IfName: "eth0"},
-				IPv4:     "1.1.2.2",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-2"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-2", IfName: "eth0"},
+				IPv4:         "1.1.2.2",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-2"},
+				IPv4ENI:      "eni-2",
+				IPv4Cidr:     "1.1.2.2/32",
+				DeviceNumber: 2,
 			},
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-3", IfName: "eth0"},
-				IPv4:     "1.1.1.2",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-3"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-3", IfName: "eth0"},
+				IPv4:         "1.1.1.2",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-3"},
+				IPv4ENI:      "eni-1",
+				IPv4Cidr:     "1.1.1.2/32",
+				DeviceNumber: 1,
 			},
 		},
 	}
@@ -670,10 +692,10 @@ func TestPodIPv4Address(t *testing.T) {
 	_, _, err = ds.AssignPodIPv4Address(key4, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-4"})
 	assert.Error(t, err)
 	// Unassign unknown Pod
-	_, _, _, err = ds.UnassignPodIPAddress(key4)
+	_, _, _, _, err = ds.UnassignPodIPAddress(key4)
 	assert.Error(t, err)
 
-	_, _, deviceNum, err := ds.UnassignPodIPAddress(key2)
+	_, _, _, deviceNum, err := ds.UnassignPodIPAddress(key2)
 	assert.NoError(t, err)
 	assert.Equal(t, ds.total, 3)
 	assert.Equal(t, ds.assigned, 2)
@@ -684,14 +706,20 @@ func TestPodIPv4Address(t *testing.T) {
 		Version: CheckpointFormatVersion,
 		Allocations: []CheckpointEntry{
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
-				IPv4:     "1.1.1.1",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
+				IPv4:         "1.1.1.1",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPv4ENI:      "eni-1",
+				IPv4Cidr:     "1.1.1.1/32",
+				DeviceNumber: 1,
 			},
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-3", IfName: "eth0"},
-				IPv4:     "1.1.1.2",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-3"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-3", IfName: "eth0"},
+				IPv4:         "1.1.1.2",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-3"},
+				IPv4ENI:      "eni-1",
+				IPv4Cidr:     "1.1.1.2/32",
+				DeviceNumber: 1,
 			},
 		},
 	}
@@ -705,12 +733,12 @@ func TestPodIPv4Address(t *testing.T) {
 	noWarmPrefixTarget := 0
 
 	// Should not be able to free this ENI
-	eni := ds.RemoveUnusedENIFromStore(noWarmIPTarget, noMinimumIPTarget, noWarmPrefixTarget)
+	eni := ds.RemoveUnusedENIFromStore(DefaultIPPool, noWarmIPTarget, noMinimumIPTarget, noWarmPrefixTarget)
 	assert.True(t, eni == "")
 
 	ds.eniPool["eni-2"].createTime = time.Time{}
 	ds.eniPool["eni-2"].AvailableIPv4Cidrs[ipv4Addr2.String()].IPAddresses["1.1.2.2"].UnassignedTime = time.Time{}
-	eni = ds.RemoveUnusedENIFromStore(noWarmIPTarget, noMinimumIPTarget, noWarmPrefixTarget)
+	eni = ds.RemoveUnusedENIFromStore(DefaultIPPool, noWarmIPTarget, noMinimumIPTarget, noWarmPrefixTarget)
 	assert.Equal(t, eni, "eni-2")
 
 	assert.Equal(t, ds.total, 2)
@@ -751,9 +779,12 @@ func TestPodIPv4AddressWithPDEnabled(t *testing.T) {
 		Version: CheckpointFormatVersion,
 		Allocations: []CheckpointEntry{
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
-				IPv4:     "10.0.0.0",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
+				IPv4:         "10.0.0.0",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPv4ENI:      "eni-1",
+				IPv4Cidr:     "10.0.0.0/28",
+				DeviceNumber: 1,
 			},
 		},
 	}
@@ -784,9 +815,12 @@ func TestPodIPv4AddressWithPDEnabled(t *testing.T) {
 		Version: CheckpointFormatVersion,
 		Allocations: []CheckpointEntry{
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
-				IPv4:     "10.0.0.0",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
+				IPv4:         "10.0.0.0",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPv4ENI:      "eni-1",
+				IPv4Cidr:     "10.0.0.0/28",
+				DeviceNumber: 1,
 			},
 		},
 	}
@@ -808,14 +842,20 @@ func TestPodIPv4AddressWithPDEnabled(t *testing.T) {
 		Version: CheckpointFormatVersion,
 		Allocations: []CheckpointEntry{
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
-				IPv4:     "10.0.0.0",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
+				IPv4:         "10.0.0.0",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPv4ENI:      "eni-1",
+				IPv4Cidr:     "10.0.0.0/28",
+				DeviceNumber: 1,
 			},
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-2", IfName: "eth0"},
-				IPv4:     "10.0.0.1",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-2"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-2", IfName: "eth0"},
+				IPv4:         "10.0.0.1",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-2"},
+				IPv4ENI:      "eni-1",
+				IPv4Cidr:     "10.0.0.0/28",
+				DeviceNumber: 1,
 			},
 		},
 	}
@@ -839,19 +879,28 @@ func TestPodIPv4AddressWithPDEnabled(t *testing.T) {
 		Version: CheckpointFormatVersion,
 		Allocations: []CheckpointEntry{
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
-				IPv4:     "10.0.0.0",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
+				IPv4:         "10.0.0.0",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPv4ENI:      "eni-1",
+				IPv4Cidr:     "10.0.0.0/28",
+				DeviceNumber: 1,
 			},
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-2", IfName: "eth0"},
-				IPv4:     "10.0.0.1",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-2"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-2", IfName: "eth0"},
+				IPv4:         "10.0.0.1",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-2"},
+				IPv4ENI:      "eni-1",
+				IPv4Cidr:     "10.0.0.0/28",
+				DeviceNumber: 1,
 			},
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-3", IfName: "eth0"},
-				IPv4:     "10.0.0.2",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-3"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-3", IfName: "eth0"},
+				IPv4:         "10.0.0.2",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-3"},
+				IPv4ENI:      "eni-1",
+				IPv4Cidr:     "10.0.0.0/28",
+				DeviceNumber: 1,
 			},
 		},
 	}
@@ -860,7 +909,7 @@ func TestPodIPv4AddressWithPDEnabled(t *testing.T) {
 		cmp.Diff(checkpoint.Data, expectedCheckpointData, checkpointDataCmpOpts),
 	)
 
-	_, _, deviceNum, err := ds.UnassignPodIPAddress(key2)
+	_, _, _, deviceNum, err := ds.UnassignPodIPAddress(key2)
 	assert.NoError(t, err)
 	assert.Equal(t, ds.total, 16)
 	assert.Equal(t, ds.assigned, 2)
@@ -871,14 +920,20 @@ func TestPodIPv4AddressWithPDEnabled(t *testing.T) {
 		Version: CheckpointFormatVersion,
 		Allocations: []CheckpointEntry{
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
-				IPv4:     "10.0.0.0",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
+				IPv4:         "10.0.0.0",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"},
+				IPv4ENI:      "eni-1",
+				IPv4Cidr:     "10.0.0.0/28",
+				DeviceNumber: 1,
 			},
 			{
-				IPAMKey:  IPAMKey{NetworkName: "net0", ContainerID: "sandbox-3", IfName: "eth0"},
-				IPv4:     "10.0.0.2",
-				Metadata: IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-3"},
+				IPAMKey:      IPAMKey{NetworkName: "net0", ContainerID: "sandbox-3", IfName: "eth0"},
+				IPv4:         "10.0.0.2",
+				Metadata:     IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-3"},
+				IPv4ENI:      "eni-1",
+				IPv4Cidr:     "10.0.0.0/28",
+				DeviceNumber: 1,
 			},
 		},
 	}
@@ -891,6 +946,366 @@ func TestPodIPv4AddressWithPDEnabled(t *testing.T) {
 	assert.Equal(t, ds.assigned, 2)
 }
 
+func TestPodDualStackAddress(t *testing.T) {
+	checkpoint := NewTestCheckpoint(struct{}{})
+	ds := NewDataStore(Testlog, checkpoint, true)
+
+	checkpointDataCmpOpts := cmp.Options{
+		cmpopts.IgnoreFields(CheckpointEntry{}, "AllocationTimestamp"),
+	}
+
+	// IPv6 prefix on the primary ENI, IPv4 prefix on a secondary ENI
+	setupENIs := func(ds *DataStore) {
+		assert.NoError(t, ds.AddENI("eni-1", 0, true, false, false))
+		assert.NoError(t, ds.AddENI("eni-2", 2, false, false, false))
+		ipv6Prefix := net.IPNet{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(80, 128)}
+		assert.NoError(t, ds.AddIPv6CidrToStore("eni-1", ipv6Prefix, true))
+		ipv4Prefix := net.IPNet{IP: net.ParseIP("10.0.0.0"), Mask: net.IPv4Mask(255, 255, 255, 240)}
+		assert.NoError(t, ds.AddIPv4CidrToStore("eni-2", ipv4Prefix, true))
+	}
+	setupENIs(ds)
+
+	key1 := IPAMKey{"net0", "sandbox-1", "eth0"}
+	metadata1 := IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"}
+	ipv4, ipv6, deviceNum, err := ds.AssignPodIPAddress(key1, metadata1, true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.0", ipv4)
+	assert.NotEmpty(t, ipv6)
+	assert.Equal(t, 2, deviceNum)
+	assert.Equal(t, 2, ds.assigned)
+
+	// Both addresses are checkpointed in a single entry
+	expectedCheckpointData := &CheckpointData{
+		Version: CheckpointFormatVersion,
+		Allocations: []CheckpointEntry{
+			{
+				IPAMKey:      key1,
+				IPv4:         ipv4,
+				IPv6:         ipv6,
+				Metadata:     metadata1,
+				IPv4ENI:      "eni-2",
+				IPv4Cidr:     "10.0.0.0/28",
+				IPv6ENI:      "eni-1",
+				IPv6Cidr:     "2001:db8::/80",
+				DeviceNumber: 2,
+			},
+		},
+	}
+	assert.True(t,
+		cmp.Equal(checkpoint.Data, expectedCheckpointData, checkpointDataCmpOpts),
+		cmp.Diff(checkpoint.Data, expectedCheckpointData, checkpointDataCmpOpts),
+	)
+
+	// duplicate add
+	dupIPv4, dupIPv6, _, err := ds.AssignPodIPAddress(key1, metadata1, true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, ipv4, dupIPv4)
+	assert.Equal(t, ipv6, dupIPv6)
+	assert.Equal(t, 2, ds.assigned)
+
+	// Checkpoint error unwinds both assignments
+	checkpoint.Error = errors.New("fake checkpoint error")
+	key2 := IPAMKey{"net0", "sandbox-2", "eth0"}
+	_, _, _, err = ds.AssignPodIPAddress(key2, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-2"}, true, true)
+	assert.Error(t, err)
+	assert.Equal(t, 2, ds.assigned)
+	_, _, addr := ds.eniPool.FindAddressForSandbox(key2)
+	assert.Nil(t, addr)
+	checkpoint.Error = nil
+
+	// Restoring the checkpoint recovers both addresses
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	netLink := mock_netlinkwrapper.NewMockNetLink(ctrl)
+	netLink.EXPECT().LinkList().Return([]netlink.Link{
+		&netlink.Veth{
+			LinkAttrs: netlink.LinkAttrs{
+				Name: "eni" + networkutils.GeneratePodHostVethNameSuffix("default", "sample-pod-1"),
+			},
+		},
+	}, nil)
+	restored := NewDataStore(Testlog, NewTestCheckpoint(checkpoint.Data), true)
+	restored.netLink = netLink
+	setupENIs(restored)
+	assert.NoError(t, restored.ReadBackingStore(true, true))
+	assert.Equal(t, 2, restored.assigned)
+	_, _, restoredIPv4 := restored.eniPool.FindIPv4AddressForSandbox(key1)
+	_, _, restoredIPv6 := restored.eniPool.FindIPv6AddressForSandbox(key1)
+	assert.Equal(t, ipv4, restoredIPv4.Address)
+	assert.Equal(t, ipv6, restoredIPv6.Address)
+
+	// Checkpoint error on release keeps both addresses
+	checkpoint.Error = errors.New("fake checkpoint error")
+	_, _, _, _, err = ds.UnassignPodIPAddress(key1)
+	assert.Error(t, err)
+	assert.Equal(t, 2, ds.assigned)
+	checkpoint.Error = nil
+
+	eni, releasedIPv4, releasedIPv6, deviceNum, err := ds.UnassignPodIPAddress(key1)
+	assert.NoError(t, err)
+	assert.Equal(t, "eni-2", eni.ID)
+	assert.Equal(t, ipv4, releasedIPv4)
+	assert.Equal(t, ipv6, releasedIPv6)
+	assert.Equal(t, 2, deviceNum)
+	assert.Equal(t, 0, ds.assigned)
+	assert.Empty(t, checkpoint.Data.(*CheckpointData).Allocations)
+
+	// No IPv6 prefix available, the IPv4 assignment is unwound
+	v4only := NewDataStore(Testlog, NullCheckpoint{}, true)
+	assert.NoError(t, v4only.AddENI("eni-1", 0, true, false, false))
+	ipv4Prefix := net.IPNet{IP: net.ParseIP("10.0.0.0"), Mask: net.IPv4Mask(255, 255, 255, 240)}
+	assert.NoError(t, v4only.AddIPv4CidrToStore("eni-1", ipv4Prefix, true))
+	_, _, _, err = v4only.AssignPodIPAddress(key1, metadata1, true, true)
+	assert.Error(t, err)
+	assert.Equal(t, 0, v4only.assigned)
+}
+
+func TestReadBackingStoreMigrateV1(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	netLink := mock_netlinkwrapper.NewMockNetLink(ctrl)
+	netLink.EXPECT().LinkList().Return([]netlink.Link{}, nil)
+
+	key1 := IPAMKey{"net0", "sandbox-1", "eth0"}
+	key2 := IPAMKey{"net0", "sandbox-2", "eth0"}
+	checkpoint := NewTestCheckpoint(CheckpointData{
+		Version: checkpointFormatVersionV1,
+		Allocations: []CheckpointEntry{
+			{IPAMKey: key1, IPv4: "1.1.1.1", AllocationTimestamp: 1},
+			{IPAMKey: key2, IPv4: "10.0.0.2", AllocationTimestamp: 2},
+		},
+	})
+	ds := NewDataStore(Testlog, checkpoint, false)
+	ds.netLink = netLink
+	assert.NoError(t, ds.AddENI("eni-1", 0, true, false, false))
+	assert.NoError(t, ds.AddENI("eni-2", 1, false, false, false))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP("1.1.1.1"), Mask: net.CIDRMask(32, 32)}, false))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-2", net.IPNet{IP: net.ParseIP("10.0.0.0"), Mask: net.CIDRMask(28, 32)}, true))
+
+	assert.NoError(t, ds.ReadBackingStore(true, false))
+	assert.Equal(t, 2, ds.assigned)
+
+	// The ENI details of the migrated entries are filled in from the ENI pool
+	expectedCheckpointData := &CheckpointData{
+		Version: CheckpointFormatVersion,
+		Allocations: []CheckpointEntry{
+			{IPAMKey: key1, IPv4: "1.1.1.1", AllocationTimestamp: 1, IPv4ENI: "eni-1", IPv4Cidr: "1.1.1.1/32"},
+			{IPAMKey: key2, IPv4: "10.0.0.2", AllocationTimestamp: 2, IPv4ENI: "eni-2", IPv4Cidr: "10.0.0.0/28", DeviceNumber: 1},
+		},
+	}
+	checkpointDataCmpOpts := cmpopts.SortSlices(func(lhs CheckpointEntry, rhs CheckpointEntry) bool {
+		return lhs.ContainerID < rhs.ContainerID
+	})
+	assert.True(t,
+		cmp.Equal(checkpoint.Data, expectedCheckpointData, checkpointDataCmpOpts),
+		cmp.Diff(checkpoint.Data, expectedCheckpointData, checkpointDataCmpOpts),
+	)
+}
+
+func TestReadBackingStoreUnknownVersion(t *testing.T) {
+	data := CheckpointData{
+		Version: "vpc-cni-ipam/3",
+		Allocations: []CheckpointEntry{
+			{IPAMKey: IPAMKey{"net0", "sandbox-1", "eth0"}, IPv4: "1.1.1.1"},
+		},
+	}
+	checkpoint := NewTestCheckpoint(data)
+	ds := NewDataStore(Testlog, checkpoint, false)
+	assert.NoError(t, ds.AddENI("eni-1", 0, true, false, false))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP("1.1.1.1"), Mask: net.CIDRMask(32, 32)}, false))
+
+	err := ds.ReadBackingStore(true, false)
+	assert.EqualError(t, err, "failed ipam state recovery due to unexpected checkpointVersion: vpc-cni-ipam/3/"+CheckpointFormatVersion)
+	assert.Equal(t, 0, ds.assigned)
+	// The checkpoint of the newer version is left alone
+	assert.Equal(t, data, checkpoint.Data)
+}
+
+func TestStaleAllocations(t *testing.T) {
+	ds := NewDataStore(Testlog, NullCheckpoint{}, false)
+	assert.NoError(t, ds.AddENI("eni-1", 1, true, false, false))
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"} {
+		assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP(ip), Mask: net.IPv4Mask(255, 255, 255, 255)}, false))
+	}
+	valid := IPAMKey{"net0", "sandbox-valid", "eth0"}
+	stale := IPAMKey{"net0", "sandbox-stale", "eth0"}
+	otherNetwork := IPAMKey{"net1", "sandbox-other", "eth0"}
+	for _, ipamKey := range []IPAMKey{valid, stale, otherNetwork} {
+		_, _, err := ds.AssignPodIPv4Address(ipamKey, IPAMMetadata{})
+		assert.NoError(t, err)
+	}
+	// The extra interface of the valid sandbox is unknown to the container runtime
+	_, err := ds.AssignPodIPv4AddressOnDevice(IPAMKey{"net0", "sandbox-valid", "net1"}, IPAMMetadata{}, 1)
+	assert.NoError(t, err)
+	branch := IPAMKey{"net0", "sandbox-branch", "eth0"}
+	assert.NoError(t, ds.AssignPodENIAddress(branch, IPAMMetadata{}, "10.0.1.5", "", "eni-branch", 7))
+
+	validContainerIDs := map[string]bool{valid.ContainerID: true}
+	assert.ElementsMatch(t, []IPAMKey{stale, branch}, ds.StaleAllocations("net0", validContainerIDs, time.Now().Add(time.Second)))
+	// Allocations made after assignedBefore are not stale yet
+	assert.Empty(t, ds.StaleAllocations("net0", validContainerIDs, time.Now().Add(-time.Minute)))
+}
+
+func TestAllocations(t *testing.T) {
+	ds := NewDataStore(Testlog, NullCheckpoint{}, false)
+	assert.NoError(t, ds.AddENI("eni-1", 1, true, false, false))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP("10.0.0.1"), Mask: net.IPv4Mask(255, 255, 255, 255)}, false))
+	assert.Empty(t, ds.Allocations())
+
+	pod := IPAMKey{"net0", "sandbox-1", "eth0"}
+	metadata := IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"}
+	_, _, err := ds.AssignPodIPv4Address(pod, metadata)
+	assert.NoError(t, err)
+	branch := IPAMKey{"net0", "sandbox-branch", "eth0"}
+	assert.NoError(t, ds.AssignPodENIAddress(branch, IPAMMetadata{}, "10.0.1.5", "", "eni-branch", 7))
+
+	expected := []CheckpointEntry{
+		{IPAMKey: pod, IPv4: "10.0.0.1", Metadata: metadata, IPv4ENI: "eni-1", IPv4Cidr: "10.0.0.1/32", DeviceNumber: 1},
+		{IPAMKey: branch, IPv4: "10.0.1.5", IPv4ENI: "eni-branch", DeviceNumber: -1, VlanID: 7},
+	}
+	allocationCmpOpts := []cmp.Option{
+		cmpopts.IgnoreFields(CheckpointEntry{}, "AllocationTimestamp"),
+		cmpopts.SortSlices(func(a, b CheckpointEntry) bool { return a.ContainerID < b.ContainerID }),
+	}
+	allocations := ds.Allocations()
+	assert.True(t, cmp.Equal(allocations, expected, allocationCmpOpts...), cmp.Diff(allocations, expected, allocationCmpOpts...))
+}
+
+func TestJournaledAllocations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.json")
+	journal := NewJournalFile(path, DefaultJournalCompactThreshold)
+	defer journal.Close()
+	ds := NewDataStore(Testlog, journal, false)
+	ds.stickyIPTTL = time.Hour
+	assert.NoError(t, ds.AddENI("eni-1", 1, true, false, false))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP("10.0.0.0"), Mask: net.IPv4Mask(255, 255, 255, 252)}, false))
+
+	// The first mutation writes the whole state, each further one appends a single record
+	pod1 := IPAMKey{"net0", "sandbox-1", "eth0"}
+	_, _, err := ds.AssignPodIPv4Address(pod1, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, journalLines(t, path))
+	pod2 := IPAMKey{"net0", "sandbox-2", "eth0"}
+	_, _, err = ds.AssignPodIPv4Address(pod2, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-2", StickyIP: true})
+	assert.NoError(t, err)
+	branch := IPAMKey{"net0", "sandbox-branch", "eth0"}
+	assert.NoError(t, ds.AssignPodENIAddress(branch, IPAMMetadata{}, "10.0.1.5", "", "eni-branch", 7))
+	_, _, _, _, err = ds.UnassignPodIPAddress(pod1)
+	assert.NoError(t, err)
+	// The address of the sticky IP pod stays reserved under the pod identity
+	_, _, _, _, err = ds.UnassignPodIPAddress(pod2)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, journalLines(t, path))
+
+	allocationCmpOpts := []cmp.Option{
+		cmpopts.SortSlices(func(a, b CheckpointEntry) bool { return a.ContainerID < b.ContainerID }),
+	}
+	data, err := ReadCheckpoint(path)
+	assert.NoError(t, err)
+	allocations := ds.Allocations()
+	assert.Len(t, allocations, 2)
+	assert.True(t, cmp.Equal(data.Allocations, allocations, allocationCmpOpts...), cmp.Diff(data.Allocations, allocations, allocationCmpOpts...))
+}
+
+func TestAssignPodIPv4AddressOnDevice(t *testing.T) {
+	ds := NewDataStore(Testlog, NullCheckpoint{}, false)
+	assert.NoError(t, ds.AddENI("eni-1", 0, true, false, false))
+	assert.NoError(t, ds.AddENIToPool("eni-2", 1, false, false, false, "pci"))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP("10.0.0.1"), Mask: net.IPv4Mask(255, 255, 255, 255)}, false))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-2", net.IPNet{IP: net.ParseIP("10.1.0.1"), Mask: net.IPv4Mask(255, 255, 255, 255)}, false))
+
+	primary := IPAMKey{"net0", "sandbox-1", "eth0"}
+	extra := IPAMKey{"net0", "sandbox-1", "net1"}
+	// The ENI of the device must belong to the IP pool of the pod
+	_, err := ds.AssignPodIPv4AddressOnDevice(extra, IPAMMetadata{}, 1)
+	assert.ErrorContains(t, err, `device 1 belongs to IP pool "pci"`)
+	ip, err := ds.AssignPodIPv4AddressOnDevice(extra, IPAMMetadata{IPPool: "pci"}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.0.1", ip)
+	// Assigning the same interface again returns its address
+	ip, err = ds.AssignPodIPv4AddressOnDevice(extra, IPAMMetadata{IPPool: "pci"}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.0.1", ip)
+	_, err = ds.AssignPodIPv4AddressOnDevice(extra, IPAMMetadata{IPPool: "pci"}, 0)
+	assert.Error(t, err)
+	_, err = ds.AssignPodIPv4AddressOnDevice(primary, IPAMMetadata{}, 1)
+	assert.Error(t, err)
+	ip, err = ds.AssignPodIPv4AddressOnDevice(primary, IPAMMetadata{}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", ip)
+
+	assert.ElementsMatch(t, []IPAMKey{primary, extra}, ds.SandboxInterfaces("net0", "sandbox-1"))
+	assert.Empty(t, ds.SandboxInterfaces("net1", "sandbox-1"))
+}
+
+func TestPodENIAddress(t *testing.T) {
+	checkpoint := NewTestCheckpoint(struct{}{})
+	ds := NewDataStore(Testlog, checkpoint, false)
+	assert.NoError(t, ds.AddENI("eni-1", 0, true, true, false))
+
+	key1 := IPAMKey{"net0", "sandbox-1", "eth0"}
+	metadata1 := IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "sample-pod-1", HostVethName: "vlanb5faff8a083"}
+	assert.Error(t, ds.AssignPodENIAddress(key1, metadata1, "10.0.1.5", "", "eni-branch", 0))
+	assert.NoError(t, ds.AssignPodENIAddress(key1, metadata1, "10.0.1.5", "", "eni-branch", 7))
+	// Branch ENI addresses are not part of the pool
+	assert.Equal(t, 0, ds.assigned)
+
+	expectedCheckpointData := &CheckpointData{
+		Version: CheckpointFormatVersion,
+		Allocations: []CheckpointEntry{
+			{
+				IPAMKey:      key1,
+				IPv4:         "10.0.1.5",
+				Metadata:     metadata1,
+				IPv4ENI:      "eni-branch",
+				DeviceNumber: -1,
+				VlanID:       7,
+			},
+		},
+	}
+	checkpointDataCmpOpts := cmpopts.IgnoreFields(CheckpointEntry{}, "AllocationTimestamp")
+	assert.True(t,
+		cmp.Equal(checkpoint.Data, expectedCheckpointData, checkpointDataCmpOpts),
+		cmp.Diff(checkpoint.Data, expectedCheckpointData, checkpointDataCmpOpts),
+	)
+
+	// duplicate add
+	assert.NoError(t, ds.AssignPodENIAddress(key1, metadata1, "10.0.1.5", "", "eni-branch", 7))
+
+	// Checkpoint error unwinds the assignment
+	checkpoint.Error = errors.New("fake checkpoint error")
+	key2 := IPAMKey{"net0", "sandbox-2", "eth0"}
+	assert.Error(t, ds.AssignPodENIAddress(key2, IPAMMetadata{}, "10.0.1.6", "", "eni-branch-2", 8))
+	_, err := ds.UnassignPodENIAddress(key1)
+	assert.Error(t, err)
+	checkpoint.Error = nil
+	_, err = ds.UnassignPodENIAddress(key2)
+	assert.Equal(t, ErrUnknownPod, err)
+
+	// Restoring the checkpoint recovers the branch ENI pod
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	netLink := mock_netlinkwrapper.NewMockNetLink(ctrl)
+	netLink.EXPECT().LinkList().Return([]netlink.Link{
+		&netlink.Vlan{LinkAttrs: netlink.LinkAttrs{Name: "vlanb5faff8a083"}},
+	}, nil)
+	restored := NewDataStore(Testlog, NewTestCheckpoint(checkpoint.Data), false)
+	restored.netLink = netLink
+	assert.NoError(t, restored.AddENI("eni-1", 0, true, true, false))
+	assert.NoError(t, restored.ReadBackingStore(true, false))
+	entry, err := restored.UnassignPodENIAddress(key1)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.1.5", entry.IPv4)
+	assert.Equal(t, 7, entry.VlanID)
+
+	entry, err = ds.UnassignPodENIAddress(key1)
+	assert.NoError(t, err)
+	assert.Equal(t, "eni-branch", entry.IPv4ENI)
+	_, err = ds.UnassignPodENIAddress(key1)
+	assert.Equal(t, ErrUnknownPod, err)
+	assert.Empty(t, checkpoint.Data.(*CheckpointData).Allocations)
+}
+
 func TestGetIPStatsV4(t *testing.T) {
 	os.Setenv(envIPCooldownPeriod, "1")
 	defer os.Unsetenv(envIPCooldownPeriod)
@@ -919,7 +1334,7 @@ func TestGetIPStatsV4(t *testing.T) {
 		*ds.GetIPStats("4"),
 	)
 
-	_, _, _, err = ds.UnassignPodIPAddress(key2)
+	_, _, _, _, err = ds.UnassignPodIPAddress(key2)
 	assert.NoError(t, err)
 
 	assert.Equal(t,
@@ -972,7 +1387,7 @@ func TestGetIPStatsV4WithPD(t *testing.T) {
 		*ds.GetIPStats("4"),
 	)
 
-	_, _, _, err = ds.UnassignPodIPAddress(key2)
+	_, _, _, _, err = ds.UnassignPodIPAddress(key2)
 	assert.NoError(t, err)
 
 	assert.Equal(t,
@@ -1060,15 +1475,15 @@ func TestWarmENIInteractions(t *testing.T) {
 	// We should not be able to remove any ENIs if either warmIPTarget >= 3 or minimumWarmIPTarget >= 5
 
 	// WARM IP TARGET=3, MINIMUM_IP_TARGET=1 => no ENI should be removed
-	eni := ds.RemoveUnusedENIFromStore(3, 1, 0)
+	eni := ds.RemoveUnusedENIFromStore(DefaultIPPool, 3, 1, 0)
 	assert.Equal(t, "", eni)
 
 	// WARM IP TARGET=1, MINIMUM_IP_TARGET=5 => no ENI should be removed
-	eni = ds.RemoveUnusedENIFromStore(1, 5, 0)
+	eni = ds.RemoveUnusedENIFromStore(DefaultIPPool, 1, 5, 0)
 	assert.Equal(t, "", eni)
 
 	// WARM IP TARGET=2, MINIMUM_IP_TARGET=4 => ENI 3 should be removed as we only need 2 free IPs, which ENI 2 has
-	removedEni := ds.RemoveUnusedENIFromStore(2, 4, 0)
+	removedEni := ds.RemoveUnusedENIFromStore(DefaultIPPool, 2, 4, 0)
 	assert.Equal(t, "eni-3", removedEni)
 
 	// We have 2 ENIs, 4 IPs and 2 pods on ENI 1.
@@ -1077,11 +1492,11 @@ func TestWarmENIInteractions(t *testing.T) {
 	// => 2 free IPs
 
 	// WARM IP TARGET=0, MINIMUM_IP_TARGET=3 => no ENI should be removed
-	eni = ds.RemoveUnusedENIFromStore(0, 3, 0)
+	eni = ds.RemoveUnusedENIFromStore(DefaultIPPool, 0, 3, 0)
 	assert.Equal(t, "", eni)
 
 	// WARM IP TARGET=0, MINIMUM_IP_TARGET=2 => ENI 2 should be removed as ENI 1 covers the requirements
-	removedEni = ds.RemoveUnusedENIFromStore(0, 2, 0)
+	removedEni = ds.RemoveUnusedENIFromStore(DefaultIPPool, 0, 2, 0)
 	assert.Contains(t, "eni-2", removedEni)
 
 	// Add 2 more ENIs to the datastore and add 1 IP address to each of them
@@ -1101,7 +1516,7 @@ func TestWarmENIInteractions(t *testing.T) {
 	// => 2 free IPs
 
 	// WARM IP TARGET=0, MINIMUM_IP_TARGET=2 => no ENI can be removed because ENI 4 is a trunk ENI and ENI 5 is an EFA ENI
-	removedEni = ds.RemoveUnusedENIFromStore(0, 2, 0)
+	removedEni = ds.RemoveUnusedENIFromStore(DefaultIPPool, 0, 2, 0)
 	assert.Equal(t, "", removedEni)
 	assert.Equal(t, 3, ds.GetENIs())
 
@@ -1117,11 +1532,53 @@ func TestWarmENIInteractions(t *testing.T) {
 	// => 2 free IPs
 
 	// WARM IP TARGET=0, MINIMUM_IP_TARGET=2 => ENI 6 can be removed
-	removedEni = ds.RemoveUnusedENIFromStore(0, 2, 0)
+	removedEni = ds.RemoveUnusedENIFromStore(DefaultIPPool, 0, 2, 0)
 	assert.Equal(t, "eni-6", removedEni)
 	assert.Equal(t, 3, ds.GetENIs())
 }
 
+func TestIPPools(t *testing.T) {
+	ds := NewDataStore(Testlog, NullCheckpoint{}, false)
+
+	_ = ds.AddENI("eni-1", 0, true, false, false)
+	_ = ds.AddENIToPool("eni-2", 1, false, false, false, "pci")
+	_ = ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP("1.1.1.1"), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	_ = ds.AddIPv4CidrToStore("eni-2", net.IPNet{IP: net.ParseIP("1.1.2.1"), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	_ = ds.AddIPv4CidrToStore("eni-2", net.IPNet{IP: net.ParseIP("1.1.2.2"), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+
+	// Pods get addresses from the ENIs of their own pool only
+	ip, deviceNumber, err := ds.AssignPodIPv4Address(IPAMKey{"net0", "sandbox-1", "eth0"}, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "pod-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.1.1", ip)
+	assert.Equal(t, 0, deviceNumber)
+
+	_, _, err = ds.AssignPodIPv4Address(IPAMKey{"net0", "sandbox-2", "eth0"}, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "pod-2"})
+	assert.Error(t, err)
+
+	ip, deviceNumber, err = ds.AssignPodIPv4Address(IPAMKey{"net0", "sandbox-3", "eth0"}, IPAMMetadata{K8SPodNamespace: "pci", K8SPodName: "pod-3", IPPool: "pci"})
+	assert.NoError(t, err)
+	assert.Contains(t, []string{"1.1.2.1", "1.1.2.2"}, ip)
+	assert.Equal(t, 1, deviceNumber)
+
+	_, _, err = ds.AssignPodIPv4Address(IPAMKey{"net0", "sandbox-4", "eth0"}, IPAMMetadata{K8SPodNamespace: "other", K8SPodName: "pod-4", IPPool: "other"})
+	assert.Error(t, err)
+
+	assert.Equal(t, DataStoreStats{TotalIPs: 1, AssignedIPs: 1}, *ds.GetPoolIPStats(DefaultIPPool, "4"))
+	assert.Equal(t, DataStoreStats{TotalIPs: 2, AssignedIPs: 1, IPPool: "pci"}, *ds.GetPoolIPStats("pci", "4"))
+	assert.Equal(t, DataStoreStats{TotalIPs: 3, AssignedIPs: 2}, *ds.GetIPStats("4"))
+
+	// The ENIs of a pool are only removed to honor the warm targets of that pool
+	_ = ds.AddENIToPool("eni-3", 2, false, false, false, "pci")
+	_ = ds.AddIPv4CidrToStore("eni-3", net.IPNet{IP: net.ParseIP("1.1.3.1"), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	ds.eniPool["eni-3"].createTime = time.Time{}
+
+	assert.Equal(t, "", ds.RemoveUnusedENIFromStore(DefaultIPPool, 0, 0, 0))
+	assert.Equal(t, "", ds.RemoveUnusedENIFromStore("pci", 2, 0, 0))
+	assert.True(t, ds.CheckFreeableENIexists("pci"))
+	assert.Equal(t, "eni-3", ds.RemoveUnusedENIFromStore("pci", 1, 0, 0))
+	assert.False(t, ds.CheckFreeableENIexists("pci"))
+}
+
 func TestDataStore_normalizeCheckpointDataByPodVethExistence(t *testing.T) {
 	containerAddr := &net.IPNet{
 		IP:   net.ParseIP("192.168.1.1"),
@@ -1505,6 +1962,58 @@ func TestDataStore_validateAllocationByPodVethExistence(t *testing.T) {
 			},
 			wantErr: errors.New("host-side veth not found for pod kube-system/coredns-57ff979f67-qqbdh"),
 		},
+		{
+			name: "recorded host veth found",
+			args: args{
+				allocation: CheckpointEntry{
+					IPAMKey: IPAMKey{
+						ContainerID: "5a1f9118a7125f87b4b0f2f601c0b55cfab8bcf28963bcf7c4ece3109a8b6b86",
+						NetworkName: "nholuongut-cni",
+						IfName:      "eth0",
+					},
+					IPv4: "192.168.9.106",
+					Metadata: IPAMMetadata{
+						K8SPodNamespace: "kube-system",
+						K8SPodName:      "coredns-57ff979f67-qqbdh",
+						HostVethName:    "vlanb5faff8a083",
+					},
+				},
+				hostNSLinks: []netlink.Link{
+					&netlink.Vlan{
+						LinkAttrs: netlink.LinkAttrs{
+							Name: "vlanb5faff8a083",
+						},
+					},
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "recorded host veth not found",
+			args: args{
+				allocation: CheckpointEntry{
+					IPAMKey: IPAMKey{
+						ContainerID: "5a1f9118a7125f87b4b0f2f601c0b55cfab8bcf28963bcf7c4ece3109a8b6b86",
+						NetworkName: "nholuongut-cni",
+						IfName:      "eth0",
+					},
+					IPv4: "192.168.9.106",
+					Metadata: IPAMMetadata{
+						K8SPodNamespace: "kube-system",
+						K8SPodName:      "coredns-57ff979f67-qqbdh",
+						HostVethName:    "vlanb5faff8a083",
+					},
+				},
+				hostNSLinks: []netlink.Link{
+					&netlink.Veth{
+						LinkAttrs: netlink.LinkAttrs{
+							Name: "enib5faff8a083",
+						},
+					},
+				},
+			},
+			wantErr: errors.New("host-side veth vlanb5faff8a083 not found for pod kube-system/coredns-57ff979f67-qqbdh"),
+		},
 		{
 			name: "allocation without metadata should pass validation",
 			args: args{
@@ -1615,3 +2124,55 @@ func TestForceRemovalMetrics(t *testing.T) {
 	eniCount = testutil.ToFloat64(prometheusmetrics.ForceRemovedENIs)
 	assert.Equal(t, float64(1), eniCount)
 }
+
+func TestAssignPodStaticIPv4Address(t *testing.T) {
+	ds := NewDataStore(Testlog, NullCheckpoint{}, false)
+	assert.NoError(t, ds.AddENI("eni-1", 1, false, false, false))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP("10.0.0.1"), Mask: net.IPv4Mask(255, 255, 255, 255)}, false))
+	_, prefix, _ := net.ParseCIDR("10.0.1.0/28")
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", *prefix, true))
+
+	key1 := IPAMKey{"net0", "sandbox-1", "eth0"}
+	ip, deviceNumber, err := ds.AssignPodStaticIPv4Address(key1, IPAMMetadata{}, net.IPNet{IP: net.ParseIP("10.0.0.1").To4(), Mask: net.CIDRMask(32, 32)})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", ip)
+	assert.Equal(t, 1, deviceNumber)
+
+	// Duplicate assign returns the same address
+	ip, _, err = ds.AssignPodStaticIPv4Address(key1, IPAMMetadata{}, net.IPNet{IP: net.ParseIP("10.0.0.1").To4(), Mask: net.CIDRMask(32, 32)})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", ip)
+
+	// The address is already in use
+	_, _, err = ds.AssignPodStaticIPv4Address(IPAMKey{"net0", "sandbox-2", "eth0"}, IPAMMetadata{}, net.IPNet{IP: net.ParseIP("10.0.0.1").To4(), Mask: net.CIDRMask(32, 32)})
+	assert.EqualError(t, err, "requested IP 10.0.0.1 is already in use by sandbox net0/sandbox-1/eth0")
+
+	// An address of a prefix can be requested, even in secondary IP mode
+	ip, _, err = ds.AssignPodStaticIPv4Address(IPAMKey{"net0", "sandbox-3", "eth0"}, IPAMMetadata{}, net.IPNet{IP: net.ParseIP("10.0.1.5").To4(), Mask: net.CIDRMask(32, 32)})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.1.5", ip)
+
+	// As well as any free address of the prefix
+	ip, _, err = ds.AssignPodStaticIPv4Address(IPAMKey{"net0", "sandbox-4", "eth0"}, IPAMMetadata{}, *prefix)
+	assert.NoError(t, err)
+	assert.True(t, prefix.Contains(net.ParseIP(ip)))
+	assert.NotEqual(t, "10.0.1.5", ip)
+	assert.Equal(t, 3, ds.assigned)
+
+	// Unknown addresses are left to the caller to allocate
+	_, _, err = ds.AssignPodStaticIPv4Address(IPAMKey{"net0", "sandbox-5", "eth0"}, IPAMMetadata{}, net.IPNet{IP: net.ParseIP("10.0.2.1").To4(), Mask: net.CIDRMask(32, 32)})
+	assert.Equal(t, ErrUnknownStaticCidr, err)
+	_, unknownPrefix, _ := net.ParseCIDR("10.0.1.16/28")
+	_, _, err = ds.AssignPodStaticIPv4Address(IPAMKey{"net0", "sandbox-5", "eth0"}, IPAMMetadata{}, *unknownPrefix)
+	assert.Equal(t, ErrUnknownStaticCidr, err)
+
+	// The address must belong to an ENI of the IP pool of the pod
+	assert.NoError(t, ds.AddENIToPool("eni-2", 2, false, false, false, "pci"))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-2", net.IPNet{IP: net.ParseIP("10.1.0.1"), Mask: net.IPv4Mask(255, 255, 255, 255)}, false))
+	_, _, err = ds.AssignPodStaticIPv4Address(IPAMKey{"net0", "sandbox-6", "eth0"}, IPAMMetadata{}, net.IPNet{IP: net.ParseIP("10.1.0.1").To4(), Mask: net.CIDRMask(32, 32)})
+	assert.EqualError(t, err, `requested IP/Prefix 10.1.0.1/32 belongs to IP pool "pci", not ""`)
+	ip, deviceNumber, err = ds.AssignPodStaticIPv4Address(IPAMKey{"net0", "sandbox-6", "eth0"}, IPAMMetadata{IPPool: "pci"}, net.IPNet{IP: net.ParseIP("10.1.0.1").To4(), Mask: net.CIDRMask(32, 32)})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.0.1", ip)
+	assert.Equal(t, 2, deviceNumber)
+}
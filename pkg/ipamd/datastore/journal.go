@@ -0,0 +1,389 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datastore
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultJournalCompactThreshold is the number of journal records after which the journal is compacted
+// into the snapshot
+const DefaultJournalCompactThreshold = 1000
+
+// journalSuffix is appended to the snapshot path to get the path of the journal
+const journalSuffix = ".journal"
+
+// journalRecord is a single line of the journal. It holds every change made by one checkpoint, so that
+// a checkpoint is either replayed completely or not at all.
+type journalRecord struct {
+	Put    []CheckpointEntry `json:"put,omitempty"`
+	Delete []IPAMKey         `json:"delete,omitempty"`
+}
+
+// JournalFile is a checkpointer that appends the changes between two checkpoints of CheckpointData to a
+// journal, instead of rewriting the whole state every time. The journal is periodically compacted into a
+// snapshot which has the same format as a JSONFile, so that both backends can read each other's state.
+//
+// Every record is fsynced before Checkpoint returns. A crash can only leave a torn last record behind,
+// which belongs to a checkpoint that never succeeded and is ignored on Restore.
+type JournalFile struct {
+	snapshot         *JSONFile
+	journalPath      string
+	compactThreshold int
+
+	journal     *os.File
+	journalSize int64
+	records     int
+
+	// State of the last successful checkpoint, nil until the snapshot is known to be up to date
+	version string
+	entries map[IPAMKey]CheckpointEntry
+}
+
+// NewJournalFile creates a new JournalFile, with the snapshot stored at path
+func NewJournalFile(path string, compactThreshold int) *JournalFile {
+	if compactThreshold <= 0 {
+		compactThreshold = DefaultJournalCompactThreshold
+	}
+	return &JournalFile{
+		snapshot:         NewJSONFile(path),
+		journalPath:      path + journalSuffix,
+		compactThreshold: compactThreshold,
+	}
+}
+
+// Checkpoint implements the Checkpointer interface
+func (c *JournalFile) Checkpoint(data interface{}) error {
+	cp, ok := asCheckpointData(data)
+	if !ok {
+		// Only CheckpointData can be journaled, anything else is written as a full snapshot
+		if err := c.snapshot.Checkpoint(data); err != nil {
+			return err
+		}
+		c.entries = nil
+		return c.truncateJournal()
+	}
+	if c.entries == nil || cp.Version != c.version || c.records >= c.compactThreshold {
+		return c.compact(cp)
+	}
+
+	entries := entriesByKey(cp.Allocations)
+	var record journalRecord
+	for key, entry := range entries {
+		if old, found := c.entries[key]; !found || old != entry {
+			record.Put = append(record.Put, entry)
+		}
+	}
+	for key := range c.entries {
+		if _, found := entries[key]; !found {
+			record.Delete = append(record.Delete, key)
+		}
+	}
+	if len(record.Put) == 0 && len(record.Delete) == 0 {
+		return nil
+	}
+
+	if err := c.appendRecord(record); err != nil {
+		return err
+	}
+	c.entries = entries
+	c.records++
+	return nil
+}
+
+// Journal implements the Journaler interface. The change is appended to the journal as a single record, unless the
+// journal is due for compaction.
+func (c *JournalFile) Journal(put []CheckpointEntry, del []IPAMKey, data func() *CheckpointData) error {
+	if c.entries == nil || c.version != CheckpointFormatVersion || c.records >= c.compactThreshold {
+		return c.compact(data())
+	}
+
+	var record journalRecord
+	for _, key := range del {
+		if _, found := c.entries[key]; found {
+			record.Delete = append(record.Delete, key)
+		}
+	}
+	for _, entry := range put {
+		if old, found := c.entries[entry.IPAMKey]; !found || old != entry {
+			record.Put = append(record.Put, entry)
+		}
+	}
+	if len(record.Put) == 0 && len(record.Delete) == 0 {
+		return nil
+	}
+
+	if err := c.appendRecord(record); err != nil {
+		return err
+	}
+	// Same order as replayJournal
+	for _, key := range record.Delete {
+		delete(c.entries, key)
+	}
+	for _, entry := range record.Put {
+		c.entries[entry.IPAMKey] = entry
+	}
+	c.records++
+	return nil
+}
+
+// Restore implements the Checkpointer interface. The journal is replayed on top of the snapshot and then
+// compacted into it. The snapshot is left untouched when there is nothing to replay, or when it was written in
+// a format version this build does not know, so that a newer ipamd can still read it after a rollback.
+func (c *JournalFile) Restore(into interface{}) error {
+	var data CheckpointData
+	if err := c.snapshot.Restore(&data); err != nil {
+		if os.IsNotExist(err) {
+			// A journal without its snapshot cannot be replayed, so start afresh
+			c.entries = nil
+			if rmErr := os.Remove(c.journalPath); rmErr != nil && !os.IsNotExist(rmErr) {
+				return rmErr
+			}
+		}
+		return err
+	}
+
+	records, err := replayJournal(&data, c.journalPath)
+	if err != nil {
+		return err
+	}
+
+	if records > 0 && isKnownCheckpointVersion(data.Version) {
+		if err := c.compact(&data); err != nil {
+			return errors.Wrap(err, "failed to compact journal")
+		}
+	} else {
+		// The journal may still hold a torn record, so the next checkpoint has to compact
+		c.entries = nil
+	}
+
+	// Round trip through json, like the JSONFile does, as `into` is not necessarily a *CheckpointData
+	buf, err := json.Marshal(&data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, into)
+}
+
+// Close closes the journal
+func (c *JournalFile) Close() error {
+	if c.journal == nil {
+		return nil
+	}
+	err := c.journal.Close()
+	c.journal = nil
+	return err
+}
+
+// compact writes the whole state to the snapshot and empties the journal
+func (c *JournalFile) compact(data *CheckpointData) error {
+	if err := c.snapshot.Checkpoint(data); err != nil {
+		return err
+	}
+	// Once the snapshot is renamed into place, replaying the old journal on top of it is harmless, so a
+	// crash before the journal is truncated does not lose or resurrect anything.
+	c.entries = nil
+	if err := c.truncateJournal(); err != nil {
+		return err
+	}
+	c.version = data.Version
+	c.entries = entriesByKey(data.Allocations)
+	return nil
+}
+
+func (c *JournalFile) openJournal() error {
+	if c.journal != nil {
+		return nil
+	}
+	f, err := os.OpenFile(c.journalPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	c.journal = f
+	c.journalSize = info.Size()
+	return nil
+}
+
+func (c *JournalFile) truncateJournal() error {
+	if err := c.openJournal(); err != nil {
+		return err
+	}
+	if err := c.journal.Truncate(0); err != nil {
+		return err
+	}
+	if err := c.journal.Sync(); err != nil {
+		return err
+	}
+	c.journalSize = 0
+	c.records = 0
+	return nil
+}
+
+func (c *JournalFile) appendRecord(record journalRecord) error {
+	if err := c.openJournal(); err != nil {
+		return err
+	}
+	buf, err := json.Marshal(&record)
+	if err != nil {
+		return err
+	}
+	buf = append(buf, '\n')
+
+	n, err := c.journal.Write(buf)
+	if err == nil {
+		err = c.journal.Sync()
+	}
+	if err != nil {
+		// Drop whatever part of the record made it to the journal, so that the next record does not
+		// follow a torn one. If that fails too, the next checkpoint rewrites the snapshot instead.
+		if n > 0 {
+			if truncErr := c.journal.Truncate(c.journalSize); truncErr != nil {
+				c.entries = nil
+			}
+		}
+		return err
+	}
+	c.journalSize += int64(n)
+	return nil
+}
+
+// readJournal returns the records of the journal at path. A torn last record is ignored.
+func readJournal(path string) ([]journalRecord, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	lines := bytes.Split(buf, []byte{'\n'})
+	// Every record ends with a newline, so the last element is either empty or a record which was not
+	// completely written before a crash
+	lines = lines[:len(lines)-1]
+
+	records := make([]journalRecord, 0, len(lines))
+	for i, line := range lines {
+		var record journalRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, errors.Wrapf(err, "corrupted journal %s at line %d", path, i+1)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// replayJournal applies the records of the journal at path to data, and returns the number of records
+func replayJournal(data *CheckpointData, path string) (int, error) {
+	records, err := readJournal(path)
+	if err != nil {
+		return 0, err
+	}
+	entries := entriesByKey(data.Allocations)
+	for _, record := range records {
+		for _, key := range record.Delete {
+			delete(entries, key)
+		}
+		for _, entry := range record.Put {
+			entries[entry.IPAMKey] = entry
+		}
+	}
+	data.Allocations = sortedEntries(entries)
+	return len(records), nil
+}
+
+// ReadCheckpoint returns the state stored at path by either a JSONFile or a JournalFile, with the journal replayed
+// on top of the snapshot. Unlike Restore, it never writes, so that it is safe to call while ipamd is running.
+func ReadCheckpoint(path string) (*CheckpointData, error) {
+	var data CheckpointData
+	if err := NewJSONFile(path).Restore(&data); err != nil {
+		return nil, err
+	}
+	if _, err := replayJournal(&data, path+journalSuffix); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// CompactJournal folds a journal left behind by a JournalFile into its snapshot at path, and removes it.
+// It is a no-op when there is no journal.
+func CompactJournal(path string) error {
+	if _, err := os.Stat(path + journalSuffix); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	journal := NewJournalFile(path, DefaultJournalCompactThreshold)
+	var data CheckpointData
+	if err := journal.Restore(&data); err != nil && !os.IsNotExist(err) {
+		journal.Close()
+		return err
+	}
+	if err := journal.Close(); err != nil {
+		return err
+	}
+	if data.Version != "" && !isKnownCheckpointVersion(data.Version) {
+		return errors.Errorf("cannot compact journal of unknown checkpointVersion %s", data.Version)
+	}
+	if err := os.Remove(path + journalSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func isKnownCheckpointVersion(version string) bool {
+	return version == CheckpointFormatVersion || version == checkpointFormatVersionV1
+}
+
+func asCheckpointData(data interface{}) (*CheckpointData, bool) {
+	switch cp := data.(type) {
+	case *CheckpointData:
+		return cp, true
+	case CheckpointData:
+		return &cp, true
+	}
+	return nil, false
+}
+
+func entriesByKey(allocations []CheckpointEntry) map[IPAMKey]CheckpointEntry {
+	entries := make(map[IPAMKey]CheckpointEntry, len(allocations))
+	for _, entry := range allocations {
+		entries[entry.IPAMKey] = entry
+	}
+	return entries
+}
+
+func sortedEntries(entries map[IPAMKey]CheckpointEntry) []CheckpointEntry {
+	allocations := make([]CheckpointEntry, 0, len(entries))
+	for _, entry := range entries {
+		allocations = append(allocations, entry)
+	}
+	sort.Slice(allocations, func(i, j int) bool {
+		return allocations[i].AllocationTimestamp < allocations[j].AllocationTimestamp ||
+			(allocations[i].AllocationTimestamp == allocations[j].AllocationTimestamp &&
+				allocations[i].IPAMKey.String() < allocations[j].IPAMKey.String())
+	})
+	return allocations
+}
@@ -0,0 +1,284 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datastore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func journalTestEntry(containerID string, ipv4 string, timestamp int64) CheckpointEntry {
+	return CheckpointEntry{
+		IPAMKey:             IPAMKey{NetworkName: "net0", ContainerID: containerID, IfName: "eth0"},
+		IPv4:                ipv4,
+		AllocationTimestamp: timestamp,
+		Metadata:            IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "pod-" + containerID},
+	}
+}
+
+func journalTestData(entries ...CheckpointEntry) *CheckpointData {
+	return &CheckpointData{Version: CheckpointFormatVersion, Allocations: entries}
+}
+
+func journalLines(t *testing.T, path string) int {
+	records, err := readJournal(path + journalSuffix)
+	assert.NoError(t, err)
+	return len(records)
+}
+
+func TestJournalFileCheckpointRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.json")
+	a := journalTestEntry("a", "10.0.0.1", 1)
+	b := journalTestEntry("b", "10.0.0.2", 2)
+	c := journalTestEntry("c", "10.0.0.3", 3)
+
+	journal := NewJournalFile(path, DefaultJournalCompactThreshold)
+	// The first checkpoint writes a full snapshot
+	assert.NoError(t, journal.Checkpoint(journalTestData(a)))
+	assert.Equal(t, 0, journalLines(t, path))
+
+	// Further checkpoints only append the changes
+	assert.NoError(t, journal.Checkpoint(journalTestData(a, b)))
+	assert.NoError(t, journal.Checkpoint(journalTestData(a, b, c)))
+	assert.NoError(t, journal.Checkpoint(journalTestData(b, c)))
+	// Unchanged state appends nothing
+	assert.NoError(t, journal.Checkpoint(journalTestData(b, c)))
+	assert.Equal(t, 3, journalLines(t, path))
+	assert.NoError(t, journal.Close())
+
+	// The snapshot alone is stale
+	var snapshot CheckpointData
+	assert.NoError(t, NewJSONFile(path).Restore(&snapshot))
+	assert.Equal(t, []CheckpointEntry{a}, snapshot.Allocations)
+
+	// Restore replays the journal and compacts it into the snapshot
+	restored := NewJournalFile(path, DefaultJournalCompactThreshold)
+	var data CheckpointData
+	assert.NoError(t, restored.Restore(&data))
+	assert.Equal(t, CheckpointFormatVersion, data.Version)
+	assert.Equal(t, []CheckpointEntry{b, c}, data.Allocations)
+	assert.Equal(t, 0, journalLines(t, path))
+
+	snapshot = CheckpointData{}
+	assert.NoError(t, NewJSONFile(path).Restore(&snapshot))
+	assert.Equal(t, []CheckpointEntry{b, c}, snapshot.Allocations)
+
+	// Journaling continues from the restored state
+	assert.NoError(t, restored.Checkpoint(journalTestData(c)))
+	assert.Equal(t, 1, journalLines(t, path))
+	assert.NoError(t, restored.Close())
+}
+
+func TestJournalFileJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.json")
+	a := journalTestEntry("a", "10.0.0.1", 1)
+	b := journalTestEntry("b", "10.0.0.2", 2)
+	c := journalTestEntry("c", "10.0.0.3", 3)
+
+	built := 0
+	state := func(entries ...CheckpointEntry) func() *CheckpointData {
+		return func() *CheckpointData {
+			built++
+			return journalTestData(entries...)
+		}
+	}
+
+	journal := NewJournalFile(path, DefaultJournalCompactThreshold)
+	// Nothing is known about the snapshot yet, so the whole state is written
+	assert.NoError(t, journal.Journal([]CheckpointEntry{a}, nil, state(a)))
+	assert.Equal(t, 1, built)
+	assert.Equal(t, 0, journalLines(t, path))
+
+	// Each change is appended as a single record, without building the whole state
+	assert.NoError(t, journal.Journal([]CheckpointEntry{b}, nil, state(a, b)))
+	assert.NoError(t, journal.Journal([]CheckpointEntry{c}, []IPAMKey{a.IPAMKey}, state(b, c)))
+	assert.Equal(t, 1, built)
+	assert.Equal(t, 2, journalLines(t, path))
+
+	// Unchanged entries and unknown keys append nothing
+	assert.NoError(t, journal.Journal([]CheckpointEntry{b}, []IPAMKey{a.IPAMKey}, state(b, c)))
+	assert.Equal(t, 2, journalLines(t, path))
+	assert.NoError(t, journal.Close())
+
+	restored := NewJournalFile(path, DefaultJournalCompactThreshold)
+	var data CheckpointData
+	assert.NoError(t, restored.Restore(&data))
+	assert.Equal(t, []CheckpointEntry{b, c}, data.Allocations)
+	assert.NoError(t, restored.Close())
+}
+
+func TestJournalFileJournalCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.json")
+	a := journalTestEntry("a", "10.0.0.1", 1)
+	b := journalTestEntry("b", "10.0.0.2", 2)
+	c := journalTestEntry("c", "10.0.0.3", 3)
+
+	journal := NewJournalFile(path, 2)
+	assert.NoError(t, journal.Journal(nil, nil, func() *CheckpointData { return journalTestData() }))
+	assert.NoError(t, journal.Journal([]CheckpointEntry{a}, nil, func() *CheckpointData { return journalTestData(a) }))
+	assert.NoError(t, journal.Journal([]CheckpointEntry{b}, nil, func() *CheckpointData { return journalTestData(a, b) }))
+	assert.Equal(t, 2, journalLines(t, path))
+
+	// The threshold is reached, so the whole state is written to the snapshot
+	assert.NoError(t, journal.Journal([]CheckpointEntry{c}, nil, func() *CheckpointData { return journalTestData(a, b, c) }))
+	assert.Equal(t, 0, journalLines(t, path))
+	var snapshot CheckpointData
+	assert.NoError(t, NewJSONFile(path).Restore(&snapshot))
+	assert.Equal(t, []CheckpointEntry{a, b, c}, snapshot.Allocations)
+	assert.NoError(t, journal.Close())
+}
+
+func TestJournalFileRestoreJSONFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.json")
+	a := journalTestEntry("a", "10.0.0.1", 1)
+	assert.NoError(t, NewJSONFile(path).Checkpoint(journalTestData(a)))
+
+	journal := NewJournalFile(path, DefaultJournalCompactThreshold)
+	var data CheckpointData
+	assert.NoError(t, journal.Restore(&data))
+	assert.Equal(t, *journalTestData(a), data)
+	assert.NoError(t, journal.Close())
+}
+
+func TestJournalFileRestoreNotExist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.json")
+	// A journal without a snapshot is discarded
+	assert.NoError(t, os.WriteFile(path+journalSuffix, []byte("{}\n"), 0600))
+
+	journal := NewJournalFile(path, DefaultJournalCompactThreshold)
+	var data CheckpointData
+	err := journal.Restore(&data)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(path + journalSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestJournalFileTornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.json")
+	a := journalTestEntry("a", "10.0.0.1", 1)
+	b := journalTestEntry("b", "10.0.0.2", 2)
+
+	journal := NewJournalFile(path, DefaultJournalCompactThreshold)
+	assert.NoError(t, journal.Checkpoint(journalTestData(a)))
+	assert.NoError(t, journal.Checkpoint(journalTestData(a, b)))
+	assert.NoError(t, journal.Close())
+
+	// Crash in the middle of appending the next record
+	f, err := os.OpenFile(path+journalSuffix, os.O_WRONLY|os.O_APPEND, 0600)
+	assert.NoError(t, err)
+	_, err = f.WriteString(`{"delete":[{"networkName":"net0","contai`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	var data CheckpointData
+	restored := NewJournalFile(path, DefaultJournalCompactThreshold)
+	assert.NoError(t, restored.Restore(&data))
+	assert.Equal(t, []CheckpointEntry{a, b}, data.Allocations)
+	assert.NoError(t, restored.Close())
+}
+
+func TestJournalFileCorruptedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.json")
+	a := journalTestEntry("a", "10.0.0.1", 1)
+	assert.NoError(t, NewJSONFile(path).Checkpoint(journalTestData(a)))
+	assert.NoError(t, os.WriteFile(path+journalSuffix, []byte("garbage\n{}\n"), 0600))
+
+	var data CheckpointData
+	err := NewJournalFile(path, DefaultJournalCompactThreshold).Restore(&data)
+	assert.Error(t, err)
+}
+
+func TestJournalFileCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.json")
+	a := journalTestEntry("a", "10.0.0.1", 1)
+	b := journalTestEntry("b", "10.0.0.2", 2)
+	c := journalTestEntry("c", "10.0.0.3", 3)
+
+	journal := NewJournalFile(path, 2)
+	assert.NoError(t, journal.Checkpoint(journalTestData()))
+	assert.NoError(t, journal.Checkpoint(journalTestData(a)))
+	assert.NoError(t, journal.Checkpoint(journalTestData(a, b)))
+	assert.Equal(t, 2, journalLines(t, path))
+
+	// The threshold is reached, so the next checkpoint is written to the snapshot
+	assert.NoError(t, journal.Checkpoint(journalTestData(a, b, c)))
+	assert.Equal(t, 0, journalLines(t, path))
+	var snapshot CheckpointData
+	assert.NoError(t, NewJSONFile(path).Restore(&snapshot))
+	assert.Equal(t, []CheckpointEntry{a, b, c}, snapshot.Allocations)
+	assert.NoError(t, journal.Close())
+}
+
+func TestCompactJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.json")
+	// No journal, nothing to do
+	assert.NoError(t, CompactJournal(path))
+
+	a := journalTestEntry("a", "10.0.0.1", 1)
+	b := journalTestEntry("b", "10.0.0.2", 2)
+	journal := NewJournalFile(path, DefaultJournalCompactThreshold)
+	assert.NoError(t, journal.Checkpoint(journalTestData(a)))
+	assert.NoError(t, journal.Checkpoint(journalTestData(a, b)))
+	assert.NoError(t, journal.Close())
+
+	assert.NoError(t, CompactJournal(path))
+	_, err := os.Stat(path + journalSuffix)
+	assert.True(t, os.IsNotExist(err))
+	var data CheckpointData
+	assert.NoError(t, NewJSONFile(path).Restore(&data))
+	assert.Equal(t, []CheckpointEntry{a, b}, data.Allocations)
+}
+
+func TestReadCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.json")
+	_, err := ReadCheckpoint(path)
+	assert.True(t, os.IsNotExist(err))
+
+	a := journalTestEntry("a", "10.0.0.1", 1)
+	b := journalTestEntry("b", "10.0.0.2", 2)
+	journal := NewJournalFile(path, DefaultJournalCompactThreshold)
+	assert.NoError(t, journal.Checkpoint(journalTestData(a)))
+	assert.NoError(t, journal.Checkpoint(journalTestData(a, b)))
+	assert.NoError(t, journal.Checkpoint(journalTestData(b)))
+
+	// The journal is replayed, but left in place
+	data, err := ReadCheckpoint(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []CheckpointEntry{b}, data.Allocations)
+	assert.Equal(t, 2, journalLines(t, path))
+	assert.NoError(t, journal.Close())
+}
+
+func TestJournalFileRestoreUnknownVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.json")
+	snapshot := []byte(`{"version":"vpc-cni-ipam/3","allocations":[],"future":true}`)
+	assert.NoError(t, os.WriteFile(path, snapshot, 0600))
+	assert.NoError(t, os.WriteFile(path+journalSuffix, []byte(`{"delete":[{"networkName":"net0"}]}`+"\n"), 0600))
+
+	// The snapshot of a newer version is neither compacted nor rewritten
+	var data CheckpointData
+	journal := NewJournalFile(path, DefaultJournalCompactThreshold)
+	assert.NoError(t, journal.Restore(&data))
+	assert.Equal(t, "vpc-cni-ipam/3", data.Version)
+	assert.NoError(t, journal.Close())
+	assert.Error(t, CompactJournal(path))
+
+	buf, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot, buf)
+	assert.Equal(t, 1, journalLines(t, path))
+}
@@ -0,0 +1,155 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datastore
+
+import (
+	"net"
+	"time"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/utils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/utils/prometheusmetrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// envStickyIPTTL (default 300 seconds) specifies how long the address of a sticky IP pod is held for its
+	// namespace/name after the pod is deleted
+	envStickyIPTTL = "STICKY_IP_TTL"
+
+	defaultStickyIPTTL = 300 * time.Second
+)
+
+// Checkpoint entries of reservations are keyed by the pod identity rather than by a sandbox, which is gone
+const stickyIPNetworkName = "_sticky-ip"
+
+// getStickyIPTTL returns the time duration in seconds configured by the STICKY_IP_TTL env variable
+func getStickyIPTTL() time.Duration {
+	ttlVal, err, _ := utils.GetIntFromStringEnvVar(envStickyIPTTL, int(defaultStickyIPTTL/time.Second))
+	if err != nil || ttlVal < 0 {
+		return defaultStickyIPTTL
+	}
+	return time.Duration(ttlVal) * time.Second
+}
+
+// isReserved checks whether an unassigned addr is still held for the sticky IP pod which released it.
+// The pod identity is kept in IPAMMetadata when the address is unassigned.
+func (addr AddressInfo) isReserved(stickyIPTTL time.Duration) bool {
+	return !addr.Assigned() && addr.IPAMMetadata.StickyIP && time.Since(addr.UnassignedTime) <= stickyIPTTL
+}
+
+// isReservedFor checks whether addr is held for the pod identified by ipamMetadata. The address is not handed back
+// if the pod moved to another IP pool.
+func (addr AddressInfo) isReservedFor(ipamMetadata IPAMMetadata, stickyIPTTL time.Duration) bool {
+	return addr.isReserved(stickyIPTTL) && ipamMetadata.K8SPodNamespace != "" && ipamMetadata.K8SPodName != "" &&
+		addr.IPAMMetadata.K8SPodNamespace == ipamMetadata.K8SPodNamespace &&
+		addr.IPAMMetadata.K8SPodName == ipamMetadata.K8SPodName &&
+		addr.IPAMMetadata.IPPool == ipamMetadata.IPPool
+}
+
+// hasReservedIP returns true if one of the addresses of the CIDR is held for a sticky IP pod
+func (cidr *CidrInfo) hasReservedIP(stickyIPTTL time.Duration) bool {
+	for _, addr := range cidr.IPAddresses {
+		if addr.isReserved(stickyIPTTL) {
+			return true
+		}
+	}
+	return false
+}
+
+// reservationKey is the key of the checkpoint entry of a reservation
+func reservationKey(ipamMetadata IPAMMetadata) IPAMKey {
+	return IPAMKey{
+		NetworkName: stickyIPNetworkName,
+		ContainerID: ipamMetadata.K8SPodNamespace + "/" + ipamMetadata.K8SPodName,
+	}
+}
+
+// findReservedIPAddressUnsafe returns the IPv4 or IPv6 address held for the pod identity of ipamMetadata, along
+// with its ENI and CIDR. It returns (nil, nil, nil) if there is no such reservation.
+func (ds *DataStore) findReservedIPAddressUnsafe(ipamMetadata IPAMMetadata, isIPv6 bool) (*ENI, *CidrInfo, *AddressInfo) {
+	for _, eni := range ds.eniPool {
+		for _, cidr := range eni.cidrs(isIPv6) {
+			// The address may belong to a prefix while PD was since disabled, or the other way around
+			if !isIPv6 && cidr.IsPrefix != ds.isPDEnabled {
+				continue
+			}
+			for _, addr := range cidr.IPAddresses {
+				if addr.isReservedFor(ipamMetadata, ds.stickyIPTTL) {
+					return eni, cidr, addr
+				}
+			}
+		}
+	}
+	return nil, nil, nil
+}
+
+// reservationUnsafe returns a copy of the address held for the pod identity of ipamMetadata, nil if there is none.
+// The copy lets an assignment which could not be persisted hold the address again.
+func (ds *DataStore) reservationUnsafe(ipamMetadata IPAMMetadata, isIPv6 bool) *AddressInfo {
+	_, _, addr := ds.findReservedIPAddressUnsafe(ipamMetadata, isIPv6)
+	if addr == nil {
+		return nil
+	}
+	reservation := *addr
+	return &reservation
+}
+
+// assignReservedIPAddressUnsafe hands the IPv4 or IPv6 address held for the pod identity of ipamMetadata back to
+// the sandbox. It returns (nil, nil, nil) if there is no such reservation.
+func (ds *DataStore) assignReservedIPAddressUnsafe(ipamKey IPAMKey, ipamMetadata IPAMMetadata, isIPv6 bool) (*ENI, *CidrInfo, *AddressInfo) {
+	eni, cidr, addr := ds.findReservedIPAddressUnsafe(ipamMetadata, isIPv6)
+	if addr == nil {
+		return nil, nil, nil
+	}
+	ds.log.Infof("Reusing %s held for pod %s/%s since %v", addr.Address,
+		ipamMetadata.K8SPodNamespace, ipamMetadata.K8SPodName, addr.UnassignedTime)
+	if !isIPv6 {
+		prometheusmetrics.IpsPerCidr.With(prometheus.Labels{"cidr": cidr.Cidr.String()}).Inc()
+	}
+	ds.assignPodIPAddressUnsafe(addr, ipamKey, ipamMetadata, time.Now())
+	return eni, cidr, addr
+}
+
+// restoreReservationUnsafe holds the checkpointed address of a sticky IP pod again, unless it expired meanwhile
+func (ds *DataStore) restoreReservationUnsafe(allocation CheckpointEntry, ipAddr net.IP, isIPv6 bool) {
+	unassignedTime := time.Unix(0, allocation.ReleaseTimestamp)
+	if time.Since(unassignedTime) > ds.stickyIPTTL {
+		ds.log.Debugf("Dropping expired reservation of %s for pod %s/%s", ipAddr,
+			allocation.Metadata.K8SPodNamespace, allocation.Metadata.K8SPodName)
+		return
+	}
+	for _, eni := range ds.eniPool {
+		for _, cidr := range eni.cidrs(isIPv6) {
+			if !cidr.Cidr.Contains(ipAddr) {
+				continue
+			}
+			if _, ok := cidr.IPAddresses[ipAddr.String()]; ok {
+				ds.log.Warnf("Dropping reservation of %s for pod %s/%s, the address is already in use", ipAddr,
+					allocation.Metadata.K8SPodNamespace, allocation.Metadata.K8SPodName)
+				return
+			}
+			cidr.IPAddresses[ipAddr.String()] = &AddressInfo{
+				Address:        ipAddr.String(),
+				IPAMMetadata:   allocation.Metadata,
+				AssignedTime:   time.Unix(0, allocation.AllocationTimestamp),
+				UnassignedTime: unassignedTime,
+			}
+			ds.log.Debugf("Recovered reservation of %s/%s for pod %s/%s", eni.ID, ipAddr,
+				allocation.Metadata.K8SPodNamespace, allocation.Metadata.K8SPodName)
+			return
+		}
+	}
+	ds.log.Infof("datastore: Reservation of unknown IP Address %s for pod %s/%s - presuming stale", ipAddr,
+		allocation.Metadata.K8SPodNamespace, allocation.Metadata.K8SPodName)
+}
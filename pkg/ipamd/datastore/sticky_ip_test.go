@@ -0,0 +1,152 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datastore
+
+import (
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	mock_netlinkwrapper "github.com/nholuongut/amazon-vpc-cni-k8s/pkg/netlinkwrapper/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+)
+
+func TestGetStickyIPTTL(t *testing.T) {
+	defer os.Unsetenv(envStickyIPTTL)
+
+	os.Unsetenv(envStickyIPTTL)
+	assert.Equal(t, defaultStickyIPTTL, getStickyIPTTL())
+
+	os.Setenv(envStickyIPTTL, "60")
+	assert.Equal(t, 60*time.Second, getStickyIPTTL())
+
+	os.Setenv(envStickyIPTTL, "-1")
+	assert.Equal(t, defaultStickyIPTTL, getStickyIPTTL())
+}
+
+// stickyIPTestDataStore returns a data store with two secondary IPs, without cooldown
+func stickyIPTestDataStore(t *testing.T, checkpoint Checkpointer) *DataStore {
+	ds := NewDataStore(Testlog, checkpoint, false)
+	ds.ipCooldownPeriod = 0
+	ds.stickyIPTTL = time.Minute
+	assert.NoError(t, ds.AddENI("eni-1", 0, true, false, false))
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP(ip), Mask: net.IPv4Mask(255, 255, 255, 255)}, false))
+	}
+	return ds
+}
+
+func TestStickyIPReservation(t *testing.T) {
+	checkpoint := NewTestCheckpoint(struct{}{})
+	ds := stickyIPTestDataStore(t, checkpoint)
+
+	sticky := IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "web-0", StickyIP: true}
+	stickyIP, _, err := ds.AssignPodIPv4Address(IPAMKey{"net0", "sandbox-1", "eth0"}, sticky)
+	assert.NoError(t, err)
+	_, _, _, _, err = ds.UnassignPodIPAddress(IPAMKey{"net0", "sandbox-1", "eth0"})
+	assert.NoError(t, err)
+
+	// The address is held and checkpointed under the pod identity
+	assert.Equal(t, 1, ds.GetIPStats("4").ReservedIPs)
+	allocations := checkpoint.Data.(*CheckpointData).Allocations
+	assert.Len(t, allocations, 1)
+	assert.Equal(t, IPAMKey{NetworkName: stickyIPNetworkName, ContainerID: "default/web-0"}, allocations[0].IPAMKey)
+	assert.Equal(t, stickyIP, allocations[0].IPv4)
+	assert.Equal(t, sticky, allocations[0].Metadata)
+	assert.NotZero(t, allocations[0].ReleaseTimestamp)
+	freeable := ds.FreeableIPs("eni-1")
+	assert.Len(t, freeable, 1)
+	assert.NotEqual(t, stickyIP, freeable[0].IP.String())
+
+	// Other pods don't get it
+	otherIP, _, err := ds.AssignPodIPv4Address(IPAMKey{"net0", "sandbox-2", "eth0"}, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "web-1"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, stickyIP, otherIP)
+	_, _, err = ds.AssignPodIPv4Address(IPAMKey{"net0", "sandbox-3", "eth0"}, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "web-2"})
+	assert.Error(t, err)
+	_, _, _, _, err = ds.UnassignPodIPAddress(IPAMKey{"net0", "sandbox-2", "eth0"})
+	assert.NoError(t, err)
+
+	// The reservation survives a restart and the recreated pod gets its address back
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	netLink := mock_netlinkwrapper.NewMockNetLink(ctrl)
+	netLink.EXPECT().LinkList().Return([]netlink.Link{}, nil)
+	restored := stickyIPTestDataStore(t, NewTestCheckpoint(checkpoint.Data))
+	restored.netLink = netLink
+	assert.NoError(t, restored.ReadBackingStore(true, false))
+	assert.Equal(t, 1, restored.GetIPStats("4").ReservedIPs)
+	ip, _, err := restored.AssignPodIPv4Address(IPAMKey{"net0", "sandbox-4", "eth0"}, sticky)
+	assert.NoError(t, err)
+	assert.Equal(t, stickyIP, ip)
+	assert.Equal(t, 0, restored.GetIPStats("4").ReservedIPs)
+}
+
+func TestStickyIPReservationExpiry(t *testing.T) {
+	checkpoint := NewTestCheckpoint(struct{}{})
+	ds := stickyIPTestDataStore(t, checkpoint)
+
+	sticky := IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "web-0", StickyIP: true}
+	stickyIP, _, err := ds.AssignPodIPv4Address(IPAMKey{"net0", "sandbox-1", "eth0"}, sticky)
+	assert.NoError(t, err)
+	_, _, _, _, err = ds.UnassignPodIPAddress(IPAMKey{"net0", "sandbox-1", "eth0"})
+	assert.NoError(t, err)
+
+	ds.eniPool["eni-1"].AvailableIPv4Cidrs[stickyIP+"/32"].IPAddresses[stickyIP].UnassignedTime = time.Now().Add(-2 * time.Minute)
+	assert.Equal(t, 0, ds.GetIPStats("4").ReservedIPs)
+	assert.Len(t, ds.FreeableIPs("eni-1"), 2)
+
+	// Once expired, the address is free for any pod and is no longer checkpointed
+	for _, name := range []string{"web-1", "web-2"} {
+		_, _, err = ds.AssignPodIPv4Address(IPAMKey{"net0", name, "eth0"}, IPAMMetadata{K8SPodNamespace: "default", K8SPodName: name})
+		assert.NoError(t, err)
+	}
+	for _, allocation := range checkpoint.Data.(*CheckpointData).Allocations {
+		assert.False(t, allocation.isReservation())
+	}
+}
+
+func TestStickyIPReservationUnwind(t *testing.T) {
+	checkpoint := NewTestCheckpoint(struct{}{})
+	ds := stickyIPTestDataStore(t, checkpoint)
+
+	sticky := IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "web-0", StickyIP: true}
+	stickyIP, _, err := ds.AssignPodIPv4Address(IPAMKey{"net0", "sandbox-1", "eth0"}, sticky)
+	assert.NoError(t, err)
+	_, _, _, _, err = ds.UnassignPodIPAddress(IPAMKey{"net0", "sandbox-1", "eth0"})
+	assert.NoError(t, err)
+	addresses := ds.eniPool["eni-1"].AvailableIPv4Cidrs[stickyIP+"/32"].IPAddresses
+	reservation := *addresses[stickyIP]
+
+	// The reservation is held again as it was when the assignment can't be checkpointed
+	checkpoint.Error = errors.New("fake checkpoint error")
+	_, _, err = ds.AssignPodIPv4Address(IPAMKey{"net0", "sandbox-2", "eth0"}, sticky)
+	assert.Error(t, err)
+	assert.Equal(t, reservation, *addresses[stickyIP])
+	_, _, err = ds.AssignPodStaticIPv4Address(IPAMKey{"net0", "sandbox-2", "eth0"}, sticky,
+		net.IPNet{IP: net.ParseIP(stickyIP), Mask: net.IPv4Mask(255, 255, 255, 255)})
+	assert.Error(t, err)
+	assert.Equal(t, reservation, *addresses[stickyIP])
+	assert.Equal(t, 0, ds.GetIPStats("4").AssignedIPs)
+	assert.Equal(t, 1, ds.GetIPStats("4").ReservedIPs)
+
+	checkpoint.Error = nil
+	ip, _, err := ds.AssignPodIPv4Address(IPAMKey{"net0", "sandbox-2", "eth0"}, sticky)
+	assert.NoError(t, err)
+	assert.Equal(t, stickyIP, ip)
+}
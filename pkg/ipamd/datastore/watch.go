@@ -0,0 +1,154 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datastore
+
+import (
+	"time"
+)
+
+// AllocationEventType is the kind of change of the data store an AllocationEvent describes
+type AllocationEventType string
+
+const (
+	// AllocationAssigned is sent when an address is assigned to a pod
+	AllocationAssigned AllocationEventType = "assign"
+	// AllocationUnassigned is sent when an address is released by a pod
+	AllocationUnassigned AllocationEventType = "unassign"
+	// ENIAttached is sent when an ENI is added to the data store
+	ENIAttached AllocationEventType = "eni-attach"
+	// ENIDetached is sent when an ENI is removed from the data store
+	ENIDetached AllocationEventType = "eni-detach"
+	// CidrAdded is sent when a prefix or secondary IP is added to an ENI
+	CidrAdded AllocationEventType = "prefix-add"
+	// CidrRemoved is sent when a prefix or secondary IP is removed from an ENI
+	CidrRemoved AllocationEventType = "prefix-remove"
+)
+
+// DefaultWatchBufferSize is the number of events a watcher can fall behind by before it is closed
+const DefaultWatchBufferSize = 1024
+
+// AllocationEvent is a change of the data store. Only the fields relevant to its type are set.
+type AllocationEvent struct {
+	Type      AllocationEventType
+	Timestamp time.Time
+	// IPAMKey and IPAMMetadata are the ones of the pod, for assign and unassign events
+	IPAMKey      IPAMKey
+	IPAMMetadata IPAMMetadata
+	// IPAddress is the address of the pod, for assign and unassign events
+	IPAddress string
+	// ENI is the ID of the ENI the event applies to
+	ENI string
+	// Cidr is the prefix or secondary IP, for prefix add and remove events
+	Cidr     string
+	IsPrefix bool
+}
+
+// AllocationWatcher receives the changes of the data store made after the Snapshot of the allocated addresses was
+// taken. Events is closed when the watcher is stopped, or when it falls too far behind, in which case it should
+// watch again to get a new snapshot.
+type AllocationWatcher struct {
+	// Snapshot holds the addresses assigned to pods when the watch started, of both address families
+	Snapshot []PodIPInfo
+	Events   <-chan AllocationEvent
+
+	ds     *DataStore
+	events chan AllocationEvent
+}
+
+// WatchAllocations returns a watcher whose snapshot and events are consistent: every change made after the snapshot
+// is sent, none before. bufferSize is the number of events the watcher can fall behind by.
+func (ds *DataStore) WatchAllocations(bufferSize int) *AllocationWatcher {
+	if bufferSize <= 0 {
+		bufferSize = DefaultWatchBufferSize
+	}
+	events := make(chan AllocationEvent, bufferSize)
+	w := &AllocationWatcher{Events: events, ds: ds, events: events}
+
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+	w.Snapshot = append(ds.allocatedIPsUnsafe(false), ds.allocatedIPsUnsafe(true)...)
+	if ds.watchers == nil {
+		ds.watchers = make(map[*AllocationWatcher]bool)
+	}
+	ds.watchers[w] = true
+	return w
+}
+
+// Stop unregisters the watcher and closes its events channel. It is safe to call more than once.
+func (w *AllocationWatcher) Stop() {
+	w.ds.lock.Lock()
+	defer w.ds.lock.Unlock()
+	w.ds.removeWatcherUnsafe(w)
+}
+
+func (ds *DataStore) removeWatcherUnsafe(w *AllocationWatcher) {
+	if ds.watchers[w] {
+		delete(ds.watchers, w)
+		close(w.events)
+	}
+}
+
+// publishUnsafe sends the event to every watcher. It never blocks the data store: a watcher whose buffer is full
+// is closed.
+func (ds *DataStore) publishUnsafe(event AllocationEvent) {
+	if len(ds.watchers) == 0 {
+		return
+	}
+	event.Timestamp = time.Now()
+	for w := range ds.watchers {
+		select {
+		case w.events <- event:
+		default:
+			ds.log.Warnf("Allocation watcher fell behind by %d events, closing it", cap(w.events))
+			ds.removeWatcherUnsafe(w)
+		}
+	}
+}
+
+// publishAddressUnsafe sends an assign or unassign event of the address of a pod
+func (ds *DataStore) publishAddressUnsafe(eventType AllocationEventType, addr *AddressInfo, ipamKey IPAMKey,
+	ipamMetadata IPAMMetadata) {
+	if len(ds.watchers) == 0 {
+		return
+	}
+	event := AllocationEvent{
+		Type:         eventType,
+		IPAMKey:      ipamKey,
+		IPAMMetadata: ipamMetadata,
+		IPAddress:    addr.Address,
+	}
+	if eni := ds.findENIOfAddressUnsafe(addr); eni != nil {
+		event.ENI = eni.ID
+	}
+	ds.publishUnsafe(event)
+}
+
+// publishCidrUnsafe sends a prefix add or remove event
+func (ds *DataStore) publishCidrUnsafe(eventType AllocationEventType, eniID string, cidr *CidrInfo) {
+	ds.publishUnsafe(AllocationEvent{Type: eventType, ENI: eniID, Cidr: cidr.Cidr.String(), IsPrefix: cidr.IsPrefix})
+}
+
+// findENIOfAddressUnsafe returns the ENI holding addr, nil if it is not in the data store
+func (ds *DataStore) findENIOfAddressUnsafe(addr *AddressInfo) *ENI {
+	for _, eni := range ds.eniPool {
+		for _, cidrs := range []map[string]*CidrInfo{eni.AvailableIPv4Cidrs, eni.IPv6Cidrs} {
+			for _, availableCidr := range cidrs {
+				if availableCidr.IPAddresses[addr.Address] == addr {
+					return eni
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datastore
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// nextEvent returns the next event of the watcher, with the timestamp cleared
+func nextEvent(t *testing.T, w *AllocationWatcher) AllocationEvent {
+	select {
+	case event, ok := <-w.Events:
+		assert.True(t, ok, "events channel is closed")
+		assert.False(t, event.Timestamp.IsZero())
+		event.Timestamp = time.Time{}
+		return event
+	default:
+		t.Fatal("no event")
+		return AllocationEvent{}
+	}
+}
+
+func TestWatchAllocations(t *testing.T) {
+	ds := NewDataStore(Testlog, NullCheckpoint{}, false)
+	assert.NoError(t, ds.AddENI("eni-1", 1, true, false, false))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP("10.0.0.1"), Mask: net.IPv4Mask(255, 255, 255, 255)}, false))
+	key1 := IPAMKey{"net0", "sandbox-1", "eth0"}
+	metadata1 := IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "pod-1"}
+	_, _, err := ds.AssignPodIPv4Address(key1, metadata1)
+	assert.NoError(t, err)
+
+	w := ds.WatchAllocations(0)
+	defer w.Stop()
+	assert.Equal(t, []PodIPInfo{{IPAMKey: key1, IPAMMetadata: metadata1, IP: "10.0.0.1", DeviceNumber: 1, ENIID: "eni-1"}}, w.Snapshot)
+
+	assert.NoError(t, ds.AddENI("eni-2", 2, false, false, false))
+	assert.Equal(t, AllocationEvent{Type: ENIAttached, ENI: "eni-2"}, nextEvent(t, w))
+
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-2", net.IPNet{IP: net.ParseIP("10.0.1.1"), Mask: net.IPv4Mask(255, 255, 255, 255)}, false))
+	assert.Equal(t, AllocationEvent{Type: CidrAdded, ENI: "eni-2", Cidr: "10.0.1.1/32"}, nextEvent(t, w))
+
+	key2 := IPAMKey{"net0", "sandbox-2", "eth0"}
+	metadata2 := IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "pod-2"}
+	_, _, err = ds.AssignPodIPv4Address(key2, metadata2)
+	assert.NoError(t, err)
+	assert.Equal(t, AllocationEvent{Type: AllocationAssigned, IPAMKey: key2, IPAMMetadata: metadata2, IPAddress: "10.0.1.1", ENI: "eni-2"},
+		nextEvent(t, w))
+
+	_, _, _, _, err = ds.UnassignPodIPAddress(key1)
+	assert.NoError(t, err)
+	assert.Equal(t, AllocationEvent{Type: AllocationUnassigned, IPAMKey: key1, IPAMMetadata: metadata1, IPAddress: "10.0.0.1", ENI: "eni-1"},
+		nextEvent(t, w))
+
+	// Removing an ENI with a pod forcibly releases its address first
+	assert.NoError(t, ds.RemoveENIFromDataStore("eni-2", true))
+	assert.Equal(t, AllocationUnassigned, nextEvent(t, w).Type)
+	assert.Equal(t, AllocationEvent{Type: ENIDetached, ENI: "eni-2"}, nextEvent(t, w))
+
+	assert.NoError(t, ds.DelIPv4CidrFromStore("eni-1", net.IPNet{IP: net.ParseIP("10.0.0.1"), Mask: net.IPv4Mask(255, 255, 255, 255)}, false))
+	assert.Equal(t, AllocationEvent{Type: CidrRemoved, ENI: "eni-1", Cidr: "10.0.0.1/32"}, nextEvent(t, w))
+
+	w.Stop()
+	_, ok := <-w.Events
+	assert.False(t, ok)
+	// Stopping again is a no-op
+	w.Stop()
+	assert.Empty(t, ds.watchers)
+}
+
+func TestWatchAllocationsSlowWatcher(t *testing.T) {
+	ds := NewDataStore(Testlog, NullCheckpoint{}, false)
+	w := ds.WatchAllocations(2)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, ds.AddENI(fmt.Sprintf("eni-%d", i), i, false, false, false))
+	}
+	// The buffered events are still delivered before the channel is closed
+	assert.Equal(t, "eni-0", nextEvent(t, w).ENI)
+	assert.Equal(t, "eni-1", nextEvent(t, w).ENI)
+	_, ok := <-w.Events
+	assert.False(t, ok)
+	assert.Empty(t, ds.watchers)
+	w.Stop()
+}
+
+func TestWatchAllocationsConcurrent(t *testing.T) {
+	ds := NewDataStore(Testlog, NullCheckpoint{}, true)
+	assert.NoError(t, ds.AddENI("eni-1", 1, true, false, false))
+	_, prefix, _ := net.ParseCIDR("10.0.1.0/24")
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", *prefix, true))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := IPAMKey{"net0", fmt.Sprintf("sandbox-%d", i), "eth0"}
+			_, _, err := ds.AssignPodIPv4Address(key, IPAMMetadata{})
+			assert.NoError(t, err)
+			if i%2 == 0 {
+				_, _, _, _, err = ds.UnassignPodIPAddress(key)
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+
+	// The snapshot of a watch started while pods are added, updated by the events sent after it, gives the final
+	// allocations
+	w := ds.WatchAllocations(0)
+	defer w.Stop()
+	wg.Wait()
+	allocations := make(map[IPAMKey]string)
+	for _, info := range w.Snapshot {
+		allocations[info.IPAMKey] = info.IP
+	}
+	for len(w.Events) > 0 {
+		event := <-w.Events
+		switch event.Type {
+		case AllocationAssigned:
+			allocations[event.IPAMKey] = event.IPAddress
+		case AllocationUnassigned:
+			delete(allocations, event.IPAMKey)
+		}
+	}
+	expected := make(map[IPAMKey]string)
+	for _, info := range ds.AllocatedIPs() {
+		expected[info.IPAMKey] = info.IP
+	}
+	assert.Len(t, expected, 25)
+	assert.Equal(t, expected, allocations)
+}
@@ -0,0 +1,585 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nholuongut/nholuongut-sdk-go/nholuongut"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/nholuongututils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/eniconfig"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/retry"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/utils"
+)
+
+const (
+	// envEnableEgressEIP enables the egress of the pods of annotated namespaces through Elastic IPs
+	envEnableEgressEIP = "ENABLE_EGRESS_EIP"
+
+	// envEgressEIPENIConfig names the ENIConfig whose subnet and security groups the egress ENI is created with. The
+	// ENI is created like the primary ENI when it is not set.
+	envEgressEIPENIConfig = "EGRESS_EIP_ENICONFIG"
+
+	// egressEIPPoolKey is the annotation of a namespace listing the allocation IDs of the Elastic IPs its pods egress
+	// through, separated by commas
+	egressEIPPoolKey = "vpc.amazonnholuongut.com/egress-eip-pool"
+
+	// eniEgressEIPTagKey is the tag set on the egress ENI, so that it is recovered on restart
+	eniEgressEIPTagKey = "node.k8s.amazonnholuongut.com/egress-eip"
+
+	// egressENIAttachBackoff is the longest wait between two lookups of the egress ENI in the instance metadata
+	egressENIAttachBackoff = 5 * time.Second
+
+	// egressEIPRetryPeriod is the period at which the association of the Elastic IPs of the pending namespaces, and the
+	// disassociation of the released ones, are retried
+	egressEIPRetryPeriod = time.Minute
+)
+
+// egressEIPs tracks the Elastic IPs which the pods of annotated namespaces egress through. Each namespace with pods on
+// the node gets an Elastic IP of its pool, associated with a secondary IP of the egress ENI by the egress Elastic IP
+// controller, which also disassociates it once the namespace has no pods left on the node. The egress ENI is not managed by ipamd, so that its addresses are never handed out to pods.
+type egressEIPs struct {
+	sync.Mutex
+	// eniID is the egress ENI, empty until it is created
+	eniID string
+	// eni is the metadata of the egress ENI, nil until its network is set up
+	eni *nholuongututils.ENIMetadata
+	// namespaces maps the namespaces with pods on the node to their Elastic IP
+	namespaces map[string]nholuongututils.ElasticIP
+	// pods maps the IPs of the pods to their namespace
+	pods map[string]string
+	// pending are the namespaces with pods on the node whose Elastic IP is yet to be associated
+	pending map[string]bool
+	// released are the Elastic IPs which no namespace uses anymore, yet to be disassociated
+	released []nholuongututils.ElasticIP
+	// releaseUnused is set until the Elastic IPs left associated with the egress ENI by a previous run of ipamd are
+	// either recovered or released
+	releaseUnused bool
+	// trigger wakes up the controller associating the Elastic IPs of the pending namespaces
+	trigger chan struct{}
+}
+
+// newEgressEIPs returns the egress Elastic IPs of the node, nil if ENABLE_EGRESS_EIP is not set
+func newEgressEIPs() *egressEIPs {
+	if !utils.GetBoolAsStringEnvVar(envEnableEgressEIP, false) {
+		return nil
+	}
+	return &egressEIPs{
+		namespaces: make(map[string]nholuongututils.ElasticIP),
+		pods:       make(map[string]string),
+		pending:    make(map[string]bool),
+		trigger:    make(chan struct{}, 1),
+	}
+}
+
+// parseEgressEIPPool returns the allocation IDs of the egress-eip-pool annotation
+func parseEgressEIPPool(value string) []string {
+	var pool []string
+	for _, allocationID := range strings.Split(value, ",") {
+		allocationID = strings.TrimSpace(allocationID)
+		if allocationID == "" || lo.Contains(pool, allocationID) {
+			continue
+		}
+		pool = append(pool, allocationID)
+	}
+	return pool
+}
+
+// namespaceEgressEIPPool returns the Elastic IPs which the pods of the namespace egress through, none if the
+// namespace is not annotated or no longer exists
+func (c *IPAMContext) namespaceEgressEIPPool(ctx context.Context, podNamespace string) ([]string, error) {
+	var namespace corev1.Namespace
+	if err := c.k8sClient.Get(ctx, types.NamespacedName{Name: podNamespace}, &namespace); err != nil {
+		if k8serror.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to look up the egress Elastic IPs of namespace %s", podNamespace)
+	}
+	return parseEgressEIPPool(namespace.Annotations[egressEIPPoolKey]), nil
+}
+
+// assignEgressEIP makes the pod with podIP egress through the Elastic IP of its namespace, if the namespace has an
+// Elastic IP pool. For the first pod of the namespace on the node, the controller associates an Elastic IP of the pool
+// with the egress ENI in the background, so that the EC2 calls do not hold up the ADD.
+func (c *IPAMContext) assignEgressEIP(ctx context.Context, podNamespace, podIP string) error {
+	e := c.egressEIPs
+	if e == nil || podNamespace == "" {
+		return nil
+	}
+	pool, err := c.namespaceEgressEIPPool(ctx, podNamespace)
+	if err != nil {
+		// The controller drops the pod if the namespace turns out to have no pool
+		log.Warnf("Unable to look up the egress Elastic IPs of pod %s, retrying in the background: %v", podIP, err)
+	} else if len(pool) == 0 {
+		return nil
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	e.pods[podIP] = podNamespace
+	if _, ok := e.namespaces[podNamespace]; !ok {
+		e.pending[podNamespace] = true
+		c.triggerEgressEIPController()
+		return nil
+	}
+	if err := c.updateEgressEIPRules(); err != nil {
+		delete(e.pods, podIP)
+		c.releaseUnusedEgressEIP(podNamespace)
+		return err
+	}
+	return nil
+}
+
+// releaseEgressEIP stops the egress of the pod with podIP through the Elastic IP of its namespace. The controller
+// disassociates the Elastic IP in the background once the namespace has no pods left on the node, so that the EC2 calls
+// do not hold up the DEL.
+func (c *IPAMContext) releaseEgressEIP(podIP string) {
+	e := c.egressEIPs
+	if e == nil || podIP == "" {
+		return
+	}
+	e.Lock()
+	defer e.Unlock()
+	podNamespace, ok := e.pods[podIP]
+	if !ok {
+		return
+	}
+	delete(e.pods, podIP)
+	c.releaseUnusedEgressEIP(podNamespace)
+	if err := c.updateEgressEIPRules(); err != nil {
+		// The SNAT rule of the pod is left behind until the next update, it does not match once the IP is reused
+		log.Warnf("Failed to update the egress Elastic IP rules: %v", err)
+	}
+}
+
+// releaseUnusedEgressEIP releases the Elastic IP of the namespace if it has no pods left on the node
+func (c *IPAMContext) releaseUnusedEgressEIP(podNamespace string) {
+	e := c.egressEIPs
+	for _, namespace := range e.pods {
+		if namespace == podNamespace {
+			return
+		}
+	}
+	delete(e.pending, podNamespace)
+	eip, ok := e.namespaces[podNamespace]
+	if !ok {
+		return
+	}
+	delete(e.namespaces, podNamespace)
+	log.Infof("Namespace %s has no pods left, releasing Elastic IP %s (%s)", podNamespace, eip.PublicIP, eip.AllocationID)
+	c.releaseEgressEIPAssociation(eip)
+}
+
+// releaseEgressEIPAssociation queues the Elastic IP for the controller to disassociate
+func (c *IPAMContext) releaseEgressEIPAssociation(eip nholuongututils.ElasticIP) {
+	c.egressEIPs.released = append(c.egressEIPs.released, eip)
+	c.triggerEgressEIPController()
+}
+
+// StartEgressEIPController disassociates the released Elastic IPs and associates the Elastic IPs of the pending
+// namespaces, and retries the ones which failed, or whose pool could not be looked up or had no free Elastic IP
+func (c *IPAMContext) StartEgressEIPController() {
+	if c.egressEIPs == nil {
+		return
+	}
+	log.Infof("Egress Elastic IP controller - retry period: %v", egressEIPRetryPeriod)
+	ctx := context.Background()
+	for !c.isTerminating() {
+		c.disassociateReleasedEgressEIPs()
+		c.associatePendingEgressEIPs(ctx)
+		select {
+		case <-c.egressEIPs.trigger:
+		case <-time.After(egressEIPRetryPeriod):
+		}
+	}
+}
+
+// triggerEgressEIPController makes the controller disassociate the released Elastic IPs and associate the Elastic IPs
+// of the pending namespaces
+func (c *IPAMContext) triggerEgressEIPController() {
+	select {
+	case c.egressEIPs.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// disassociateReleasedEgressEIPs disassociates the released Elastic IPs. The ones which fail are kept for the next run.
+func (c *IPAMContext) disassociateReleasedEgressEIPs() {
+	e := c.egressEIPs
+	e.Lock()
+	released := e.released
+	e.released = nil
+	e.Unlock()
+	var failed []nholuongututils.ElasticIP
+	for _, eip := range released {
+		if err := c.disassociateEgressEIP(eip); err != nil {
+			ipamdErrInc("disassociateEgressEIPFailed")
+			log.Errorf("Failed to disassociate Elastic IP %s, retrying in %v: %v", eip.AllocationID, egressEIPRetryPeriod, err)
+			failed = append(failed, eip)
+		}
+	}
+	e.Lock()
+	e.released = append(e.released, failed...)
+	e.Unlock()
+}
+
+// associatePendingEgressEIPs associates an Elastic IP with each pending namespace. Once none is pending, the Elastic
+// IPs left associated with the egress ENI by a previous run of ipamd which were not recovered are released.
+func (c *IPAMContext) associatePendingEgressEIPs(ctx context.Context) {
+	e := c.egressEIPs
+	e.Lock()
+	pending := lo.Keys(e.pending)
+	e.Unlock()
+	for _, podNamespace := range pending {
+		if err := c.associateNamespaceEgressEIP(ctx, podNamespace); err != nil {
+			ipamdErrInc("associateEgressEIPFailed")
+			log.Errorf("Failed to associate an Elastic IP of namespace %s, retrying in %v: %v", podNamespace, egressEIPRetryPeriod, err)
+		}
+	}
+
+	e.Lock()
+	releaseUnused := e.releaseUnused && len(e.pending) == 0
+	eniID := e.eniID
+	e.Unlock()
+	if !releaseUnused {
+		return
+	}
+	associated, err := c.nholuongutClient.DescribeElasticIPs(nil, eniID)
+	if err != nil {
+		log.Warnf("Failed to describe the Elastic IPs of egress ENI %s, retrying in %v: %v", eniID, egressEIPRetryPeriod, err)
+		return
+	}
+	e.Lock()
+	defer e.Unlock()
+	c.releaseUnusedEgressEIPs(associated)
+}
+
+// associateNamespaceEgressEIP associates an Elastic IP of the pool of the pending namespace, and SNATs the traffic of
+// its pods to it. The lock is only held around the updates of the state, so that ADD and DEL go on meanwhile.
+func (c *IPAMContext) associateNamespaceEgressEIP(ctx context.Context, podNamespace string) error {
+	e := c.egressEIPs
+	pool, err := c.namespaceEgressEIPPool(ctx, podNamespace)
+	if err != nil {
+		return err
+	}
+	if len(pool) == 0 {
+		// The pods of a namespace without a pool egress through the node
+		e.Lock()
+		defer e.Unlock()
+		delete(e.pending, podNamespace)
+		for podIP, namespace := range e.pods {
+			if namespace == podNamespace {
+				delete(e.pods, podIP)
+			}
+		}
+		return nil
+	}
+	eip, err := c.associateEgressEIP(ctx, pool)
+	if err != nil {
+		return err
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	if !e.pending[podNamespace] {
+		// The last pod of the namespace was deleted meanwhile
+		c.releaseEgressEIPAssociation(eip)
+		return nil
+	}
+	log.Infof("Pods of namespace %s egress through Elastic IP %s (%s)", podNamespace, eip.PublicIP, eip.AllocationID)
+	delete(e.pending, podNamespace)
+	e.namespaces[podNamespace] = eip
+	return c.updateEgressEIPRules()
+}
+
+// associateEgressEIP associates a free Elastic IP of the pool with a new secondary IP of the egress ENI, unless an
+// Elastic IP of the pool left associated with the egress ENI by a previous run of ipamd is not used yet. The egress
+// ENI is created if it does not exist yet. It must only be called by the egress Elastic IP controller.
+func (c *IPAMContext) associateEgressEIP(ctx context.Context, pool []string) (nholuongututils.ElasticIP, error) {
+	e := c.egressEIPs
+	if err := c.ensureEgressENI(ctx); err != nil {
+		return nholuongututils.ElasticIP{}, err
+	}
+	eips, err := c.nholuongutClient.DescribeElasticIPs(pool, "")
+	if err != nil {
+		return nholuongututils.ElasticIP{}, err
+	}
+	e.Lock()
+	recovered, found := lo.Find(eips, func(eip nholuongututils.ElasticIP) bool {
+		return eip.NetworkInterfaceID == e.eniID && !c.isEgressEIPInUse(eip.AllocationID) && !c.isEgressEIPReleased(eip.AllocationID)
+	})
+	e.Unlock()
+	if found {
+		if err := c.setupEgressENINetwork(); err != nil {
+			return nholuongututils.ElasticIP{}, err
+		}
+		return recovered, nil
+	}
+	for _, eip := range eips {
+		if eip.AssociationID != "" {
+			continue
+		}
+		privateIP, err := c.nholuongutClient.AllocSecondaryIP(e.eniID)
+		if err != nil {
+			return nholuongututils.ElasticIP{}, err
+		}
+		if err := c.setupEgressENINetwork(); err != nil {
+			c.deallocEgressIP(privateIP)
+			return nholuongututils.ElasticIP{}, err
+		}
+		associationID, err := c.nholuongutClient.AssociateElasticIP(eip.AllocationID, e.eniID, privateIP)
+		if err != nil {
+			// Another node may have taken the Elastic IP since it was described
+			log.Warnf("Failed to associate Elastic IP %s, trying the next one of the pool: %v", eip.AllocationID, err)
+			c.deallocEgressIP(privateIP)
+			continue
+		}
+		eip.AssociationID = associationID
+		eip.NetworkInterfaceID = e.eniID
+		eip.PrivateIP = privateIP
+		return eip, nil
+	}
+	return nholuongututils.ElasticIP{}, errors.Errorf("no Elastic IP of %v is free", pool)
+}
+
+// disassociateEgressEIP disassociates the Elastic IP and frees its secondary IP on the egress ENI. It must only be
+// called by the egress Elastic IP controller.
+func (c *IPAMContext) disassociateEgressEIP(eip nholuongututils.ElasticIP) error {
+	if err := c.nholuongutClient.DisassociateElasticIP(eip.AssociationID); err != nil {
+		return err
+	}
+	c.deallocEgressIP(eip.PrivateIP)
+	return nil
+}
+
+func (c *IPAMContext) deallocEgressIP(privateIP string) {
+	if err := c.nholuongutClient.DeallocIPAddresses(c.egressEIPs.eniID, []string{privateIP}); err != nil {
+		ipamdErrInc("deallocEgressIPFailed")
+		log.Errorf("Failed to free egress IP %s: %v", privateIP, err)
+	}
+}
+
+// ensureEgressENI creates the egress ENI, in the subnet of the ENIConfig of EGRESS_EIP_ENICONFIG if it is set
+func (c *IPAMContext) ensureEgressENI(ctx context.Context) error {
+	e := c.egressEIPs
+	if e.eniID != "" {
+		return nil
+	}
+	var securityGroups []*string
+	var subnet string
+	if eniConfigName := os.Getenv(envEgressEIPENIConfig); eniConfigName != "" {
+		eniCfg, err := eniconfig.GetENIConfig(ctx, c.k8sClient, eniConfigName)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get ENIConfig %s of the egress ENI", eniConfigName)
+		}
+		for _, sgID := range eniCfg.SecurityGroups {
+			securityGroups = append(securityGroups, nholuongut.String(sgID))
+		}
+		subnet = eniCfg.Subnet
+	}
+	eniID, err := c.nholuongutClient.AllocEgressENI(securityGroups, subnet)
+	if err != nil {
+		ipamdErrInc("allocEgressENIFailed")
+		return errors.Wrap(err, "failed to allocate the egress ENI")
+	}
+	log.Infof("Created egress ENI %s", eniID)
+	e.Lock()
+	e.eniID = eniID
+	e.Unlock()
+	return nil
+}
+
+// setupEgressENINetwork sets up the route table of the egress ENI, once it has a secondary IP
+func (c *IPAMContext) setupEgressENINetwork() error {
+	e := c.egressEIPs
+	if e.eni != nil {
+		return nil
+	}
+	// WaitForENIAndIPsAttached counts prefixes when prefix delegation is enabled, the egress ENI only has secondary IPs
+	var eniMetadata nholuongututils.ENIMetadata
+	err := retry.NWithBackoff(retry.NewSimpleBackoff(100*time.Millisecond, egressENIAttachBackoff, 0.15, 2.0), maxRetryCheckENI, func() error {
+		enis, err := c.nholuongutClient.GetAttachedENIs()
+		if err != nil {
+			return err
+		}
+		for _, eni := range enis {
+			if eni.ENIID == e.eniID {
+				eniMetadata = eni
+				return nil
+			}
+		}
+		return nholuongututils.ErrENINotFound
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to wait for egress ENI %s", e.eniID)
+	}
+	if err := c.networkClient.SetupENINetwork(eniMetadata.PrimaryIPv4Address(), eniMetadata.MAC, eniMetadata.DeviceNumber,
+		eniMetadata.SubnetIPv4CIDR); err != nil {
+		return errors.Wrapf(err, "failed to set up the network of egress ENI %s", e.eniID)
+	}
+	e.Lock()
+	e.eni = &eniMetadata
+	e.Unlock()
+	return nil
+}
+
+// updateEgressEIPRules SNATs the traffic of each pod to outside of the VPC to the private IP of the Elastic IP of its
+// namespace, and routes it through the egress ENI
+func (c *IPAMContext) updateEgressEIPRules() error {
+	e := c.egressEIPs
+	vpcCIDRs, err := c.nholuongutClient.GetVPCIPv4CIDRs()
+	if err != nil {
+		return err
+	}
+	var eniMAC string
+	var deviceNumber int
+	if e.eni != nil {
+		eniMAC, deviceNumber = e.eni.MAC, e.eni.DeviceNumber
+	}
+	podSNATIPs := make(map[string]string, len(e.pods))
+	for podIP, namespace := range e.pods {
+		// The pods of the pending namespaces egress through the node until their Elastic IP is associated
+		if eip, ok := e.namespaces[namespace]; ok {
+			podSNATIPs[podIP] = eip.PrivateIP
+		}
+	}
+	return c.networkClient.UpdateEgressEIPRules(vpcCIDRs, eniMAC, deviceNumber, podSNATIPs)
+}
+
+// initEgressEIPs recovers the egress ENI and the Elastic IPs of the namespaces of the pods on the node. The namespaces
+// whose Elastic IP cannot be recovered are left pending for the egress Elastic IP controller, so that API server and
+// EC2 errors do not hold up the start of ipamd. When egress Elastic IPs are disabled, the egress ENI is freed.
+func (c *IPAMContext) initEgressEIPs(ctx context.Context, metadataResult nholuongututils.DescribeAllENIsResult) error {
+	var egressENI *nholuongututils.ENIMetadata
+	for i, eni := range metadataResult.ENIMetadata {
+		if metadataResult.TagMap[eni.ENIID][eniEgressEIPTagKey] == "true" {
+			egressENI = &metadataResult.ENIMetadata[i]
+		}
+	}
+	e := c.egressEIPs
+	if e == nil {
+		if egressENI != nil {
+			log.Infof("Freeing egress ENI %s since %s is not set", egressENI.ENIID, envEnableEgressEIP)
+			if err := c.freeEgressENI(egressENI.ENIID); err != nil {
+				ipamdErrInc("freeEgressENIFailed")
+				log.Errorf("Failed to free egress ENI %s, retrying on the next restart: %v", egressENI.ENIID, err)
+			}
+		}
+		return nil
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	var associated []nholuongututils.ElasticIP
+	describedAssociated := false
+	if egressENI != nil {
+		log.Infof("Recovering egress ENI %s", egressENI.ENIID)
+		e.eniID = egressENI.ENIID
+		e.releaseUnused = true
+		// Without its network, the Elastic IPs of the egress ENI are recovered by the controller, which sets it up
+		if err := c.networkClient.SetupENINetwork(egressENI.PrimaryIPv4Address(), egressENI.MAC, egressENI.DeviceNumber,
+			egressENI.SubnetIPv4CIDR); err != nil {
+			log.Warnf("Failed to set up the network of egress ENI %s, retrying in the background: %v", egressENI.ENIID, err)
+		} else if associated, err = c.nholuongutClient.DescribeElasticIPs(nil, egressENI.ENIID); err != nil {
+			log.Warnf("Failed to describe the Elastic IPs of egress ENI %s, retrying in the background: %v", egressENI.ENIID, err)
+		} else {
+			e.eni = egressENI
+			describedAssociated = true
+		}
+	}
+
+	for _, info := range c.dataStore.AllocatedIPs() {
+		podNamespace := info.IPAMMetadata.K8SPodNamespace
+		if podNamespace == "" || net.ParseIP(info.IP).To4() == nil {
+			continue
+		}
+		if _, ok := e.namespaces[podNamespace]; !ok && !e.pending[podNamespace] {
+			pool, err := c.namespaceEgressEIPPool(ctx, podNamespace)
+			if err != nil {
+				log.Warnf("Unable to look up the egress Elastic IPs of namespace %s, retrying in the background: %v", podNamespace, err)
+				e.pending[podNamespace] = true
+			} else if len(pool) == 0 {
+				continue
+			} else if eip, found := lo.Find(associated, func(eip nholuongututils.ElasticIP) bool {
+				return lo.Contains(pool, eip.AllocationID) && !c.isEgressEIPInUse(eip.AllocationID)
+			}); found {
+				e.namespaces[podNamespace] = eip
+			} else {
+				e.pending[podNamespace] = true
+			}
+		}
+		e.pods[info.IP] = podNamespace
+	}
+
+	if len(e.pending) > 0 {
+		c.triggerEgressEIPController()
+	} else if describedAssociated {
+		c.releaseUnusedEgressEIPs(associated)
+	}
+	return c.updateEgressEIPRules()
+}
+
+// releaseUnusedEgressEIPs releases the Elastic IPs associated with the egress ENI which no namespace uses
+func (c *IPAMContext) releaseUnusedEgressEIPs(associated []nholuongututils.ElasticIP) {
+	for _, eip := range associated {
+		if !c.isEgressEIPInUse(eip.AllocationID) && !c.isEgressEIPReleased(eip.AllocationID) {
+			log.Infof("Releasing unused Elastic IP %s (%s)", eip.PublicIP, eip.AllocationID)
+			c.releaseEgressEIPAssociation(eip)
+		}
+	}
+	c.egressEIPs.releaseUnused = false
+}
+
+func (c *IPAMContext) isEgressEIPInUse(allocationID string) bool {
+	for _, eip := range c.egressEIPs.namespaces {
+		if eip.AllocationID == allocationID {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *IPAMContext) isEgressEIPReleased(allocationID string) bool {
+	return lo.ContainsBy(c.egressEIPs.released, func(eip nholuongututils.ElasticIP) bool {
+		return eip.AllocationID == allocationID
+	})
+}
+
+// freeEgressENI disassociates the Elastic IPs of the egress ENI and frees it
+func (c *IPAMContext) freeEgressENI(eniID string) error {
+	associated, err := c.nholuongutClient.DescribeElasticIPs(nil, eniID)
+	if err != nil {
+		return err
+	}
+	for _, eip := range associated {
+		if err := c.nholuongutClient.DisassociateElasticIP(eip.AssociationID); err != nil {
+			return err
+		}
+	}
+	if err := c.nholuongutClient.FreeENI(eniID); err != nil {
+		return errors.Wrapf(err, "failed to free egress ENI %s", eniID)
+	}
+	return c.networkClient.UpdateEgressEIPRules(nil, "", 0, nil)
+}
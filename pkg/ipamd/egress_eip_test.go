@@ -0,0 +1,277 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/nholuongut/nholuongut-sdk-go/nholuongut"
+	"github.com/nholuongut/nholuongut-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/nholuongututils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+)
+
+const (
+	egressENIID  = "eni-egress"
+	egressENIMAC = "12:34:56:78:9a:bc"
+)
+
+var egressENIMetadata = nholuongututils.ENIMetadata{
+	ENIID:          egressENIID,
+	MAC:            egressENIMAC,
+	DeviceNumber:   2,
+	SubnetIPv4CIDR: "10.0.2.0/24",
+	IPv4Addresses: []*ec2.NetworkInterfacePrivateIpAddress{
+		{PrivateIpAddress: nholuongut.String("10.0.2.10"), Primary: nholuongut.Bool(true)},
+	},
+}
+
+func TestParseEgressEIPPool(t *testing.T) {
+	assert.Empty(t, parseEgressEIPPool(""))
+	assert.Equal(t, []string{"eipalloc-1"}, parseEgressEIPPool("eipalloc-1"))
+	assert.Equal(t, []string{"eipalloc-1", "eipalloc-2"}, parseEgressEIPPool(" eipalloc-1, ,eipalloc-2,eipalloc-1,"))
+}
+
+func TestAssignAndReleaseEgressEIP(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	assert.NoError(t, m.k8sClient.Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "payments",
+			Annotations: map[string]string{egressEIPPoolKey: "eipalloc-1,eipalloc-2"},
+		},
+	}))
+	assert.NoError(t, m.k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}))
+
+	os.Setenv(envEnableEgressEIP, "true")
+	defer os.Unsetenv(envEnableEgressEIP)
+	c := &IPAMContext{
+		nholuongutClient:     m.nholuongututils,
+		k8sClient:     m.k8sClient,
+		networkClient: m.network,
+		egressEIPs:    newEgressEIPs(),
+	}
+	vpcCIDRs := []string{"10.0.0.0/16"}
+
+	// Pods of namespaces without a pool egress as before
+	assert.NoError(t, c.assignEgressEIP(ctx, "default", "10.0.0.4"))
+	assert.NoError(t, c.assignEgressEIP(ctx, "deleted", "10.0.0.7"))
+	assert.Empty(t, c.egressEIPs.pods)
+
+	// The first pod of the namespace leaves the EC2 calls to the controller
+	assert.NoError(t, c.assignEgressEIP(ctx, "payments", "10.0.0.5"))
+	assert.Equal(t, map[string]bool{"payments": true}, c.egressEIPs.pending)
+
+	// The controller creates the egress ENI, and takes the first free Elastic IP of the pool
+	gomock.InOrder(
+		m.nholuongututils.EXPECT().AllocEgressENI(nil, "").Return(egressENIID, nil),
+		m.nholuongututils.EXPECT().DescribeElasticIPs([]string{"eipalloc-1", "eipalloc-2"}, "").Return([]nholuongututils.ElasticIP{
+			{AllocationID: "eipalloc-1", PublicIP: "3.0.0.1", AssociationID: "eipassoc-other"},
+			{AllocationID: "eipalloc-2", PublicIP: "3.0.0.2"},
+		}, nil),
+		m.nholuongututils.EXPECT().AllocSecondaryIP(egressENIID).Return("10.0.2.20", nil),
+		m.nholuongututils.EXPECT().GetAttachedENIs().Return([]nholuongututils.ENIMetadata{egressENIMetadata}, nil),
+		m.network.EXPECT().SetupENINetwork("10.0.2.10", egressENIMAC, 2, "10.0.2.0/24").Return(nil),
+		m.nholuongututils.EXPECT().AssociateElasticIP("eipalloc-2", egressENIID, "10.0.2.20").Return("eipassoc-2", nil),
+		m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return(vpcCIDRs, nil),
+		m.network.EXPECT().UpdateEgressEIPRules(vpcCIDRs, egressENIMAC, 2, map[string]string{"10.0.0.5": "10.0.2.20"}).Return(nil),
+	)
+	c.associatePendingEgressEIPs(ctx)
+	assert.Empty(t, c.egressEIPs.pending)
+
+	// The next pods of the namespace share its Elastic IP
+	m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return(vpcCIDRs, nil)
+	m.network.EXPECT().UpdateEgressEIPRules(vpcCIDRs, egressENIMAC, 2,
+		map[string]string{"10.0.0.5": "10.0.2.20", "10.0.0.6": "10.0.2.20"}).Return(nil)
+	assert.NoError(t, c.assignEgressEIP(ctx, "payments", "10.0.0.6"))
+
+	m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return(vpcCIDRs, nil)
+	m.network.EXPECT().UpdateEgressEIPRules(vpcCIDRs, egressENIMAC, 2, map[string]string{"10.0.0.6": "10.0.2.20"}).Return(nil)
+	c.releaseEgressEIP("10.0.0.5")
+
+	// The last pod of the namespace releases the Elastic IP, which the controller disassociates. The egress ENI is kept.
+	m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return(vpcCIDRs, nil)
+	m.network.EXPECT().UpdateEgressEIPRules(vpcCIDRs, egressENIMAC, 2, map[string]string{}).Return(nil)
+	c.releaseEgressEIP("10.0.0.6")
+	assert.Empty(t, c.egressEIPs.namespaces)
+	assert.Len(t, c.egressEIPs.released, 1)
+
+	// A failed disassociation is retried
+	m.nholuongututils.EXPECT().DisassociateElasticIP("eipassoc-2").Return(errors.New("RequestLimitExceeded"))
+	c.disassociateReleasedEgressEIPs()
+	assert.Len(t, c.egressEIPs.released, 1)
+	gomock.InOrder(
+		m.nholuongututils.EXPECT().DisassociateElasticIP("eipassoc-2").Return(nil),
+		m.nholuongututils.EXPECT().DeallocIPAddresses(egressENIID, []string{"10.0.2.20"}).Return(nil),
+	)
+	c.disassociateReleasedEgressEIPs()
+	assert.Empty(t, c.egressEIPs.released)
+	assert.Equal(t, egressENIID, c.egressEIPs.eniID)
+
+	// Pods without an Elastic IP are ignored
+	c.releaseEgressEIP("10.0.0.4")
+}
+
+func TestAssignEgressEIPPoolExhausted(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	assert.NoError(t, m.k8sClient.Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "payments",
+			Annotations: map[string]string{egressEIPPoolKey: "eipalloc-1"},
+		},
+	}))
+
+	os.Setenv(envEnableEgressEIP, "true")
+	defer os.Unsetenv(envEnableEgressEIP)
+	c := &IPAMContext{
+		nholuongutClient:     m.nholuongututils,
+		k8sClient:     m.k8sClient,
+		networkClient: m.network,
+		egressEIPs:    newEgressEIPs(),
+	}
+	c.egressEIPs.eniID = egressENIID
+	c.egressEIPs.eni = &egressENIMetadata
+
+	// The Elastic IP is taken by another node between the lookup and the association
+	gomock.InOrder(
+		m.nholuongututils.EXPECT().DescribeElasticIPs([]string{"eipalloc-1"}, "").Return([]nholuongututils.ElasticIP{
+			{AllocationID: "eipalloc-1", PublicIP: "3.0.0.1"},
+		}, nil),
+		m.nholuongututils.EXPECT().AllocSecondaryIP(egressENIID).Return("10.0.2.20", nil),
+		m.nholuongututils.EXPECT().AssociateElasticIP("eipalloc-1", egressENIID, "10.0.2.20").Return("", errors.New("Resource.AlreadyAssociated")),
+		m.nholuongututils.EXPECT().DeallocIPAddresses(egressENIID, []string{"10.0.2.20"}).Return(nil),
+	)
+	assert.NoError(t, c.assignEgressEIP(ctx, "payments", "10.0.0.5"))
+	c.associatePendingEgressEIPs(ctx)
+	// The pod egresses through the node until an Elastic IP of the pool is free
+	assert.Equal(t, map[string]bool{"payments": true}, c.egressEIPs.pending)
+	assert.Empty(t, c.egressEIPs.namespaces)
+
+	// The pending namespace is dropped with its last pod
+	m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return([]string{"10.0.0.0/16"}, nil)
+	m.network.EXPECT().UpdateEgressEIPRules([]string{"10.0.0.0/16"}, egressENIMAC, 2, map[string]string{}).Return(nil)
+	c.releaseEgressEIP("10.0.0.5")
+	assert.Empty(t, c.egressEIPs.pending)
+}
+
+func TestInitEgressEIPs(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	assert.NoError(t, m.k8sClient.Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "payments",
+			Annotations: map[string]string{egressEIPPoolKey: "eipalloc-1,eipalloc-2"},
+		},
+	}))
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	assert.NoError(t, ds.AddENI("eni-1", 0, true, false, false))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP("10.0.0.5"), Mask: net.CIDRMask(32, 32)}, false))
+	_, _, err := ds.AssignPodIPv4Address(datastore.IPAMKey{NetworkName: "net0", ContainerID: "cid", IfName: "eth0"},
+		datastore.IPAMMetadata{K8SPodNamespace: "payments", K8SPodName: "pod"})
+	assert.NoError(t, err)
+
+	os.Setenv(envEnableEgressEIP, "true")
+	defer os.Unsetenv(envEnableEgressEIP)
+	c := &IPAMContext{
+		nholuongutClient:     m.nholuongututils,
+		k8sClient:     m.k8sClient,
+		networkClient: m.network,
+		dataStore:     ds,
+		egressEIPs:    newEgressEIPs(),
+	}
+	metadataResult := nholuongututils.DescribeAllENIsResult{
+		ENIMetadata: []nholuongututils.ENIMetadata{egressENIMetadata},
+		TagMap:      map[string]nholuongututils.TagMap{egressENIID: {eniEgressEIPTagKey: "true"}},
+	}
+	vpcCIDRs := []string{"10.0.0.0/16"}
+
+	// The Elastic IP of the pool is kept for the pod, the one of a namespace without pods is released
+	gomock.InOrder(
+		m.network.EXPECT().SetupENINetwork("10.0.2.10", egressENIMAC, 2, "10.0.2.0/24").Return(nil),
+		m.nholuongututils.EXPECT().DescribeElasticIPs(nil, egressENIID).Return([]nholuongututils.ElasticIP{
+			{AllocationID: "eipalloc-9", AssociationID: "eipassoc-9", NetworkInterfaceID: egressENIID, PrivateIP: "10.0.2.29"},
+			{AllocationID: "eipalloc-2", AssociationID: "eipassoc-2", NetworkInterfaceID: egressENIID, PrivateIP: "10.0.2.20"},
+		}, nil),
+		m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return(vpcCIDRs, nil),
+		m.network.EXPECT().UpdateEgressEIPRules(vpcCIDRs, egressENIMAC, 2, map[string]string{"10.0.0.5": "10.0.2.20"}).Return(nil),
+		m.nholuongututils.EXPECT().DisassociateElasticIP("eipassoc-9").Return(nil),
+		m.nholuongututils.EXPECT().DeallocIPAddresses(egressENIID, []string{"10.0.2.29"}).Return(nil),
+	)
+	assert.NoError(t, c.initEgressEIPs(ctx, metadataResult))
+	assert.Equal(t, "eipalloc-2", c.egressEIPs.namespaces["payments"].AllocationID)
+	assert.False(t, c.egressEIPs.releaseUnused)
+	c.disassociateReleasedEgressEIPs()
+
+	// EC2 errors leave the namespace to the controller rather than failing the start of ipamd
+	c.egressEIPs = newEgressEIPs()
+	gomock.InOrder(
+		m.network.EXPECT().SetupENINetwork("10.0.2.10", egressENIMAC, 2, "10.0.2.0/24").Return(nil),
+		m.nholuongututils.EXPECT().DescribeElasticIPs(nil, egressENIID).Return(nil, errors.New("RequestLimitExceeded")),
+		m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return(vpcCIDRs, nil),
+		m.network.EXPECT().UpdateEgressEIPRules(vpcCIDRs, "", 0, map[string]string{}).Return(nil),
+	)
+	assert.NoError(t, c.initEgressEIPs(ctx, metadataResult))
+	assert.Equal(t, map[string]bool{"payments": true}, c.egressEIPs.pending)
+
+	// The controller recovers the Elastic IP of the pool still associated with the egress ENI, and releases the other
+	gomock.InOrder(
+		m.nholuongututils.EXPECT().DescribeElasticIPs([]string{"eipalloc-1", "eipalloc-2"}, "").Return([]nholuongututils.ElasticIP{
+			{AllocationID: "eipalloc-2", AssociationID: "eipassoc-2", NetworkInterfaceID: egressENIID, PrivateIP: "10.0.2.20"},
+		}, nil),
+		m.nholuongututils.EXPECT().GetAttachedENIs().Return([]nholuongututils.ENIMetadata{egressENIMetadata}, nil),
+		m.network.EXPECT().SetupENINetwork("10.0.2.10", egressENIMAC, 2, "10.0.2.0/24").Return(nil),
+		m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return(vpcCIDRs, nil),
+		m.network.EXPECT().UpdateEgressEIPRules(vpcCIDRs, egressENIMAC, 2, map[string]string{"10.0.0.5": "10.0.2.20"}).Return(nil),
+		m.nholuongututils.EXPECT().DescribeElasticIPs(nil, egressENIID).Return([]nholuongututils.ElasticIP{
+			{AllocationID: "eipalloc-9", AssociationID: "eipassoc-9", NetworkInterfaceID: egressENIID, PrivateIP: "10.0.2.29"},
+			{AllocationID: "eipalloc-2", AssociationID: "eipassoc-2", NetworkInterfaceID: egressENIID, PrivateIP: "10.0.2.20"},
+		}, nil),
+		m.nholuongututils.EXPECT().DisassociateElasticIP("eipassoc-9").Return(nil),
+		m.nholuongututils.EXPECT().DeallocIPAddresses(egressENIID, []string{"10.0.2.29"}).Return(nil),
+	)
+	c.associatePendingEgressEIPs(ctx)
+	c.disassociateReleasedEgressEIPs()
+	assert.Equal(t, "eipalloc-2", c.egressEIPs.namespaces["payments"].AllocationID)
+	assert.Empty(t, c.egressEIPs.pending)
+	assert.False(t, c.egressEIPs.releaseUnused)
+
+	// Once egress Elastic IPs are disabled, the egress ENI is freed
+	c.egressEIPs = nil
+	gomock.InOrder(
+		m.nholuongututils.EXPECT().DescribeElasticIPs(nil, egressENIID).Return([]nholuongututils.ElasticIP{
+			{AllocationID: "eipalloc-2", AssociationID: "eipassoc-2", NetworkInterfaceID: egressENIID, PrivateIP: "10.0.2.20"},
+		}, nil),
+		m.nholuongututils.EXPECT().DisassociateElasticIP("eipassoc-2").Return(nil),
+		m.nholuongututils.EXPECT().FreeENI(egressENIID).Return(nil),
+		m.network.EXPECT().UpdateEgressEIPRules(nil, "", 0, nil).Return(nil),
+	)
+	assert.NoError(t, c.initEgressEIPs(ctx, metadataResult))
+}
@@ -0,0 +1,161 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"os"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/apis/crd/v1alpha1"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/nholuongututils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/eniconfig"
+)
+
+// effectiveConfig are the settings ipamd runs with, once the ENIConfig of the node is applied
+type effectiveConfig struct {
+	ENIConfig              string   `json:"eniConfig,omitempty"`
+	EnablePrefixDelegation bool     `json:"ENABLE_PREFIX_DELEGATION"`
+	WarmENITarget          int      `json:"WARM_ENI_TARGET"`
+	WarmIPTarget           int      `json:"WARM_IP_TARGET"`
+	MinimumIPTarget        int      `json:"MINIMUM_IP_TARGET"`
+	WarmPrefixTarget       int      `json:"WARM_PREFIX_TARGET"`
+	OverriddenByENIConfig  []string `json:"overriddenByENIConfig,omitempty"`
+	// WarmPoolProfile is the active profile of WARM_POOL_PROFILES
+	WarmPoolProfile string `json:"warmPoolProfile,omitempty"`
+	// AdaptiveWarmIPTarget is the warm IP target the pools are sized by when the adaptive warm pool is enabled
+	AdaptiveWarmIPTarget *int `json:"adaptiveWarmIPTarget,omitempty"`
+}
+
+// initENIConfigPrefixDelegation applies the prefix delegation setting of the ENIConfig of the node. It is only read on
+// start, since the datastore is set up for one mode.
+func (c *IPAMContext) initENIConfigPrefixDelegation(ctx context.Context) {
+	if !c.useCustomNetworking {
+		return
+	}
+	eniCfg, err := eniconfig.MyENIConfig(ctx, c.k8sClient)
+	if err != nil {
+		return
+	}
+	if eniCfg.EnablePrefixDelegation != nil && *eniCfg.EnablePrefixDelegation != c.enablePrefixDelegation {
+		log.Infof("Prefix delegation is set to %v by the ENIConfig of the node", *eniCfg.EnablePrefixDelegation)
+		c.enablePrefixDelegation = *eniCfg.EnablePrefixDelegation
+	}
+}
+
+// applyENIConfigOverrides sets the warm targets from the ENIConfig of the node, the env variables, or the ConfigMap of
+// IPAMD_CONFIG_MAP once it is applied, are used for the ones it leaves unset. The active warm pool profile still takes
+// precedence.
+func (c *IPAMContext) applyENIConfigOverrides(eniConfigName string, eniCfg *v1alpha1.ENIConfigSpec) {
+	c.configLock.Lock()
+	defer c.configLock.Unlock()
+	c.eniConfigName = eniConfigName
+	c.eniConfigSpec = eniCfg
+	config := c.reloadedConfig
+	if config == nil {
+		envConfig := loadReloadableConfig(os.LookupEnv)
+		config = &envConfig
+	}
+	c.warmENITarget = config.warmENITarget
+	c.warmIPTarget = config.warmIPTarget
+	c.minimumIPTarget = config.minimumIPTarget
+	c.warmPrefixTarget = config.warmPrefixTarget
+	defer c.applyWarmPoolProfile()
+	if eniCfg == nil {
+		return
+	}
+	if eniCfg.WarmIPTarget != nil {
+		c.warmIPTarget = *eniCfg.WarmIPTarget
+	}
+	if eniCfg.MinimumIPTarget != nil {
+		c.minimumIPTarget = *eniCfg.MinimumIPTarget
+	}
+	if eniCfg.WarmPrefixTarget != nil {
+		c.warmPrefixTarget = *eniCfg.WarmPrefixTarget
+	}
+	if eniCfg.EnablePrefixDelegation != nil && *eniCfg.EnablePrefixDelegation != c.enablePrefixDelegation {
+		log.Warnf("ENIConfig %s sets prefix delegation to %v, restart nholuongut-node to apply it", eniConfigName,
+			*eniCfg.EnablePrefixDelegation)
+	}
+	log.Infof("Using ENIConfig %s - warm IP target: %d, minimum IP target: %d, warm prefix target: %d",
+		eniConfigName, c.warmIPTarget, c.minimumIPTarget, c.warmPrefixTarget)
+}
+
+// refreshENIConfigOverrides applies the ENIConfig of the node again when it, or the choice of ENIConfig, changed
+func (c *IPAMContext) refreshENIConfigOverrides(ctx context.Context) {
+	if !c.useCustomNetworking {
+		return
+	}
+	var node corev1.Node
+	if err := c.k8sClient.Get(ctx, types.NamespacedName{Name: c.myNodeName}, &node); err != nil {
+		log.Debugf("Unable to get node %s to refresh its ENIConfig: %v", c.myNodeName, err)
+		return
+	}
+	eniConfigName, _ := eniconfig.GetNodeSpecificENIConfigName(node)
+	var eniConfig v1alpha1.ENIConfig
+	if err := c.k8sClient.Get(ctx, types.NamespacedName{Name: eniConfigName}, &eniConfig); err != nil {
+		log.Debugf("Unable to refresh ENIConfig %s: %v", eniConfigName, err)
+		return
+	}
+	if eniConfigName == c.eniConfigName && reflect.DeepEqual(&eniConfig.Spec, c.eniConfigSpec) {
+		return
+	}
+	log.Infof("ENIConfig %s of the node changed", eniConfigName)
+	c.applyENIConfigOverrides(eniConfigName, &eniConfig.Spec)
+}
+
+// eniOptions returns the settings of the ENIConfig for new ENIs
+func eniOptions(eniCfg *v1alpha1.ENIConfigSpec) nholuongututils.ENIOptions {
+	return nholuongututils.ENIOptions{Tags: eniCfg.Tags, Description: eniCfg.Description}
+}
+
+// getEffectiveConfig returns the settings ipamd runs with
+func (c *IPAMContext) getEffectiveConfig() effectiveConfig {
+	c.configLock.RLock()
+	defer c.configLock.RUnlock()
+	config := effectiveConfig{
+		ENIConfig:              c.eniConfigName,
+		EnablePrefixDelegation: c.enablePrefixDelegation,
+		WarmENITarget:          c.warmENITarget,
+		WarmIPTarget:           c.warmIPTarget,
+		MinimumIPTarget:        c.minimumIPTarget,
+		WarmPrefixTarget:       c.warmPrefixTarget,
+	}
+	if profile := c.warmPoolProfile; profile != nil {
+		config.WarmPoolProfile = profile.Name
+	}
+	if c.adaptiveWarmPool != nil {
+		if warmIPTarget, ok := c.adaptiveWarmPool.target(); ok {
+			config.AdaptiveWarmIPTarget = &warmIPTarget
+		}
+	}
+	if eniCfg := c.eniConfigSpec; eniCfg != nil {
+		if eniCfg.EnablePrefixDelegation != nil && *eniCfg.EnablePrefixDelegation == c.enablePrefixDelegation {
+			config.OverriddenByENIConfig = append(config.OverriddenByENIConfig, envEnableIpv4PrefixDelegation)
+		}
+		if eniCfg.WarmIPTarget != nil {
+			config.OverriddenByENIConfig = append(config.OverriddenByENIConfig, envWarmIPTarget)
+		}
+		if eniCfg.MinimumIPTarget != nil {
+			config.OverriddenByENIConfig = append(config.OverriddenByENIConfig, envMinimumIPTarget)
+		}
+		if eniCfg.WarmPrefixTarget != nil {
+			config.OverriddenByENIConfig = append(config.OverriddenByENIConfig, envWarmPrefixTarget)
+		}
+	}
+	return config
+}
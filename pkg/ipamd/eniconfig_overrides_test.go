@@ -0,0 +1,85 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/apis/crd/v1alpha1"
+)
+
+func TestRefreshENIConfigOverrides(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+	os.Setenv(envWarmIPTarget, "5")
+	defer os.Unsetenv(envWarmIPTarget)
+
+	warmIPTarget, minimumIPTarget := 2, 10
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   myNodeName,
+		Labels: map[string]string{"k8s.amazonnholuongut.com/eniConfig": "az1"},
+	}}
+	eniConfig := &v1alpha1.ENIConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "az1"},
+		Spec: v1alpha1.ENIConfigSpec{
+			Subnet:          "subnet-1",
+			WarmIPTarget:    &warmIPTarget,
+			MinimumIPTarget: &minimumIPTarget,
+		},
+	}
+	assert.NoError(t, m.k8sClient.Create(ctx, node))
+	assert.NoError(t, m.k8sClient.Create(ctx, eniConfig))
+
+	mockContext := &IPAMContext{
+		k8sClient:           m.k8sClient,
+		useCustomNetworking: true,
+		myNodeName:          myNodeName,
+		warmENITarget:       1,
+	}
+	mockContext.refreshENIConfigOverrides(ctx)
+	assert.Equal(t, effectiveConfig{
+		ENIConfig:             "az1",
+		WarmENITarget:         1,
+		WarmIPTarget:          2,
+		MinimumIPTarget:       10,
+		WarmPrefixTarget:      defaultWarmPrefixTarget,
+		OverriddenByENIConfig: []string{envWarmIPTarget, envMinimumIPTarget},
+	}, mockContext.getEffectiveConfig())
+
+	// Unset overrides fall back to the env variables
+	eniConfig.Spec.WarmIPTarget = nil
+	assert.NoError(t, m.k8sClient.Update(ctx, eniConfig))
+	mockContext.refreshENIConfigOverrides(ctx)
+	assert.Equal(t, 5, mockContext.warmIPTarget)
+	assert.Equal(t, 10, mockContext.minimumIPTarget)
+	assert.Equal(t, []string{envMinimumIPTarget}, mockContext.getEffectiveConfig().OverriddenByENIConfig)
+}
+
+func TestENIOptions(t *testing.T) {
+	eniCfg := &v1alpha1.ENIConfigSpec{
+		Subnet:      "subnet-1",
+		Tags:        map[string]string{"team": "payments"},
+		Description: "PCI workloads",
+	}
+	opts := eniOptions(eniCfg)
+	assert.Equal(t, eniCfg.Tags, opts.Tags)
+	assert.Equal(t, "PCI workloads", opts.Description)
+}
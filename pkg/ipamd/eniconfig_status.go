@@ -0,0 +1,192 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"time"
+
+	"github.com/nholuongut/nholuongut-sdk-go/nholuongut"
+	"github.com/nholuongut/nholuongut-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/apis/crd/v1alpha1"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/eniconfig"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/k8sapi"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/utils"
+)
+
+const (
+	// envENIConfigStatus (default false) enables the validation of the ENIConfigs against EC2. The results are
+	// reported in the status of the ENIConfigs.
+	envENIConfigStatus = "ENABLE_ENICONFIG_STATUS"
+
+	// eniConfigStatusCheckInterval is how often the validating node looks for ENIConfigs due for validation
+	eniConfigStatusCheckInterval = time.Minute
+
+	// eniConfigStatusResyncPeriod is how often an ENIConfig is validated
+	eniConfigStatusResyncPeriod = 5 * time.Minute
+
+	// eniConfigStatusLeaseName is the Lease the nodes elect the one validating all the ENIConfigs with, so that the
+	// API server and EC2 are not called by every node
+	eniConfigStatusLeaseName = "nholuongut-node-eniconfig-status"
+	// eniConfigStatusLeaseNamespace is the namespace of the Lease
+	eniConfigStatusLeaseNamespace = "kube-system"
+
+	// The other nodes only get the Lease every eniConfigStatusRetryPeriod, and take over once it was not renewed for
+	// eniConfigStatusLeaseDuration
+	eniConfigStatusLeaseDuration = time.Minute
+	eniConfigStatusRenewDeadline = 40 * time.Second
+	eniConfigStatusRetryPeriod   = 15 * time.Second
+)
+
+// StartENIConfigStatusController periodically validates the ENIConfigs and updates their status, if enabled. Only the
+// node holding the Lease validates them.
+func (c *IPAMContext) StartENIConfigStatusController() {
+	if !utils.GetBoolAsStringEnvVar(envENIConfigStatus, false) {
+		return
+	}
+	clientSet, err := k8sapi.GetKubeClientSet()
+	if err != nil {
+		log.Errorf("Failed to create clientset, ENIConfig status is not reported: %v", err)
+		return
+	}
+	// The cache of ipamd only holds this node, so the nodes are listed from the API server
+	listNodes := func(ctx context.Context) ([]corev1.Node, error) {
+		nodes, err := clientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{ResourceVersion: "0"})
+		if err != nil {
+			return nil, err
+		}
+		return nodes.Items, nil
+	}
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: eniConfigStatusLeaseName, Namespace: eniConfigStatusLeaseNamespace},
+		Client:     clientSet.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: c.myNodeName},
+	}
+
+	log.Infof("ENIConfig status controller - resync period: %v", eniConfigStatusResyncPeriod)
+	ctx := context.Background()
+	for !c.isTerminating() {
+		// RunOrDie returns when the node loses the Lease, it then runs for it again
+		leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			LeaseDuration:   eniConfigStatusLeaseDuration,
+			RenewDeadline:   eniConfigStatusRenewDeadline,
+			RetryPeriod:     eniConfigStatusRetryPeriod,
+			ReleaseOnCancel: true,
+			Name:            eniConfigStatusLeaseName,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(ctx context.Context) {
+					log.Infof("Validating the ENIConfigs, this node holds Lease %s", eniConfigStatusLeaseName)
+					c.runENIConfigStatus(ctx, listNodes)
+				},
+				OnStoppedLeading: func() {
+					log.Infof("No longer validating the ENIConfigs, this node lost Lease %s", eniConfigStatusLeaseName)
+				},
+			},
+		})
+	}
+}
+
+// runENIConfigStatus validates the ENIConfigs due for it every check interval, until ctx is done
+func (c *IPAMContext) runENIConfigStatus(ctx context.Context, listNodes func(context.Context) ([]corev1.Node, error)) {
+	for {
+		if err := c.updateENIConfigStatus(ctx, listNodes, time.Now()); err != nil {
+			log.Errorf("Failed to update ENIConfig status: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eniConfigStatusCheckInterval):
+		}
+	}
+}
+
+// isENIConfigStatusDue returns true if the ENIConfig was never validated, was changed or was last validated more
+// than the resync period ago
+func isENIConfigStatusDue(eniConfig *v1alpha1.ENIConfig, now time.Time) bool {
+	status := eniConfig.Status
+	return status.LastValidatedTime == nil || status.ObservedGeneration != eniConfig.Generation ||
+		now.Sub(status.LastValidatedTime.Time) >= eniConfigStatusResyncPeriod
+}
+
+// updateENIConfigStatus validates the ENIConfigs due for it against EC2, and updates their status
+func (c *IPAMContext) updateENIConfigStatus(ctx context.Context, listNodes func(context.Context) ([]corev1.Node, error), now time.Time) error {
+	var eniConfigs v1alpha1.ENIConfigList
+	if err := c.k8sClient.List(ctx, &eniConfigs); err != nil {
+		return errors.Wrap(err, "failed to list ENIConfigs")
+	}
+	due := lo.Filter(eniConfigs.Items, func(eniConfig v1alpha1.ENIConfig, _ int) bool {
+		return isENIConfigStatusDue(&eniConfig, now)
+	})
+	if len(due) == 0 {
+		return nil
+	}
+
+	nodes, err := listNodes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list nodes")
+	}
+	nodeZones := make(map[string][]string)
+	for _, node := range nodes {
+		eniConfigName, _ := eniconfig.GetNodeSpecificENIConfigName(node)
+		nodeZones[eniConfigName] = append(nodeZones[eniConfigName], node.Labels[corev1.LabelTopologyZone])
+	}
+
+	var subnetIDs, groupIDs []string
+	for _, eniConfig := range due {
+		subnetIDs = append(subnetIDs, eniConfig.Spec.Subnet)
+		groupIDs = append(groupIDs, eniConfig.Spec.SecurityGroups...)
+	}
+	subnets, err := c.nholuongutClient.GetSubnetsFromEC2(lo.Uniq(subnetIDs))
+	if err != nil {
+		return err
+	}
+	securityGroups, err := c.nholuongutClient.GetSecurityGroupsFromEC2(lo.Uniq(groupIDs))
+	if err != nil {
+		return err
+	}
+	subnetByID := lo.KeyBy(subnets, func(subnet *ec2.Subnet) string { return nholuongut.StringValue(subnet.SubnetId) })
+	securityGroupByID := lo.KeyBy(securityGroups, func(securityGroup *ec2.SecurityGroup) string {
+		return nholuongut.StringValue(securityGroup.GroupId)
+	})
+
+	for i := range due {
+		eniConfig := &due[i]
+		eniConfig.Status = eniconfig.ValidateENIConfig(eniConfig, c.nholuongutClient.GetVPCID(), subnetByID[eniConfig.Spec.Subnet],
+			securityGroupByID, nodeZones[eniConfig.Name], metav1.NewTime(now))
+		if err := c.k8sClient.Status().Update(ctx, eniConfig); err != nil {
+			if apierrors.IsConflict(err) {
+				// Its spec was changed meanwhile, it is validated again at the next check
+				log.Debugf("ENIConfig %s was updated meanwhile, skipping its status", eniConfig.Name)
+				continue
+			}
+			log.Errorf("Failed to update the status of ENIConfig %s: %v", eniConfig.Name, err)
+			continue
+		}
+		if ready := meta.FindStatusCondition(eniConfig.Status.Conditions, v1alpha1.ENIConfigReady); ready.Status != metav1.ConditionTrue {
+			log.Warnf("ENIConfig %s is not valid: %s", eniConfig.Name, ready.Message)
+		}
+	}
+	return nil
+}
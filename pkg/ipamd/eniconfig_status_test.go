@@ -0,0 +1,111 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nholuongut/nholuongut-sdk-go/nholuongut"
+	"github.com/nholuongut/nholuongut-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	testclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/apis/crd/v1alpha1"
+)
+
+func TestUpdateENIConfigStatus(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	now := time.Now().Truncate(time.Second)
+	validated := metav1.NewTime(now.Add(-time.Minute))
+	k8sSchema := runtime.NewScheme()
+	v1alpha1.AddToScheme(k8sSchema)
+	k8sClient := testclient.NewClientBuilder().WithScheme(k8sSchema).
+		WithStatusSubresource(&v1alpha1.ENIConfig{}).
+		WithObjects(
+			&v1alpha1.ENIConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "us-west-2a"},
+				Spec:       v1alpha1.ENIConfigSpec{Subnet: "subnet-a", SecurityGroups: []string{"sg-1"}},
+			},
+			&v1alpha1.ENIConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "us-west-2b"},
+				Spec:       v1alpha1.ENIConfigSpec{Subnet: "subnet-b", SecurityGroups: []string{"sg-2"}},
+			},
+			// Validated recently, so skipped
+			&v1alpha1.ENIConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "us-west-2c"},
+				Spec:       v1alpha1.ENIConfigSpec{Subnet: "subnet-c"},
+				Status:     v1alpha1.ENIConfigStatus{LastValidatedTime: &validated},
+			},
+		).Build()
+	mockContext := &IPAMContext{
+		nholuongutClient: m.nholuongututils,
+		k8sClient: k8sClient,
+	}
+
+	node := func(name, eniConfig, zone string) corev1.Node {
+		return corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"k8s.amazonnholuongut.com/eniConfig": eniConfig, corev1.LabelTopologyZone: zone},
+		}}
+	}
+	listNodes := func(context.Context) ([]corev1.Node, error) {
+		return []corev1.Node{
+			node("node-1", "us-west-2a", "us-west-2a"),
+			node("node-2", "us-west-2a", "us-west-2a"),
+			node("node-3", "us-west-2b", "us-west-2a"),
+		}, nil
+	}
+
+	m.nholuongututils.EXPECT().GetSubnetsFromEC2(gomock.InAnyOrder([]string{"subnet-a", "subnet-b"})).Return([]*ec2.Subnet{
+		{SubnetId: nholuongut.String("subnet-a"), VpcId: nholuongut.String("vpc-1"), AvailabilityZone: nholuongut.String("us-west-2a"), AvailableIpAddressCount: nholuongut.Int64(100)},
+		{SubnetId: nholuongut.String("subnet-b"), VpcId: nholuongut.String("vpc-1"), AvailabilityZone: nholuongut.String("us-west-2b"), AvailableIpAddressCount: nholuongut.Int64(100)},
+	}, nil)
+	m.nholuongututils.EXPECT().GetSecurityGroupsFromEC2(gomock.InAnyOrder([]string{"sg-1", "sg-2"})).Return([]*ec2.SecurityGroup{
+		{GroupId: nholuongut.String("sg-1"), VpcId: nholuongut.String("vpc-1")},
+	}, nil)
+	m.nholuongututils.EXPECT().GetVPCID().Return("vpc-1").AnyTimes()
+
+	err := mockContext.updateENIConfigStatus(ctx, listNodes, now)
+	assert.NoError(t, err)
+
+	var eniConfig v1alpha1.ENIConfig
+	assert.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: "us-west-2a"}, &eniConfig))
+	assert.Equal(t, 2, eniConfig.Status.NodeCount)
+	assert.Equal(t, int64(100), *eniConfig.Status.AvailableIPAddressCount)
+	assert.True(t, meta.IsStatusConditionTrue(eniConfig.Status.Conditions, v1alpha1.ENIConfigReady))
+
+	assert.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: "us-west-2b"}, &eniConfig))
+	assert.Equal(t, 1, eniConfig.Status.NodeCount)
+	assert.False(t, meta.IsStatusConditionTrue(eniConfig.Status.Conditions, v1alpha1.ENIConfigSubnetValid))
+	assert.False(t, meta.IsStatusConditionTrue(eniConfig.Status.Conditions, v1alpha1.ENIConfigSecurityGroupsValid))
+	assert.False(t, meta.IsStatusConditionTrue(eniConfig.Status.Conditions, v1alpha1.ENIConfigReady))
+
+	assert.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: "us-west-2c"}, &eniConfig))
+	assert.Empty(t, eniConfig.Status.Conditions)
+
+	// Nothing is due anymore
+	err = mockContext.updateENIConfigStatus(ctx, listNodes, now.Add(time.Minute))
+	assert.NoError(t, err)
+}
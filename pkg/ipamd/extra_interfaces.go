@@ -0,0 +1,126 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/networkutils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/rpc"
+)
+
+// extraInterfacesKey asks for interfaces besides the one set up by the CNI ADD, each with an IPv4 address of an IP
+// pool, optionally of a given ENI of the pool, e.g. [{"ifName":"net1","ipPool":"pci"},{"ifName":"net2","ipPool":"sriov","deviceNumber":2}]
+const extraInterfacesKey = "vpc.amazonnholuongut.com/extra-interfaces"
+
+// maxInterfaceNameLength is the maximum length of a linux interface name
+const maxInterfaceNameLength = 15
+
+// extraInterfaceSpec is an interface of the extra interfaces annotation
+type extraInterfaceSpec struct {
+	IfName string `json:"ifName"`
+	// IPPool is the IP pool the address is taken from, backed by the ENIConfig of the same name
+	IPPool string `json:"ipPool,omitempty"`
+	// DeviceNumber is the device number of the ENI the address is taken from, which must belong to IPPool
+	DeviceNumber *int `json:"deviceNumber,omitempty"`
+}
+
+// parseExtraInterfaces parses the value of the extra interfaces annotation of a pod whose interface is primaryIfName
+func parseExtraInterfaces(value string, primaryIfName string) ([]extraInterfaceSpec, error) {
+	var specs []extraInterfaceSpec
+	if err := json.Unmarshal([]byte(value), &specs); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s annotation", extraInterfacesKey)
+	}
+	ifNames := map[string]bool{primaryIfName: true}
+	for i, spec := range specs {
+		if spec.IfName == "" || len(spec.IfName) > maxInterfaceNameLength {
+			return nil, errors.Errorf("interface %d needs an ifName of 1 to %d characters", i, maxInterfaceNameLength)
+		}
+		if ifNames[spec.IfName] {
+			return nil, errors.Errorf("interface %s: ifName is already in use", spec.IfName)
+		}
+		ifNames[spec.IfName] = true
+		if spec.DeviceNumber != nil && *spec.DeviceNumber < 0 {
+			return nil, errors.Errorf("interface %s: deviceNumber must not be negative", spec.IfName)
+		}
+	}
+	return specs, nil
+}
+
+// assignExtraInterfaces assigns an IPv4 address to each interface of the extra interfaces annotation of the pod. On
+// failure, the addresses assigned so far are released.
+func (s *server) assignExtraInterfaces(in *rpc.AddNetworkRequest, specs []extraInterfaceSpec) ([]*rpc.PodInterface, error) {
+	var extraInterfaces []*rpc.PodInterface
+	for _, spec := range specs {
+		ipamKey := datastore.IPAMKey{
+			ContainerID: in.ContainerID,
+			IfName:      spec.IfName,
+			NetworkName: in.NetworkName,
+		}
+		ipamMetadata := datastore.IPAMMetadata{
+			K8SPodNamespace: in.K8S_POD_NAMESPACE,
+			K8SPodName:      in.K8S_POD_NAME,
+			HostVethName:    networkutils.GeneratePodExtraHostVethName(s.ipamContext.vethPrefix, in.K8S_POD_NAMESPACE, in.K8S_POD_NAME, spec.IfName),
+			IPPool:          spec.IPPool,
+		}
+		var ipv4Addr string
+		deviceNumber := datastore.AnyDeviceNumber
+		var err error
+		if !s.ipamContext.hasIPPool(spec.IPPool) {
+			err = errors.Errorf("IP pool %q is not configured on this node", spec.IPPool)
+		} else if spec.DeviceNumber != nil {
+			deviceNumber = *spec.DeviceNumber
+			ipv4Addr, err = s.ipamContext.dataStore.AssignPodIPv4AddressOnDevice(ipamKey, ipamMetadata, deviceNumber)
+		} else {
+			ipv4Addr, deviceNumber, err = s.ipamContext.dataStore.AssignPodIPv4Address(ipamKey, ipamMetadata)
+		}
+		if err != nil {
+			for _, extraInterface := range extraInterfaces {
+				s.releaseExtraInterface(datastore.IPAMKey{ContainerID: in.ContainerID, IfName: extraInterface.IfName, NetworkName: in.NetworkName})
+			}
+			return nil, errors.Wrapf(err, "failed to assign an address to interface %s", spec.IfName)
+		}
+		log.Infof("Assigned %s of device %d to interface %s of sandbox %s", ipv4Addr, deviceNumber, spec.IfName, in.ContainerID)
+		extraInterfaces = append(extraInterfaces, &rpc.PodInterface{IfName: spec.IfName, IPv4Addr: ipv4Addr, DeviceNumber: int32(deviceNumber)})
+	}
+	return extraInterfaces, nil
+}
+
+// releaseExtraInterfaces releases the addresses of the interfaces of the sandbox besides the one of ipamKey
+func (s *server) releaseExtraInterfaces(ipamKey datastore.IPAMKey) []*rpc.PodInterface {
+	var extraInterfaces []*rpc.PodInterface
+	for _, key := range s.ipamContext.dataStore.SandboxInterfaces(ipamKey.NetworkName, ipamKey.ContainerID) {
+		if key.IfName == ipamKey.IfName {
+			continue
+		}
+		if extraInterface := s.releaseExtraInterface(key); extraInterface != nil {
+			extraInterfaces = append(extraInterfaces, extraInterface)
+		}
+	}
+	return extraInterfaces
+}
+
+// releaseExtraInterface releases the address of an extra interface, and returns it unless it was released already
+func (s *server) releaseExtraInterface(ipamKey datastore.IPAMKey) *rpc.PodInterface {
+	eni, ipv4Addr, _, deviceNumber, err := s.ipamContext.dataStore.UnassignPodIPAddress(ipamKey)
+	if err != nil {
+		log.Warnf("Failed to release the address of interface %s of sandbox %s: %v", ipamKey.IfName, ipamKey.ContainerID, err)
+		return nil
+	}
+	s.tryFreeReleasedIPv4Address(eni, ipv4Addr)
+	return &rpc.PodInterface{IfName: ipamKey.IfName, IPv4Addr: ipv4Addr, DeviceNumber: int32(deviceNumber)}
+}
@@ -0,0 +1,148 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	pb "github.com/nholuongut/amazon-vpc-cni-k8s/rpc"
+)
+
+func TestParseExtraInterfaces(t *testing.T) {
+	tests := []struct {
+		name    string
+		val     string
+		want    int
+		wantErr string
+	}{
+		{name: "pool and device", val: `[{"ifName":"net1","ipPool":"pci"},{"ifName":"net2","deviceNumber":2}]`, want: 2},
+		{name: "default pool", val: `[{"ifName":"net1"}]`, want: 1},
+		{name: "not json", val: `net1`, wantErr: "invalid vpc.amazonnholuongut.com/extra-interfaces annotation"},
+		{name: "no ifName", val: `[{"ipPool":"pci"}]`, wantErr: "interface 0 needs an ifName"},
+		{name: "long ifName", val: `[{"ifName":"net1234567890123"}]`, wantErr: "interface 0 needs an ifName"},
+		{name: "primary ifName", val: `[{"ifName":"eth0"}]`, wantErr: "interface eth0: ifName is already in use"},
+		{name: "duplicate ifName", val: `[{"ifName":"net1"},{"ifName":"net1"}]`, wantErr: "interface net1: ifName is already in use"},
+		{name: "device of a pool", val: `[{"ifName":"net1","ipPool":"pci","deviceNumber":1}]`, want: 1},
+		{name: "negative device number", val: `[{"ifName":"net1","deviceNumber":-1}]`, wantErr: "must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseExtraInterfaces(tt.val, "eth0")
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_ExtraInterfacesPod(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	assert.NoError(t, ds.AddENI("eni-1", 0, true, false, false))
+	assert.NoError(t, ds.AddENIToPool("eni-2", 1, false, false, false, "pci"))
+	assert.NoError(t, ds.AddENIToPool("eni-3", 2, false, false, false, "sriov"))
+	for eni, addr := range map[string]string{"eni-1": "10.0.0.1", "eni-2": "10.1.0.1", "eni-3": "10.2.0.1"} {
+		assert.NoError(t, ds.AddIPv4CidrToStore(eni, net.IPNet{IP: net.ParseIP(addr), Mask: net.CIDRMask(32, 32)}, false))
+	}
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP("10.0.0.2"), Mask: net.CIDRMask(32, 32)}, false))
+	m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return([]string{"10.0.0.0/8"}, nil)
+	m.network.EXPECT().UseExternalSNAT().Return(true)
+
+	s := &server{
+		version: "1.2.3",
+		ipamContext: &IPAMContext{
+			nholuongutClient:     m.nholuongututils,
+			k8sClient:     m.k8sClient,
+			networkClient: m.network,
+			dataStore:     ds,
+			enableIPv4:    true,
+			maxIPsPerENI:  14,
+			vethPrefix:    "eni",
+			ipPools:       []string{"pci", "sriov"},
+		},
+	}
+
+	assert.NoError(t, m.k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}))
+	for _, name := range []string{"nfv-0", "nfv-1"} {
+		assert.NoError(t, m.k8sClient.Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				Annotations: map[string]string{extraInterfacesKey: `[{"ifName":"net1","ipPool":"pci"},{"ifName":"net2","ipPool":"sriov","deviceNumber":2}]`},
+			},
+		}))
+	}
+	addRequest := func(name string) *pb.AddNetworkRequest {
+		return &pb.AddNetworkRequest{
+			ClientVersion:     "1.2.3",
+			K8S_POD_NAME:      name,
+			K8S_POD_NAMESPACE: "default",
+			Netns:             "netns",
+			NetworkName:       "net0",
+			ContainerID:       "cid-" + name,
+			IfName:            "eth0",
+		}
+	}
+
+	resp, err := s.AddNetwork(ctx, addRequest("nfv-0"))
+	assert.NoError(t, err)
+	assert.True(t, resp.Success)
+	primaryIPv4Addr := resp.IPv4Addr
+	assert.Contains(t, []string{"10.0.0.1", "10.0.0.2"}, primaryIPv4Addr)
+	wantExtraInterfaces := []*pb.PodInterface{
+		{IfName: "net1", IPv4Addr: "10.1.0.1", DeviceNumber: 1},
+		{IfName: "net2", IPv4Addr: "10.2.0.1", DeviceNumber: 2},
+	}
+	assert.Len(t, resp.ExtraInterfaces, 2)
+	for i, extraInterface := range resp.ExtraInterfaces {
+		assert.Equal(t, wantExtraInterfaces[i].String(), extraInterface.String())
+	}
+	assert.Equal(t, 3, ds.GetIPStats(ipV4AddrFamily).AssignedIPs)
+
+	// The pool of the second pod is exhausted, so its address is released as well
+	_, err = s.AddNetwork(ctx, addRequest("nfv-1"))
+	assert.ErrorContains(t, err, "failed to assign an address to interface net1")
+	assert.Equal(t, 3, ds.GetIPStats(ipV4AddrFamily).AssignedIPs)
+
+	delResp, err := s.DelNetwork(ctx, &pb.DelNetworkRequest{
+		ClientVersion:     "1.2.3",
+		K8S_POD_NAME:      "nfv-0",
+		K8S_POD_NAMESPACE: "default",
+		NetworkName:       "net0",
+		ContainerID:       "cid-nfv-0",
+		IfName:            "eth0",
+	})
+	assert.NoError(t, err)
+	assert.True(t, delResp.Success)
+	assert.Equal(t, primaryIPv4Addr, delResp.IPv4Addr)
+	var released []string
+	for _, extraInterface := range delResp.ExtraInterfaces {
+		released = append(released, extraInterface.String())
+	}
+	assert.ElementsMatch(t, []string{wantExtraInterfaces[0].String(), wantExtraInterfaces[1].String()}, released)
+	assert.Equal(t, 0, ds.GetIPStats(ipV4AddrFamily).AssignedIPs)
+}
@@ -0,0 +1,184 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/networkutils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/eventrecorder"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/utils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/utils/prometheusmetrics"
+)
+
+const (
+	// envHostNetworkReconcileInterval (default 60 seconds) is how often the ip rules, routes and iptables rules set up
+	// by ipamd and the CNI plugin are checked, and added back when they are missing. 0 disables the check.
+	envHostNetworkReconcileInterval     = "HOST_NETWORK_RECONCILE_INTERVAL"
+	defaultHostNetworkReconcileInterval = 60
+
+	// maxDriftsPerEvent is the number of repairs named in an event, the others are only counted
+	maxDriftsPerEvent = 10
+)
+
+// getHostNetworkReconcileInterval returns the interval of the host network reconciler, 0 if it is disabled
+func getHostNetworkReconcileInterval() time.Duration {
+	seconds, _, _ := utils.GetIntFromStringEnvVar(envHostNetworkReconcileInterval, defaultHostNetworkReconcileInterval)
+	if seconds < 0 {
+		seconds = 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// StartHostNetworkReconciler periodically adds back the ip rules, routes and iptables rules of the node which another
+// agent removed, unless HOST_NETWORK_RECONCILE_INTERVAL is 0
+func (c *IPAMContext) StartHostNetworkReconciler() {
+	interval := getHostNetworkReconcileInterval()
+	if interval == 0 {
+		return
+	}
+	log.Infof("Host network reconciler - interval: %v", interval)
+	for !c.isTerminating() {
+		time.Sleep(interval)
+		c.reconcileHostNetwork()
+	}
+}
+
+// reconcileHostNetwork compares the host network with the state of the datastore, and repairs what is missing
+func (c *IPAMContext) reconcileHostNetwork() {
+	var drifts []networkutils.Drift
+	drifts = append(drifts, c.repairHostNetwork()...)
+	drifts = append(drifts, c.repairENINetworks()...)
+	drifts = append(drifts, c.repairPodIPRules()...)
+	if len(drifts) == 0 {
+		return
+	}
+
+	descriptions := make([]string, 0, len(drifts))
+	for _, drift := range drifts {
+		log.Warnf("Host network drift repaired: %s", drift.Description)
+		prometheusmetrics.HostNetworkRepairs.With(prometheus.Labels{"kind": string(drift.Kind)}).Inc()
+		if len(descriptions) < maxDriftsPerEvent {
+			descriptions = append(descriptions, drift.Description)
+		}
+	}
+	message := fmt.Sprintf("Repaired %d missing host network entries: %s", len(drifts), strings.Join(descriptions, ", "))
+	if len(drifts) > maxDriftsPerEvent {
+		message += fmt.Sprintf(" and %d more", len(drifts)-maxDriftsPerEvent)
+	}
+	if eventRecorder := eventrecorder.Get(); eventRecorder != nil {
+		eventRecorder.SendPodEvent(v1.EventTypeWarning, eventrecorder.EventReason, "HostNetworkRepair", message)
+	}
+}
+
+// repairHostNetwork repairs the ip rules and iptables rules of SetupHostNetwork
+func (c *IPAMContext) repairHostNetwork() []networkutils.Drift {
+	var vpcV4CIDRs []string
+	if c.enableIPv4 {
+		var err error
+		if vpcV4CIDRs, err = c.nholuongutClient.GetVPCIPv4CIDRs(); err != nil {
+			log.Warnf("Skipping the host network repair, failed to get the VPC CIDRs: %v", err)
+			return nil
+		}
+	}
+	primaryIP := c.nholuongutClient.GetLocalIPv4()
+	drifts, err := c.networkClient.RepairHostNetwork(vpcV4CIDRs, c.nholuongutClient.GetPrimaryENImac(), &primaryIP,
+		c.enableIPv4, c.enableIPv6)
+	if err != nil {
+		log.Warnf("Failed to repair the host network: %v", err)
+		ipamdErrInc("repairHostNetwork")
+	}
+	return drifts
+}
+
+// repairENINetworks repairs the route tables of the secondary ENIs of the datastore
+func (c *IPAMContext) repairENINetworks() []networkutils.Drift {
+	attachedENIs, err := c.nholuongutClient.GetAttachedENIs()
+	if err != nil {
+		log.Warnf("Skipping the ENI network repair, failed to get the attached ENIs: %v", err)
+		return nil
+	}
+	// Only ENIs which completed setupENI are in the datastore
+	dataStoreENIs := c.dataStore.GetENIInfos().ENIs
+	primaryENI := c.nholuongutClient.GetPrimaryENI()
+
+	var drifts []networkutils.Drift
+	for _, eni := range attachedENIs {
+		if eni.ENIID == primaryENI {
+			continue
+		}
+		if _, ok := dataStoreENIs[eni.ENIID]; !ok {
+			continue
+		}
+		eniIP := eni.PrimaryIPv4Address()
+		subnetCidr := eni.SubnetIPv4CIDR
+		if c.enableIPv6 && !c.enableIPv4 {
+			eniIP = eni.PrimaryIPv6Address()
+			subnetCidr = eni.SubnetIPv6CIDR
+		}
+		eniDrifts, err := c.networkClient.RepairENINetwork(eniIP, eni.MAC, eni.DeviceNumber, subnetCidr)
+		drifts = append(drifts, eniDrifts...)
+		if err != nil {
+			log.Warnf("Failed to repair the network of ENI %s: %v", eni.ENIID, err)
+			ipamdErrInc("repairENINetwork")
+		}
+	}
+	return drifts
+}
+
+// repairPodIPRules repairs the ip rules, or the routes of the pod route table in the eBPF datapath, which the CNI
+// plugin set up for the pod addresses of the datastore. The datastore is locked meanwhile, so that the rules of a pod
+// being deleted are not added back after the CNI plugin removed them: DelNetwork releases the address first.
+func (c *IPAMContext) repairPodIPRules() []networkutils.Drift {
+	var drifts []networkutils.Drift
+	c.dataStore.WithAllocatedIPs(func(ipv4s []datastore.PodIPInfo, ipv6s []datastore.PodIPInfo) {
+		var pods []networkutils.PodIPRule
+		for _, info := range ipv4s {
+			routeTable := unix.RT_TABLE_MAIN
+			if info.DeviceNumber > 0 {
+				routeTable = info.DeviceNumber + 1
+			}
+			pods = append(pods, networkutils.PodIPRule{
+				IP:           net.ParseIP(info.IP),
+				RouteTable:   routeTable,
+				HostVethName: info.IPAMMetadata.HostVethName,
+			})
+		}
+		// IPv6 addresses are always allocated from the prefix of the primary ENI
+		for _, info := range ipv6s {
+			pods = append(pods, networkutils.PodIPRule{
+				IP:           net.ParseIP(info.IP),
+				RouteTable:   unix.RT_TABLE_MAIN,
+				HostVethName: info.IPAMMetadata.HostVethName,
+			})
+		}
+		if len(pods) == 0 {
+			return
+		}
+		var err error
+		if drifts, err = c.networkClient.RepairPodIPRules(pods); err != nil {
+			log.Warnf("Failed to repair the ip rules of the pods: %v", err)
+			ipamdErrInc("repairPodIPRules")
+		}
+	})
+	return drifts
+}
@@ -0,0 +1,108 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/nholuongut/nholuongut-sdk-go/nholuongut"
+	"github.com/nholuongut/nholuongut-sdk-go/service/ec2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/networkutils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/nholuongututils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/eventrecorder"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/utils/prometheusmetrics"
+)
+
+func TestGetHostNetworkReconcileInterval(t *testing.T) {
+	defer os.Unsetenv(envHostNetworkReconcileInterval)
+	assert.Equal(t, "1m0s", getHostNetworkReconcileInterval().String())
+	os.Setenv(envHostNetworkReconcileInterval, "15")
+	assert.Equal(t, "15s", getHostNetworkReconcileInterval().String())
+	os.Setenv(envHostNetworkReconcileInterval, "-1")
+	assert.Equal(t, "0s", getHostNetworkReconcileInterval().String())
+}
+
+func TestReconcileHostNetwork(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	fakeRecorder := eventrecorder.InitMockEventRecorder()
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	assert.NoError(t, ds.AddENI(primaryENIid, 0, true, false, false))
+	assert.NoError(t, ds.AddENI(secENIid, 1, false, false, false))
+	assert.NoError(t, ds.AddIPv4CidrToStore(secENIid, net.IPNet{IP: net.ParseIP("10.0.1.5"), Mask: net.CIDRMask(32, 32)}, false))
+	_, _, err := ds.AssignPodIPv4Address(datastore.IPAMKey{NetworkName: "net0", ContainerID: "cid", IfName: "eth0"},
+		datastore.IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "pod", HostVethName: "eni1234"})
+	assert.NoError(t, err)
+
+	c := &IPAMContext{
+		nholuongutClient: m.nholuongututils,
+		networkClient:    m.network,
+		dataStore:        ds,
+		enableIPv4:       true,
+	}
+	primaryIP := net.ParseIP("10.0.0.1")
+	vpcCIDRs := []string{"10.0.0.0/16"}
+	attachedENIs := []nholuongututils.ENIMetadata{
+		{ENIID: primaryENIid, MAC: primaryMAC, DeviceNumber: 0, SubnetIPv4CIDR: "10.0.0.0/24"},
+		{
+			ENIID:          secENIid,
+			MAC:            secMAC,
+			DeviceNumber:   1,
+			SubnetIPv4CIDR: "10.0.1.0/24",
+			IPv4Addresses: []*ec2.NetworkInterfacePrivateIpAddress{
+				{PrivateIpAddress: nholuongut.String("10.0.1.1"), Primary: nholuongut.Bool(true)},
+			},
+		},
+		// ENIs outside of the datastore, such as unmanaged ones, are left alone
+		{ENIID: "eni-unmanaged", MAC: "02:00:00:00:00:09", DeviceNumber: 3, SubnetIPv4CIDR: "10.0.3.0/24"},
+	}
+	m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return(vpcCIDRs, nil).Times(2)
+	m.nholuongututils.EXPECT().GetLocalIPv4().Return(primaryIP).Times(2)
+	m.nholuongututils.EXPECT().GetPrimaryENImac().Return(primaryMAC).Times(2)
+	m.nholuongututils.EXPECT().GetAttachedENIs().Return(attachedENIs, nil).Times(2)
+	m.nholuongututils.EXPECT().GetPrimaryENI().Return(primaryENIid).Times(2)
+	podRules := []networkutils.PodIPRule{{IP: net.ParseIP("10.0.1.5"), RouteTable: 2, HostVethName: "eni1234"}}
+
+	// Nothing drifted
+	m.network.EXPECT().RepairHostNetwork(vpcCIDRs, primaryMAC, &primaryIP, true, false).Return(nil, nil)
+	m.network.EXPECT().RepairENINetwork("10.0.1.1", secMAC, 1, "10.0.1.0/24").Return(nil, nil)
+	m.network.EXPECT().RepairPodIPRules(podRules).Return(nil, nil)
+	before := testutil.ToFloat64(prometheusmetrics.HostNetworkRepairs.With(prometheus.Labels{"kind": "ip_rule"}))
+	c.reconcileHostNetwork()
+	assert.Empty(t, fakeRecorder.Events)
+
+	// Each repair is counted, and named in an event
+	m.network.EXPECT().RepairHostNetwork(vpcCIDRs, primaryMAC, &primaryIP, true, false).Return([]networkutils.Drift{
+		{Kind: networkutils.DriftKindIptables, Description: "iptables chain nat/nholuongut-SNAT-CHAIN-0"},
+	}, nil)
+	m.network.EXPECT().RepairENINetwork("10.0.1.1", secMAC, 1, "10.0.1.0/24").Return([]networkutils.Drift{
+		{Kind: networkutils.DriftKindRoute, Description: "route 0.0.0.0/0 via 10.0.1.1 table 2"},
+	}, nil)
+	m.network.EXPECT().RepairPodIPRules(podRules).Return([]networkutils.Drift{
+		{Kind: networkutils.DriftKindIPRule, Description: "ip rule 512: to 10.0.1.5/32 lookup 254"},
+		{Kind: networkutils.DriftKindIPRule, Description: "ip rule 1536: from 10.0.1.5/32 lookup 2"},
+	}, nil)
+	c.reconcileHostNetwork()
+	assert.Equal(t, before+2, testutil.ToFloat64(prometheusmetrics.HostNetworkRepairs.With(prometheus.Labels{"kind": "ip_rule"})))
+	assertEvent(t, fakeRecorder, "Warning", "Repaired 4 missing host network entries: iptables chain nat/nholuongut-SNAT-CHAIN-0, "+
+		"route 0.0.0.0/0 via 10.0.1.1 table 2, ip rule 512: to 10.0.1.5/32 lookup 254, ip rule 1536: from 10.0.1.5/32 lookup 2")
+}
@@ -78,7 +78,13 @@ func (c *IPAMContext) setupIntrospectionServer() *http.Server {
 		"/v1/enis":                      eniV1RequestHandler(c),
 		"/v1/eni-configs":               eniConfigRequestHandler(c),
 		"/v1/networkutils-env-settings": networkEnvV1RequestHandler(),
-		"/v1/ipamd-env-settings":        ipamdEnvV1RequestHandler(),
+		"/v1/ipamd-env-settings":        ipamdEnvV1RequestHandler(c),
+		"/v1/ipamd-effective-settings":  ipamdEffectiveV1RequestHandler(c),
+		"/v1/warm-pool-profiles":        warmPoolProfilesV1RequestHandler(c),
+		"/v2/allocations":               allocationsV2RequestHandler(c),
+		"/v2/enis":                      enisV2RequestHandler(c),
+		"/v2/pool-target":               poolTargetV2RequestHandler(c),
+		"/v2/pool-actions":              poolActionsV2RequestHandler(c),
 	}
 	paths := make([]string, 0, len(serverFunctions))
 	for path := range serverFunctions {
@@ -170,9 +176,9 @@ func networkEnvV1RequestHandler() func(http.ResponseWriter, *http.Request) {
 	}
 }
 
-func ipamdEnvV1RequestHandler() func(http.ResponseWriter, *http.Request) {
+func ipamdEnvV1RequestHandler(ipam *IPAMContext) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		responseJSON, err := json.Marshal(GetConfigForDebug())
+		responseJSON, err := json.Marshal(ipam.GetConfigForDebug())
 		if err != nil {
 			log.Errorf("Failed to marshal ipamd env var data: %v", err)
 			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
@@ -182,6 +188,30 @@ func ipamdEnvV1RequestHandler() func(http.ResponseWriter, *http.Request) {
 	}
 }
 
+func ipamdEffectiveV1RequestHandler(ipam *IPAMContext) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responseJSON, err := json.Marshal(ipam.getEffectiveConfig())
+		if err != nil {
+			log.Errorf("Failed to marshal ipamd effective settings: %v", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		logErr(w.Write(responseJSON))
+	}
+}
+
+func warmPoolProfilesV1RequestHandler(ipam *IPAMContext) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responseJSON, err := json.Marshal(ipam.getWarmPoolSchedule())
+		if err != nil {
+			log.Errorf("Failed to marshal warm pool profiles: %v", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		logErr(w.Write(responseJSON))
+	}
+}
+
 func logErr(_ int, err error) {
 	if err != nil {
 		log.Errorf("Write failed: %v", err)
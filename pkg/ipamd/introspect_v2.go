@@ -0,0 +1,417 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//      http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+)
+
+// The /v2 introspection endpoints serve types of their own rather than the internal structs, so that their schema,
+// documented in docs/introspection-api-v2.md, stays stable when the datastore changes.
+
+const (
+	// maxPageSizeV2 is the page size of the /v2 list endpoints, and the largest limit they accept
+	maxPageSizeV2 = 500
+)
+
+// allocationV2 is the allocation of the addresses of a pod interface
+type allocationV2 struct {
+	// NetworkName, ContainerID and IfName identify the sandbox interface, they are empty for released addresses
+	NetworkName string `json:"networkName,omitempty"`
+	ContainerID string `json:"containerID,omitempty"`
+	IfName      string `json:"ifName,omitempty"`
+
+	PodNamespace string `json:"podNamespace,omitempty"`
+	PodName      string `json:"podName,omitempty"`
+	HostVethName string `json:"hostVethName,omitempty"`
+	IPPool       string `json:"ipPool,omitempty"`
+	StickyIP     bool   `json:"stickyIP,omitempty"`
+
+	IPv4     string `json:"ipv4,omitempty"`
+	IPv4ENI  string `json:"ipv4ENI,omitempty"`
+	IPv4Cidr string `json:"ipv4Cidr,omitempty"`
+	IPv6     string `json:"ipv6,omitempty"`
+	IPv6ENI  string `json:"ipv6ENI,omitempty"`
+	IPv6Cidr string `json:"ipv6Cidr,omitempty"`
+	// DeviceNumber is the device number of the ENI of the pod, -1 for pods using a branch ENI
+	DeviceNumber int `json:"deviceNumber"`
+	// VlanID is the VLAN of the branch ENI of pods using security groups for pods
+	VlanID int `json:"vlanID,omitempty"`
+
+	AllocationTime time.Time `json:"allocationTime"`
+	// ReleaseTime is set on the addresses held for a deleted sticky IP pod
+	ReleaseTime *time.Time `json:"releaseTime,omitempty"`
+}
+
+// allocationListV2 is a page of allocations
+type allocationListV2 struct {
+	Items []allocationV2 `json:"items"`
+	// Continue is passed as the continue parameter to get the next page, it is empty on the last page
+	Continue string `json:"continue,omitempty"`
+}
+
+// eniV2 is an ENI of the datastore
+type eniV2 struct {
+	ID           string   `json:"id"`
+	IPPool       string   `json:"ipPool,omitempty"`
+	DeviceNumber int      `json:"deviceNumber"`
+	IsPrimary    bool     `json:"isPrimary"`
+	IsTrunk      bool     `json:"isTrunk"`
+	IsEFA        bool     `json:"isEFA"`
+	TotalIPs     int      `json:"totalIPs"`
+	AssignedIPs  int      `json:"assignedIPs"`
+	Cidrs        []cidrV2 `json:"cidrs"`
+}
+
+// cidrV2 is a secondary IP (/32) or prefix of an ENI
+type cidrV2 struct {
+	Cidr      string      `json:"cidr"`
+	IsPrefix  bool        `json:"isPrefix"`
+	Addresses []addressV2 `json:"addresses"`
+}
+
+// addressV2 is an address of a CIDR of an ENI
+type addressV2 struct {
+	Address  string `json:"address"`
+	Assigned bool   `json:"assigned"`
+	// ContainerID, PodNamespace and PodName are set if the address is assigned
+	ContainerID  string `json:"containerID,omitempty"`
+	PodNamespace string `json:"podNamespace,omitempty"`
+	PodName      string `json:"podName,omitempty"`
+}
+
+// eniListV2 is a page of ENIs
+type eniListV2 struct {
+	Items    []eniV2 `json:"items"`
+	Continue string  `json:"continue,omitempty"`
+}
+
+// poolTargetStateV2 is how far the IP pool is from its warm targets
+type poolTargetStateV2 struct {
+	IPPool string `json:"ipPool"`
+	// WarmIPTargetsEnabled is set if WARM_IP_TARGET or MINIMUM_IP_TARGET is, Short and Over are only computed then
+	WarmIPTargetsEnabled bool `json:"warmIPTargetsEnabled"`
+	// Short is the number of IPs, or prefixes with prefix delegation, missing to reach the targets
+	Short int `json:"short"`
+	// Over is the number of IPs, or prefixes with prefix delegation, beyond the targets
+	Over int `json:"over"`
+	// WarmPrefixTargetEnabled is set if WARM_PREFIX_TARGET is, ShortPrefixes is only computed then
+	WarmPrefixTargetEnabled bool `json:"warmPrefixTargetEnabled"`
+	ShortPrefixes           int  `json:"shortPrefixes"`
+	TotalIPs                int  `json:"totalIPs"`
+	TotalPrefixes           int  `json:"totalPrefixes"`
+	AssignedIPs             int  `json:"assignedIPs"`
+	AvailableIPs            int  `json:"availableIPs"`
+	CooldownIPs             int  `json:"cooldownIPs"`
+	ReservedIPs             int  `json:"reservedIPs"`
+}
+
+// poolTargetV2 is the pool target state of the IP pools and the targets it is computed from
+type poolTargetV2 struct {
+	EnablePrefixDelegation bool                `json:"enablePrefixDelegation"`
+	WarmENITarget          int                 `json:"warmENITarget"`
+	WarmIPTarget           int                 `json:"warmIPTarget"`
+	MinimumIPTarget        int                 `json:"minimumIPTarget"`
+	WarmPrefixTarget       int                 `json:"warmPrefixTarget"`
+	Pools                  []poolTargetStateV2 `json:"pools"`
+}
+
+// poolActionListV2 are the recent pool actions, the most recent first
+type poolActionListV2 struct {
+	Items []poolAction `json:"items"`
+}
+
+func allocationsV2RequestHandler(ipam *IPAMContext) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		limit, err := parseLimitV2(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ip := query.Get("ip")
+		if ip != "" && net.ParseIP(ip) == nil {
+			http.Error(w, fmt.Sprintf("invalid ip %q", ip), http.StatusBadRequest)
+			return
+		}
+		namespace, name, containerID := query.Get("namespace"), query.Get("name"), query.Get("containerID")
+
+		var allocations []allocationV2
+		for _, entry := range ipam.dataStore.Allocations() {
+			allocation := newAllocationV2(entry)
+			if (namespace != "" && allocation.PodNamespace != namespace) ||
+				(name != "" && allocation.PodName != name) ||
+				(containerID != "" && allocation.ContainerID != containerID) ||
+				(ip != "" && !sameIP(ip, allocation.IPv4) && !sameIP(ip, allocation.IPv6)) {
+				continue
+			}
+			allocations = append(allocations, allocation)
+		}
+		items, next := paginateV2(allocations, allocationV2Key, limit, query.Get("continue"))
+		writeJSONV2(w, &allocationListV2{Items: items, Continue: next})
+	}
+}
+
+func enisV2RequestHandler(ipam *IPAMContext) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		limit, err := parseLimitV2(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		requirements, err := parseFieldSelectorV2(query.Get("fieldSelector"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var enis []eniV2
+		for _, eni := range ipam.dataStore.GetENIInfos().ENIs {
+			info := newENIV2(eni)
+			if matchesFieldSelectorV2(&info, requirements) {
+				enis = append(enis, info)
+			}
+		}
+		items, next := paginateV2(enis, func(eni eniV2) string { return eni.ID }, limit, query.Get("continue"))
+		writeJSONV2(w, &eniListV2{Items: items, Continue: next})
+	}
+}
+
+func poolTargetV2RequestHandler(ipam *IPAMContext) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSONV2(w, ipam.getPoolTargetV2())
+	}
+}
+
+func poolActionsV2RequestHandler(ipam *IPAMContext) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, err := parseLimitV2(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSONV2(w, &poolActionListV2{Items: ipam.poolActions.recent(limit)})
+	}
+}
+
+// getPoolTargetV2 returns the output of datastoreTargetState and datastorePrefixTargetState for each IP pool
+func (c *IPAMContext) getPoolTargetV2() *poolTargetV2 {
+	c.configLock.RLock()
+	defer c.configLock.RUnlock()
+	target := &poolTargetV2{
+		EnablePrefixDelegation: c.enablePrefixDelegation,
+		WarmENITarget:          c.warmENITarget,
+		WarmIPTarget:           c.effectiveWarmIPTarget(),
+		MinimumIPTarget:        c.minimumIPTarget,
+		WarmPrefixTarget:       c.warmPrefixTarget,
+		Pools:                  []poolTargetStateV2{},
+	}
+	for _, ipPool := range c.allIPPools() {
+		stats := c.dataStore.GetPoolIPStats(ipPool, ipV4AddrFamily)
+		state := poolTargetStateV2{
+			IPPool:        ipPool,
+			TotalIPs:      stats.TotalIPs,
+			TotalPrefixes: stats.TotalPrefixes,
+			AssignedIPs:   stats.AssignedIPs,
+			AvailableIPs:  stats.AvailableAddresses(),
+			CooldownIPs:   stats.CooldownIPs,
+			ReservedIPs:   stats.ReservedIPs,
+		}
+		state.Short, state.Over, state.WarmIPTargetsEnabled = c.datastoreTargetState(stats)
+		state.ShortPrefixes, state.WarmPrefixTargetEnabled = c.datastorePrefixTargetState(ipPool)
+		target.Pools = append(target.Pools, state)
+	}
+	return target
+}
+
+func newAllocationV2(entry datastore.CheckpointEntry) allocationV2 {
+	allocation := allocationV2{
+		PodNamespace:   entry.Metadata.K8SPodNamespace,
+		PodName:        entry.Metadata.K8SPodName,
+		HostVethName:   entry.Metadata.HostVethName,
+		IPPool:         entry.Metadata.IPPool,
+		StickyIP:       entry.Metadata.StickyIP,
+		IPv4:           entry.IPv4,
+		IPv4ENI:        entry.IPv4ENI,
+		IPv4Cidr:       entry.IPv4Cidr,
+		IPv6:           entry.IPv6,
+		IPv6ENI:        entry.IPv6ENI,
+		IPv6Cidr:       entry.IPv6Cidr,
+		DeviceNumber:   entry.DeviceNumber,
+		VlanID:         entry.VlanID,
+		AllocationTime: time.Unix(0, entry.AllocationTimestamp).UTC(),
+	}
+	// Released addresses are keyed by the pod identity rather than by a sandbox
+	if entry.ReleaseTimestamp != 0 {
+		releaseTime := time.Unix(0, entry.ReleaseTimestamp).UTC()
+		allocation.ReleaseTime = &releaseTime
+	} else {
+		allocation.NetworkName = entry.NetworkName
+		allocation.ContainerID = entry.ContainerID
+		allocation.IfName = entry.IfName
+	}
+	return allocation
+}
+
+// allocationV2Key orders the allocations by pod, and by sandbox interface
+func allocationV2Key(allocation allocationV2) string {
+	return strings.Join([]string{allocation.PodNamespace, allocation.PodName, allocation.NetworkName,
+		allocation.ContainerID, allocation.IfName}, "/")
+}
+
+func newENIV2(eni datastore.ENI) eniV2 {
+	ret := eniV2{
+		ID:           eni.ID,
+		IPPool:       eni.IPPool,
+		DeviceNumber: eni.DeviceNumber,
+		IsPrimary:    eni.IsPrimary,
+		IsTrunk:      eni.IsTrunk,
+		IsEFA:        eni.IsEFA,
+		Cidrs:        []cidrV2{},
+	}
+	for _, cidrInfos := range []map[string]*datastore.CidrInfo{eni.AvailableIPv4Cidrs, eni.IPv6Cidrs} {
+		for _, cidrInfo := range cidrInfos {
+			cidr := cidrV2{Cidr: cidrInfo.Cidr.String(), IsPrefix: cidrInfo.IsPrefix, Addresses: []addressV2{}}
+			for _, addr := range cidrInfo.IPAddresses {
+				address := addressV2{Address: addr.Address, Assigned: addr.Assigned()}
+				if address.Assigned {
+					address.ContainerID = addr.IPAMKey.ContainerID
+					address.PodNamespace = addr.IPAMMetadata.K8SPodNamespace
+					address.PodName = addr.IPAMMetadata.K8SPodName
+					ret.AssignedIPs++
+				}
+				cidr.Addresses = append(cidr.Addresses, address)
+			}
+			sort.Slice(cidr.Addresses, func(i, j int) bool { return cidr.Addresses[i].Address < cidr.Addresses[j].Address })
+			ret.TotalIPs += len(cidr.Addresses)
+			ret.Cidrs = append(ret.Cidrs, cidr)
+		}
+	}
+	sort.Slice(ret.Cidrs, func(i, j int) bool { return ret.Cidrs[i].Cidr < ret.Cidrs[j].Cidr })
+	return ret
+}
+
+// eniFieldsV2 are the fields of the ENIs the field selector of /v2/enis can match
+var eniFieldsV2 = map[string]func(eni *eniV2) string{
+	"id":           func(eni *eniV2) string { return eni.ID },
+	"ipPool":       func(eni *eniV2) string { return eni.IPPool },
+	"deviceNumber": func(eni *eniV2) string { return strconv.Itoa(eni.DeviceNumber) },
+	"isPrimary":    func(eni *eniV2) string { return strconv.FormatBool(eni.IsPrimary) },
+	"isTrunk":      func(eni *eniV2) string { return strconv.FormatBool(eni.IsTrunk) },
+	"isEFA":        func(eni *eniV2) string { return strconv.FormatBool(eni.IsEFA) },
+}
+
+// fieldRequirementV2 is a field=value or field!=value term of a field selector
+type fieldRequirementV2 struct {
+	field string
+	value string
+	equal bool
+}
+
+// parseFieldSelectorV2 parses a comma separated list of field=value and field!=value terms, as the field selectors
+// of the Kubernetes API
+func parseFieldSelectorV2(selector string) ([]fieldRequirementV2, error) {
+	if selector == "" {
+		return nil, nil
+	}
+	var requirements []fieldRequirementV2
+	for _, term := range strings.Split(selector, ",") {
+		requirement := fieldRequirementV2{equal: true}
+		var ok bool
+		if requirement.field, requirement.value, ok = strings.Cut(term, "!="); ok {
+			requirement.equal = false
+		} else if requirement.field, requirement.value, ok = strings.Cut(term, "=="); !ok {
+			if requirement.field, requirement.value, ok = strings.Cut(term, "="); !ok {
+				return nil, fmt.Errorf("invalid field selector term %q", term)
+			}
+		}
+		requirement.field = strings.TrimSpace(requirement.field)
+		requirement.value = strings.TrimSpace(requirement.value)
+		if _, ok := eniFieldsV2[requirement.field]; !ok {
+			return nil, fmt.Errorf("unknown field %q in field selector", requirement.field)
+		}
+		requirements = append(requirements, requirement)
+	}
+	return requirements, nil
+}
+
+func matchesFieldSelectorV2(eni *eniV2, requirements []fieldRequirementV2) bool {
+	for _, requirement := range requirements {
+		if (eniFieldsV2[requirement.field](eni) == requirement.value) != requirement.equal {
+			return false
+		}
+	}
+	return true
+}
+
+// parseLimitV2 returns the limit parameter, maxPageSizeV2 if it is not set
+func parseLimitV2(query url.Values) (int, error) {
+	value := query.Get("limit")
+	if value == "" {
+		return maxPageSizeV2, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 || limit > maxPageSizeV2 {
+		return 0, fmt.Errorf("invalid limit %q, must be between 1 and %d", value, maxPageSizeV2)
+	}
+	return limit, nil
+}
+
+// paginateV2 sorts the items by key, and returns up to limit of them after the continue token, along with the token
+// of the next page. The token is the key of the last item returned, so that pages stay consistent while items are
+// added and removed between requests.
+func paginateV2[T any](items []T, key func(T) string, limit int, continueToken string) ([]T, string) {
+	sort.Slice(items, func(i, j int) bool { return key(items[i]) < key(items[j]) })
+	start := sort.Search(len(items), func(i int) bool { return key(items[i]) > continueToken })
+	if continueToken == "" {
+		start = 0
+	}
+	items = items[start:]
+	if len(items) <= limit {
+		if items == nil {
+			items = []T{}
+		}
+		return items, ""
+	}
+	items = items[:limit]
+	return items, key(items[limit-1])
+}
+
+// sameIP returns true if the address strings are the same IP, whatever their notation
+func sameIP(a, b string) bool {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	return ipA != nil && ipA.Equal(ipB)
+}
+
+func writeJSONV2(w http.ResponseWriter, v interface{}) {
+	responseJSON, err := json.Marshal(v)
+	if err != nil {
+		log.Errorf("Failed to marshal introspection response: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	logErr(w.Write(responseJSON))
+}
@@ -0,0 +1,184 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+)
+
+// introspectionV2Context is a node with two pods on the primary ENI and a free IP on an ENI of the "blue" IP pool
+func introspectionV2Context(t *testing.T) *IPAMContext {
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	assert.NoError(t, ds.AddENI(primaryENIid, 0, true, false, false))
+	assert.NoError(t, ds.AddENIToPool(secENIid, 1, false, false, false, "blue"))
+	for _, ip := range []string{ipaddr01, ipaddr02} {
+		assert.NoError(t, ds.AddIPv4CidrToStore(primaryENIid, net.IPNet{IP: net.ParseIP(ip), Mask: net.CIDRMask(32, 32)}, false))
+	}
+	assert.NoError(t, ds.AddIPv4CidrToStore(secENIid, net.IPNet{IP: net.ParseIP(ipaddr11), Mask: net.CIDRMask(32, 32)}, false))
+	for _, pod := range []string{"pod-a", "pod-b"} {
+		_, _, err := ds.AssignPodIPv4Address(datastore.IPAMKey{NetworkName: "nholuongut-cni", ContainerID: "cid-" + pod, IfName: "eth0"},
+			datastore.IPAMMetadata{K8SPodNamespace: "default", K8SPodName: pod})
+		assert.NoError(t, err)
+	}
+	return &IPAMContext{dataStore: ds, ipPools: []string{"blue"}}
+}
+
+// getV2 serves the request with the introspection server, and decodes the response into v if it succeeded
+func getV2(t *testing.T, c *IPAMContext, uri string, v interface{}) int {
+	recorder := httptest.NewRecorder()
+	c.setupIntrospectionServer().Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, uri, nil))
+	if recorder.Code == http.StatusOK {
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), v))
+	}
+	return recorder.Code
+}
+
+func TestAllocationsV2(t *testing.T) {
+	c := introspectionV2Context(t)
+
+	var list allocationListV2
+	assert.Equal(t, http.StatusOK, getV2(t, c, "/v2/allocations", &list))
+	assert.Len(t, list.Items, 2)
+	assert.Empty(t, list.Continue)
+	assert.Equal(t, "pod-a", list.Items[0].PodName)
+	assert.Equal(t, "pod-b", list.Items[1].PodName)
+	assert.Equal(t, primaryENIid, list.Items[0].IPv4ENI)
+	assert.Equal(t, "eth0", list.Items[0].IfName)
+
+	for _, query := range []string{"namespace=default&name=pod-b", "containerID=cid-pod-b", "ip=" + list.Items[1].IPv4} {
+		list = allocationListV2{}
+		assert.Equal(t, http.StatusOK, getV2(t, c, "/v2/allocations?"+query, &list), query)
+		assert.Len(t, list.Items, 1, query)
+		assert.Equal(t, "cid-pod-b", list.Items[0].ContainerID, query)
+	}
+
+	list = allocationListV2{}
+	assert.Equal(t, http.StatusOK, getV2(t, c, "/v2/allocations?name=pod-c", &list))
+	assert.NotNil(t, list.Items)
+	assert.Empty(t, list.Items)
+
+	// Paging
+	list = allocationListV2{}
+	assert.Equal(t, http.StatusOK, getV2(t, c, "/v2/allocations?limit=1", &list))
+	assert.Len(t, list.Items, 1)
+	assert.Equal(t, "pod-a", list.Items[0].PodName)
+	assert.NotEmpty(t, list.Continue)
+	next := list.Continue
+	list = allocationListV2{}
+	assert.Equal(t, http.StatusOK, getV2(t, c, "/v2/allocations?limit=1&continue="+next, &list))
+	assert.Len(t, list.Items, 1)
+	assert.Equal(t, "pod-b", list.Items[0].PodName)
+	assert.Empty(t, list.Continue)
+
+	assert.Equal(t, http.StatusBadRequest, getV2(t, c, "/v2/allocations?limit=0", nil))
+	assert.Equal(t, http.StatusBadRequest, getV2(t, c, "/v2/allocations?limit=abc", nil))
+	assert.Equal(t, http.StatusBadRequest, getV2(t, c, "/v2/allocations?ip=10.0.0", nil))
+}
+
+func TestENIsV2(t *testing.T) {
+	c := introspectionV2Context(t)
+
+	var list eniListV2
+	assert.Equal(t, http.StatusOK, getV2(t, c, "/v2/enis", &list))
+	assert.Len(t, list.Items, 2)
+	primary := list.Items[0]
+	assert.Equal(t, primaryENIid, primary.ID)
+	assert.True(t, primary.IsPrimary)
+	assert.Equal(t, 2, primary.TotalIPs)
+	assert.Equal(t, 2, primary.AssignedIPs)
+	assert.Len(t, primary.Cidrs, 2)
+	assert.Equal(t, ipaddr01+"/32", primary.Cidrs[0].Cidr)
+	assert.True(t, primary.Cidrs[0].Addresses[0].Assigned)
+	assert.Equal(t, "default", primary.Cidrs[0].Addresses[0].PodNamespace)
+
+	for query, expected := range map[string][]string{
+		"fieldSelector=isPrimary=false":                {secENIid},
+		"fieldSelector=ipPool==blue":                   {secENIid},
+		"fieldSelector=ipPool!=blue":                   {primaryENIid},
+		"fieldSelector=deviceNumber=0,isTrunk=false":   {primaryENIid},
+		"fieldSelector=id=" + secENIid + ",isEFA=true": {},
+		"limit=1":                                   {primaryENIid},
+		"limit=1&continue=" + primaryENIid:          {secENIid},
+		"limit=5&fieldSelector=id!=" + primaryENIid: {secENIid},
+	} {
+		list = eniListV2{}
+		assert.Equal(t, http.StatusOK, getV2(t, c, "/v2/enis?"+query, &list), query)
+		ids := []string{}
+		for _, eni := range list.Items {
+			ids = append(ids, eni.ID)
+		}
+		assert.Equal(t, expected, ids, query)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, getV2(t, c, "/v2/enis?fieldSelector=subnet=foo", nil))
+	assert.Equal(t, http.StatusBadRequest, getV2(t, c, "/v2/enis?fieldSelector=isPrimary", nil))
+	assert.Equal(t, http.StatusBadRequest, getV2(t, c, "/v2/enis?limit=501", nil))
+}
+
+func TestPoolTargetV2(t *testing.T) {
+	c := introspectionV2Context(t)
+	c.warmIPTarget = 2
+
+	var target poolTargetV2
+	assert.Equal(t, http.StatusOK, getV2(t, c, "/v2/pool-target", &target))
+	assert.Equal(t, 2, target.WarmIPTarget)
+	assert.Equal(t, []poolTargetStateV2{
+		{
+			IPPool:               datastore.DefaultIPPool,
+			WarmIPTargetsEnabled: true,
+			Short:                2,
+			TotalIPs:             2,
+			AssignedIPs:          2,
+		},
+		{
+			IPPool:               "blue",
+			WarmIPTargetsEnabled: true,
+			Short:                1,
+			TotalIPs:             1,
+			AvailableIPs:         1,
+		},
+	}, target.Pools)
+}
+
+func TestPoolActionsV2(t *testing.T) {
+	c := introspectionV2Context(t)
+
+	var list poolActionListV2
+	assert.Equal(t, http.StatusOK, getV2(t, c, "/v2/pool-actions", &list))
+	assert.NotNil(t, list.Items)
+	assert.Empty(t, list.Items)
+
+	c.recordPoolAction(poolActionAllocateENI, "blue", secENIid, nil, nil)
+	c.recordPoolAction(poolActionFreeENI, "blue", secENIid, nil, errors.New("ENI is in use"))
+	assert.Equal(t, http.StatusOK, getV2(t, c, "/v2/pool-actions", &list))
+	assert.Len(t, list.Items, 2)
+	assert.Equal(t, poolActionFreeENI, list.Items[0].Action)
+	assert.Equal(t, "ENI is in use", list.Items[0].Error)
+	assert.Equal(t, poolActionAllocateENI, list.Items[1].Action)
+	assert.Empty(t, list.Items[1].Error)
+
+	assert.Equal(t, http.StatusOK, getV2(t, c, "/v2/pool-actions?limit=1", &list))
+	assert.Len(t, list.Items, 1)
+	assert.Equal(t, poolActionFreeENI, list.Items[0].Action)
+}
@@ -0,0 +1,159 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/apis/crd/v1alpha1"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/nholuongututils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/eniconfig"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+)
+
+const (
+	// envIPPools is a comma separated list of IP pools kept on the node besides the default one. The ENIs of a pool
+	// are created in the subnet and with the security groups of the ENIConfig named after the pool, and only pods
+	// asking for the pool get addresses from them.
+	envIPPools = "IP_POOLS"
+
+	// eniIPPoolTagKey is the tag set on the ENIs of an IP pool, so that they are recovered on restart
+	eniIPPoolTagKey = "node.k8s.amazonnholuongut.com/ip-pool"
+
+	// ipPoolKey is the label of a pod, or of its namespace, which selects the IP pool of the pod
+	ipPoolKey = "vpc.amazonnholuongut.com/ip-pool"
+)
+
+// getIPPools returns the IP pools configured by the IP_POOLS env variable
+func getIPPools() []string {
+	var ipPools []string
+	for _, ipPool := range strings.Split(os.Getenv(envIPPools), ",") {
+		ipPool = strings.TrimSpace(ipPool)
+		if ipPool == "" || lo.Contains(ipPools, ipPool) {
+			continue
+		}
+		ipPools = append(ipPools, ipPool)
+	}
+	return ipPools
+}
+
+// allIPPools returns the default IP pool followed by the configured ones
+func (c *IPAMContext) allIPPools() []string {
+	return append([]string{datastore.DefaultIPPool}, c.ipPools...)
+}
+
+// hasIPPool returns true if pods can get addresses from ipPool on this node
+func (c *IPAMContext) hasIPPool(ipPool string) bool {
+	return ipPool == datastore.DefaultIPPool || lo.Contains(c.ipPools, ipPool)
+}
+
+// ipPoolLogSuffix names the IP pool in log messages, but for the default one
+func ipPoolLogSuffix(ipPool string) string {
+	if ipPool == datastore.DefaultIPPool {
+		return ""
+	}
+	return fmt.Sprintf(" of IP pool %q", ipPool)
+}
+
+// setIPPoolENIs will rebuild the IP pools of the ENIs from their "ip-pool" tag
+func (c *IPAMContext) setIPPoolENIs(tagMap map[string]nholuongututils.TagMap) {
+	if len(tagMap) == 0 {
+		return
+	}
+	eniIPPools := make(map[string]string)
+	for eniID, tags := range tagMap {
+		if ipPool := tags[eniIPPoolTagKey]; ipPool != datastore.DefaultIPPool {
+			if !lo.Contains(c.ipPools, ipPool) {
+				log.Warnf("ENI %s belongs to IP pool %q which is not configured, it will be freed once unused", eniID, ipPool)
+			}
+			eniIPPools[eniID] = ipPool
+		}
+	}
+	c.eniIPPools = eniIPPools
+}
+
+// getENIIPPool returns the IP pool of the ENI
+func (c *IPAMContext) getENIIPPool(eniID string) string {
+	return c.eniIPPools[eniID]
+}
+
+// setENIIPPool records the IP pool of the ENI
+func (c *IPAMContext) setENIIPPool(eniID, ipPool string) {
+	if ipPool == datastore.DefaultIPPool {
+		delete(c.eniIPPools, eniID)
+		return
+	}
+	c.eniIPPools[eniID] = ipPool
+}
+
+// getENIConfig returns the ENIConfig which new ENIs of the IP pool are created with. For the default pool, it is the
+// ENIConfig of the node.
+func (c *IPAMContext) getENIConfig(ctx context.Context, ipPool string) (*v1alpha1.ENIConfigSpec, error) {
+	if ipPool == datastore.DefaultIPPool {
+		return eniconfig.MyENIConfig(ctx, c.k8sClient)
+	}
+	return eniconfig.GetENIConfig(ctx, c.k8sClient, ipPool)
+}
+
+// tryFreeUnconfiguredIPPoolENIs frees an unused ENI of each IP pool which was removed from IP_POOLS
+func (c *IPAMContext) tryFreeUnconfiguredIPPoolENIs() {
+	if c.isTerminating() {
+		return
+	}
+	var unconfigured []string
+	for _, ipPool := range c.eniIPPools {
+		if !c.hasIPPool(ipPool) && !lo.Contains(unconfigured, ipPool) {
+			unconfigured = append(unconfigured, ipPool)
+		}
+	}
+	for _, ipPool := range unconfigured {
+		eni := c.dataStore.RemoveUnusedENIFromStore(ipPool, noWarmIPTarget, noMinimumIPTarget, defaultWarmPrefixTarget)
+		if eni == "" {
+			continue
+		}
+		log.Infof("Freeing ENI %s of IP pool %q which is no longer configured", eni, ipPool)
+		if err := c.nholuongutClient.FreeENI(eni); err != nil {
+			ipamdErrInc("decreaseIPPoolFreeENIFailed")
+			log.Errorf("Failed to free ENI %s, err: %v", eni, err)
+			continue
+		}
+		c.setENIIPPool(eni, datastore.DefaultIPPool)
+	}
+}
+
+// podIPPool returns the IP pool selected by the ip-pool label of the pod, or else of its namespace. pod is nil if the
+// request doesn't name a pod. An error is returned if the namespace can't be looked up, rather than the default pool.
+func (c *IPAMContext) podIPPool(podNamespace string, pod *corev1.Pod) (string, error) {
+	if podNamespace == "" {
+		return datastore.DefaultIPPool, nil
+	}
+	if pod != nil {
+		if ipPool, ok := pod.Labels[ipPoolKey]; ok {
+			return ipPool, nil
+		}
+	}
+
+	var namespace corev1.Namespace
+	if err := c.k8sClient.Get(context.TODO(), types.NamespacedName{Name: podNamespace}, &namespace); err != nil {
+		return "", errors.Wrapf(err, "failed to get namespace %s", podNamespace)
+	}
+	return namespace.Labels[ipPoolKey], nil
+}
@@ -0,0 +1,101 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/nholuongut/nholuongut-sdk-go/nholuongut"
+	"github.com/nholuongut/nholuongut-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/apis/crd/v1alpha1"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/nholuongututils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+)
+
+func TestGetIPPools(t *testing.T) {
+	defer os.Unsetenv(envIPPools)
+
+	os.Unsetenv(envIPPools)
+	assert.Empty(t, getIPPools())
+
+	os.Setenv(envIPPools, "pci, restricted,,pci")
+	assert.Equal(t, []string{"pci", "restricted"}, getIPPools())
+}
+
+func TestIncreaseDatastorePoolForIPPool(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	mockContext := &IPAMContext{
+		nholuongutClient:     m.nholuongututils,
+		k8sClient:     m.k8sClient,
+		maxIPsPerENI:  14,
+		maxENI:        4,
+		warmENITarget: 1,
+		maxPods:       110,
+		networkClient: m.network,
+		primaryIP:     make(map[string]string),
+		ipPools:       []string{"pci"},
+		eniIPPools:    make(map[string]string),
+		dataStore:     testDatastore(),
+	}
+	assert.NoError(t, mockContext.dataStore.AddENI(primaryENIid, primaryDevice, true, false, false))
+	assert.NoError(t, mockContext.dataStore.AddIPv4CidrToStore(primaryENIid, net.IPNet{IP: net.ParseIP(ipaddr01), Mask: net.CIDRMask(32, 32)}, false))
+
+	// The pool is backed by the subnet and security groups of the ENIConfig named after it
+	assert.NoError(t, m.k8sClient.Create(ctx, &v1alpha1.ENIConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "pci"},
+		Spec:       v1alpha1.ENIConfigSpec{Subnet: "subnet-pci", SecurityGroups: []string{"sg-pci"}},
+	}))
+
+	tooLow, stats := mockContext.isDatastorePoolTooLow("pci")
+	assert.True(t, tooLow)
+	assert.Equal(t, 0, stats.TotalIPs)
+
+	notPrimary := false
+	eniMetadata := nholuongututils.ENIMetadata{
+		ENIID:          secENIid,
+		MAC:            secMAC,
+		DeviceNumber:   secDevice,
+		SubnetIPv4CIDR: secSubnet,
+		IPv4Addresses: []*ec2.NetworkInterfacePrivateIpAddress{
+			{PrivateIpAddress: nholuongut.String(ipaddr11), Primary: &notPrimary},
+			{PrivateIpAddress: nholuongut.String(ipaddr12), Primary: &notPrimary},
+		},
+	}
+	m.nholuongututils.EXPECT().AllocIPPoolENI("pci", []*string{nholuongut.String("sg-pci")}, "subnet-pci", 14, nholuongututils.ENIOptions{}).Return(secENIid, nil)
+	m.nholuongututils.EXPECT().WaitForENIAndIPsAttached(secENIid, 14).Return(eniMetadata, nil)
+	m.nholuongututils.EXPECT().GetPrimaryENI().Return(primaryENIid)
+	m.network.EXPECT().SetupENINetwork(gomock.Any(), secMAC, secDevice, secSubnet)
+
+	assert.NoError(t, mockContext.increaseDatastorePool(ctx, "pci"))
+	assert.Equal(t, "pci", mockContext.getENIIPPool(secENIid))
+	assert.Equal(t, "pci", mockContext.dataStore.GetENIInfos().ENIs[secENIid].IPPool)
+	assert.Equal(t, 2, mockContext.dataStore.GetPoolIPStats("pci", ipV4AddrFamily).TotalIPs)
+	assert.Equal(t, 1, mockContext.dataStore.GetPoolIPStats(datastore.DefaultIPPool, ipV4AddrFamily).TotalIPs)
+
+	// Only pods of the pool get addresses from its ENI
+	ip, _, err := mockContext.dataStore.AssignPodIPv4Address(datastore.IPAMKey{NetworkName: "net0", ContainerID: "sandbox-1", IfName: "eth0"},
+		datastore.IPAMMetadata{K8SPodNamespace: "payments", K8SPodName: "pod-1", IPPool: "pci"})
+	assert.NoError(t, err)
+	assert.Contains(t, []string{ipaddr11, ipaddr12}, ip)
+}
@@ -38,11 +38,13 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/util/retry"
 
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/apis/crd/v1alpha1"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/nholuongututils"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/eniconfig"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/k8sapi"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/networkutils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/sgpp"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/cniutils"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/logger"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/utils"
@@ -136,6 +138,12 @@ const (
 	envBackingStorePath     = "nholuongut_VPC_K8S_CNI_BACKING_STORE"
 	defaultBackingStorePath = "/var/run/nholuongut-node/ipam.json"
 
+	// Specify how ipam persists its allocations. "file" rewrites the whole backing store on every change, while
+	// "journal" appends the changes to a journal which is periodically compacted into the backing store.
+	envBackingStoreType     = "nholuongut_VPC_K8S_CNI_BACKING_STORE_TYPE"
+	backingStoreTypeFile    = "file"
+	backingStoreTypeJournal = "journal"
+
 	// envEnablePodENI is used to attach a Trunk ENI to every node. Required in order to give Branch ENIs to pods.
 	envEnablePodENI = "ENABLE_POD_ENI"
 
@@ -177,6 +185,9 @@ const (
 	// The empty string one helps close a trace at pod shutdown where it looks like the pod still has its IP when the IP has been released
 	envAnnotatePodIP = "ANNOTATE_POD_IP"
 
+	// When the upstream bandwidth plugin is chained, it shapes the pod traffic rather than the CNI plugin
+	envEnableBandwidthPlugin = "ENABLE_BANDWIDTH_PLUGIN"
+
 	// nholuongut error codes for insufficient IP address scenario
 	INSUFFICIENT_CIDR_BLOCKS    = "InsufficientCidrBlocks"
 	INSUFFICIENT_FREE_IP_SUBNET = "InsufficientFreeAddressesInSubnet"
@@ -227,8 +238,37 @@ type IPAMContext struct {
 	lastInsufficientCidrError time.Time
 	enableManageUntaggedMode  bool
 	enablePodIPAnnotation     bool
+	enableBandwidthPlugin     bool
 	maxPods                   int // maximum number of pods that can be scheduled on the node
 	networkPolicyMode         string
+	vethPrefix                string
+	podSGEnforcingMode        sgpp.EnforcingMode
+	// ipPools are the IP pools besides the default one, backed by ENIs in the subnets of their ENIConfigs
+	ipPools []string
+	// eniIPPools maps the ENIs of ipPools to their pool
+	eniIPPools map[string]string
+	// configLock guards the warm targets, maxENI, reloadedConfig, eniConfigName, eniConfigSpec and warmPoolProfile. They
+	// are only written by the pool manager, which reads them without it, introspection reads them under it.
+	configLock sync.RWMutex
+	// reloadedConfig holds the reloadable settings once the ConfigMap of IPAMD_CONFIG_MAP is applied, nil until then
+	reloadedConfig *reloadableConfig
+	// eniConfigName is the ENIConfig of the node when custom networking is enabled
+	eniConfigName string
+	// eniConfigSpec is the last applied spec of eniConfigName, its warm targets override the env variables
+	eniConfigSpec *v1alpha1.ENIConfigSpec
+	// configReloader holds the changes of the ConfigMap of IPAMD_CONFIG_MAP, nil if it is not watched
+	configReloader *configReloader
+	// adaptiveWarmPool adjusts the warm IP target to the pod churn of the node, nil if it is disabled
+	adaptiveWarmPool *adaptiveWarmPool
+	// egressEIPs are the Elastic IPs the pods of annotated namespaces egress through, nil if they are disabled
+	egressEIPs *egressEIPs
+	// snatPolicies are the SNAT policies applied on the node, nil if they are disabled
+	snatPolicies *snatPolicies
+	// warmPoolProfiles are the profiles of WARM_POOL_PROFILES, and warmPoolProfile the one which applies to the node
+	warmPoolProfiles []warmPoolProfile
+	warmPoolProfile  *warmPoolProfile
+	// poolActions are the last ENIs and addresses allocated and freed, shown by introspection
+	poolActions poolActionLog
 }
 
 // setUnmanagedENIs will rebuild the set of ENI IDs for ENIs tagged as "no_manage"
@@ -350,16 +390,25 @@ func New(k8sClient client.Client) (*IPAMContext, error) {
 	c.nholuongutClient = client
 
 	c.primaryIP = make(map[string]string)
+	c.eniIPPools = make(map[string]string)
 	c.reconcileCooldownCache.cache = make(map[string]time.Time)
 	// WARM and Min IP/Prefix targets are ignored in IPv6 mode
 	c.warmENITarget = getWarmENITarget()
 	c.warmIPTarget = getWarmIPTarget()
 	c.minimumIPTarget = getMinimumIPTarget()
 	c.warmPrefixTarget = getWarmPrefixTarget()
+	c.adaptiveWarmPool = newAdaptiveWarmPool()
+	c.warmPoolProfiles = getWarmPoolProfiles()
 	c.enablePodENI = enablePodENI()
 	c.enableManageUntaggedMode = enableManageUntaggedMode()
 	c.enablePodIPAnnotation = enablePodIPAnnotation()
+	c.enableBandwidthPlugin = utils.GetBoolAsStringEnvVar(envEnableBandwidthPlugin, false)
+	c.vethPrefix = networkutils.GetVethPrefixName()
+	c.podSGEnforcingMode = sgpp.LoadEnforcingModeFromEnv()
 	c.numNetworkCards = len(c.nholuongutClient.GetNetworkCards())
+	c.ipPools = getIPPools()
+	c.egressEIPs = newEgressEIPs()
+	c.snatPolicies = newSNATPolicies()
 
 	c.networkPolicyMode, err = getNetworkPolicyMode()
 	if err != nil {
@@ -372,6 +421,8 @@ func New(k8sClient client.Client) (*IPAMContext, error) {
 		return nil, err
 	}
 
+	c.initENIConfigPrefixDelegation(context.TODO())
+
 	// Validate if the configured combination of env variables is supported before proceeding further
 	if !c.isConfigValid() {
 		return nil, fmt.Errorf("ipamd: failed to validate configuration")
@@ -379,7 +430,7 @@ func New(k8sClient client.Client) (*IPAMContext, error) {
 
 	c.nholuongutClient.InitCachedPrefixDelegation(c.enablePrefixDelegation)
 	c.myNodeName = os.Getenv(envNodeName)
-	checkpointer := datastore.NewJSONFile(dsBackingStorePath())
+	checkpointer := dsBackingStore()
 	c.dataStore = datastore.NewDataStore(log, checkpointer, c.enablePrefixDelegation)
 
 	if err := c.nodeInit(); err != nil {
@@ -429,6 +480,7 @@ func (c *IPAMContext) nodeInit() error {
 	log.Debugf("DescribeAllENIs success: ENIs: %d, tagged: %d", len(metadataResult.ENIMetadata), len(metadataResult.TagMap))
 	c.nholuongutClient.SetMultiCardENIs(metadataResult.MultiCardENIIDs)
 	c.setUnmanagedENIs(metadataResult.TagMap)
+	c.setIPPoolENIs(metadataResult.TagMap)
 	enis := c.filterUnmanagedENIs(metadataResult.ENIMetadata)
 
 	for _, eni := range enis {
@@ -467,11 +519,15 @@ func (c *IPAMContext) nodeInit() error {
 		}
 	}
 
-	if err := c.dataStore.ReadBackingStore(c.enableIPv6); err != nil {
+	if err := c.dataStore.ReadBackingStore(c.enableIPv4, c.enableIPv6); err != nil {
 		return err
 	}
 
-	if c.enableIPv6 {
+	if err := c.initEgressEIPs(ctx, metadataResult); err != nil {
+		return errors.Wrap(err, "ipamd init: failed to set up egress Elastic IPs")
+	}
+
+	if c.enableIPv6 && !c.enableIPv4 {
 		// Security Groups for Pods cannot be enabled for IPv4 at this point, as Custom Networking must be enabled first.
 		if c.enablePodENI {
 			// Try to patch CNINode with Security Groups for Pods feature.
@@ -540,6 +596,9 @@ func (c *IPAMContext) nodeInit() error {
 		// resource for this instance. The operation is safe as enabling/disabling custom networking
 		// requires terminating the previous instance.
 		eniConfigName, err := eniconfig.GetNodeSpecificENIConfigName(node)
+		if eniCfg, err := eniconfig.GetENIConfig(ctx, c.k8sClient, eniConfigName); err == nil {
+			c.applyENIConfigOverrides(eniConfigName, eniCfg)
+		}
 		if err == nil && eniConfigName != "default" {
 			// If Security Groups for Pods is enabled, the VPC Resource Controller must also know that Custom Networking is enabled
 			if c.enablePodENI {
@@ -562,9 +621,9 @@ func (c *IPAMContext) nodeInit() error {
 	}
 
 	// On node init, check if datastore pool needs to be increased. If so, attach CIDRs from existing ENIs and attach new ENIs.
-	datastorePoolTooLow, _ := c.isDatastorePoolTooLow()
+	datastorePoolTooLow, _ := c.isDatastorePoolTooLow(datastore.DefaultIPPool)
 	if !c.disableENIProvisioning && datastorePoolTooLow {
-		if err := c.increaseDatastorePool(ctx); err != nil {
+		if err := c.increaseDatastorePool(ctx, datastore.DefaultIPPool); err != nil {
 			// Note that the only error currently returned by increaseDatastorePool is an error attaching CIDRs (other than insufficient IPs)
 			podENIErrInc("nodeInit")
 			return errors.New("error while trying to increase datastore pool")
@@ -628,6 +687,14 @@ func (c *IPAMContext) updateCIDRsRulesOnChange(oldVPCCIDRs []string) []string {
 		if err != nil {
 			log.Warnf("unable to update host iptables rules for VPC CIDRs due to error: %v", err)
 		}
+		if c.egressEIPs != nil {
+			c.egressEIPs.Lock()
+			err = c.updateEgressEIPRules()
+			c.egressEIPs.Unlock()
+			if err != nil {
+				log.Warnf("unable to update egress Elastic IP rules for VPC CIDRs due to error: %v", err)
+			}
+		}
 	}
 	return newVPCCIDRs
 }
@@ -640,7 +707,8 @@ func (c *IPAMContext) updateIPStats(unmanaged int) {
 // StartNodeIPPoolManager monitors the IP pool, add or del them when it is required.
 func (c *IPAMContext) StartNodeIPPoolManager() {
 	// For IPv6, if Security Groups for Pods is enabled, wait until trunk ENI is attached and add it to the datastore.
-	if c.enableIPv6 {
+	// In dual stack mode the IPv4 pool is managed as usual.
+	if c.enableIPv6 && !c.enableIPv4 {
 		if c.enablePodENI && c.dataStore.GetTrunkENI() == "" {
 			for !c.checkForTrunkENI() {
 				time.Sleep(ipPoolMonitorInterval)
@@ -656,8 +724,12 @@ func (c *IPAMContext) StartNodeIPPoolManager() {
 	sleepDuration := ipPoolMonitorInterval / 2
 	ctx := context.Background()
 	for {
+		c.applyReloadedConfig()
 		if !c.disableENIProvisioning {
 			time.Sleep(sleepDuration)
+			c.refreshENIConfigOverrides(ctx)
+			c.refreshWarmPoolSchedule(ctx, time.Now())
+			c.updateAdaptiveWarmPool(ctx, time.Now())
 			c.updateIPPoolIfRequired(ctx)
 		}
 		time.Sleep(sleepDuration)
@@ -671,18 +743,22 @@ func (c *IPAMContext) updateIPPoolIfRequired(ctx context.Context) {
 		c.tryEnableSecurityGroupsForPods(ctx)
 	}
 
-	datastorePoolTooLow, stats := c.isDatastorePoolTooLow()
-	// Each iteration, log the current datastore IP stats
-	log.Debugf("IP stats - total IPs: %d, assigned IPs: %d, cooldown IPs: %d", stats.TotalIPs, stats.AssignedIPs, stats.CooldownIPs)
+	for _, ipPool := range c.allIPPools() {
+		datastorePoolTooLow, stats := c.isDatastorePoolTooLow(ipPool)
+		// Each iteration, log the current datastore IP stats
+		log.Debugf("IP stats%s - total IPs: %d, assigned IPs: %d, cooldown IPs: %d, reserved IPs: %d",
+			ipPoolLogSuffix(ipPool), stats.TotalIPs, stats.AssignedIPs, stats.CooldownIPs, stats.ReservedIPs)
 
-	if datastorePoolTooLow {
-		c.increaseDatastorePool(ctx)
-	} else if c.isDatastorePoolTooHigh(stats) {
-		c.decreaseDatastorePool(decreaseIPPoolInterval)
-	}
-	if c.shouldRemoveExtraENIs() {
-		c.tryFreeENI()
+		if datastorePoolTooLow {
+			c.increaseDatastorePool(ctx, ipPool)
+		} else if c.isDatastorePoolTooHigh(stats) {
+			c.decreaseDatastorePool(decreaseIPPoolInterval)
+		}
+		if c.shouldRemoveExtraENIs(ipPool) {
+			c.tryFreeENI(ipPool)
+		}
 	}
+	c.tryFreeUnconfiguredIPPoolENIs()
 }
 
 // decreaseDatastorePool runs every `interval` and attempts to return unused ENIs and IPs
@@ -699,7 +775,9 @@ func (c *IPAMContext) decreaseDatastorePool(interval time.Duration) {
 	}
 
 	log.Debugf("Starting to decrease Datastore pool")
-	c.tryUnassignCidrsFromAll()
+	for _, ipPool := range c.allIPPools() {
+		c.tryUnassignCidrsFromAll(ipPool)
+	}
 	c.lastDecreaseIPPool = now
 	c.lastNodeIPPoolAction = now
 
@@ -707,8 +785,8 @@ func (c *IPAMContext) decreaseDatastorePool(interval time.Duration) {
 	c.logPoolStats(c.dataStore.GetIPStats(ipV4AddrFamily))
 }
 
-// tryFreeENI always tries to free one ENI
-func (c *IPAMContext) tryFreeENI() {
+// tryFreeENI always tries to free one ENI of the IP pool
+func (c *IPAMContext) tryFreeENI(ipPool string) {
 	if c.isTerminating() {
 		log.Debug("nholuongut CNI is terminating, not detaching any ENIs")
 		return
@@ -719,32 +797,37 @@ func (c *IPAMContext) tryFreeENI() {
 		return
 	}
 
-	eni := c.dataStore.RemoveUnusedENIFromStore(c.warmIPTarget, c.minimumIPTarget, c.warmPrefixTarget)
+	eni := c.dataStore.RemoveUnusedENIFromStore(ipPool, c.effectiveWarmIPTarget(), c.minimumIPTarget, c.warmPrefixTarget)
 	if eni == "" {
 		return
 	}
 
 	log.Debugf("Start freeing ENI %s", eni)
 	err := c.nholuongutClient.FreeENI(eni)
+	c.recordPoolAction(poolActionFreeENI, ipPool, eni, nil, err)
 	if err != nil {
 		ipamdErrInc("decreaseIPPoolFreeENIFailed")
 		log.Errorf("Failed to free ENI %s, err: %v", eni, err)
 		return
 	}
+	c.setENIIPPool(eni, datastore.DefaultIPPool)
 
 }
 
-// When warm IP/prefix targets are defined, free extra IPs
-func (c *IPAMContext) tryUnassignCidrsFromAll() {
-	_, over, warmIPTargetsDefined := c.datastoreTargetState(nil)
+// When warm IP/prefix targets are defined, free extra IPs of the IP pool
+func (c *IPAMContext) tryUnassignCidrsFromAll(ipPool string) {
+	_, over, warmIPTargetsDefined := c.datastoreTargetState(c.dataStore.GetPoolIPStats(ipPool, ipV4AddrFamily))
 	// If WARM IP targets are not defined, check if WARM_PREFIX_TARGET is defined.
 	if !warmIPTargetsDefined {
-		over = c.computeExtraPrefixesOverWarmTarget()
+		over = c.computeExtraPrefixesOverWarmTarget(ipPool)
 	}
 
 	if over > 0 {
 		eniInfos := c.dataStore.GetENIInfos()
-		for eniID := range eniInfos.ENIs {
+		for eniID, eni := range eniInfos.ENIs {
+			if eni.IPPool != ipPool {
+				continue
+			}
 			// Either returns prefixes or IPs [Cidrs]
 			cidrs := c.dataStore.FindFreeableCidrs(eniID)
 			if cidrs == nil {
@@ -787,7 +870,7 @@ func (c *IPAMContext) tryUnassignCidrsFromAll() {
 }
 
 // PRECONDITION: isDatastorePoolTooLow returned true
-func (c *IPAMContext) increaseDatastorePool(ctx context.Context) error {
+func (c *IPAMContext) increaseDatastorePool(ctx context.Context, ipPool string) error {
 	log.Debug("Starting to increase pool size")
 	prometheusmetrics.IpamdActionsInprogress.WithLabelValues("increaseDatastorePool").Add(float64(1))
 	defer prometheusmetrics.IpamdActionsInprogress.WithLabelValues("increaseDatastorePool").Sub(float64(1))
@@ -807,7 +890,7 @@ func (c *IPAMContext) increaseDatastorePool(ctx context.Context) error {
 		return nil
 	}
 
-	increasedPool, err := c.tryAssignCidrs()
+	increasedPool, err := c.tryAssignCidrs(ipPool)
 	if err != nil {
 		if containsInsufficientCIDRsOrSubnetIPs(err) {
 			log.Errorf("Unable to attach IPs/Prefixes for the ENI, subnet doesn't seem to have enough IPs/Prefixes. Consider using new subnet or carve a reserved range using create-subnet-cidr-reservation")
@@ -822,7 +905,7 @@ func (c *IPAMContext) increaseDatastorePool(ctx context.Context) error {
 	} else {
 		// If we did not add any IPs, try to allocate an ENI.
 		if c.hasRoomForEni() {
-			if err = c.tryAllocateENI(ctx); err == nil {
+			if err = c.tryAllocateENI(ctx, ipPool); err == nil {
 				c.updateLastNodeIPPoolAction()
 			} else {
 				// Note that no error is returned if ENI allocation fails. This is because ENI allocation failure should not cause node to be "NotReady".
@@ -841,29 +924,38 @@ func (c *IPAMContext) updateLastNodeIPPoolAction() {
 	c.logPoolStats(stats)
 }
 
-func (c *IPAMContext) tryAllocateENI(ctx context.Context) error {
+func (c *IPAMContext) tryAllocateENI(ctx context.Context, ipPool string) error {
 	var securityGroups []*string
 	var eniCfgSubnet string
+	var opts nholuongututils.ENIOptions
 
-	if c.useCustomNetworking {
-		eniCfg, err := eniconfig.MyENIConfig(ctx, c.k8sClient)
+	if c.useCustomNetworking || ipPool != datastore.DefaultIPPool {
+		eniCfg, err := c.getENIConfig(ctx, ipPool)
 		if err != nil {
-			log.Errorf("Failed to get pod ENI config")
+			log.Errorf("Failed to get pod ENI config%s", ipPoolLogSuffix(ipPool))
 			return err
 		}
 
-		log.Infof("ipamd: using custom network config: %v, %s", eniCfg.SecurityGroups, eniCfg.Subnet)
+		log.Infof("ipamd: using custom network config%s: %v, %s", ipPoolLogSuffix(ipPool), eniCfg.SecurityGroups, eniCfg.Subnet)
 		for _, sgID := range eniCfg.SecurityGroups {
 			log.Debugf("Found security-group id: %s", sgID)
 			securityGroups = append(securityGroups, nholuongut.String(sgID))
 		}
 		eniCfgSubnet = eniCfg.Subnet
+		opts = eniOptions(eniCfg)
 	}
 
-	resourcesToAllocate := c.GetENIResourcesToAllocate()
+	resourcesToAllocate := c.GetENIResourcesToAllocate(ipPool)
 	if resourcesToAllocate > 0 {
-		eni, err := c.nholuongutClient.AllocENI(c.useCustomNetworking, securityGroups, eniCfgSubnet, resourcesToAllocate)
+		var eni string
+		var err error
+		if ipPool != datastore.DefaultIPPool {
+			eni, err = c.nholuongutClient.AllocIPPoolENI(ipPool, securityGroups, eniCfgSubnet, resourcesToAllocate, opts)
+		} else {
+			eni, err = c.nholuongutClient.AllocENI(c.useCustomNetworking, securityGroups, eniCfgSubnet, resourcesToAllocate, opts)
+		}
 		if err != nil {
+			c.recordPoolAction(poolActionAllocateENI, ipPool, "", nil, err)
 			log.Errorf("Failed to increase pool size due to not able to allocate ENI %v", err)
 			ipamdErrInc("increaseIPPoolAllocENI")
 			log.Warnf("Failed to allocate %d IP addresses on an ENI: %v", resourcesToAllocate, err)
@@ -875,6 +967,7 @@ func (c *IPAMContext) tryAllocateENI(ctx context.Context) error {
 			return err
 		}
 
+		c.recordPoolAction(poolActionAllocateENI, ipPool, eni, nil, nil)
 		eniMetadata, err := c.nholuongutClient.WaitForENIAndIPsAttached(eni, resourcesToAllocate)
 		if err != nil {
 			ipamdErrInc("increaseIPPoolwaitENIAttachedFailed")
@@ -883,6 +976,7 @@ func (c *IPAMContext) tryAllocateENI(ctx context.Context) error {
 		}
 
 		// The CNI does not create trunk or EFA ENIs, so they will always be false here
+		c.setENIIPPool(eni, ipPool)
 		err = c.setupENI(eni, eniMetadata, false, false)
 		if err != nil {
 			ipamdErrInc("increaseIPPoolsetupENIFailed")
@@ -897,20 +991,20 @@ func (c *IPAMContext) tryAllocateENI(ctx context.Context) error {
 
 // For an ENI, fill in missing IPs or prefixes.
 // PRECONDITION: isDatastorePoolTooLow returned true
-func (c *IPAMContext) tryAssignCidrs() (increasedPool bool, err error) {
+func (c *IPAMContext) tryAssignCidrs(ipPool string) (increasedPool bool, err error) {
 	if c.enablePrefixDelegation {
-		return c.tryAssignPrefixes()
+		return c.tryAssignPrefixes(ipPool)
 	} else {
-		return c.tryAssignIPs()
+		return c.tryAssignIPs(ipPool)
 	}
 }
 
 // For an ENI, try to fill in missing IPs on an existing ENI.
 // PRECONDITION: isDatastorePoolTooLow returned true
-func (c *IPAMContext) tryAssignIPs() (increasedPool bool, err error) {
+func (c *IPAMContext) tryAssignIPs(ipPool string) (increasedPool bool, err error) {
 
 	// If WARM_IP_TARGET is set, only proceed if we are short of target
-	short, _, warmIPTargetsDefined := c.datastoreTargetState(nil)
+	short, _, warmIPTargetsDefined := c.datastoreTargetState(c.dataStore.GetPoolIPStats(ipPool, ipV4AddrFamily))
 	if warmIPTargetsDefined && short == 0 {
 		return false, nil
 	}
@@ -924,6 +1018,9 @@ func (c *IPAMContext) tryAssignIPs() (increasedPool bool, err error) {
 	// Find an ENI where we can add more IPs
 	enis := c.dataStore.GetAllocatableENIs(c.maxIPsPerENI, c.useCustomNetworking)
 	for _, eni := range enis {
+		if eni.IPPool != ipPool {
+			continue
+		}
 		if len(eni.AvailableIPv4Cidrs) < c.maxIPsPerENI {
 			currentNumberOfAllocatedIPs := len(eni.AvailableIPv4Cidrs)
 			// Try to allocate all available IPs for this ENI
@@ -964,12 +1061,68 @@ func (c *IPAMContext) tryAssignIPs() (increasedPool bool, err error) {
 				}
 			}
 			c.addENIsecondaryIPsToDataStore(ec2ip4s, eni.ID)
+			cidrs := make([]string, 0, len(ec2ip4s))
+			for _, ec2ip4 := range ec2ip4s {
+				cidrs = append(cidrs, nholuongut.StringValue(ec2ip4.PrivateIpAddress)+"/32")
+			}
+			c.recordPoolAction(poolActionAssignCidrs, ipPool, eni.ID, cidrs, nil)
 			return true, nil
 		}
 	}
 	return false, nil
 }
 
+// assignStaticIPv4Address assigns the IPv4 address (/32), or an address of the prefix (/28), requested by the pod. If it
+// isn't allocated yet, it is allocated on an attached ENI of the IP pool of the pod whose subnet contains it.
+func (c *IPAMContext) assignStaticIPv4Address(ipamKey datastore.IPAMKey, ipamMetadata datastore.IPAMMetadata, cidr net.IPNet) (string, int, error) {
+	ipv4Addr, deviceNumber, err := c.dataStore.AssignPodStaticIPv4Address(ipamKey, ipamMetadata, cidr)
+	if err != datastore.ErrUnknownStaticCidr {
+		return ipv4Addr, deviceNumber, err
+	}
+
+	eniID, err := c.findENIForStaticCidr(cidr, ipamMetadata.IPPool)
+	if err != nil {
+		return "", -1, err
+	}
+	if err := c.nholuongutClient.AllocIPv4Cidr(eniID, cidr); err != nil {
+		ipamdErrInc("assignStaticIPv4AddressAllocFailed")
+		return "", -1, errors.Wrapf(err, "failed to allocate requested IP/Prefix %s, it may be in use by another interface", cidr.String())
+	}
+	ones, bits := cidr.Mask.Size()
+	if err := c.dataStore.AddIPv4CidrToStore(eniID, cidr, ones != bits); err != nil && err.Error() != datastore.IPAlreadyInStoreError {
+		return "", -1, err
+	}
+	return c.dataStore.AssignPodStaticIPv4Address(ipamKey, ipamMetadata, cidr)
+}
+
+// findENIForStaticCidr returns an ENI of the IP pool which can take more secondary IPs or prefixes in the subnet of cidr
+func (c *IPAMContext) findENIForStaticCidr(cidr net.IPNet, ipPool string) (string, error) {
+	attachedENIs, err := c.nholuongutClient.GetAttachedENIs()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get attached ENIs")
+	}
+	inSubnet := make(map[string]bool, len(attachedENIs))
+	for _, eni := range attachedENIs {
+		if _, subnet, err := net.ParseCIDR(eni.SubnetIPv4CIDR); err == nil && subnet.Contains(cidr.IP) {
+			inSubnet[eni.ENIID] = true
+		}
+	}
+	if len(inSubnet) == 0 {
+		return "", errors.Errorf("requested IP/Prefix %s is outside the subnets of the node's ENIs", cidr.String())
+	}
+
+	maxCidrsPerENI := c.maxIPsPerENI
+	if c.enablePrefixDelegation {
+		maxCidrsPerENI = c.maxPrefixesPerENI
+	}
+	for _, eni := range c.dataStore.GetAllocatableENIs(maxCidrsPerENI, c.useCustomNetworking) {
+		if inSubnet[eni.ID] && eni.IPPool == ipPool {
+			return eni.ID, nil
+		}
+	}
+	return "", errors.Errorf("no ENI%s in the subnet of requested IP/Prefix %s can take more IPs/Prefixes", ipPoolLogSuffix(ipPool), cidr.String())
+}
+
 func (c *IPAMContext) assignIPv6Prefix(eniID string) (err error) {
 	log.Debugf("Assigning an IPv6Prefix for ENI: %s", eniID)
 	//Let's make an EC2 API call to get a list of IPv6 prefixes (if any) that are already attached to the
@@ -1010,12 +1163,15 @@ func (c *IPAMContext) assignIPv6Prefix(eniID string) (err error) {
 }
 
 // PRECONDITION: isDatastorePoolTooLow returned true
-func (c *IPAMContext) tryAssignPrefixes() (increasedPool bool, err error) {
-	toAllocate := c.getPrefixesNeeded()
+func (c *IPAMContext) tryAssignPrefixes(ipPool string) (increasedPool bool, err error) {
+	toAllocate := c.getPrefixesNeeded(ipPool)
 	// Returns an ENI which has space for more prefixes to be attached, but this
 	// ENI might not suffice the WARM_IP_TARGET/WARM_PREFIX_TARGET
 	enis := c.dataStore.GetAllocatableENIs(c.maxPrefixesPerENI, c.useCustomNetworking)
 	for _, eni := range enis {
+		if eni.IPPool != ipPool {
+			continue
+		}
 		currentNumberOfAllocatedPrefixes := len(eni.AvailableIPv4Cidrs)
 		resourcesToAllocate := min((c.maxPrefixesPerENI - currentNumberOfAllocatedPrefixes), toAllocate)
 		output, err := c.nholuongutClient.AllocIPAddresses(eni.ID, resourcesToAllocate)
@@ -1050,6 +1206,11 @@ func (c *IPAMContext) tryAssignPrefixes() (increasedPool bool, err error) {
 			ec2Prefixes = output.AssignedIpv4Prefixes
 		}
 		c.addENIv4prefixesToDataStore(ec2Prefixes, eni.ID)
+		cidrs := make([]string, 0, len(ec2Prefixes))
+		for _, ec2Prefix := range ec2Prefixes {
+			cidrs = append(cidrs, nholuongut.StringValue(ec2Prefix.Ipv4Prefix))
+		}
+		c.recordPoolAction(poolActionAssignCidrs, ipPool, eni.ID, cidrs, nil)
 		return true, nil
 	}
 	return false, nil
@@ -1062,12 +1223,12 @@ func (c *IPAMContext) tryAssignPrefixes() (increasedPool bool, err error) {
 func (c *IPAMContext) setupENI(eni string, eniMetadata nholuongututils.ENIMetadata, isTrunkENI, isEFAENI bool) error {
 	primaryENI := c.nholuongutClient.GetPrimaryENI()
 	// Add the ENI to the datastore
-	err := c.dataStore.AddENI(eni, eniMetadata.DeviceNumber, eni == primaryENI, isTrunkENI, isEFAENI)
+	err := c.dataStore.AddENIToPool(eni, eniMetadata.DeviceNumber, eni == primaryENI, isTrunkENI, isEFAENI, c.getENIIPPool(eni))
 	if err != nil && err.Error() != datastore.DuplicatedENIError {
 		return errors.Wrapf(err, "failed to add ENI %s to data store", eni)
 	}
 	// Store the addressable IP for the ENI
-	if c.enableIPv6 {
+	if c.enableIPv6 && !c.enableIPv4 {
 		c.primaryIP[eni] = eniMetadata.PrimaryIPv6Address()
 	} else {
 		c.primaryIP[eni] = eniMetadata.PrimaryIPv4Address()
@@ -1080,11 +1241,17 @@ func (c *IPAMContext) setupENI(eni string, eniMetadata nholuongututils.ENIMetada
 		if err != nil {
 			return errors.Wrapf(err, "Failed to allocate IPv6 Prefixes to Primary ENI")
 		}
+		if c.enableIPv4 {
+			// In dual stack mode, the primary ENI also serves IPv4 addresses to pods.
+			log.Infof("Found primary ENI having %d secondary IPs and %d Prefixes", len(eniMetadata.IPv4Addresses), len(eniMetadata.IPv4Prefixes))
+			c.addENIsecondaryIPsToDataStore(eniMetadata.IPv4Addresses, eni)
+			c.addENIv4prefixesToDataStore(eniMetadata.IPv4Prefixes, eni)
+		}
 	} else {
 		// For other ENIs, set up the network
 		if eni != primaryENI {
 			subnetCidr := eniMetadata.SubnetIPv4CIDR
-			if c.enableIPv6 {
+			if c.enableIPv6 && !c.enableIPv4 {
 				subnetCidr = eniMetadata.SubnetIPv6CIDR
 			}
 			err = c.networkClient.SetupENINetwork(c.primaryIP[eni], eniMetadata.MAC, eniMetadata.DeviceNumber, subnetCidr)
@@ -1098,7 +1265,7 @@ func (c *IPAMContext) setupENI(eni string, eniMetadata nholuongututils.ENIMetada
 				return errors.Wrapf(err, "failed to set up ENI %s network", eni)
 			}
 		}
-		if !c.enableIPv6 {
+		if !c.enableIPv6 || c.enableIPv4 {
 			log.Infof("Found ENIs having %d secondary IPs and %d Prefixes", len(eniMetadata.IPv4Addresses), len(eniMetadata.IPv4Prefixes))
 			// Either case add the IPs and prefixes to datastore.
 			c.addENIsecondaryIPsToDataStore(eniMetadata.IPv4Addresses, eni)
@@ -1175,9 +1342,19 @@ func (c *IPAMContext) addENIv6prefixesToDataStore(ec2PrefixAddrs []*ec2.Ipv6Pref
 // the limit for the instance type and the value configured via the MAX_ENI environment variable. If the value of
 // the environment variable is 0 or less, it will be ignored and the maximum for the instance is returned.
 func (c *IPAMContext) getMaxENI() (int, error) {
+	return c.limitMaxENI(parseMaxENI(os.LookupEnv(envMaxENI))), nil
+}
+
+// limitMaxENI returns the MAX_ENI setting envMax, limited by the ENI limit of the instance
+func (c *IPAMContext) limitMaxENI(envMax int) int {
 	instanceMaxENI := c.nholuongutClient.GetENILimit()
+	if envMax >= 1 && envMax < instanceMaxENI {
+		return envMax
+	}
+	return instanceMaxENI
+}
 
-	inputStr, found := os.LookupEnv(envMaxENI)
+func parseMaxENI(inputStr string, found bool) int {
 	envMax := defaultMaxENI
 	if found {
 		if input, err := strconv.Atoi(inputStr); err == nil && input >= 1 {
@@ -1185,16 +1362,14 @@ func (c *IPAMContext) getMaxENI() (int, error) {
 			envMax = input
 		}
 	}
-
-	if envMax >= 1 && envMax < instanceMaxENI {
-		return envMax, nil
-	}
-	return instanceMaxENI, nil
+	return envMax
 }
 
 func getWarmENITarget() int {
-	inputStr, found := os.LookupEnv(envWarmENITarget)
+	return parseWarmENITarget(os.LookupEnv(envWarmENITarget))
+}
 
+func parseWarmENITarget(inputStr string, found bool) int {
 	if !found {
 		return defaultWarmENITarget
 	}
@@ -1210,8 +1385,10 @@ func getWarmENITarget() int {
 }
 
 func getWarmPrefixTarget() int {
-	inputStr, found := os.LookupEnv(envWarmPrefixTarget)
+	return parseWarmPrefixTarget(os.LookupEnv(envWarmPrefixTarget))
+}
 
+func parseWarmPrefixTarget(inputStr string, found bool) int {
 	if !found {
 		return defaultWarmPrefixTarget
 	}
@@ -1256,13 +1433,13 @@ func (c *IPAMContext) tryEnableSecurityGroupsForPods(ctx context.Context) {
 // PD enabled: If the WARM_PREFIX_TARGET is spread across ENIs and we have more than needed, this function will return true.
 // If the number of prefixes are on just one ENI, and there are more than available, it returns true so getDeletableENI will
 // recheck if we need the ENI for prefix target.
-func (c *IPAMContext) shouldRemoveExtraENIs() bool {
+func (c *IPAMContext) shouldRemoveExtraENIs(ipPool string) bool {
 	// When WARM_IP_TARGET is set, return true as verification is always done in getDeletableENI()
 	if c.warmIPTargetsDefined() {
 		return true
 	}
 
-	stats := c.dataStore.GetIPStats(ipV4AddrFamily)
+	stats := c.dataStore.GetPoolIPStats(ipPool, ipV4AddrFamily)
 	available := stats.AvailableAddresses()
 	var shouldRemoveExtra bool
 
@@ -1280,20 +1457,20 @@ func (c *IPAMContext) shouldRemoveExtraENIs() bool {
 	} else if c.enablePrefixDelegation {
 		// When prefix target count is reduced, datastore would have deleted extra prefixes over the warm prefix target.
 		// Hence available will be less than (warmTarget)*c.maxIPsPerENI, but there can be some extra ENIs which are not used hence see if we can clean it up.
-		shouldRemoveExtra = c.dataStore.CheckFreeableENIexists()
+		shouldRemoveExtra = c.dataStore.CheckFreeableENIexists(ipPool)
 	}
 	return shouldRemoveExtra
 }
 
-func (c *IPAMContext) computeExtraPrefixesOverWarmTarget() int {
+func (c *IPAMContext) computeExtraPrefixesOverWarmTarget(ipPool string) int {
 	if !c.warmPrefixTargetDefined() {
 		return 0
 	}
 
-	freePrefixes := c.dataStore.GetFreePrefixes()
+	freePrefixes := c.dataStore.GetFreePrefixes(ipPool)
 	over := max(freePrefixes-c.warmPrefixTarget, 0)
 
-	stats := c.dataStore.GetIPStats(ipV4AddrFamily)
+	stats := c.dataStore.GetPoolIPStats(ipPool, ipV4AddrFamily)
 	log.Debugf("computeExtraPrefixesOverWarmTarget - available: %d, over: %d, warm_prefix_target: %d", stats.AvailableAddresses(), over, c.warmPrefixTarget)
 	c.logPoolStats(stats)
 	return over
@@ -1389,6 +1566,7 @@ func (c *IPAMContext) nodeIPPoolReconcile(ctx context.Context, interval time.Dur
 		efaENIs = metadataResult.EFAENIs
 		eniTagMap = metadataResult.TagMap
 		c.setUnmanagedENIs(metadataResult.TagMap)
+		c.setIPPoolENIs(metadataResult.TagMap)
 		c.nholuongutClient.SetMultiCardENIs(metadataResult.MultiCardENIIDs)
 		attachedENIs = c.filterUnmanagedENIs(metadataResult.ENIMetadata)
 	}
@@ -1720,8 +1898,29 @@ func dsBackingStorePath() string {
 	return defaultBackingStorePath
 }
 
+// dsBackingStore returns the checkpointer selected by envBackingStoreType
+func dsBackingStore() datastore.Checkpointer {
+	path := dsBackingStorePath()
+	storeType := os.Getenv(envBackingStoreType)
+	switch storeType {
+	case backingStoreTypeJournal:
+		return datastore.NewJournalFile(path, datastore.DefaultJournalCompactThreshold)
+	case "", backingStoreTypeFile:
+	default:
+		log.Warnf("Unknown %s %q, using %q", envBackingStoreType, storeType, backingStoreTypeFile)
+	}
+	// Fold back any journal left behind when switching from the journal backend
+	if err := datastore.CompactJournal(path); err != nil {
+		log.Warnf("Failed to compact the backing store journal: %v", err)
+	}
+	return datastore.NewJSONFile(path)
+}
+
 func getWarmIPTarget() int {
-	inputStr, found := os.LookupEnv(envWarmIPTarget)
+	return parseWarmIPTarget(os.LookupEnv(envWarmIPTarget))
+}
+
+func parseWarmIPTarget(inputStr string, found bool) int {
 	if !found {
 		return noWarmIPTarget
 	}
@@ -1736,7 +1935,10 @@ func getWarmIPTarget() int {
 }
 
 func getMinimumIPTarget() int {
-	inputStr, found := os.LookupEnv(envMinimumIPTarget)
+	return parseMinimumIPTarget(os.LookupEnv(envMinimumIPTarget))
+}
+
+func parseMinimumIPTarget(inputStr string, found bool) int {
 	if !found {
 		return noMinimumIPTarget
 	}
@@ -1756,10 +1958,10 @@ func disableENIProvisioning() bool {
 
 func disableLeakedENICleanup() bool {
 	// Cases where leaked ENI cleanup is disabled:
-	// 1. IPv6 is enabled, so no ENIs are attached
+	// 1. IPv6 only mode is enabled, so no ENIs are attached
 	// 2. ENI provisioning is disabled, so ENIs are not managed by IPAMD
 	// 3. Environment var explicitly disabling task is set
-	return isIPv6Enabled() || disableENIProvisioning() || utils.GetBoolAsStringEnvVar(envDisableLeakedENICleanup, false)
+	return (isIPv6Enabled() && !isIPv4Enabled()) || disableENIProvisioning() || utils.GetBoolAsStringEnvVar(envDisableLeakedENICleanup, false)
 }
 
 func enablePodENI() bool {
@@ -1804,7 +2006,7 @@ func (c *IPAMContext) filterUnmanagedENIs(enis []nholuongututils.ENIMetadata) []
 		//Filter out any Unmanaged ENIs. VPC CNI will only work with Primary ENI in IPv6 Prefix Delegation mode until
 		//we open up IPv6 support in Secondary IP and Custom networking modes. Filtering out the ENIs here will
 		//help us avoid myriad of if/else loops elsewhere in the code.
-		if c.enableIPv6 && !c.nholuongutClient.IsPrimaryENI(eni.ENIID) {
+		if c.enableIPv6 && !c.enableIPv4 && !c.nholuongutClient.IsPrimaryENI(eni.ENIID) {
 			log.Debugf("Skipping ENI %s: IPv6 Mode is enabled and VPC CNI will only manage Primary ENI in v6 PD mode",
 				eni.ENIID)
 			numFiltered++
@@ -1834,18 +2036,19 @@ func (c *IPAMContext) datastoreTargetState(stats *datastore.DataStoreStats) (sho
 
 	// Calculating DataStore stats can be expensive, so allow the caller to optionally pass stats it already calculated
 	if stats == nil {
-		stats = c.dataStore.GetIPStats(ipV4AddrFamily)
+		stats = c.dataStore.GetPoolIPStats(datastore.DefaultIPPool, ipV4AddrFamily)
 	}
 	available := stats.AvailableAddresses()
+	warmIPTarget := c.effectiveWarmIPTarget()
 
 	// short is greater than 0 when we have fewer available IPs than the warm IP target
-	short = max(c.warmIPTarget-available, 0)
+	short = max(warmIPTarget-available, 0)
 
 	// short is greater than the warm IP target alone when we have fewer total IPs than the minimum target
 	short = max(short, c.minimumIPTarget-stats.TotalIPs)
 
 	// over is the number of available IPs we have beyond the warm IP target
-	over = max(available-c.warmIPTarget, 0)
+	over = max(available-warmIPTarget, 0)
 
 	// over is less than the warm IP target alone if it would imply reducing total IPs below the minimum target
 	over = max(min(over, stats.TotalIPs-c.minimumIPTarget), 0)
@@ -1860,13 +2063,13 @@ func (c *IPAMContext) datastoreTargetState(stats *datastore.DataStoreStats) (sho
 		// Over will have number of IPs more than needed but with PD we would have allocated in chunks of /28
 		// Say assigned = 1, warm ip target = 16, this will need 2 prefixes. But over will return 15.
 		// Hence we need to check if 'over' number of IPs are needed to maintain the warm targets
-		prefixNeededForWarmIP := datastore.DivCeil(stats.AssignedIPs+c.warmIPTarget, numIPsPerPrefix)
+		prefixNeededForWarmIP := datastore.DivCeil(stats.AssignedIPs+warmIPTarget, numIPsPerPrefix)
 		prefixNeededForMinIP := datastore.DivCeil(c.minimumIPTarget, numIPsPerPrefix)
 
 		// over will be number of prefixes over than needed but could be spread across used prefixes,
 		// say, after couple of pod churns, 3 prefixes are allocated with 1 IP each assigned and warm ip target is 15
 		// (J : is this needed? since we have to walk thru the loop of prefixes)
-		freePrefixes := c.dataStore.GetFreePrefixes()
+		freePrefixes := c.dataStore.GetFreePrefixes(stats.IPPool)
 		overPrefix := max(min(freePrefixes, stats.TotalPrefixes-prefixNeededForWarmIP), 0)
 		overPrefix = max(min(overPrefix, stats.TotalPrefixes-prefixNeededForMinIP), 0)
 		return shortPrefix, overPrefix, true
@@ -1874,13 +2077,13 @@ func (c *IPAMContext) datastoreTargetState(stats *datastore.DataStoreStats) (sho
 	return short, over, true
 }
 
-// datastorePrefixTargetState determines the number of prefixes short to reach WARM_PREFIX_TARGET
-func (c *IPAMContext) datastorePrefixTargetState() (short int, enabled bool) {
+// datastorePrefixTargetState determines the number of prefixes of the IP pool short to reach WARM_PREFIX_TARGET
+func (c *IPAMContext) datastorePrefixTargetState(ipPool string) (short int, enabled bool) {
 	if !c.warmPrefixTargetDefined() {
 		return 0, false
 	}
 	// /28 will consume 16 IPs so let's not allocate if not needed.
-	freePrefixesInStore := c.dataStore.GetFreePrefixes()
+	freePrefixesInStore := c.dataStore.GetFreePrefixes(ipPool)
 	toAllocate := max(c.warmPrefixTarget-freePrefixesInStore, 0)
 	log.Debugf("Prefix target is %d, short of %d prefixes, free %d prefixes", c.warmPrefixTarget, toAllocate, freePrefixesInStore)
 
@@ -1925,13 +2128,18 @@ func (c *IPAMContext) isNodeNonSchedulable() bool {
 }
 
 // GetConfigForDebug returns the active values of the configuration env vars (for debugging purposes).
-func GetConfigForDebug() map[string]interface{} {
+func (c *IPAMContext) GetConfigForDebug() map[string]interface{} {
 	return map[string]interface{}{
 		envWarmIPTarget:             getWarmIPTarget(),
 		envWarmENITarget:            getWarmENITarget(),
 		envCustomNetworkCfg:         UseCustomNetworkCfg(),
 		envManageENIsNonSchedulable: ManageENIsOnNonSchedulableNode(),
 		envSubnetDiscovery:          UseSubnetDiscovery(),
+		envIPPools:                  getIPPools(),
+		envENIConfigStatus:          utils.GetBoolAsStringEnvVar(envENIConfigStatus, false),
+		envIPAMDConfigMap:           os.Getenv(envIPAMDConfigMap),
+		envAdaptiveWarmPool:         utils.GetBoolAsStringEnvVar(envAdaptiveWarmPool, false),
+		envWarmPoolProfiles:         c.warmPoolProfiles,
 	}
 }
 
@@ -2111,13 +2319,13 @@ func (c *IPAMContext) tryUnassignPrefixFromENI(eniID string) {
 	}
 }
 
-func (c *IPAMContext) GetENIResourcesToAllocate() int {
+func (c *IPAMContext) GetENIResourcesToAllocate(ipPool string) int {
 	var resourcesToAllocate int
 	if c.enablePrefixDelegation {
-		resourcesToAllocate = min(c.getPrefixesNeeded(), c.maxPrefixesPerENI)
+		resourcesToAllocate = min(c.getPrefixesNeeded(ipPool), c.maxPrefixesPerENI)
 	} else {
 		resourcesToAllocate = c.maxIPsPerENI
-		short, _, warmTargetDefined := c.datastoreTargetState(nil)
+		short, _, warmTargetDefined := c.datastoreTargetState(c.dataStore.GetPoolIPStats(ipPool, ipV4AddrFamily))
 		if warmTargetDefined {
 			resourcesToAllocate = min(short, c.maxIPsPerENI)
 		}
@@ -2155,10 +2363,10 @@ func (c *IPAMContext) hasRoomForEni() bool {
 	return c.dataStore.GetENIs() < (c.maxENI - c.unmanagedENI - trunkEni)
 }
 
-func (c *IPAMContext) isDatastorePoolTooLow() (bool, *datastore.DataStoreStats) {
-	stats := c.dataStore.GetIPStats(ipV4AddrFamily)
-	// If max pods has been reached, pool is not too low
-	if stats.TotalIPs >= c.maxPods {
+func (c *IPAMContext) isDatastorePoolTooLow(ipPool string) (bool, *datastore.DataStoreStats) {
+	stats := c.dataStore.GetPoolIPStats(ipPool, ipV4AddrFamily)
+	// If max pods has been reached, pool is not too low. The IPs of all pools count towards max pods.
+	if c.dataStore.GetIPStats(ipV4AddrFamily).TotalIPs >= c.maxPods {
 		return false, stats
 	}
 
@@ -2178,7 +2386,7 @@ func (c *IPAMContext) isDatastorePoolTooLow() (bool, *datastore.DataStoreStats)
 	available := stats.AvailableAddresses()
 	poolTooLow := available < totalIPs*warmTarget || (warmTarget == 0 && available == 0)
 	if poolTooLow {
-		log.Debugf("IP pool is too low: available (%d) < ENI target (%d) * addrsPerENI (%d)", available, warmTarget, totalIPs)
+		log.Debugf("IP pool is too low%s: available (%d) < ENI target (%d) * addrsPerENI (%d)", ipPoolLogSuffix(ipPool), available, warmTarget, totalIPs)
 		c.logPoolStats(stats)
 	}
 	return poolTooLow, stats
@@ -2193,7 +2401,7 @@ func (c *IPAMContext) isDatastorePoolTooHigh(stats *datastore.DataStoreStats) bo
 
 	// For the existing ENIs check if we can cleanup prefixes
 	if c.warmPrefixTargetDefined() {
-		freePrefixes := c.dataStore.GetFreePrefixes()
+		freePrefixes := c.dataStore.GetFreePrefixes(stats.IPPool)
 		poolTooHigh := freePrefixes > c.warmPrefixTarget
 		if poolTooHigh {
 			log.Debugf("Prefix pool is high so might be able to deallocate - free prefixes: %d, warm prefix target: %d", freePrefixes, c.warmPrefixTarget)
@@ -2229,24 +2437,36 @@ func (c *IPAMContext) DeallocCidrs(eniID string, deletableCidrs []datastore.Cidr
 		}
 	}
 
-	if err := c.nholuongutClient.DeallocPrefixAddresses(eniID, deletablePrefixes); err != nil {
+	err := c.nholuongutClient.DeallocPrefixAddresses(eniID, deletablePrefixes)
+	if err != nil {
 		log.Warnf("Failed to free Prefixes %v from ENI %s: %s", deletablePrefixes, eniID, err)
 	}
+	if len(deletablePrefixes) > 0 {
+		c.recordPoolAction(poolActionFreeCidrs, c.getENIIPPool(eniID), eniID, deletablePrefixes, err)
+	}
 
-	if err := c.nholuongutClient.DeallocIPAddresses(eniID, deletableIPs); err != nil {
+	err = c.nholuongutClient.DeallocIPAddresses(eniID, deletableIPs)
+	if err != nil {
 		log.Warnf("Failed to free IPs %v from ENI %s: %s", deletableIPs, eniID, err)
 	}
+	if len(deletableIPs) > 0 {
+		deletableIPCidrs := make([]string, 0, len(deletableIPs))
+		for _, ip := range deletableIPs {
+			deletableIPCidrs = append(deletableIPCidrs, ip+"/32")
+		}
+		c.recordPoolAction(poolActionFreeCidrs, c.getENIIPPool(eniID), eniID, deletableIPCidrs, err)
+	}
 }
 
-// getPrefixesNeeded returns the number of prefixes need to be allocated to the ENI
-func (c *IPAMContext) getPrefixesNeeded() int {
+// getPrefixesNeeded returns the number of prefixes need to be allocated to the ENI of the IP pool
+func (c *IPAMContext) getPrefixesNeeded(ipPool string) int {
 	// By default allocate 1 prefix at a time
 	toAllocate := 1
 
 	// TODO - post GA we can evaluate to see if these two calls can be merged.
 	// datastoreTargetState already has complex math so adding Prefix target will make it even more complex.
-	short, _, warmIPTargetsDefined := c.datastoreTargetState(nil)
-	shortPrefixes, warmPrefixTargetDefined := c.datastorePrefixTargetState()
+	short, _, warmIPTargetsDefined := c.datastoreTargetState(c.dataStore.GetPoolIPStats(ipPool, ipV4AddrFamily))
+	shortPrefixes, warmPrefixTargetDefined := c.datastorePrefixTargetState(ipPool)
 
 	// WARM_IP_TARGET takes precendence over WARM_PREFIX_TARGET
 	if warmIPTargetsDefined {
@@ -2280,12 +2500,11 @@ func (c *IPAMContext) initENIAndIPLimits() (err error) {
 	return nil
 }
 
+// isConfigValid returns whether the configuration is supported. It runs on start, and again each time the ConfigMap of
+// IPAMD_CONFIG_MAP is reloaded.
 func (c *IPAMContext) isConfigValid() bool {
-	// Validate that only one among v4 and v6 is enabled.
-	if c.enableIPv4 && c.enableIPv6 {
-		log.Errorf("IPv4 and IPv6 are both enabled. VPC CNI currently does not support dual stack mode")
-		return false
-	} else if !c.enableIPv4 && !c.enableIPv6 {
+	// Validate that at least one among v4 and v6 is enabled.
+	if !c.enableIPv4 && !c.enableIPv6 {
 		log.Errorf("IPv4 and IPv6 are both disabled. One of them have to be enabled")
 		return false
 	}
@@ -2306,6 +2525,31 @@ func (c *IPAMContext) isConfigValid() bool {
 		c.enablePrefixDelegation = false
 	}
 
+	// The ENIs of IP pools only provide pods with IPv4 addresses
+	if c.enableIPv6 && len(c.ipPools) > 0 {
+		log.Errorf("IP pools are only supported in IPv4 mode. Please unset %s", envIPPools)
+		return false
+	}
+
+	// Elastic IPs only carry IPv4 traffic
+	if c.enableIPv6 && c.egressEIPs != nil {
+		log.Errorf("Egress Elastic IPs are only supported in IPv4 mode. Please unset %s", envEnableEgressEIP)
+		return false
+	}
+
+	// SNAT is only done for IPv4 traffic
+	if c.enableIPv6 && c.snatPolicies != nil {
+		log.Errorf("SNAT policies are only supported in IPv4 mode. Please unset %s", envEnableSNATPolicy)
+		return false
+	}
+
+	// In dual stack mode, pods get an IPv4 address from the ENI pool and an IPv6 address from the primary ENI prefix.
+	// Branch ENIs only carry a single address family, so Security Groups for Pods is not supported.
+	if c.enableIPv4 && c.enableIPv6 && c.enablePodENI {
+		log.Errorf("IPv4 and IPv6 are both enabled. Security Groups for Pods is not supported in dual stack mode")
+		return false
+	}
+
 	return true
 }
 
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"testing"
@@ -620,7 +621,7 @@ func testIncreaseIPPool(t *testing.T, useENIConfig bool, unschedulabeNode bool,
 		}
 		m.k8sClient.Create(ctx, &fakeENIConfig)
 	}
-	mockContext.increaseDatastorePool(ctx)
+	mockContext.increaseDatastorePool(ctx, datastore.DefaultIPPool)
 }
 
 func assertAllocationExternalCalls(shouldCall bool, useENIConfig bool, m *testMocks, sg []*string, podENIConfig *eniconfigscheme.ENIConfigSpec, eni2 string, eniMetadata []nholuongututils.ENIMetadata, subnetDiscovery bool) {
@@ -630,13 +631,13 @@ func assertAllocationExternalCalls(shouldCall bool, useENIConfig bool, m *testMo
 	}
 
 	if useENIConfig {
-		m.nholuongututils.EXPECT().AllocENI(true, sg, podENIConfig.Subnet, 14).Times(callCount).Return(eni2, nil)
+		m.nholuongututils.EXPECT().AllocENI(true, sg, podENIConfig.Subnet, 14, nholuongututils.ENIOptions{}).Times(callCount).Return(eni2, nil)
 	} else if subnetDiscovery {
 		m.nholuongututils.EXPECT().AllocIPAddresses(primaryENIid, 14).Times(callCount).Return(nil, nholuonguterr.New("InsufficientFreeAddressesInSubnet", "", errors.New("err")))
 		m.nholuongututils.EXPECT().AllocIPAddresses(primaryENIid, 1).Times(callCount).Return(nil, nholuonguterr.New("InsufficientFreeAddressesInSubnet", "", errors.New("err")))
-		m.nholuongututils.EXPECT().AllocENI(false, nil, "", 14).Times(callCount).Return(eni2, nil)
+		m.nholuongututils.EXPECT().AllocENI(false, nil, "", 14, nholuongututils.ENIOptions{}).Times(callCount).Return(eni2, nil)
 	} else {
-		m.nholuongututils.EXPECT().AllocENI(false, nil, "", 14).Times(callCount).Return(eni2, nil)
+		m.nholuongututils.EXPECT().AllocENI(false, nil, "", 14, nholuongututils.ENIOptions{}).Times(callCount).Return(eni2, nil)
 	}
 	m.nholuongututils.EXPECT().GetPrimaryENI().Times(callCount).Return(primaryENIid)
 	m.nholuongututils.EXPECT().WaitForENIAndIPsAttached(secENIid, 14).Times(callCount).Return(eniMetadata[1], nil)
@@ -703,13 +704,13 @@ func testIncreasePrefixPool(t *testing.T, useENIConfig, subnetDiscovery bool) {
 	}
 
 	if useENIConfig {
-		m.nholuongututils.EXPECT().AllocENI(true, sg, podENIConfig.Subnet, 1).Return(eni2, nil)
+		m.nholuongututils.EXPECT().AllocENI(true, sg, podENIConfig.Subnet, 1, nholuongututils.ENIOptions{}).Return(eni2, nil)
 	} else if subnetDiscovery {
 		m.nholuongututils.EXPECT().AllocIPAddresses(primaryENIid, 1).Return(nil, nholuonguterr.New("InsufficientFreeAddressesInSubnet", "", errors.New("err")))
 		m.nholuongututils.EXPECT().AllocIPAddresses(primaryENIid, 1).Return(nil, nholuonguterr.New("InsufficientFreeAddressesInSubnet", "", errors.New("err")))
-		m.nholuongututils.EXPECT().AllocENI(false, nil, "", 1).Return(eni2, nil)
+		m.nholuongututils.EXPECT().AllocENI(false, nil, "", 1, nholuongututils.ENIOptions{}).Return(eni2, nil)
 	} else {
-		m.nholuongututils.EXPECT().AllocENI(false, nil, "", 1).Return(eni2, nil)
+		m.nholuongututils.EXPECT().AllocENI(false, nil, "", 1, nholuongututils.ENIOptions{}).Return(eni2, nil)
 	}
 
 	eniMetadata := []nholuongututils.ENIMetadata{
@@ -779,7 +780,7 @@ func testIncreasePrefixPool(t *testing.T, useENIConfig, subnetDiscovery bool) {
 		m.k8sClient.Create(ctx, &fakeENIConfig)
 	}
 
-	mockContext.increaseDatastorePool(ctx)
+	mockContext.increaseDatastorePool(ctx, datastore.DefaultIPPool)
 }
 
 // TestDecreaseIPPool checks that the deallocation honors the warm IP targets when deallocations happens across multiple enis
@@ -865,7 +866,7 @@ func TestTryAddIPToENI(t *testing.T) {
 
 	mockContext.dataStore = testDatastore()
 
-	m.nholuongututils.EXPECT().AllocENI(false, nil, "", warmIPTarget).Return(secENIid, nil)
+	m.nholuongututils.EXPECT().AllocENI(false, nil, "", warmIPTarget, nholuongututils.ENIOptions{}).Return(secENIid, nil)
 	eniMetadata := []nholuongututils.ENIMetadata{
 		{
 			ENIID:          primaryENIid,
@@ -910,7 +911,7 @@ func TestTryAddIPToENI(t *testing.T) {
 		Status:     v1.NodeStatus{},
 	}
 	m.k8sClient.Create(ctx, &fakeNode)
-	mockContext.increaseDatastorePool(ctx)
+	mockContext.increaseDatastorePool(ctx, datastore.DefaultIPPool)
 }
 
 func TestNodeIPPoolReconcile(t *testing.T) {
@@ -1281,7 +1282,7 @@ func TestIPAMContext_nodeIPPoolTooLow(t *testing.T) {
 				enablePrefixDelegation: false,
 				maxPods:                tt.fields.maxPods,
 			}
-			if got, _ := c.isDatastorePoolTooLow(); got != tt.want {
+			if got, _ := c.isDatastorePoolTooLow(datastore.DefaultIPPool); got != tt.want {
 				t.Errorf("nodeIPPoolTooLow() = %v, want %v", got, tt.want)
 			}
 		})
@@ -1329,7 +1330,7 @@ func TestIPAMContext_nodePrefixPoolTooLow(t *testing.T) {
 				enablePrefixDelegation: true,
 				maxPods:                tt.fields.maxPods,
 			}
-			if got, _ := c.isDatastorePoolTooLow(); got != tt.want {
+			if got, _ := c.isDatastorePoolTooLow(datastore.DefaultIPPool); got != tt.want {
 				t.Errorf("nodeIPPoolTooLow() = %v, want %v", got, tt.want)
 			}
 		})
@@ -1636,6 +1637,23 @@ func TestPodENIConfigFlag(t *testing.T) {
 	assert.False(t, disabled)
 }
 
+func TestDsBackingStore(t *testing.T) {
+	_ = os.Setenv(envBackingStorePath, filepath.Join(t.TempDir(), "ipam.json"))
+	defer os.Unsetenv(envBackingStorePath)
+
+	_, isJSONFile := dsBackingStore().(*datastore.JSONFile)
+	assert.True(t, isJSONFile)
+
+	_ = os.Setenv(envBackingStoreType, backingStoreTypeJournal)
+	_, isJournalFile := dsBackingStore().(*datastore.JournalFile)
+	assert.True(t, isJournalFile)
+
+	_ = os.Setenv(envBackingStoreType, "unknown")
+	_, isJSONFile = dsBackingStore().(*datastore.JSONFile)
+	assert.True(t, isJSONFile)
+	_ = os.Unsetenv(envBackingStoreType)
+}
+
 func TestNodeIPPoolReconcileBadIMDSData(t *testing.T) {
 	m := setup(t)
 	defer m.ctrl.Finish()
@@ -2138,6 +2156,27 @@ func TestIsConfigValid(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "both v4 and v6 enabled in PD mode",
+			fields: fields{
+				ipV4Enabled:             true,
+				ipV6Enabled:             true,
+				prefixDelegationEnabled: true,
+				isNitroInstance:         true,
+			},
+			want: true,
+		},
+		{
+			name: "ppsg enabled in dual stack mode",
+			fields: fields{
+				ipV4Enabled:             true,
+				ipV6Enabled:             true,
+				prefixDelegationEnabled: true,
+				podENIEnabled:           true,
+				isNitroInstance:         true,
+			},
+			want: false,
+		},
 		{
 			name: "v4 disabled and v6 enabled in PD mode on Non-Nitro instance",
 			fields: fields{
@@ -2430,7 +2469,7 @@ func TestPodENIErrInc(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Mock nholuongut API error
-	m.nholuongututils.EXPECT().AllocENI(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+	m.nholuongututils.EXPECT().AllocENI(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 		Return("", errors.New("API error")).Times(2) // Expect 2 calls
 
 	// Test case 1: First error
@@ -2461,7 +2500,7 @@ func TestPodENIErrInc(t *testing.T) {
 
 func (c *IPAMContext) tryAssignPodENI(ctx context.Context, pod *corev1.Pod, fnName string) error {
 	// Mock implementation for the test
-	_, err := c.nholuongutClient.AllocENI(false, nil, "", 0)
+	_, err := c.nholuongutClient.AllocENI(false, nil, "", 0, nholuongututils.ENIOptions{})
 	if err != nil {
 		prometheusmetrics.PodENIErr.With(prometheus.Labels{"fn": fnName}).Inc()
 		return err
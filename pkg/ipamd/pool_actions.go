@@ -0,0 +1,92 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//      http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"sync"
+	"time"
+)
+
+// maxPoolActions is the number of pool actions kept for introspection
+const maxPoolActions = 100
+
+// Kinds of pool actions
+const (
+	poolActionAllocateENI = "AllocateENI"
+	poolActionFreeENI     = "FreeENI"
+	poolActionAssignCidrs = "AssignCidrs"
+	poolActionFreeCidrs   = "FreeCidrs"
+)
+
+// poolAction is a change ipamd made to the ENIs or addresses of the node. It is part of the /v2/pool-actions schema.
+type poolAction struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	IPPool string    `json:"ipPool,omitempty"`
+	ENIID  string    `json:"eniID,omitempty"`
+	// Cidrs are the secondary IPs (/32) or prefixes assigned or freed
+	Cidrs []string `json:"cidrs,omitempty"`
+	// Error is set if the action failed
+	Error string `json:"error,omitempty"`
+}
+
+// poolActionLog keeps the last maxPoolActions pool actions, its zero value is ready to use
+type poolActionLog struct {
+	sync.Mutex
+	actions []poolAction
+	// next is the index of actions the next action is written at once it is full
+	next int
+}
+
+// add records the action, dropping the oldest one if the log is full
+func (l *poolActionLog) add(action poolAction) {
+	l.Lock()
+	defer l.Unlock()
+	if len(l.actions) < maxPoolActions {
+		l.actions = append(l.actions, action)
+		return
+	}
+	l.actions[l.next] = action
+	l.next = (l.next + 1) % maxPoolActions
+}
+
+// recent returns up to limit actions, the most recent first. All actions are returned if limit is 0.
+func (l *poolActionLog) recent(limit int) []poolAction {
+	l.Lock()
+	defer l.Unlock()
+	if limit <= 0 || limit > len(l.actions) {
+		limit = len(l.actions)
+	}
+	ret := make([]poolAction, 0, limit)
+	for i := 0; i < limit; i++ {
+		// The most recent action is the one before next
+		ret = append(ret, l.actions[(l.next-1-i+2*len(l.actions))%len(l.actions)])
+	}
+	return ret
+}
+
+// recordPoolAction adds the action to the pool actions shown by introspection
+func (c *IPAMContext) recordPoolAction(action, ipPool, eniID string, cidrs []string, err error) {
+	entry := poolAction{
+		Time:   time.Now(),
+		Action: action,
+		IPPool: ipPool,
+		ENIID:  eniID,
+		Cidrs:  cidrs,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	c.poolActions.add(entry)
+}
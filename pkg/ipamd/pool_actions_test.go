@@ -0,0 +1,42 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolActionLog(t *testing.T) {
+	var l poolActionLog
+	assert.Empty(t, l.recent(0))
+
+	for i := 0; i < maxPoolActions+10; i++ {
+		l.add(poolAction{ENIID: fmt.Sprintf("eni-%d", i)})
+	}
+	actions := l.recent(0)
+	assert.Len(t, actions, maxPoolActions)
+	// The oldest actions were dropped, the most recent one comes first
+	assert.Equal(t, fmt.Sprintf("eni-%d", maxPoolActions+9), actions[0].ENIID)
+	assert.Equal(t, "eni-10", actions[maxPoolActions-1].ENIID)
+
+	actions = l.recent(3)
+	assert.Equal(t, []string{
+		fmt.Sprintf("eni-%d", maxPoolActions+9),
+		fmt.Sprintf("eni-%d", maxPoolActions+8),
+		fmt.Sprintf("eni-%d", maxPoolActions+7),
+	}, []string{actions[0].ENIID, actions[1].ENIID, actions[2].ENIID})
+}
@@ -20,6 +20,7 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
@@ -33,8 +34,10 @@ import (
 
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/networkutils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/sgpp"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/rpc"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/utils/prometheusmetrics"
+	corev1 "k8s.io/api/core/v1"
 	k8serror "k8s.io/apimachinery/pkg/api/errors"
 )
 
@@ -43,6 +46,15 @@ const (
 	grpcHealthServiceName = "grpc.health.v1.nholuongut-node"
 
 	vpccniPodIPKey = "vpc.amazonnholuongut.com/pod-ips"
+	// stickyIPKey opts a pod in to get its address back when it is recreated with the same namespace/name
+	stickyIPKey = "vpc.amazonnholuongut.com/sticky-ip"
+	// staticIPKey asks for a specific secondary IP, e.g. "10.0.1.5", or for an address of a specific /28 prefix,
+	// e.g. "10.0.1.16/28"
+	staticIPKey = "vpc.amazonnholuongut.com/static-ip"
+
+	// gcMinAllocationAge keeps GarbageCollect from releasing the addresses of a sandbox whose ADD is still in flight,
+	// which the container runtime may not list as a valid attachment yet
+	gcMinAllocationAge = time.Minute
 )
 
 // server controls RPC service responses.
@@ -68,6 +80,7 @@ func (s *server) AddNetwork(ctx context.Context, in *rpc.AddNetworkRequest) (*rp
 		in.Netns, in.ContainerID, in.IfName)
 	log.Debugf("AddNetworkRequest: %s", in)
 	prometheusmetrics.AddIPCnt.Inc()
+	s.ipamContext.adaptiveWarmPool.recordAdd(time.Now())
 
 	// Do this early, but after logging trace
 	if err := s.validateVersion(in.ClientVersion); err != nil {
@@ -77,13 +90,22 @@ func (s *server) AddNetwork(ctx context.Context, in *rpc.AddNetworkRequest) (*rp
 
 	failureResponse := rpc.AddNetworkReply{Success: false}
 	var deviceNumber, vlanID, trunkENILinkIndex int
-	var ipv4Addr, ipv6Addr, branchENIMAC, podENISubnetGW string
+	var ipv4Addr, ipv6Addr, branchENIID, branchENIMAC, podENISubnetGW string
+	var extraInterfaces []*rpc.PodInterface
 	var err error
+	// The pod is only looked up once, for all the labels and annotations the request depends on. Without them the pod
+	// could get an address of the wrong IP pool, or lose its static IP, sticky IP or bandwidth limits.
+	var pod *corev1.Pod
+	if in.K8S_POD_NAMESPACE != "" && in.K8S_POD_NAME != "" {
+		if pod, err = s.ipamContext.GetPod(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE); err != nil {
+			log.Errorf("Send AddNetworkReply: Failed to get pod: %v", err)
+			return nil, errors.Wrapf(err, "failed to get pod %s/%s", in.K8S_POD_NAMESPACE, in.K8S_POD_NAME)
+		}
+	}
 	if s.ipamContext.enablePodENI {
 		// Check pod spec for Branch ENI
-		pod, err := s.ipamContext.GetPod(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE)
-		if err != nil {
-			log.Warnf("Send AddNetworkReply: Failed to get pod: %v", err)
+		if pod == nil {
+			log.Warnf("Send AddNetworkReply: No pod to check for a pod ENI")
 			return &failureResponse, nil
 		}
 		limits := pod.Spec.Containers[0].Resources.Limits
@@ -116,6 +138,7 @@ func (s *server) AddNetwork(ctx context.Context, in *rpc.AddNetworkRequest) (*rp
 					} else {
 						ipv4Addr = firstENI.PrivateIP
 					}
+					branchENIID = firstENI.ENIID
 					branchENIMAC = firstENI.IfAddress
 					vlanID = firstENI.VlanID
 					log.Debugf("Pod vlandId: %d", vlanID)
@@ -156,7 +179,40 @@ func (s *server) AddNetwork(ctx context.Context, in *rpc.AddNetworkRequest) (*rp
 		}
 	}
 
-	if s.ipamContext.enableIPv4 && ipv4Addr == "" ||
+	var podAnnotations map[string]string
+	if pod != nil {
+		podAnnotations = pod.Annotations
+	}
+	var ingressBandwidth, egressBandwidth uint64
+	if !s.ipamContext.enableBandwidthPlugin {
+		// The rate limits are passed on to the CNI plugin, which has no access to the API server
+		if ingressBandwidth, egressBandwidth, err = podBandwidth(podAnnotations); err != nil {
+			log.Errorf("Send AddNetworkReply: Failed to parse the bandwidth annotations: %v", err)
+			return nil, errors.Wrapf(err, "failed to limit the bandwidth of pod %s/%s", in.K8S_POD_NAMESPACE, in.K8S_POD_NAME)
+		}
+	}
+
+	if vlanID != 0 {
+		// Record the branch ENI of the pod, so that DelNetwork does not depend on the pod annotation
+		if in.ContainerID == "" || in.IfName == "" || in.NetworkName == "" {
+			log.Errorf("Unable to generate IPAMKey from %+v", in)
+			return nil, errors.Errorf("failed to record the branch ENI of pod %s/%s: missing sandbox key", in.K8S_POD_NAMESPACE, in.K8S_POD_NAME)
+		}
+		ipamKey := datastore.IPAMKey{
+			ContainerID: in.ContainerID,
+			IfName:      in.IfName,
+			NetworkName: in.NetworkName,
+		}
+		ipamMetadata := datastore.IPAMMetadata{
+			K8SPodNamespace: in.K8S_POD_NAMESPACE,
+			K8SPodName:      in.K8S_POD_NAME,
+			HostVethName:    s.ipamContext.podHostVethName(in.K8S_POD_NAMESPACE, in.K8S_POD_NAME, true),
+		}
+		if err = s.ipamContext.dataStore.AssignPodENIAddress(ipamKey, ipamMetadata, ipv4Addr, ipv6Addr, branchENIID, vlanID); err != nil {
+			log.Errorf("Send AddNetworkReply: Failed to record branch ENI %s: %v", branchENIID, err)
+			return nil, errors.Wrapf(err, "failed to record branch ENI %s of pod %s/%s", branchENIID, in.K8S_POD_NAMESPACE, in.K8S_POD_NAME)
+		}
+	} else if s.ipamContext.enableIPv4 && ipv4Addr == "" ||
 		s.ipamContext.enableIPv6 && ipv6Addr == "" {
 		if in.ContainerID == "" || in.IfName == "" || in.NetworkName == "" {
 			log.Errorf("Unable to generate IPAMKey from %+v", in)
@@ -167,11 +223,71 @@ func (s *server) AddNetwork(ctx context.Context, in *rpc.AddNetworkRequest) (*rp
 			IfName:      in.IfName,
 			NetworkName: in.NetworkName,
 		}
+		ipPool, poolErr := s.ipamContext.podIPPool(in.K8S_POD_NAMESPACE, pod)
+		if poolErr != nil {
+			// Don't fall back to the default pool either
+			log.Errorf("Send AddNetworkReply: Failed to look up the IP pool: %v", poolErr)
+			return nil, errors.Wrapf(poolErr, "failed to look up the IP pool of pod %s/%s", in.K8S_POD_NAMESPACE, in.K8S_POD_NAME)
+		}
 		ipamMetadata := datastore.IPAMMetadata{
 			K8SPodNamespace: in.K8S_POD_NAMESPACE,
 			K8SPodName:      in.K8S_POD_NAME,
+			HostVethName:    s.ipamContext.podHostVethName(in.K8S_POD_NAMESPACE, in.K8S_POD_NAME, false),
+			StickyIP:        podAnnotations[stickyIPKey] == "true",
+			IPPool:          ipPool,
+		}
+		if !s.ipamContext.hasIPPool(ipamMetadata.IPPool) {
+			// Don't fall back to another pool, the pod may only be allowed in the subnet of its pool
+			log.Errorf("Send AddNetworkReply: IP pool %q is not configured on this node", ipamMetadata.IPPool)
+			return nil, errors.Errorf("IP pool %q of pod %s/%s is not configured on this node", ipamMetadata.IPPool, in.K8S_POD_NAMESPACE, in.K8S_POD_NAME)
+		}
+		if staticIP, ok := podAnnotations[staticIPKey]; ok {
+			var staticCidr net.IPNet
+			if s.ipamContext.enableIPv6 {
+				err = errors.Errorf("%s is only supported in IPv4 mode", staticIPKey)
+			} else if staticCidr, err = parseStaticIP(staticIP); err == nil {
+				ipv4Addr, deviceNumber, err = s.ipamContext.assignStaticIPv4Address(ipamKey, ipamMetadata, staticCidr)
+			}
+			if err != nil {
+				log.Errorf("Send AddNetworkReply: Failed to assign static IP %q: %v", staticIP, err)
+				return nil, errors.Wrapf(err, "failed to assign static IP %q of pod %s/%s", staticIP, in.K8S_POD_NAMESPACE, in.K8S_POD_NAME)
+			}
+		} else {
+			ipv4Addr, ipv6Addr, deviceNumber, err = s.ipamContext.dataStore.AssignPodIPAddress(ipamKey, ipamMetadata, s.ipamContext.enableIPv4, s.ipamContext.enableIPv6)
+		}
+		if value, ok := podAnnotations[extraInterfacesKey]; ok && err == nil {
+			var specs []extraInterfaceSpec
+			if s.ipamContext.enableIPv6 {
+				err = errors.Errorf("%s is only supported in IPv4 mode", extraInterfacesKey)
+			} else if specs, err = parseExtraInterfaces(value, in.IfName); err == nil {
+				extraInterfaces, err = s.assignExtraInterfaces(in, specs)
+			}
+			if err != nil {
+				// Release the address of the pod interface too, the CNI does not set up pods with part of their interfaces
+				eni, releasedIPv4Addr, _, _, unassignErr := s.ipamContext.dataStore.UnassignPodIPAddress(ipamKey)
+				if unassignErr != nil {
+					log.Warnf("Failed to release the address of sandbox %s: %v", in.ContainerID, unassignErr)
+				}
+				s.tryFreeReleasedIPv4Address(eni, releasedIPv4Addr)
+				log.Errorf("Send AddNetworkReply: Failed to assign extra interfaces: %v", err)
+				return nil, errors.Wrapf(err, "failed to assign the extra interfaces of pod %s/%s", in.K8S_POD_NAMESPACE, in.K8S_POD_NAME)
+			}
+		}
+		if err == nil && ipv4Addr != "" {
+			if err = s.ipamContext.assignEgressEIP(ctx, in.K8S_POD_NAMESPACE, ipv4Addr); err != nil {
+				// Without its Elastic IP, the traffic of the pod would be SNATed to the primary ENI address
+				s.releaseExtraInterfaces(ipamKey)
+				eni, releasedIPv4Addr, _, _, unassignErr := s.ipamContext.dataStore.UnassignPodIPAddress(ipamKey)
+				if unassignErr != nil {
+					log.Warnf("Failed to release the address of sandbox %s: %v", in.ContainerID, unassignErr)
+				}
+				s.tryFreeReleasedIPv4Address(eni, releasedIPv4Addr)
+				log.Errorf("Send AddNetworkReply: Failed to assign egress Elastic IP: %v", err)
+				return nil, errors.Wrapf(err, "failed to assign the egress Elastic IP of pod %s/%s", in.K8S_POD_NAMESPACE, in.K8S_POD_NAME)
+			}
+			// The SNAT policies selecting the pod are applied to its address in the background
+			s.ipamContext.triggerSNATPolicyUpdate()
 		}
-		ipv4Addr, ipv6Addr, deviceNumber, err = s.ipamContext.dataStore.AssignPodIPAddress(ipamKey, ipamMetadata, s.ipamContext.enableIPv4, s.ipamContext.enableIPv6)
 	}
 
 	var pbVPCV4cidrs, pbVPCV6cidrs []string
@@ -191,7 +307,8 @@ func (s *server) AddNetwork(ctx context.Context, in *rpc.AddNetworkRequest) (*rp
 				pbVPCV4cidrs = append(pbVPCV4cidrs, cidr)
 			}
 		}
-	} else if s.ipamContext.enableIPv6 && ipv6Addr != "" {
+	}
+	if s.ipamContext.enableIPv6 && ipv6Addr != "" {
 		pbVPCV6cidrs, err = s.ipamContext.nholuongutClient.GetVPCIPv6CIDRs()
 		if err != nil {
 			return nil, err
@@ -203,13 +320,8 @@ func (s *server) AddNetwork(ctx context.Context, in *rpc.AddNetworkRequest) (*rp
 
 	if s.ipamContext.enablePodIPAnnotation {
 		// On ADD, we pass empty string as there is no IP being released
-		if ipv4Addr != "" {
-			err = s.ipamContext.AnnotatePod(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE, vpccniPodIPKey, ipv4Addr, "")
-			if err != nil {
-				log.Errorf("Failed to add the pod annotation: %v", err)
-			}
-		} else if ipv6Addr != "" {
-			err = s.ipamContext.AnnotatePod(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE, vpccniPodIPKey, ipv6Addr, "")
+		if podIPs := podIPAnnotationValue(ipv4Addr, ipv6Addr); podIPs != "" {
+			err = s.ipamContext.AnnotatePod(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE, vpccniPodIPKey, podIPs, "")
 			if err != nil {
 				log.Errorf("Failed to add the pod annotation: %v", err)
 			}
@@ -228,6 +340,9 @@ func (s *server) AddNetwork(ctx context.Context, in *rpc.AddNetworkRequest) (*rp
 		PodENISubnetGW:    podENISubnetGW,
 		ParentIfIndex:     int32(trunkENILinkIndex),
 		NetworkPolicyMode: s.ipamContext.networkPolicyMode,
+		ExtraInterfaces:   extraInterfaces,
+		IngressBandwidth:  ingressBandwidth,
+		EgressBandwidth:   egressBandwidth,
 	}
 
 	log.Infof("Send AddNetworkReply: IPv4Addr: %s, IPv6Addr: %s, DeviceNumber: %d, err: %v", ipv4Addr, ipv6Addr, deviceNumber, err)
@@ -245,7 +360,7 @@ func (s *server) DelNetwork(ctx context.Context, in *rpc.DelNetworkRequest) (*rp
 	log.Infof("Received DelNetwork for Sandbox %s", in.ContainerID)
 	log.Debugf("DelNetworkRequest: %s", in)
 	prometheusmetrics.DelIPCnt.With(prometheus.Labels{"reason": in.Reason}).Inc()
-	var ipv4Addr, ipv6Addr, cidrStr string
+	s.ipamContext.adaptiveWarmPool.recordDel(time.Now())
 
 	// Do this early, but after logging trace
 	if err := s.validateVersion(in.ClientVersion); err != nil {
@@ -258,31 +373,27 @@ func (s *server) DelNetwork(ctx context.Context, in *rpc.DelNetworkRequest) (*rp
 		IfName:      in.IfName,
 		NetworkName: in.NetworkName,
 	}
-	eni, ip, deviceNumber, err := s.ipamContext.dataStore.UnassignPodIPAddress(ipamKey)
-	if s.ipamContext.enableIPv4 {
-		ipv4Addr = ip
-		cidr := net.IPNet{IP: net.ParseIP(ip), Mask: net.IPv4Mask(255, 255, 255, 255)}
-		cidrStr = cidr.String()
-	} else if s.ipamContext.enableIPv6 {
-		ipv6Addr = ip
-	}
-
-	if s.ipamContext.enableIPv4 && eni != nil {
-		//cidrStr will be pod IP i.e, IP/32 for v4 (or) IP/128 for v6.
-		// Case 1: PD is enabled but IP/32 key in AvailableIPv4Cidrs[cidrStr] exists, this means it is a secondary IP. Added IsPrefix check just for sanity.
-		// So this IP should be released immediately.
-		// Case 2: PD is disabled then IP/32 key in AvailableIPv4Cidrs[cidrStr] will not exists since key to AvailableIPv4Cidrs will be either /28 prefix or /32
-		// secondary IP. Hence now see if we need free up a prefix is no other pods are using it.
-		if s.ipamContext.enablePrefixDelegation && eni.AvailableIPv4Cidrs[cidrStr] != nil && eni.AvailableIPv4Cidrs[cidrStr].IsPrefix == false {
-			log.Debugf("IP belongs to secondary pool with PD enabled so free IP from EC2")
-			s.ipamContext.tryUnassignIPFromENI(eni.ID)
-		} else if !s.ipamContext.enablePrefixDelegation && eni.AvailableIPv4Cidrs[cidrStr] == nil {
-			log.Debugf("IP belongs to prefix pool with PD disabled so try free prefix from EC2")
-			s.ipamContext.tryUnassignPrefixFromENI(eni.ID)
-		}
-	}
+	eni, ipv4Addr, ipv6Addr, deviceNumber, err := s.ipamContext.dataStore.UnassignPodIPAddress(ipamKey)
+	s.ipamContext.releaseEgressEIP(ipv4Addr)
+	s.ipamContext.triggerSNATPolicyUpdate()
+	s.tryFreeReleasedIPv4Address(eni, ipv4Addr)
+	extraInterfaces := s.releaseExtraInterfaces(ipamKey)
 
 	if err == datastore.ErrUnknownPod && s.ipamContext.enablePodENI {
+		// Pods using a branch ENI are recorded by AddNetwork. Pods added before ipamd recorded them are
+		// looked up through their annotation below.
+		entry, unassignErr := s.ipamContext.dataStore.UnassignPodENIAddress(ipamKey)
+		if unassignErr == nil {
+			log.Infof("Send DelNetworkReply: IPv4Addr: %s, IPv6Addr: %s, PodVlanId: %d", entry.IPv4, entry.IPv6, entry.VlanID)
+			return &rpc.DelNetworkReply{
+				Success:   true,
+				PodVlanId: int32(entry.VlanID),
+				IPv4Addr:  entry.IPv4,
+				IPv6Addr:  entry.IPv6}, nil
+		} else if unassignErr != datastore.ErrUnknownPod {
+			log.Warnf("Send DelNetworkReply: Failed to release branch ENI pod: %v", unassignErr)
+			return &rpc.DelNetworkReply{Success: false}, unassignErr
+		}
 		pod, err := s.ipamContext.GetPod(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE)
 		if err != nil {
 			if k8serror.IsNotFound(err) {
@@ -309,7 +420,7 @@ func (s *server) DelNetwork(ctx context.Context, in *rpc.DelNetworkRequest) (*rp
 
 	if s.ipamContext.enablePodIPAnnotation {
 		// On DEL, we pass IP being released
-		err = s.ipamContext.AnnotatePod(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE, vpccniPodIPKey, "", ip)
+		err = s.ipamContext.AnnotatePod(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE, vpccniPodIPKey, "", podIPAnnotationValue(ipv4Addr, ipv6Addr))
 		if err != nil {
 			log.Errorf("Failed to delete the pod annotation: %v", err)
 		}
@@ -317,7 +428,222 @@ func (s *server) DelNetwork(ctx context.Context, in *rpc.DelNetworkRequest) (*rp
 
 	log.Infof("Send DelNetworkReply: IPv4Addr: %s, IPv6Addr: %s, DeviceNumber: %d, err: %v", ipv4Addr, ipv6Addr, deviceNumber, err)
 
-	return &rpc.DelNetworkReply{Success: err == nil, IPv4Addr: ipv4Addr, IPv6Addr: ipv6Addr, DeviceNumber: int32(deviceNumber)}, err
+	return &rpc.DelNetworkReply{Success: err == nil, IPv4Addr: ipv4Addr, IPv6Addr: ipv6Addr, DeviceNumber: int32(deviceNumber), ExtraInterfaces: extraInterfaces}, err
+}
+
+// CheckNetwork processes the CNI check network request. It fails if the sandbox no longer holds the addresses the pod
+// was set up with.
+func (s *server) CheckNetwork(ctx context.Context, in *rpc.CheckNetworkRequest) (*rpc.CheckNetworkReply, error) {
+	log.Debugf("CheckNetworkRequest: %s", in)
+
+	if err := s.validateVersion(in.ClientVersion); err != nil {
+		log.Warnf("Rejecting CheckNetwork request: %v", err)
+		return nil, err
+	}
+
+	ipamKey := datastore.IPAMKey{
+		ContainerID: in.ContainerID,
+		IfName:      in.IfName,
+		NetworkName: in.NetworkName,
+	}
+	entry, err := s.ipamContext.dataStore.GetPodAllocation(ipamKey)
+	if err == datastore.ErrUnknownPod && s.ipamContext.enablePodENI {
+		// Pods using a branch ENI which were added before ipamd recorded them are only known by their annotation
+		entry, err = s.podENIAllocation(in.K8S_POD_NAME, in.K8S_POD_NAMESPACE)
+	}
+	if err != nil {
+		log.Warnf("Send CheckNetworkReply: sandbox %s: %v", ipamKey, err)
+		return &rpc.CheckNetworkReply{Success: false}, err
+	}
+	if entry.IPv4 != in.IPv4Addr || entry.IPv6 != in.IPv6Addr {
+		err := errors.Errorf("sandbox %s holds IPv4Addr %q and IPv6Addr %q, not %q and %q", ipamKey, entry.IPv4, entry.IPv6,
+			in.IPv4Addr, in.IPv6Addr)
+		log.Warnf("Send CheckNetworkReply: %v", err)
+		return &rpc.CheckNetworkReply{Success: false}, err
+	}
+	log.Debugf("Send CheckNetworkReply: DeviceNumber: %d, PodVlanId: %d", entry.DeviceNumber, entry.VlanID)
+	return &rpc.CheckNetworkReply{Success: true, DeviceNumber: int32(entry.DeviceNumber), PodVlanId: int32(entry.VlanID)}, nil
+}
+
+// tryFreeReleasedIPv4Address gives the IPv4 address a pod released back to EC2 when the pool does not use it, that is
+// a secondary IP while prefix delegation is enabled, or the last address in use of a prefix while it is disabled
+func (s *server) tryFreeReleasedIPv4Address(eni *datastore.ENI, ipv4Addr string) {
+	if !s.ipamContext.enableIPv4 || eni == nil {
+		return
+	}
+	//cidrStr will be pod IP i.e, IP/32 for v4 (or) IP/128 for v6.
+	cidr := net.IPNet{IP: net.ParseIP(ipv4Addr), Mask: net.IPv4Mask(255, 255, 255, 255)}
+	cidrStr := cidr.String()
+	// Case 1: PD is enabled but IP/32 key in AvailableIPv4Cidrs[cidrStr] exists, this means it is a secondary IP. Added IsPrefix check just for sanity.
+	// So this IP should be released immediately.
+	// Case 2: PD is disabled then IP/32 key in AvailableIPv4Cidrs[cidrStr] will not exists since key to AvailableIPv4Cidrs will be either /28 prefix or /32
+	// secondary IP. Hence now see if we need free up a prefix is no other pods are using it.
+	if s.ipamContext.enablePrefixDelegation && eni.AvailableIPv4Cidrs[cidrStr] != nil && eni.AvailableIPv4Cidrs[cidrStr].IsPrefix == false {
+		log.Debugf("IP belongs to secondary pool with PD enabled so free IP from EC2")
+		s.ipamContext.tryUnassignIPFromENI(eni.ID)
+	} else if !s.ipamContext.enablePrefixDelegation && eni.AvailableIPv4Cidrs[cidrStr] == nil {
+		log.Debugf("IP belongs to prefix pool with PD disabled so try free prefix from EC2")
+		s.ipamContext.tryUnassignPrefixFromENI(eni.ID)
+	}
+}
+
+// GarbageCollect processes the CNI GC request. The allocations of the network whose attachment the container runtime
+// no longer knows are released, and returned so that the plugin can clean up their routes and rules on the host.
+func (s *server) GarbageCollect(ctx context.Context, in *rpc.GarbageCollectRequest) (*rpc.GarbageCollectReply, error) {
+	log.Infof("Received GarbageCollect for network %s with %d valid attachments", in.NetworkName, len(in.ValidAttachments))
+
+	if err := s.validateVersion(in.ClientVersion); err != nil {
+		log.Warnf("Rejecting GarbageCollect request: %v", err)
+		return nil, err
+	}
+
+	return s.garbageCollect(in, time.Now()), nil
+}
+
+// garbageCollect releases the stale allocations of the GC request assigned at least gcMinAllocationAge before now
+func (s *server) garbageCollect(in *rpc.GarbageCollectRequest, now time.Time) *rpc.GarbageCollectReply {
+	// The extra interfaces of a pod are set up by the ADD of its attachment, which is the only one the container
+	// runtime knows of, so that they are kept as long as the container of the attachment is
+	validContainerIDs := make(map[string]bool, len(in.ValidAttachments))
+	for _, attachment := range in.ValidAttachments {
+		validContainerIDs[attachment.ContainerID] = true
+	}
+	reply := &rpc.GarbageCollectReply{Success: true}
+	stale := s.ipamContext.dataStore.StaleAllocations(in.NetworkName, validContainerIDs, now.Add(-gcMinAllocationAge))
+	for _, ipamKey := range stale {
+		released, err := s.releaseStaleAllocation(ipamKey)
+		if err == datastore.ErrUnknownPod {
+			// Released by a DEL in the meantime
+			continue
+		}
+		if err != nil {
+			log.Warnf("GarbageCollect: failed to release sandbox %s: %v", ipamKey, err)
+			reply.Success = false
+			continue
+		}
+		prometheusmetrics.DelIPCnt.With(prometheus.Labels{"reason": "GarbageCollected"}).Inc()
+		reply.Released = append(reply.Released, released)
+	}
+	log.Infof("Send GarbageCollectReply: released %d of %d stale allocations", len(reply.Released), len(stale))
+	return reply
+}
+
+// releaseStaleAllocation releases the addresses or the branch ENI of a sandbox, ErrUnknownPod if it holds none
+func (s *server) releaseStaleAllocation(ipamKey datastore.IPAMKey) (*rpc.ReleasedAllocation, error) {
+	released := &rpc.ReleasedAllocation{ContainerID: ipamKey.ContainerID, IfName: ipamKey.IfName}
+	entry, err := s.ipamContext.dataStore.UnassignPodENIAddress(ipamKey)
+	if err == nil {
+		released.IPv4Addr = entry.IPv4
+		released.IPv6Addr = entry.IPv6
+		released.PodVlanId = int32(entry.VlanID)
+		return released, nil
+	} else if err != datastore.ErrUnknownPod {
+		return nil, err
+	}
+
+	eni, ipv4Addr, ipv6Addr, deviceNumber, err := s.ipamContext.dataStore.UnassignPodIPAddress(ipamKey)
+	if err != nil {
+		return nil, err
+	}
+	s.ipamContext.releaseEgressEIP(ipv4Addr)
+	s.ipamContext.triggerSNATPolicyUpdate()
+	s.tryFreeReleasedIPv4Address(eni, ipv4Addr)
+	released.IPv4Addr = ipv4Addr
+	released.IPv6Addr = ipv6Addr
+	released.DeviceNumber = int32(deviceNumber)
+	return released, nil
+}
+
+// Status processes the CNI STATUS request. It reports the addresses of the pool which can be assigned right away.
+func (s *server) Status(ctx context.Context, in *rpc.StatusRequest) (*rpc.StatusReply, error) {
+	log.Debugf("StatusRequest: %s", in)
+
+	if err := s.validateVersion(in.ClientVersion); err != nil {
+		log.Warnf("Rejecting Status request: %v", err)
+		return nil, err
+	}
+
+	reply := &rpc.StatusReply{Success: true}
+	if s.ipamContext.enableIPv4 {
+		reply.FreeIPv4Addresses = int64(s.ipamContext.dataStore.GetIPStats(ipV4AddrFamily).FreeAddresses())
+	}
+	if s.ipamContext.enableIPv6 {
+		reply.FreeIPv6Addresses = int64(s.ipamContext.dataStore.GetIPStats(ipV6AddrFamily).FreeAddresses())
+	}
+	if reply.FreeIPv4Addresses <= 0 && reply.FreeIPv6Addresses <= 0 {
+		// Reported rather than failed, as the pool manager replenishes the pool
+		log.Warnf("Status: the IP pool has no free address")
+	}
+	log.Debugf("Send StatusReply: FreeIPv4Addresses: %d, FreeIPv6Addresses: %d", reply.FreeIPv4Addresses, reply.FreeIPv6Addresses)
+	return reply, nil
+}
+
+// podENIAllocation returns the addresses of a pod using a branch ENI from its annotation, ErrUnknownPod if it has none
+func (s *server) podENIAllocation(podName, podNamespace string) (datastore.CheckpointEntry, error) {
+	pod, err := s.ipamContext.GetPod(podName, podNamespace)
+	if err != nil {
+		if k8serror.IsNotFound(err) {
+			return datastore.CheckpointEntry{}, datastore.ErrUnknownPod
+		}
+		return datastore.CheckpointEntry{}, err
+	}
+	val, branch := pod.Annotations["vpc.amazonnholuongut.com/pod-eni"]
+	if !branch {
+		return datastore.CheckpointEntry{}, datastore.ErrUnknownPod
+	}
+	var podENIData []PodENIData
+	if err := json.Unmarshal([]byte(val), &podENIData); err != nil || len(podENIData) < 1 {
+		return datastore.CheckpointEntry{}, errors.Errorf("failed to unmarshal PodENIData JSON %q", val)
+	}
+	return datastore.CheckpointEntry{
+		IPv4:         podENIData[0].PrivateIP,
+		IPv6:         podENIData[0].IPV6Addr,
+		DeviceNumber: -1,
+		VlanID:       podENIData[0].VlanID,
+	}, nil
+}
+
+// podHostVethName returns the name of the host side interface the CNI plugin creates for the pod, or "" if the pod
+// is unknown
+func (c *IPAMContext) podHostVethName(podNamespace, podName string, isBranchENI bool) string {
+	if podNamespace == "" || podName == "" {
+		return ""
+	}
+	prefix := c.vethPrefix
+	if isBranchENI {
+		prefix = sgpp.BuildHostVethNamePrefix(c.vethPrefix, c.podSGEnforcingMode)
+	}
+	return networkutils.GeneratePodHostVethName(prefix, podNamespace, podName)
+}
+
+// parseStaticIP parses the value of the static IP annotation, either an IPv4 address or a /28 prefix
+func parseStaticIP(val string) (net.IPNet, error) {
+	if !strings.Contains(val, "/") {
+		ip := net.ParseIP(val).To4()
+		if ip == nil {
+			return net.IPNet{}, errors.Errorf("invalid IPv4 address %q", val)
+		}
+		return net.IPNet{IP: ip, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	ip, prefix, err := net.ParseCIDR(val)
+	if err != nil || ip.To4() == nil {
+		return net.IPNet{}, errors.Errorf("invalid IPv4 prefix %q", val)
+	}
+	if ones, _ := prefix.Mask.Size(); ones != 28 || !ip.Equal(prefix.IP) {
+		return net.IPNet{}, errors.Errorf("invalid IPv4 prefix %q, a /28 prefix such as %s/28 is expected", val, prefix.IP)
+	}
+	return *prefix, nil
+}
+
+// podIPAnnotationValue returns the pod IPs to annotate the pod with. Dual stack pods get a comma separated list.
+func podIPAnnotationValue(ipv4Addr, ipv6Addr string) string {
+	if ipv4Addr != "" && ipv6Addr != "" {
+		return ipv4Addr + "," + ipv6Addr
+	}
+	if ipv4Addr != "" {
+		return ipv4Addr
+	}
+	return ipv6Addr
 }
 
 // RunRPCHandler handles request from gRPC
@@ -329,7 +655,9 @@ func (c *IPAMContext) RunRPCHandler(version string) error {
 		return errors.Wrap(err, "ipamd: failed to listen to gRPC port")
 	}
 	grpcServer := grpc.NewServer()
-	rpc.RegisterCNIBackendServer(grpcServer, &server{version: version, ipamContext: c})
+	rpcServer := &server{version: version, ipamContext: c}
+	rpc.RegisterCNIBackendServer(grpcServer, rpcServer)
+	rpc.RegisterIPAMBackendServer(grpcServer, rpcServer)
 	healthServer := health.NewServer()
 	// If ipamd can talk to the API server and to the EC2 API, the pod is healthy.
 	// No need to ever change this to HealthCheckResponse_NOT_SERVING since it's a local service only
@@ -15,10 +15,22 @@ package ipamd
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"testing"
+	"time"
 
+	"github.com/golang/mock/gomock"
+	"github.com/vishvananda/netlink"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/nholuongututils"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/networkutils"
 
 	pb "github.com/nholuongut/amazon-vpc-cni-k8s/rpc"
 
@@ -101,6 +113,7 @@ func TestServer_AddNetwork(t *testing.T) {
 
 	type fields struct {
 		ipV4AddressByENIID       map[string][]string
+		ipV4PrefixByENIID        map[string][]string
 		ipV6PrefixByENIID        map[string][]string
 		getVPCIPv4CIDRsCalls     []getVPCIPv4CIDRsCall
 		getVPCIPv6CIDRsCalls     []getVPCIPv6CIDRsCall
@@ -209,6 +222,44 @@ func TestServer_AddNetwork(t *testing.T) {
 				VPCv6CIDRs:   []string{"2001:db8::/56"},
 			},
 		},
+		{
+			name: "successfully allocated IPv4 and IPv6 Addresses in dual stack mode",
+			fields: fields{
+				ipV4PrefixByENIID: map[string][]string{
+					"eni-1": {"192.168.1.96/28"},
+				},
+				ipV6PrefixByENIID: map[string][]string{
+					"eni-1": {"2001:db8::/64"},
+				},
+				getVPCIPv4CIDRsCalls: []getVPCIPv4CIDRsCall{
+					{
+						cidrs: []string{"192.168.0.0/16"},
+					},
+				},
+				getVPCIPv6CIDRsCalls: []getVPCIPv6CIDRsCall{
+					{
+						cidrs: []string{"2001:db8::/56"},
+					},
+				},
+				useExternalSNATCalls: []useExternalSNATCall{
+					{
+						useExternalSNAT: true,
+					},
+				},
+				ipV4Enabled:             true,
+				ipV6Enabled:             true,
+				prefixDelegationEnabled: true,
+			},
+			want: &pb.AddNetworkReply{
+				Success:         true,
+				IPv4Addr:        "192.168.1.96",
+				IPv6Addr:        "2001:db8::",
+				DeviceNumber:    int32(0),
+				UseExternalSNAT: true,
+				VPCv4CIDRs:      []string{"192.168.0.0/16"},
+				VPCv6CIDRs:      []string{"2001:db8::/56"},
+			},
+		},
 		{
 			name: "failed allocating IPv6Address - No IP addresses available",
 			fields: fields{
@@ -270,6 +321,13 @@ func TestServer_AddNetwork(t *testing.T) {
 					ds.AddIPv4CidrToStore(eniID, ipv4Addr, false)
 				}
 			}
+			for eniID, ipv4Prefixes := range tt.fields.ipV4PrefixByENIID {
+				ds.AddENI(eniID, 0, false, false, false)
+				for _, ipv4Prefix := range ipv4Prefixes {
+					_, ipnet, _ := net.ParseCIDR(ipv4Prefix)
+					ds.AddIPv4CidrToStore(eniID, *ipnet, true)
+				}
+			}
 			for eniID, ipv6Prefixes := range tt.fields.ipV6PrefixByENIID {
 				ds.AddENI(eniID, 0, false, false, false)
 				for _, ipv6Prefix := range ipv6Prefixes {
@@ -318,3 +376,514 @@ func TestServer_AddNetwork(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_BranchENIPod(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sgpp-pod",
+			Namespace: "default",
+			Annotations: map[string]string{
+				"vpc.amazonnholuongut.com/pod-eni": `[{"eniId":"eni-branch","ifAddress":"0a:00:00:00:00:01","privateIp":"192.168.1.10",` +
+					`"vlanID":7,"subnetCidr":"192.168.0.0/16"}]`,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{"vpc.amazonnholuongut.com/pod-eni": resource.MustParse("1")},
+				},
+			}},
+		},
+	}
+	assert.NoError(t, m.k8sClient.Create(ctx, pod))
+
+	checkpoint := datastore.NewTestCheckpoint(struct{}{})
+	ds := datastore.NewDataStore(log, checkpoint, false)
+	assert.NoError(t, ds.AddENI("eni-trunk", 1, false, true, false))
+	m.nholuongututils.EXPECT().GetAttachedENIs().Return([]nholuongututils.ENIMetadata{{ENIID: "eni-trunk", MAC: "0a:00:00:00:00:02"}}, nil)
+	m.network.EXPECT().GetLinkByMac("0a:00:00:00:00:02", gomock.Any()).Return(&netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 5}}, nil)
+	m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return([]string{"192.168.0.0/16"}, nil)
+	m.network.EXPECT().UseExternalSNAT().Return(true)
+
+	s := &server{
+		version: "1.2.3",
+		ipamContext: &IPAMContext{
+			nholuongutClient:     m.nholuongututils,
+			k8sClient:     m.k8sClient,
+			networkClient: m.network,
+			dataStore:     ds,
+			enableIPv4:    true,
+			enablePodENI:  true,
+			vethPrefix:    "eni",
+		},
+	}
+
+	addReq := &pb.AddNetworkRequest{
+		ClientVersion:     "1.2.3",
+		K8S_POD_NAME:      pod.Name,
+		K8S_POD_NAMESPACE: pod.Namespace,
+		Netns:             "netns",
+		NetworkName:       "net0",
+		ContainerID:       "cid",
+		IfName:            "eth0",
+	}
+	addResp, err := s.AddNetwork(ctx, addReq)
+	assert.NoError(t, err)
+	assert.True(t, addResp.Success)
+	assert.Equal(t, "192.168.1.10", addResp.IPv4Addr)
+	assert.Equal(t, int32(7), addResp.PodVlanId)
+	assert.Equal(t, int32(5), addResp.ParentIfIndex)
+
+	// The branch ENI is checkpointed along with the host side interface of the pod
+	allocations := checkpoint.Data.(*datastore.CheckpointData).Allocations
+	assert.Len(t, allocations, 1)
+	assert.Equal(t, "eni-branch", allocations[0].IPv4ENI)
+	assert.Equal(t, 7, allocations[0].VlanID)
+	assert.Equal(t, -1, allocations[0].DeviceNumber)
+	assert.Equal(t, networkutils.GeneratePodHostVethName("vlan", pod.Namespace, pod.Name), allocations[0].Metadata.HostVethName)
+
+	checkResp, err := s.CheckNetwork(ctx, &pb.CheckNetworkRequest{
+		ClientVersion:     "1.2.3",
+		K8S_POD_NAME:      pod.Name,
+		K8S_POD_NAMESPACE: pod.Namespace,
+		NetworkName:       "net0",
+		ContainerID:       "cid",
+		IfName:            "eth0",
+		IPv4Addr:          "192.168.1.10",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &pb.CheckNetworkReply{Success: true, DeviceNumber: -1, PodVlanId: 7}, checkResp)
+
+	// The pod is torn down from the checkpoint even once its annotation is gone
+	assert.NoError(t, m.k8sClient.Delete(ctx, pod))
+	delReq := &pb.DelNetworkRequest{
+		ClientVersion:     "1.2.3",
+		K8S_POD_NAME:      pod.Name,
+		K8S_POD_NAMESPACE: pod.Namespace,
+		NetworkName:       "net0",
+		ContainerID:       "cid",
+		IfName:            "eth0",
+	}
+	delResp, err := s.DelNetwork(ctx, delReq)
+	assert.NoError(t, err)
+	assert.Equal(t, &pb.DelNetworkReply{Success: true, PodVlanId: 7, IPv4Addr: "192.168.1.10"}, delResp)
+	assert.Empty(t, checkpoint.Data.(*datastore.CheckpointData).Allocations)
+}
+
+func TestServer_StickyIPPod(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	assert.NoError(t, m.k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}))
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web-0",
+			Namespace:   "default",
+			Annotations: map[string]string{stickyIPKey: "true"},
+		},
+	}
+	assert.NoError(t, m.k8sClient.Create(ctx, pod))
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	assert.NoError(t, ds.AddENI("eni-1", 0, true, false, false))
+	for _, ip := range []string{"192.168.1.10", "192.168.1.11"} {
+		assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP(ip), Mask: net.IPv4Mask(255, 255, 255, 255)}, false))
+	}
+	m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return([]string{"192.168.0.0/16"}, nil).Times(2)
+	m.network.EXPECT().UseExternalSNAT().Return(true).Times(2)
+
+	s := &server{
+		version: "1.2.3",
+		ipamContext: &IPAMContext{
+			nholuongutClient:     m.nholuongututils,
+			k8sClient:     m.k8sClient,
+			networkClient: m.network,
+			dataStore:     ds,
+			enableIPv4:    true,
+			vethPrefix:    "eni",
+		},
+	}
+
+	addReq := &pb.AddNetworkRequest{
+		ClientVersion:     "1.2.3",
+		K8S_POD_NAME:      pod.Name,
+		K8S_POD_NAMESPACE: pod.Namespace,
+		Netns:             "netns",
+		NetworkName:       "net0",
+		ContainerID:       "cid-1",
+		IfName:            "eth0",
+	}
+	addResp, err := s.AddNetwork(ctx, addReq)
+	assert.NoError(t, err)
+	assert.True(t, addResp.Success)
+
+	delResp, err := s.DelNetwork(ctx, &pb.DelNetworkRequest{
+		ClientVersion:     "1.2.3",
+		K8S_POD_NAME:      pod.Name,
+		K8S_POD_NAMESPACE: pod.Namespace,
+		NetworkName:       "net0",
+		ContainerID:       "cid-1",
+		IfName:            "eth0",
+	})
+	assert.NoError(t, err)
+	assert.True(t, delResp.Success)
+	assert.Equal(t, 1, ds.GetIPStats("4").ReservedIPs)
+
+	// The pod recreated with the same name gets its address back
+	addReq.ContainerID = "cid-2"
+	readdResp, err := s.AddNetwork(ctx, addReq)
+	assert.NoError(t, err)
+	assert.True(t, readdResp.Success)
+	assert.Equal(t, addResp.IPv4Addr, readdResp.IPv4Addr)
+}
+
+func TestServer_CheckNetwork(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	assert.NoError(t, ds.AddENI("eni-1", 2, false, false, false))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP("192.168.1.10"), Mask: net.IPv4Mask(255, 255, 255, 255)}, false))
+	_, _, err := ds.AssignPodIPv4Address(datastore.IPAMKey{NetworkName: "net0", ContainerID: "cid", IfName: "eth0"},
+		datastore.IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "web-0"})
+	assert.NoError(t, err)
+
+	s := &server{
+		version: "1.2.3",
+		ipamContext: &IPAMContext{
+			k8sClient:  m.k8sClient,
+			dataStore:  ds,
+			enableIPv4: true,
+		},
+	}
+
+	checkReq := &pb.CheckNetworkRequest{
+		ClientVersion:     "1.2.3",
+		K8S_POD_NAME:      "web-0",
+		K8S_POD_NAMESPACE: "default",
+		NetworkName:       "net0",
+		ContainerID:       "cid",
+		IfName:            "eth0",
+		IPv4Addr:          "192.168.1.10",
+	}
+	checkResp, err := s.CheckNetwork(ctx, checkReq)
+	assert.NoError(t, err)
+	assert.Equal(t, &pb.CheckNetworkReply{Success: true, DeviceNumber: 2}, checkResp)
+
+	// The pod was set up with an address the sandbox no longer holds
+	checkReq.IPv4Addr = "192.168.1.11"
+	checkResp, err = s.CheckNetwork(ctx, checkReq)
+	assert.EqualError(t, err, `sandbox net0/cid/eth0 holds IPv4Addr "192.168.1.10" and IPv6Addr "", not "192.168.1.11" and ""`)
+	assert.False(t, checkResp.Success)
+
+	checkReq.ContainerID = "unknown"
+	checkResp, err = s.CheckNetwork(ctx, checkReq)
+	assert.Equal(t, datastore.ErrUnknownPod, err)
+	assert.False(t, checkResp.Success)
+}
+
+func TestServer_GarbageCollect(t *testing.T) {
+	ctx := context.Background()
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	assert.NoError(t, ds.AddENI("eni-1", 2, false, false, false))
+	for _, ip := range []string{"192.168.1.10", "192.168.1.11", "192.168.1.12", "192.168.1.13", "192.168.1.14"} {
+		assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP(ip), Mask: net.IPv4Mask(255, 255, 255, 255)}, false))
+	}
+	for _, ipamKey := range []datastore.IPAMKey{
+		{NetworkName: "net0", ContainerID: "cid-valid", IfName: "eth0"},
+		{NetworkName: "net0", ContainerID: "cid-stale", IfName: "eth0"},
+		{NetworkName: "net1", ContainerID: "cid-other-network", IfName: "eth0"},
+	} {
+		_, _, err := ds.AssignPodIPv4Address(ipamKey, datastore.IPAMMetadata{})
+		assert.NoError(t, err)
+	}
+	// The extra interface of the valid pod is not among the attachments the container runtime knows of
+	_, err := ds.AssignPodIPv4AddressOnDevice(datastore.IPAMKey{NetworkName: "net0", ContainerID: "cid-valid", IfName: "net1"},
+		datastore.IPAMMetadata{}, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, ds.AssignPodENIAddress(datastore.IPAMKey{NetworkName: "net0", ContainerID: "cid-sgpp", IfName: "eth0"},
+		datastore.IPAMMetadata{}, "192.168.2.10", "", "eni-branch", 7))
+
+	stale, err := ds.GetPodAllocation(datastore.IPAMKey{NetworkName: "net0", ContainerID: "cid-stale", IfName: "eth0"})
+	assert.NoError(t, err)
+
+	s := &server{
+		version: "1.2.3",
+		ipamContext: &IPAMContext{
+			dataStore:  ds,
+			enableIPv4: true,
+		},
+	}
+
+	gcReq := &pb.GarbageCollectRequest{
+		ClientVersion:    "1.2.3",
+		NetworkName:      "net0",
+		ValidAttachments: []*pb.Attachment{{ContainerID: "cid-valid", IfName: "eth0"}},
+	}
+	// Allocations which may belong to an ADD in flight are left alone
+	gcResp, err := s.GarbageCollect(ctx, gcReq)
+	assert.NoError(t, err)
+	assert.Equal(t, &pb.GarbageCollectReply{Success: true}, gcResp)
+
+	gcResp = s.garbageCollect(gcReq, time.Now().Add(2*gcMinAllocationAge))
+	assert.True(t, gcResp.Success)
+	assert.ElementsMatch(t, []*pb.ReleasedAllocation{
+		{ContainerID: "cid-stale", IfName: "eth0", IPv4Addr: stale.IPv4, DeviceNumber: 2},
+		{ContainerID: "cid-sgpp", IfName: "eth0", IPv4Addr: "192.168.2.10", PodVlanId: 7},
+	}, gcResp.Released)
+	assert.Equal(t, 3, ds.GetIPStats("4").AssignedIPs)
+
+	statusResp, err := s.Status(ctx, &pb.StatusRequest{ClientVersion: "1.2.3"})
+	assert.NoError(t, err)
+	// The released address is in cooldown
+	assert.Equal(t, &pb.StatusReply{Success: true, FreeIPv4Addresses: 1}, statusResp)
+}
+
+func TestParseStaticIP(t *testing.T) {
+	tests := []struct {
+		val     string
+		want    string
+		wantErr bool
+	}{
+		{val: "10.0.1.5", want: "10.0.1.5/32"},
+		{val: "10.0.1.16/28", want: "10.0.1.16/28"},
+		{val: "10.0.1.17/28", wantErr: true},
+		{val: "10.0.1.0/24", wantErr: true},
+		{val: "2001:db8::1", wantErr: true},
+		{val: "not-an-ip", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.val, func(t *testing.T) {
+			got, err := parseStaticIP(tt.val)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got.String())
+			}
+		})
+	}
+}
+
+func TestServer_StaticIPPod(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	assert.NoError(t, ds.AddENI("eni-1", 0, true, false, false))
+	assert.NoError(t, ds.AddENIToPool("eni-2", 1, false, false, false, "pci"))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-2", net.IPNet{IP: net.ParseIP("192.168.2.7"), Mask: net.CIDRMask(32, 32)}, false))
+	m.nholuongututils.EXPECT().GetAttachedENIs().Return([]nholuongututils.ENIMetadata{{ENIID: "eni-1", SubnetIPv4CIDR: "192.168.0.0/16"}}, nil).Times(2)
+	m.nholuongututils.EXPECT().AllocIPv4Cidr("eni-1", net.IPNet{IP: net.ParseIP("192.168.1.50").To4(), Mask: net.CIDRMask(32, 32)}).Return(nil)
+	m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return([]string{"192.168.0.0/16"}, nil)
+	m.network.EXPECT().UseExternalSNAT().Return(true)
+
+	s := &server{
+		version: "1.2.3",
+		ipamContext: &IPAMContext{
+			nholuongutClient:     m.nholuongututils,
+			k8sClient:     m.k8sClient,
+			networkClient: m.network,
+			dataStore:     ds,
+			enableIPv4:    true,
+			maxIPsPerENI:  14,
+			vethPrefix:    "eni",
+		},
+	}
+
+	assert.NoError(t, m.k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}))
+	for i, tc := range []struct {
+		staticIP string
+		wantIP   string
+	}{
+		{staticIP: "192.168.1.50", wantIP: "192.168.1.50"},
+		// Outside the subnets of the node's ENIs
+		{staticIP: "10.0.0.5"},
+		// Not a /28 prefix
+		{staticIP: "192.168.1.50/24"},
+		// Attached to an ENI of another IP pool
+		{staticIP: "192.168.2.7"},
+	} {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        fmt.Sprintf("legacy-%d", i),
+				Namespace:   "default",
+				Annotations: map[string]string{staticIPKey: tc.staticIP},
+			},
+		}
+		assert.NoError(t, m.k8sClient.Create(ctx, pod))
+
+		resp, err := s.AddNetwork(ctx, &pb.AddNetworkRequest{
+			ClientVersion:     "1.2.3",
+			K8S_POD_NAME:      pod.Name,
+			K8S_POD_NAMESPACE: pod.Namespace,
+			Netns:             "netns",
+			NetworkName:       "net0",
+			ContainerID:       fmt.Sprintf("cid-%d", i),
+			IfName:            "eth0",
+		})
+		if tc.wantIP == "" {
+			// The reason of the failure is passed on to the runtime
+			assert.ErrorContains(t, err, fmt.Sprintf("failed to assign static IP %q of pod default/%s", tc.staticIP, pod.Name))
+			continue
+		}
+		assert.NoError(t, err)
+		assert.True(t, resp.Success)
+		assert.Equal(t, tc.wantIP, resp.IPv4Addr)
+	}
+}
+
+func TestServer_IPPoolPod(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	assert.NoError(t, ds.AddENI("eni-1", 0, true, false, false))
+	assert.NoError(t, ds.AddENIToPool("eni-2", 1, false, false, false, "pci"))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP("10.0.0.1"), Mask: net.CIDRMask(32, 32)}, false))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-2", net.IPNet{IP: net.ParseIP("10.1.0.1"), Mask: net.CIDRMask(32, 32)}, false))
+	m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return([]string{"10.0.0.0/8"}, nil).Times(2)
+	m.network.EXPECT().UseExternalSNAT().Return(true).Times(2)
+
+	s := &server{
+		version: "1.2.3",
+		ipamContext: &IPAMContext{
+			nholuongutClient:     m.nholuongututils,
+			k8sClient:     m.k8sClient,
+			networkClient: m.network,
+			dataStore:     ds,
+			enableIPv4:    true,
+			maxIPsPerENI:  14,
+			vethPrefix:    "eni",
+			ipPools:       []string{"pci"},
+		},
+	}
+	assert.NoError(t, m.k8sClient.Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{ipPoolKey: "pci"}},
+	}))
+	assert.NoError(t, m.k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}))
+
+	for i, tc := range []struct {
+		namespace string
+		labels    map[string]string
+		wantIP    string
+		wantErr   string
+	}{
+		{namespace: "default", wantIP: "10.0.0.1"},
+		{namespace: "payments", wantIP: "10.1.0.1"},
+		{namespace: "default", labels: map[string]string{ipPoolKey: "restricted"}, wantErr: `IP pool "restricted" of pod default/pod-2 is not configured on this node`},
+		// The default pool isn't used when the namespace can't be looked up
+		{namespace: "missing", wantErr: "failed to look up the IP pool of pod missing/pod-3: failed to get namespace missing"},
+	} {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("pod-%d", i),
+				Namespace: tc.namespace,
+				Labels:    tc.labels,
+			},
+		}
+		assert.NoError(t, m.k8sClient.Create(ctx, pod))
+
+		resp, err := s.AddNetwork(ctx, &pb.AddNetworkRequest{
+			ClientVersion:     "1.2.3",
+			K8S_POD_NAME:      pod.Name,
+			K8S_POD_NAMESPACE: pod.Namespace,
+			Netns:             "netns",
+			NetworkName:       "net0",
+			ContainerID:       fmt.Sprintf("cid-%d", i),
+			IfName:            "eth0",
+		})
+		if tc.wantErr != "" {
+			assert.ErrorContains(t, err, tc.wantErr)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.True(t, resp.Success)
+		assert.Equal(t, tc.wantIP, resp.IPv4Addr)
+	}
+
+	// Nor when the pod can't be looked up, its label may select another pool
+	_, err := s.AddNetwork(ctx, &pb.AddNetworkRequest{
+		ClientVersion:     "1.2.3",
+		K8S_POD_NAME:      "not-cached",
+		K8S_POD_NAMESPACE: "payments",
+		Netns:             "netns",
+		NetworkName:       "net0",
+		ContainerID:       "cid-4",
+		IfName:            "eth0",
+	})
+	assert.ErrorContains(t, err, "failed to get pod payments/not-cached")
+}
+
+func TestServer_AddNetworkGetsPodOnce(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	assert.NoError(t, ds.AddENI("eni-1", 0, true, false, false))
+	assert.NoError(t, ds.AddENIToPool("eni-2", 1, false, false, false, "pci"))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-1", net.IPNet{IP: net.ParseIP("10.0.0.1"), Mask: net.CIDRMask(32, 32)}, false))
+	assert.NoError(t, ds.AddIPv4CidrToStore("eni-2", net.IPNet{IP: net.ParseIP("10.1.0.1"), Mask: net.CIDRMask(32, 32)}, false))
+	m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return([]string{"10.0.0.0/8"}, nil)
+	m.network.EXPECT().UseExternalSNAT().Return(true)
+
+	assert.NoError(t, m.k8sClient.Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod-1",
+			Namespace:   "default",
+			Labels:      map[string]string{ipPoolKey: "pci"},
+			Annotations: map[string]string{stickyIPKey: "true"},
+		},
+	}))
+	podGets := 0
+	k8sClient := interceptor.NewClient(m.k8sClient.(client.WithWatch), interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if _, ok := obj.(*corev1.Pod); ok {
+				podGets++
+			}
+			return c.Get(ctx, key, obj, opts...)
+		},
+	})
+	s := &server{
+		version: "1.2.3",
+		ipamContext: &IPAMContext{
+			nholuongutClient: m.nholuongututils,
+			k8sClient:        k8sClient,
+			networkClient:    m.network,
+			dataStore:        ds,
+			enableIPv4:       true,
+			maxIPsPerENI:     14,
+			vethPrefix:       "eni",
+			ipPools:          []string{"pci"},
+		},
+	}
+
+	resp, err := s.AddNetwork(ctx, &pb.AddNetworkRequest{
+		ClientVersion:     "1.2.3",
+		K8S_POD_NAME:      "pod-1",
+		K8S_POD_NAMESPACE: "default",
+		Netns:             "netns",
+		NetworkName:       "net0",
+		ContainerID:       "cid-1",
+		IfName:            "eth0",
+	})
+	assert.NoError(t, err)
+	assert.True(t, resp.Success)
+	// Both the label and the annotation of the pod are honored
+	assert.Equal(t, "10.1.0.1", resp.IPv4Addr)
+	allocation, err := ds.GetPodAllocation(datastore.IPAMKey{NetworkName: "net0", ContainerID: "cid-1", IfName: "eth0"})
+	assert.NoError(t, err)
+	assert.True(t, allocation.Metadata.StickyIP)
+	assert.Equal(t, 1, podGets)
+}
@@ -0,0 +1,275 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/apis/crd/v1alpha1"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/networkutils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/eventrecorder"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/utils"
+)
+
+const (
+	// envEnableSNATPolicy enables the SNATPolicy custom resources, which are applied ahead of the SNAT env variables
+	envEnableSNATPolicy = "ENABLE_SNAT_POLICY"
+
+	// snatPolicyResyncPeriod is how often the SNAT policies are checked for changes. Pods being added or deleted
+	// trigger a check right away.
+	snatPolicyResyncPeriod = 10 * time.Second
+
+	// snatPolicyAppliedReason and snatPolicyFailedReason are the reasons of the events the nodes raise on the policies
+	// they select
+	snatPolicyAppliedReason = "SNATPolicyApplied"
+	snatPolicyFailedReason  = "SNATPolicyFailed"
+)
+
+// snatPolicies tracks the SNAT policies applied on the node
+type snatPolicies struct {
+	// trigger wakes the controller up when pods are added or deleted
+	trigger chan struct{}
+	// applied are the rules last applied, the iptables rules are only updated when they change
+	applied []networkutils.SNATPolicyRule
+	// reported are the reports of the policies selecting the node, events are only raised when they change
+	reported map[string]snatPolicyReport
+}
+
+// snatPolicyReport is the generation of a policy the node applied, and the error it failed with, if any
+type snatPolicyReport struct {
+	generation int64
+	err        string
+}
+
+// newSNATPolicies returns the SNAT policies of the node, nil if ENABLE_SNAT_POLICY is not set
+func newSNATPolicies() *snatPolicies {
+	if !utils.GetBoolAsStringEnvVar(envEnableSNATPolicy, false) {
+		return nil
+	}
+	return &snatPolicies{trigger: make(chan struct{}, 1)}
+}
+
+// StartSNATPolicyController applies the SNAT policies selecting the node, and reports it with events on them, if enabled
+func (c *IPAMContext) StartSNATPolicyController() {
+	if c.snatPolicies == nil {
+		return
+	}
+	log.Infof("SNAT policy controller - resync period: %v", snatPolicyResyncPeriod)
+	ctx := context.Background()
+	for !c.isTerminating() {
+		if err := c.reconcileSNATPolicies(ctx); err != nil {
+			log.Errorf("Failed to apply SNAT policies: %v", err)
+		}
+		select {
+		case <-c.snatPolicies.trigger:
+		case <-time.After(snatPolicyResyncPeriod):
+		}
+	}
+}
+
+// triggerSNATPolicyUpdate makes the controller apply the SNAT policies again, for the pods they select
+func (c *IPAMContext) triggerSNATPolicyUpdate() {
+	if c.snatPolicies == nil {
+		return
+	}
+	select {
+	case c.snatPolicies.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// reconcileSNATPolicies renders the SNAT policies selecting the node into iptables rules, and reports the generation
+// of each policy the node applied with an event on it
+func (c *IPAMContext) reconcileSNATPolicies(ctx context.Context) error {
+	var policies v1alpha1.SNATPolicyList
+	if err := c.k8sClient.List(ctx, &policies); err != nil {
+		return errors.Wrap(err, "failed to list SNAT policies")
+	}
+	var node corev1.Node
+	if err := c.k8sClient.Get(ctx, types.NamespacedName{Name: c.myNodeName}, &node); err != nil {
+		return errors.Wrapf(err, "failed to get node %s", c.myNodeName)
+	}
+
+	// The first policy matching the traffic applies
+	sort.Slice(policies.Items, func(i, j int) bool {
+		if policies.Items[i].Spec.Priority != policies.Items[j].Spec.Priority {
+			return policies.Items[i].Spec.Priority < policies.Items[j].Spec.Priority
+		}
+		return policies.Items[i].Name < policies.Items[j].Name
+	})
+	var rules []networkutils.SNATPolicyRule
+	policyErrors := make(map[string]string)
+	var selected []*v1alpha1.SNATPolicy
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		selects, err := selectorMatches(policy.Spec.NodeSelector, node.Labels)
+		if err != nil {
+			// Report the error on all nodes, since the policy may be meant for any of them
+			policyErrors[policy.Name] = errors.Wrap(err, "invalid node selector").Error()
+			selected = append(selected, policy)
+			continue
+		}
+		if !selects {
+			continue
+		}
+		selected = append(selected, policy)
+		rule, err := c.buildSNATPolicyRule(ctx, policy)
+		if err != nil {
+			policyErrors[policy.Name] = err.Error()
+			continue
+		}
+		if rule != nil {
+			rules = append(rules, *rule)
+		}
+	}
+
+	if !reflect.DeepEqual(rules, c.snatPolicies.applied) {
+		if err := c.applySNATPolicyRules(rules); err != nil {
+			for _, policy := range selected {
+				if _, ok := policyErrors[policy.Name]; !ok {
+					policyErrors[policy.Name] = err.Error()
+				}
+			}
+		} else {
+			log.Infof("Applied %d SNAT policy rules", len(rules))
+			c.snatPolicies.applied = rules
+		}
+	}
+
+	reported := make(map[string]snatPolicyReport, len(selected))
+	for _, policy := range selected {
+		report := snatPolicyReport{generation: policy.Generation, err: policyErrors[policy.Name]}
+		reported[policy.Name] = report
+		if last, ok := c.snatPolicies.reported[policy.Name]; !ok || last != report {
+			c.reportSNATPolicy(policy, report)
+		}
+	}
+	c.snatPolicies.reported = reported
+	return nil
+}
+
+// buildSNATPolicyRule returns the rule of the policy for the node, nil if its pod selector selects none of the pods
+// of the node
+func (c *IPAMContext) buildSNATPolicyRule(ctx context.Context, policy *v1alpha1.SNATPolicy) (*networkutils.SNATPolicyRule, error) {
+	rule := networkutils.SNATPolicyRule{Policy: policy.Name}
+	switch policy.Spec.Action {
+	case v1alpha1.SNATActionNoSNAT:
+		rule.Action = networkutils.SNATPolicyNoSNAT
+	case v1alpha1.SNATActionSNAT:
+		rule.Action = networkutils.SNATPolicySNAT
+	case v1alpha1.SNATActionSNATRandomFully:
+		rule.Action = networkutils.SNATPolicySNATRandomFully
+	default:
+		return nil, errors.Errorf("invalid action %q", policy.Spec.Action)
+	}
+	for _, destination := range policy.Spec.Destinations {
+		_, ipNet, err := net.ParseCIDR(destination)
+		if err != nil || ipNet.IP.To4() == nil {
+			return nil, errors.Errorf("invalid IPv4 destination %q", destination)
+		}
+		// iptables lists the network address of the CIDR
+		rule.Destinations = append(rule.Destinations, ipNet.String())
+	}
+	if policy.Spec.PodSelector == nil {
+		return &rule, nil
+	}
+
+	podSelector, err := metav1.LabelSelectorAsSelector(policy.Spec.PodSelector)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid pod selector")
+	}
+	// The cache of ipamd only holds the pods of this node
+	var pods corev1.PodList
+	if err := c.k8sClient.List(ctx, &pods, client.MatchingLabelsSelector{Selector: podSelector}); err != nil {
+		return nil, errors.Wrap(err, "failed to list the pods of the pod selector")
+	}
+	podNames := lo.SliceToMap(pods.Items, func(pod corev1.Pod) (types.NamespacedName, bool) {
+		return types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, true
+	})
+	for _, info := range c.dataStore.AllocatedIPs() {
+		podName := types.NamespacedName{Namespace: info.IPAMMetadata.K8SPodNamespace, Name: info.IPAMMetadata.K8SPodName}
+		if podNames[podName] && net.ParseIP(info.IP).To4() != nil {
+			rule.Sources = append(rule.Sources, info.IP)
+		}
+	}
+	if len(rule.Sources) == 0 {
+		return nil, nil
+	}
+	sort.Strings(rule.Sources)
+	rule.Sources = lo.Uniq(rule.Sources)
+	return &rule, nil
+}
+
+// applySNATPolicyRules updates the host iptables rules with the rules of the SNAT policies
+func (c *IPAMContext) applySNATPolicyRules(rules []networkutils.SNATPolicyRule) error {
+	vpcCIDRs, err := c.nholuongutClient.GetVPCIPv4CIDRs()
+	if err != nil {
+		return errors.Wrap(err, "failed to get VPC CIDRs to update the SNAT rules")
+	}
+	c.networkClient.SetSNATPolicyRules(rules)
+	primaryIP := c.nholuongutClient.GetLocalIPv4()
+	if err := c.networkClient.UpdateHostIptablesRules(vpcCIDRs, c.nholuongutClient.GetPrimaryENImac(), &primaryIP,
+		c.enableIPv4, c.enableIPv6); err != nil {
+		return errors.Wrap(err, "failed to update the SNAT rules")
+	}
+	return nil
+}
+
+// reportSNATPolicy raises an event on the policy with the generation the node applied, or the error it failed with
+func (c *IPAMContext) reportSNATPolicy(policy *v1alpha1.SNATPolicy, report snatPolicyReport) {
+	eventRecorder := eventrecorder.Get()
+	if eventRecorder == nil {
+		return
+	}
+	// The policy is referenced explicitly, since the scheme of the recorder doesn't know its type
+	regarding := &corev1.ObjectReference{
+		APIVersion:      v1alpha1.GroupVersion.String(),
+		Kind:            "SNATPolicy",
+		Name:            policy.Name,
+		UID:             policy.UID,
+		ResourceVersion: policy.ResourceVersion,
+	}
+	if report.err != "" {
+		eventRecorder.SendEvent(regarding, corev1.EventTypeWarning, snatPolicyFailedReason, "ApplySNATPolicy",
+			fmt.Sprintf("Node %s failed to apply generation %d: %s", c.myNodeName, report.generation, report.err))
+		return
+	}
+	eventRecorder.SendEvent(regarding, corev1.EventTypeNormal, snatPolicyAppliedReason, "ApplySNATPolicy",
+		fmt.Sprintf("Node %s applied generation %d", c.myNodeName, report.generation))
+}
+
+// selectorMatches returns true if the selector matches the labels, or is not set
+func selectorMatches(selector *metav1.LabelSelector, objectLabels map[string]string) (bool, error) {
+	if selector == nil {
+		return true, nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, err
+	}
+	return s.Matches(labels.Set(objectLabels)), nil
+}
@@ -0,0 +1,153 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	testclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/apis/crd/v1alpha1"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/ipamd/datastore"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/networkutils"
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/utils/eventrecorder"
+)
+
+func TestReconcileSNATPolicies(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+
+	fakeRecorder := eventrecorder.InitMockEventRecorder()
+	k8sSchema := runtime.NewScheme()
+	clientgoscheme.AddToScheme(k8sSchema)
+	v1alpha1.AddToScheme(k8sSchema)
+	k8sClient := testclient.NewClientBuilder().WithScheme(k8sSchema).
+		WithObjects(
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   myNodeName,
+				Labels: map[string]string{corev1.LabelTopologyZone: "us-west-2a"},
+			}},
+			&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "billing-1", Namespace: "default", Labels: map[string]string{"app": "billing"}}},
+			&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", Labels: map[string]string{"app": "web"}}},
+			&v1alpha1.SNATPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "partner"},
+				Spec: v1alpha1.SNATPolicySpec{
+					Destinations: []string{"172.16.0.0/12"},
+					Action:       v1alpha1.SNATActionSNATRandomFully,
+					Priority:     20,
+				},
+			},
+			&v1alpha1.SNATPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "billing"},
+				Spec: v1alpha1.SNATPolicySpec{
+					PodSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"app": "billing"}},
+					Destinations: []string{"10.100.1.0/16"},
+					Action:       v1alpha1.SNATActionNoSNAT,
+					Priority:     10,
+				},
+			},
+			&v1alpha1.SNATPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "invalid"},
+				Spec: v1alpha1.SNATPolicySpec{
+					Destinations: []string{"fd00::/64"},
+					Action:       v1alpha1.SNATActionSNAT,
+				},
+			},
+			// Doesn't select the node, so the node doesn't report it
+			&v1alpha1.SNATPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "other-zone"},
+				Spec: v1alpha1.SNATPolicySpec{
+					NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{corev1.LabelTopologyZone: "us-west-2b"}},
+					Destinations: []string{"192.168.0.0/24"},
+					Action:       v1alpha1.SNATActionSNAT,
+				},
+			},
+		).Build()
+
+	ds := datastore.NewDataStore(log, datastore.NullCheckpoint{}, false)
+	ds.AddENI(primaryENIid, primaryDevice, true, false, false)
+	ds.AddIPv4CidrToStore(primaryENIid, net.IPNet{IP: net.ParseIP(ipaddr01), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	ds.AddIPv4CidrToStore(primaryENIid, net.IPNet{IP: net.ParseIP(ipaddr02), Mask: net.IPv4Mask(255, 255, 255, 255)}, false)
+	billingIP, _, err := ds.AssignPodIPv4Address(datastore.IPAMKey{ContainerID: "container1"},
+		datastore.IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "billing-1"})
+	assert.NoError(t, err)
+	_, _, err = ds.AssignPodIPv4Address(datastore.IPAMKey{ContainerID: "container2"},
+		datastore.IPAMMetadata{K8SPodNamespace: "default", K8SPodName: "web-1"})
+	assert.NoError(t, err)
+
+	os.Setenv(envEnableSNATPolicy, "true")
+	defer os.Unsetenv(envEnableSNATPolicy)
+	c := &IPAMContext{
+		nholuongutClient:     m.nholuongututils,
+		k8sClient:     k8sClient,
+		networkClient: m.network,
+		dataStore:     ds,
+		myNodeName:    myNodeName,
+		enableIPv4:    true,
+		snatPolicies:  newSNATPolicies(),
+	}
+
+	vpcCIDRs := []string{"10.0.0.0/16"}
+	primaryIP := net.ParseIP(ipaddr01)
+	m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return(vpcCIDRs, nil)
+	m.network.EXPECT().SetSNATPolicyRules([]networkutils.SNATPolicyRule{
+		{Policy: "billing", Destinations: []string{"10.100.0.0/16"}, Sources: []string{billingIP}, Action: networkutils.SNATPolicyNoSNAT},
+		{Policy: "partner", Destinations: []string{"172.16.0.0/12"}, Action: networkutils.SNATPolicySNATRandomFully},
+	})
+	m.nholuongututils.EXPECT().GetLocalIPv4().Return(primaryIP)
+	m.nholuongututils.EXPECT().GetPrimaryENImac().Return(primaryMAC)
+	m.network.EXPECT().UpdateHostIptablesRules(vpcCIDRs, primaryMAC, &primaryIP, true, false).Return(nil)
+	assert.NoError(t, c.reconcileSNATPolicies(ctx))
+
+	generation := func(name string) int64 {
+		var policy v1alpha1.SNATPolicy
+		assert.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: name}, &policy))
+		return policy.Generation
+	}
+	// Policies are evaluated by priority
+	assert.Equal(t, []string{
+		fmt.Sprintf("%s %s Node %s failed to apply generation %d: invalid IPv4 destination \"fd00::/64\"",
+			corev1.EventTypeWarning, snatPolicyFailedReason, myNodeName, generation("invalid")),
+		fmt.Sprintf("%s %s Node %s applied generation %d", corev1.EventTypeNormal, snatPolicyAppliedReason, myNodeName, generation("billing")),
+		fmt.Sprintf("%s %s Node %s applied generation %d", corev1.EventTypeNormal, snatPolicyAppliedReason, myNodeName, generation("partner")),
+	}, []string{<-fakeRecorder.Events, <-fakeRecorder.Events, <-fakeRecorder.Events})
+
+	// Nothing changed, so neither the rules are updated nor events raised
+	assert.NoError(t, c.reconcileSNATPolicies(ctx))
+	assert.Empty(t, fakeRecorder.Events)
+
+	// The pod of the policy is deleted
+	_, _, _, _, err = ds.UnassignPodIPAddress(datastore.IPAMKey{ContainerID: "container1"})
+	assert.NoError(t, err)
+	m.nholuongututils.EXPECT().GetVPCIPv4CIDRs().Return(vpcCIDRs, nil)
+	m.network.EXPECT().SetSNATPolicyRules([]networkutils.SNATPolicyRule{
+		{Policy: "partner", Destinations: []string{"172.16.0.0/12"}, Action: networkutils.SNATPolicySNATRandomFully},
+	})
+	m.nholuongututils.EXPECT().GetLocalIPv4().Return(primaryIP)
+	m.nholuongututils.EXPECT().GetPrimaryENImac().Return(primaryMAC)
+	m.network.EXPECT().UpdateHostIptablesRules(vpcCIDRs, primaryMAC, &primaryIP, true, false).Return(nil)
+	assert.NoError(t, c.reconcileSNATPolicies(ctx))
+	assert.Empty(t, fakeRecorder.Events)
+}
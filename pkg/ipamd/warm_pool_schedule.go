@@ -0,0 +1,270 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	// The time zones of the profiles do not depend on the zoneinfo of the image
+	_ "time/tzdata"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// envWarmPoolProfiles is a JSON list of warm target profiles. The first profile whose node selector matches the labels
+// of the node and whose time window contains the current time overrides the warm targets.
+const envWarmPoolProfiles = "WARM_POOL_PROFILES"
+
+// warmPoolProfile holds warm targets which apply to the nodes matching NodeSelector during a time window
+type warmPoolProfile struct {
+	Name string `json:"name"`
+	// NodeSelector restricts the profile to the nodes with these labels
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Days are the days of the week the window starts on, as "Mon" to "Sun", every day if empty
+	Days []string `json:"days,omitempty"`
+	// Start and End bound the window as "HH:MM", it spans midnight if End is before Start. The profile applies all
+	// day if both are empty.
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+	// TimeZone of the window, such as "Europe/Paris", UTC if empty
+	TimeZone string `json:"timeZone,omitempty"`
+
+	WarmENITarget    *int `json:"warmENITarget,omitempty"`
+	WarmIPTarget     *int `json:"warmIPTarget,omitempty"`
+	MinimumIPTarget  *int `json:"minimumIPTarget,omitempty"`
+	WarmPrefixTarget *int `json:"warmPrefixTarget,omitempty"`
+
+	location     *time.Location
+	days         map[time.Weekday]bool
+	startMinutes int
+	endMinutes   int
+}
+
+// warmPoolSchedule is reported by the introspection endpoint
+type warmPoolSchedule struct {
+	Profiles      []warmPoolProfile `json:"profiles"`
+	ActiveProfile string            `json:"activeProfile,omitempty"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"Sun": time.Sunday, "Mon": time.Monday, "Tue": time.Tuesday, "Wed": time.Wednesday, "Thu": time.Thursday,
+	"Fri": time.Friday, "Sat": time.Saturday,
+}
+
+// getWarmPoolProfiles returns the profiles configured by the WARM_POOL_PROFILES env variable. An invalid value is
+// ignored as a whole.
+func getWarmPoolProfiles() []warmPoolProfile {
+	value := os.Getenv(envWarmPoolProfiles)
+	if value == "" {
+		return nil
+	}
+	profiles, err := parseWarmPoolProfiles(value)
+	if err != nil {
+		log.Errorf("Ignoring %s: %v", envWarmPoolProfiles, err)
+		return nil
+	}
+	return profiles
+}
+
+func parseWarmPoolProfiles(value string) ([]warmPoolProfile, error) {
+	var profiles []warmPoolProfile
+	if err := json.Unmarshal([]byte(value), &profiles); err != nil {
+		return nil, errors.Wrap(err, "invalid JSON")
+	}
+	names := make(map[string]bool)
+	for i := range profiles {
+		profile := &profiles[i]
+		if profile.Name == "" || names[profile.Name] {
+			return nil, errors.Errorf("profile %d needs a unique name", i)
+		}
+		names[profile.Name] = true
+		if err := profile.init(); err != nil {
+			return nil, errors.Wrapf(err, "profile %s", profile.Name)
+		}
+	}
+	return profiles, nil
+}
+
+// init validates the profile and parses its window
+func (p *warmPoolProfile) init() error {
+	for _, target := range []*int{p.WarmENITarget, p.WarmIPTarget, p.MinimumIPTarget, p.WarmPrefixTarget} {
+		if target != nil && *target < 0 {
+			return errors.New("warm targets must not be negative")
+		}
+	}
+	location, err := time.LoadLocation(p.TimeZone)
+	if err != nil {
+		return errors.Wrapf(err, "invalid time zone %q", p.TimeZone)
+	}
+	p.location = location
+	if len(p.Days) > 0 {
+		p.days = make(map[time.Weekday]bool)
+		for _, day := range p.Days {
+			weekday, ok := weekdays[day]
+			if !ok {
+				return errors.Errorf("invalid day %q", day)
+			}
+			p.days[weekday] = true
+		}
+	}
+	if (p.Start == "") != (p.End == "") {
+		return errors.New("start and end must be set together")
+	}
+	if p.Start == "" {
+		return nil
+	}
+	if p.startMinutes, err = parseTimeOfDay(p.Start); err != nil {
+		return err
+	}
+	if p.endMinutes, err = parseTimeOfDay(p.End); err != nil {
+		return err
+	}
+	if p.startMinutes == p.endMinutes {
+		return errors.New("start and end must differ")
+	}
+	return nil
+}
+
+// parseTimeOfDay returns the minutes since midnight of "HH:MM"
+func parseTimeOfDay(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, errors.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// isActive returns true if the profile applies to a node with nodeLabels at now
+func (p *warmPoolProfile) isActive(nodeLabels map[string]string, now time.Time) bool {
+	if !labels.SelectorFromSet(p.NodeSelector).Matches(labels.Set(nodeLabels)) {
+		return false
+	}
+	local := now.In(p.location)
+	day := local.Weekday()
+	if p.Start != "" {
+		minutes := local.Hour()*60 + local.Minute()
+		switch {
+		case p.startMinutes < p.endMinutes:
+			if minutes < p.startMinutes || minutes >= p.endMinutes {
+				return false
+			}
+		case minutes >= p.startMinutes:
+		case minutes < p.endMinutes:
+			// The window started the day before
+			day = (day + 6) % 7
+		default:
+			return false
+		}
+	}
+	return p.days == nil || p.days[day]
+}
+
+// String describes the overrides of the profile for logging
+func (p *warmPoolProfile) String() string {
+	var overrides []string
+	for _, target := range []struct {
+		name  string
+		value *int
+	}{
+		{envWarmENITarget, p.WarmENITarget},
+		{envWarmIPTarget, p.WarmIPTarget},
+		{envMinimumIPTarget, p.MinimumIPTarget},
+		{envWarmPrefixTarget, p.WarmPrefixTarget},
+	} {
+		if target.value != nil {
+			overrides = append(overrides, fmt.Sprintf("%s=%d", target.name, *target.value))
+		}
+	}
+	return fmt.Sprintf("%s (%s)", p.Name, strings.Join(overrides, ", "))
+}
+
+// activeWarmPoolProfile returns the profile which applies to the node at now, nil if none
+func (c *IPAMContext) activeWarmPoolProfile(nodeLabels map[string]string, now time.Time) *warmPoolProfile {
+	for i := range c.warmPoolProfiles {
+		if c.warmPoolProfiles[i].isActive(nodeLabels, now) {
+			return &c.warmPoolProfiles[i]
+		}
+	}
+	return nil
+}
+
+// applyWarmPoolProfile sets the warm targets of the active profile, over the ones of the env variables and of the
+// ENIConfig of the node
+func (c *IPAMContext) applyWarmPoolProfile() {
+	profile := c.warmPoolProfile
+	if profile == nil {
+		return
+	}
+	if profile.WarmENITarget != nil {
+		c.warmENITarget = *profile.WarmENITarget
+	}
+	if profile.WarmIPTarget != nil {
+		c.warmIPTarget = *profile.WarmIPTarget
+	}
+	if profile.MinimumIPTarget != nil {
+		c.minimumIPTarget = *profile.MinimumIPTarget
+	}
+	if profile.WarmPrefixTarget != nil {
+		c.warmPrefixTarget = *profile.WarmPrefixTarget
+	}
+}
+
+// refreshWarmPoolSchedule switches to the profile which applies to the node at now, if it changed
+func (c *IPAMContext) refreshWarmPoolSchedule(ctx context.Context, now time.Time) {
+	if len(c.warmPoolProfiles) == 0 {
+		return
+	}
+	var node corev1.Node
+	if err := c.k8sClient.Get(ctx, types.NamespacedName{Name: c.myNodeName}, &node); err != nil {
+		log.Debugf("Unable to get node %s to select its warm pool profile: %v", c.myNodeName, err)
+		return
+	}
+	profile := c.activeWarmPoolProfile(node.Labels, now)
+	if profile == c.warmPoolProfile {
+		return
+	}
+	if profile != nil {
+		log.Infof("Warm pool profile %s is active", profile)
+	} else {
+		log.Infof("Warm pool profile %s is no longer active", c.warmPoolProfile.Name)
+	}
+	c.configLock.Lock()
+	c.warmPoolProfile = profile
+	c.configLock.Unlock()
+	// Start over from the env variables and the ENIConfig of the node, the profile is applied on top of them
+	c.applyENIConfigOverrides(c.eniConfigName, c.eniConfigSpec)
+	log.Infof("Warm targets - warm ENI target: %d, warm IP target: %d, minimum IP target: %d, warm prefix target: %d",
+		c.warmENITarget, c.warmIPTarget, c.minimumIPTarget, c.warmPrefixTarget)
+}
+
+// getWarmPoolSchedule returns the profiles and the name of the active one
+func (c *IPAMContext) getWarmPoolSchedule() warmPoolSchedule {
+	c.configLock.RLock()
+	defer c.configLock.RUnlock()
+	schedule := warmPoolSchedule{Profiles: c.warmPoolProfiles}
+	if schedule.Profiles == nil {
+		schedule.Profiles = []warmPoolProfile{}
+	}
+	if profile := c.warmPoolProfile; profile != nil {
+		schedule.ActiveProfile = profile.Name
+	}
+	return schedule
+}
@@ -0,0 +1,146 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipamd
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseWarmPoolProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			value: `[{"name":"day","days":["Mon","Fri"],"start":"07:00","end":"19:00","timeZone":"Europe/Paris","warmIPTarget":20},{"name":"batch","nodeSelector":{"workload":"batch"}}]`,
+		},
+		{
+			name:    "duplicate name",
+			value:   `[{"name":"day"},{"name":"day"}]`,
+			wantErr: "profile 1 needs a unique name",
+		},
+		{
+			name:    "invalid day",
+			value:   `[{"name":"day","days":["Monday"]}]`,
+			wantErr: "profile day: invalid day \"Monday\"",
+		},
+		{
+			name:    "missing end",
+			value:   `[{"name":"day","start":"07:00"}]`,
+			wantErr: "profile day: start and end must be set together",
+		},
+		{
+			name:    "invalid time",
+			value:   `[{"name":"day","start":"7am","end":"19:00"}]`,
+			wantErr: "profile day: invalid time of day \"7am\", expected HH:MM",
+		},
+		{
+			name:    "negative target",
+			value:   `[{"name":"day","warmIPTarget":-1}]`,
+			wantErr: "profile day: warm targets must not be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseWarmPoolProfiles(tt.value)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWarmPoolProfileIsActive(t *testing.T) {
+	profiles, err := parseWarmPoolProfiles(`[
+		{"name":"business-hours","days":["Mon","Tue","Wed","Thu","Fri"],"start":"07:00","end":"19:00","timeZone":"America/New_York"},
+		{"name":"overnight","days":["Fri"],"start":"22:00","end":"06:00"},
+		{"name":"batch","nodeSelector":{"workload":"batch"}}
+	]`)
+	assert.NoError(t, err)
+	businessHours, overnight, batch := &profiles[0], &profiles[1], &profiles[2]
+
+	// Monday 2024-01-15, 12:00 UTC is 07:00 in New York
+	monday := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	assert.True(t, businessHours.isActive(nil, monday))
+	assert.False(t, businessHours.isActive(nil, monday.Add(-time.Minute)))
+	assert.False(t, businessHours.isActive(nil, monday.Add(12*time.Hour)))
+	assert.False(t, businessHours.isActive(nil, monday.Add(-48*time.Hour)))
+
+	// The overnight window of Friday goes on Saturday morning
+	friday := time.Date(2024, 1, 19, 23, 0, 0, 0, time.UTC)
+	assert.True(t, overnight.isActive(nil, friday))
+	assert.True(t, overnight.isActive(nil, friday.Add(6*time.Hour)))
+	assert.False(t, overnight.isActive(nil, friday.Add(8*time.Hour)))
+	assert.False(t, overnight.isActive(nil, friday.Add(-24*time.Hour)))
+
+	assert.True(t, batch.isActive(map[string]string{"workload": "batch", "zone": "a"}, monday))
+	assert.False(t, batch.isActive(map[string]string{"workload": "web"}, monday))
+}
+
+func TestRefreshWarmPoolSchedule(t *testing.T) {
+	m := setup(t)
+	defer m.ctrl.Finish()
+	ctx := context.Background()
+	os.Setenv(envWarmIPTarget, "5")
+	defer os.Unsetenv(envWarmIPTarget)
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: myNodeName, Labels: map[string]string{"workload": "batch"}}}
+	assert.NoError(t, m.k8sClient.Create(ctx, node))
+	profiles, err := parseWarmPoolProfiles(`[
+		{"name":"night","start":"20:00","end":"06:00","warmIPTarget":1,"warmENITarget":0},
+		{"name":"batch","nodeSelector":{"workload":"batch"},"warmIPTarget":30,"minimumIPTarget":40}
+	]`)
+	assert.NoError(t, err)
+	mockContext := &IPAMContext{
+		k8sClient:        m.k8sClient,
+		myNodeName:       myNodeName,
+		warmENITarget:    1,
+		warmIPTarget:     5,
+		minimumIPTarget:  noMinimumIPTarget,
+		warmPoolProfiles: profiles,
+	}
+
+	day := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	mockContext.refreshWarmPoolSchedule(ctx, day)
+	assert.Equal(t, 30, mockContext.warmIPTarget)
+	assert.Equal(t, 40, mockContext.minimumIPTarget)
+	assert.Equal(t, warmPoolSchedule{Profiles: profiles, ActiveProfile: "batch"}, mockContext.getWarmPoolSchedule())
+
+	// The first matching profile wins, and targets it leaves unset come from the env variables
+	night := day.Add(10 * time.Hour)
+	mockContext.refreshWarmPoolSchedule(ctx, night)
+	assert.Equal(t, 1, mockContext.warmIPTarget)
+	assert.Equal(t, 0, mockContext.warmENITarget)
+	assert.Equal(t, noMinimumIPTarget, mockContext.minimumIPTarget)
+	assert.Equal(t, "night", mockContext.getEffectiveConfig().WarmPoolProfile)
+
+	// Without an active profile, the env variables apply again
+	node.Labels = nil
+	assert.NoError(t, m.k8sClient.Update(ctx, node))
+	mockContext.refreshWarmPoolSchedule(ctx, day)
+	assert.Equal(t, 5, mockContext.warmIPTarget)
+	assert.Equal(t, defaultWarmENITarget, mockContext.warmENITarget)
+	assert.Equal(t, "", mockContext.getWarmPoolSchedule().ActiveProfile)
+}
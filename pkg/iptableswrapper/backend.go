@@ -0,0 +1,80 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iptableswrapper
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/coreos/go-iptables/iptables"
+)
+
+// Backend is the netfilter backend used to program iptables rules
+type Backend string
+
+const (
+	// BackendIPTables programs rules through the iptables binaries
+	BackendIPTables Backend = "iptables"
+	// BackendNFTables programs rules natively through the nft binary
+	BackendNFTables Backend = "nftables"
+	// BackendAuto selects nftables when the host uses it and iptables otherwise
+	BackendAuto Backend = "auto"
+	// DefaultBackend is the backend used when none is configured
+	DefaultBackend = BackendIPTables
+
+	envBackend = "NETFILTER_BACKEND"
+)
+
+var (
+	lookPath        = exec.LookPath
+	iptablesVersion = func() (string, error) {
+		out, err := exec.Command("iptables", "--version").Output()
+		return string(out), err
+	}
+)
+
+// LoadBackendFromEnv loads the netfilter backend from the NETFILTER_BACKEND environment variable
+func LoadBackendFromEnv() Backend {
+	switch backend := Backend(os.Getenv(envBackend)); backend {
+	case BackendIPTables, BackendNFTables, BackendAuto:
+		return backend
+	default:
+		return DefaultBackend
+	}
+}
+
+// ResolveBackend turns BackendAuto into a concrete backend. nftables is selected when the nft binary is
+// available and iptables either is missing or already runs on top of nf_tables.
+func ResolveBackend(backend Backend) Backend {
+	if backend != BackendAuto {
+		return backend
+	}
+	if _, err := lookPath("nft"); err != nil {
+		return BackendIPTables
+	}
+	version, err := iptablesVersion()
+	if err != nil || strings.Contains(version, "nf_tables") {
+		return BackendNFTables
+	}
+	return BackendIPTables
+}
+
+// New returns an IPTablesIface for the given protocol, backed by iptables or nftables
+func New(protocol iptables.Protocol, backend Backend) (IPTablesIface, error) {
+	if ResolveBackend(backend) == BackendNFTables {
+		return NewNFTables(protocol)
+	}
+	return NewIPTables(protocol)
+}
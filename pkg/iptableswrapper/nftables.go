@@ -0,0 +1,530 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iptableswrapper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+
+	"github.com/coreos/go-iptables/iptables"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// nftTablePrefix prefixes the nftables table holding the rules of each iptables table, e.g. vpc-cni-nat
+	nftTablePrefix = "vpc-cni"
+	// nftLockFile is the lock iptables itself takes, so that nftables and iptables updates are serialized too
+	nftLockFile = "/run/xtables.lock"
+
+	errNoChain        = "No chain/target/match by that name."
+	errBadRule        = "Bad rule (does a matching rule exist in that chain?)."
+	errChainExists    = "Chain already exists."
+	errChainNotEmpty  = "Directory not empty."
+	errChainReference = "Too many links."
+	errBuiltinChain   = "Operation not permitted."
+	errTableNotExists = "No such file or directory"
+)
+
+// nftBaseChain describes the nftables base chain standing in for a built-in iptables chain
+type nftBaseChain struct {
+	name      string
+	chainType string
+	hook      string
+	priority  int
+}
+
+// nftBaseChains lists the built-in chains of each supported iptables table, with the iptables priorities
+var nftBaseChains = map[string][]nftBaseChain{
+	"nat": {
+		{"PREROUTING", "nat", "prerouting", -100},
+		{"INPUT", "nat", "input", 100},
+		{"OUTPUT", "nat", "output", -100},
+		{"POSTROUTING", "nat", "postrouting", 100},
+	},
+	"mangle": {
+		{"PREROUTING", "filter", "prerouting", -150},
+		{"INPUT", "filter", "input", -150},
+		{"FORWARD", "filter", "forward", -150},
+		{"OUTPUT", "route", "output", -150},
+		{"POSTROUTING", "filter", "postrouting", -150},
+	},
+	"filter": {
+		{"INPUT", "filter", "input", 0},
+		{"FORWARD", "filter", "forward", 0},
+		{"OUTPUT", "filter", "output", 0},
+	},
+}
+
+// NFTablesError is returned by the nftables backend. Like iptables.Error, it reports whether the chain or rule
+// being operated on does not exist.
+type NFTablesError struct {
+	msg      string
+	notExist bool
+}
+
+// Error implements the error interface
+func (e *NFTablesError) Error() string {
+	return "nftables: " + e.msg
+}
+
+// IsNotExist returns true if the error is due to the chain or rule not existing
+func (e *NFTablesError) IsNotExist() bool {
+	return e.notExist
+}
+
+// nftChain is a chain of an nftables table, holding the rules in order
+type nftChain struct {
+	name  string
+	rules []*nftRule
+}
+
+// nftTable is the in-memory model of the nftables table standing in for an iptables table
+type nftTable struct {
+	name   string
+	base   []nftBaseChain
+	chains []*nftChain
+}
+
+func (t *nftTable) chain(name string) *nftChain {
+	for _, chain := range t.chains {
+		if chain.name == name {
+			return chain
+		}
+	}
+	return nil
+}
+
+func (t *nftTable) baseChain(name string) *nftBaseChain {
+	for i := range t.base {
+		if t.base[i].name == name {
+			return &t.base[i]
+		}
+	}
+	return nil
+}
+
+// ensureChain returns the chain, creating base chains on first use. It fails for missing user chains.
+func (t *nftTable) ensureChain(name string) (*nftChain, error) {
+	if chain := t.chain(name); chain != nil {
+		return chain, nil
+	}
+	if t.baseChain(name) == nil {
+		return nil, &NFTablesError{msg: errNoChain, notExist: true}
+	}
+	chain := &nftChain{name: name}
+	t.chains = append(t.chains, chain)
+	return chain, nil
+}
+
+// nfTables implements IPTablesIface natively on nftables. Every change rewrites the whole table in a single
+// nft transaction, so the rules are never observed half-updated.
+type nfTables struct {
+	family   string
+	lockFile string
+	run      func(stdin []byte, args ...string) ([]byte, error)
+}
+
+// NewNFTables returns an nftables backed IPTablesIface. Rules for each iptables table are kept in an nftables table
+// of their own, e.g. "ip vpc-cni-nat", whose base chains hook in at the priorities of the iptables ones.
+func NewNFTables(protocol iptables.Protocol) (IPTablesIface, error) {
+	if _, err := exec.LookPath("nft"); err != nil {
+		return nil, errors.Wrap(err, "nftables backend requires the nft binary")
+	}
+	family := "ip"
+	if protocol == iptables.ProtocolIPv6 {
+		family = "ip6"
+	}
+	return &nfTables{
+		family:   family,
+		lockFile: nftLockFile,
+		run:      runNft,
+	}, nil
+}
+
+func runNft(stdin []byte, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("nft", args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		return nil, &NFTablesError{msg: fmt.Sprintf("running %v: %v: %s", cmd.Args, err, msg),
+			notExist: strings.Contains(msg, errTableNotExists)}
+	}
+	return stdout.Bytes(), nil
+}
+
+func (n *nfTables) ipv6() bool {
+	return n.family == "ip6"
+}
+
+// load reads the current content of the nftables table standing in for the iptables table
+func (n *nfTables) load(table string) (*nftTable, error) {
+	base, ok := nftBaseChains[table]
+	if !ok {
+		return nil, &NFTablesError{msg: fmt.Sprintf("unsupported table %s", table)}
+	}
+	t := &nftTable{name: nftTablePrefix + "-" + table, base: base}
+	out, err := n.run(nil, "-j", "list", "table", n.family, t.name)
+	if err != nil {
+		var nftErr *NFTablesError
+		if errors.As(err, &nftErr) && nftErr.IsNotExist() {
+			return t, nil
+		}
+		return nil, err
+	}
+
+	var listing struct {
+		Nftables []map[string]json.RawMessage `json:"nftables"`
+	}
+	if err := json.Unmarshal(out, &listing); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse nftables table %s", t.name)
+	}
+	for _, obj := range listing.Nftables {
+		if raw, ok := obj["chain"]; ok {
+			var chain struct {
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(raw, &chain); err != nil {
+				return nil, errors.Wrapf(err, "failed to parse chain of nftables table %s", t.name)
+			}
+			t.chains = append(t.chains, &nftChain{name: chain.Name})
+		}
+		if raw, ok := obj["rule"]; ok {
+			var rule struct {
+				Chain   string        `json:"chain"`
+				Comment string        `json:"comment"`
+				Expr    []interface{} `json:"expr"`
+			}
+			if err := json.Unmarshal(raw, &rule); err != nil {
+				return nil, errors.Wrapf(err, "failed to parse rule of nftables table %s", t.name)
+			}
+			chain := t.chain(rule.Chain)
+			if chain == nil {
+				return nil, errors.Errorf("rule of unknown chain %s in nftables table %s", rule.Chain, t.name)
+			}
+			r, err := ruleFromExprs(rule.Expr, rule.Comment, n.ipv6())
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to parse rule of chain %s in nftables table %s", rule.Chain, t.name)
+			}
+			chain.rules = append(chain.rules, r)
+		}
+	}
+	return t, nil
+}
+
+// commit atomically replaces the nftables table with the in-memory model
+func (n *nfTables) commit(t *nftTable) error {
+	table := map[string]interface{}{"family": n.family, "name": t.name}
+	// Adding the table first makes the delete succeed when it does not exist yet
+	cmds := []interface{}{
+		map[string]interface{}{"add": map[string]interface{}{"table": table}},
+		map[string]interface{}{"delete": map[string]interface{}{"table": table}},
+	}
+	if len(t.chains) > 0 {
+		cmds = append(cmds, map[string]interface{}{"add": map[string]interface{}{"table": table}})
+	}
+	// Chains are all added before the rules, so that jumps always resolve
+	for _, chain := range t.chains {
+		c := map[string]interface{}{"family": n.family, "table": t.name, "name": chain.name}
+		if base := t.baseChain(chain.name); base != nil {
+			c["type"], c["hook"], c["prio"], c["policy"] = base.chainType, base.hook, base.priority, "accept"
+		}
+		cmds = append(cmds, map[string]interface{}{"add": map[string]interface{}{"chain": c}})
+	}
+	for _, chain := range t.chains {
+		for _, rule := range chain.rules {
+			r := map[string]interface{}{"family": n.family, "table": t.name, "chain": chain.name, "expr": rule.exprs(n.ipv6())}
+			if rule.comment != "" {
+				r["comment"] = rule.comment
+			}
+			cmds = append(cmds, map[string]interface{}{"add": map[string]interface{}{"rule": r}})
+		}
+	}
+
+	script, err := json.Marshal(map[string]interface{}{"nftables": cmds})
+	if err != nil {
+		return errors.Wrapf(err, "failed to render nftables table %s", t.name)
+	}
+	_, err = n.run(script, "-j", "-f", "-")
+	return err
+}
+
+// update loads the table, applies the change and commits the result, holding the xtables lock throughout
+func (n *nfTables) update(table string, change func(t *nftTable) error) error {
+	if n.lockFile != "" {
+		lock, err := os.OpenFile(n.lockFile, os.O_CREATE|os.O_RDWR, 0600)
+		if err != nil {
+			return errors.Wrapf(err, "failed to open lock file %s", n.lockFile)
+		}
+		defer lock.Close()
+		if err := unix.Flock(int(lock.Fd()), unix.LOCK_EX); err != nil {
+			return errors.Wrapf(err, "failed to lock %s", n.lockFile)
+		}
+	}
+	t, err := n.load(table)
+	if err != nil {
+		return err
+	}
+	if err := change(t); err != nil {
+		return err
+	}
+	return n.commit(t)
+}
+
+// rule parses the rulespec and checks that a jump target exists in the table
+func (n *nfTables) rule(t *nftTable, rulespec []string) (*nftRule, error) {
+	r, err := parseRulespec(rulespec, n.ipv6())
+	if err != nil {
+		return nil, &NFTablesError{msg: err.Error()}
+	}
+	switch r.target {
+	case "", "ACCEPT", "DROP", "RETURN", "SNAT", "CONNMARK":
+	default:
+		if t.chain(r.target) == nil {
+			return nil, &NFTablesError{msg: errNoChain, notExist: true}
+		}
+	}
+	return r, nil
+}
+
+func (n *nfTables) find(chain *nftChain, rulespec []string) (int, error) {
+	r, err := parseRulespec(rulespec, n.ipv6())
+	if err != nil {
+		return -1, &NFTablesError{msg: err.Error()}
+	}
+	for i, rule := range chain.rules {
+		if reflect.DeepEqual(rule.rulespec(), r.rulespec()) {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// Exists implements IPTablesIface interface by checking the nftables table
+func (n *nfTables) Exists(table, chain string, rulespec ...string) (bool, error) {
+	t, err := n.load(table)
+	if err != nil {
+		return false, err
+	}
+	c := t.chain(chain)
+	if c == nil {
+		return false, nil
+	}
+	i, err := n.find(c, rulespec)
+	return i >= 0, err
+}
+
+// Insert implements IPTablesIface interface by inserting the rule at the 1-based position
+func (n *nfTables) Insert(table, chain string, pos int, rulespec ...string) error {
+	return n.update(table, func(t *nftTable) error {
+		c, err := t.ensureChain(chain)
+		if err != nil {
+			return err
+		}
+		if pos < 1 || pos > len(c.rules)+1 {
+			return &NFTablesError{msg: fmt.Sprintf("Index of insertion too big: %d", pos)}
+		}
+		r, err := n.rule(t, rulespec)
+		if err != nil {
+			return err
+		}
+		c.rules = append(c.rules[:pos-1], append([]*nftRule{r}, c.rules[pos-1:]...)...)
+		return nil
+	})
+}
+
+// Append implements IPTablesIface interface by appending the rule
+func (n *nfTables) Append(table, chain string, rulespec ...string) error {
+	return n.update(table, func(t *nftTable) error {
+		c, err := t.ensureChain(chain)
+		if err != nil {
+			return err
+		}
+		r, err := n.rule(t, rulespec)
+		if err != nil {
+			return err
+		}
+		c.rules = append(c.rules, r)
+		return nil
+	})
+}
+
+// AppendUnique implements IPTablesIface interface by appending the rule unless it already exists
+func (n *nfTables) AppendUnique(table, chain string, rulespec ...string) error {
+	return n.update(table, func(t *nftTable) error {
+		c, err := t.ensureChain(chain)
+		if err != nil {
+			return err
+		}
+		if i, err := n.find(c, rulespec); err != nil || i >= 0 {
+			return err
+		}
+		r, err := n.rule(t, rulespec)
+		if err != nil {
+			return err
+		}
+		c.rules = append(c.rules, r)
+		return nil
+	})
+}
+
+// Delete implements IPTablesIface interface by deleting the first matching rule
+func (n *nfTables) Delete(table, chain string, rulespec ...string) error {
+	return n.update(table, func(t *nftTable) error {
+		c := t.chain(chain)
+		if c == nil {
+			return &NFTablesError{msg: errNoChain, notExist: true}
+		}
+		i, err := n.find(c, rulespec)
+		if err != nil {
+			return err
+		}
+		if i < 0 {
+			return &NFTablesError{msg: errBadRule, notExist: true}
+		}
+		c.rules = append(c.rules[:i], c.rules[i+1:]...)
+		return nil
+	})
+}
+
+// List implements IPTablesIface interface by listing the chain in `iptables -S` format
+func (n *nfTables) List(table, chain string) ([]string, error) {
+	t, err := n.load(table)
+	if err != nil {
+		return nil, err
+	}
+	var rules []string
+	c := t.chain(chain)
+	switch {
+	case t.baseChain(chain) != nil:
+		rules = append(rules, fmt.Sprintf("-P %s ACCEPT", chain))
+	case c != nil:
+		rules = append(rules, fmt.Sprintf("-N %s", chain))
+	default:
+		return nil, &NFTablesError{msg: errNoChain, notExist: true}
+	}
+	if c == nil {
+		return rules, nil
+	}
+	for _, rule := range c.rules {
+		line := []string{"-A", chain}
+		for _, arg := range rule.rulespec() {
+			line = append(line, quoteArg(arg))
+		}
+		rules = append(rules, strings.Join(line, " "))
+	}
+	return rules, nil
+}
+
+// NewChain implements IPTablesIface interface by adding an empty chain
+func (n *nfTables) NewChain(table, chain string) error {
+	return n.update(table, func(t *nftTable) error {
+		if t.chain(chain) != nil || t.baseChain(chain) != nil {
+			return &NFTablesError{msg: errChainExists}
+		}
+		t.chains = append(t.chains, &nftChain{name: chain})
+		return nil
+	})
+}
+
+// ClearChain implements IPTablesIface interface by flushing the chain, creating it if needed
+func (n *nfTables) ClearChain(table, chain string) error {
+	return n.update(table, func(t *nftTable) error {
+		if c := t.chain(chain); c != nil {
+			c.rules = nil
+			return nil
+		}
+		if t.baseChain(chain) == nil {
+			t.chains = append(t.chains, &nftChain{name: chain})
+		}
+		return nil
+	})
+}
+
+// DeleteChain implements IPTablesIface interface by deleting an empty, unreferenced chain
+func (n *nfTables) DeleteChain(table, chain string) error {
+	return n.update(table, func(t *nftTable) error {
+		if t.baseChain(chain) != nil {
+			return &NFTablesError{msg: errBuiltinChain}
+		}
+		c := t.chain(chain)
+		if c == nil {
+			return &NFTablesError{msg: errNoChain, notExist: true}
+		}
+		if len(c.rules) > 0 {
+			return &NFTablesError{msg: errChainNotEmpty}
+		}
+		var chains []*nftChain
+		for _, other := range t.chains {
+			for _, rule := range other.rules {
+				if rule.target == chain {
+					return &NFTablesError{msg: errChainReference}
+				}
+			}
+			if other != c {
+				chains = append(chains, other)
+			}
+		}
+		t.chains = chains
+		return nil
+	})
+}
+
+// ListChains implements IPTablesIface interface by listing the built-in chains followed by the user chains
+func (n *nfTables) ListChains(table string) ([]string, error) {
+	t, err := n.load(table)
+	if err != nil {
+		return nil, err
+	}
+	var chains []string
+	for _, base := range t.base {
+		chains = append(chains, base.name)
+	}
+	for _, chain := range t.chains {
+		if t.baseChain(chain.name) == nil {
+			chains = append(chains, chain.name)
+		}
+	}
+	return chains, nil
+}
+
+// ChainExists implements IPTablesIface interface by checking the nftables table
+func (n *nfTables) ChainExists(table, chain string) (bool, error) {
+	t, err := n.load(table)
+	if err != nil {
+		return false, err
+	}
+	return t.baseChain(chain) != nil || t.chain(chain) != nil, nil
+}
+
+// HasRandomFully implements IPTablesIface interface, nftables always supports fully-random SNAT
+func (n *nfTables) HasRandomFully() bool {
+	return true
+}
+
+// quoteArg quotes a rulespec argument the way `iptables -S` does
+func quoteArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'") {
+		return arg
+	}
+	return `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+}
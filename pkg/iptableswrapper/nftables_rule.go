@@ -0,0 +1,612 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iptableswrapper
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const allMarkBits = 0xffffffff
+
+// invertibleOptions are the rulespec options that can be preceded by "!"
+var invertibleOptions = map[string]bool{
+	"-s": true, "--source": true,
+	"-d": true, "--destination": true,
+	"-i": true, "--in-interface": true,
+	"-o": true, "--out-interface": true,
+	"--dst-type": true,
+}
+
+// nftRule is the subset of an iptables rulespec that the CNI programs, in a form that can be translated to and
+// from nftables JSON expressions. Rules are compared through their canonical rulespec, which follows the
+// option order of `iptables -S`.
+type nftRule struct {
+	src, dst             string
+	srcInvert, dstInvert bool
+	in, out              string
+	inInvert, outInvert  bool
+	comment              string
+
+	// addrtype match, only --dst-type is supported
+	dstType       string
+	dstTypeInvert bool
+	limitIfaceIn  bool
+
+	// state match
+	states []string
+
+	target string
+
+	// SNAT target
+	toSource            string
+	random, randomFully bool
+
+	// CONNMARK target, either --set-xmark mark/mask or --restore-mark --nfmask mask --ctmask mask
+	restoreMark bool
+	mark, mask  uint32
+}
+
+// parseRulespec parses an iptables rulespec into an nftRule
+func parseRulespec(rulespec []string, ipv6 bool) (*nftRule, error) {
+	r := &nftRule{mask: allMarkBits}
+	nfmask, ctmask := uint32(allMarkBits), uint32(allMarkBits)
+	invert := false
+	for i := 0; i < len(rulespec); i++ {
+		opt := rulespec[i]
+		if opt == "!" {
+			invert = true
+			continue
+		}
+		arg := func() (string, error) {
+			if i+1 >= len(rulespec) {
+				return "", errors.Errorf("option %s requires an argument", opt)
+			}
+			i++
+			return rulespec[i], nil
+		}
+		var val string
+		var err error
+		switch opt {
+		case "-s", "--source", "-d", "--destination":
+			if val, err = arg(); err != nil {
+				return nil, err
+			}
+			cidr, err := normalizeCIDR(val, ipv6)
+			if err != nil {
+				return nil, err
+			}
+			if opt == "-s" || opt == "--source" {
+				r.src, r.srcInvert = cidr, invert
+			} else {
+				r.dst, r.dstInvert = cidr, invert
+			}
+		case "-i", "--in-interface":
+			if r.in, err = arg(); err != nil {
+				return nil, err
+			}
+			r.inInvert = invert
+		case "-o", "--out-interface":
+			if r.out, err = arg(); err != nil {
+				return nil, err
+			}
+			r.outInvert = invert
+		case "-m", "--match":
+			// Match options are parsed on their own, the module name carries no information
+			if val, err = arg(); err != nil {
+				return nil, err
+			}
+			switch val {
+			case "comment", "addrtype", "state":
+			default:
+				return nil, errors.Errorf("unsupported match %s", val)
+			}
+		case "--comment":
+			if r.comment, err = arg(); err != nil {
+				return nil, err
+			}
+		case "--dst-type":
+			if val, err = arg(); err != nil {
+				return nil, err
+			}
+			r.dstType, r.dstTypeInvert = strings.ToUpper(val), invert
+		case "--limit-iface-in":
+			r.limitIfaceIn = true
+		case "--state":
+			if val, err = arg(); err != nil {
+				return nil, err
+			}
+			r.states = strings.Split(strings.ToUpper(val), ",")
+		case "-j", "--jump":
+			if r.target, err = arg(); err != nil {
+				return nil, err
+			}
+		case "--to-source":
+			if r.toSource, err = arg(); err != nil {
+				return nil, err
+			}
+		case "--random":
+			r.random = true
+		case "--random-fully":
+			r.randomFully = true
+		case "--set-mark", "--set-xmark":
+			if val, err = arg(); err != nil {
+				return nil, err
+			}
+			if r.mark, r.mask, err = parseMarkMask(val); err != nil {
+				return nil, err
+			}
+			// --set-mark ORs the value in, which is the same as XORing it in after clearing its bits
+			if opt == "--set-mark" {
+				r.mask |= r.mark
+			}
+		case "--restore-mark":
+			r.restoreMark = true
+		case "--mask", "--nfmask", "--ctmask":
+			if val, err = arg(); err != nil {
+				return nil, err
+			}
+			mask, err := strconv.ParseUint(val, 0, 32)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid mask %s", val)
+			}
+			if opt != "--ctmask" {
+				nfmask = uint32(mask)
+			}
+			if opt != "--nfmask" {
+				ctmask = uint32(mask)
+			}
+		default:
+			return nil, errors.Errorf("unsupported option %s", opt)
+		}
+		if invert && !invertibleOptions[opt] {
+			return nil, errors.Errorf("option %s cannot be inverted", opt)
+		}
+		invert = false
+	}
+
+	if r.restoreMark {
+		// Only the bits of a single mask are translated, the packet mark keeping the bits outside of it
+		if nfmask != ctmask {
+			return nil, errors.Errorf("--nfmask %#x and --ctmask %#x must be equal", nfmask, ctmask)
+		}
+		r.mask = ctmask
+	}
+	return r, nil
+}
+
+// rulespec returns the canonical iptables rulespec of the rule
+func (r *nftRule) rulespec() []string {
+	var spec []string
+	addOpt := func(invert bool, opt ...string) {
+		if invert {
+			spec = append(spec, "!")
+		}
+		spec = append(spec, opt...)
+	}
+	if r.src != "" {
+		addOpt(r.srcInvert, "-s", r.src)
+	}
+	if r.dst != "" {
+		addOpt(r.dstInvert, "-d", r.dst)
+	}
+	if r.in != "" {
+		addOpt(r.inInvert, "-i", r.in)
+	}
+	if r.out != "" {
+		addOpt(r.outInvert, "-o", r.out)
+	}
+	if r.comment != "" {
+		spec = append(spec, "-m", "comment", "--comment", r.comment)
+	}
+	if r.dstType != "" {
+		spec = append(spec, "-m", "addrtype")
+		addOpt(r.dstTypeInvert, "--dst-type", r.dstType)
+		if r.limitIfaceIn {
+			spec = append(spec, "--limit-iface-in")
+		}
+	}
+	if len(r.states) > 0 {
+		spec = append(spec, "-m", "state", "--state", strings.Join(r.states, ","))
+	}
+	if r.target == "" {
+		return spec
+	}
+	spec = append(spec, "-j", r.target)
+	switch r.target {
+	case "SNAT":
+		spec = append(spec, "--to-source", r.toSource)
+		if r.random {
+			spec = append(spec, "--random")
+		}
+		if r.randomFully {
+			spec = append(spec, "--random-fully")
+		}
+	case "CONNMARK":
+		if r.restoreMark {
+			spec = append(spec, "--restore-mark", "--nfmask", fmt.Sprintf("0x%x", r.mask), "--ctmask", fmt.Sprintf("0x%x", r.mask))
+		} else {
+			spec = append(spec, "--set-xmark", fmt.Sprintf("0x%x/0x%x", r.mark, r.mask))
+		}
+	}
+	return spec
+}
+
+// exprs translates the rule to nftables JSON expressions
+func (r *nftRule) exprs(ipv6 bool) []interface{} {
+	var exprs []interface{}
+	protocol := "ip"
+	if ipv6 {
+		protocol = "ip6"
+	}
+	if r.src != "" {
+		exprs = append(exprs, nftMatch(r.srcInvert, nftPayload(protocol, "saddr"), nftPrefix(r.src)))
+	}
+	if r.dst != "" {
+		exprs = append(exprs, nftMatch(r.dstInvert, nftPayload(protocol, "daddr"), nftPrefix(r.dst)))
+	}
+	if r.in != "" {
+		exprs = append(exprs, nftMatch(r.inInvert, nftMeta("iifname"), nftIfname(r.in)))
+	}
+	if r.out != "" {
+		exprs = append(exprs, nftMatch(r.outInvert, nftMeta("oifname"), nftIfname(r.out)))
+	}
+	if r.dstType != "" {
+		flags := []interface{}{"daddr"}
+		if r.limitIfaceIn {
+			flags = append(flags, "iif")
+		}
+		fib := map[string]interface{}{"fib": map[string]interface{}{"result": "type", "flags": flags}}
+		exprs = append(exprs, nftMatch(r.dstTypeInvert, fib, strings.ToLower(r.dstType)))
+	}
+	if len(r.states) > 0 {
+		var states []interface{}
+		for _, state := range r.states {
+			states = append(states, strings.ToLower(state))
+		}
+		exprs = append(exprs, map[string]interface{}{"match": map[string]interface{}{
+			"op": "in", "left": nftCt("state"), "right": states,
+		}})
+	}
+
+	switch r.target {
+	case "":
+	case "ACCEPT", "DROP", "RETURN":
+		exprs = append(exprs, map[string]interface{}{strings.ToLower(r.target): nil})
+	case "SNAT":
+		snat := map[string]interface{}{"addr": r.toSource}
+		if r.random {
+			snat["flags"] = "random"
+		}
+		if r.randomFully {
+			snat["flags"] = "fully-random"
+		}
+		exprs = append(exprs, map[string]interface{}{"snat": snat})
+	case "CONNMARK":
+		if r.restoreMark {
+			// The bits of the packet mark outside of the mask are kept, e.g. the ones set by the eBPF datapath
+			var value interface{} = nftCt("mark")
+			if r.mask != allMarkBits {
+				value = map[string]interface{}{"|": []interface{}{
+					map[string]interface{}{"&": []interface{}{nftMeta("mark"), ^r.mask}},
+					map[string]interface{}{"&": []interface{}{nftCt("mark"), r.mask}},
+				}}
+			}
+			exprs = append(exprs, nftMangle(nftMeta("mark"), value))
+			break
+		}
+		var value interface{} = r.mark
+		if r.mask != allMarkBits {
+			value = map[string]interface{}{"^": []interface{}{
+				map[string]interface{}{"&": []interface{}{nftCt("mark"), ^r.mask}}, r.mark,
+			}}
+		}
+		exprs = append(exprs, nftMangle(nftCt("mark"), value))
+	default:
+		// Anything else is a user chain, which nfTables checks for existence
+		exprs = append(exprs, map[string]interface{}{"jump": map[string]interface{}{"target": r.target}})
+	}
+	return exprs
+}
+
+// ruleFromExprs translates nftables JSON expressions, as listed by `nft -j`, back to an nftRule
+func ruleFromExprs(exprs []interface{}, comment string, ipv6 bool) (*nftRule, error) {
+	r := &nftRule{comment: comment, mask: allMarkBits}
+	for _, e := range exprs {
+		expr, ok := e.(map[string]interface{})
+		if !ok || len(expr) != 1 {
+			return nil, errors.Errorf("unexpected expression %v", e)
+		}
+		for kind, body := range expr {
+			var err error
+			switch kind {
+			case "match":
+				err = r.parseMatch(body, ipv6)
+			case "accept", "drop", "return":
+				r.target = strings.ToUpper(kind)
+			case "jump":
+				target, _ := body.(map[string]interface{})["target"].(string)
+				r.target = target
+			case "snat":
+				r.parseSNAT(body)
+			case "mangle":
+				err = r.parseMangle(body)
+			case "counter":
+			default:
+				err = errors.Errorf("unsupported expression %s", kind)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return r, nil
+}
+
+func (r *nftRule) parseMatch(body interface{}, ipv6 bool) error {
+	match, _ := body.(map[string]interface{})
+	left, _ := match["left"].(map[string]interface{})
+	right := match["right"]
+	invert := match["op"] == "!="
+	switch {
+	case left["payload"] != nil:
+		field, _ := left["payload"].(map[string]interface{})["field"].(string)
+		cidr, err := parseNftAddr(right, ipv6)
+		if err != nil {
+			return err
+		}
+		switch field {
+		case "saddr":
+			r.src, r.srcInvert = cidr, invert
+		case "daddr":
+			r.dst, r.dstInvert = cidr, invert
+		default:
+			return errors.Errorf("unsupported payload field %s", field)
+		}
+	case left["meta"] != nil:
+		key, _ := left["meta"].(map[string]interface{})["key"].(string)
+		name, _ := right.(string)
+		if strings.HasSuffix(name, "*") && !strings.HasSuffix(name, `\*`) {
+			name = strings.TrimSuffix(name, "*") + "+"
+		}
+		switch key {
+		case "iifname":
+			r.in, r.inInvert = name, invert
+		case "oifname":
+			r.out, r.outInvert = name, invert
+		default:
+			return errors.Errorf("unsupported meta key %s", key)
+		}
+	case left["fib"] != nil:
+		fib, _ := left["fib"].(map[string]interface{})
+		for _, flag := range stringList(fib["flags"]) {
+			if flag == "iif" {
+				r.limitIfaceIn = true
+			}
+		}
+		dstType, _ := right.(string)
+		r.dstType, r.dstTypeInvert = strings.ToUpper(dstType), invert
+	case left["ct"] != nil:
+		for _, state := range stringList(right) {
+			r.states = append(r.states, strings.ToUpper(state))
+		}
+	default:
+		return errors.Errorf("unsupported match %v", left)
+	}
+	return nil
+}
+
+func (r *nftRule) parseSNAT(body interface{}) {
+	snat, _ := body.(map[string]interface{})
+	r.target = "SNAT"
+	r.toSource, _ = snat["addr"].(string)
+	for _, flag := range stringList(snat["flags"]) {
+		switch flag {
+		case "random":
+			r.random = true
+		case "fully-random":
+			r.randomFully = true
+		}
+	}
+}
+
+func (r *nftRule) parseMangle(body interface{}) error {
+	mangle, _ := body.(map[string]interface{})
+	key, _ := mangle["key"].(map[string]interface{})
+	value := mangle["value"]
+	r.target = "CONNMARK"
+
+	if key["meta"] != nil {
+		// meta mark set ct mark, or (meta mark & ^mask) | (ct mark & mask)
+		r.restoreMark = true
+		if v, ok := value.(map[string]interface{}); ok && v["ct"] != nil {
+			return nil
+		}
+		operands, ok := nftBinop(value, "|")
+		if !ok {
+			return errors.Errorf("unsupported mark restore %v", value)
+		}
+		keep, keepOK := nftBinop(operands[0], "&")
+		restore, restoreOK := nftBinop(operands[1], "&")
+		if !keepOK || !restoreOK {
+			return errors.Errorf("unsupported mark restore %v", value)
+		}
+		keptBits, keepOK := nftNumber(keep[1])
+		mask, restoreOK := nftNumber(restore[1])
+		if !keepOK || !restoreOK || keptBits != ^mask {
+			return errors.Errorf("unsupported mark restore %v", value)
+		}
+		r.mask = mask
+		return nil
+	}
+
+	// ct mark set [ct mark & ^mask] ^ mark, listed with | when the bits do not overlap
+	if mark, ok := nftNumber(value); ok {
+		r.mark, r.mask = mark, allMarkBits
+		return nil
+	}
+	for _, op := range []string{"^", "|"} {
+		operands, ok := nftBinop(value, op)
+		if !ok {
+			continue
+		}
+		mark, ok := nftNumber(operands[1])
+		if !ok {
+			break
+		}
+		r.mark = mark
+		if and, ok := nftBinop(operands[0], "&"); ok {
+			keep, ok := nftNumber(and[1])
+			if !ok {
+				break
+			}
+			r.mask = ^keep
+		} else if op == "|" {
+			r.mask = mark
+		} else {
+			r.mask = 0
+		}
+		return nil
+	}
+	if and, ok := nftBinop(value, "&"); ok {
+		if keep, ok := nftNumber(and[1]); ok {
+			r.mark, r.mask = 0, ^keep
+			return nil
+		}
+	}
+	return errors.Errorf("unsupported mark %v", value)
+}
+
+func normalizeCIDR(addr string, ipv6 bool) (string, error) {
+	if !strings.Contains(addr, "/") {
+		if ipv6 {
+			addr += "/128"
+		} else {
+			addr += "/32"
+		}
+	}
+	_, ipNet, err := net.ParseCIDR(addr)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid address %s", addr)
+	}
+	return ipNet.String(), nil
+}
+
+func parseNftAddr(value interface{}, ipv6 bool) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return normalizeCIDR(v, ipv6)
+	case map[string]interface{}:
+		if prefix, ok := v["prefix"].(map[string]interface{}); ok {
+			addr, _ := prefix["addr"].(string)
+			length, _ := nftNumber(prefix["len"])
+			return normalizeCIDR(fmt.Sprintf("%s/%d", addr, length), ipv6)
+		}
+	}
+	return "", errors.Errorf("unsupported address %v", value)
+}
+
+func nftMatch(invert bool, left, right interface{}) map[string]interface{} {
+	op := "=="
+	if invert {
+		op = "!="
+	}
+	return map[string]interface{}{"match": map[string]interface{}{"op": op, "left": left, "right": right}}
+}
+
+func nftPayload(protocol, field string) map[string]interface{} {
+	return map[string]interface{}{"payload": map[string]interface{}{"protocol": protocol, "field": field}}
+}
+
+func nftPrefix(cidr string) interface{} {
+	ip, ipNet, _ := net.ParseCIDR(cidr)
+	length, _ := ipNet.Mask.Size()
+	return map[string]interface{}{"prefix": map[string]interface{}{"addr": ip.String(), "len": length}}
+}
+
+func nftMeta(key string) map[string]interface{} {
+	return map[string]interface{}{"meta": map[string]interface{}{"key": key}}
+}
+
+func nftCt(key string) map[string]interface{} {
+	return map[string]interface{}{"ct": map[string]interface{}{"key": key}}
+}
+
+func nftMangle(key, value interface{}) map[string]interface{} {
+	return map[string]interface{}{"mangle": map[string]interface{}{"key": key, "value": value}}
+}
+
+// nftIfname translates an iptables interface name, where a trailing + is a wildcard, to nftables
+func nftIfname(name string) string {
+	if strings.HasSuffix(name, "+") {
+		return strings.TrimSuffix(name, "+") + "*"
+	}
+	return name
+}
+
+func nftBinop(value interface{}, op string) ([]interface{}, bool) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	operands, ok := m[op].([]interface{})
+	return operands, ok && len(operands) == 2
+}
+
+func nftNumber(value interface{}) (uint32, bool) {
+	switch v := value.(type) {
+	case float64:
+		return uint32(v), true
+	case uint32:
+		return v, true
+	case int:
+		return uint32(v), true
+	}
+	return 0, false
+}
+
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var list []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func parseMarkMask(value string) (uint32, uint32, error) {
+	parts := strings.SplitN(value, "/", 2)
+	mark, err := strconv.ParseUint(parts[0], 0, 32)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "invalid mark %s", value)
+	}
+	mask := uint64(allMarkBits)
+	if len(parts) == 2 {
+		if mask, err = strconv.ParseUint(parts[1], 0, 32); err != nil {
+			return 0, 0, errors.Wrapf(err, "invalid mask %s", value)
+		}
+	}
+	return uint32(mark), uint32(mask), nil
+}
@@ -0,0 +1,308 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iptableswrapper
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNft stands in for the nft binary, keeping the tables committed through `nft -j -f -` and listing them
+// back the way `nft -j list table` does
+type fakeNft struct {
+	tables  map[string][]map[string]interface{}
+	commits int
+}
+
+func newFakeNfTables(family string) (*nfTables, *fakeNft) {
+	f := &fakeNft{tables: map[string][]map[string]interface{}{}}
+	return &nfTables{family: family, run: f.run}, f
+}
+
+func (f *fakeNft) run(stdin []byte, args ...string) ([]byte, error) {
+	if args[1] == "list" {
+		objs, ok := f.tables[args[3]+" "+args[4]]
+		if !ok {
+			return nil, &NFTablesError{msg: "Error: No such file or directory", notExist: true}
+		}
+		return json.Marshal(map[string]interface{}{"nftables": objs})
+	}
+
+	f.commits++
+	var script struct {
+		Nftables []map[string]map[string]map[string]interface{} `json:"nftables"`
+	}
+	if err := json.Unmarshal(stdin, &script); err != nil {
+		return nil, err
+	}
+	for _, cmd := range script.Nftables {
+		for verb, objs := range cmd {
+			for kind, obj := range objs {
+				switch {
+				case kind == "table" && verb == "add":
+					key := obj["family"].(string) + " " + obj["name"].(string)
+					if _, ok := f.tables[key]; !ok {
+						f.tables[key] = []map[string]interface{}{}
+					}
+				case kind == "table" && verb == "delete":
+					delete(f.tables, obj["family"].(string)+" "+obj["name"].(string))
+				default:
+					key := obj["family"].(string) + " " + obj["table"].(string)
+					f.tables[key] = append(f.tables[key], map[string]interface{}{kind: obj})
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+func TestNFTablesSNATChain(t *testing.T) {
+	ipt, f := newFakeNfTables("ip")
+	chain := "nholuongut-SNAT-CHAIN-0"
+	snatRule := []string{"!", "-o", "vlan+", "-m", "comment", "--comment", "nholuongut, SNAT",
+		"-m", "addrtype", "!", "--dst-type", "LOCAL", "-j", "SNAT", "--to-source", "10.0.0.10", "--random-fully"}
+
+	require.NoError(t, ipt.NewChain("nat", chain))
+	require.NoError(t, ipt.Append("nat", "POSTROUTING", "-m", "comment", "--comment", "nholuongut SNAT CHAIN", "-j", chain))
+	require.NoError(t, ipt.Append("nat", chain, snatRule...))
+	require.NoError(t, ipt.Insert("nat", chain, 1, "-d", "10.0.0.0/16", "-m", "comment", "--comment", "nholuongut SNAT CHAIN", "-j", "RETURN"))
+	assert.Equal(t, 4, f.commits)
+
+	chains, err := ipt.ListChains("nat")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"PREROUTING", "INPUT", "OUTPUT", "POSTROUTING", chain}, chains)
+
+	rules, err := ipt.List("nat", chain)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"-N nholuongut-SNAT-CHAIN-0",
+		`-A nholuongut-SNAT-CHAIN-0 -d 10.0.0.0/16 -m comment --comment "nholuongut SNAT CHAIN" -j RETURN`,
+		`-A nholuongut-SNAT-CHAIN-0 ! -o vlan+ -m comment --comment "nholuongut, SNAT" -m addrtype ! --dst-type LOCAL -j SNAT --to-source 10.0.0.10 --random-fully`,
+	}, rules)
+
+	exists, err := ipt.Exists("nat", chain, snatRule...)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	// The chain is still referenced from POSTROUTING
+	assert.Error(t, ipt.DeleteChain("nat", chain))
+	require.NoError(t, ipt.Delete("nat", "POSTROUTING", "-m", "comment", "--comment", "nholuongut SNAT CHAIN", "-j", chain))
+	require.NoError(t, ipt.ClearChain("nat", chain))
+	require.NoError(t, ipt.DeleteChain("nat", chain))
+
+	exists, err = ipt.ChainExists("nat", chain)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestNFTablesConnmark(t *testing.T) {
+	ipt, _ := newFakeNfTables("ip")
+
+	require.NoError(t, ipt.Append("mangle", "PREROUTING", "-m", "comment", "--comment", "nholuongut, primary ENI",
+		"-i", "eth0", "-m", "addrtype", "--dst-type", "LOCAL", "--limit-iface-in", "-j", "CONNMARK", "--set-mark", "0x80/0x80"))
+	require.NoError(t, ipt.Append("mangle", "PREROUTING", "-m", "comment", "--comment", "nholuongut, primary ENI",
+		"-i", "eni+", "-j", "CONNMARK", "--restore-mark", "--mask", "0x80"))
+
+	rules, err := ipt.List("mangle", "PREROUTING")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"-P PREROUTING ACCEPT",
+		`-A PREROUTING -i eth0 -m comment --comment "nholuongut, primary ENI" -m addrtype --dst-type LOCAL --limit-iface-in -j CONNMARK --set-xmark 0x80/0x80`,
+		`-A PREROUTING -i eni+ -m comment --comment "nholuongut, primary ENI" -j CONNMARK --restore-mark --nfmask 0x80 --ctmask 0x80`,
+	}, rules)
+
+	// Rules are matched regardless of the option order and spelling of the rulespec
+	exists, err := ipt.Exists("mangle", "PREROUTING", "-i", "eth0", "-m", "comment", "--comment", "nholuongut, primary ENI",
+		"-m", "addrtype", "--dst-type", "LOCAL", "--limit-iface-in", "-j", "CONNMARK", "--set-xmark", "0x80/0x80")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestNFTablesRestoreMarkKeepsOtherBits(t *testing.T) {
+	r, err := parseRulespec([]string{"-i", "eni+", "-j", "CONNMARK", "--restore-mark", "--mask", "0x80"}, false)
+	require.NoError(t, err)
+	exprs := r.exprs(false)
+	mangle := exprs[len(exprs)-1].(map[string]interface{})["mangle"].(map[string]interface{})
+	assert.Equal(t, nftMeta("mark"), mangle["key"])
+
+	tests := []struct {
+		metaMark uint32
+		ctMark   uint32
+		want     uint32
+	}{
+		{metaMark: 0x0, ctMark: 0x80, want: 0x80},
+		// The bits outside of the mask, e.g. set by the eBPF datapath, are kept
+		{metaMark: 0x10003, ctMark: 0x80, want: 0x10083},
+		{metaMark: 0x10083, ctMark: 0x0, want: 0x10003},
+		// The bits of the connection mark outside of the mask are not restored
+		{metaMark: 0x1, ctMark: 0x180, want: 0x81},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, evalNftMark(t, mangle["value"], tt.metaMark, tt.ctMark), "meta mark %#x, ct mark %#x", tt.metaMark, tt.ctMark)
+	}
+}
+
+// evalNftMark evaluates an nftables mark expression for the given packet and connection marks
+func evalNftMark(t *testing.T, value interface{}, metaMark, ctMark uint32) uint32 {
+	if n, ok := nftNumber(value); ok {
+		return n
+	}
+	expr := value.(map[string]interface{})
+	switch {
+	case expr["meta"] != nil:
+		return metaMark
+	case expr["ct"] != nil:
+		return ctMark
+	}
+	if operands, ok := nftBinop(value, "&"); ok {
+		return evalNftMark(t, operands[0], metaMark, ctMark) & evalNftMark(t, operands[1], metaMark, ctMark)
+	}
+	if operands, ok := nftBinop(value, "|"); ok {
+		return evalNftMark(t, operands[0], metaMark, ctMark) | evalNftMark(t, operands[1], metaMark, ctMark)
+	}
+	t.Fatalf("unexpected mark expression %v", value)
+	return 0
+}
+
+func TestNFTablesEgressSNAT(t *testing.T) {
+	ipt, _ := newFakeNfTables("ip6")
+	chain := "CNI-E6-2ab0e6e3e3d7a5b2f2e8c3a1"
+	comment := `name: "nholuongut-cni" id: "1234567890"`
+
+	require.NoError(t, ipt.NewChain("nat", chain))
+	require.NoError(t, ipt.AppendUnique("nat", chain, "-d", "ff00::/8", "-j", "ACCEPT", "-m", "comment", "--comment", comment))
+	require.NoError(t, ipt.AppendUnique("nat", chain, "-d", "ff00::/8", "-j", "ACCEPT", "-m", "comment", "--comment", comment))
+	require.NoError(t, ipt.AppendUnique("nat", "POSTROUTING", "-s", "fd00::10", "-j", chain, "-m", "comment", "--comment", comment))
+
+	rules, err := ipt.List("nat", chain)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"-N " + chain,
+		`-A ` + chain + ` -d ff00::/8 -m comment --comment "name: \"nholuongut-cni\" id: \"1234567890\"" -j ACCEPT`,
+	}, rules)
+	rules, err = ipt.List("nat", "POSTROUTING")
+	assert.NoError(t, err)
+	assert.Equal(t, `-A POSTROUTING -s fd00::10/128 -m comment --comment "name: \"nholuongut-cni\" id: \"1234567890\"" -j `+chain, rules[1])
+}
+
+func TestNFTablesErrors(t *testing.T) {
+	ipt, f := newFakeNfTables("ip")
+
+	err := ipt.Delete("nat", "POSTROUTING", "-j", "RETURN")
+	var nftErr *NFTablesError
+	assert.True(t, errors.As(err, &nftErr))
+	assert.True(t, nftErr.IsNotExist())
+
+	err = ipt.Append("nat", "POSTROUTING", "-j", "nholuongut-SNAT-CHAIN-0")
+	assert.True(t, errors.As(err, &nftErr))
+	assert.True(t, nftErr.IsNotExist())
+
+	require.NoError(t, ipt.NewChain("nat", "nholuongut-SNAT-CHAIN-0"))
+	err = ipt.NewChain("nat", "nholuongut-SNAT-CHAIN-0")
+	assert.Contains(t, err.Error(), "Chain already exists")
+
+	err = ipt.Append("nat", "POSTROUTING", "-p", "tcp", "-j", "RETURN")
+	assert.Contains(t, err.Error(), "unsupported option -p")
+
+	_, err = ipt.List("raw", "PREROUTING")
+	assert.Error(t, err)
+
+	// Failed updates are never committed
+	assert.Equal(t, 1, f.commits)
+}
+
+func Test_ruleFromExprs(t *testing.T) {
+	tests := []struct {
+		name    string
+		listing string
+		ipv6    bool
+		want    []string
+	}{
+		{
+			name: "host address and single fib flag",
+			listing: `[{"match": {"op": "==", "left": {"payload": {"protocol": "ip", "field": "daddr"}}, "right": "10.0.0.1"}},
+				{"match": {"op": "!=", "left": {"fib": {"result": "type", "flags": "daddr"}}, "right": "local"}},
+				{"return": null}]`,
+			want: []string{"-d", "10.0.0.1/32", "-m", "addrtype", "!", "--dst-type", "LOCAL", "-j", "RETURN"},
+		},
+		{
+			name: "connmark listed with or",
+			listing: `[{"match": {"op": "==", "left": {"meta": {"key": "iifname"}}, "right": "eni*"}},
+				{"mangle": {"key": {"ct": {"key": "mark"}}, "value": {"|": [{"&": [{"ct": {"key": "mark"}}, 4294967167]}, 128]}}}]`,
+			want: []string{"-i", "eni+", "-j", "CONNMARK", "--set-xmark", "0x80/0x80"},
+		},
+		{
+			name: "mark restore and connection state",
+			listing: `[{"match": {"op": "in", "left": {"ct": {"key": "state"}}, "right": "new"}},
+				{"mangle": {"key": {"meta": {"key": "mark"}}, "value": {"|": [{"&": [{"meta": {"key": "mark"}}, 4294967167]}, {"&": [{"ct": {"key": "mark"}}, 128]}]}}}]`,
+			want: []string{"-m", "state", "--state", "NEW", "-j", "CONNMARK", "--restore-mark", "--nfmask", "0x80", "--ctmask", "0x80"},
+		},
+		{
+			name: "v6 prefix with counter and random snat",
+			listing: `[{"match": {"op": "==", "left": {"payload": {"protocol": "ip6", "field": "saddr"}}, "right": {"prefix": {"addr": "fd00::", "len": 64}}}},
+				{"counter": {"packets": 0, "bytes": 0}},
+				{"snat": {"addr": "2600::1", "flags": ["random"]}}]`,
+			ipv6: true,
+			want: []string{"-s", "fd00::/64", "-j", "SNAT", "--to-source", "2600::1", "--random"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var exprs []interface{}
+			require.NoError(t, json.Unmarshal([]byte(tt.listing), &exprs))
+			r, err := ruleFromExprs(exprs, "", tt.ipv6)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, r.rulespec())
+		})
+	}
+}
+
+func TestResolveBackend(t *testing.T) {
+	defer func(lp func(string) (string, error), v func() (string, error)) {
+		lookPath, iptablesVersion = lp, v
+	}(lookPath, iptablesVersion)
+	found := func(file string) (string, error) { return "/usr/sbin/" + file, nil }
+	version := func(v string) func() (string, error) {
+		return func() (string, error) { return v, nil }
+	}
+
+	assert.Equal(t, BackendIPTables, ResolveBackend(BackendIPTables))
+	assert.Equal(t, BackendNFTables, ResolveBackend(BackendNFTables))
+
+	lookPath, iptablesVersion = found, version("iptables v1.8.7 (nf_tables)")
+	assert.Equal(t, BackendNFTables, ResolveBackend(BackendAuto))
+
+	lookPath, iptablesVersion = found, version("iptables v1.8.7 (legacy)")
+	assert.Equal(t, BackendIPTables, ResolveBackend(BackendAuto))
+
+	lookPath = func(file string) (string, error) { return "", errors.New("not found") }
+	assert.Equal(t, BackendIPTables, ResolveBackend(BackendAuto))
+}
+
+func TestLoadBackendFromEnv(t *testing.T) {
+	defer os.Unsetenv(envBackend)
+
+	assert.Equal(t, DefaultBackend, LoadBackendFromEnv())
+
+	_ = os.Setenv(envBackend, "auto")
+	assert.Equal(t, BackendAuto, LoadBackendFromEnv())
+
+	_ = os.Setenv(envBackend, "ebtables")
+	assert.Equal(t, DefaultBackend, LoadBackendFromEnv())
+}
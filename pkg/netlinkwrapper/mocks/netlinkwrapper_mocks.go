@@ -91,6 +91,50 @@ func (mr *MockNetLinkMockRecorder) AddrList(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddrList", reflect.TypeOf((*MockNetLink)(nil).AddrList), arg0, arg1)
 }
 
+// BpfProgLoad mocks base method.
+func (m *MockNetLink) BpfProgLoad(arg0 netlink.BpfProgType, arg1 []uint64, arg2 string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BpfProgLoad", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BpfProgLoad indicates an expected call of BpfProgLoad.
+func (mr *MockNetLinkMockRecorder) BpfProgLoad(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BpfProgLoad", reflect.TypeOf((*MockNetLink)(nil).BpfProgLoad), arg0, arg1, arg2)
+}
+
+// FilterAdd mocks base method.
+func (m *MockNetLink) FilterAdd(arg0 netlink.Filter) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FilterAdd", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FilterAdd indicates an expected call of FilterAdd.
+func (mr *MockNetLinkMockRecorder) FilterAdd(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FilterAdd", reflect.TypeOf((*MockNetLink)(nil).FilterAdd), arg0)
+}
+
+// FilterList mocks base method.
+func (m *MockNetLink) FilterList(arg0 netlink.Link, arg1 uint32) ([]netlink.Filter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FilterList", arg0, arg1)
+	ret0, _ := ret[0].([]netlink.Filter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FilterList indicates an expected call of FilterList.
+func (mr *MockNetLinkMockRecorder) FilterList(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FilterList", reflect.TypeOf((*MockNetLink)(nil).FilterList), arg0, arg1)
+}
+
 // LinkAdd mocks base method.
 func (m *MockNetLink) LinkAdd(arg0 netlink.Link) error {
 	m.ctrl.T.Helper()
@@ -248,6 +292,20 @@ func (mr *MockNetLinkMockRecorder) ParseAddr(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseAddr", reflect.TypeOf((*MockNetLink)(nil).ParseAddr), arg0)
 }
 
+// QdiscAdd mocks base method.
+func (m *MockNetLink) QdiscAdd(arg0 netlink.Qdisc) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QdiscAdd", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// QdiscAdd indicates an expected call of QdiscAdd.
+func (mr *MockNetLinkMockRecorder) QdiscAdd(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QdiscAdd", reflect.TypeOf((*MockNetLink)(nil).QdiscAdd), arg0)
+}
+
 // RouteAdd mocks base method.
 func (m *MockNetLink) RouteAdd(arg0 *netlink.Route) error {
 	m.ctrl.T.Helper()
@@ -291,6 +349,21 @@ func (mr *MockNetLinkMockRecorder) RouteList(arg0, arg1 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RouteList", reflect.TypeOf((*MockNetLink)(nil).RouteList), arg0, arg1)
 }
 
+// RouteListFiltered mocks base method.
+func (m *MockNetLink) RouteListFiltered(arg0 int, arg1 *netlink.Route, arg2 uint64) ([]netlink.Route, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RouteListFiltered", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]netlink.Route)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RouteListFiltered indicates an expected call of RouteListFiltered.
+func (mr *MockNetLinkMockRecorder) RouteListFiltered(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RouteListFiltered", reflect.TypeOf((*MockNetLink)(nil).RouteListFiltered), arg0, arg1, arg2)
+}
+
 // RouteReplace mocks base method.
 func (m *MockNetLink) RouteReplace(arg0 *netlink.Route) error {
 	m.ctrl.T.Helper()
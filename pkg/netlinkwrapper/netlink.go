@@ -15,9 +15,12 @@
 package netlinkwrapper
 
 import (
+	"runtime"
 	"syscall"
+	"unsafe"
 
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 )
 
 // NetLink wraps methods used from the vishvananda/netlink package
@@ -44,6 +47,8 @@ type NetLink interface {
 	LinkSetDown(link netlink.Link) error
 	// RouteList gets a list of routes in the system.
 	RouteList(link netlink.Link, family int) ([]netlink.Route, error)
+	// RouteListFiltered gets a list of routes matching filter, e.g. the ones of a route table
+	RouteListFiltered(family int, filter *netlink.Route, filterMask uint64) ([]netlink.Route, error)
 	// RouteAdd will add a route to the route table
 	RouteAdd(route *netlink.Route) error
 	// RouteReplace will replace the route in the route table
@@ -64,6 +69,14 @@ type NetLink interface {
 	RuleList(family int) ([]netlink.Rule, error)
 	// LinkSetMTU is equivalent to `ip link set dev $link mtu $mtu`
 	LinkSetMTU(link netlink.Link, mtu int) error
+	// QdiscAdd is equivalent to `tc qdisc add $qdisc`
+	QdiscAdd(qdisc netlink.Qdisc) error
+	// FilterAdd is equivalent to `tc filter add $filter`
+	FilterAdd(filter netlink.Filter) error
+	// FilterList is equivalent to `tc filter show dev $link parent $parent`
+	FilterList(link netlink.Link, parent uint32) ([]netlink.Filter, error)
+	// BpfProgLoad loads the eBPF program of insns and returns its file descriptor, to be attached with a netlink.BpfFilter
+	BpfProgLoad(progType netlink.BpfProgType, insns []uint64, license string) (int, error)
 }
 
 type netLink struct {
@@ -114,6 +127,10 @@ func (*netLink) RouteList(link netlink.Link, family int) ([]netlink.Route, error
 	return netlink.RouteList(link, family)
 }
 
+func (*netLink) RouteListFiltered(family int, filter *netlink.Route, filterMask uint64) ([]netlink.Route, error) {
+	return netlink.RouteListFiltered(family, filter, filterMask)
+}
+
 func (*netLink) RouteAdd(route *netlink.Route) error {
 	return netlink.RouteAdd(route)
 }
@@ -158,6 +175,36 @@ func (*netLink) LinkSetMTU(link netlink.Link, mtu int) error {
 	return netlink.LinkSetMTU(link, mtu)
 }
 
+func (*netLink) QdiscAdd(qdisc netlink.Qdisc) error {
+	return netlink.QdiscAdd(qdisc)
+}
+
+func (*netLink) FilterAdd(filter netlink.Filter) error {
+	return netlink.FilterAdd(filter)
+}
+
+func (*netLink) FilterList(link netlink.Link, parent uint32) ([]netlink.Filter, error) {
+	return netlink.FilterList(link, parent)
+}
+
+func (*netLink) BpfProgLoad(progType netlink.BpfProgType, insns []uint64, license string) (int, error) {
+	licenseBytes := append([]byte(license), 0)
+	attr := netlink.BPFAttr{
+		ProgType: uint32(progType),
+		InsnCnt:  uint32(len(insns)),
+		Insns:    uintptr(unsafe.Pointer(&insns[0])),
+		License:  uintptr(unsafe.Pointer(&licenseBytes[0])),
+	}
+	fd, _, errno := unix.Syscall(unix.SYS_BPF, unix.BPF_PROG_LOAD, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr))
+	// The kernel reads insns and license through the addresses held in attr
+	runtime.KeepAlive(insns)
+	runtime.KeepAlive(licenseBytes)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
 // IsNotExistsError returns true if the error type is syscall.ESRCH
 // This helps us determine if we should ignore this error as the route
 // that we want to cleanup has been deleted already routing table
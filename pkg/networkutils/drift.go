@@ -0,0 +1,384 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package networkutils
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/coreos/go-iptables/iptables"
+	"github.com/pkg/errors"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/datapath"
+)
+
+// DriftKind is the kind of host network state found missing
+type DriftKind string
+
+const (
+	// DriftKindIPRule is a missing ip rule
+	DriftKindIPRule DriftKind = "ip_rule"
+	// DriftKindRoute is a missing route of the route table of an ENI, or of the pod route table
+	DriftKindRoute DriftKind = "route"
+	// DriftKindIptables is a missing iptables chain or rule
+	DriftKindIptables DriftKind = "iptables"
+)
+
+// Drift is a part of the host network which was missing, and has been repaired
+type Drift struct {
+	Kind DriftKind
+	// Description names what was missing
+	Description string
+}
+
+// PodIPRule is the routing of a pod address, which the CNI plugin sets up with a toContainer and a fromContainer rule,
+// or with a route of the pod route table in the eBPF datapath
+type PodIPRule struct {
+	// IP is the address of the pod
+	IP net.IP
+	// RouteTable is the route table of the ENI of the address, the main route table for the primary ENI
+	RouteTable int
+	// HostVethName is the host side of the veth pair of the pod interface of the address
+	HostVethName string
+}
+
+// RepairHostNetwork adds back the ip rules and iptables rules of SetupHostNetwork which are missing
+func (n *linuxNetwork) RepairHostNetwork(vpcCIDRs []string, primaryMAC string, primaryAddr *net.IP, v4Enabled bool,
+	v6Enabled bool) ([]Drift, error) {
+	n.snatLock.Lock()
+	defer n.snatLock.Unlock()
+
+	ipFamily := unix.AF_INET
+	if v6Enabled && !v4Enabled {
+		ipFamily = unix.AF_INET6
+	}
+	var hostRules []*netlink.Rule
+	if n.nodePortSupportEnabled || !n.useExternalSNAT {
+		mainENIRule := n.netLink.NewRule()
+		mainENIRule.Mark = n.mainENIMark
+		mainENIRule.Mask = &n.mainENIMark
+		mainENIRule.Table = mainRoutingTable
+		mainENIRule.Priority = hostRulePriority
+		mainENIRule.Family = ipFamily
+		hostRules = append(hostRules, mainENIRule)
+	}
+	if n.podDatapath == datapath.ModeEBPF {
+		for _, family := range podRouteFamilies(v4Enabled, v6Enabled) {
+			podRouteRule := n.netLink.NewRule()
+			podRouteRule.Table = PodRouteTable
+			podRouteRule.Priority = ToContainerRulePriority
+			podRouteRule.Family = family
+			hostRules = append(hostRules, podRouteRule)
+		}
+	}
+	drifts, err := n.repairIPRules(hostRules)
+	if err != nil {
+		return drifts, err
+	}
+
+	// No iptables rules are set up in IPv6 mode
+	if !v4Enabled {
+		return drifts, nil
+	}
+	iptablesDrifts, err := n.findMissingHostIptablesRules(vpcCIDRs, primaryMAC, primaryAddr)
+	if err != nil {
+		return drifts, err
+	}
+	if len(iptablesDrifts) > 0 {
+		if err := n.updateHostIptablesRules(vpcCIDRs, primaryMAC, primaryAddr, v4Enabled, v6Enabled); err != nil {
+			return drifts, err
+		}
+		drifts = append(drifts, iptablesDrifts...)
+	}
+	return drifts, nil
+}
+
+// findMissingHostIptablesRules returns the IPv4 chains and rules of updateHostIptablesRules which are missing
+func (n *linuxNetwork) findMissingHostIptablesRules(vpcCIDRs []string, primaryMAC string, primaryAddr *net.IP) ([]Drift, error) {
+	primaryIntf, err := findPrimaryInterfaceName(primaryMAC)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find the primary interface")
+	}
+	ipt, err := n.newIptables(iptables.ProtocolIPv4)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create iptables")
+	}
+
+	var drifts []Drift
+	chains, err := ipt.ListChains("nat")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list iptables nat chains")
+	}
+	for _, chain := range []string{"nholuongut-SNAT-CHAIN-0", "nholuongut-CONNMARK-CHAIN-0"} {
+		if !sets.NewString(chains...).Has(chain) {
+			drifts = append(drifts, Drift{Kind: DriftKindIptables, Description: "iptables chain nat/" + chain})
+		}
+	}
+
+	// Building the rules creates the missing chains, which updateHostIptablesRules then fills
+	snatRules, err := n.buildIptablesSNATRules(vpcCIDRs, primaryAddr, primaryIntf, ipt)
+	if err != nil {
+		return nil, err
+	}
+	connmarkRules, err := n.buildIptablesConnmarkRules(vpcCIDRs, ipt)
+	if err != nil {
+		return nil, err
+	}
+	rules := append(append(snatRules, connmarkRules...), n.buildSNATPolicySNATRules(primaryAddr, ipt)...)
+	rules = append(rules, n.buildSNATPolicyConnmarkRules()...)
+	for _, rule := range rules {
+		if !rule.shouldExist {
+			continue
+		}
+		exists, err := ipt.Exists(rule.table, rule.chain, rule.rule...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to check existence of %v", rule)
+		}
+		if !exists {
+			drifts = append(drifts, Drift{
+				Kind:        DriftKindIptables,
+				Description: fmt.Sprintf("iptables rule %s/%s %s", rule.table, rule.chain, strings.Join(rule.rule, " ")),
+			})
+		}
+	}
+	return drifts, nil
+}
+
+// RepairENINetwork adds back the routes of the route table of the ENI which are missing, and the rule of its route
+// table in the eBPF datapath
+func (n *linuxNetwork) RepairENINetwork(eniIP string, eniMAC string, deviceNumber int, eniSubnetCIDR string) ([]Drift, error) {
+	if deviceNumber == 0 {
+		return nil, errors.New("RepairENINetwork should never be called on the primary ENI")
+	}
+	tableNumber := deviceNumber + 1
+	link, err := linkByMac(eniMAC, n.netLink, retryLinkByMacInterval)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find the link which uses MAC address %s", eniMAC)
+	}
+	_, eniSubnetIPNet, err := net.ParseCIDR(eniSubnetCIDR)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid IP CIDR block %s", eniSubnetCIDR)
+	}
+	isV6 := strings.Contains(eniSubnetCIDR, ":")
+	family := unix.AF_INET
+	gw := GetIPv4Gateway(eniSubnetIPNet)
+	if isV6 {
+		family = unix.AF_INET6
+		gw = GetIPv6Gateway()
+	}
+
+	routes, err := n.netLink.RouteListFiltered(family, &netlink.Route{Table: tableNumber}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the routes of route table %d", tableNumber)
+	}
+	var drifts []Drift
+	for _, route := range eniRoutes(link.Attrs().Index, gw, tableNumber, isV6) {
+		if containsRoute(routes, route) {
+			continue
+		}
+		if err := n.netLink.RouteReplace(&route); err != nil {
+			return drifts, errors.Wrapf(err, "failed to replace route %s of ENI %s", route.Dst, eniIP)
+		}
+		description := fmt.Sprintf("route %s table %d", route.Dst, tableNumber)
+		if route.Gw != nil {
+			description = fmt.Sprintf("route %s via %s table %d", route.Dst, route.Gw, tableNumber)
+		}
+		drifts = append(drifts, Drift{Kind: DriftKindRoute, Description: description})
+	}
+
+	if n.podDatapath == datapath.ModeEBPF && !isV6 {
+		podRouteMarkRule := n.netLink.NewRule()
+		podRouteMarkRule.Mark = PodRouteMark(tableNumber)
+		podRouteMarkMask := uint32(PodRouteMarkMask)
+		podRouteMarkRule.Mask = &podRouteMarkMask
+		podRouteMarkRule.Table = tableNumber
+		podRouteMarkRule.Priority = FromPodRulePriority
+		podRouteMarkRule.Family = unix.AF_INET
+		ruleDrifts, err := n.repairIPRules([]*netlink.Rule{podRouteMarkRule})
+		drifts = append(drifts, ruleDrifts...)
+		if err != nil {
+			return drifts, err
+		}
+	}
+	return drifts, nil
+}
+
+// RepairPodIPRules adds back the toContainer and fromContainer rules of the pod addresses which are missing. The eBPF
+// datapath has no rules per pod, the routes of the pod route table are added back instead. The pods whose host veth
+// does not exist are being set up or torn down, and are left to the CNI plugin.
+func (n *linuxNetwork) RepairPodIPRules(pods []PodIPRule) ([]Drift, error) {
+	if n.podDatapath == datapath.ModeEBPF {
+		return n.repairPodRoutes(pods)
+	}
+	var podRules []*netlink.Rule
+	for _, pod := range pods {
+		if _, err := n.netLink.LinkByName(pod.HostVethName); err != nil {
+			continue
+		}
+		family, bits := unix.AF_INET, 32
+		if pod.IP.To4() == nil {
+			family, bits = unix.AF_INET6, 128
+		}
+		addr := &net.IPNet{IP: pod.IP, Mask: net.CIDRMask(bits, bits)}
+
+		toContainerRule := n.netLink.NewRule()
+		toContainerRule.Dst = addr
+		toContainerRule.Priority = ToContainerRulePriority
+		toContainerRule.Table = mainRoutingTable
+		toContainerRule.Family = family
+		podRules = append(podRules, toContainerRule)
+
+		if pod.RouteTable != mainRoutingTable {
+			fromContainerRule := n.netLink.NewRule()
+			fromContainerRule.Src = addr
+			fromContainerRule.Priority = FromPodRulePriority
+			fromContainerRule.Table = pod.RouteTable
+			fromContainerRule.Family = family
+			podRules = append(podRules, fromContainerRule)
+		}
+	}
+	return n.repairIPRules(podRules)
+}
+
+// repairPodRoutes adds back the routes of the pod addresses of the pod route table which are missing, for the pods whose
+// host veth exists. The tc program marking the traffic from the pod is not repaired.
+func (n *linuxNetwork) repairPodRoutes(pods []PodIPRule) ([]Drift, error) {
+	current := make(map[int][]netlink.Route)
+	var drifts []Drift
+	for _, pod := range pods {
+		family, bits := unix.AF_INET, 32
+		if pod.IP.To4() == nil {
+			family, bits = unix.AF_INET6, 128
+		}
+		familyRoutes, ok := current[family]
+		if !ok {
+			var err error
+			familyRoutes, err = n.netLink.RouteListFiltered(family, &netlink.Route{Table: PodRouteTable}, netlink.RT_FILTER_TABLE)
+			if err != nil {
+				return drifts, errors.Wrapf(err, "failed to list the routes of route table %d", PodRouteTable)
+			}
+			current[family] = familyRoutes
+		}
+		hostVeth, err := n.netLink.LinkByName(pod.HostVethName)
+		if err != nil {
+			continue
+		}
+		route := netlink.Route{
+			LinkIndex: hostVeth.Attrs().Index,
+			Scope:     netlink.SCOPE_LINK,
+			Dst:       &net.IPNet{IP: pod.IP, Mask: net.CIDRMask(bits, bits)},
+			Table:     PodRouteTable,
+		}
+		if containsRoute(familyRoutes, route) {
+			continue
+		}
+		if err := n.netLink.RouteReplace(&route); err != nil {
+			return drifts, errors.Wrapf(err, "failed to replace route %s of hostVeth %s", route.Dst, pod.HostVethName)
+		}
+		drifts = append(drifts, Drift{
+			Kind:        DriftKindRoute,
+			Description: fmt.Sprintf("route %s dev %s table %d", route.Dst, pod.HostVethName, PodRouteTable),
+		})
+	}
+	return drifts, nil
+}
+
+// repairIPRules adds the rules which are missing
+func (n *linuxNetwork) repairIPRules(rules []*netlink.Rule) ([]Drift, error) {
+	current := make(map[int][]netlink.Rule)
+	var drifts []Drift
+	for _, rule := range rules {
+		familyRules, ok := current[rule.Family]
+		if !ok {
+			var err error
+			if familyRules, err = n.netLink.RuleList(rule.Family); err != nil {
+				return drifts, errors.Wrap(err, "failed to list ip rules")
+			}
+			current[rule.Family] = familyRules
+		}
+		if containsRule(familyRules, rule) {
+			continue
+		}
+		if err := n.netLink.RuleAdd(rule); err != nil && !isRuleExistsError(err) {
+			return drifts, errors.Wrapf(err, "failed to add ip rule %s", ruleDescription(rule))
+		}
+		drifts = append(drifts, Drift{Kind: DriftKindIPRule, Description: "ip rule " + ruleDescription(rule)})
+	}
+	return drifts, nil
+}
+
+// ruleDescription formats the rule as ip rule show does
+func ruleDescription(rule *netlink.Rule) string {
+	description := fmt.Sprintf("%d:", rule.Priority)
+	if rule.Src != nil {
+		description += " from " + rule.Src.String()
+	}
+	if rule.Dst != nil {
+		description += " to " + rule.Dst.String()
+	}
+	if rule.Mark != 0 {
+		description += fmt.Sprintf(" fwmark %#x/%#x", rule.Mark, ruleMask(rule))
+	}
+	return description + fmt.Sprintf(" lookup %d", rule.Table)
+}
+
+func containsRule(rules []netlink.Rule, want *netlink.Rule) bool {
+	for _, rule := range rules {
+		if rule.Priority == want.Priority && rule.Table == want.Table && rule.Mark == want.Mark &&
+			ruleMask(&rule) == ruleMask(want) && ipNetEqual(rule.Src, want.Src) && ipNetEqual(rule.Dst, want.Dst) {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleMask(rule *netlink.Rule) uint32 {
+	if rule.Mask == nil {
+		return 0
+	}
+	return *rule.Mask
+}
+
+func containsRoute(routes []netlink.Route, want netlink.Route) bool {
+	for _, route := range routes {
+		if route.LinkIndex == want.LinkIndex && ipNetEqual(route.Dst, want.Dst) &&
+			(want.Gw == nil || want.Gw.Equal(route.Gw)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ipNetEqual returns true if both networks are equal. Default routes and rules matching all addresses are listed
+// without a network.
+func ipNetEqual(a, b *net.IPNet) bool {
+	if a == nil || b == nil {
+		return isAllAddresses(a) && isAllAddresses(b)
+	}
+	aOnes, aBits := a.Mask.Size()
+	bOnes, bBits := b.Mask.Size()
+	return a.IP.Equal(b.IP) && aOnes == bOnes && aBits == bBits
+}
+
+func isAllAddresses(ipNet *net.IPNet) bool {
+	if ipNet == nil {
+		return true
+	}
+	ones, _ := ipNet.Mask.Size()
+	return ones == 0
+}
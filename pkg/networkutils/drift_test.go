@@ -0,0 +1,107 @@
+// Copyright Amazon.com Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://nholuongut.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package networkutils
+
+import (
+	"net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/datapath"
+)
+
+func TestRepairPodIPRules(t *testing.T) {
+	ctrl, mockNetLink, _, _, _ := setup(t)
+	defer ctrl.Finish()
+
+	ln := &linuxNetwork{netLink: mockNetLink, podDatapath: datapath.ModeIPRule}
+	mockNetLink.EXPECT().NewRule().DoAndReturn(func() *netlink.Rule { return netlink.NewRule() }).Times(3)
+
+	// The rules of the pod on the primary ENI are in place, the fromContainer rule of the other one is missing, and
+	// the third pod was torn down
+	_, primaryPodAddr, _ := net.ParseCIDR("10.0.0.5/32")
+	_, podAddr, _ := net.ParseCIDR("10.0.1.5/32")
+	mockNetLink.EXPECT().LinkByName("eni1").Return(&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "eni1", Index: 10}}, nil)
+	mockNetLink.EXPECT().LinkByName("eni2").Return(&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "eni2", Index: 11}}, nil)
+	mockNetLink.EXPECT().LinkByName("eni3").Return(nil, netlink.LinkNotFoundError{})
+	mockNetLink.EXPECT().RuleList(unix.AF_INET).Return([]netlink.Rule{
+		{Dst: primaryPodAddr, Priority: ToContainerRulePriority, Table: unix.RT_TABLE_MAIN},
+		{Dst: podAddr, Priority: ToContainerRulePriority, Table: unix.RT_TABLE_MAIN},
+		// A rule to another table does not count
+		{Src: podAddr, Priority: FromPodRulePriority, Table: 3},
+	}, nil)
+	mockNetLink.EXPECT().RuleAdd(gomock.Any()).DoAndReturn(func(rule *netlink.Rule) error {
+		assert.Equal(t, podAddr.String(), rule.Src.String())
+		assert.Equal(t, 2, rule.Table)
+		return nil
+	})
+
+	drifts, err := ln.RepairPodIPRules([]PodIPRule{
+		{IP: net.ParseIP("10.0.0.5"), RouteTable: unix.RT_TABLE_MAIN, HostVethName: "eni1"},
+		{IP: net.ParseIP("10.0.1.5"), RouteTable: 2, HostVethName: "eni2"},
+		{IP: net.ParseIP("10.0.1.6"), RouteTable: 2, HostVethName: "eni3"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Drift{{Kind: DriftKindIPRule, Description: "ip rule 1536: from 10.0.1.5/32 lookup 2"}}, drifts)
+}
+
+func TestRepairPodIPRulesWithEBPFDatapath(t *testing.T) {
+	ctrl, mockNetLink, _, _, _ := setup(t)
+	defer ctrl.Finish()
+
+	ln := &linuxNetwork{netLink: mockNetLink, podDatapath: datapath.ModeEBPF}
+	// The route of the first pod is in place, the one of the second pod is missing, and the third pod has no host
+	// veth yet
+	_, podAddr, _ := net.ParseCIDR("10.0.1.5/32")
+	_, otherPodAddr, _ := net.ParseCIDR("10.0.1.6/32")
+	mockNetLink.EXPECT().RouteListFiltered(unix.AF_INET, &netlink.Route{Table: PodRouteTable}, netlink.RT_FILTER_TABLE).Return([]netlink.Route{
+		{LinkIndex: 10, Dst: podAddr, Table: PodRouteTable},
+	}, nil)
+	mockNetLink.EXPECT().LinkByName("eni1").Return(&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "eni1", Index: 10}}, nil)
+	mockNetLink.EXPECT().LinkByName("eni2").Return(&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "eni2", Index: 11}}, nil)
+	mockNetLink.EXPECT().LinkByName("eni3").Return(nil, netlink.LinkNotFoundError{})
+	mockNetLink.EXPECT().RouteReplace(gomock.Any()).DoAndReturn(func(route *netlink.Route) error {
+		assert.Equal(t, 11, route.LinkIndex)
+		assert.Equal(t, netlink.SCOPE_LINK, route.Scope)
+		assert.Equal(t, otherPodAddr.String(), route.Dst.String())
+		assert.Equal(t, PodRouteTable, route.Table)
+		return nil
+	})
+
+	drifts, err := ln.RepairPodIPRules([]PodIPRule{
+		{IP: net.ParseIP("10.0.1.5"), RouteTable: 2, HostVethName: "eni1"},
+		{IP: net.ParseIP("10.0.1.6"), RouteTable: 2, HostVethName: "eni2"},
+		{IP: net.ParseIP("10.0.1.7"), RouteTable: 2, HostVethName: "eni3"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Drift{{Kind: DriftKindRoute, Description: "route 10.0.1.6/32 dev eni2 table 5000"}}, drifts)
+}
+
+func TestContainsRoute(t *testing.T) {
+	gw := net.ParseIP("10.0.1.1")
+	routes := eniRoutes(3, gw, 2, false)
+	// Default routes are listed without a destination
+	listed := []netlink.Route{
+		{LinkIndex: 3, Dst: routes[0].Dst, Table: 2},
+		{LinkIndex: 3, Gw: gw, Table: 2},
+	}
+	assert.True(t, containsRoute(listed, routes[0]))
+	assert.True(t, containsRoute(listed, routes[1]))
+	assert.False(t, containsRoute(listed[:1], routes[1]))
+	assert.False(t, containsRoute(listed, eniRoutes(4, gw, 2, false)[0]))
+}
@@ -23,6 +23,7 @@ import (
 	reflect "reflect"
 	time "time"
 
+	networkutils "github.com/nholuongut/amazon-vpc-cni-k8s/pkg/networkutils"
 	gomock "github.com/golang/mock/gomock"
 	netlink "github.com/vishvananda/netlink"
 )
@@ -137,6 +138,75 @@ func (mr *MockNetworkAPIsMockRecorder) GetRuleListBySrc(arg0, arg1 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRuleListBySrc", reflect.TypeOf((*MockNetworkAPIs)(nil).GetRuleListBySrc), arg0, arg1)
 }
 
+// ReloadSNATConfig mocks base method.
+func (m *MockNetworkAPIs) ReloadSNATConfig(arg0 map[string]string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReloadSNATConfig", arg0)
+}
+
+// ReloadSNATConfig indicates an expected call of ReloadSNATConfig.
+func (mr *MockNetworkAPIsMockRecorder) ReloadSNATConfig(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReloadSNATConfig", reflect.TypeOf((*MockNetworkAPIs)(nil).ReloadSNATConfig), arg0)
+}
+
+// RepairENINetwork mocks base method.
+func (m *MockNetworkAPIs) RepairENINetwork(arg0, arg1 string, arg2 int, arg3 string) ([]networkutils.Drift, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RepairENINetwork", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]networkutils.Drift)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RepairENINetwork indicates an expected call of RepairENINetwork.
+func (mr *MockNetworkAPIsMockRecorder) RepairENINetwork(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RepairENINetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).RepairENINetwork), arg0, arg1, arg2, arg3)
+}
+
+// RepairHostNetwork mocks base method.
+func (m *MockNetworkAPIs) RepairHostNetwork(arg0 []string, arg1 string, arg2 *net.IP, arg3, arg4 bool) ([]networkutils.Drift, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RepairHostNetwork", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].([]networkutils.Drift)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RepairHostNetwork indicates an expected call of RepairHostNetwork.
+func (mr *MockNetworkAPIsMockRecorder) RepairHostNetwork(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RepairHostNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).RepairHostNetwork), arg0, arg1, arg2, arg3, arg4)
+}
+
+// RepairPodIPRules mocks base method.
+func (m *MockNetworkAPIs) RepairPodIPRules(arg0 []networkutils.PodIPRule) ([]networkutils.Drift, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RepairPodIPRules", arg0)
+	ret0, _ := ret[0].([]networkutils.Drift)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RepairPodIPRules indicates an expected call of RepairPodIPRules.
+func (mr *MockNetworkAPIsMockRecorder) RepairPodIPRules(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RepairPodIPRules", reflect.TypeOf((*MockNetworkAPIs)(nil).RepairPodIPRules), arg0)
+}
+
+// SetSNATPolicyRules mocks base method.
+func (m *MockNetworkAPIs) SetSNATPolicyRules(arg0 []networkutils.SNATPolicyRule) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSNATPolicyRules", arg0)
+}
+
+// SetSNATPolicyRules indicates an expected call of SetSNATPolicyRules.
+func (mr *MockNetworkAPIsMockRecorder) SetSNATPolicyRules(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSNATPolicyRules", reflect.TypeOf((*MockNetworkAPIs)(nil).SetSNATPolicyRules), arg0)
+}
+
 // SetupENINetwork mocks base method.
 func (m *MockNetworkAPIs) SetupENINetwork(arg0, arg1 string, arg2 int, arg3 string) error {
 	m.ctrl.T.Helper()
@@ -165,6 +235,20 @@ func (mr *MockNetworkAPIsMockRecorder) SetupHostNetwork(arg0, arg1, arg2, arg3,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetupHostNetwork", reflect.TypeOf((*MockNetworkAPIs)(nil).SetupHostNetwork), arg0, arg1, arg2, arg3, arg4, arg5)
 }
 
+// UpdateEgressEIPRules mocks base method.
+func (m *MockNetworkAPIs) UpdateEgressEIPRules(arg0 []string, arg1 string, arg2 int, arg3 map[string]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEgressEIPRules", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateEgressEIPRules indicates an expected call of UpdateEgressEIPRules.
+func (mr *MockNetworkAPIsMockRecorder) UpdateEgressEIPRules(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEgressEIPRules", reflect.TypeOf((*MockNetworkAPIs)(nil).UpdateEgressEIPRules), arg0, arg1, arg2, arg3)
+}
+
 // UpdateExternalServiceIpRules mocks base method.
 func (m *MockNetworkAPIs) UpdateExternalServiceIpRules(arg0 []netlink.Rule, arg1 []string) error {
 	m.ctrl.T.Helper()
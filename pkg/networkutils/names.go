@@ -19,3 +19,11 @@ func GeneratePodHostVethNameSuffix(podNamespace string, podName string) string {
 	h.Write([]byte(fmt.Sprintf("%s.%s", podNamespace, podName)))
 	return hex.EncodeToString(h.Sum(nil))[:11]
 }
+
+// GeneratePodExtraHostVethName generates the name for the host-side veth device of an extra interface of a
+// multi-homed pod, which must differ from the one of the pod's primary interface.
+func GeneratePodExtraHostVethName(prefix string, podNamespace string, podName string, ifName string) string {
+	h := sha1.New()
+	h.Write([]byte(fmt.Sprintf("%s.%s.%s", podNamespace, podName, ifName)))
+	return fmt.Sprintf("%s%s", prefix, hex.EncodeToString(h.Sum(nil))[:11])
+}
@@ -86,3 +86,10 @@ func TestGeneratePodHostVethNameSuffix(t *testing.T) {
 		})
 	}
 }
+
+func TestGeneratePodExtraHostVethName(t *testing.T) {
+	net1 := GeneratePodExtraHostVethName("eni", "kube-system", "coredns-57ff979f67-qqbdh", "net1")
+	assert.Len(t, net1, 14)
+	assert.NotEqual(t, GeneratePodHostVethName("eni", "kube-system", "coredns-57ff979f67-qqbdh"), net1)
+	assert.NotEqual(t, GeneratePodExtraHostVethName("eni", "kube-system", "coredns-57ff979f67-qqbdh", "net2"), net1)
+}
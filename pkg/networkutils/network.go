@@ -21,13 +21,16 @@ import (
 	"net"
 	"os"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/coreos/go-iptables/iptables"
 
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/datapath"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/sgpp"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/utils"
 
@@ -60,6 +63,9 @@ const (
 
 	// 513 - 1023, can be used for priority lower than fromPodRule but higher than default nonVPC CIDR rule
 
+	// Rule priority for the traffic of the pods which egress through the Elastic IPs of the egress ENI
+	egressEIPRulePriority = 1000
+
 	// 1024 is reserved for (ip rule not to <VPC's subnet> table main)
 	hostRulePriority = 1024
 
@@ -71,6 +77,13 @@ const (
 	// Rule priority for traffic from pod
 	FromPodRulePriority = 1536
 
+	// Route table of the routes to the pods in the eBPF datapath, above the route tables of the vlans of branch ENIs
+	PodRouteTable = 5000
+
+	// Part of the mark set by the eBPF datapath on the traffic from the pods, holding the route table of their ENI.
+	// Note: the mark space is a little crowded, see defaultConnmark.
+	PodRouteMarkMask = 0x00ff0000
+
 	// Main route table
 	mainRoutingTable = unix.RT_TABLE_MAIN
 
@@ -115,6 +128,17 @@ const (
 	// - Calico uses 0xffff0000.
 	defaultConnmark = 0x80
 
+	// egressEIPMark marks the connections to outside of the VPC of the pods which egress through the Elastic IPs of
+	// the egress ENI, so that they are routed through it. It must not overlap with the connmark above.
+	egressEIPMark = 0x40
+
+	// snatPolicyComment prefixes the comment of the rules of the SNAT policies, followed by the name of the policy
+	snatPolicyComment = "nholuongut, SNAT policy "
+
+	// egressEIPChain and egressEIPSNATChain hold the per pod rules of the pods which egress through Elastic IPs
+	egressEIPChain     = "nholuongut-EGRESS-CHAIN-0"
+	egressEIPSNATChain = "nholuongut-EGRESS-SNAT-CHAIN-0"
+
 	// envMTU gives a way to configure the MTU size for new ENIs attached. Range is from 576 to 9001.
 	envMTU     = "nholuongut_VPC_ENI_MTU"
 	defaultMTU = 9001
@@ -154,15 +178,31 @@ type NetworkAPIs interface {
 	CleanUpStalenholuongutChains(v4Enabled, v6Enabled bool) error
 	UseExternalSNAT() bool
 	GetExcludeSNATCIDRs() []string
+	// ReloadSNATConfig applies the values of the SNAT env variables in env, the next UpdateHostIptablesRules call
+	// applies them to the host
+	ReloadSNATConfig(env map[string]string)
+	// SetSNATPolicyRules sets the rules of the SNAT policies of the node, the next UpdateHostIptablesRules call applies
+	// them
+	SetSNATPolicyRules(rules []SNATPolicyRule)
 	GetExternalServiceCIDRs() []string
 	GetRuleList() ([]netlink.Rule, error)
 	GetRuleListBySrc(ruleList []netlink.Rule, src net.IPNet) ([]netlink.Rule, error)
 	UpdateRuleListBySrc(ruleList []netlink.Rule, src net.IPNet) error
 	UpdateExternalServiceIpRules(ruleList []netlink.Rule, externalIPs []string) error
+	// UpdateEgressEIPRules updates the routing and SNAT rules of the pods which egress through the egress ENI
+	UpdateEgressEIPRules(vpcCIDRs []string, eniMAC string, deviceNumber int, podSNATIPs map[string]string) error
 	GetLinkByMac(mac string, retryInterval time.Duration) (netlink.Link, error)
+	// RepairHostNetwork adds back the ip rules and iptables rules of SetupHostNetwork which are missing
+	RepairHostNetwork(vpcCIDRs []string, primaryMAC string, primaryAddr *net.IP, v4Enabled bool, v6Enabled bool) ([]Drift, error)
+	// RepairENINetwork adds back the routes of SetupENINetwork which are missing
+	RepairENINetwork(eniIP string, mac string, deviceNumber int, subnetCIDR string) ([]Drift, error)
+	// RepairPodIPRules adds back the ip rules of the pod addresses which are missing
+	RepairPodIPRules(pods []PodIPRule) ([]Drift, error)
 }
 
 type linuxNetwork struct {
+	// snatLock guards the SNAT settings, which are reloaded while the host iptables rules may be updated
+	snatLock               sync.Mutex
 	useExternalSNAT        bool
 	ipv6EgressEnabled      bool
 	excludeSNATCIDRs       []string
@@ -172,6 +212,8 @@ type linuxNetwork struct {
 	mtu                    int
 	vethPrefix             string
 	podSGEnforcingMode     sgpp.EnforcingMode
+	podDatapath            datapath.Mode
+	snatPolicyRules        []SNATPolicyRule
 
 	netLink     netlinkwrapper.NetLink
 	ns          nswrapper.NS
@@ -187,8 +229,35 @@ const (
 	randomPRNGSNAT
 )
 
+// SNATPolicyAction is what is done to the source address of the traffic matched by a SNAT policy rule
+type SNATPolicyAction int
+
+const (
+	// SNATPolicyNoSNAT keeps the pod IP as the source address
+	SNATPolicyNoSNAT SNATPolicyAction = iota
+	// SNATPolicySNAT translates the source address to the primary IP of the node, with the port randomization of
+	// nholuongut_VPC_K8S_CNI_RANDOMIZESNAT
+	SNATPolicySNAT
+	// SNATPolicySNATRandomFully translates the source address to the primary IP of the node, with fully randomized
+	// source ports
+	SNATPolicySNATRandomFully
+)
+
+// SNATPolicyRule is the rule of a SNAT policy. The rules are matched in order, ahead of the SNAT env variables.
+type SNATPolicyRule struct {
+	// Policy is the name of the policy, set in the comment of its iptables rules
+	Policy string
+	// Destinations are the IPv4 CIDRs the rule applies to
+	Destinations []string
+	// Sources are the IPs of the pods the rule applies to, all pods when it is empty
+	Sources []string
+	Action  SNATPolicyAction
+}
+
 // New creates a linuxNetwork object
 func New() NetworkAPIs {
+	netfilterBackend := iptableswrapper.ResolveBackend(iptableswrapper.LoadBackendFromEnv())
+	log.Infof("Using %s to program host netfilter rules", netfilterBackend)
 	return &linuxNetwork{
 		useExternalSNAT:        useExternalSNAT(),
 		ipv6EgressEnabled:      ipV6EgressEnabled(),
@@ -198,14 +267,14 @@ func New() NetworkAPIs {
 		nodePortSupportEnabled: nodePortSupportEnabled(),
 		mainENIMark:            getConnmark(),
 		mtu:                    GetEthernetMTU(),
-		vethPrefix:             getVethPrefixName(),
+		vethPrefix:             GetVethPrefixName(),
 		podSGEnforcingMode:     sgpp.LoadEnforcingModeFromEnv(),
+		podDatapath:            datapath.LoadModeFromEnv(),
 
 		netLink: netlinkwrapper.NewNetLink(),
 		ns:      nswrapper.NewNS(),
 		newIptables: func(IPProtocol iptables.Protocol) (iptableswrapper.IPTablesIface, error) {
-			ipt, err := iptables.NewWithProtocol(IPProtocol)
-			return ipt, err
+			return iptableswrapper.New(IPProtocol, netfilterBackend)
 		},
 	}
 }
@@ -301,7 +370,10 @@ func (n *linuxNetwork) SetupHostNetwork(vpcv4CIDRs []string, primaryMAC string,
 
 	ipFamily := unix.AF_INET
 	if v6Enabled {
-		ipFamily = unix.AF_INET6
+		// In dual stack mode, the main ENI rule is only needed for IPv4 pods behind secondary ENIs.
+		if !v4Enabled {
+			ipFamily = unix.AF_INET6
+		}
 		if err := n.enableIPv6(); err != nil {
 			return errors.Wrapf(err, "failed to enable IPv6")
 		}
@@ -366,18 +438,50 @@ func (n *linuxNetwork) SetupHostNetwork(vpcv4CIDRs []string, primaryMAC string,
 		}
 	}
 
+	// In the eBPF datapath, the routes to the pods are kept in a route table of their own, which replaces the
+	// toContainer rule of each pod. Its rule is left in place if the datapath is switched back, for the existing pods.
+	if n.podDatapath == datapath.ModeEBPF {
+		for _, family := range podRouteFamilies(v4Enabled, v6Enabled) {
+			podRouteRule := n.netLink.NewRule()
+			podRouteRule.Table = PodRouteTable
+			podRouteRule.Priority = ToContainerRulePriority
+			podRouteRule.Family = family
+			if err := n.netLink.RuleAdd(podRouteRule); err != nil && !isRuleExistsError(err) {
+				return errors.Wrap(err, "host network setup: failed to add pod route rule")
+			}
+		}
+	}
+
 	return n.updateHostIptablesRules(vpcv4CIDRs, primaryMAC, primaryAddr, v4Enabled, v6Enabled)
 }
 
+func podRouteFamilies(v4Enabled bool, v6Enabled bool) []int {
+	var families []int
+	if v4Enabled {
+		families = append(families, unix.AF_INET)
+	}
+	if v6Enabled {
+		families = append(families, unix.AF_INET6)
+	}
+	return families
+}
+
+// PodRouteMark returns the mark set by the eBPF datapath on the traffic from the pods behind the ENI of rtTable
+func PodRouteMark(rtTable int) uint32 {
+	return uint32(rtTable) << 16 & PodRouteMarkMask
+}
+
 // UpdateHostIptablesRules updates the NAT table rules based on the VPC CIDRs configuration
 func (n *linuxNetwork) UpdateHostIptablesRules(vpcCIDRs []string, primaryMAC string, primaryAddr *net.IP, v4Enabled bool,
 	v6Enabled bool) error {
+	n.snatLock.Lock()
+	defer n.snatLock.Unlock()
 	return n.updateHostIptablesRules(vpcCIDRs, primaryMAC, primaryAddr, v4Enabled, v6Enabled)
 }
 
 func (n *linuxNetwork) CleanUpStalenholuongutChains(v4Enabled, v6Enabled bool) error {
 	ipProtocol := iptables.ProtocolIPv4
-	if v6Enabled {
+	if v6Enabled && !v4Enabled {
 		ipProtocol = iptables.ProtocolIPv6
 	}
 
@@ -428,7 +532,7 @@ func (n *linuxNetwork) updateHostIptablesRules(vpcCIDRs []string, primaryMAC str
 	}
 
 	ipProtocol := iptables.ProtocolIPv4
-	if v6Enabled {
+	if v6Enabled && !v4Enabled {
 		// Essentially a stub function for now in V6 mode. We will need it when we support v6 in secondary IP and
 		// custom networking modes. We don't need to install any SNAT rules in v6 mode and currently there is no need
 		// to mark packets entering via Primary ENI as all the pods in v6 mode will be behind primary ENI. Will have to
@@ -457,6 +561,13 @@ func (n *linuxNetwork) updateHostIptablesRules(vpcCIDRs []string, primaryMAC str
 		if err := n.updateIptablesRules(iptablesConnmarkRules, ipt); err != nil {
 			return err
 		}
+
+		if err := updateSNATPolicyIptablesRules("nholuongut-SNAT-CHAIN-0", n.buildSNATPolicySNATRules(primaryAddr, ipt), ipt); err != nil {
+			return err
+		}
+		if err := updateSNATPolicyIptablesRules("nholuongut-CONNMARK-CHAIN-0", n.buildSNATPolicyConnmarkRules(), ipt); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -521,20 +632,11 @@ func (n *linuxNetwork) buildIptablesSNATRules(vpcCIDRs []string, primaryAddr *ne
 		"-m", "comment", "--comment", "nholuongut, SNAT",
 		"-m", "addrtype", "!", "--dst-type", "LOCAL",
 		"-j", "SNAT", "--to-source", primaryAddr.String()}
-	if n.typeOfSNAT == randomHashSNAT {
-		snatRule = append(snatRule, "--random")
-	}
-	if n.typeOfSNAT == randomPRNGSNAT {
-		if ipt.HasRandomFully() {
-			snatRule = append(snatRule, "--random-fully")
-		} else {
-			log.Warn("prng (--random-fully) requested, but iptables version does not support it. " +
-				"Falling back to hashrandom (--random)")
-			snatRule = append(snatRule, "--random")
-		}
-	}
+	snatRule = append(snatRule, n.snatRandomOptions(ipt)...)
 
-	snatStaleRules, err := computeStaleIptablesRules(ipt, "nat", "nholuongut-SNAT-CHAIN", iptableRules, chains)
+	// The rules of the SNAT policies are kept in order by updateSNATPolicyIptablesRules
+	activeRules := append(append([]iptablesRule{}, iptableRules...), n.buildSNATPolicySNATRules(primaryAddr, ipt)...)
+	snatStaleRules, err := computeStaleIptablesRules(ipt, "nat", "nholuongut-SNAT-CHAIN", activeRules, chains)
 	if err != nil {
 		return []iptablesRule{}, err
 	}
@@ -588,6 +690,23 @@ func (n *linuxNetwork) buildIptablesSNATRules(vpcCIDRs []string, primaryAddr *ne
 	return iptableRules, nil
 }
 
+// snatRandomOptions returns the options of SNAT rules which randomize the port allocation, as set by
+// nholuongut_VPC_K8S_CNI_RANDOMIZESNAT
+func (n *linuxNetwork) snatRandomOptions(ipt iptableswrapper.IPTablesIface) []string {
+	switch n.typeOfSNAT {
+	case randomHashSNAT:
+		return []string{"--random"}
+	case randomPRNGSNAT:
+		if ipt.HasRandomFully() {
+			return []string{"--random-fully"}
+		}
+		log.Warn("prng (--random-fully) requested, but iptables version does not support it. " +
+			"Falling back to hashrandom (--random)")
+		return []string{"--random"}
+	}
+	return nil
+}
+
 func (n *linuxNetwork) buildIptablesConnmarkRules(vpcCIDRs []string, ipt iptableswrapper.IPTablesIface) ([]iptablesRule, error) {
 	var allCIDRs []string
 	allCIDRs = append(allCIDRs, vpcCIDRs...)
@@ -670,7 +789,8 @@ func (n *linuxNetwork) buildIptablesConnmarkRules(vpcCIDRs []string, ipt iptable
 		},
 	})
 
-	connmarkStaleRules, err := computeStaleIptablesRules(ipt, "nat", "nholuongut-CONNMARK-CHAIN", iptableRules, chains)
+	activeRules := append(append([]iptablesRule{}, iptableRules...), n.buildSNATPolicyConnmarkRules()...)
+	connmarkStaleRules, err := computeStaleIptablesRules(ipt, "nat", "nholuongut-CONNMARK-CHAIN", activeRules, chains)
 	if err != nil {
 		return []iptablesRule{}, err
 	}
@@ -691,6 +811,129 @@ func (n *linuxNetwork) buildIptablesConnmarkRules(vpcCIDRs []string, ipt iptable
 	return iptableRules, nil
 }
 
+// buildSNATPolicySNATRules returns the rules of the SNAT policies in the SNAT chain, in order
+func (n *linuxNetwork) buildSNATPolicySNATRules(primaryAddr *net.IP, ipt iptableswrapper.IPTablesIface) []iptablesRule {
+	var iptableRules []iptablesRule
+	for _, policyRule := range n.snatPolicyRules {
+		var target []string
+		switch policyRule.Action {
+		case SNATPolicyNoSNAT:
+			target = []string{"-j", "RETURN"}
+		case SNATPolicySNAT:
+			target = append([]string{"-m", "addrtype", "!", "--dst-type", "LOCAL", "-j", "SNAT", "--to-source",
+				primaryAddr.String()}, n.snatRandomOptions(ipt)...)
+		case SNATPolicySNATRandomFully:
+			randomOption := "--random-fully"
+			if !ipt.HasRandomFully() {
+				log.Warnf("SNAT policy %s: --random-fully is not supported by iptables, falling back to --random",
+					policyRule.Policy)
+				randomOption = "--random"
+			}
+			target = []string{"-m", "addrtype", "!", "--dst-type", "LOCAL", "-j", "SNAT", "--to-source",
+				primaryAddr.String(), randomOption}
+		}
+		iptableRules = append(iptableRules, n.snatPolicyIptablesRules(policyRule, "nholuongut-SNAT-CHAIN-0",
+			policyRule.Action != SNATPolicyNoSNAT, target)...)
+	}
+	return iptableRules
+}
+
+// buildSNATPolicyConnmarkRules returns the rules of the SNAT policies in the connmark chain, in order. The traffic
+// which is SNATed to the primary IP is marked so that it leaves through the primary ENI.
+func (n *linuxNetwork) buildSNATPolicyConnmarkRules() []iptablesRule {
+	var iptableRules []iptablesRule
+	for _, policyRule := range n.snatPolicyRules {
+		target := []string{"-j", "RETURN"}
+		if policyRule.Action != SNATPolicyNoSNAT {
+			target = []string{"-j", "CONNMARK", "--set-xmark", fmt.Sprintf("%#x/%#x", n.mainENIMark, n.mainENIMark)}
+		}
+		iptableRules = append(iptableRules, n.snatPolicyIptablesRules(policyRule, "nholuongut-CONNMARK-CHAIN-0", false, target)...)
+	}
+	return iptableRules
+}
+
+// snatPolicyIptablesRules returns a rule of chain per source and destination of the SNAT policy rule
+func (n *linuxNetwork) snatPolicyIptablesRules(policyRule SNATPolicyRule, chain string, skipVLAN bool, target []string) []iptablesRule {
+	sources := []string{""}
+	if len(policyRule.Sources) > 0 {
+		sources = policyRule.Sources
+	}
+	var iptableRules []iptablesRule
+	for _, source := range sources {
+		for _, destination := range policyRule.Destinations {
+			var rule []string
+			if source != "" {
+				rule = append(rule, "-s", source+"/32")
+			}
+			rule = append(rule, "-d", destination)
+			// Pods using security groups for pods are not SNATed, as with the last rule of the SNAT chain
+			if skipVLAN {
+				rule = append(rule, "!", "-o", "vlan+")
+			}
+			rule = append(rule, "-m", "comment", "--comment", snatPolicyComment+policyRule.Policy)
+			iptableRules = append(iptableRules, iptablesRule{
+				name:        "SNAT policy " + policyRule.Policy,
+				shouldExist: !n.useExternalSNAT,
+				table:       "nat",
+				chain:       chain,
+				rule:        append(rule, target...),
+			})
+		}
+	}
+	return iptableRules
+}
+
+// updateSNATPolicyIptablesRules keeps the rules of the SNAT policies at the top of their chain, in order. Rules can not
+// be moved, so they are all inserted again when they are out of order. The rules of deleted policies are removed.
+func updateSNATPolicyIptablesRules(chain string, policyRules []iptablesRule, ipt iptableswrapper.IPTablesIface) error {
+	var desired [][]string
+	for _, policyRule := range policyRules {
+		if policyRule.shouldExist {
+			desired = append(desired, policyRule.rule)
+		}
+	}
+	currentRules, err := listCurrentIptablesRules(ipt, "nat", chain)
+	if err != nil {
+		return err
+	}
+	var current, stale [][]string
+	for _, currentRule := range currentRules {
+		if currentRule.chain != chain || len(currentRule.rule) == 0 {
+			continue
+		}
+		current = append(current, currentRule.rule)
+		if isSNATPolicyRule(currentRule.rule) {
+			stale = append(stale, currentRule.rule)
+		}
+	}
+	if len(current) >= len(desired) && reflect.DeepEqual(current[:len(desired)], desired) &&
+		len(stale) == len(desired) {
+		return nil
+	}
+
+	log.Infof("Updating the %d SNAT policy rules of chain %s", len(desired), chain)
+	for _, rule := range stale {
+		if err := ipt.Delete("nat", chain, rule...); err != nil {
+			return errors.Wrapf(err, "failed to delete SNAT policy rule %v", rule)
+		}
+	}
+	for i, rule := range desired {
+		if err := ipt.Insert("nat", chain, i+1, rule...); err != nil {
+			return errors.Wrapf(err, "failed to insert SNAT policy rule %v", rule)
+		}
+	}
+	return nil
+}
+
+func isSNATPolicyRule(rule []string) bool {
+	for _, arg := range rule {
+		if strings.HasPrefix(arg, snatPolicyComment) {
+			return true
+		}
+	}
+	return false
+}
+
 func (n *linuxNetwork) updateIptablesRules(iptableRules []iptablesRule, ipt iptableswrapper.IPTablesIface) error {
 	for _, rule := range iptableRules {
 		log.Debugf("execute iptable rule : %s", rule.name)
@@ -702,8 +945,10 @@ func (n *linuxNetwork) updateIptablesRules(iptableRules []iptablesRule, ipt ipta
 		}
 
 		if !exists && rule.shouldExist {
-			if rule.name == "nholuongut-CONNMARK-CHAIN-0" || rule.name == "nholuongut-SNAT-CHAIN-0" {
-				// All CIDR rules must go before the SNAT/Mark rule
+			if rule.name == "nholuongut-CONNMARK-CHAIN-0" || rule.name == "nholuongut-SNAT-CHAIN-0" ||
+				rule.name == egressEIPChain || rule.name == egressEIPSNATChain {
+				// All CIDR rules must go before the SNAT/Mark rule, and the egress Elastic IP SNAT before the SNAT to
+				// the primary ENI address
 				err = ipt.Insert(rule.table, rule.chain, 1, rule.rule...)
 				if err != nil {
 					log.Errorf("host network setup: failed to insert %v, %v", rule, err)
@@ -849,7 +1094,7 @@ func GetConfigForDebug() map[string]interface{} {
 		envExternalSNAT:         useExternalSNAT(),
 		envExternalServiceCIDRs: parseCIDRString(envExternalServiceCIDRs),
 		envMTU:                  GetEthernetMTU(),
-		envVethPrefix:           getVethPrefixName(),
+		envVethPrefix:           GetVethPrefixName(),
 		envNodePortSupport:      nodePortSupportEnabled(),
 		envRandomizeSNAT:        typeOfSNAT(),
 	}
@@ -859,7 +1104,9 @@ func GetConfigForDebug() map[string]interface{} {
 // NAT gateway rather than on node. Failure to parse the setting will result in a log and the
 // setting will be disabled.
 func (n *linuxNetwork) UseExternalSNAT() bool {
-	return useExternalSNAT()
+	n.snatLock.Lock()
+	defer n.snatLock.Unlock()
+	return n.useExternalSNAT
 }
 
 func useExternalSNAT() bool {
@@ -877,10 +1124,64 @@ func ipV6EgressEnabled() bool {
 // GetExcludeSNATCIDRs returns a list of CIDRs that should be excluded from SNAT if UseExternalSNAT is false,
 // otherwise it returns an empty list.
 func (n *linuxNetwork) GetExcludeSNATCIDRs() []string {
-	if useExternalSNAT() {
+	n.snatLock.Lock()
+	defer n.snatLock.Unlock()
+	if n.useExternalSNAT {
 		return nil
 	}
-	return parseCIDRString(envExcludeSNATCIDRs)
+	return n.excludeSNATCIDRs
+}
+
+// ReloadableEnvs are the SNAT env variables which can be changed without restarting nholuongut-node
+var ReloadableEnvs = []string{envExternalSNAT, envExcludeSNATCIDRs, envRandomizeSNAT}
+
+// SetSNATPolicyRules sets the rules of the SNAT policies of the node, the next UpdateHostIptablesRules call applies them
+func (n *linuxNetwork) SetSNATPolicyRules(rules []SNATPolicyRule) {
+	n.snatLock.Lock()
+	defer n.snatLock.Unlock()
+	n.snatPolicyRules = rules
+}
+
+// ReloadSNATConfig applies the values of the SNAT env variables in env, a missing one is unset. The process
+// environment is left as nholuongut-node was started with.
+func (n *linuxNetwork) ReloadSNATConfig(env map[string]string) {
+	n.snatLock.Lock()
+	defer n.snatLock.Unlock()
+	n.useExternalSNAT = parseBoolEnvVar(envExternalSNAT, env[envExternalSNAT], false)
+	n.excludeSNATCIDRs = parseCIDRs(envExcludeSNATCIDRs, env[envExcludeSNATCIDRs])
+	n.typeOfSNAT = parseSNATType(env[envRandomizeSNAT])
+	log.Infof("Reloaded SNAT settings - external SNAT: %v, excluded CIDRs: %v, SNAT type: %v", n.useExternalSNAT,
+		n.excludeSNATCIDRs, n.typeOfSNAT)
+}
+
+// ValidateReloadableEnv returns an error if value is not valid for the SNAT env variable, which the helpers reading
+// it would otherwise replace with the default
+func ValidateReloadableEnv(envVar, value string) error {
+	switch envVar {
+	case envExternalSNAT:
+		if _, err := strconv.ParseBool(value); value != "" && err != nil {
+			return errors.Errorf("%s must be true or false, got %q", envVar, value)
+		}
+	case envExcludeSNATCIDRs:
+		for _, cidr := range strings.Split(value, ",") {
+			if strings.TrimSpace(cidr) == "" {
+				continue
+			}
+			ip, _, err := net.ParseCIDR(strings.TrimSpace(cidr))
+			if err != nil || ip.To4() == nil {
+				return errors.Errorf("%s must be a list of IPv4 CIDRs, %q is not one", envVar, cidr)
+			}
+		}
+	case envRandomizeSNAT:
+		switch value {
+		case "", "prng", "none", "hashrandom":
+		default:
+			return errors.Errorf("%s must be one of prng, none or hashrandom, got %q", envVar, value)
+		}
+	default:
+		return errors.Errorf("%s is not a SNAT setting", envVar)
+	}
+	return nil
 }
 
 // GetExternalServiceCIDRs return a list of CIDRs that should always be routed to via main routing table.
@@ -889,7 +1190,11 @@ func (n *linuxNetwork) GetExternalServiceCIDRs() []string {
 }
 
 func parseCIDRString(envVar string) []string {
-	cidrString := os.Getenv(envVar)
+	return parseCIDRs(envVar, os.Getenv(envVar))
+}
+
+// parseCIDRs returns the IPv4 CIDRs of the comma separated cidrString, the value of envVar
+func parseCIDRs(envVar, cidrString string) []string {
 	if cidrString == "" {
 		return nil
 	}
@@ -909,8 +1214,12 @@ func parseCIDRString(envVar string) []string {
 }
 
 func typeOfSNAT() snatType {
+	return parseSNATType(os.Getenv(envRandomizeSNAT))
+}
+
+// parseSNATType returns the SNAT type of strValue, the value of nholuongut_VPC_K8S_CNI_RANDOMIZESNAT
+func parseSNATType(strValue string) snatType {
 	defaultValue := randomPRNGSNAT
-	strValue := os.Getenv(envRandomizeSNAT)
 	switch strValue {
 	case "":
 		// empty means default, which is --random-fully
@@ -937,7 +1246,13 @@ func nodePortSupportEnabled() bool {
 }
 
 func getBoolEnvVar(name string, defaultValue bool) bool {
-	if strValue := os.Getenv(name); strValue != "" {
+	return parseBoolEnvVar(name, os.Getenv(name), defaultValue)
+}
+
+// parseBoolEnvVar returns the boolean of strValue, the value of env variable name, defaultValue if it is empty or
+// invalid
+func parseBoolEnvVar(name, strValue string, defaultValue bool) bool {
+	if strValue != "" {
 		parsedValue, err := strconv.ParseBool(strValue)
 		if err != nil {
 			log.Errorf("Failed to parse "+name+"; using default: "+fmt.Sprint(defaultValue), err.Error())
@@ -1016,11 +1331,12 @@ func GetIPv4Gateway(eniSubnetCIDR *net.IPNet) net.IP {
 
 // SetupENINetwork adds default route to route table (eni-<eni_table>), so it does not need to be called on the primary ENI
 func (n *linuxNetwork) SetupENINetwork(eniIP string, eniMAC string, deviceNumber int, eniSubnetCIDR string) error {
-	return setupENINetwork(eniIP, eniMAC, deviceNumber, eniSubnetCIDR, n.netLink, retryLinkByMacInterval, retryRouteAddInterval, n.mtu)
+	return setupENINetwork(eniIP, eniMAC, deviceNumber, eniSubnetCIDR, n.netLink, retryLinkByMacInterval, retryRouteAddInterval, n.mtu,
+		n.podDatapath)
 }
 
 func setupENINetwork(eniIP string, eniMAC string, deviceNumber int, eniSubnetCIDR string, netLink netlinkwrapper.NetLink,
-	retryLinkByMacInterval time.Duration, retryRouteAddInterval time.Duration, mtu int) error {
+	retryLinkByMacInterval time.Duration, retryRouteAddInterval time.Duration, mtu int, podDatapath datapath.Mode) error {
 	if deviceNumber == 0 {
 		return errors.New("setupENINetwork should never be called on the primary ENI")
 	}
@@ -1089,30 +1405,7 @@ func setupENINetwork(eniIP string, eniMAC string, deviceNumber int, eniSubnetCID
 
 	linkIndex := link.Attrs().Index
 	log.Debugf("Setting up ENI's default gateway %v, table %d, linkIndex %d", gw, tableNumber, linkIndex)
-	mask := 32
-	zeroAddr := net.IPv4zero
-	if isV6 {
-		mask = 128
-		zeroAddr = net.IPv6zero
-	}
-	routes := []netlink.Route{
-		// Add a direct link route for the host's ENI IP only
-		{
-			LinkIndex: linkIndex,
-			Dst:       &net.IPNet{IP: gw, Mask: net.CIDRMask(mask, mask)},
-			Scope:     netlink.SCOPE_LINK,
-			Table:     tableNumber,
-		},
-		// Route all other traffic via the host's ENI IP
-		{
-			LinkIndex: linkIndex,
-			Dst:       &net.IPNet{IP: zeroAddr, Mask: net.CIDRMask(0, mask)},
-			Scope:     netlink.SCOPE_UNIVERSE,
-			Gw:        gw,
-			Table:     tableNumber,
-		},
-	}
-	for _, r := range routes {
+	for _, r := range eniRoutes(linkIndex, gw, tableNumber, isV6) {
 		err := netLink.RouteDel(&r)
 		if err != nil && !netlinkwrapper.IsNotExistsError(err) {
 			return errors.Wrap(err, "setupENINetwork: failed to clean up old routes")
@@ -1157,9 +1450,50 @@ func setupENINetwork(eniIP string, eniMAC string, deviceNumber int, eniSubnetCID
 			return errors.Wrapf(err, "setupENINetwork: unable to delete default route %s for source IP %s", eniSubnetIPNet.String(), eniIP)
 		}
 	}
+
+	// In the eBPF datapath, the traffic from the pods behind the ENI is marked with its route table, and a single
+	// rule replaces the fromContainer rule of each pod. IPv6 pods are always behind the primary ENI.
+	if podDatapath == datapath.ModeEBPF && !isV6 {
+		podRouteMarkRule := netLink.NewRule()
+		podRouteMarkRule.Mark = PodRouteMark(tableNumber)
+		podRouteMarkMask := uint32(PodRouteMarkMask)
+		podRouteMarkRule.Mask = &podRouteMarkMask
+		podRouteMarkRule.Table = tableNumber
+		podRouteMarkRule.Priority = FromPodRulePriority
+		if err := netLink.RuleAdd(podRouteMarkRule); err != nil && !isRuleExistsError(err) {
+			return errors.Wrapf(err, "setupENINetwork: unable to add pod route mark rule for route table %d", tableNumber)
+		}
+	}
 	return nil
 }
 
+// eniRoutes returns the routes of the route table of an ENI
+func eniRoutes(linkIndex int, gw net.IP, tableNumber int, isV6 bool) []netlink.Route {
+	mask := 32
+	zeroAddr := net.IPv4zero
+	if isV6 {
+		mask = 128
+		zeroAddr = net.IPv6zero
+	}
+	return []netlink.Route{
+		// Add a direct link route for the host's ENI IP only
+		{
+			LinkIndex: linkIndex,
+			Dst:       &net.IPNet{IP: gw, Mask: net.CIDRMask(mask, mask)},
+			Scope:     netlink.SCOPE_LINK,
+			Table:     tableNumber,
+		},
+		// Route all other traffic via the host's ENI IP
+		{
+			LinkIndex: linkIndex,
+			Dst:       &net.IPNet{IP: zeroAddr, Mask: net.CIDRMask(0, mask)},
+			Scope:     netlink.SCOPE_UNIVERSE,
+			Gw:        gw,
+			Table:     tableNumber,
+		},
+	}
+}
+
 // For IPv6 strict mode, ICMPv6 packets from the gateway must lookup in the local routing table so that branch interfaces can resolve their gateway.
 func (n *linuxNetwork) createIPv6GatewayRule() error {
 	gatewayRule := n.netLink.NewRule()
@@ -1292,6 +1626,180 @@ func (n *linuxNetwork) UpdateExternalServiceIpRules(ruleList []netlink.Rule, ext
 	return nil
 }
 
+// UpdateEgressEIPRules programs the routing and the SNAT of the pods which egress through the Elastic IPs of the egress
+// ENI. Their connections to outside of the VPC are marked, routed through the route table of the ENI and SNATed to
+// the private IP which their Elastic IP is associated with. podSNATIPs maps the IP of each pod to that private IP.
+// An empty eniMAC removes all the rules.
+func (n *linuxNetwork) UpdateEgressEIPRules(vpcCIDRs []string, eniMAC string, deviceNumber int, podSNATIPs map[string]string) error {
+	n.snatLock.Lock()
+	defer n.snatLock.Unlock()
+
+	var eniName string
+	var tableNumber int
+	if eniMAC != "" {
+		link, err := linkByMac(eniMAC, n.netLink, retryLinkByMacInterval)
+		if err != nil {
+			return errors.Wrapf(err, "UpdateEgressEIPRules: failed to find the link of the egress ENI with MAC address %s", eniMAC)
+		}
+		eniName = link.Attrs().Name
+		tableNumber = deviceNumber + 1
+	}
+	if err := n.updateEgressEIPRule(tableNumber); err != nil {
+		return err
+	}
+
+	ipt, err := n.newIptables(iptables.ProtocolIPv4)
+	if err != nil {
+		return errors.Wrap(err, "UpdateEgressEIPRules: failed to create iptables")
+	}
+	iptableRules, err := n.buildEgressEIPIptablesRules(vpcCIDRs, eniName, podSNATIPs, ipt)
+	if err != nil {
+		return err
+	}
+	return n.updateIptablesRules(iptableRules, ipt)
+}
+
+// updateEgressEIPRule routes the marked traffic through route table tableNumber, or removes the rule when it is 0
+func (n *linuxNetwork) updateEgressEIPRule(tableNumber int) error {
+	rules, err := n.netLink.RuleList(unix.AF_INET)
+	if err != nil {
+		return errors.Wrap(err, "UpdateEgressEIPRules: failed to list IP rules")
+	}
+	exists := false
+	for _, rule := range rules {
+		if rule.Priority != egressEIPRulePriority {
+			continue
+		}
+		if tableNumber != 0 && rule.Table == tableNumber && rule.Mark == egressEIPMark {
+			exists = true
+			continue
+		}
+		if err := n.netLink.RuleDel(&rule); err != nil && !containsNoSuchRule(err) {
+			return errors.Wrap(err, "UpdateEgressEIPRules: failed to delete old egress Elastic IP rule")
+		}
+	}
+	if tableNumber == 0 || exists {
+		return nil
+	}
+
+	egressRule := n.netLink.NewRule()
+	egressRule.Mark = egressEIPMark
+	egressMask := uint32(egressEIPMark)
+	egressRule.Mask = &egressMask
+	egressRule.Table = tableNumber
+	egressRule.Priority = egressEIPRulePriority
+	egressRule.Family = unix.AF_INET
+	if err := n.netLink.RuleAdd(egressRule); err != nil && !isRuleExistsError(err) {
+		return errors.Wrap(err, "UpdateEgressEIPRules: failed to add egress Elastic IP rule")
+	}
+	return nil
+}
+
+func (n *linuxNetwork) buildEgressEIPIptablesRules(vpcCIDRs []string, eniName string, podSNATIPs map[string]string,
+	ipt iptableswrapper.IPTablesIface) ([]iptablesRule, error) {
+	shouldExist := eniName != ""
+	chains := []string{egressEIPChain, egressEIPSNATChain}
+	for _, chain := range chains {
+		if err := ipt.NewChain("nat", chain); err != nil && !containChainExistErr(err) {
+			return nil, errors.Wrapf(err, "UpdateEgressEIPRules: failed to add chain %s", chain)
+		}
+	}
+
+	restoreMarkRule := []string{
+		"-i", n.vethPrefix + "+", "-m", "comment", "--comment", "nholuongut, egress Elastic IPs",
+		"-j", "CONNMARK", "--restore-mark", "--mask", fmt.Sprintf("%#x", egressEIPMark),
+	}
+	iptableRules := []iptablesRule{
+		{
+			name:        "egress Elastic IP rule for non-VPC outbound traffic",
+			shouldExist: shouldExist,
+			table:       "nat",
+			chain:       "PREROUTING",
+			rule: []string{
+				"-i", n.vethPrefix + "+", "-m", "comment", "--comment", "nholuongut, egress Elastic IPs", "-j", egressEIPChain,
+			},
+		},
+		// The mark of the first packet of the connection is restored in the nat table, after the mark chain
+		{
+			name:        "egress Elastic IP connmark to fwmark copy",
+			shouldExist: shouldExist,
+			table:       "nat",
+			chain:       "PREROUTING",
+			rule:        restoreMarkRule,
+		},
+		{
+			name:        "egress Elastic IP connmark to fwmark copy",
+			shouldExist: shouldExist,
+			table:       "mangle",
+			chain:       "PREROUTING",
+			rule:        restoreMarkRule,
+		},
+		{
+			name:        egressEIPSNATChain,
+			shouldExist: shouldExist,
+			table:       "nat",
+			chain:       "POSTROUTING",
+			rule: []string{
+				"-m", "comment", "--comment", "nholuongut, egress Elastic IPs", "-j", egressEIPSNATChain,
+			},
+		},
+	}
+
+	var allCIDRs []string
+	allCIDRs = append(allCIDRs, vpcCIDRs...)
+	allCIDRs = append(allCIDRs, n.excludeSNATCIDRs...)
+	for _, cidr := range allCIDRs {
+		iptableRules = append(iptableRules, iptablesRule{
+			name:        egressEIPChain,
+			shouldExist: shouldExist,
+			table:       "nat",
+			chain:       egressEIPChain,
+			rule: []string{
+				"-d", cidr, "-m", "comment", "--comment", "nholuongut EGRESS CHAIN", "-j", "RETURN",
+			}})
+	}
+
+	if shouldExist {
+		podIPs := make([]string, 0, len(podSNATIPs))
+		for podIP := range podSNATIPs {
+			podIPs = append(podIPs, podIP)
+		}
+		sort.Strings(podIPs)
+		randomOptions := n.snatRandomOptions(ipt)
+		for _, podIP := range podIPs {
+			log.Debugf("Setup egress Elastic IP: SNAT %s to %s through %s", podIP, podSNATIPs[podIP], eniName)
+			iptableRules = append(iptableRules, iptablesRule{
+				name:        "egress Elastic IP mark for pod",
+				shouldExist: true,
+				table:       "nat",
+				chain:       egressEIPChain,
+				rule: []string{
+					"-s", podIP + "/32", "-m", "comment", "--comment", "nholuongut, egress Elastic IP",
+					"-j", "CONNMARK", "--set-xmark", fmt.Sprintf("%#x/%#x", egressEIPMark, egressEIPMark),
+				}})
+			iptableRules = append(iptableRules, iptablesRule{
+				name:        "egress Elastic IP SNAT for pod",
+				shouldExist: true,
+				table:       "nat",
+				chain:       egressEIPSNATChain,
+				rule: append([]string{
+					"-s", podIP + "/32", "-o", eniName, "-m", "comment", "--comment", "nholuongut, egress Elastic IP",
+					"-j", "SNAT", "--to-source", podSNATIPs[podIP],
+				}, randomOptions...)})
+		}
+	}
+
+	for _, chainPrefix := range []string{"nholuongut-EGRESS-CHAIN", "nholuongut-EGRESS-SNAT-CHAIN"} {
+		staleRules, err := computeStaleIptablesRules(ipt, "nat", chainPrefix, iptableRules, chains)
+		if err != nil {
+			return nil, err
+		}
+		iptableRules = append(iptableRules, staleRules...)
+	}
+	log.Debugf("iptableRules: %v", iptableRules)
+	return iptableRules, nil
+}
+
 // GetEthernetMTU returns the MTU value to program for ENIs. Note that the value was already validated during container initialization.
 func GetEthernetMTU() int {
 	mtu, _, _ := utils.GetIntFromStringEnvVar(envMTU, defaultMTU)
@@ -1313,8 +1821,8 @@ func GetPodMTU(podMTU string) int {
 	return mtu
 }
 
-// getVethPrefixName gets the name prefix of the veth devices based on the nholuongut_VPC_K8S_CNI_VETHPREFIX environment variable
-func getVethPrefixName() string {
+// GetVethPrefixName gets the name prefix of the veth devices based on the nholuongut_VPC_K8S_CNI_VETHPREFIX environment variable
+func GetVethPrefixName() string {
 	if envVal, found := os.LookupEnv(envVethPrefix); found {
 		return envVal
 	}
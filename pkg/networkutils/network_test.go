@@ -21,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/datapath"
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/sgpp"
 	"github.com/pkg/errors"
 
@@ -122,10 +123,39 @@ func TestSetupENINetwork(t *testing.T) {
 
 	mockNetLink.EXPECT().RouteDel(gomock.Any()).Return(nil)
 
-	err = setupENINetwork(testEniIP, testMAC2, testTable, testEniSubnet, mockNetLink, 0*time.Second, 0*time.Second, testMTU)
+	err = setupENINetwork(testEniIP, testMAC2, testTable, testEniSubnet, mockNetLink, 0*time.Second, 0*time.Second, testMTU, datapath.ModeIPRule)
 	assert.NoError(t, err)
 }
 
+func TestSetupENINetworkWithEBPFDatapath(t *testing.T) {
+	ctrl, mockNetLink, _, _, _ := setup(t)
+	defer ctrl.Finish()
+
+	hwAddr, err := net.ParseMAC(testMAC2)
+	assert.NoError(t, err)
+	eth1 := mock_netlink.NewMockLink(ctrl)
+	eth1.EXPECT().Attrs().Return(&netlink.LinkAttrs{HardwareAddr: hwAddr}).AnyTimes()
+	mockNetLink.EXPECT().LinkList().Return([]netlink.Link{eth1}, nil)
+	mockNetLink.EXPECT().LinkSetMTU(eth1, testMTU).Return(nil)
+	mockNetLink.EXPECT().LinkSetUp(eth1).Return(nil)
+	mockNetLink.EXPECT().AddrList(eth1, unix.AF_INET).Return([]netlink.Addr{}, nil)
+	mockNetLink.EXPECT().AddrAdd(eth1, gomock.Any()).Return(nil)
+	mockNetLink.EXPECT().RouteDel(gomock.Any()).Return(nil).Times(3)
+	mockNetLink.EXPECT().RouteReplace(gomock.Any()).Return(nil).Times(2)
+
+	// The traffic of the pods behind the ENI is marked with its route table
+	var podRouteMarkRule netlink.Rule
+	mockNetLink.EXPECT().NewRule().Return(&podRouteMarkRule)
+	mockNetLink.EXPECT().RuleAdd(&podRouteMarkRule).Return(syscall.EEXIST)
+
+	err = setupENINetwork(testEniIP, testMAC2, testTable, testEniSubnet, mockNetLink, 0*time.Second, 0*time.Second, testMTU, datapath.ModeEBPF)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(testTable+1)<<16, podRouteMarkRule.Mark)
+	assert.Equal(t, uint32(PodRouteMarkMask), *podRouteMarkRule.Mask)
+	assert.Equal(t, testTable+1, podRouteMarkRule.Table)
+	assert.Equal(t, FromPodRulePriority, podRouteMarkRule.Priority)
+}
+
 func TestSetupENIV6Network(t *testing.T) {
 	ctrl, mockNetLink, _, _, _ := setup(t)
 	defer ctrl.Finish()
@@ -171,7 +201,7 @@ func TestSetupENIV6Network(t *testing.T) {
 	mockNetLink.EXPECT().RouteReplace(gomock.Any()).Return(nil)
 	mockNetLink.EXPECT().RouteDel(gomock.Any()).Return(nil)
 
-	err = setupENINetwork(testEniIP6, testMAC2, testTable, testEniV6Subnet, mockNetLink, 0*time.Second, 0*time.Second, testMTU)
+	err = setupENINetwork(testEniIP6, testMAC2, testTable, testEniV6Subnet, mockNetLink, 0*time.Second, 0*time.Second, testMTU, datapath.ModeIPRule)
 	assert.NoError(t, err)
 }
 
@@ -185,7 +215,7 @@ func TestSetupENINetworkMACFail(t *testing.T) {
 		mockNetLink.EXPECT().LinkList().Return(nil, fmt.Errorf("simulated failure"))
 	}
 
-	err := setupENINetwork(testEniIP, testMAC2, testTable, testEniSubnet, mockNetLink, 0*time.Second, 0*time.Second, testMTU)
+	err := setupENINetwork(testEniIP, testMAC2, testTable, testEniSubnet, mockNetLink, 0*time.Second, 0*time.Second, testMTU, datapath.ModeIPRule)
 	assert.Errorf(t, err, "simulated failure")
 }
 
@@ -193,7 +223,7 @@ func TestSetupENINetworkErrorOnPrimaryENI(t *testing.T) {
 	ctrl, mockNetLink, _, _, _ := setup(t)
 	defer ctrl.Finish()
 	deviceNumber := 0
-	err := setupENINetwork(testEniIP, testMAC2, deviceNumber, testEniSubnet, mockNetLink, 0*time.Second, 0*time.Second, testMTU)
+	err := setupENINetwork(testEniIP, testMAC2, deviceNumber, testEniSubnet, mockNetLink, 0*time.Second, 0*time.Second, testMTU, datapath.ModeIPRule)
 	assert.Error(t, err)
 }
 
@@ -444,6 +474,38 @@ func TestLoadExcludeSNATCIDRsFromEnv(t *testing.T) {
 	assert.Equal(t, parseCIDRString(envExcludeSNATCIDRs), expected)
 }
 
+func TestReloadSNATConfig(t *testing.T) {
+	ln := &linuxNetwork{typeOfSNAT: randomPRNGSNAT}
+	ln.ReloadSNATConfig(map[string]string{
+		envExternalSNAT:     "true",
+		envExcludeSNATCIDRs: "10.12.0.0/16",
+		envRandomizeSNAT:    "none",
+	})
+	assert.True(t, ln.UseExternalSNAT())
+	assert.Equal(t, []string{"10.12.0.0/16"}, ln.excludeSNATCIDRs)
+	assert.Equal(t, sequentialSNAT, ln.typeOfSNAT)
+	// The CIDRs are not excluded from SNAT done outside of the node
+	assert.Empty(t, ln.GetExcludeSNATCIDRs())
+
+	// The SNAT env variables missing from env are unset
+	ln.ReloadSNATConfig(map[string]string{envExcludeSNATCIDRs: "10.12.0.0/16"})
+	assert.False(t, ln.UseExternalSNAT())
+	assert.Equal(t, []string{"10.12.0.0/16"}, ln.GetExcludeSNATCIDRs())
+	assert.Equal(t, randomPRNGSNAT, ln.typeOfSNAT)
+	_, found := os.LookupEnv(envExternalSNAT)
+	assert.False(t, found)
+}
+
+func TestValidateReloadableEnv(t *testing.T) {
+	assert.NoError(t, ValidateReloadableEnv(envExternalSNAT, ""))
+	assert.NoError(t, ValidateReloadableEnv(envExcludeSNATCIDRs, "10.12.0.0/16, 10.13.0.0/16"))
+	assert.NoError(t, ValidateReloadableEnv(envRandomizeSNAT, "prng"))
+	assert.Error(t, ValidateReloadableEnv(envExternalSNAT, "yes please"))
+	assert.Error(t, ValidateReloadableEnv(envExcludeSNATCIDRs, "10.12.0.0"))
+	assert.Error(t, ValidateReloadableEnv(envRandomizeSNAT, "random"))
+	assert.Error(t, ValidateReloadableEnv(envConnmark, "0x80"))
+}
+
 func TestSetupHostNetworkWithExcludeSNATCIDRs(t *testing.T) {
 	ctrl, mockNetLink, _, mockNS, mockIptables := setup(t)
 	defer ctrl.Finish()
@@ -871,6 +933,64 @@ func TestUpdateHostIptablesRules(t *testing.T) {
 		}, mockIptables.(*mock_iptables.MockIptables).DataplaneState)
 }
 
+func TestUpdateHostIptablesRulesWithSNATPolicies(t *testing.T) {
+	ctrl, mockNetLink, _, mockNS, mockIptables := setup(t)
+	defer ctrl.Finish()
+
+	ln := &linuxNetwork{
+		useExternalSNAT:        false,
+		nodePortSupportEnabled: true,
+		mainENIMark:            defaultConnmark,
+		mtu:                    testMTU,
+		vethPrefix:             eniPrefix,
+
+		netLink: mockNetLink,
+		ns:      mockNS,
+		newIptables: func(iptables.Protocol) (iptableswrapper.IPTablesIface, error) {
+			return mockIptables, nil
+		},
+	}
+	setupNetLinkMocks(ctrl, mockNetLink)
+
+	vpcCIDRs := []string{"10.10.0.0/16"}
+	err := ln.SetupHostNetwork(vpcCIDRs, loopback, &testEniIPNet, false, true, false)
+	assert.NoError(t, err)
+
+	ln.SetSNATPolicyRules([]SNATPolicyRule{
+		{Policy: "billing", Destinations: []string{"10.100.0.0/16"}, Sources: []string{"10.10.1.5"}, Action: SNATPolicyNoSNAT},
+		{Policy: "partner", Destinations: []string{"172.16.0.0/12"}, Action: SNATPolicySNATRandomFully},
+	})
+	err = ln.UpdateHostIptablesRules(vpcCIDRs, loopback, &testEniIPNet, true, false)
+	assert.NoError(t, err)
+
+	noSNAT := []string{"-s", "10.10.1.5/32", "-d", "10.100.0.0/16", "-m", "comment", "--comment", "nholuongut, SNAT policy billing", "-j", "RETURN"}
+	snat := []string{"-d", "172.16.0.0/12", "!", "-o", "vlan+", "-m", "comment", "--comment", "nholuongut, SNAT policy partner", "-m", "addrtype", "!", "--dst-type", "LOCAL", "-j", "SNAT", "--to-source", "10.10.10.20", "--random-fully"}
+	connmark := []string{"-d", "172.16.0.0/12", "-m", "comment", "--comment", "nholuongut, SNAT policy partner", "-j", "CONNMARK", "--set-xmark", "0x80/0x80"}
+	vpcSNAT := []string{"-d", "10.10.0.0/16", "-m", "comment", "--comment", "nholuongut SNAT CHAIN", "-j", "RETURN"}
+	lastSNAT := []string{"!", "-o", "vlan+", "-m", "comment", "--comment", "nholuongut, SNAT", "-m", "addrtype", "!", "--dst-type", "LOCAL", "-j", "SNAT", "--to-source", "10.10.10.20"}
+	vpcConnmark := []string{"-d", "10.10.0.0/16", "-m", "comment", "--comment", "nholuongut CONNMARK CHAIN, VPC CIDR", "-j", "RETURN"}
+	lastConnmark := []string{"-m", "comment", "--comment", "nholuongut, CONNMARK", "-j", "CONNMARK", "--set-xmark", "0x80/0x80"}
+	state := mockIptables.(*mock_iptables.MockIptables).DataplaneState
+	assert.Equal(t, [][]string{{"-N", "nholuongut-SNAT-CHAIN-0"}, noSNAT, snat, vpcSNAT, lastSNAT}, state["nat"]["nholuongut-SNAT-CHAIN-0"])
+	assert.Equal(t, [][]string{{"-N", "nholuongut-CONNMARK-CHAIN-0"}, noSNAT, connmark, vpcConnmark, lastConnmark}, state["nat"]["nholuongut-CONNMARK-CHAIN-0"])
+
+	// A change of priority moves the rules
+	ln.SetSNATPolicyRules([]SNATPolicyRule{
+		{Policy: "partner", Destinations: []string{"172.16.0.0/12"}, Action: SNATPolicySNATRandomFully},
+		{Policy: "billing", Destinations: []string{"10.100.0.0/16"}, Sources: []string{"10.10.1.5"}, Action: SNATPolicyNoSNAT},
+	})
+	err = ln.UpdateHostIptablesRules(vpcCIDRs, loopback, &testEniIPNet, true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"-N", "nholuongut-SNAT-CHAIN-0"}, snat, noSNAT, vpcSNAT, lastSNAT}, state["nat"]["nholuongut-SNAT-CHAIN-0"])
+
+	// The rules of deleted policies are removed
+	ln.SetSNATPolicyRules(nil)
+	err = ln.UpdateHostIptablesRules(vpcCIDRs, loopback, &testEniIPNet, true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"-N", "nholuongut-SNAT-CHAIN-0"}, vpcSNAT, lastSNAT}, state["nat"]["nholuongut-SNAT-CHAIN-0"])
+	assert.Equal(t, [][]string{{"-N", "nholuongut-CONNMARK-CHAIN-0"}, vpcConnmark, lastConnmark}, state["nat"]["nholuongut-CONNMARK-CHAIN-0"])
+}
+
 func TestCleanUpStalenholuongutChains(t *testing.T) {
 	ctrl, mockNetLink, _, mockNS, mockIptables := setup(t)
 	defer ctrl.Finish()
@@ -1082,6 +1202,38 @@ func TestSetupHostNetworkUpdateLocalRule(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestSetupHostNetworkWithEBPFDatapath(t *testing.T) {
+	ctrl, mockNetLink, _, mockNS, mockIptables := setup(t)
+	defer ctrl.Finish()
+
+	ln := &linuxNetwork{
+		useExternalSNAT:        true,
+		nodePortSupportEnabled: true,
+		mainENIMark:            defaultConnmark,
+		mtu:                    testMTU,
+		vethPrefix:             eniPrefix,
+		podDatapath:            datapath.ModeEBPF,
+
+		netLink: mockNetLink,
+		ns:      mockNS,
+		newIptables: func(iptables.Protocol) (iptableswrapper.IPTablesIface, error) {
+			return mockIptables, nil
+		},
+	}
+	setupNetLinkMocks(ctrl, mockNetLink)
+
+	var podRouteRule netlink.Rule
+	mockNetLink.EXPECT().NewRule().Return(&podRouteRule)
+	mockNetLink.EXPECT().RuleAdd(&podRouteRule).Return(nil)
+
+	var vpcCIDRs []string
+	err := ln.SetupHostNetwork(vpcCIDRs, loopback, &testEniIPNet, false, true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, PodRouteTable, podRouteRule.Table)
+	assert.Equal(t, ToContainerRulePriority, podRouteRule.Priority)
+	assert.Equal(t, unix.AF_INET, podRouteRule.Family)
+}
+
 func TestSetupHostNetworkDeleteOldConnmarkRuleForNonVpcOutboundTraffic(t *testing.T) {
 	ctrl, mockNetLink, _, mockNS, mockIptables := setup(t)
 	defer ctrl.Finish()
@@ -1253,6 +1405,88 @@ func TestUpdateExternalIpRules(t *testing.T) {
 	}
 }
 
+func TestUpdateEgressEIPRules(t *testing.T) {
+	ctrl, mockNetLink, _, _, mockIptables := setup(t)
+	defer ctrl.Finish()
+
+	ln := &linuxNetwork{
+		vethPrefix: "eni",
+		typeOfSNAT: randomHashSNAT,
+
+		netLink: mockNetLink,
+		newIptables: func(iptables.Protocol) (iptableswrapper.IPTablesIface, error) {
+			return mockIptables, nil
+		},
+	}
+
+	hwAddr, err := net.ParseMAC(testMAC2)
+	assert.NoError(t, err)
+	eth1 := mock_netlink.NewMockLink(ctrl)
+	eth1.EXPECT().Attrs().Return(&netlink.LinkAttrs{Name: "eth1", HardwareAddr: hwAddr}).AnyTimes()
+	mockNetLink.EXPECT().LinkList().Return([]netlink.Link{eth1}, nil)
+
+	// A leftover rule to another route table is replaced
+	staleRule := netlink.Rule{Priority: egressEIPRulePriority, Mark: egressEIPMark, Table: 3}
+	mockNetLink.EXPECT().RuleList(unix.AF_INET).Return([]netlink.Rule{staleRule}, nil)
+	mockNetLink.EXPECT().RuleDel(gomock.Any()).Return(nil)
+	var egressRule netlink.Rule
+	mockNetLink.EXPECT().NewRule().Return(&egressRule)
+	mockNetLink.EXPECT().RuleAdd(&egressRule).Return(nil)
+
+	podSNATIPs := map[string]string{"10.10.1.6": "10.10.2.21", "10.10.1.5": "10.10.2.20"}
+	err = ln.UpdateEgressEIPRules([]string{"10.10.0.0/16"}, testMAC2, 1, podSNATIPs)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, egressRule.Table)
+	assert.Equal(t, uint32(egressEIPMark), egressRule.Mark)
+	assert.Equal(t, egressEIPRulePriority, egressRule.Priority)
+	assert.Equal(t,
+		map[string]map[string][][]string{
+			"nat": {
+				"nholuongut-EGRESS-CHAIN-0": [][]string{
+					{"-N", "nholuongut-EGRESS-CHAIN-0"},
+					{"-d", "10.10.0.0/16", "-m", "comment", "--comment", "nholuongut EGRESS CHAIN", "-j", "RETURN"},
+					{"-s", "10.10.1.5/32", "-m", "comment", "--comment", "nholuongut, egress Elastic IP", "-j", "CONNMARK", "--set-xmark", "0x40/0x40"},
+					{"-s", "10.10.1.6/32", "-m", "comment", "--comment", "nholuongut, egress Elastic IP", "-j", "CONNMARK", "--set-xmark", "0x40/0x40"},
+				},
+				"nholuongut-EGRESS-SNAT-CHAIN-0": [][]string{
+					{"-N", "nholuongut-EGRESS-SNAT-CHAIN-0"},
+					{"-s", "10.10.1.5/32", "-o", "eth1", "-m", "comment", "--comment", "nholuongut, egress Elastic IP", "-j", "SNAT", "--to-source", "10.10.2.20", "--random"},
+					{"-s", "10.10.1.6/32", "-o", "eth1", "-m", "comment", "--comment", "nholuongut, egress Elastic IP", "-j", "SNAT", "--to-source", "10.10.2.21", "--random"},
+				},
+				"PREROUTING": [][]string{
+					{"-i", "eni+", "-m", "comment", "--comment", "nholuongut, egress Elastic IPs", "-j", "nholuongut-EGRESS-CHAIN-0"},
+					{"-i", "eni+", "-m", "comment", "--comment", "nholuongut, egress Elastic IPs", "-j", "CONNMARK", "--restore-mark", "--mask", "0x40"},
+				},
+				"POSTROUTING": [][]string{
+					{"-m", "comment", "--comment", "nholuongut, egress Elastic IPs", "-j", "nholuongut-EGRESS-SNAT-CHAIN-0"},
+				},
+			},
+			"mangle": {
+				"PREROUTING": [][]string{
+					{"-i", "eni+", "-m", "comment", "--comment", "nholuongut, egress Elastic IPs", "-j", "CONNMARK", "--restore-mark", "--mask", "0x40"},
+				},
+			},
+		}, mockIptables.(*mock_iptables.MockIptables).DataplaneState)
+
+	// Without an egress ENI, the rule and the jumps to the egress chains are removed, and the chains are emptied
+	mockNetLink.EXPECT().RuleList(unix.AF_INET).Return([]netlink.Rule{egressRule}, nil)
+	mockNetLink.EXPECT().RuleDel(gomock.Any()).Return(nil)
+	err = ln.UpdateEgressEIPRules(nil, "", 0, nil)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		map[string]map[string][][]string{
+			"nat": {
+				"nholuongut-EGRESS-CHAIN-0":      [][]string{{"-N", "nholuongut-EGRESS-CHAIN-0"}},
+				"nholuongut-EGRESS-SNAT-CHAIN-0": [][]string{{"-N", "nholuongut-EGRESS-SNAT-CHAIN-0"}},
+				"PREROUTING":              [][]string{},
+				"POSTROUTING":             [][]string{},
+			},
+			"mangle": {
+				"PREROUTING": [][]string{},
+			},
+		}, mockIptables.(*mock_iptables.MockIptables).DataplaneState)
+}
+
 func setupNetLinkMocks(ctrl *gomock.Controller, mockNetLink *mock_netlinkwrapper.MockNetLink) {
 	mockPrimaryInterfaceLookup(ctrl, mockNetLink)
 	mockNetLink.EXPECT().LinkSetMTU(gomock.Any(), testMTU).Return(nil)
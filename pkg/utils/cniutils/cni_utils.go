@@ -7,7 +7,6 @@ import (
 	"time"
 
 	current "github.com/containernetworking/cni/pkg/types/100"
-	"github.com/coreos/go-iptables/iptables"
 	"github.com/vishvananda/netlink"
 
 	"github.com/nholuongut/amazon-vpc-cni-k8s/pkg/netlinkwrapper"
@@ -137,10 +136,10 @@ func IsLinkNotFoundError(err error) bool {
 	return strings.Contains(err.Error(), "Link not found")
 }
 
-// IsIptableTargetNotExist returns true if the error is from iptables indicating
-// that the target does not exist.
+// IsIptableTargetNotExist returns true if the error is from iptables, or its nftables
+// backend, indicating that the target does not exist.
 func IsIptableTargetNotExist(err error) bool {
-	e, ok := err.(*iptables.Error)
+	e, ok := err.(interface{ IsNotExist() bool })
 	if !ok {
 		return false
 	}
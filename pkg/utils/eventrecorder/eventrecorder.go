@@ -84,6 +84,12 @@ func (e *EventRecorder) SendPodEvent(eventType, reason, action, message string)
 	log.Debugf("Sent pod event: eventType: %s, reason: %s, message: %s", eventType, reason, message)
 }
 
+// SendEvent will raise event on the given object with given type, reason, & message
+func (e *EventRecorder) SendEvent(regarding runtime.Object, eventType, reason, action, message string) {
+	e.Recorder.Eventf(regarding, nil, eventType, reason, action, "%s", message)
+	log.Debugf("Sent event: eventType: %s, reason: %s, message: %s", eventType, reason, message)
+}
+
 func findMyPod(k8sClient client.Client) (corev1.Pod, error) {
 	var pod corev1.Pod
 	// Find my nholuongut-node pod
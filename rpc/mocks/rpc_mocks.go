@@ -13,7 +13,7 @@
 //
 
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/nholuongut/amazon-vpc-cni-k8s/rpc (interfaces: CNIBackendClient,NPBackendClient)
+// Source: github.com/nholuongut/amazon-vpc-cni-k8s/rpc (interfaces: CNIBackendClient,IPAMBackendClient,NPBackendClient)
 
 // Package mock_rpc is a generated GoMock package.
 package mock_rpc
@@ -70,6 +70,26 @@ func (mr *MockCNIBackendClientMockRecorder) AddNetwork(arg0, arg1 interface{}, a
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNetwork", reflect.TypeOf((*MockCNIBackendClient)(nil).AddNetwork), varargs...)
 }
 
+// CheckNetwork mocks base method.
+func (m *MockCNIBackendClient) CheckNetwork(arg0 context.Context, arg1 *rpc.CheckNetworkRequest, arg2 ...grpc.CallOption) (*rpc.CheckNetworkReply, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CheckNetwork", varargs...)
+	ret0, _ := ret[0].(*rpc.CheckNetworkReply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckNetwork indicates an expected call of CheckNetwork.
+func (mr *MockCNIBackendClientMockRecorder) CheckNetwork(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckNetwork", reflect.TypeOf((*MockCNIBackendClient)(nil).CheckNetwork), varargs...)
+}
+
 // DelNetwork mocks base method.
 func (m *MockCNIBackendClient) DelNetwork(arg0 context.Context, arg1 *rpc.DelNetworkRequest, arg2 ...grpc.CallOption) (*rpc.DelNetworkReply, error) {
 	m.ctrl.T.Helper()
@@ -90,6 +110,89 @@ func (mr *MockCNIBackendClientMockRecorder) DelNetwork(arg0, arg1 interface{}, a
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DelNetwork", reflect.TypeOf((*MockCNIBackendClient)(nil).DelNetwork), varargs...)
 }
 
+// GarbageCollect mocks base method.
+func (m *MockCNIBackendClient) GarbageCollect(arg0 context.Context, arg1 *rpc.GarbageCollectRequest, arg2 ...grpc.CallOption) (*rpc.GarbageCollectReply, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GarbageCollect", varargs...)
+	ret0, _ := ret[0].(*rpc.GarbageCollectReply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GarbageCollect indicates an expected call of GarbageCollect.
+func (mr *MockCNIBackendClientMockRecorder) GarbageCollect(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GarbageCollect", reflect.TypeOf((*MockCNIBackendClient)(nil).GarbageCollect), varargs...)
+}
+
+// Status mocks base method.
+func (m *MockCNIBackendClient) Status(arg0 context.Context, arg1 *rpc.StatusRequest, arg2 ...grpc.CallOption) (*rpc.StatusReply, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Status", varargs...)
+	ret0, _ := ret[0].(*rpc.StatusReply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Status indicates an expected call of Status.
+func (mr *MockCNIBackendClientMockRecorder) Status(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockCNIBackendClient)(nil).Status), varargs...)
+}
+
+// MockIPAMBackendClient is a mock of IPAMBackendClient interface.
+type MockIPAMBackendClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockIPAMBackendClientMockRecorder
+}
+
+// MockIPAMBackendClientMockRecorder is the mock recorder for MockIPAMBackendClient.
+type MockIPAMBackendClientMockRecorder struct {
+	mock *MockIPAMBackendClient
+}
+
+// NewMockIPAMBackendClient creates a new mock instance.
+func NewMockIPAMBackendClient(ctrl *gomock.Controller) *MockIPAMBackendClient {
+	mock := &MockIPAMBackendClient{ctrl: ctrl}
+	mock.recorder = &MockIPAMBackendClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIPAMBackendClient) EXPECT() *MockIPAMBackendClientMockRecorder {
+	return m.recorder
+}
+
+// WatchAllocations mocks base method.
+func (m *MockIPAMBackendClient) WatchAllocations(arg0 context.Context, arg1 *rpc.WatchAllocationsRequest, arg2 ...grpc.CallOption) (rpc.IPAMBackend_WatchAllocationsClient, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "WatchAllocations", varargs...)
+	ret0, _ := ret[0].(rpc.IPAMBackend_WatchAllocationsClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WatchAllocations indicates an expected call of WatchAllocations.
+func (mr *MockIPAMBackendClientMockRecorder) WatchAllocations(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchAllocations", reflect.TypeOf((*MockIPAMBackendClient)(nil).WatchAllocations), varargs...)
+}
+
 // MockNPBackendClient is a mock of NPBackendClient interface.
 type MockNPBackendClient struct {
 	ctrl     *gomock.Controller
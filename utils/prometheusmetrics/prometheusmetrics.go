@@ -172,6 +172,39 @@ var (
 		},
 		[]string{"eni"},
 	)
+	AdaptiveWarmIPTarget = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "nholuongutcni_adaptive_warm_ip_target",
+			Help: "The warm IP target computed by the adaptive warm pool",
+		},
+	)
+	AdaptiveWarmIPTargetChanges = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nholuongutcni_adaptive_warm_ip_target_change_count",
+			Help: "The number of times the adaptive warm pool changed the warm IP target, by direction from the previous one",
+		},
+		[]string{"direction"},
+	)
+	AdaptivePendingPods = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "nholuongutcni_adaptive_pending_pods",
+			Help: "The number of pods of the node waiting for an IP address, as seen by the adaptive warm pool",
+		},
+	)
+	AdaptivePodChurn = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "nholuongutcni_adaptive_pod_churn",
+			Help: "The number of AddNetwork and DelNetwork calls within the window of the adaptive warm pool",
+		},
+		[]string{"call"},
+	)
+	HostNetworkRepairs = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nholuongutcni_host_network_repair_count",
+			Help: "The number of ip rules, routes and iptables rules of the host network found missing and added back, by kind",
+		},
+		[]string{"kind"},
+	)
 )
 
 // ServeMetrics sets up ipamd metrics and introspection endpoints
@@ -225,6 +258,11 @@ func PrometheusRegister() {
 	prometheus.MustRegister(IpsPerCidr)
 	prometheus.MustRegister(NoAvailableIPAddrs)
 	prometheus.MustRegister(EniIPsInUse)
+	prometheus.MustRegister(AdaptiveWarmIPTarget)
+	prometheus.MustRegister(AdaptiveWarmIPTargetChanges)
+	prometheus.MustRegister(AdaptivePendingPods)
+	prometheus.MustRegister(AdaptivePodChurn)
+	prometheus.MustRegister(HostNetworkRepairs)
 
 }
 